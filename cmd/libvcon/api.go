@@ -0,0 +1,181 @@
+//go:build cgo
+
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+// The functions below implement the C API on Go strings; test files cannot
+// import "C", so this is what the tests exercise.
+
+// validateJSON parses doc as BuildFromJSON does and reports whether it is
+// valid, with the issues found as JSON.
+func validateJSON(doc string) (bool, string, error) {
+	v, err := vcon.BuildFromJSON(doc)
+	if err != nil {
+		return false, "", err
+	}
+	ok, issues := v.IsValid()
+	if issues == nil {
+		issues = []vcon.ValidationIssue{}
+	}
+	data, err := json.Marshal(map[string]any{"valid": ok, "issues": issues})
+	return ok, string(data), err
+}
+
+// signJSON signs an unsigned vCon with keyPEM and the certificate chain in
+// chainPEM, leaf first.
+func signJSON(doc, keyPEM, chainPEM string) (string, error) {
+	v, err := vcon.BuildFromJSON(doc)
+	if err != nil {
+		return "", err
+	}
+	key, err := parsePrivateKeyPEM([]byte(keyPEM))
+	if err != nil {
+		return "", err
+	}
+	chain, err := parseCertificatesPEM([]byte(chainPEM))
+	if err != nil {
+		return "", err
+	}
+	signed, err := v.Sign(key, chain)
+	if err != nil {
+		return "", fmt.Errorf("signing: %w", err)
+	}
+	return marshalString(signed.JSON)
+}
+
+// verifyJSON verifies a JWS, wrapped in {"jws": ...} or bare, against the
+// trust anchors in caPEM and returns the vCon it carries.
+func verifyJSON(jws, caPEM string) (string, error) {
+	obj, err := parseObject(jws, "jws")
+	if err != nil {
+		return "", err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(caPEM)) {
+		return "", errors.New("no certificates found in trust anchor PEM")
+	}
+	v, err := (&vcon.SignedVCon{JSON: obj}).Verify(roots)
+	if err != nil {
+		return "", err
+	}
+	return v.ToJSON(), nil
+}
+
+// encryptJSON encrypts a JWS, wrapped or bare, for the holder of certPEM.
+func encryptJSON(jws, certPEM string) (string, error) {
+	obj, err := parseObject(jws, "jws")
+	if err != nil {
+		return "", err
+	}
+	certs, err := parseCertificatesPEM([]byte(certPEM))
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := (&vcon.SignedVCon{JSON: obj}).Encrypt([]jose.Recipient{{
+		Algorithm: jose.RSA_OAEP,
+		Key:       certs[0].PublicKey,
+	}})
+	if err != nil {
+		return "", fmt.Errorf("encrypting: %w", err)
+	}
+	return marshalString(encrypted)
+}
+
+// decryptJSON decrypts a JWE, wrapped in {"jwe": ...} or bare, with keyPEM
+// and returns the JWS it carries.
+func decryptJSON(jwe, keyPEM string) (string, error) {
+	obj, err := parseObject(jwe, "jwe")
+	if err != nil {
+		return "", err
+	}
+	key, err := parsePrivateKeyPEM([]byte(keyPEM))
+	if err != nil {
+		return "", err
+	}
+	plain, err := (&vcon.EncryptedVCon{JSON: obj}).Decrypt(key)
+	if err != nil {
+		return "", fmt.Errorf("decrypting: %w", err)
+	}
+	return marshalString(plain)
+}
+
+// parseObject decodes a JSON object, unwrapping it from the wrapper key
+// when it has one.
+func parseObject(s, wrapper string) (map[string]any, error) {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(s), &obj); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if inner, ok := obj[wrapper].(map[string]any); ok {
+		return inner, nil
+	}
+	return obj, nil
+}
+
+func marshalString(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parsePrivateKeyPEM decodes a PKCS#1 or PKCS#8 RSA private key.
+func parsePrivateKeyPEM(raw []byte) (*rsa.PrivateKey, error) {
+	b, _ := pem.Decode(raw)
+	if b == nil {
+		return nil, errors.New("no PEM block found in private key")
+	}
+	switch b.Type {
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(b.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("PKCS1 parse: %w", err)
+		}
+		return k, nil
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(b.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("PKCS8 parse: %w", err)
+		}
+		if rsaK, ok := k.(*rsa.PrivateKey); ok {
+			return rsaK, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported key type %q", b.Type)
+}
+
+// parseCertificatesPEM decodes every certificate in raw, in order.
+func parseCertificatesPEM(raw []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var b *pem.Block
+		b, raw = pem.Decode(raw)
+		if b == nil {
+			break
+		}
+		if b.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(b.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found in PEM")
+	}
+	return certs, nil
+}
@@ -0,0 +1,100 @@
+//go:build cgo
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+// testIdentity returns a PKCS#1 key and a self-signed certificate for it.
+func testIdentity(t *testing.T) (keyPEM, certPEM string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "recorder"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	return keyPEM, certPEM
+}
+
+func TestSignVerifyEncryptDecrypt(t *testing.T) {
+	keyPEM, certPEM := testIdentity(t)
+	v := vcon.New("example.com")
+	v.Subject = "c-shared"
+
+	jws, err := signJSON(v.ToJSON(), keyPEM, certPEM)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	verified, err := verifyJSON(jws, certPEM)
+	if err != nil || !strings.Contains(verified, `"subject":"c-shared"`) {
+		t.Fatalf("verify: %v %s", err, verified)
+	}
+
+	jwe, err := encryptJSON(jws, certPEM)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !strings.HasPrefix(jwe, `{"jwe":`) {
+		t.Errorf("expected a wrapped JWE, got %.40s", jwe)
+	}
+	decrypted, err := decryptJSON(jwe, keyPEM)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if _, err := verifyJSON(decrypted, certPEM); err != nil {
+		t.Errorf("decrypted JWS does not verify: %v", err)
+	}
+
+	otherKey, otherCert := testIdentity(t)
+	if _, err := verifyJSON(jws, otherCert); err == nil {
+		t.Error("expected verification against another trust anchor to fail")
+	}
+	if _, err := decryptJSON(jwe, otherKey); err == nil {
+		t.Error("expected decryption with another key to fail")
+	}
+	if _, err := signJSON(v.ToJSON(), "not a key", certPEM); err == nil {
+		t.Error("expected an invalid key to be rejected")
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	ok, report, err := validateJSON(vcon.New("example.com").ToJSON())
+	if err != nil || !ok || report != `{"issues":[],"valid":true}` {
+		t.Errorf("unexpected result: %v %v %s", ok, err, report)
+	}
+
+	ok, report, err = validateJSON(`{"vcon": "0.4.0", "uuid": "0190b6c4-0000-8000-8000-000000000000", "created_at": "2024-01-01T00:00:00Z",
+		"parties": [], "dialog": [{"type": "text", "start": "2024-01-01T00:00:00Z", "parties": [3]}]}`)
+	if err != nil || ok || !strings.Contains(report, `"code":"`+vcon.IssueInvalidPartyIndex+`"`) {
+		t.Errorf("expected an invalid party index issue: %v %v %s", ok, err, report)
+	}
+
+	if _, _, err := validateJSON("{"); err == nil {
+		t.Error("expected malformed JSON to fail")
+	}
+}
@@ -0,0 +1,86 @@
+// Command libvcon builds go-vcon as a C shared library for software that
+// cannot embed Go, such as C++ or .NET call recorders:
+//
+//	go build -buildmode=c-shared -o libvcon.so ./cmd/libvcon
+//
+// vcon.h declares the API; its functions and return codes are kept stable
+// across releases.
+package main
+
+/*
+#include <stdlib.h>
+#include "vcon.h"
+
+// cchar lets exported functions take the const strings vcon.h declares.
+typedef const char cchar;
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+func main() {}
+
+// result stores s in *out and returns code, or the error message and
+// VCON_ERROR when err is set.
+func result(out **C.char, s string, code C.int, err error) C.int {
+	if err != nil {
+		s, code = err.Error(), C.VCON_ERROR
+	}
+	if out != nil {
+		*out = C.CString(s)
+	}
+	return code
+}
+
+// goString copies a C string passed to the API.
+func goString(s *C.cchar) string {
+	return C.GoString((*C.char)(unsafe.Pointer(s)))
+}
+
+//export vcon_validate
+func vcon_validate(json *C.cchar, out **C.char) C.int {
+	ok, report, err := validateJSON(goString(json))
+	code := C.int(C.VCON_OK)
+	if !ok {
+		code = C.VCON_INVALID
+	}
+	return result(out, report, code, err)
+}
+
+//export vcon_sign
+func vcon_sign(json, keyPEM, chainPEM *C.cchar, out **C.char) C.int {
+	s, err := signJSON(goString(json), goString(keyPEM), goString(chainPEM))
+	return result(out, s, C.VCON_OK, err)
+}
+
+//export vcon_verify
+func vcon_verify(jws, caPEM *C.cchar, out **C.char) C.int {
+	s, err := verifyJSON(goString(jws), goString(caPEM))
+	return result(out, s, C.VCON_OK, err)
+}
+
+//export vcon_encrypt
+func vcon_encrypt(jws, certPEM *C.cchar, out **C.char) C.int {
+	s, err := encryptJSON(goString(jws), goString(certPEM))
+	return result(out, s, C.VCON_OK, err)
+}
+
+//export vcon_decrypt
+func vcon_decrypt(jwe, keyPEM *C.cchar, out **C.char) C.int {
+	s, err := decryptJSON(goString(jwe), goString(keyPEM))
+	return result(out, s, C.VCON_OK, err)
+}
+
+//export vcon_spec_version
+func vcon_spec_version() *C.char {
+	return C.CString(vcon.SpecVersion)
+}
+
+//export vcon_free
+func vcon_free(p *C.char) {
+	C.free(unsafe.Pointer(p))
+}
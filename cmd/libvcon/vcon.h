@@ -0,0 +1,50 @@
+/*
+ * go-vcon C API, built with:
+ *
+ *   go build -buildmode=c-shared -o libvcon.so ./cmd/libvcon
+ *
+ * Every function takes NUL-terminated UTF-8 strings and stores its result,
+ * or an error message when it fails, in *out. The caller owns *out and must
+ * release it with vcon_free. JSON results use the same forms as vconctl:
+ * signed vCons are General JSON JWS objects, encrypted ones are wrapped in
+ * {"jwe": ...}.
+ */
+#ifndef GO_VCON_H
+#define GO_VCON_H
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* Return codes. */
+#define VCON_OK 0
+#define VCON_INVALID 1 /* vcon_validate: the vCon parsed but has errors */
+#define VCON_ERROR (-1) /* *out holds an error message */
+
+/* Validates an unsigned vCon. *out receives {"valid": bool, "issues": [...]}. */
+int vcon_validate(const char* json, char** out);
+
+/* Signs an unsigned vCon with a PEM RSA private key and PEM certificate
+ * chain, leaf first. *out receives the JWS. */
+int vcon_sign(const char* json, const char* key_pem, const char* chain_pem, char** out);
+
+/* Verifies a JWS against PEM trust anchors. *out receives the vCon. */
+int vcon_verify(const char* jws, const char* ca_pem, char** out);
+
+/* Encrypts a JWS for the holder of a PEM certificate. *out receives the JWE. */
+int vcon_encrypt(const char* jws, const char* cert_pem, char** out);
+
+/* Decrypts a JWE with a PEM RSA private key. *out receives the JWS. */
+int vcon_decrypt(const char* jwe, const char* key_pem, char** out);
+
+/* Returns the vCon spec version supported; release it with vcon_free. */
+char* vcon_spec_version(void);
+
+/* Releases a string returned by this library. */
+void vcon_free(char* p);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* GO_VCON_H */
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+// The functions below are the JavaScript API. They take and return plain
+// values (strings, booleans, maps and slices of them) so the same code is
+// exercised by the host tests and by the WebAssembly build. Failures are
+// reported in an "error" field rather than thrown.
+
+// validate parses doc as BuildFromJSON does and validates the result.
+func validate(doc string) map[string]any {
+	v, err := vcon.BuildFromJSON(doc)
+	if err != nil {
+		return map[string]any{"valid": false, "issues": []any{}, "error": err.Error()}
+	}
+	ok, issues := v.IsValid()
+	list := make([]any, len(issues))
+	for i, issue := range issues {
+		list[i] = map[string]any{
+			"path":     issue.Path,
+			"code":     issue.Code,
+			"message":  issue.Message,
+			"severity": string(issue.Severity),
+		}
+	}
+	return map[string]any{"valid": ok, "issues": list}
+}
+
+// verify checks a signed vCon, either a bare General JSON JWS or one wrapped
+// in {"jws": ...}, against the PEM trust anchors in caPEM. On success the
+// verified vCon is returned as JSON.
+func verify(signed, caPEM string) map[string]any {
+	var jws map[string]any
+	if err := json.Unmarshal([]byte(signed), &jws); err != nil {
+		return map[string]any{"valid": false, "error": "failed to parse JSON: " + err.Error()}
+	}
+	if inner, ok := jws["jws"].(map[string]any); ok {
+		jws = inner
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(caPEM)) {
+		return map[string]any{"valid": false, "error": "no certificates found in trust anchor PEM"}
+	}
+	v, err := (&vcon.SignedVCon{JSON: jws}).Verify(roots)
+	if err != nil {
+		return map[string]any{"valid": false, "error": err.Error()}
+	}
+	return map[string]any{"valid": true, "vcon": v.ToJSON()}
+}
+
+// canonicalize returns the RFC 8785 canonical form of the JSON in doc, the
+// form a vCon is signed in.
+func canonicalize(doc string) map[string]any {
+	var value any
+	if err := json.Unmarshal([]byte(doc), &value); err != nil {
+		return map[string]any{"error": "failed to parse JSON: " + err.Error()}
+	}
+	canon, err := vcon.Canonicalise(value)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	return map[string]any{"canonical": string(canon)}
+}
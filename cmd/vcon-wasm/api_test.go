@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+func TestValidate(t *testing.T) {
+	doc, err := os.ReadFile("../../testdata/sample_vcons/simple-vcon.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := validate(string(doc)); got["valid"] != true {
+		t.Errorf("sample vCon should be valid: %v", got)
+	}
+
+	got := validate(`{"vcon": "0.4.0", "uuid": "0190b6c4-0000-8000-8000-000000000000", "created_at": "2024-01-01T00:00:00Z",
+		"parties": [], "dialog": [{"type": "text", "start": "2024-01-01T00:00:00Z", "parties": [3]}]}`)
+	issues := got["issues"].([]any)
+	if got["valid"] != false || len(issues) == 0 {
+		t.Fatalf("expected issues, got %v", got)
+	}
+	if code := issues[0].(map[string]any)["code"]; code != vcon.IssueInvalidPartyIndex {
+		t.Errorf("unexpected issue code %v", code)
+	}
+
+	if got := validate("{"); got["valid"] != false || got["error"] == nil {
+		t.Errorf("malformed JSON should report an error: %v", got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "vcon test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+	v := vcon.New("example.com")
+	v.Subject = "wasm"
+	signed, err := v.Sign(key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(signed)
+
+	got := verify(string(data), caPEM)
+	if got["valid"] != true || !strings.Contains(got["vcon"].(string), `"subject":"wasm"`) {
+		t.Errorf("expected a verified vCon, got %v", got)
+	}
+
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ = x509.CreateCertificate(rand.Reader, tmpl, tmpl, &other.PublicKey, other)
+	untrusted := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	if got := verify(string(data), untrusted); got["valid"] != false || got["error"] == nil {
+		t.Errorf("untrusted signer should fail: %v", got)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	got := canonicalize(`{"b": 1, "a": [2.0, "x"]}`)
+	if got["canonical"] != `{"a":[2,"x"],"b":1}` {
+		t.Errorf("unexpected canonical form: %v", got)
+	}
+	if got := canonicalize("["); got["error"] == nil {
+		t.Errorf("malformed JSON should report an error: %v", got)
+	}
+}
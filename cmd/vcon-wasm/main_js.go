@@ -0,0 +1,46 @@
+//go:build js && wasm
+
+// Command vcon-wasm exposes vCon validation, signature verification and
+// canonicalization to JavaScript. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o vcon.wasm ./cmd/vcon-wasm
+//
+// and load it with the wasm_exec.js shipped with Go. It installs a global
+// "vcon" object:
+//
+//	vcon.validate(json)          // {valid, issues, error?}
+//	vcon.verify(signedJSON, pem) // {valid, vcon?, error?}
+//	vcon.canonicalize(json)      // {canonical?, error?}
+//	vcon.specVersion             // the vCon spec version supported
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+func main() {
+	js.Global().Set("vcon", js.ValueOf(map[string]any{
+		"validate": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			return validate(stringArg(args, 0))
+		}),
+		"verify": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			return verify(stringArg(args, 0), stringArg(args, 1))
+		}),
+		"canonicalize": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			return canonicalize(stringArg(args, 0))
+		}),
+		"specVersion": vcon.SpecVersion,
+	}))
+	// Keep the functions callable for the lifetime of the page or worker.
+	select {}
+}
+
+// stringArg returns argument i as a string, or "" when it is missing.
+func stringArg(args []js.Value, i int) string {
+	if i >= len(args) || args[i].Type() != js.TypeString {
+		return ""
+	}
+	return args[i].String()
+}
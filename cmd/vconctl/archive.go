@@ -0,0 +1,536 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+// Command: archive
+
+const (
+	archiveFormat       = "vconctl-archive/1"
+	archiveManifestName = "MANIFEST.json"
+	archiveSigName      = "MANIFEST.jws"
+	archiveVConDir      = "vcons/"
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Create and verify long-term archives of signed vCons",
+	Long: `Bundle signed vCons into a tar or zip archive for long-term, write-once
+storage, and later prove the archive is exactly what was written.
+
+Each archive holds the vCons under vcons/, a MANIFEST.json listing the size
+and SHA-256 of every file together with the root of a Merkle tree over them,
+and MANIFEST.jws, a detached JWS over the manifest signed with the archiver's
+key. Adding, removing or altering any record changes the Merkle root, which
+is covered by the signature. --previous links an archive to the one before
+it, forming a chain of custody.`,
+}
+
+var archiveCreateCmd = &cobra.Command{
+	Use:   "create <file> [file ...]",
+	Short: "Write signed vCons to an archive with a signed manifest",
+	Long: `Write signed vCons to a new archive with a signed Merkle-tree manifest.
+
+The format follows the --output extension: .tar, .tar.gz, .tgz or .zip.
+Entries are written in a fixed order with the manifest's timestamp and
+read-only permissions, so the archive suits WORM storage. Unsigned and
+encrypted vCons are rejected; sign them first.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runArchiveCreate,
+}
+
+var archiveVerifyCmd = &cobra.Command{
+	Use:   "verify <archive>",
+	Short: "Check an archive against its signed manifest",
+	Long: `Check that an archive holds exactly the files its manifest lists, with
+the recorded hashes, that the Merkle root matches them and that the manifest
+signature chains to --ca. --vcons also verifies the signature on every vCon
+and --previous checks the link to the archive before this one.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runArchiveVerify,
+}
+
+// archiveManifest is the MANIFEST.json of an archive.
+type archiveManifest struct {
+	Format        string         `json:"format"`
+	CreatedAt     string         `json:"created_at"`
+	CreatedBy     string         `json:"created_by,omitempty"`
+	HashAlgorithm string         `json:"hash_algorithm"`
+	Entries       []archiveEntry `json:"entries"`
+	MerkleRoot    string         `json:"merkle_root"`
+	Previous      *archiveLink   `json:"previous,omitempty"`
+}
+
+type archiveEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	UUID   string `json:"uuid"`
+}
+
+// archiveLink records the archive before this one in a chain of custody.
+type archiveLink struct {
+	Archive        string `json:"archive"`
+	MerkleRoot     string `json:"merkle_root"`
+	ManifestSHA256 string `json:"manifest_sha256"`
+}
+
+// archiveFile is a file read from or written to an archive.
+type archiveFile struct {
+	Name string
+	Data []byte
+}
+
+func runArchiveCreate(cmd *cobra.Command, args []string) error {
+	out, _ := cmd.Flags().GetString("output")
+	prevPath, _ := cmd.Flags().GetString("previous")
+	if out == "" {
+		return errors.New("--output is required")
+	}
+	priv, cert, err := signingMaterial(cmd)
+	if err != nil {
+		return err
+	}
+
+	created := time.Now().UTC().Truncate(time.Second)
+	m := archiveManifest{
+		Format:        archiveFormat,
+		CreatedAt:     created.Format(time.RFC3339),
+		CreatedBy:     cert.Subject.CommonName,
+		HashAlgorithm: "sha256",
+	}
+	var files []archiveFile
+	for _, p := range args {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		entry, err := archiveEntryFor(archiveVConDir+filepath.Base(p), data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if slices.ContainsFunc(m.Entries, func(e archiveEntry) bool { return e.Path == entry.Path }) {
+			return fmt.Errorf("%s: another input is also named %s", p, filepath.Base(p))
+		}
+		m.Entries = append(m.Entries, entry)
+		files = append(files, archiveFile{entry.Path, data})
+	}
+	slices.SortFunc(m.Entries, func(a, b archiveEntry) int { return strings.Compare(a.Path, b.Path) })
+	slices.SortFunc(files, func(a, b archiveFile) int { return strings.Compare(a.Name, b.Name) })
+	m.MerkleRoot = hex.EncodeToString(merkleRoot(m.Entries))
+
+	if prevPath != "" {
+		link, err := archiveLinkTo(prevPath)
+		if err != nil {
+			return fmt.Errorf("previous archive: %w", err)
+		}
+		m.Previous = link
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	sig, err := signManifest(manifest, priv, cert)
+	if err != nil {
+		return fmt.Errorf("signing manifest: %w", err)
+	}
+	files = append([]archiveFile{{archiveManifestName, manifest}, {archiveSigName, []byte(sig)}}, files...)
+
+	data, err := writeArchive(out, files, created)
+	if err != nil {
+		return err
+	}
+	if err := writeOutputFile(out, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Archived %d vCons to %s\n", len(m.Entries), out)
+	fmt.Printf("   merkle root %s\n", m.MerkleRoot)
+	return nil
+}
+
+// archiveEntryFor describes a signed vCon stored at name.
+func archiveEntryFor(name string, data []byte) (archiveEntry, error) {
+	form, err := vcon.DetectForm(data)
+	if err != nil {
+		return archiveEntry{}, err
+	}
+	if form != vcon.VConFormSigned {
+		return archiveEntry{}, fmt.Errorf("only signed vCons can be archived, got a %s vCon", form)
+	}
+	payload, err := canonicalPayload(data)
+	if err != nil {
+		return archiveEntry{}, err
+	}
+	var v struct {
+		UUID string `json:"uuid"`
+	}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return archiveEntry{}, fmt.Errorf("decode payload: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return archiveEntry{Path: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:]), UUID: v.UUID}, nil
+}
+
+// merkleRoot computes an RFC 6962 style Merkle tree hash over entries, in
+// order. Leaves hash the path with the content hash, so renaming a record
+// changes the root as well as altering it.
+func merkleRoot(entries []archiveEntry) []byte {
+	if len(entries) == 0 {
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	}
+	if len(entries) == 1 {
+		h := sha256.New()
+		h.Write([]byte{0x00})
+		h.Write([]byte(entries[0].Path))
+		h.Write([]byte{0x00})
+		h.Write([]byte(entries[0].SHA256))
+		return h.Sum(nil)
+	}
+	k := 1
+	for k*2 < len(entries) {
+		k *= 2
+	}
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(merkleRoot(entries[:k]))
+	h.Write(merkleRoot(entries[k:]))
+	return h.Sum(nil)
+}
+
+// signManifest returns a compact JWS with a detached payload over manifest,
+// carrying cert in its x5c header.
+func signManifest(manifest []byte, priv crypto.Signer, cert *x509.Certificate) (string, error) {
+	alg, err := vcon.SigningAlgorithm(priv)
+	if err != nil {
+		return "", err
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: priv},
+		(&jose.SignerOptions{}).
+			WithContentType("application/json").
+			WithHeader("x5c", []string{base64.StdEncoding.EncodeToString(cert.Raw)}))
+	if err != nil {
+		return "", err
+	}
+	obj, err := signer.Sign(manifest)
+	if err != nil {
+		return "", err
+	}
+	return obj.DetachedCompactSerialize()
+}
+
+// verifyManifest checks sig over manifest against roots and returns the
+// signing certificate.
+func verifyManifest(manifest []byte, sig string, roots *x509.CertPool) (*x509.Certificate, error) {
+	obj, err := jose.ParseDetached(strings.TrimSpace(sig), manifest, vcon.DefaultSignatureAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("parse JWS: %w", err)
+	}
+	chains, err := obj.Signatures[0].Header.Certificates(x509.VerifyOptions{Roots: roots})
+	if err != nil {
+		return nil, fmt.Errorf("bad cert chain: %w", err)
+	}
+	leaf := chains[0][0]
+	if _, err := obj.Verify(leaf.PublicKey); err != nil {
+		return nil, fmt.Errorf("signature invalid: %w", err)
+	}
+	return leaf, nil
+}
+
+// archiveLinkTo reads the manifest of the archive at p for --previous.
+func archiveLinkTo(p string) (*archiveLink, error) {
+	files, err := readArchive(p)
+	if err != nil {
+		return nil, err
+	}
+	manifest, _, err := archiveManifestOf(files)
+	if err != nil {
+		return nil, err
+	}
+	var m archiveManifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", archiveManifestName, err)
+	}
+	sum := sha256.Sum256(manifest)
+	return &archiveLink{Archive: filepath.Base(p), MerkleRoot: m.MerkleRoot, ManifestSHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+// archiveManifestOf returns the manifest and its signature from files.
+func archiveManifestOf(files []archiveFile) (manifest, sig []byte, err error) {
+	for _, f := range files {
+		switch f.Name {
+		case archiveManifestName:
+			manifest = f.Data
+		case archiveSigName:
+			sig = f.Data
+		}
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("no %s in archive", archiveManifestName)
+	}
+	if sig == nil {
+		return nil, nil, fmt.Errorf("no %s in archive", archiveSigName)
+	}
+	return manifest, sig, nil
+}
+
+func runArchiveVerify(cmd *cobra.Command, args []string) error {
+	caPath, _ := cmd.Flags().GetString("ca")
+	checkVCons, _ := cmd.Flags().GetBool("vcons")
+	prevPath, _ := cmd.Flags().GetString("previous")
+	if caPath == "" {
+		return errors.New("--ca is required")
+	}
+	roots := x509.NewCertPool()
+	if !appendPEMToPool(roots, caPath) {
+		return fmt.Errorf("invalid PEM in %s", caPath)
+	}
+
+	files, err := readArchive(args[0])
+	if err != nil {
+		return err
+	}
+	manifest, sig, err := archiveManifestOf(files)
+	if err != nil {
+		return err
+	}
+	signer, err := verifyManifest(manifest, string(sig), roots)
+	if err != nil {
+		return fmt.Errorf("manifest signature: %w", err)
+	}
+	var m archiveManifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return fmt.Errorf("parse %s: %w", archiveManifestName, err)
+	}
+	if m.Format != archiveFormat {
+		return fmt.Errorf("unsupported archive format %q", m.Format)
+	}
+	fmt.Printf("✅ Manifest signed by %s at %s\n", signer.Subject.CommonName, m.CreatedAt)
+
+	var problems []string
+	listed := make(map[string]archiveEntry, len(m.Entries))
+	for _, e := range m.Entries {
+		listed[e.Path] = e
+	}
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		if f.Name == archiveManifestName || f.Name == archiveSigName {
+			continue
+		}
+		if seen[f.Name] {
+			problems = append(problems, f.Name+": duplicate entry")
+			continue
+		}
+		seen[f.Name] = true
+		e, ok := listed[f.Name]
+		if !ok {
+			problems = append(problems, f.Name+": not in manifest")
+			continue
+		}
+		sum := sha256.Sum256(f.Data)
+		if int64(len(f.Data)) != e.Size || hex.EncodeToString(sum[:]) != e.SHA256 {
+			problems = append(problems, f.Name+": content does not match manifest")
+			continue
+		}
+		if checkVCons {
+			var jws map[string]any
+			if err := json.Unmarshal(f.Data, &jws); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", f.Name, err))
+			} else if _, err := (&vcon.SignedVCon{JSON: jws}).Verify(roots); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", f.Name, err))
+			}
+		}
+	}
+	for _, e := range m.Entries {
+		if !seen[e.Path] {
+			problems = append(problems, e.Path+": missing from archive")
+		}
+	}
+	if root := hex.EncodeToString(merkleRoot(m.Entries)); root != m.MerkleRoot {
+		problems = append(problems, "merkle root does not match the manifest entries")
+	}
+
+	if prevPath != "" {
+		link, err := archiveLinkTo(prevPath)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("previous archive: %v", err))
+		case m.Previous == nil:
+			problems = append(problems, "manifest does not link to a previous archive")
+		case m.Previous.MerkleRoot != link.MerkleRoot || m.Previous.ManifestSHA256 != link.ManifestSHA256:
+			problems = append(problems, fmt.Sprintf("previous archive %s does not match the recorded link", prevPath))
+		}
+	}
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Printf("   ❌ %s\n", p)
+		}
+		return fmt.Errorf("%s failed verification with %d problems", args[0], len(problems))
+	}
+	fmt.Printf("✅ %d vCons match merkle root %s\n", len(m.Entries), m.MerkleRoot)
+	if m.Previous != nil {
+		fmt.Printf("   follows %s (merkle root %s)\n", m.Previous.Archive, m.Previous.MerkleRoot)
+	}
+	return nil
+}
+
+// archiveKind returns "zip", "tgz" or "tar" from the extension of p.
+func archiveKind(p string) (string, error) {
+	lower := strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip", nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tgz", nil
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar", nil
+	}
+	return "", fmt.Errorf("%s: archive must end in .tar, .tar.gz, .tgz or .zip", p)
+}
+
+// writeArchive encodes files in the format named by p's extension, stamping
+// every entry with modTime and read-only permissions.
+func writeArchive(p string, files []archiveFile, modTime time.Time) ([]byte, error) {
+	kind, err := archiveKind(p)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if kind == "zip" {
+		zw := zip.NewWriter(&buf)
+		for _, f := range files {
+			hdr := &zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: modTime}
+			hdr.SetMode(0444)
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := w.Write(f.Data); err != nil {
+				return nil, err
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if kind == "tgz" {
+		gz = gzip.NewWriter(&buf)
+		gz.ModTime = modTime
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.Name, Mode: 0444, Size: int64(len(f.Data)), ModTime: modTime, Format: tar.FormatPAX}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// readArchive returns the regular files in the archive at p, in archive
+// order, with names cleaned of any leading "./".
+func readArchive(p string) ([]archiveFile, error) {
+	kind, err := archiveKind(p)
+	if err != nil {
+		return nil, err
+	}
+	var files []archiveFile
+	if kind == "zip" {
+		zr, err := zip.OpenReader(p)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", zf.Name, err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", zf.Name, err)
+			}
+			files = append(files, archiveFile{path.Clean(zf.Name), data})
+		}
+		return files, nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if kind == "tgz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", p, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+		files = append(files, archiveFile{path.Clean(hdr.Name), data})
+	}
+	return files, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+func TestArchiveCreateVerify(t *testing.T) {
+	dir := t.TempDir()
+	keyPath, certPath := writeTestKeyPair(t, t.TempDir())
+	priv, cert := readPrivateKey(keyPath), readCertificate(certPath)
+
+	var inputs []string
+	for _, subject := range []string{"first", "second", "third"} {
+		v := vcon.New("test.example.com")
+		v.Subject = subject
+		signed, err := v.Sign(priv, []*x509.Certificate{cert})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(signed.JSON)
+		p := filepath.Join(dir, subject+".json")
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, p)
+	}
+
+	newCmd := func(flags map[string]string) *cobra.Command {
+		cmd := &cobra.Command{}
+		for _, name := range []string{"output", "key", "cert", "keyring-alias", "previous", "ca"} {
+			cmd.Flags().String(name, "", "")
+		}
+		cmd.Flags().Bool("vcons", false, "")
+		for k, val := range flags {
+			if err := cmd.Flags().Set(k, val); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cmd
+	}
+
+	first := filepath.Join(dir, "first.tar")
+	captureStdout(t, func() {
+		if err := runArchiveCreate(newCmd(map[string]string{"output": first, "key": keyPath, "cert": certPath}), inputs); err != nil {
+			t.Fatalf("archive create: %v", err)
+		}
+	})
+	out := captureStdout(t, func() {
+		if err := runArchiveVerify(newCmd(map[string]string{"ca": certPath, "vcons": "true"}), []string{first}); err != nil {
+			t.Fatalf("archive verify: %v", err)
+		}
+	})
+	if !strings.Contains(out, "3 vCons match merkle root") {
+		t.Errorf("unexpected verify output %q", out)
+	}
+
+	second := filepath.Join(dir, "second.zip")
+	captureStdout(t, func() {
+		if err := runArchiveCreate(newCmd(map[string]string{"output": second, "key": keyPath, "cert": certPath, "previous": first}), inputs[:1]); err != nil {
+			t.Fatalf("archive create --previous: %v", err)
+		}
+	})
+	out = captureStdout(t, func() {
+		if err := runArchiveVerify(newCmd(map[string]string{"ca": certPath, "previous": first}), []string{second}); err != nil {
+			t.Fatalf("archive verify --previous: %v", err)
+		}
+	})
+	if !strings.Contains(out, "follows first.tar") {
+		t.Errorf("unexpected verify output %q", out)
+	}
+
+	// Rewrite the first archive with one record altered, one removed and one
+	// added; each must be reported.
+	files, err := readArchive(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tampered []archiveFile
+	for _, f := range files {
+		switch f.Name {
+		case "vcons/first.json":
+			f.Data = append(f.Data, ' ')
+		case "vcons/second.json":
+			continue
+		}
+		tampered = append(tampered, f)
+	}
+	tampered = append(tampered, archiveFile{"vcons/extra.json", []byte("{}")})
+	data, err := writeArchive(first, tampered, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "tampered.tar")
+	if err := os.WriteFile(bad, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out = captureStdout(t, func() {
+		if err := runArchiveVerify(newCmd(map[string]string{"ca": certPath}), []string{bad}); err == nil {
+			t.Error("expected a tampered archive to fail")
+		}
+	})
+	for _, want := range []string{"first.json: content does not match", "second.json: missing", "extra.json: not in manifest"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("verify output %q lacks %q", out, want)
+		}
+	}
+
+	_, otherCert := writeTestKeyPair(t, t.TempDir())
+	if err := runArchiveVerify(newCmd(map[string]string{"ca": otherCert}), []string{first}); err == nil {
+		t.Error("expected an untrusted manifest signature to fail")
+	}
+	if err := runArchiveVerify(newCmd(map[string]string{"ca": certPath, "previous": second}), []string{first}); err == nil {
+		t.Error("expected a missing chain-of-custody link to fail")
+	}
+
+	unsigned := filepath.Join(dir, "unsigned.json")
+	if err := vcon.New("test.example.com").SaveToFile(unsigned); err != nil {
+		t.Fatal(err)
+	}
+	err = runArchiveCreate(newCmd(map[string]string{"output": filepath.Join(dir, "x.tar"), "key": keyPath, "cert": certPath}), []string{unsigned})
+	if err == nil || !strings.Contains(err.Error(), "only signed vCons") {
+		t.Errorf("expected an unsigned vCon to be rejected, got %v", err)
+	}
+}
+
+func TestMerkleRoot(t *testing.T) {
+	entries := []archiveEntry{{Path: "a", SHA256: "1"}, {Path: "b", SHA256: "2"}, {Path: "c", SHA256: "3"}}
+	root := merkleRoot(entries)
+	if string(merkleRoot(entries)) != string(root) {
+		t.Error("merkle root is not deterministic")
+	}
+	for i := range entries {
+		changed := append([]archiveEntry(nil), entries...)
+		changed[i].SHA256 = "x"
+		if string(merkleRoot(changed)) == string(root) {
+			t.Errorf("changing entry %d kept the root", i)
+		}
+	}
+	if string(merkleRoot(entries[:2])) == string(root) {
+		t.Error("removing an entry kept the root")
+	}
+}
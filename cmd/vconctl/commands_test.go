@@ -2,11 +2,13 @@ package main
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"io"
 	"math/big"
 	"os"
@@ -18,6 +20,7 @@ import (
 	"github.com/go-jose/go-jose/v4"
 	"github.com/robjsliwa/go-vcon/pkg/vcon"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func TestValidateCommand(t *testing.T) {
@@ -265,9 +268,9 @@ func TestEmailCommandValidation(t *testing.T) {
 			expectError: true,
 		},
 		{
-			name:        "too many args",
+			name:        "several messages",
 			args:        []string{"file1.eml", "file2.eml"},
-			expectError: true,
+			expectError: false,
 		},
 	}
 
@@ -517,3 +520,733 @@ func TestDetectCommandIntegration(t *testing.T) {
 		t.Error("detect nonexistent file should return error")
 	}
 }
+
+func TestValidateStrictReject(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.json")
+	bad := filepath.Join(dir, "bad.json")
+	writeFile := func(path, body string) {
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(good, `{"uuid":"018f0000-0000-8000-8000-000000000000","created_at":"2024-01-01T00:00:00Z","parties":[{"name":"Alice"}]}`)
+	writeFile(bad, `{"uuid":"018f0000-0000-8000-8000-000000000000","created_at":"2024-01-01T00:00:00Z","parties":[{"name":"Alice","x_crm_id":"42"}],"x_tenant":"blue"}`)
+
+	out := captureStdout(t, func() {
+		if err := runValidate(validateCmd, []string{good, bad}); err != nil {
+			t.Errorf("without --strict-reject unknown properties should be dropped: %v", err)
+		}
+	})
+	if strings.Contains(out, "❌") {
+		t.Errorf("unexpected failure: %q", out)
+	}
+
+	setFlags(t, map[string]string{"report-unknown": "true"}, validateCmd.Flags().Set)
+	t.Cleanup(func() { validateCmd.Flags().Set("report-unknown", "false") })
+	out = captureStdout(t, func() {
+		if err := runValidate(validateCmd, []string{good, bad}); err != nil {
+			t.Errorf("--report-unknown should only warn: %v", err)
+		}
+	})
+	for _, want := range []string{"⚠️  /parties/0/x_crm_id: non-standard property dropped", "⚠️  /x_tenant: non-standard property dropped", "bad.json is valid"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+
+	setFlags(t, map[string]string{"strict-reject": "true"}, validateCmd.Flags().Set)
+	t.Cleanup(func() { validateCmd.Flags().Set("strict-reject", "false") })
+	var err error
+	out = captureStdout(t, func() { err = runValidate(validateCmd, []string{good, bad}) })
+	if err == nil {
+		t.Error("expected --strict-reject to fail")
+	}
+	for _, want := range []string{"good.json is valid", "/parties/0/x_crm_id", "/x_tenant"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+}
+
+func TestValidatePartyURIs(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "bare.json")
+	body := `{"uuid":"018f0000-0000-8000-8000-000000000000","created_at":"2024-01-01T00:00:00Z","parties":[{"name":"Alice","tel":"+12025551234"}]}`
+	if err := os.WriteFile(src, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	out := captureStdout(t, func() { err = runValidate(validateCmd, []string{src}) })
+	if err != nil {
+		t.Errorf("a bare number should only warn: %v", err)
+	}
+	if !strings.Contains(out, "⚠️") || !strings.Contains(out, "bare.json is valid") {
+		t.Errorf("expected a warning and a valid file, got %q", out)
+	}
+
+	setFlags(t, map[string]string{"strict-uris": "true"}, validateCmd.Flags().Set)
+	t.Cleanup(func() { validateCmd.Flags().Set("strict-uris", "false") })
+	out = captureStdout(t, func() { err = runValidate(validateCmd, []string{src}) })
+	if err == nil {
+		t.Error("expected --strict-uris to fail")
+	}
+	if !strings.Contains(out, `"+12025551234" does not start with tel:`) {
+		t.Errorf("missing URI error in %q", out)
+	}
+}
+
+func TestValidateSchemaProfile(t *testing.T) {
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "profile.json")
+	src := filepath.Join(dir, "call.json")
+	schema := `{"allOf": [{"$ref": "` + vcon.EmbeddedSchemaID + `"}, {"required": ["subject"]}]}`
+	if err := os.WriteFile(profile, []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	body := `{"uuid":"018f0000-0000-8000-8000-000000000000","created_at":"2024-01-01T00:00:00Z","parties":[{"name":"Alice"}]}`
+	if err := os.WriteFile(src, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setFlags(t, map[string]string{"schema": profile}, validateCmd.Flags().Set)
+	t.Cleanup(func() { validateCmd.Flags().Set("schema", "") })
+	var err error
+	out := captureStdout(t, func() { err = runValidate(validateCmd, []string{src}) })
+	if err == nil {
+		t.Error("expected the profile to reject a vCon without a subject")
+	}
+	if !strings.Contains(out, "subject") {
+		t.Errorf("missing schema violation in %q", out)
+	}
+
+	validateCmd.Flags().Set("schema", filepath.Join(dir, "missing.json"))
+	if err := runValidate(validateCmd, []string{src}); err == nil {
+		t.Error("expected an error for a missing schema")
+	}
+}
+
+func TestValidateCheckDurations(t *testing.T) {
+	stubAudioGlobals(t) // ffprobe reports 12.5s
+	src := filepath.Join(t.TempDir(), "call.json")
+	body := `{"uuid":"018f0000-0000-8000-8000-000000000000","created_at":"2024-01-01T00:00:00Z","parties":[{"name":"Alice"}],` +
+		`"dialog":[{"type":"recording","start":"2024-01-01T00:00:00Z","parties":[0],"duration":20,"mediatype":"audio/wav","body":"UklGRg","encoding":"base64url"}]}`
+	if err := os.WriteFile(src, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	captureStdout(t, func() { err = runValidate(validateCmd, []string{src}) })
+	if err != nil {
+		t.Errorf("durations should only be checked on request: %v", err)
+	}
+
+	setFlags(t, map[string]string{"check-durations": "true"}, validateCmd.Flags().Set)
+	t.Cleanup(func() {
+		validateCmd.Flags().Set("check-durations", "false")
+		validateCmd.Flags().Set("duration-tolerance", "1")
+	})
+	out := captureStdout(t, func() { err = runValidate(validateCmd, []string{src}) })
+	if err == nil || !strings.Contains(out, "duration 20.00s does not match the media (12.50s)") {
+		t.Errorf("expected a duration mismatch, got %v: %q", err, out)
+	}
+
+	validateCmd.Flags().Set("duration-tolerance", "10")
+	captureStdout(t, func() { err = runValidate(validateCmd, []string{src}) })
+	if err != nil {
+		t.Errorf("expected the mismatch to be within tolerance: %v", err)
+	}
+}
+
+func TestSizeLimits(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "big.json")
+	body := strings.Repeat("x", 300)
+	doc := `{"uuid":"018f0000-0000-8000-8000-000000000000","created_at":"2024-01-01T00:00:00Z","parties":[{"name":"Alice"}],` +
+		`"dialog":[{"type":"text","start":"2024-01-01T00:00:00Z","parties":[0],"body":"` + body + `","encoding":"none"}]}`
+	if err := os.WriteFile(src, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sizeLimits = vcon.Limits{MaxInlineBodyBytes: 100}
+	t.Cleanup(func() { sizeLimits = vcon.Limits{} })
+
+	var err error
+	out := captureStdout(t, func() { err = runValidate(validateCmd, []string{src}) })
+	if err == nil {
+		t.Error("expected validate to fail on an oversized body")
+	}
+	if !strings.Contains(out, "dialog[0] inline body is 300 bytes") {
+		t.Errorf("missing actionable message in %q", out)
+	}
+
+	v := vcon.New("example.com")
+	v.Dialog = []vcon.Dialog{{Type: "text", Body: body}}
+	if err := writeVconFile(v, filepath.Join(dir, "out.json"), src); err == nil || !strings.Contains(err.Error(), "externally") {
+		t.Errorf("expected conversion to enforce limits, got %v", err)
+	}
+}
+
+func TestDebugCanonical(t *testing.T) {
+	dir := t.TempDir()
+	v := vcon.New("test.example.com")
+	v.AddParty(vcon.Party{Name: "Alice"})
+
+	privateKey, certs, err := generateSelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := v.Sign(privateKey, certs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedData, _ := json.Marshal(signed.JSON)
+	signedFile := filepath.Join(dir, "signed.json")
+	if err := os.WriteFile(signedFile, signedData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t, func() {
+		if err := runDebugCanonical(debugCanonicalCmd, []string{signedFile}); err != nil {
+			t.Errorf("signed payload should be canonical: %v", err)
+		}
+	})
+	if !strings.Contains(out, "is RFC 8785 canonical") {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	var m map[string]interface{}
+	json.Unmarshal([]byte(v.ToJSON()), &m)
+	m["subject"] = ""
+	plain, _ := json.MarshalIndent(m, "", "  ")
+	plainFile := filepath.Join(dir, "plain.json")
+	if err := os.WriteFile(plainFile, plain, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out = captureStdout(t, func() { err = runDebugCanonical(debugCanonicalCmd, []string{plainFile}) })
+	if err == nil {
+		t.Error("expected non-canonical payload to fail")
+	}
+	for _, want := range []string{"First difference at byte 1", "/subject: dropped"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+}
+
+func TestVerifyOptions(t *testing.T) {
+	newCmd := func(flags map[string]string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().StringSlice("alg", nil, "")
+		cmd.Flags().StringSlice("require-header", nil, "")
+		cmd.Flags().Bool("skip-uuid-check", false, "")
+		for k, v := range flags {
+			if err := cmd.Flags().Set(k, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cmd
+	}
+
+	opts, err := verifyOptions(newCmd(nil))
+	if err != nil || len(opts) != 0 {
+		t.Errorf("no flags: %d options, %v", len(opts), err)
+	}
+	opts, err = verifyOptions(newCmd(map[string]string{"alg": "rs256,es256,eddsa", "require-header": "kid", "skip-uuid-check": "true"}))
+	if err != nil || len(opts) != 3 {
+		t.Errorf("all flags: %d options, %v", len(opts), err)
+	}
+	if _, err := verifyOptions(newCmd(map[string]string{"alg": "HS256"})); err == nil {
+		t.Error("expected HS256 to be rejected")
+	}
+}
+
+func TestSealCommand(t *testing.T) {
+	dir := t.TempDir()
+	signerDir, recipientDir := t.TempDir(), t.TempDir()
+	keyPath, certPath := writeTestKeyPair(t, signerDir)
+	recipientKey, recipientCert := writeTestKeyPair(t, recipientDir)
+
+	v := vcon.New("test.example.com")
+	v.AddParty(vcon.Party{Name: "Alice"})
+	src := filepath.Join(dir, "call.json")
+	if err := os.WriteFile(src, []byte(v.ToJSON()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runSeal(sealCmd, []string{src}); err == nil {
+		t.Error("expected error without --key, --cert and --recipient")
+	}
+
+	setFlags(t, map[string]string{"key": keyPath, "cert": certPath}, sealCmd.Flags().Set)
+	if err := sealCmd.Flags().Set("recipient", recipientCert); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sealCmd.Flags().Lookup("recipient").Value.(pflag.SliceValue).Replace(nil) })
+
+	captureStdout(t, func() {
+		if err := runSeal(sealCmd, []string{src}); err != nil {
+			t.Fatalf("seal: %v", err)
+		}
+	})
+
+	raw, err := os.ReadFile(filepath.Join(dir, "call.encrypted.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sealed vcon.EncryptedVCon
+	if err := json.Unmarshal(raw, &sealed); err != nil {
+		t.Fatal(err)
+	}
+	plain, err := sealed.Decrypt(readDecryptionKey(recipientKey))
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(readCertificate(certPath))
+	got, err := (&vcon.SignedVCon{JSON: plain}).Verify(roots)
+	if err != nil {
+		t.Fatalf("sealed vCon does not wrap a valid signature: %v", err)
+	}
+	if got.UUID != v.UUID {
+		t.Errorf("UUID = %s, want %s", got.UUID, v.UUID)
+	}
+}
+
+func TestSignECDSAKey(t *testing.T) {
+	dir := t.TempDir()
+	keyPath, certPath := filepath.Join(dir, "ec_key.pem"), filepath.Join(dir, "ec_cert.pem")
+	captureStdout(t, func() { generateKeyPair(keyPath, certPath, "p-256") })
+
+	priv, cert := readPrivateKey(keyPath), readCertificate(certPath)
+	if got := describeKey(priv); got != "ECDSA P-256" {
+		t.Errorf("describeKey = %q", got)
+	}
+	if !keyMatchesCert(priv, cert) {
+		t.Error("generated certificate does not certify the key")
+	}
+
+	// SEC 1 keys, as openssl ecparam writes them, parse too.
+	der, err := x509.MarshalECPrivateKey(priv.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err != nil {
+		t.Errorf("SEC 1 key: %v", err)
+	}
+
+	v := vcon.New("test.example.com")
+	v.AddParty(vcon.Party{Name: "Alice"})
+	src := filepath.Join(dir, "call.json")
+	if err := os.WriteFile(src, []byte(v.ToJSON()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "call.signed.json")
+	captureStdout(t, func() { signFile(src, priv, cert, out, false) })
+
+	signed := vcon.SignedVCon{JSON: readBareJWS(out)}
+	headers, err := signed.Headers()
+	if err != nil || headers[0].Algorithm != string(jose.ES256) {
+		t.Fatalf("expected an ES256 signature: %v %v", headers, err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	if _, err := signed.Verify(roots, vcon.WithSignatureAlgorithms(jose.ES256)); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+}
+
+func TestDecryptWithKeyRing(t *testing.T) {
+	dir := t.TempDir()
+	ringDir := t.TempDir()
+	oldKey, oldCerts, err := generateSelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, _, err := generateSelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, k := range map[string]*rsa.PrivateKey{"2023": oldKey, "2024": newKey} {
+		keyPath := writeKeyPEM(t, t.TempDir(), k)
+		data, _ := os.ReadFile(keyPath)
+		if err := os.WriteFile(filepath.Join(ringDir, name+".pem"), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeCertPEM(t, ringDir, oldCerts[0]) // certificates in the keyring are skipped
+
+	v := vcon.New("test.example.com")
+	v.AddParty(vcon.Party{Name: "Alice"})
+	sealed, err := v.SignAndEncrypt(oldKey, oldCerts, []jose.Recipient{{Algorithm: jose.RSA_OAEP, Key: &oldKey.PublicKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "archive.json")
+	if err := writeJSON(src, sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := readKeyRingDir(ringDir)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("readKeyRingDir: %d keys, %v", len(keys), err)
+	}
+	out := filepath.Join(dir, "keyring.json")
+	captureStdout(t, func() { decryptFile(src, keys, out) })
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("keyring decrypt wrote no output: %v", err)
+	}
+
+	jwks, _ := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: newKey, KeyID: "2024", Algorithm: string(jose.RSA_OAEP)},
+		{Key: oldKey, KeyID: "2023", Algorithm: string(jose.RSA_OAEP)},
+	}})
+	jwksPath := filepath.Join(dir, "keys.jwks")
+	if err := os.WriteFile(jwksPath, jwks, 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err = readJWKS(jwksPath)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("readJWKS: %d keys, %v", len(keys), err)
+	}
+	out = filepath.Join(dir, "jwks.json")
+	captureStdout(t, func() { decryptFile(src, keys, out) })
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("JWKS decrypt wrote no output: %v", err)
+	}
+}
+
+func TestReviewSet(t *testing.T) {
+	dir := t.TempDir()
+	v := vcon.New("test.example.com")
+	v.AddParty(vcon.Party{Name: "Alice"})
+	v.AddAnalysis(vcon.Analysis{Type: "summary", Vendor: "acme", Body: "ok", Encoding: "none"})
+	file := filepath.Join(dir, "call.json")
+	if err := v.SaveToFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	newCmd := func(flags map[string]string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().Int("analysis", 0, "")
+		cmd.Flags().String("status", "", "")
+		cmd.Flags().String("reviewer", "", "")
+		cmd.Flags().String("note", "", "")
+		cmd.Flags().Float64("confidence", 0, "")
+		cmd.Flags().StringP("output", "o", "", "")
+		cmd.Flags().Bool("dry-run", false, "")
+		for k, val := range flags {
+			if err := cmd.Flags().Set(k, val); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cmd
+	}
+
+	captureStdout(t, func() {
+		err := runReviewSet(newCmd(map[string]string{"status": "human-reviewed", "reviewer": "qa", "confidence": "0.9"}), []string{file})
+		if err != nil {
+			t.Fatalf("review set: %v", err)
+		}
+	})
+	updated, err := vcon.LoadFromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := updated.Analysis[0].Review()
+	if err != nil || r == nil || r.Status != vcon.ReviewHumanReviewed || r.Reviewer != "qa" {
+		t.Errorf("review = %+v, %v", r, err)
+	}
+	if score, ok := updated.Analysis[0].Confidence(); !ok || score != 0.9 {
+		t.Errorf("confidence = %v, %v", score, ok)
+	}
+
+	before, _ := os.ReadFile(file)
+	out := captureStdout(t, func() {
+		err := runReviewSet(newCmd(map[string]string{"status": "disputed", "note": "wrong", "dry-run": "true"}), []string{file})
+		if err != nil {
+			t.Fatalf("review set --dry-run: %v", err)
+		}
+	})
+	if !strings.Contains(out, "would overwrite "+file) || !strings.Contains(out, `~ /analysis/0/meta/review/status: "human-reviewed" -> "disputed"`) ||
+		!strings.Contains(out, "result is valid") {
+		t.Errorf("unexpected dry-run output %q", out)
+	}
+	if after, _ := os.ReadFile(file); !bytes.Equal(before, after) {
+		t.Error("dry run rewrote the vCon")
+	}
+
+	if err := runReviewSet(newCmd(map[string]string{"status": "approved"}), []string{file}); err == nil {
+		t.Error("expected invalid status to be rejected")
+	}
+	if err := runReviewSet(newCmd(map[string]string{"status": "disputed", "analysis": "3"}), []string{file}); err == nil {
+		t.Error("expected out-of-range analysis index to be rejected")
+	}
+}
+
+func TestExportAnonymized(t *testing.T) {
+	dir := t.TempDir()
+	v := vcon.New("test.example.com")
+	v.AddParty(vcon.Party{Name: "Alice", Tel: "tel:+12025551234"})
+	v.AddParty(vcon.Party{Name: "Bob", Mailto: "mailto:bob@example.com"})
+	now := time.Now().UTC()
+	v.AddDialog(vcon.Dialog{Type: "text", StartTime: &now, Parties: vcon.NewPartyRefs(0, 1), MediaType: "text/plain",
+		Encoding: "none", Body: "Alice: my number is 202-555-1234"})
+	v.AddDialog(vcon.Dialog{Type: "recording", StartTime: &now, Parties: vcon.NewPartyRefs(0, 1), MediaType: "audio/wav",
+		Encoding: "base64url", Body: "UklGRg"})
+	file := filepath.Join(dir, "call.json")
+	if err := v.SaveToFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	newCmd := func(flags map[string]string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("profile", "", "")
+		cmd.Flags().String("pseudonym-key", "", "")
+		cmd.Flags().Float64("pitch-shift", 0, "")
+		cmd.Flags().StringP("output", "o", "", "")
+		for k, val := range flags {
+			if err := cmd.Flags().Set(k, val); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cmd
+	}
+
+	captureStdout(t, func() {
+		if err := runExport(newCmd(map[string]string{"profile": "anonymized"}), []string{file}); err != nil {
+			t.Fatalf("export: %v", err)
+		}
+	})
+	exported, err := vcon.LoadFromFile(filepath.Join(dir, "call.anonymized.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported.Parties[0].Name != "Party 1" || exported.Parties[0].Tel != "" {
+		t.Errorf("party not pseudonymized: %+v", exported.Parties[0])
+	}
+	if got := exported.Dialog[0].Body; got != "Party 1: my number is [PHONE]" {
+		t.Errorf("text body = %q", got)
+	}
+	if exported.Dialog[1].Body != "" {
+		t.Error("audio should be removed without --pitch-shift")
+	}
+	if exported.Redacted == nil || exported.Redacted.Type != "anonymized" {
+		t.Errorf("redacted = %+v", exported.Redacted)
+	}
+
+	orig := shiftPitch
+	t.Cleanup(func() { shiftPitch = orig })
+	var gotShift float64
+	shiftPitch = func(media []byte, semitones float64) ([]byte, string, error) {
+		gotShift = semitones
+		return []byte("shifted"), "audio/wav", nil
+	}
+	out := filepath.Join(dir, "shifted.json")
+	captureStdout(t, func() {
+		if err := runExport(newCmd(map[string]string{"profile": "anonymized", "pitch-shift": "-3", "output": out}), []string{file}); err != nil {
+			t.Fatalf("export: %v", err)
+		}
+	})
+	shifted, err := vcon.LoadFromFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotShift != -3 || shifted.Dialog[1].Body == "" {
+		t.Errorf("audio not pitch-shifted: shift %v, dialog %+v", gotShift, shifted.Dialog[1])
+	}
+
+	if err := runExport(newCmd(map[string]string{"profile": "public"}), []string{file}); err == nil {
+		t.Error("expected unknown profile to be rejected")
+	}
+	if err := runExport(newCmd(map[string]string{"profile": "anonymized", "pitch-shift": "30"}), []string{file}); err == nil {
+		t.Error("expected out-of-range pitch shift to be rejected")
+	}
+}
+
+func TestSlice(t *testing.T) {
+	dir := t.TempDir()
+	v := vcon.New("test.example.com")
+	v.AddParty(vcon.Party{Name: "Alice", Tel: "tel:+12025551234"})
+	v.AddParty(vcon.Party{Name: "Bob", Mailto: "mailto:bob@example.com"})
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	late := start.Add(20 * time.Minute)
+	v.AddDialog(vcon.Dialog{Type: "recording", StartTime: &start, Duration: 1800, Parties: vcon.NewPartyRefs(0, 1),
+		MediaType: "audio/wav", Encoding: "base64url", Body: "UklGRg"})
+	v.AddDialog(vcon.Dialog{Type: "text", StartTime: &late, Parties: vcon.NewPartyRefs(0, 1), MediaType: "text/plain",
+		Encoding: "none", Body: "see you"})
+	file := filepath.Join(dir, "call.json")
+	if err := v.SaveToFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	newCmd := func(flags map[string]string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("from", "", "")
+		cmd.Flags().String("to", "", "")
+		cmd.Flags().Bool("trim-media", false, "")
+		cmd.Flags().StringP("output", "o", "", "")
+		cmd.Flags().Bool("dry-run", false, "")
+		setFlags(t, flags, cmd.Flags().Set)
+		return cmd
+	}
+
+	captureStdout(t, func() {
+		if err := runSlice(newCmd(map[string]string{"from": "5m", "to": "10m"}), []string{file}); err != nil {
+			t.Fatalf("slice: %v", err)
+		}
+	})
+	excerpt, err := vcon.LoadFromFile(filepath.Join(dir, "call.slice.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(excerpt.Dialog) != 1 || excerpt.Dialog[0].Duration != 1800 {
+		t.Errorf("expected the whole recording alone, got %+v", excerpt.Dialog)
+	}
+	if excerpt.Redacted == nil || excerpt.Redacted.UUID != v.UUID || excerpt.Redacted.Type != "slice" {
+		t.Errorf("redacted = %+v", excerpt.Redacted)
+	}
+
+	orig := trimMediaWindow
+	t.Cleanup(func() { trimMediaWindow = orig })
+	var gotOffset, gotLength time.Duration
+	trimMediaWindow = func(media []byte, mediaType string, offset, length time.Duration) ([]byte, string, error) {
+		gotOffset, gotLength = offset, length
+		return []byte("trimmed"), mediaType, nil
+	}
+	out := filepath.Join(dir, "trimmed.json")
+	captureStdout(t, func() {
+		flags := map[string]string{"from": "2024-01-01T10:15:00Z", "to": "25m", "trim-media": "true", "output": out}
+		if err := runSlice(newCmd(flags), []string{file}); err != nil {
+			t.Fatalf("slice --trim-media: %v", err)
+		}
+	})
+	trimmed, err := vcon.LoadFromFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotOffset != 15*time.Minute || gotLength != 10*time.Minute {
+		t.Errorf("trimmed at %v for %v", gotOffset, gotLength)
+	}
+	if len(trimmed.Dialog) != 2 || trimmed.Dialog[0].Duration != 600 || !trimmed.Dialog[0].StartTime.Equal(start.Add(15*time.Minute)) {
+		t.Errorf("unexpected dialogs %+v", trimmed.Dialog)
+	}
+
+	if err := runSlice(newCmd(map[string]string{"from": "10m", "to": "5m"}), []string{file}); err == nil {
+		t.Error("expected an empty window to be rejected")
+	}
+	if err := runSlice(newCmd(map[string]string{"from": "soon", "to": "5m"}), []string{file}); err == nil {
+		t.Error("expected an unparsable time to be rejected")
+	}
+}
+
+func TestDocsGeneration(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	manDir := filepath.Join(t.TempDir(), "man")
+	mdDir := filepath.Join(t.TempDir(), "md")
+	captureStdout(t, func() {
+		if err := runDocsMan(docsManCmd, []string{manDir}); err != nil {
+			t.Fatalf("docs man: %v", err)
+		}
+		if err := runDocsMarkdown(docsMarkdownCmd, []string{mdDir}); err != nil {
+			t.Fatalf("docs markdown: %v", err)
+		}
+	})
+
+	page, err := os.ReadFile(filepath.Join(manDir, "vconctl-review-set.1"))
+	if err != nil {
+		t.Fatalf("missing man page: %v", err)
+	}
+	if !strings.Contains(string(page), "Nov 2023") || !strings.Contains(string(page), "--status") {
+		t.Errorf("unexpected man page:\n%s", page)
+	}
+	md, err := os.ReadFile(filepath.Join(mdDir, "vconctl_export.md"))
+	if err != nil {
+		t.Fatalf("missing markdown page: %v", err)
+	}
+	if strings.Contains(string(md), "Auto generated") {
+		t.Error("markdown should not carry a dated footer")
+	}
+}
+
+func TestCompletionCommand(t *testing.T) {
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetArgs([]string{"completion", "bash"})
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+	})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("completion: %v", err)
+	}
+	if !strings.Contains(buf.String(), "__start_vconctl") {
+		t.Errorf("unexpected completion script: %.200s", buf.String())
+	}
+}
+
+func TestMigrateCommand(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "legacy.json")
+	doc := `{"vcon":"0.0.2","uuid":"018f0000-0000-8000-8000-000000000000","created_at":"2024-01-01T00:00:00Z",
+		"parties":[{"name":"Alice"}],"dialog":[{"type":"text","start":"2024-01-01T00:00:00Z","parties":[0],"body":"hi","encoding":"none","mimetype":"text/plain"}]}`
+	if err := os.WriteFile(legacy, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	current := filepath.Join(dir, "current.json")
+	if err := vcon.New("test.example.com").SaveToFile(current); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(current)
+
+	newCmd := func(flags map[string]string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("to", "", "")
+		cmd.Flags().StringP("output", "o", "", "")
+		cmd.Flags().Bool("dry-run", false, "")
+		for k, val := range flags {
+			if err := cmd.Flags().Set(k, val); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cmd
+	}
+
+	out := captureStdout(t, func() {
+		if err := runMigrate(newCmd(map[string]string{"dry-run": "true"}), []string{legacy}); err != nil {
+			t.Fatalf("migrate --dry-run: %v", err)
+		}
+	})
+	if !strings.Contains(out, "would overwrite "+legacy) || !strings.Contains(out, "+ /dialog/0/mediatype") ||
+		!strings.Contains(out, "- /dialog/0/mimetype") || !strings.Contains(out, "result is valid") {
+		t.Errorf("unexpected dry-run output %q", out)
+	}
+	if data, _ := os.ReadFile(legacy); string(data) != doc {
+		t.Error("dry run rewrote the vCon")
+	}
+
+	out = captureStdout(t, func() {
+		if err := runMigrate(newCmd(nil), []string{legacy, current}); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+	})
+	if !strings.Contains(out, "Migrated "+legacy) || !strings.Contains(out, current+" is already at") {
+		t.Errorf("unexpected output %q", out)
+	}
+	v, err := vcon.LoadFromFile(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Vcon != vcon.SpecVersion || v.Dialog[0].MediaType != "text/plain" {
+		t.Errorf("not migrated: %s %+v", v.Vcon, v.Dialog[0])
+	}
+	if after, _ := os.ReadFile(current); !bytes.Equal(before, after) {
+		t.Error("current vCon was rewritten")
+	}
+
+	if err := runMigrate(newCmd(map[string]string{"output": "x.json"}), []string{legacy, current}); err == nil {
+		t.Error("expected --output with several files to be rejected")
+	}
+	if err := runMigrate(newCmd(map[string]string{"to": "0.0.1"}), []string{legacy}); err == nil {
+		t.Error("expected a downgrade to be rejected")
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+// Command: conformance
+
+var conformanceCmd = &cobra.Command{
+	Use:   "conformance <file|dir> [file|dir ...]",
+	Short: "Run a corpus of example vCons through parse, validate and canonicalization",
+	Long: `conformance runs every *.json file found in the given files/directories
+(for example the IETF draft examples or the vcon-dev/fake-vcons corpus)
+through the library and prints a compatibility matrix. A file passes when it
+parses, validates and survives a canonical JSON round trip unchanged.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runConformance,
+}
+
+// Conformance check outcomes.
+const (
+	conformanceOK       = "ok"
+	conformanceFail     = "fail"
+	conformanceDiverges = "diverges"
+	conformanceMigrated = "migrated"
+	conformanceSkipped  = "skip"
+)
+
+// conformanceRow is one line of the compatibility matrix.
+type conformanceRow struct {
+	File      string `json:"file"`
+	Form      string `json:"form"`
+	Parse     string `json:"parse"`
+	Validate  string `json:"validate"`
+	Canonical string `json:"canonical"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+func (r conformanceRow) failed() bool {
+	return r.Parse == conformanceFail || r.Validate == conformanceFail || r.Canonical == conformanceDiverges
+}
+
+func runConformance(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+
+	files, err := collectJSONFiles(args)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]conformanceRow, 0, len(files))
+	for _, f := range files {
+		rows = append(rows, checkConformance(f))
+	}
+
+	switch format {
+	case "json":
+		out, _ := json.MarshalIndent(rows, "", "  ")
+		fmt.Println(string(out))
+	case "table", "":
+		printConformanceTable(rows)
+	default:
+		return fmt.Errorf("unknown format %q (want table or json)", format)
+	}
+
+	failed := 0
+	for _, r := range rows {
+		if r.failed() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) diverged from the library", failed, len(rows))
+	}
+	return nil
+}
+
+// collectJSONFiles expands directories into the *.json files they contain.
+func collectJSONFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, wErr error) error {
+			if wErr != nil {
+				return wErr
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".json") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func checkConformance(path string) conformanceRow {
+	row := conformanceRow{
+		File:      path,
+		Parse:     conformanceSkipped,
+		Validate:  conformanceSkipped,
+		Canonical: conformanceSkipped,
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		row.Parse, row.Detail = conformanceFail, err.Error()
+		return row
+	}
+
+	form, err := vcon.DetectForm(raw)
+	row.Form = form.String()
+	if err != nil {
+		row.Parse, row.Detail = conformanceFail, err.Error()
+		return row
+	}
+	if form != vcon.VConFormUnsigned {
+		row.Detail = "only unsigned vCons are checked"
+		return row
+	}
+
+	v, err := vcon.BuildFromJSON(string(raw))
+	if err != nil {
+		row.Parse, row.Detail = conformanceFail, err.Error()
+		return row
+	}
+	row.Parse = conformanceOK
+
+	if ok, issues := v.IsValid(); !ok {
+		var msgs []string
+		for _, issue := range issues {
+			if issue.Severity == vcon.SeverityError {
+				msgs = append(msgs, issue.Message)
+			}
+		}
+		row.Validate, row.Detail = conformanceFail, strings.Join(msgs, "; ")
+	} else {
+		row.Validate = conformanceOK
+	}
+
+	row.Canonical, row.Detail = checkCanonicalRoundTrip(raw, v, row.Detail)
+	return row
+}
+
+// checkCanonicalRoundTrip compares the canonical form of the input document
+// with the canonical form of the parsed VCon.
+func checkCanonicalRoundTrip(raw []byte, v *vcon.VCon, detail string) (string, string) {
+	var original map[string]any
+	if err := json.Unmarshal(raw, &original); err != nil {
+		return conformanceFail, err.Error()
+	}
+	if ver, _ := original["vcon"].(string); ver != "" && ver != vcon.SpecVersion {
+		return conformanceMigrated, joinDetail(detail, fmt.Sprintf("migrated from %s", ver))
+	}
+
+	want, err := vcon.Canonicalise(original)
+	if err != nil {
+		return conformanceFail, joinDetail(detail, err.Error())
+	}
+	got, err := vcon.Canonicalise(v)
+	if err != nil {
+		return conformanceFail, joinDetail(detail, err.Error())
+	}
+	if bytes.Equal(want, got) {
+		return conformanceOK, detail
+	}
+
+	var roundTrip map[string]any
+	_ = json.Unmarshal(got, &roundTrip)
+	return conformanceDiverges, joinDetail(detail, "first difference at "+firstDivergence(original, roundTrip, ""))
+}
+
+// firstDivergence returns the JSON pointer of the first value that differs
+// between two decoded JSON documents.
+func firstDivergence(a, b any, path string) string {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			return pathOrRoot(path)
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, seen := av[k]; !seen {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !reflect.DeepEqual(av[k], bv[k]) {
+				return firstDivergence(av[k], bv[k], path+"/"+k)
+			}
+		}
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			return pathOrRoot(path)
+		}
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if !reflect.DeepEqual(av[i], bv[i]) {
+				return firstDivergence(av[i], bv[i], fmt.Sprintf("%s/%d", path, i))
+			}
+		}
+		if len(av) != len(bv) {
+			return fmt.Sprintf("%s/%d", path, min(len(av), len(bv)))
+		}
+	}
+	return pathOrRoot(path)
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func joinDetail(detail, extra string) string {
+	if detail == "" {
+		return extra
+	}
+	return detail + "; " + extra
+}
+
+func printConformanceTable(rows []conformanceRow) {
+	fmt.Printf("%-50s %-10s %-8s %-8s %-10s %s\n", "FILE", "FORM", "PARSE", "VALIDATE", "CANONICAL", "DETAIL")
+	for _, r := range rows {
+		fmt.Printf("%-50s %-10s %-8s %-8s %-10s %s\n", r.File, r.Form, r.Parse, r.Validate, r.Canonical,
+			strings.Join(strings.Fields(r.Detail), " "))
+	}
+}
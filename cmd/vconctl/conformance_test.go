@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+func TestCheckConformanceRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	v := vcon.New("test.example.com")
+	v.Subject = "Conformance"
+	v.AddParty(vcon.Party{Name: "Alice", Tel: "tel:+12025551234"})
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "good.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	row := checkConformance(path)
+	if row.Parse != conformanceOK || row.Validate != conformanceOK || row.Canonical != conformanceOK {
+		t.Errorf("expected all checks to pass, got %+v", row)
+	}
+	if row.failed() {
+		t.Error("row should not be marked failed")
+	}
+}
+
+func TestCheckConformanceDivergence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "diverges.json")
+	doc := `{"vcon":"0.4.0","uuid":"01982d6c-5a0b-815f-a902-4fdbe644b582","created_at":"2025-01-01T00:00:00Z",
+		"parties":[{"name":"Alice"}],"dialog":[{"type":"text","start":"2025-01-01T00:00:00Z","duration":0,"parties":[0]}]}`
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	row := checkConformance(path)
+	if row.Canonical != conformanceDiverges {
+		t.Fatalf("expected canonical divergence, got %+v", row)
+	}
+	if !strings.Contains(row.Detail, "/dialog/0/duration") {
+		t.Errorf("expected divergence path in detail, got %q", row.Detail)
+	}
+}
+
+func TestCheckConformanceParseFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(path, []byte(`{"uuid":"x","parties":[],"dialog":[{"type":"bogus"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	row := checkConformance(path)
+	if row.Parse != conformanceFail || !row.failed() {
+		t.Errorf("expected parse failure, got %+v", row)
+	}
+}
+
+func TestRunConformanceSampleCorpus(t *testing.T) {
+	out := captureStdout(t, func() {
+		// The sample corpus intentionally contains legacy and invalid files,
+		// so only the report itself is checked here.
+		_ = runConformance(conformanceCmd, []string{"../../testdata/sample_vcons"})
+	})
+	for _, name := range []string{"simple-vcon.json", "rec1.vcon.json", "CANONICAL"} {
+		if !strings.Contains(out, name) {
+			t.Errorf("expected %q in report, got %q", name, out)
+		}
+	}
+}
+
+func TestFirstDivergence(t *testing.T) {
+	a := map[string]any{"parties": []any{map[string]any{"name": "A"}}, "uuid": "x"}
+	b := map[string]any{"parties": []any{map[string]any{"name": "B"}}, "uuid": "x"}
+	if got := firstDivergence(a, b, ""); got != "/parties/0/name" {
+		t.Errorf("expected /parties/0/name, got %s", got)
+	}
+	if got := firstDivergence(a, a, ""); got != "/" {
+		t.Errorf("expected / for identical docs, got %s", got)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/robjsliwa/go-vcon/pkg/vcon/ext/cc"
+	"github.com/spf13/cobra"
+)
+
+// Telephony metadata set by the --campaign, --interaction-id and --skill
+// flags of every convert command.
+var convertDialogData cc.DialogData
+
+// Attachment scanning options of every convert command.
+var (
+	convertClamd         string // --clamd: clamd address, scanning is off when empty
+	convertOnThreat      string // --on-threat: reject or quarantine
+	convertQuarantineDir string // --quarantine-dir
+)
+
+// newScanner returns the scanner for --clamd. Tests replace it.
+var newScanner = func(addr string) vcon.Scanner {
+	return vcon.NewClamdScanner(addr)
+}
+
+// finishConversion applies the options shared by every convert command to
+// the vCons a converter built, just before they are written: the telephony
+// metadata flags, then --provenance. With --clamd the attachments are
+// scanned first.
+func finishConversion(cmd *cobra.Command, vcons []*vcon.VCon, sources ...provenanceSource) error {
+	if err := scanConverted(vcons); err != nil {
+		return err
+	}
+	for _, v := range vcons {
+		if err := applyDialogData(v, convertDialogData); err != nil {
+			return err
+		}
+	}
+	return attachBuildProvenance(cmd, vcons, sources...)
+}
+
+// applyDialogData stores the non-empty fields of data as Contact Center
+// extension parameters on every dialog of v that does not carry them
+// already, for instance from a generic-json mapping, and declares the CC
+// extension.
+func applyDialogData(v *vcon.VCon, data cc.DialogData) error {
+	if data == (cc.DialogData{}) || len(v.Dialog) == 0 {
+		return nil
+	}
+	for i := range v.Dialog {
+		d := &v.Dialog[i]
+		have, err := cc.DialogFields(d)
+		if err != nil {
+			return err
+		}
+		missing := cc.DialogData{}
+		if have.Campaign == "" {
+			missing.Campaign = data.Campaign
+		}
+		if have.InteractionType == "" {
+			missing.InteractionType = data.InteractionType
+		}
+		if have.InteractionID == "" {
+			missing.InteractionID = data.InteractionID
+		}
+		if have.Skill == "" {
+			missing.Skill = data.Skill
+		}
+		if err := cc.SetDialogFields(d, missing); err != nil {
+			return err
+		}
+	}
+	if !slices.Contains(v.Extensions, cc.Name) {
+		v.Extensions = append(v.Extensions, cc.Name)
+	}
+	return nil
+}
+
+// scanConverted passes the attachments of every vCon to the --clamd
+// scanner. A malicious attachment fails the conversion with
+// --on-threat=reject; with quarantine it is saved to --quarantine-dir and
+// removed from the vCon.
+func scanConverted(vcons []*vcon.VCon) error {
+	if convertClamd == "" {
+		return nil
+	}
+	switch convertOnThreat {
+	case "reject":
+	case "quarantine":
+		if convertQuarantineDir == "" {
+			return fmt.Errorf("--on-threat=quarantine requires --quarantine-dir")
+		}
+	default:
+		return fmt.Errorf("invalid --on-threat %q: must be reject or quarantine", convertOnThreat)
+	}
+
+	scanner := newScanner(convertClamd)
+	for _, v := range vcons {
+		threats, err := v.ScanAttachments(context.Background(), scanner)
+		if err != nil {
+			return fmt.Errorf("vCon %s: %w", v.UUID, err)
+		}
+		if len(threats) == 0 {
+			continue
+		}
+		if convertOnThreat == "reject" {
+			t := threats[0]
+			return fmt.Errorf("vCon %s: attachment %d %q: %s found", v.UUID, t.Index, v.Attachments[t.Index].Filename, t.Threat)
+		}
+		if err := quarantineAttachments(v, threats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quarantineAttachments saves the flagged attachments of v to
+// --quarantine-dir as <uuid>-<index>-<filename> and removes them from v.
+func quarantineAttachments(v *vcon.VCon, threats []vcon.AttachmentThreat) error {
+	if err := os.MkdirAll(convertQuarantineDir, 0o700); err != nil {
+		return fmt.Errorf("create quarantine directory: %w", err)
+	}
+	for i := len(threats) - 1; i >= 0; i-- {
+		t := threats[i]
+		content, err := v.AttachmentContent(t.Index)
+		if err != nil {
+			return fmt.Errorf("vCon %s: %w", v.UUID, err)
+		}
+		name := fmt.Sprintf("%s-%d", v.UUID, t.Index)
+		if filename := filepath.Base(v.Attachments[t.Index].Filename); filename != "." && filename != "/" {
+			name += "-" + filename
+		}
+		path := filepath.Join(convertQuarantineDir, name)
+		if err := writeOutputFile(path, content, 0o600); err != nil {
+			return fmt.Errorf("quarantine attachment %d: %w", t.Index, err)
+		}
+		v.Attachments = slices.Delete(v.Attachments, t.Index, t.Index+1)
+		fmt.Fprintf(os.Stderr, "⚠️  vCon %s: quarantined attachment %d (%s) to %s\n", v.UUID, t.Index, t.Threat, path)
+	}
+	return nil
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
 	"github.com/spf13/cobra"
 	"github.com/vansante/go-ffprobe"
 )
@@ -14,46 +16,202 @@ import (
 // Command: audio
 
 var audioCmd = &cobra.Command{
-	Use:   "audio --input <file|url> --party <spec> [--party <spec> ...] --date <RFC3339>",
+	Use:   "audio --input <file|url> [--input <file|url> ...] --party <spec> [--party <spec> ...] --date <RFC3339>",
 	Short: "Create a vCon from a standalone recording",
-	Args:  cobra.NoArgs,
-	RunE:  runAudio,
+	Long: `Create a vCon from one or more recordings.
+
+With a single --input the recording becomes one dialog shared by all parties.
+With several --input flags each input is the recording of the party at the
+same position: each becomes its own recording dialog, or, when --mixed is
+given, a per-channel attachment of the mixed recording's dialog.`,
+	Args: cobra.NoArgs,
+	RunE: runAudio,
 }
 
-func runAudio(cmd *cobra.Command, _ []string) error {
-	path, cleanup, err := fetchIfRemote(audioInput)
-	if err != nil {
-		return err
-	}
-	defer cleanup()
+// mediaInfo is the subset of probe data used by the converters.
+type mediaInfo struct {
+	DurationSeconds float64
+	FormatName      string    // ffprobe format, e.g. "wav" or "mov,mp4,m4a,3gp,3g2,mj2"
+	CreationTime    time.Time // zero when the container has no creation_time tag
+}
 
+// probeMedia inspects a local media file with ffprobe, or with probeHeaders
+// when ffprobe is not installed. It is a variable so tests can stub it.
+var probeMedia = func(path string) (*mediaInfo, error) {
+	if !ffprobeAvailable() {
+		return probeHeaders(path)
+	}
 	info, err := ffprobe.GetProbeData(path, 10*time.Second)
 	if err != nil {
-		return fmt.Errorf("ffprobe: %w", err)
+		return nil, fmt.Errorf("ffprobe: %w", err)
+	}
+	mi := &mediaInfo{
+		DurationSeconds: info.Format.DurationSeconds,
+		FormatName:      info.Format.FormatName,
+	}
+	// Prefer the container tag; fall back to the first stream that has one.
+	if info.Format.Tags != nil {
+		mi.CreationTime, _ = parseMediaTime(info.Format.Tags.CreationTime)
+	}
+	for _, s := range info.Streams {
+		if !mi.CreationTime.IsZero() {
+			break
+		}
+		mi.CreationTime, _ = parseMediaTime(s.Tags.CreationTime)
+	}
+	return mi, nil
+}
+
+// audioSource is a probed recording referenced by the vCon.
+type audioSource struct {
+	Input     string // original --input value (path or URL)
+	Path      string // local path
+	Info      *mediaInfo
+	MediaType string
+}
+
+// ffprobeMediaTypes maps ffprobe format names to MIME types.
+var ffprobeMediaTypes = map[string]string{
+	"wav":  vcon.MIMETypeAudioWav2,
+	"mp3":  vcon.MIMETypeAudioMpeg,
+	"ogg":  vcon.MIMETypeAudioOgg,
+	"webm": vcon.MIMETypeAudioWebm,
+	"aac":  vcon.MIMETypeAudioAAC,
+	"flac": "audio/flac",
+	"mp4":  vcon.MIMETypeVideoMPG4,
+}
+
+// audioMediaType returns the MIME type of a recording from its ffprobe
+// format name. Formats covering several containers, such as
+// "mov,mp4,m4a,3gp,3g2,mj2", are told apart by the file extension.
+func audioMediaType(format, path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".m4a") {
+		return vcon.MIMETypeAudioMP4
+	}
+	for _, name := range strings.Split(format, ",") {
+		if t, ok := ffprobeMediaTypes[name]; ok {
+			return t
+		}
+	}
+	return mediatype.FromExtension(path)
+}
+
+func runAudio(cmd *cobra.Command, _ []string) error {
+	if len(audioInputs) == 0 {
+		return errors.New("at least one --input is required")
+	}
+	if len(audioInputs) > 1 && len(audioInputs) > len(audioParties) {
+		return fmt.Errorf("%d inputs given but only %d parties: each input must map to a party", len(audioInputs), len(audioParties))
+	}
+
+	var cleanups []func()
+	defer func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}()
+	probe := func(input string) (*audioSource, error) {
+		path, cleanup, err := fetchIfRemote(input)
+		if err != nil {
+			return nil, err
+		}
+		cleanups = append(cleanups, cleanup)
+		info, err := probeMedia(path)
+		if err != nil {
+			return nil, err
+		}
+		return &audioSource{Input: input, Path: path, Info: info, MediaType: audioMediaType(info.FormatName, path)}, nil
+	}
+
+	sources := make([]*audioSource, len(audioInputs))
+	for i, input := range audioInputs {
+		src, err := probe(input)
+		if err != nil {
+			return err
+		}
+		sources[i] = src
+	}
+	var mixed *audioSource
+	if audioMixed != "" {
+		src, err := probe(audioMixed)
+		if err != nil {
+			return err
+		}
+		mixed = src
+	}
+
+	primary := sources[0]
+	if mixed != nil {
+		primary = mixed
 	}
 
 	v := vcon.New(globalDomain)
-	v.Subject = filepath.Base(path)
-	v.CreatedAt = getDate(audioDate, path)
+	v.Subject = filepath.Base(primary.Path)
+	v.CreatedAt = recordingStart(audioDate, primary.Path, primary.Info)
 
 	var dialogParties []int
+	roles := map[int]string{}
 
 	for _, spec := range audioParties {
-		p := parseParty(spec)
+		p, role := parsePartyWithRole(spec)
 		v.Parties = append(v.Parties, *p)
 		dialogParties = append(dialogParties, len(v.Parties)-1)
+		if role != "" {
+			roles[len(v.Parties)-1] = role
+		}
 	}
 
-	dur := time.Duration(float64(time.Second) * info.Format.DurationSeconds)
-	v.Dialog = append(v.Dialog, vcon.Dialog{
+	switch {
+	case mixed != nil:
+		// One mixed dialog with every party; the per-party inputs are kept as
+		// channel attachments linked to their party.
+		v.AddDialog(recordingDialog(mixed, &v.CreatedAt, vcon.NewPartyRefs(dialogParties...), roles))
+		for i, src := range sources {
+			v.AddAttachment(vcon.Attachment{
+				URL:       src.Input,
+				Filename:  filepath.Base(src.Path),
+				MediaType: src.MediaType,
+				DialogIdx: vcon.IntPtr(0),
+				PartyIdx:  i,
+				StartTime: v.CreatedAt,
+				Purpose:   "channel",
+			})
+		}
+	case len(sources) == 1:
+		v.AddDialog(recordingDialog(sources[0], &v.CreatedAt, vcon.NewPartyRefs(dialogParties...), roles))
+	default:
+		// Separate recordings: one dialog per party.
+		for i, src := range sources {
+			v.AddDialog(recordingDialog(src, &v.CreatedAt, vcon.NewPartyRef(i), nil))
+		}
+	}
+
+	var inputs []provenanceSource
+	for _, src := range sources {
+		inputs = append(inputs, provenanceSource{uri: src.Input, path: src.Path})
+	}
+	if mixed != nil {
+		inputs = append(inputs, provenanceSource{uri: mixed.Input, path: mixed.Path})
+	}
+	if err := finishConversion(cmd, []*vcon.VCon{v}, inputs...); err != nil {
+		return err
+	}
+	return writeVconFile(v, vConOut, primary.Path)
+}
+
+func recordingDialog(src *audioSource, start *time.Time, parties *vcon.PartyRefs, roles map[int]string) vcon.Dialog {
+	dur := time.Duration(float64(time.Second) * src.Info.DurationSeconds)
+	dialog := vcon.Dialog{
 		Type:      "recording",
-		StartTime: &v.CreatedAt,
+		StartTime: start,
 		Duration:  dur.Seconds(),
-		Parties:   dialogParties,
-		Filename:  filepath.Base(path),
-		MediaType: strings.ReplaceAll(info.Format.FormatName, ",", "/"),
-		URL:       audioInput,
-	})
-
-	return writeVconFile(v, vConOut, path)
+		Parties:   parties,
+		Filename:  filepath.Base(src.Path),
+		MediaType: src.MediaType,
+		URL:       src.Input,
+	}
+	// Take the originator from a party role such as "caller" rather than
+	// assuming it is the first party.
+	dialog.DeriveOriginator(roles)
+	return dialog
 }
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
 	"github.com/spf13/cobra"
 )
 
@@ -23,14 +26,14 @@ func TestRunAudio(t *testing.T) {
 
 	// Reset global variables for testing
 	originalGlobalDomain := globalDomain
-	originalAudioInput := audioInput
+	originalAudioInputs := audioInputs
 	originalAudioParties := audioParties
 	originalAudioDate := audioDate
 	originalVConOut := vConOut
 
 	defer func() {
 		globalDomain = originalGlobalDomain
-		audioInput = originalAudioInput
+		audioInputs = originalAudioInputs
 		audioParties = originalAudioParties
 		audioDate = originalAudioDate
 		vConOut = originalVConOut
@@ -64,7 +67,7 @@ func TestRunAudio(t *testing.T) {
 			name: "valid audio conversion with parties",
 			setupFunc: func() {
 				globalDomain = "test.example.com"
-				audioInput = absTestAudioPath
+				audioInputs = []string{absTestAudioPath}
 				audioParties = []string{"Alice,tel:+15551234567", "Bob,mailto:bob@example.com"}
 				audioDate = "2023-01-15T10:30:00Z"
 				vConOut = filepath.Join(tmpDir, "test_output.vcon.json")
@@ -75,7 +78,7 @@ func TestRunAudio(t *testing.T) {
 			name: "valid audio conversion without explicit date",
 			setupFunc: func() {
 				globalDomain = "test.example.com"
-				audioInput = absTestAudioPath
+				audioInputs = []string{absTestAudioPath}
 				audioParties = []string{"Alice"}
 				audioDate = ""
 				vConOut = filepath.Join(tmpDir, "test_output2.vcon.json")
@@ -86,7 +89,7 @@ func TestRunAudio(t *testing.T) {
 			name: "invalid audio file",
 			setupFunc: func() {
 				globalDomain = "test.example.com"
-				audioInput = "/nonexistent/file.wav"
+				audioInputs = []string{"/nonexistent/file.wav"}
 				audioParties = []string{"Alice"}
 				audioDate = ""
 				vConOut = filepath.Join(tmpDir, "test_output3.vcon.json")
@@ -146,14 +149,14 @@ func TestRunAudioIntegration(t *testing.T) {
 
 	// Save original values
 	originalGlobalDomain := globalDomain
-	originalAudioInput := audioInput
+	originalAudioInputs := audioInputs
 	originalAudioParties := audioParties
 	originalAudioDate := audioDate
 	originalVConOut := vConOut
 
 	defer func() {
 		globalDomain = originalGlobalDomain
-		audioInput = originalAudioInput
+		audioInputs = originalAudioInputs
 		audioParties = originalAudioParties
 		audioDate = originalAudioDate
 		vConOut = originalVConOut
@@ -161,7 +164,7 @@ func TestRunAudioIntegration(t *testing.T) {
 
 	// Set up test values
 	globalDomain = "test.example.com"
-	audioInput = absTestAudioPath
+	audioInputs = []string{absTestAudioPath}
 	audioParties = []string{"Test Speaker,tel:+15551234567"}
 	audioDate = "2023-01-15T10:30:00Z"
 	vConOut = filepath.Join(tmpDir, "integration_test.vcon.json")
@@ -241,3 +244,162 @@ func containsSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+// stubAudioGlobals stubs probeMedia and resets the audio command globals for
+// the duration of a test.
+func stubAudioGlobals(t *testing.T) {
+	t.Helper()
+	origProbe := probeMedia
+	origInputs, origMixed, origParties := audioInputs, audioMixed, audioParties
+	origDate, origOut := audioDate, vConOut
+	t.Cleanup(func() {
+		probeMedia = origProbe
+		audioInputs, audioMixed, audioParties = origInputs, origMixed, origParties
+		audioDate, vConOut = origDate, origOut
+	})
+	probeMedia = func(path string) (*mediaInfo, error) {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		return &mediaInfo{DurationSeconds: 12.5, FormatName: "wav"}, nil
+	}
+	audioMixed = ""
+	audioDate = "2024-03-01T09:00:00Z"
+}
+
+func writeFakeRecordings(t *testing.T, names ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(names))
+	for i, n := range names {
+		paths[i] = filepath.Join(dir, n)
+		if err := os.WriteFile(paths[i], []byte("RIFF"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func loadConvertedVCon(t *testing.T, path string) *vcon.VCon {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v vcon.VCon
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatalf("decode %s: %v", path, err)
+	}
+	return &v
+}
+
+func TestRunAudioPerPartyRecordings(t *testing.T) {
+	stubAudioGlobals(t)
+	paths := writeFakeRecordings(t, "agent.wav", "customer.wav")
+	audioInputs = paths
+	audioParties = []string{"Agent,tel:+15550000001", "Customer,tel:+15550000002,caller"}
+	vConOut = filepath.Join(t.TempDir(), "out.vcon.json")
+
+	if err := runAudio(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runAudio: %v", err)
+	}
+
+	v := loadConvertedVCon(t, vConOut)
+	if len(v.Dialog) != 2 {
+		t.Fatalf("expected one dialog per input, got %d", len(v.Dialog))
+	}
+	for i, d := range v.Dialog {
+		if idx, ok := d.Parties.AsInt(); !ok || idx != i {
+			t.Errorf("dialog %d should reference party %d, got %v", i, i, d.Parties)
+		}
+		if d.Filename != filepath.Base(paths[i]) {
+			t.Errorf("dialog %d filename = %s", i, d.Filename)
+		}
+	}
+	if len(v.Attachments) != 0 {
+		t.Errorf("expected no attachments, got %d", len(v.Attachments))
+	}
+}
+
+func TestRunAudioMixedWithChannelAttachments(t *testing.T) {
+	stubAudioGlobals(t)
+	paths := writeFakeRecordings(t, "agent.wav", "customer.wav", "mixed.wav")
+	audioInputs = paths[:2]
+	audioMixed = paths[2]
+	audioParties = []string{"Agent,tel:+15550000001", "Customer,tel:+15550000002,caller"}
+	vConOut = filepath.Join(t.TempDir(), "out.vcon.json")
+
+	if err := runAudio(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runAudio: %v", err)
+	}
+
+	v := loadConvertedVCon(t, vConOut)
+	if len(v.Dialog) != 1 || v.Dialog[0].Filename != "mixed.wav" {
+		t.Fatalf("expected a single mixed dialog, got %+v", v.Dialog)
+	}
+	if v.Dialog[0].MediaType != vcon.MIMETypeAudioWav2 {
+		t.Errorf("mediatype = %q, want %q", v.Dialog[0].MediaType, vcon.MIMETypeAudioWav2)
+	}
+	if o := v.Dialog[0].Originator; o == nil || *o != 1 {
+		t.Errorf("expected caller to be originator, got %v", o)
+	}
+	if len(v.Attachments) != 2 {
+		t.Fatalf("expected one channel attachment per input, got %d", len(v.Attachments))
+	}
+	for i, att := range v.Attachments {
+		if att.PartyIdx != i || att.DialogIdx == nil || *att.DialogIdx != 0 || att.Purpose != "channel" {
+			t.Errorf("attachment %d has wrong linkage: %+v", i, att)
+		}
+	}
+	if ok, errs := v.IsValid(); !ok {
+		t.Errorf("converted vCon is invalid: %v", errs)
+	}
+}
+
+func TestRunAudioRejectsUnmappedInputs(t *testing.T) {
+	stubAudioGlobals(t)
+	audioInputs = writeFakeRecordings(t, "a.wav", "b.wav")
+	audioParties = []string{"Only One"}
+
+	if err := runAudio(&cobra.Command{}, nil); err == nil {
+		t.Error("expected error when inputs outnumber parties")
+	}
+}
+
+func TestRunAudioProvenance(t *testing.T) {
+	stubAudioGlobals(t)
+	t.Cleanup(func() { convertProvenance = false })
+	convertProvenance = true
+	paths := writeFakeRecordings(t, "call.wav")
+	audioInputs = paths
+	audioParties = []string{"Agent,tel:+15550000001"}
+	vConOut = filepath.Join(t.TempDir(), "out.vcon.json")
+
+	cmd := &cobra.Command{Use: "audio"}
+	cmd.Flags().String("date", "", "")
+	cmd.Flags().String("output", "", "")
+	cmd.Flags().Set("date", audioDate)
+	cmd.Flags().Set("output", vConOut)
+	if err := runAudio(cmd, nil); err != nil {
+		t.Fatalf("runAudio: %v", err)
+	}
+
+	v := loadConvertedVCon(t, vConOut)
+	p, err := v.BuildProvenance()
+	if err != nil || p == nil {
+		t.Fatalf("BuildProvenance = %v, %v", p, err)
+	}
+	if p.Tool.Name != "vconctl" || p.Tool.Version == "" {
+		t.Errorf("tool = %+v", p.Tool)
+	}
+	if len(p.Sources) != 1 || p.Sources[0].URI != paths[0] || p.Sources[0].ContentHash.First() != vcon.ComputeSHA512([]byte("RIFF")) {
+		t.Errorf("sources = %+v", p.Sources)
+	}
+	want := []vcon.BuildStep{{Name: "audio", Parameters: map[string]string{"date": audioDate}}}
+	if !reflect.DeepEqual(p.Steps, want) {
+		t.Errorf("steps = %+v, want %+v", p.Steps, want)
+	}
+	if ok, issues := v.IsValid(); !ok {
+		t.Errorf("vCon with provenance is invalid: %v", issues)
+	}
+}
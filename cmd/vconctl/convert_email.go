@@ -1,28 +1,98 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/mail"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/jhillyerd/enmime"
 	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
 	"github.com/spf13/cobra"
 )
 
 // Command: email
 var emailCmd = &cobra.Command{
-	Use:   "email <file.eml>",
-	Short: "Convert a raw RFC-822 mail into vCon",
+	Use:   "email <file.eml> [file.eml ...]",
+	Short: "Convert raw RFC-822 mail into vCon",
+	Long: `Convert one or more RFC-822 messages into vCons.
+
+With --group-by message (the default) every message becomes its own vCon.
+With --group-by thread messages are grouped by their Message-ID, In-Reply-To
+and References headers and each thread becomes one vCon whose dialogs are in
+chronological order.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runEmail,
+}
+
+// Command: mbox
+var mboxCmd = &cobra.Command{
+	Use:   "mbox <file.mbox>",
+	Short: "Convert an mbox mailbox into vCons",
 	Args:  cobra.ExactArgs(1),
-	RunE:  runEmail,
+	RunE:  runMbox,
+}
+
+// Email grouping modes for --group-by.
+const (
+	emailGroupByMessage = "message"
+	emailGroupByThread  = "thread"
+)
+
+// emailThreadPurpose is the purpose of the attachment recording which dialog
+// of a thread replies to which.
+const emailThreadPurpose = "email_thread"
+
+// emailAttachmentPurpose is the purpose of the files attached to a message.
+const emailAttachmentPurpose = "email_attachment"
+
+// emailMessage is a parsed message together with its threading headers.
+type emailMessage struct {
+	Env        *enmime.Envelope
+	Date       time.Time
+	MessageID  string
+	InReplyTo  string
+	References []string
 }
 
-func runEmail(_ *cobra.Command, args []string) error {
+// emailReply is one entry of the email_thread attachment.
+type emailReply struct {
+	Dialog       int    `json:"dialog"`
+	MessageID    string `json:"message_id,omitempty"`
+	InReplyTo    string `json:"in_reply_to,omitempty"`
+	ParentDialog *int   `json:"parent_dialog,omitempty"`
+}
+
+func runEmail(cmd *cobra.Command, args []string) error {
+	var msgs []*emailMessage
+	for _, f := range args {
+		r, err := os.Open(f)
+		if err != nil {
+			return err
+		}
+		msg, err := parseEmail(r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return writeEmailVCons(cmd, msgs, args)
+}
+
+func runMbox(cmd *cobra.Command, args []string) error {
 	f := args[0]
 	r, err := os.Open(f)
 	if err != nil {
@@ -30,62 +100,258 @@ func runEmail(_ *cobra.Command, args []string) error {
 	}
 	defer r.Close()
 
-	env, err := enmime.ReadEnvelope(r)
+	raw, err := splitMbox(r)
 	if err != nil {
+		return fmt.Errorf("reading mbox: %w", err)
+	}
+	if len(raw) == 0 {
+		return fmt.Errorf("%s contains no messages", f)
+	}
+	var msgs []*emailMessage
+	for i, m := range raw {
+		msg, err := parseEmail(bytes.NewReader(m))
+		if err != nil {
+			return fmt.Errorf("message %d: %w", i+1, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return writeEmailVCons(cmd, msgs, args)
+}
+
+// writeEmailVCons groups msgs, read from files, according to --group-by and
+// writes the resulting vCons with writeVconFiles.
+func writeEmailVCons(cmd *cobra.Command, msgs []*emailMessage, files []string) error {
+	var groups [][]*emailMessage
+	switch emailGroupBy {
+	case emailGroupByMessage, "":
+		for _, m := range msgs {
+			groups = append(groups, []*emailMessage{m})
+		}
+	case emailGroupByThread:
+		groups = groupEmailThreads(msgs)
+	default:
+		return fmt.Errorf("unknown --group-by %q (want message or thread)", emailGroupBy)
+	}
+
+	vcons := make([]*vcon.VCon, 0, len(groups))
+	for _, g := range groups {
+		v, err := buildEmailVCon(g)
+		if err != nil {
+			return err
+		}
+		vcons = append(vcons, v)
+	}
+	var sources []provenanceSource
+	for _, f := range files {
+		sources = append(sources, provenanceSource{uri: f, path: f})
+	}
+	if err := finishConversion(cmd, vcons, sources...); err != nil {
 		return err
 	}
+	return writeVconFiles(vcons, vConOut, files[0])
+}
 
-	v := vcon.New(globalDomain)
-	v.Subject = env.GetHeader("Subject")
-	dateStr := env.GetHeader("Date")
-	created, err := mail.ParseDate(dateStr)
+func parseEmail(r io.Reader) (*emailMessage, error) {
+	env, err := enmime.ReadEnvelope(r)
+	if err != nil {
+		return nil, err
+	}
+	date, err := mail.ParseDate(env.GetHeader("Date"))
 	if err != nil {
-		return fmt.Errorf("parsing Date header: %w", err)
+		return nil, fmt.Errorf("parsing Date header: %w", err)
+	}
+	msg := &emailMessage{
+		Env:       env,
+		Date:      date,
+		MessageID: env.GetHeader("Message-Id"),
+		InReplyTo: firstMessageID(env.GetHeader("In-Reply-To")),
 	}
-	v.CreatedAt = created
+	msg.References = strings.Fields(env.GetHeader("References"))
+	return msg, nil
+}
 
-	var dialogParties []int
+func firstMessageID(s string) string {
+	if f := strings.Fields(s); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
 
-	parseAndAdd := func(header string) error {
-		addrsStr := env.GetHeader(header)
-		if addrsStr == "" && header == "Cc" {
-			return nil
-		}
-		addrs, err := mail.ParseAddressList(addrsStr)
-		if err != nil {
-			return fmt.Errorf("parsing %s header: %w", header, err)
+// groupEmailThreads partitions msgs into threads: two messages are in the
+// same thread when one references the other, directly or through a chain of
+// In-Reply-To/References headers. Threads are ordered by their first message.
+func groupEmailThreads(msgs []*emailMessage) [][]*emailMessage {
+	parent := map[string]string{}
+	var find func(id string) string
+	find = func(id string) string {
+		p, ok := parent[id]
+		if !ok || p == id {
+			parent[id] = id
+			return id
 		}
-		for _, a := range addrs {
-			v.Parties = append(v.Parties, vcon.Party{
-				Name:   a.Name,
-				Mailto: "mailto:" + a.Address,
-			})
-			dialogParties = append(dialogParties, len(v.Parties)-1)
+		root := find(p)
+		parent[id] = root
+		return root
+	}
+	union := func(a, b string) {
+		if ra, rb := find(a), find(b); ra != rb {
+			parent[ra] = rb
 		}
-		return nil
 	}
 
-	if err := parseAndAdd("From"); err != nil {
-		return err
+	keys := make([]string, len(msgs))
+	for i, m := range msgs {
+		keys[i] = m.MessageID
+		if keys[i] == "" {
+			// Messages without an ID can only start their own thread.
+			keys[i] = fmt.Sprintf("#%d", i)
+		}
+		find(keys[i])
+		for _, ref := range append(m.References, m.InReplyTo) {
+			if ref != "" {
+				union(keys[i], ref)
+			}
+		}
 	}
-	if err := parseAndAdd("To"); err != nil {
-		return err
+
+	byRoot := map[string][]*emailMessage{}
+	var roots []string
+	for i, m := range msgs {
+		r := find(keys[i])
+		if _, seen := byRoot[r]; !seen {
+			roots = append(roots, r)
+		}
+		byRoot[r] = append(byRoot[r], m)
 	}
-	if err := parseAndAdd("Cc"); err != nil {
-		return err
+	threads := make([][]*emailMessage, 0, len(roots))
+	for _, r := range roots {
+		t := byRoot[r]
+		sortEmails(t)
+		threads = append(threads, t)
 	}
-
-	v.Dialog = append(v.Dialog, vcon.Dialog{
-		Type:        "text",
-		Application: "email",
-		StartTime:   &v.CreatedAt,
-		Parties:     dialogParties,
-		Body:        env.Text,
-		MediaType:   "text/plain",
-		MessageID:   env.GetHeader("Message-Id"),
+	sort.SliceStable(threads, func(i, j int) bool {
+		return threads[i][0].Date.Before(threads[j][0].Date)
 	})
+	return threads
+}
+
+func sortEmails(msgs []*emailMessage) {
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].Date.Before(msgs[j].Date) })
+}
+
+// buildEmailVCon converts one or more messages into a single vCon with one
+// text dialog per message, in chronological order. Parties are shared across
+// messages by address. When there is more than one message the reply
+// relationships are recorded in an email_thread attachment.
+func buildEmailVCon(msgs []*emailMessage) (*vcon.VCon, error) {
+	msgs = append([]*emailMessage(nil), msgs...)
+	sortEmails(msgs)
+
+	v := vcon.New(globalDomain)
+	v.Subject = msgs[0].Env.GetHeader("Subject")
+	v.CreatedAt = msgs[0].Date
+
+	partyIdx := map[string]int{}
+	dialogIdx := map[string]int{}
+	var replies []emailReply
+
+	for _, m := range msgs {
+		var dialogParties []int
+		roles := map[int]string{}
+
+		addParties := func(header string) error {
+			addrsStr := m.Env.GetHeader(header)
+			if addrsStr == "" && header != "From" {
+				return nil
+			}
+			addrs, err := mail.ParseAddressList(addrsStr)
+			if err != nil {
+				return fmt.Errorf("parsing %s header: %w", header, err)
+			}
+			for _, a := range addrs {
+				key := strings.ToLower(a.Address)
+				idx, ok := partyIdx[key]
+				if !ok {
+					v.Parties = append(v.Parties, vcon.Party{
+						Name:   a.Name,
+						Mailto: "mailto:" + a.Address,
+					})
+					idx = len(v.Parties) - 1
+					partyIdx[key] = idx
+				}
+				if !slices.Contains(dialogParties, idx) {
+					dialogParties = append(dialogParties, idx)
+				}
+				if header == "From" {
+					roles[idx] = "from"
+				}
+			}
+			return nil
+		}
+		for _, h := range []string{"From", "To", "Cc"} {
+			if err := addParties(h); err != nil {
+				return nil, err
+			}
+		}
 
-	return writeVconFile(v, vConOut, f)
+		start := m.Date
+		dialog := vcon.Dialog{
+			Type:        "text",
+			Application: "email",
+			StartTime:   &start,
+			Parties:     vcon.NewPartyRefs(dialogParties...),
+			Body:        m.Env.Text,
+			MediaType:   "text/plain",
+			MessageID:   m.MessageID,
+		}
+		dialog.DeriveOriginator(roles)
+		v.Dialog = append(v.Dialog, dialog)
+
+		idx := len(v.Dialog) - 1
+		sender, _ := dialog.OriginatorIndex()
+		for _, part := range m.Env.Attachments {
+			mt := mediatype.Normalize(part.ContentType)
+			if mt == "" || mediatype.Equal(mt, mediatype.OctetStream) {
+				mt = mediatype.Detect(part.FileName, part.Content)
+			}
+			v.AddAttachment(vcon.Attachment{
+				Purpose:   emailAttachmentPurpose,
+				StartTime: start,
+				PartyIdx:  sender,
+				DialogIdx: vcon.IntPtr(idx),
+				MediaType: mt,
+				Filename:  part.FileName,
+				Encoding:  "base64url",
+				Body:      base64.RawURLEncoding.EncodeToString(part.Content),
+			})
+		}
+		reply := emailReply{Dialog: idx, MessageID: m.MessageID, InReplyTo: m.InReplyTo}
+		if p, ok := dialogIdx[m.InReplyTo]; ok && m.InReplyTo != "" {
+			reply.ParentDialog = vcon.IntPtr(p)
+		}
+		replies = append(replies, reply)
+		if m.MessageID != "" {
+			dialogIdx[m.MessageID] = idx
+		}
+	}
+
+	if len(msgs) > 1 {
+		body, err := json.Marshal(replies)
+		if err != nil {
+			return nil, err
+		}
+		originator, _ := v.Dialog[0].OriginatorIndex()
+		v.AddAttachment(vcon.Attachment{
+			Purpose:   emailThreadPurpose,
+			StartTime: v.CreatedAt,
+			PartyIdx:  originator,
+			DialogIdx: vcon.IntPtr(0),
+			MediaType: "application/json",
+			Encoding:  "json",
+			Body:      string(body),
+		})
+	}
+	return v, nil
 }
 
 // helpers
@@ -98,7 +364,11 @@ func fetchIfRemote(src string) (path string, cleanup func(), err error) {
 			return "", nil, err
 		}
 
-		resp, err := http.Get(downloadURL)
+		req, err := vcon.NewHTTPRequest(context.Background(), http.MethodGet, downloadURL, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		resp, err := vcon.DoHTTP(req)
 		if err != nil {
 			return "", nil, err
 		}
@@ -123,3 +393,38 @@ func fetchIfRemote(src string) (path string, cleanup func(), err error) {
 	}
 	return src, func() {}, nil
 }
+
+// splitMbox splits an mbox mailbox into raw messages. Messages start at a
+// "From " line; ">From " quoting (mboxrd) is undone.
+func splitMbox(r io.Reader) ([][]byte, error) {
+	var msgs [][]byte
+	var cur *bytes.Buffer
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case bytes.HasPrefix(line, []byte("From ")):
+				if cur != nil {
+					msgs = append(msgs, cur.Bytes())
+				}
+				cur = &bytes.Buffer{}
+			case cur != nil:
+				if trimmed := bytes.TrimLeft(line, ">"); len(trimmed) < len(line) && bytes.HasPrefix(trimmed, []byte("From ")) {
+					line = line[1:]
+				}
+				cur.Write(line)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cur != nil {
+		msgs = append(msgs, cur.Bytes())
+	}
+	return msgs, nil
+}
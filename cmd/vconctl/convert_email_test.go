@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
 	"github.com/spf13/cobra"
 )
 
@@ -262,3 +266,242 @@ to test the email parsing functionality.
 		}
 	}
 }
+
+const threadMbox = `From alice@example.com Mon Jan  1 09:00:00 2024
+From: Alice <alice@example.com>
+To: Bob <bob@example.com>
+Subject: Re: Invoice
+Date: Mon, 1 Jan 2024 11:00:00 +0000
+Message-Id: <3@example.com>
+In-Reply-To: <2@example.com>
+References: <1@example.com> <2@example.com>
+
+Thanks, paid.
+
+From bob@example.com Mon Jan  1 09:00:00 2024
+From: Bob <bob@example.com>
+To: Alice <alice@example.com>
+Cc: Carol <carol@example.com>
+Subject: Invoice
+Date: Mon, 1 Jan 2024 09:00:00 +0000
+Message-Id: <1@example.com>
+
+Please pay the invoice.
+>From the desk of Bob.
+
+From carol@example.com Mon Jan  1 09:00:00 2024
+From: Carol <carol@example.com>
+To: Dave <dave@example.com>
+Subject: Lunch?
+Date: Mon, 1 Jan 2024 10:00:00 +0000
+Message-Id: <9@example.com>
+
+Lunch today?
+
+From bob@example.com Mon Jan  1 09:00:00 2024
+From: Bob <BOB@example.com>
+To: Alice <alice@example.com>
+Subject: Re: Invoice
+Date: Mon, 1 Jan 2024 10:30:00 +0000
+Message-Id: <2@example.com>
+In-Reply-To: <1@example.com>
+References: <1@example.com>
+
+Reminder.
+`
+
+func withEmailGlobals(t *testing.T, groupBy, out string) {
+	t.Helper()
+	origGroupBy, origOut := emailGroupBy, vConOut
+	t.Cleanup(func() { emailGroupBy, vConOut = origGroupBy, origOut })
+	emailGroupBy, vConOut = groupBy, out
+}
+
+func TestSplitMbox(t *testing.T) {
+	msgs, err := splitMbox(strings.NewReader(threadMbox))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(msgs))
+	}
+	if !strings.Contains(string(msgs[1]), "\nFrom the desk of Bob.") {
+		t.Errorf("expected >From quoting to be undone, got %q", msgs[1])
+	}
+}
+
+func TestRunMboxGroupByThread(t *testing.T) {
+	dir := t.TempDir()
+	mbox := filepath.Join(dir, "inbox.mbox")
+	if err := os.WriteFile(mbox, []byte(threadMbox), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outDir := filepath.Join(dir, "out")
+	withEmailGlobals(t, emailGroupByThread, outDir)
+
+	captureStdout(t, func() {
+		if err := runMbox(mboxCmd, []string{mbox}); err != nil {
+			t.Fatalf("runMbox: %v", err)
+		}
+	})
+
+	thread := loadConvertedVCon(t, filepath.Join(outDir, "inbox-1.vcon.json"))
+	if len(thread.Dialog) != 3 {
+		t.Fatalf("expected 3 dialogs in thread, got %d", len(thread.Dialog))
+	}
+	for i, id := range []string{"<1@example.com>", "<2@example.com>", "<3@example.com>"} {
+		if thread.Dialog[i].MessageID != id {
+			t.Errorf("dialog %d: got message %s, want %s", i, thread.Dialog[i].MessageID, id)
+		}
+	}
+	if thread.Subject != "Invoice" {
+		t.Errorf("expected subject of first message, got %q", thread.Subject)
+	}
+	if len(thread.Parties) != 3 {
+		t.Errorf("expected parties to be shared by address, got %d", len(thread.Parties))
+	}
+	// Alice (party 1) sent the last message.
+	if o := thread.Dialog[2].Originator; o == nil || *o != 1 {
+		t.Errorf("expected originator 1 for Alice's reply, got %v", o)
+	}
+
+	if len(thread.Attachments) != 1 || thread.Attachments[0].Purpose != emailThreadPurpose {
+		t.Fatalf("expected email_thread attachment, got %+v", thread.Attachments)
+	}
+	var replies []emailReply
+	if err := json.Unmarshal([]byte(thread.Attachments[0].Body), &replies); err != nil {
+		t.Fatal(err)
+	}
+	if replies[0].ParentDialog != nil || replies[1].ParentDialog == nil || *replies[1].ParentDialog != 0 ||
+		replies[2].ParentDialog == nil || *replies[2].ParentDialog != 1 {
+		t.Errorf("unexpected reply graph: %+v", replies)
+	}
+	if ok, errs := thread.IsValid(); !ok {
+		t.Errorf("thread vCon is invalid: %v", errs)
+	}
+
+	lunch := loadConvertedVCon(t, filepath.Join(outDir, "inbox-2.vcon.json"))
+	if len(lunch.Dialog) != 1 || lunch.Subject != "Lunch?" || len(lunch.Attachments) != 0 {
+		t.Errorf("unexpected standalone vCon: %+v", lunch)
+	}
+}
+
+func TestRunEmailGroupByMessage(t *testing.T) {
+	dir := t.TempDir()
+	raw, err := splitMbox(strings.NewReader(threadMbox))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for i, m := range raw[:2] {
+		f := filepath.Join(dir, fmt.Sprintf("m%d.eml", i))
+		if err := os.WriteFile(f, m, 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	withEmailGlobals(t, emailGroupByMessage, "")
+
+	captureStdout(t, func() {
+		if err := runEmail(emailCmd, files); err != nil {
+			t.Fatalf("runEmail: %v", err)
+		}
+	})
+	for i := 1; i <= 2; i++ {
+		v := loadConvertedVCon(t, filepath.Join(dir, fmt.Sprintf("m0-%d.vcon.json", i)))
+		if len(v.Dialog) != 1 {
+			t.Errorf("vCon %d: expected a single dialog, got %d", i, len(v.Dialog))
+		}
+	}
+
+	withEmailGlobals(t, emailGroupByThread, filepath.Join(dir, "thread.vcon.json"))
+	if err := runEmail(emailCmd, files); err != nil {
+		t.Fatalf("runEmail thread: %v", err)
+	}
+	if v := loadConvertedVCon(t, filepath.Join(dir, "thread.vcon.json")); len(v.Dialog) != 2 {
+		t.Errorf("expected both messages in one thread, got %d dialogs", len(v.Dialog))
+	}
+
+	withEmailGlobals(t, "conversation", "")
+	if err := runEmail(emailCmd, files); err == nil {
+		t.Error("expected error for unknown --group-by")
+	}
+}
+
+const attachmentEmail = "From: Alice <alice@example.com>\r\n" +
+	"To: Bob <bob@example.com>\r\n" +
+	"Subject: Invoice\r\n" +
+	"Date: Mon, 2 Jun 2025 10:00:00 +0000\r\n" +
+	"Message-ID: <invoice@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=b1\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"See attached.\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Content-Disposition: attachment; filename=\"invoice.txt\"\r\n" +
+	"\r\n" +
+	"EICAR-STANDARD-ANTIVIRUS-TEST-FILE\r\n" +
+	"--b1--\r\n"
+
+// fakeScanner flags content containing "EICAR".
+type fakeScanner struct{}
+
+func (fakeScanner) Scan(_ context.Context, name string, content []byte) error {
+	if strings.Contains(string(content), "EICAR") {
+		return &vcon.ThreatError{Name: name, Threat: "Eicar-Signature"}
+	}
+	return nil
+}
+
+func TestRunEmailScanAttachments(t *testing.T) {
+	dir := t.TempDir()
+	eml := filepath.Join(dir, "invoice.eml")
+	if err := os.WriteFile(eml, []byte(attachmentEmail), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "invoice.vcon.json")
+	withEmailGlobals(t, emailGroupByMessage, out)
+
+	origScanner := newScanner
+	origClamd, origOnThreat, origDir := convertClamd, convertOnThreat, convertQuarantineDir
+	t.Cleanup(func() {
+		newScanner = origScanner
+		convertClamd, convertOnThreat, convertQuarantineDir = origClamd, origOnThreat, origDir
+	})
+	newScanner = func(string) vcon.Scanner { return fakeScanner{} }
+
+	// Without --clamd the attachment is embedded as is.
+	if err := runEmail(emailCmd, []string{eml}); err != nil {
+		t.Fatalf("runEmail: %v", err)
+	}
+	v := loadConvertedVCon(t, out)
+	if len(v.Attachments) != 1 || v.Attachments[0].Filename != "invoice.txt" || v.Attachments[0].Purpose != emailAttachmentPurpose {
+		t.Fatalf("expected the invoice attachment, got %+v", v.Attachments)
+	}
+	os.Remove(out)
+
+	convertClamd, convertOnThreat = "127.0.0.1:3310", "reject"
+	err := runEmail(emailCmd, []string{eml})
+	if err == nil || !strings.Contains(err.Error(), "Eicar-Signature") {
+		t.Fatalf("expected the attachment to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("expected no output for a rejected message")
+	}
+
+	convertOnThreat, convertQuarantineDir = "quarantine", filepath.Join(dir, "quarantine")
+	if err := runEmail(emailCmd, []string{eml}); err != nil {
+		t.Fatalf("runEmail quarantine: %v", err)
+	}
+	if v := loadConvertedVCon(t, out); len(v.Attachments) != 0 {
+		t.Errorf("expected the attachment to be removed, got %+v", v.Attachments)
+	}
+	saved, _ := filepath.Glob(filepath.Join(convertQuarantineDir, "*-0-invoice.txt"))
+	if len(saved) != 1 {
+		t.Errorf("expected the attachment in the quarantine directory, got %v", saved)
+	}
+}
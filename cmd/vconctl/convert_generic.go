@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
+	"github.com/spf13/cobra"
+)
+
+// Command: generic-json
+var genericJSONCmd = &cobra.Command{
+	Use:   "generic-json <export.json> --mapping <mapping.json>",
+	Short: "Convert a vendor JSON export into vCons using a JMESPath mapping",
+	Long: `Convert an arbitrary JSON export into vCons. A mapping file describes,
+with JMESPath expressions, where each vCon field comes from:
+
+  {
+    "each":       "calls",
+    "subject":    "title",
+    "created_at": "started_at",
+    "parties": {
+      "each": "participants",
+      "fields": {"id": "user_id", "name": "display_name", "tel": "phone"}
+    },
+    "dialog": {
+      "each": "recordings",
+      "fields": {"type": "'recording'", "start": "started_at",
+                 "parties": "speaker_ids", "url": "download_url"}
+    }
+  }
+
+"each" selects the records: at the top level one vCon is produced per record
+(omit it to convert the whole document into one vCon); inside parties, dialog
+and attachments one object is produced per record. Field names are vCon
+property names and expressions are evaluated against the current record.
+Literals use JMESPath syntax ('text' or ` + "`" + `{"json": 1}` + "`" + `).
+
+A party may map a pseudo-field "id". Dialog "parties" and "originator" and
+attachment "party" values are then resolved from those ids to party indices.
+Numeric "created_at" and "start" values are read as Unix seconds.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGenericJSON,
+}
+
+// genericMapping is the mapping file read by convert generic-json.
+type genericMapping struct {
+	Each        string          `json:"each"`
+	Subject     string          `json:"subject"`
+	CreatedAt   string          `json:"created_at"`
+	Parties     *genericObjects `json:"parties"`
+	Dialog      *genericObjects `json:"dialog"`
+	Attachments *genericObjects `json:"attachments"`
+	Analysis    *genericObjects `json:"analysis"`
+}
+
+// genericObjects maps each record selected by Each to one vCon object.
+type genericObjects struct {
+	Each   string            `json:"each"`
+	Fields map[string]string `json:"fields"`
+}
+
+// genericPartyID is the pseudo-field used to resolve party references.
+const genericPartyID = "id"
+
+func runGenericJSON(cmd *cobra.Command, args []string) error {
+	src := args[0]
+	mappingPath, _ := cmd.Flags().GetString("mapping")
+
+	mapping, err := readGenericMapping(mappingPath)
+	if err != nil {
+		return err
+	}
+
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("parse %s: %w", src, err)
+	}
+
+	records := []interface{}{doc}
+	if mapping.Each != "" {
+		if records, err = searchList(mapping.Each, doc); err != nil {
+			return fmt.Errorf("each: %w", err)
+		}
+		if len(records) == 0 {
+			return fmt.Errorf("each: %q selected no records", mapping.Each)
+		}
+	}
+
+	vcons := make([]*vcon.VCon, 0, len(records))
+	for i, rec := range records {
+		v, err := mapping.build(rec)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i, err)
+		}
+		vcons = append(vcons, v)
+	}
+	err = finishConversion(cmd, vcons,
+		provenanceSource{uri: src, path: src},
+		provenanceSource{uri: mappingPath, path: mappingPath})
+	if err != nil {
+		return err
+	}
+	return writeVconFiles(vcons, vConOut, src)
+}
+
+func readGenericMapping(path string) (*genericMapping, error) {
+	if path == "" {
+		return nil, errors.New("--mapping is required")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m genericMapping
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("parse mapping %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// build produces one vCon from a record.
+func (m *genericMapping) build(rec interface{}) (*vcon.VCon, error) {
+	v := vcon.New(globalDomain)
+
+	if m.Subject != "" {
+		subject, err := jmespath.Search(m.Subject, rec)
+		if err != nil {
+			return nil, fmt.Errorf("subject: %w", err)
+		}
+		if s, ok := subject.(string); ok {
+			v.Subject = s
+		}
+	}
+	if m.CreatedAt != "" {
+		created, err := jmespath.Search(m.CreatedAt, rec)
+		if err != nil {
+			return nil, fmt.Errorf("created_at: %w", err)
+		}
+		t, err := genericTime(created)
+		if err != nil {
+			return nil, fmt.Errorf("created_at: %w", err)
+		}
+		v.CreatedAt = t
+	}
+
+	ids := map[string]int{}
+	parties, err := m.Parties.objects(rec, "parties")
+	if err != nil {
+		return nil, err
+	}
+	for i, obj := range parties {
+		if id, ok := obj[genericPartyID]; ok {
+			ids[fmt.Sprint(id)] = i
+			delete(obj, genericPartyID)
+		}
+	}
+	if err := decodeGeneric(parties, &v.Parties); err != nil {
+		return nil, fmt.Errorf("parties: %w", err)
+	}
+
+	dialogs, err := m.Dialog.objects(rec, "dialog")
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range dialogs {
+		if err := resolvePartyRefs(obj, ids, "parties", "originator"); err != nil {
+			return nil, fmt.Errorf("dialog: %w", err)
+		}
+	}
+	if err := decodeGeneric(dialogs, &v.Dialog); err != nil {
+		return nil, fmt.Errorf("dialog: %w", err)
+	}
+
+	attachments, err := m.Attachments.objects(rec, "attachments")
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range attachments {
+		if err := resolvePartyRefs(obj, ids, "party"); err != nil {
+			return nil, fmt.Errorf("attachments: %w", err)
+		}
+	}
+	if err := decodeGeneric(attachments, &v.Attachments); err != nil {
+		return nil, fmt.Errorf("attachments: %w", err)
+	}
+
+	analysis, err := m.Analysis.objects(rec, "analysis")
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeGeneric(analysis, &v.Analysis); err != nil {
+		return nil, fmt.Errorf("analysis: %w", err)
+	}
+
+	// Source systems spell media types loosely, e.g. audio/x-wav
+	for i := range v.Dialog {
+		v.Dialog[i].MediaType = mediatype.Normalize(v.Dialog[i].MediaType)
+	}
+	for i := range v.Attachments {
+		v.Attachments[i].MediaType = mediatype.Normalize(v.Attachments[i].MediaType)
+	}
+	for i := range v.Analysis {
+		v.Analysis[i].MediaType = mediatype.Normalize(v.Analysis[i].MediaType)
+	}
+
+	return v, nil
+}
+
+// objects evaluates the mapping against rec and returns one JSON object per
+// selected record. Fields evaluating to null are omitted.
+func (g *genericObjects) objects(rec interface{}, name string) ([]map[string]interface{}, error) {
+	if g == nil {
+		return nil, nil
+	}
+	items := []interface{}{rec}
+	if g.Each != "" {
+		var err error
+		if items, err = searchList(g.Each, rec); err != nil {
+			return nil, fmt.Errorf("%s.each: %w", name, err)
+		}
+	}
+
+	// Evaluate fields in a stable order so errors are reproducible.
+	fields := make([]string, 0, len(g.Fields))
+	for f := range g.Fields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+
+	out := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		obj := map[string]interface{}{}
+		for _, f := range fields {
+			val, err := jmespath.Search(g.Fields[f], item)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", name, f, err)
+			}
+			if val == nil {
+				continue
+			}
+			if f == "start" {
+				t, err := genericTime(val)
+				if err != nil {
+					return nil, fmt.Errorf("%s.start: %w", name, err)
+				}
+				val = t
+			}
+			obj[f] = val
+		}
+		out = append(out, obj)
+	}
+	return out, nil
+}
+
+// searchList evaluates expr and requires the result to be an array.
+func searchList(expr string, data interface{}) ([]interface{}, error) {
+	res, err := jmespath.Search(expr, data)
+	if err != nil {
+		return nil, err
+	}
+	switch l := res.(type) {
+	case []interface{}:
+		return l, nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%q selected %T, want an array", expr, res)
+	}
+}
+
+// resolvePartyRefs replaces party ids in the given keys with party indices.
+// Numbers are taken to be indices already.
+func resolvePartyRefs(obj map[string]interface{}, ids map[string]int, keys ...string) error {
+	resolve := func(ref interface{}) (interface{}, error) {
+		if _, isNum := ref.(float64); isNum {
+			return ref, nil
+		}
+		idx, ok := ids[fmt.Sprint(ref)]
+		if !ok {
+			return nil, fmt.Errorf("unknown party id %v", ref)
+		}
+		return idx, nil
+	}
+	for _, key := range keys {
+		switch ref := obj[key].(type) {
+		case nil:
+		case []interface{}:
+			resolved := make([]interface{}, len(ref))
+			for i, r := range ref {
+				idx, err := resolve(r)
+				if err != nil {
+					return err
+				}
+				resolved[i] = idx
+			}
+			obj[key] = resolved
+		default:
+			idx, err := resolve(ref)
+			if err != nil {
+				return err
+			}
+			obj[key] = idx
+		}
+	}
+	return nil
+}
+
+// genericTime accepts RFC 3339 strings and Unix seconds.
+func genericTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339, t)
+	case float64:
+		sec := int64(t)
+		return time.Unix(sec, int64((t-float64(sec))*1e9)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported time value %v", v)
+	}
+}
+
+// decodeGeneric converts the mapped objects into vCon structs through their
+// JSON tags, so every vCon property can be mapped.
+func decodeGeneric(objs []map[string]interface{}, dst interface{}) error {
+	if len(objs) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(objs)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon/ext/cc"
+)
+
+const genericExport = `{
+  "calls": [
+    {
+      "title": "Support call",
+      "started": 1704103200,
+      "people": [
+        {"uid": "u-1", "display": "Agent Smith", "phone": "tel:+15550000001"},
+        {"uid": "u-2", "display": "Customer", "email": "mailto:c@example.com"}
+      ],
+      "recordings": [
+        {"at": "2024-01-01T10:00:00Z", "secs": 95.5, "speakers": ["u-1", "u-2"], "caller": "u-2", "link": "https://rec.example.com/1.wav"}
+      ],
+      "notes": [{"by": "u-1", "at": "2024-01-01T10:02:00Z", "text": "follow up"}]
+    },
+    {
+      "title": "Callback",
+      "started": 1704189600,
+      "people": [{"uid": "u-3", "display": "Solo"}],
+      "recordings": []
+    }
+  ]
+}`
+
+const genericMappingJSON = `{
+  "each": "calls",
+  "subject": "title",
+  "created_at": "started",
+  "parties": {
+    "each": "people",
+    "fields": {"id": "uid", "name": "display", "tel": "phone", "mailto": "email"}
+  },
+  "dialog": {
+    "each": "recordings",
+    "fields": {"type": "'recording'", "start": "at", "duration": "secs",
+               "parties": "speakers", "originator": "caller", "url": "link"}
+  },
+  "attachments": {
+    "each": "notes",
+    "fields": {"purpose": "'note'", "party": "by", "start": "at", "dialog": "` + "`0`" + `",
+               "body": "text", "encoding": "'none'"}
+  }
+}`
+
+func writeGenericFixture(t *testing.T, export, mapping string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "export.json")
+	mappingPath := filepath.Join(dir, "mapping.json")
+	if err := os.WriteFile(src, []byte(export), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mappingPath, []byte(mapping), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return src, mappingPath
+}
+
+func runGenericForTest(t *testing.T, src, mappingPath string) error {
+	t.Helper()
+	origOut := vConOut
+	t.Cleanup(func() { vConOut = origOut })
+	vConOut = ""
+	setFlags(t, map[string]string{"mapping": mappingPath}, genericJSONCmd.Flags().Set)
+	var err error
+	captureStdout(t, func() { err = runGenericJSON(genericJSONCmd, []string{src}) })
+	return err
+}
+
+func TestRunGenericJSON(t *testing.T) {
+	src, mappingPath := writeGenericFixture(t, genericExport, genericMappingJSON)
+	if err := runGenericForTest(t, src, mappingPath); err != nil {
+		t.Fatalf("runGenericJSON: %v", err)
+	}
+
+	dir := filepath.Dir(src)
+	v := loadConvertedVCon(t, filepath.Join(dir, "export-1.vcon.json"))
+	if v.Subject != "Support call" {
+		t.Errorf("subject = %q", v.Subject)
+	}
+	if want := time.Unix(1704103200, 0).UTC(); !v.CreatedAt.Equal(want) {
+		t.Errorf("created_at = %v, want %v", v.CreatedAt, want)
+	}
+	if len(v.Parties) != 2 || v.Parties[0].Tel != "tel:+15550000001" || v.Parties[1].Mailto != "mailto:c@example.com" {
+		t.Errorf("parties = %+v", v.Parties)
+	}
+	if len(v.Dialog) != 1 {
+		t.Fatalf("expected 1 dialog, got %d", len(v.Dialog))
+	}
+	d := v.Dialog[0]
+	if d.Duration != 95.5 || d.Originator == nil || *d.Originator != 1 || d.URL != "https://rec.example.com/1.wav" {
+		t.Errorf("dialog = %+v", d)
+	}
+	if parties := d.Parties.Indices(); len(parties) != 2 || parties[1] != 1 {
+		t.Errorf("dialog parties not resolved to indices: %v", d.Parties)
+	}
+	if len(v.Attachments) != 1 || v.Attachments[0].PartyIdx != 0 || v.Attachments[0].Body != "follow up" {
+		t.Errorf("attachments = %+v", v.Attachments)
+	}
+	if ok, errs := v.IsValid(); !ok {
+		t.Errorf("converted vCon is invalid: %v", errs)
+	}
+
+	second := loadConvertedVCon(t, filepath.Join(dir, "export-2.vcon.json"))
+	if second.Subject != "Callback" || len(second.Parties) != 1 || len(second.Dialog) != 0 {
+		t.Errorf("second vCon = %+v", second)
+	}
+}
+
+func TestRunGenericJSONErrors(t *testing.T) {
+	tests := []struct {
+		name, export, mapping, want string
+	}{
+		{
+			name:    "unknown party id",
+			export:  `{"people":[{"uid":"a"}],"recs":[{"who":["b"]}]}`,
+			mapping: `{"parties":{"each":"people","fields":{"id":"uid"}},"dialog":{"each":"recs","fields":{"type":"'text'","start":"'2024-01-01T00:00:00Z'","parties":"who"}}}`,
+			want:    "unknown party id b",
+		},
+		{
+			name:    "unknown mapping key",
+			export:  `{}`,
+			mapping: `{"subjct":"title"}`,
+			want:    "unknown field",
+		},
+		{
+			name:    "each is not an array",
+			export:  `{"calls":{"a":1}}`,
+			mapping: `{"each":"calls"}`,
+			want:    "want an array",
+		},
+		{
+			name:    "invalid expression",
+			export:  `{}`,
+			mapping: `{"subject":"[["}`,
+			want:    "subject",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, mappingPath := writeGenericFixture(t, tt.export, tt.mapping)
+			err := runGenericForTest(t, src, mappingPath)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRunGenericJSONDialogData(t *testing.T) {
+	mapping := strings.Replace(genericMappingJSON, `"url": "link"`, `"url": "link", "campaign": "'renewals'"`, 1)
+	src, mappingPath := writeGenericFixture(t, genericExport, mapping)
+	t.Cleanup(func() { convertDialogData = cc.DialogData{} })
+	convertDialogData = cc.DialogData{Campaign: "ignored", Skill: "billing"}
+	if err := runGenericForTest(t, src, mappingPath); err != nil {
+		t.Fatalf("runGenericJSON: %v", err)
+	}
+
+	v := loadConvertedVCon(t, filepath.Join(filepath.Dir(src), "export-1.vcon.json"))
+	got, err := cc.DialogFields(&v.Dialog[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (cc.DialogData{Campaign: "renewals", Skill: "billing"}); got != want {
+		t.Errorf("CC fields = %+v, want %+v (the mapping's campaign wins over the flag)", got, want)
+	}
+	if len(v.Extensions) != 1 || v.Extensions[0] != cc.Name {
+		t.Errorf("extensions = %v, want [CC]", v.Extensions)
+	}
+	second := loadConvertedVCon(t, filepath.Join(filepath.Dir(src), "export-2.vcon.json"))
+	if len(second.Extensions) != 0 {
+		t.Errorf("vCon without dialogs should not declare CC: %v", second.Extensions)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+// Command: ics
+var icsCmd = &cobra.Command{
+	Use:   "ics <invite.ics>",
+	Short: "Pre-register a vCon from a calendar invite",
+	Long: `Create a skeleton vCon from the first VEVENT of an iCalendar file.
+
+The organizer and attendees become parties, SUMMARY becomes the subject and
+the scheduled DTSTART becomes created_at. The vCon has no dialogs yet; later
+stages add the recording once the meeting has taken place.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runICS,
+}
+
+// icsEvent is the subset of a VEVENT used to pre-register a vCon.
+type icsEvent struct {
+	Summary   string
+	Start     time.Time
+	Organizer *icsAttendee
+	Attendees []icsAttendee
+}
+
+type icsAttendee struct {
+	Name  string
+	Email string
+}
+
+func runICS(cmd *cobra.Command, args []string) error {
+	f := args[0]
+	r, err := os.Open(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	ev, err := parseICSEvent(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f, err)
+	}
+
+	v := vcon.New(globalDomain)
+	v.Subject = ev.Summary
+	v.CreatedAt = ev.Start
+
+	seen := map[string]bool{}
+	addParty := func(a icsAttendee) {
+		key := strings.ToLower(a.Email)
+		if key != "" && seen[key] {
+			return
+		}
+		seen[key] = true
+		p := vcon.Party{Name: a.Name}
+		if a.Email != "" {
+			p.Mailto = "mailto:" + a.Email
+		}
+		v.Parties = append(v.Parties, p)
+	}
+	if ev.Organizer != nil {
+		addParty(*ev.Organizer)
+	}
+	for _, a := range ev.Attendees {
+		addParty(a)
+	}
+
+	if err := finishConversion(cmd, []*vcon.VCon{v}, provenanceSource{uri: f, path: f}); err != nil {
+		return err
+	}
+	return writeVconFile(v, vConOut, f)
+}
+
+// parseICSEvent returns the first VEVENT in an iCalendar (RFC 5545) stream.
+func parseICSEvent(r io.Reader) (*icsEvent, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var ev *icsEvent
+	for _, line := range lines {
+		name, params, value := splitICSLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			ev = &icsEvent{}
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT") && ev != nil:
+			if ev.Start.IsZero() {
+				return nil, errors.New("VEVENT has no DTSTART")
+			}
+			return ev, nil
+		case ev == nil:
+			continue
+		}
+
+		switch name {
+		case "SUMMARY":
+			ev.Summary = unescapeICSText(value)
+		case "DTSTART":
+			t, err := parseICSTime(value, params["TZID"])
+			if err != nil {
+				return nil, fmt.Errorf("DTSTART: %w", err)
+			}
+			ev.Start = t
+		case "ORGANIZER":
+			a := icsAttendeeFrom(params, value)
+			ev.Organizer = &a
+		case "ATTENDEE":
+			ev.Attendees = append(ev.Attendees, icsAttendeeFrom(params, value))
+		}
+	}
+	return nil, errors.New("no VEVENT found")
+}
+
+// unfoldICS joins continuation lines (those starting with a space or tab).
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+// splitICSLine splits "NAME;PARAM=x;PARAM="y:z":value" into its parts.
+// Parameter names are upper-cased; quoted parameter values may contain ':'
+// and ';'.
+func splitICSLine(line string) (string, map[string]string, string) {
+	params := map[string]string{}
+	inQuote := false
+	var fields []string
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			inQuote = !inQuote
+		case c == ';' && !inQuote:
+			fields = append(fields, line[start:i])
+			start = i + 1
+		case c == ':' && !inQuote:
+			fields = append(fields, line[start:i])
+			for _, p := range fields[1:] {
+				k, v, _ := strings.Cut(p, "=")
+				params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+			}
+			return strings.ToUpper(fields[0]), params, line[i+1:]
+		}
+	}
+	return strings.ToUpper(line), params, ""
+}
+
+func icsAttendeeFrom(params map[string]string, value string) icsAttendee {
+	email := value
+	if len(email) >= 7 && strings.EqualFold(email[:7], "mailto:") {
+		email = email[7:]
+	}
+	return icsAttendee{Name: unescapeICSText(params["CN"]), Email: email}
+}
+
+// parseICSTime parses DATE and DATE-TIME values: UTC ("...Z"), floating or
+// TZID-qualified local times, and all-day dates.
+func parseICSTime(value, tzid string) (time.Time, error) {
+	loc := time.UTC
+	if tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	case len(value) == len("20060102"):
+		return time.ParseInLocation("20060102", value, loc)
+	default:
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+}
+
+func unescapeICSText(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
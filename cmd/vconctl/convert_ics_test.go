@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleInvite = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/Warsaw\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:abc-123@example.com\r\n" +
+	"SUMMARY:Quarterly review\\, Q3\r\n" +
+	"DTSTART;TZID=Europe/Warsaw:20240905T100000\r\n" +
+	"DTEND;TZID=Europe/Warsaw:20240905T110000\r\n" +
+	"ORGANIZER;CN=\"Doe, Jane\":mailto:jane@example.com\r\n" +
+	"ATTENDEE;CN=Jane Doe;ROLE=CHAIR:mailto:JANE@example.com\r\n" +
+	"ATTENDEE;CN=Bob;PARTSTAT=ACCEPTED;ROLE=REQ-PARTICIPANT:mailto:bob@exa\r\n" +
+	" mple.com\r\n" +
+	"ATTENDEE;CUTYPE=ROOM:mailto:room-4@example.com\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICSEvent(t *testing.T) {
+	ev, err := parseICSEvent(strings.NewReader(sampleInvite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Summary != "Quarterly review, Q3" {
+		t.Errorf("summary = %q", ev.Summary)
+	}
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	if want := time.Date(2024, 9, 5, 10, 0, 0, 0, warsaw); !ev.Start.Equal(want) {
+		t.Errorf("start = %v, want %v", ev.Start, want)
+	}
+	if ev.Organizer == nil || ev.Organizer.Name != "Doe, Jane" || ev.Organizer.Email != "jane@example.com" {
+		t.Errorf("organizer = %+v", ev.Organizer)
+	}
+	if len(ev.Attendees) != 3 || ev.Attendees[1].Email != "bob@example.com" {
+		t.Errorf("attendees = %+v", ev.Attendees)
+	}
+}
+
+func TestParseICSTime(t *testing.T) {
+	tests := []struct {
+		value, tzid string
+		want        time.Time
+	}{
+		{"20240101T090000Z", "", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)},
+		{"20240101T090000", "", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)},
+		{"20240101", "", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"20240101T090000", "Not/AZone", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseICSTime(tt.value, tt.tzid)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseICSTime(%q, %q) = %v, %v; want %v", tt.value, tt.tzid, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseICSEventErrors(t *testing.T) {
+	if _, err := parseICSEvent(strings.NewReader("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n")); err == nil {
+		t.Error("expected error for calendar without VEVENT")
+	}
+	if _, err := parseICSEvent(strings.NewReader("BEGIN:VEVENT\r\nSUMMARY:x\r\nEND:VEVENT\r\n")); err == nil {
+		t.Error("expected error for VEVENT without DTSTART")
+	}
+}
+
+func TestRunICS(t *testing.T) {
+	dir := t.TempDir()
+	invite := filepath.Join(dir, "invite.ics")
+	if err := os.WriteFile(invite, []byte(sampleInvite), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origOut := vConOut
+	t.Cleanup(func() { vConOut = origOut })
+	vConOut = ""
+
+	if err := runICS(icsCmd, []string{invite}); err != nil {
+		t.Fatalf("runICS: %v", err)
+	}
+
+	v := loadConvertedVCon(t, filepath.Join(dir, "invite.vcon.json"))
+	if v.Subject != "Quarterly review, Q3" {
+		t.Errorf("subject = %q", v.Subject)
+	}
+	if len(v.Parties) != 3 {
+		t.Fatalf("expected organizer + 2 attendees (deduplicated), got %+v", v.Parties)
+	}
+	if v.Parties[0].Mailto != "mailto:jane@example.com" || v.Parties[2].Name != "" {
+		t.Errorf("unexpected parties: %+v", v.Parties)
+	}
+	if len(v.Dialog) != 0 {
+		t.Errorf("skeleton vCon should have no dialogs, got %d", len(v.Dialog))
+	}
+	if ok, errs := v.IsValid(); !ok {
+		t.Errorf("skeleton vCon is invalid: %v", errs)
+	}
+}
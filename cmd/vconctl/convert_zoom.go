@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"io/fs"
-	"mime"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -12,6 +11,7 @@ import (
 	"time"
 
 	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
 	"github.com/spf13/cobra"
 )
 
@@ -43,7 +43,7 @@ var zoomCmd = &cobra.Command{
 	RunE:  runZoom,
 }
 
-func runZoom(_ *cobra.Command, args []string) error {
+func runZoom(cmd *cobra.Command, args []string) error {
 	folder := args[0]
 	meta, err := readZoomMeta(folder)
 	if err != nil {
@@ -55,10 +55,10 @@ func runZoom(_ *cobra.Command, args []string) error {
 	v.CreatedAt = meta.Start
 
 	// host
-	v.Parties = append(v.Parties, vcon.Party{Name: meta.Host, Mailto: meta.HostEmail})
+	v.Parties = append(v.Parties, vcon.Party{Name: meta.Host, Mailto: mailtoURI(meta.HostEmail)})
 	// participants
 	for _, p := range meta.Participants {
-		v.Parties = append(v.Parties, vcon.Party{Name: p.Name, Mailto: p.Email})
+		v.Parties = append(v.Parties, vcon.Party{Name: p.Name, Mailto: mailtoURI(p.Email)})
 	}
 
 	// main MP4 and VTT transcript become attachments
@@ -74,9 +74,24 @@ func runZoom(_ *cobra.Command, args []string) error {
 		v.Attachments = append(v.Attachments, att)
 	}
 
+	var sources []provenanceSource
+	for _, f := range meta.Files {
+		sources = append(sources, provenanceSource{uri: f.Path, path: f.Path})
+	}
+	if err := finishConversion(cmd, []*vcon.VCon{v}, sources...); err != nil {
+		return err
+	}
 	return writeVconFile(v, "", folder)
 }
 
+// mailtoURI returns the mailto URI of an email address, or "" for none.
+func mailtoURI(email string) string {
+	if email == "" || strings.HasPrefix(email, "mailto:") {
+		return email
+	}
+	return "mailto:" + email
+}
+
 func readZoomMeta(folder string) (*ZoomMeta, error) {
 	fi, err := os.Stat(folder)
 	if err != nil {
@@ -107,9 +122,9 @@ func readZoomMeta(folder string) (*ZoomMeta, error) {
 		ext := strings.ToLower(filepath.Ext(d.Name()))
 		switch ext {
 		case ".mp4", ".m4a", ".mov", ".vtt", ".txt":
-			mt := mime.TypeByExtension(ext)
+			mt := mediatype.FromExtension(ext)
 			if mt == "" {
-				mt = "application/octet-stream"
+				mt = mediatype.OctetStream
 			}
 			meta.Files = append(meta.Files, ZFile{
 				Name: d.Name(),
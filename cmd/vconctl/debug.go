@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Diagnostics for vCon files",
+}
+
+// Command: debug canonical
+var debugCanonicalCmd = &cobra.Command{
+	Use:   "canonical <file>",
+	Short: "Explain why a vCon payload is not RFC 8785 canonical",
+	Long: `Decode a vCon, re-encode it canonically and show where the two diverge.
+
+The file may be a signed vCon, whose payload is checked, or plain vCon JSON.
+This explains the "payload not RFC 8785 canonical" error from verify: the first
+divergent bytes are shown together with every property that is dropped or
+rewritten when the payload is decoded and re-encoded. The command exits
+non-zero if the payload is not canonical.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDebugCanonical,
+}
+
+func runDebugCanonical(_ *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	payload, err := canonicalPayload(data)
+	if err != nil {
+		return err
+	}
+	diff, err := vcon.DiagnoseCanonical(payload)
+	if err != nil {
+		return err
+	}
+	if diff == nil {
+		fmt.Printf("✅ %s: payload is RFC 8785 canonical\n", args[0])
+		return nil
+	}
+
+	fmt.Printf("❌ %s: payload is not RFC 8785 canonical\n", args[0])
+	fmt.Printf("First difference at byte %d:\n", diff.Offset)
+	fmt.Printf("  payload:   %s\n", diff.Payload)
+	fmt.Printf("  canonical: %s\n", diff.Canonical)
+	if len(diff.Fields) == 0 {
+		fmt.Println("Values are identical; only the serialization differs (key order, whitespace, number formatting or escaping).")
+	} else {
+		fmt.Println("Fields changed by re-encoding:")
+		for _, f := range diff.Fields {
+			switch {
+			case f.Canonical == "":
+				fmt.Printf("  %s: dropped (%s)\n", f.Path, f.Payload)
+			case f.Payload == "":
+				fmt.Printf("  %s: added (%s)\n", f.Path, f.Canonical)
+			default:
+				fmt.Printf("  %s: %s → %s\n", f.Path, f.Payload, f.Canonical)
+			}
+		}
+	}
+	return fmt.Errorf("%s is not canonical", args[0])
+}
+
+// canonicalPayload returns the signed payload of a JWS, or data itself for an
+// unsigned vCon.
+func canonicalPayload(data []byte) ([]byte, error) {
+	form, err := vcon.DetectForm(data)
+	if err != nil {
+		return nil, fmt.Errorf("detect form: %w", err)
+	}
+	switch form {
+	case vcon.VConFormUnsigned:
+		return data, nil
+	case vcon.VConFormSigned:
+		var jws struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.Unmarshal(data, &jws); err != nil {
+			return nil, fmt.Errorf("parse JWS: %w", err)
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("decode payload: %w", err)
+		}
+		return payload, nil
+	default:
+		return nil, fmt.Errorf("cannot check a %s vCon; decrypt it first", form)
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+// Certificates expiring within certExpiryWarning are reported as warnings.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// doctorTimeout bounds each network check.
+const doctorTimeout = 10 * time.Second
+
+// Command: doctor
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check external tools, keys, certificates and endpoints, and suggest fixes",
+	Long: `Report the external tools vconctl can use and the features each one enables,
+check the configured keys, certificates and HTTP settings, and test that
+endpoints can be reached. Every problem is printed with a suggested fix, and
+the command fails when a check does.
+
+Without ffprobe, audio conversion and validate --check-durations read WAV,
+MP3 and Ogg (Vorbis, Opus) headers themselves: durations are exact for WAV,
+Ogg and VBR MP3 with a Xing header and estimated for other MP3, and no
+creation time is read. slice --trim-media and export --pitch-shift need both
+ffmpeg and ffprobe; missing tools are warnings, not failures.
+
+The keyring is checked when it exists or --keyring-alias is given. The
+global --ca-bundle, --client-cert and --client-key files are checked here
+rather than refused before the command runs.`,
+	Example: `  vconctl doctor
+  vconctl doctor -k signing_key.pem -c signing_cert.pem
+  vconctl doctor --keyring-alias prod --endpoint https://collector.example.com/vcons --clamd 127.0.0.1:3310`,
+	Args: cobra.NoArgs,
+	// The HTTP settings are checked by runDoctor, not refused up front.
+	PersistentPreRunE: configureOutput,
+	RunE:              runDoctor,
+}
+
+// doctorReport prints check results and counts the failures.
+type doctorReport struct {
+	failed int
+}
+
+func (r *doctorReport) ok(name, detail string) {
+	fmt.Printf("✅ %s: %s\n", name, detail)
+}
+
+func (r *doctorReport) warn(name, detail, fix string) {
+	fmt.Printf("⚠️  %s: %s\n", name, detail)
+	if fix != "" {
+		fmt.Printf("   fix: %s\n", fix)
+	}
+}
+
+func (r *doctorReport) fail(name, detail, fix string) {
+	r.failed++
+	fmt.Printf("❌ %s: %s\n", name, detail)
+	if fix != "" {
+		fmt.Printf("   fix: %s\n", fix)
+	}
+}
+
+func (r *doctorReport) note(detail string) {
+	fmt.Printf("   %s\n", detail)
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	r := &doctorReport{}
+	doctorTools(r)
+
+	keyPath, _ := cmd.Flags().GetString("key")
+	certPath, _ := cmd.Flags().GetString("cert")
+	alias, _ := cmd.Flags().GetString("keyring-alias")
+	if keyPath != "" || certPath != "" {
+		doctorKeyFiles(r, keyPath, certPath)
+	}
+	doctorKeyRing(r, alias)
+
+	endpoints, _ := cmd.Flags().GetStringArray("endpoint")
+	if doctorHTTPConfig(r) {
+		for _, u := range endpoints {
+			doctorEndpoint(r, u)
+		}
+	} else if len(endpoints) > 0 {
+		r.note("endpoints not checked until the http configuration loads")
+	}
+	if addr, _ := cmd.Flags().GetString("clamd"); addr != "" {
+		doctorClamd(r, addr)
+	}
+
+	if r.failed > 0 {
+		return fmt.Errorf("%d check(s) failed", r.failed)
+	}
+	return nil
+}
+
+// doctorTools reports ffprobe and ffmpeg and the features they enable.
+func doctorTools(r *doctorReport) {
+	ffprobePath, ffprobeErr := exec.LookPath("ffprobe")
+	ffmpegPath, ffmpegErr := exec.LookPath("ffmpeg")
+	const install = "install FFmpeg (https://ffmpeg.org/download.html) and put its bin directory on the PATH"
+
+	if ffprobeErr == nil {
+		r.ok("ffprobe", ffprobePath)
+		r.note("convert audio, validate --check-durations: all formats, creation time tags")
+	} else {
+		r.warn("ffprobe", "not found", install)
+		r.note(fmt.Sprintf("convert audio, validate --check-durations: built-in header parsing of %s only, no creation time",
+			strings.Join(headerProbeFormats, ", ")))
+	}
+	if ffmpegErr == nil {
+		r.ok("ffmpeg", ffmpegPath)
+	} else {
+		r.warn("ffmpeg", "not found", install)
+	}
+	if ffprobeErr == nil && ffmpegErr == nil {
+		r.note("slice --trim-media, export --pitch-shift: available")
+	} else {
+		r.note("slice --trim-media, export --pitch-shift: unavailable (need ffmpeg and ffprobe)")
+	}
+}
+
+// doctorKeyFiles checks a --key and --cert pair.
+func doctorKeyFiles(r *doctorReport, keyPath, certPath string) {
+	var keyPEM, certPEM []byte
+	if keyPath != "" {
+		raw, err := os.ReadFile(keyPath)
+		if err != nil {
+			r.fail("key "+keyPath, err.Error(), "check the path and that the file is readable by this user")
+		} else {
+			keyPEM = raw
+		}
+	}
+	if certPath != "" {
+		raw, err := os.ReadFile(certPath)
+		if err != nil {
+			r.fail("certificate "+certPath, err.Error(), "check the path and that the file is readable by this user")
+		} else {
+			certPEM = raw
+		}
+	}
+	doctorKeyPair(r, "key "+keyPath, keyPEM, "certificate "+certPath, certPEM)
+}
+
+// doctorKeyRing checks that the keyring opens and, when alias is given, that
+// it holds a usable signing key. A missing keyring is skipped unless alias
+// is given.
+func doctorKeyRing(r *doctorReport, alias string) {
+	path := keyRingPath()
+	if _, err := os.Stat(path); err != nil && alias == "" {
+		return
+	}
+	ring, err := loadKeyRing()
+	if err != nil {
+		fix := "check the file, and that $" + keyRingPassphraseEnv + " holds its passphrase"
+		if os.Getenv(keyRingPassphraseEnv) == "" {
+			fix = "export " + keyRingPassphraseEnv + " with the keyring passphrase"
+		}
+		r.fail("keyring "+path, err.Error(), fix)
+		return
+	}
+	aliases := make([]string, 0, len(ring.Entries))
+	for a := range ring.Entries {
+		aliases = append(aliases, a)
+	}
+	sort.Strings(aliases)
+	r.ok("keyring "+path, fmt.Sprintf("%d key(s): %s", len(aliases), strings.Join(aliases, ", ")))
+
+	for _, a := range aliases {
+		e := ring.Entries[a]
+		if a != alias && e.Cert == "" {
+			continue
+		}
+		if a == alias && e.Cert == "" {
+			r.fail("keyring alias "+a, "has no certificate, so it cannot sign",
+				"vconctl keyring add "+a+" -k <key.pem> -c <cert.pem> --force")
+			continue
+		}
+		doctorKeyPair(r, "keyring alias "+a+" key", []byte(e.Key), "keyring alias "+a+" certificate", []byte(e.Cert))
+	}
+	if _, ok := ring.Entries[alias]; alias != "" && !ok {
+		r.fail("keyring alias "+alias, "not found", "vconctl keyring add "+alias+" -k <key.pem> -c <cert.pem>")
+	}
+}
+
+// doctorKeyPair checks that a private key and certificate parse, that the
+// certificate is in date and that they belong together. Either may be
+// missing.
+func doctorKeyPair(r *doctorReport, keyName string, keyPEM []byte, certName string, certPEM []byte) {
+	var (
+		certOK bool
+		cert   *x509.Certificate
+	)
+	if certPEM != nil {
+		b, _ := pem.Decode(certPEM)
+		var err error
+		if b == nil || b.Type != "CERTIFICATE" {
+			err = errors.New("no CERTIFICATE PEM block")
+		} else {
+			cert, err = x509.ParseCertificate(b.Bytes)
+		}
+		if err != nil {
+			r.fail(certName, err.Error(), "use a PEM encoded X.509 certificate")
+		} else {
+			certOK = doctorCertValidity(r, certName, cert)
+		}
+	}
+	if keyPEM == nil {
+		return
+	}
+	key, err := parsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		r.fail(keyName, err.Error(), "use a PEM encoded RSA or ECDSA private key (PKCS#1, SEC 1 or PKCS#8)")
+		return
+	}
+	if cert == nil {
+		r.ok(keyName, describeKey(key)+" private key")
+		return
+	}
+	if !keyMatchesCert(key, cert) {
+		r.fail(keyName, "private key does not match the certificate", "pass the certificate issued for this key")
+		return
+	}
+	if certOK {
+		r.ok(keyName, describeKey(key)+" private key matching the certificate")
+	}
+}
+
+// doctorCertValidity reports the validity window of cert and whether it is
+// usable now.
+func doctorCertValidity(r *doctorReport, name string, cert *x509.Certificate) bool {
+	now := time.Now()
+	subject := cert.Subject.String()
+	switch {
+	case now.After(cert.NotAfter):
+		r.fail(name, fmt.Sprintf("%s expired on %s", subject, cert.NotAfter.Format(time.DateOnly)),
+			"issue a new certificate, then re-sign existing vCons with vconctl resign")
+		return false
+	case now.Before(cert.NotBefore):
+		r.fail(name, fmt.Sprintf("%s is not valid until %s", subject, cert.NotBefore.Format(time.RFC3339)),
+			"check the system clock, or wait until the certificate is valid")
+		return false
+	case cert.NotAfter.Sub(now) < certExpiryWarning:
+		r.warn(name, fmt.Sprintf("%s expires on %s", subject, cert.NotAfter.Format(time.DateOnly)),
+			"issue a new certificate soon, then re-sign existing vCons with vconctl resign")
+	default:
+		r.ok(name, fmt.Sprintf("%s, valid until %s", subject, cert.NotAfter.Format(time.DateOnly)))
+	}
+	return true
+}
+
+// doctorHTTPConfig checks the global HTTP flags and installs them for the
+// endpoint checks.
+func doctorHTTPConfig(r *doctorReport) bool {
+	if err := vcon.SetHTTPConfig(httpFlags); err != nil {
+		r.fail("http configuration", err.Error(),
+			"check --ca-bundle, --client-cert and --client-key are readable PEM files, and --proxy is a URL")
+		return false
+	}
+	if httpFlags.CAFile != "" || httpFlags.ClientCertFile != "" || httpFlags.Proxy != "" {
+		r.ok("http configuration", "proxy, CA bundle and client certificate load")
+	}
+	return true
+}
+
+// doctorEndpoint sends a HEAD request to u. Any response counts as
+// reachable; 5xx statuses are warnings.
+func doctorEndpoint(r *doctorReport, u string) {
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	name := "endpoint " + u
+	req, err := vcon.NewHTTPRequest(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		r.fail(name, err.Error(), "use an absolute http:// or https:// URL")
+		return
+	}
+	resp, err := vcon.DoHTTP(req)
+	if err != nil {
+		r.fail(name, err.Error(), endpointFix(err))
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		r.warn(name, "reachable, but answered "+resp.Status, "check the server's logs")
+		return
+	}
+	r.ok(name, "reachable ("+resp.Status+")")
+}
+
+// endpointFix suggests a remedy for a failed request.
+func endpointFix(err error) string {
+	var (
+		dnsErr     *net.DNSError
+		unknownCA  x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		return "check the host name and DNS, or set --proxy when the network requires one"
+	case errors.As(err, &unknownCA):
+		return "trust the server's CA with --ca-bundle <ca.pem>"
+	case errors.As(err, &hostErr):
+		return "use the host name the server's certificate was issued for"
+	case errors.As(err, &invalidErr):
+		return "the server's certificate is invalid or expired; renew it on the server"
+	case errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err):
+		return "check firewalls and --proxy, or raise --http-timeout for slow servers"
+	}
+	return "check the server is running and that firewalls allow the connection"
+}
+
+// doctorClamd pings the ClamAV daemon used by convert --clamd.
+func doctorClamd(r *doctorReport, addr string) {
+	name := "clamd " + addr
+	s := vcon.NewClamdScanner(addr)
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		r.fail(name, err.Error(), "start clamd, or check its TCPSocket/LocalSocket setting matches --clamd")
+		return
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	if _, err := conn.Write([]byte("zPING\x00")); err == nil {
+		var reply string
+		if reply, err = bufio.NewReader(conn).ReadString(0); err == nil {
+			if strings.TrimRight(reply, "\x00\n") != "PONG" {
+				err = fmt.Errorf("unexpected reply %q", reply)
+			}
+		}
+	}
+	if err != nil {
+		r.fail(name, err.Error(), "check that --clamd points at clamd, not another service")
+		return
+	}
+	r.ok(name, "answered PING")
+}
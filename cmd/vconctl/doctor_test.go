@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestDoctorChecks(t *testing.T) {
+	useTestKeyRing(t)
+	keyPath, certPath := writeTestKeyPair(t, t.TempDir())
+	otherKey, _ := writeTestKeyPair(t, t.TempDir())
+
+	setFlags(t, map[string]string{"key": keyPath, "cert": certPath}, keyringAddCmd.Flags().Set)
+	captureStdout(t, func() {
+		if err := runKeyringAdd(keyringAddCmd, []string{"prod"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if cmd, _ := bufio.NewReader(conn).ReadString(0); cmd == "zPING\x00" {
+			conn.Write([]byte("PONG\x00"))
+		}
+	}()
+
+	setFlags(t, map[string]string{"key": keyPath, "cert": certPath, "keyring-alias": "prod", "clamd": ln.Addr().String()},
+		doctorCmd.Flags().Set)
+	endpoints := doctorCmd.Flags().Lookup("endpoint").Value.(pflag.SliceValue)
+	endpoints.Replace([]string{srv.URL})
+	t.Cleanup(func() { endpoints.Replace(nil) })
+
+	var runErr error
+	out := captureStdout(t, func() { runErr = runDoctor(doctorCmd, nil) })
+	if runErr != nil {
+		t.Fatalf("doctor failed: %v\n%s", runErr, out)
+	}
+	for _, want := range []string{
+		"✅ key " + keyPath + ": RSA 2048-bit private key matching the certificate",
+		"keyring alias prod key: RSA 2048-bit",
+		"✅ endpoint " + srv.URL + ": reachable (200 OK)",
+		"answered PING",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	setFlags(t, map[string]string{"key": otherKey, "keyring-alias": "missing", "clamd": ""}, doctorCmd.Flags().Set)
+	endpoints.Replace([]string{"http://127.0.0.1:1/"})
+	out = captureStdout(t, func() { runErr = runDoctor(doctorCmd, nil) })
+	if runErr == nil || !strings.Contains(runErr.Error(), "3 check(s) failed") {
+		t.Errorf("expected 3 failed checks, got %v\n%s", runErr, out)
+	}
+	for _, want := range []string{
+		"private key does not match the certificate",
+		"keyring alias missing: not found",
+		"fix: vconctl keyring add missing",
+		"❌ endpoint http://127.0.0.1:1/",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
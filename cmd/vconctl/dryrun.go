@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+// The commands that write vCons accept --dry-run: they do all of their work,
+// signing and encrypting included, and report what they would write instead
+// of writing it, so they can be tried safely against production archives.
+
+// reportWrite prints what writing data to path would do. Like
+// writeOutputFile it fails on an existing file without --force, unless the
+// command updates the file in place.
+func reportWrite(path string, data []byte, inPlace bool) error {
+	if !inPlace {
+		if err := checkOutput(path); err != nil {
+			return err
+		}
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		fmt.Printf("🔍 Dry run: would write %s (%d bytes)\n", path, len(data))
+		return nil
+	}
+	fmt.Printf("🔍 Dry run: would overwrite %s (%d -> %d bytes)\n", path, fi.Size(), len(data))
+	if backupOriginals {
+		fmt.Printf("   original kept as %s.bak\n", path)
+	}
+	return nil
+}
+
+// previewJSON reports writing v to path as indented JSON, like writeJSON.
+func previewJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return reportWrite(path, data, false)
+}
+
+// previewVCon reports writing after to path in place of before: the bytes
+// written, every change as a JSON pointer and the validation outcome.
+func previewVCon(before, after *vcon.VCon, path string, inPlace bool) error {
+	data, err := json.MarshalIndent(after, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := reportWrite(path, data, inPlace); err != nil {
+		return err
+	}
+	d, err := vcon.Diff(before, after)
+	if err != nil {
+		return err
+	}
+	reportChanges(d)
+	reportValidation(after)
+	return nil
+}
+
+// reportChanges prints the changes of a dry run.
+func reportChanges(d *vcon.VConDiff) {
+	if d.IsEmpty() {
+		fmt.Println("   no changes")
+		return
+	}
+	for _, c := range d.Changes {
+		fmt.Printf("   %s\n", c)
+	}
+}
+
+// reportValidation prints whether v is valid and within the global size
+// limits, followed by any warnings. Problems are reported rather than
+// returned, so a dry run shows all of them.
+func reportValidation(v *vcon.VCon) {
+	_, issues := v.IsValid()
+	var problems, warnings []string
+	for _, issue := range issues {
+		if issue.Severity == vcon.SeverityWarning {
+			warnings = append(warnings, issue.Message)
+		} else {
+			problems = append(problems, issue.Message)
+		}
+	}
+	var limitErr *vcon.LimitError
+	if err := v.CheckLimits(sizeLimits); errors.As(err, &limitErr) {
+		problems = append(problems, limitErr.Violations...)
+	} else if err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) == 0 {
+		fmt.Println("   ✅ result is valid")
+	} else {
+		fmt.Println("   ❌ result is invalid:")
+		for _, p := range problems {
+			fmt.Printf("      %s\n", p)
+		}
+	}
+	for _, w := range warnings {
+		fmt.Printf("   ⚠️  %s\n", w)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -20,16 +21,17 @@ var encryptCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		certPath, _ := cmd.Flags().GetString("cert")
 		outPath, _ := cmd.Flags().GetString("output")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
 		if certPath == "" {
 			fmt.Println("Error: --cert is required")
 			_ = cmd.Help()
 			os.Exit(1)
 		}
-		encryptFile(args[0], certPath, outPath)
+		encryptFile(args[0], certPath, outPath, dryRun)
 	},
 }
 
-func encryptFile(path, certPath, outPath string) {
+func encryptFile(path, certPath, outPath string, dryRun bool) {
 	fmt.Printf("Encrypting %s…\n", path)
 
 	jwsMap := readBareJWS(path)
@@ -48,31 +50,136 @@ func encryptFile(path, certPath, outPath string) {
 		ext := filepath.Ext(path)
 		outPath = path[:len(path)-len(ext)] + ".encrypted" + ext
 	}
+	if dryRun {
+		if err := previewJSON(outPath, obj); err != nil {
+			die("writing output", err)
+		}
+		fmt.Printf("   encrypted for %s\n", cert.Subject.CommonName)
+		return
+	}
 	if err := writeJSON(outPath, obj); err != nil {
 		die("writing output", err)
 	}
 	fmt.Printf("✅ Encrypted vCon written to %s\n", outPath)
 }
 
+// Command: seal
+
+var sealCmd = &cobra.Command{
+	Use:   "seal [file]",
+	Short: "Sign and encrypt an unsigned vCon in one step",
+	Long: `Sign an unsigned vCon and encrypt the signed form for one or more
+recipients. The signature is verified before encrypting, so the output always
+wraps a valid signed vCon and no unsigned payload is ever encrypted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSeal,
+}
+
+func runSeal(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	recipientPaths, _ := cmd.Flags().GetStringArray("recipient")
+	outPath, _ := cmd.Flags().GetString("output")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if len(recipientPaths) == 0 {
+		return fmt.Errorf("--recipient is required")
+	}
+	priv, cert, err := signingMaterial(cmd)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Sealing %s…\n", path)
+	v, err := vcon.LoadFromFile(path, vcon.PropertyHandlingDefault)
+	if err != nil {
+		return fmt.Errorf("loading vCon: %w", err)
+	}
+
+	recipients := make([]jose.Recipient, len(recipientPaths))
+	for i, p := range recipientPaths {
+		recipients[i] = jose.Recipient{Algorithm: jose.RSA_OAEP, Key: readCertificate(p).PublicKey}
+	}
+
+	sealed, err := v.SignAndEncrypt(priv, []*x509.Certificate{cert}, recipients)
+	if err != nil {
+		return fmt.Errorf("sealing: %w", err)
+	}
+
+	if outPath == "" {
+		ext := filepath.Ext(path)
+		outPath = path[:len(path)-len(ext)] + ".encrypted" + ext
+	}
+	if dryRun {
+		if err := previewJSON(outPath, sealed); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		fmt.Printf("   signed by %s, encrypted for %d recipient(s)\n", cert.Subject.CommonName, len(recipients))
+		reportValidation(v)
+		return nil
+	}
+	if err := writeJSON(outPath, sealed); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	fmt.Printf("✅ Signed and encrypted vCon written to %s\n", outPath)
+	return nil
+}
+
 // Command decrypt
 
 var decryptCmd = &cobra.Command{
 	Use:   "decrypt [file]",
 	Short: "Decrypt an encrypted vCon file",
-	Args:  cobra.ExactArgs(1),
+	Long: `Decrypt an encrypted vCon with a private key.
+
+With --keyring-alias (a key from the local keyring), --keyring (a directory
+of PEM private keys) or --jwks (a JSON Web Key Set), the key is selected by the recipient kid or key thumbprint, so archives
+encrypted for rotated keys still open.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		keyPath, _ := cmd.Flags().GetString("key")
+		alias, _ := cmd.Flags().GetString("keyring-alias")
+		keyRingDir, _ := cmd.Flags().GetString("keyring")
+		jwksPath, _ := cmd.Flags().GetString("jwks")
 		outPath, _ := cmd.Flags().GetString("output")
-		if keyPath == "" {
-			fmt.Println("Error: --key is required")
+		if keyPath == "" && alias == "" && keyRingDir == "" && jwksPath == "" {
+			fmt.Println("Error: --key, --keyring-alias, --keyring or --jwks is required")
 			_ = cmd.Help()
 			os.Exit(1)
 		}
-		decryptFile(args[0], keyPath, outPath)
+
+		var keys []jose.JSONWebKey
+		if keyPath != "" {
+			keys = append(keys, jose.JSONWebKey{Key: readPrivateKey(keyPath)})
+		}
+		if alias != "" {
+			e, err := keyRingEntryFor(alias)
+			if err != nil {
+				die("reading keyring", err)
+			}
+			priv, err := e.privateKey()
+			if err != nil {
+				die("keyring alias "+alias, err)
+			}
+			keys = append(keys, jose.JSONWebKey{Key: priv, KeyID: alias})
+		}
+		if keyRingDir != "" {
+			ring, err := readKeyRingDir(keyRingDir)
+			if err != nil {
+				die("reading keyring", err)
+			}
+			keys = append(keys, ring...)
+		}
+		if jwksPath != "" {
+			set, err := readJWKS(jwksPath)
+			if err != nil {
+				die("reading JWKS", err)
+			}
+			keys = append(keys, set...)
+		}
+		decryptFile(args[0], keys, outPath)
 	},
 }
 
-func decryptFile(path, keyPath, outPath string) {
+func decryptFile(path string, keys []jose.JSONWebKey, outPath string) {
 	fmt.Printf("Decrypting %s…\n", path)
 
 	// Read encrypted JWE
@@ -95,9 +202,8 @@ func decryptFile(path, keyPath, outPath string) {
 	}
 
 	encrypted := vcon.EncryptedVCon{JSON: jweMap}
-	priv := readPrivateKey(keyPath)
 
-	decrypted, err := encrypted.Decrypt(priv)
+	decrypted, err := encrypted.DecryptWithKeys(keys)
 	if err != nil {
 		die("decrypting", err)
 	}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+	"github.com/vansante/go-ffprobe"
+)
+
+// Command: export
+var exportCmd = &cobra.Command{
+	Use:   "export <file> --profile <profile>",
+	Short: "Export a redacted copy of a vCon for a given use",
+	Long: `Export a redacted copy of a vCon according to a profile.
+
+The anonymized profile produces research-safe vCons: parties are replaced by
+pseudonyms, email addresses, phone and card numbers and party names are
+scrubbed from the subject, text dialogs and analysis, audio and video content
+is removed and attachments are dropped. Party and dialog indices are kept.
+
+--pseudonym-key names each party after a keyed hash of its address, so the
+same person has the same pseudonym across every vCon exported with the key.
+--pitch-shift keeps audio dialogs, shifted by the given number of semitones
+with ffmpeg and stored inline as WAV, instead of removing them.
+
+--dry-run lists the changes and validates the result without writing it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExport,
+}
+
+// exportProfiles are the redaction rules selected by --profile.
+var exportProfiles = map[string]vcon.RedactionRules{
+	"anonymized": {
+		PseudonymizeParties: true,
+		ScrubText:           true,
+		DropMediaBodies:     true,
+		RemoveAttachments:   true,
+	},
+}
+
+// maxPitchShift bounds --pitch-shift to the range atempo supports.
+const maxPitchShift = 12
+
+func runExport(cmd *cobra.Command, args []string) error {
+	src := args[0]
+	profile, _ := cmd.Flags().GetString("profile")
+	keyFile, _ := cmd.Flags().GetString("pseudonym-key")
+	out, _ := cmd.Flags().GetString("output")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	rules, ok := exportProfiles[profile]
+	if !ok {
+		names := make([]string, 0, len(exportProfiles))
+		for name := range exportProfiles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("unknown profile %q (available: %s)", profile, strings.Join(names, ", "))
+	}
+	if keyFile != "" {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return fmt.Errorf("pseudonym key: %w", err)
+		}
+		rules.PseudonymKey = bytes.TrimSpace(key)
+	}
+	if cmd.Flags().Changed("pitch-shift") {
+		semitones, _ := cmd.Flags().GetFloat64("pitch-shift")
+		if semitones == 0 || math.Abs(semitones) > maxPitchShift {
+			return fmt.Errorf("--pitch-shift must be non-zero and within ±%d semitones", maxPitchShift)
+		}
+		rules.VoiceTransform = func(media []byte, _ string) ([]byte, string, error) {
+			return shiftPitch(media, semitones)
+		}
+	}
+
+	v, err := vcon.LoadFromFile(src, vcon.PropertyHandlingDefault)
+	if err != nil {
+		return err
+	}
+	exported, err := v.RedactWithRules(profile, rules)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+
+	if out == "" {
+		out = strings.TrimSuffix(src, filepath.Ext(src)) + "." + profile + ".json"
+	}
+	if dryRun {
+		if err := exported.CheckLimits(sizeLimits); err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+		return previewVCon(v, exported, out, false)
+	}
+	if err := writeVconFile(exported, out, src); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Exported %s vCon to %s\n", profile, out)
+	return nil
+}
+
+// shiftPitch shifts the pitch of a recording by the given number of
+// semitones with ffmpeg, keeping its duration and dropping its metadata
+// tags. The result is WAV. It is a variable so tests can run without ffmpeg
+// installed.
+var shiftPitch = func(media []byte, semitones float64) ([]byte, string, error) {
+	dir, err := os.MkdirTemp("", "vconctl-pitch-")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in")
+	if err := os.WriteFile(in, media, 0600); err != nil {
+		return nil, "", err
+	}
+	info, err := ffprobe.GetProbeData(in, 10*time.Second)
+	if err != nil {
+		return nil, "", fmt.Errorf("ffprobe: %w", err)
+	}
+	stream := info.GetFirstAudioStream()
+	if stream == nil {
+		return nil, "", errors.New("no audio stream")
+	}
+	rate, err := strconv.Atoi(stream.SampleRate)
+	if err != nil || rate <= 0 {
+		return nil, "", fmt.Errorf("unknown sample rate %q", stream.SampleRate)
+	}
+
+	// Resampling raises the pitch and the tempo; atempo restores the tempo.
+	factor := math.Pow(2, semitones/12)
+	filter := fmt.Sprintf("asetrate=%d,aresample=%d,atempo=%.6f", int(math.Round(float64(rate)*factor)), rate, 1/factor)
+	out := filepath.Join(dir, "out.wav")
+	ffmpeg := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "error", "-i", in, "-af", filter, "-map_metadata", "-1", out)
+	if output, err := ffmpeg.CombinedOutput(); err != nil {
+		return nil, "", fmt.Errorf("ffmpeg: %w: %s", err, bytes.TrimSpace(output))
+	}
+	shifted, err := os.ReadFile(out)
+	if err != nil {
+		return nil, "", err
+	}
+	return shifted, vcon.MIMETypeAudioWav2, nil
+}
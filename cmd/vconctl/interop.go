@@ -0,0 +1,275 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+// Command: interop
+//
+// interop exchanges fixtures with other vCon implementations (primarily the
+// Python reference library). "generate" writes the unsigned, signed and
+// encrypted forms produced by go-vcon; "check" reads a directory of fixtures
+// produced elsewhere and runs them through parse/verify/decrypt.
+
+var interopCmd = &cobra.Command{
+	Use:   "interop",
+	Short: "Exchange conformance fixtures with other vCon implementations",
+}
+
+var interopGenerateCmd = &cobra.Command{
+	Use:   "generate <dir>",
+	Short: "Write unsigned, signed and encrypted fixtures produced by go-vcon",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runInteropGenerate,
+}
+
+var interopCheckCmd = &cobra.Command{
+	Use:   "check <dir>",
+	Short: "Parse, verify and decrypt fixtures produced by another implementation",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runInteropCheck,
+}
+
+// interopManifest describes the fixtures written by "interop generate".
+type interopManifest struct {
+	Implementation string            `json:"implementation"`
+	SpecVersion    string            `json:"spec_version"`
+	Domain         string            `json:"domain"`
+	UUID           string            `json:"uuid"`
+	GeneratedAt    time.Time         `json:"generated_at"` // signing time, for checking chains that have since expired
+	Files          map[string]string `json:"files"`
+}
+
+const interopManifestName = "manifest.json"
+
+// interopFixture builds the deterministic content shared by all fixture forms.
+func interopFixture() *vcon.VCon {
+	v := vcon.New(globalDomain)
+	v.Subject = "go-vcon interop fixture"
+	v.CreatedAt = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	v.AddParty(vcon.Party{Name: "Alice", Tel: "tel:+12025551234"})
+	v.AddParty(vcon.Party{Name: "Bob", Mailto: "mailto:bob@example.com"})
+	v.AddDialog(vcon.Dialog{
+		Type:      "text",
+		StartTime: &v.CreatedAt,
+		Parties:   vcon.NewPartyRefs(0, 1),
+		Body:      "Hello from go-vcon",
+		Encoding:  "none",
+		MediaType: vcon.MIMETypePlainText,
+	})
+	return v
+}
+
+func runInteropGenerate(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	keyPath, _ := cmd.Flags().GetString("key")
+	certPath, _ := cmd.Flags().GetString("cert")
+	recipientPath, _ := cmd.Flags().GetString("recipient")
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create fixture dir: %w", err)
+	}
+
+	v := interopFixture()
+	manifest := interopManifest{
+		Implementation: "go-vcon",
+		SpecVersion:    vcon.SpecVersion,
+		Domain:         globalDomain,
+		UUID:           v.UUID,
+		GeneratedAt:    time.Now().UTC(),
+		Files:          map[string]string{},
+	}
+
+	manifest.Files["unsigned"] = "unsigned.vcon.json"
+	if err := writeJSON(filepath.Join(dir, manifest.Files["unsigned"]), v); err != nil {
+		return fmt.Errorf("write unsigned fixture: %w", err)
+	}
+
+	if keyPath != "" && certPath != "" {
+		signed, err := v.Sign(readPrivateKey(keyPath), []*x509.Certificate{readCertificate(certPath)})
+		if err != nil {
+			return fmt.Errorf("sign fixture: %w", err)
+		}
+		manifest.Files["signed"] = "signed.vcon.json"
+		if err := writeJSON(filepath.Join(dir, manifest.Files["signed"]), signed.JSON); err != nil {
+			return fmt.Errorf("write signed fixture: %w", err)
+		}
+
+		if recipientPath != "" {
+			cert := readCertificate(recipientPath)
+			encrypted, err := signed.Encrypt([]jose.Recipient{{
+				Algorithm: jose.RSA_OAEP,
+				Key:       cert.PublicKey,
+			}})
+			if err != nil {
+				return fmt.Errorf("encrypt fixture: %w", err)
+			}
+			manifest.Files["encrypted"] = "encrypted.vcon.json"
+			if err := writeJSON(filepath.Join(dir, manifest.Files["encrypted"]), encrypted.JSON); err != nil {
+				return fmt.Errorf("write encrypted fixture: %w", err)
+			}
+		}
+	}
+
+	if err := writeJSON(filepath.Join(dir, interopManifestName), manifest); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	fmt.Printf("✅ Wrote %d fixture(s) to %s\n", len(manifest.Files), dir)
+	return nil
+}
+
+// interopResult is the outcome of checking a single fixture file.
+type interopResult struct {
+	File   string
+	Form   vcon.VConForm
+	Status string // "ok", "fail" or "skip"
+	Detail string
+}
+
+func runInteropCheck(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	caPath, _ := cmd.Flags().GetString("ca")
+	keyPath, _ := cmd.Flags().GetString("key")
+
+	var roots *x509.CertPool
+	if caPath != "" {
+		roots = x509.NewCertPool()
+		if !appendPEMToPool(roots, caPath) {
+			return fmt.Errorf("invalid PEM in %s", caPath)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	var results []interopResult
+	for _, f := range files {
+		if filepath.Base(f) == interopManifestName {
+			continue
+		}
+		results = append(results, checkInteropFixture(f, roots, keyPath))
+	}
+
+	failed := 0
+	for _, r := range results {
+		mark := "✅"
+		switch r.Status {
+		case "fail":
+			mark = "❌"
+			failed++
+		case "skip":
+			mark = "⏭️"
+		}
+		fmt.Printf("%s %-40s %-10s %s\n", mark, filepath.Base(r.File), r.Form, r.Detail)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fixture(s) failed", failed, len(results))
+	}
+	return nil
+}
+
+func checkInteropFixture(path string, roots *x509.CertPool, keyPath string) interopResult {
+	res := interopResult{File: path}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		res.Status, res.Detail = "fail", err.Error()
+		return res
+	}
+	obj, err := unwrapEnvelope(raw)
+	if err != nil {
+		res.Status, res.Detail = "fail", err.Error()
+		return res
+	}
+	data, _ := json.Marshal(obj)
+	res.Form, err = vcon.DetectForm(data)
+	if err != nil {
+		res.Status, res.Detail = "fail", err.Error()
+		return res
+	}
+
+	var v *vcon.VCon
+	switch res.Form {
+	case vcon.VConFormUnsigned:
+		v, err = vcon.BuildFromJSON(string(data))
+	case vcon.VConFormSigned:
+		if roots == nil {
+			res.Status, res.Detail = "skip", "no --ca supplied"
+			return res
+		}
+		v, err = (&vcon.SignedVCon{JSON: obj}).Verify(roots)
+	case vcon.VConFormEncrypted:
+		if keyPath == "" || roots == nil {
+			res.Status, res.Detail = "skip", "--key and --ca are required for encrypted fixtures"
+			return res
+		}
+		var plain map[string]any
+		plain, err = (&vcon.EncryptedVCon{JSON: obj}).Decrypt(readDecryptionKey(keyPath))
+		if err == nil {
+			v, err = (&vcon.SignedVCon{JSON: plain}).Verify(roots)
+		}
+	default:
+		err = errors.New("unrecognised vCon form")
+	}
+	if err != nil {
+		res.Status, res.Detail = "fail", err.Error()
+		return res
+	}
+
+	if problems := interopChecks(v); len(problems) > 0 {
+		res.Status, res.Detail = "fail", strings.Join(problems, "; ")
+		return res
+	}
+	res.Status, res.Detail = "ok", v.UUID
+	return res
+}
+
+// interopChecks applies the cross-implementation expectations to a decoded vCon.
+func interopChecks(v *vcon.VCon) []string {
+	var problems []string
+	id, err := uuid.Parse(v.UUID)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("uuid %q: %v", v.UUID, err))
+	} else if id.Version() != 8 {
+		problems = append(problems, fmt.Sprintf("uuid %s is version %d, want 8", v.UUID, id.Version()))
+	}
+	_, issues := v.IsValid()
+	for _, issue := range issues {
+		if issue.Severity == vcon.SeverityError {
+			problems = append(problems, issue.Message)
+		}
+	}
+	return problems
+}
+
+// unwrapEnvelope strips the {"jws": ...} / {"jwe": ...} wrappers some tools
+// (including vconctl encrypt) put around the serialized object.
+func unwrapEnvelope(raw []byte) (map[string]any, error) {
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("parse JSON: %w", err)
+	}
+	for _, key := range []string{"jws", "jwe"} {
+		if inner, ok := m[key].(map[string]any); ok && len(m) == 1 {
+			return inner, nil
+		}
+	}
+	return m, nil
+}
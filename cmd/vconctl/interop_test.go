@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+// writeTestKeyPair writes a freshly generated key and self-signed certificate
+// to dir and returns their paths.
+func writeTestKeyPair(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	priv, certs, err := generateSelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeKeyPEM(t, dir, priv), writeCertPEM(t, dir, certs[0])
+}
+
+func writeKeyPEM(t *testing.T, dir string, priv *rsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writeCertPEM(t *testing.T, dir string, cert *x509.Certificate) string {
+	t.Helper()
+	path := filepath.Join(dir, "cert.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func setFlags(t *testing.T, flags map[string]string, set func(name, value string) error) {
+	t.Helper()
+	for name, value := range flags {
+		if err := set(name, value); err != nil {
+			t.Fatal(err)
+		}
+		name := name
+		t.Cleanup(func() { set(name, "") })
+	}
+}
+
+func TestInteropGenerateAndCheck(t *testing.T) {
+	keyDir := t.TempDir()
+	fixtureDir := filepath.Join(t.TempDir(), "fixtures")
+	keyPath, certPath := writeTestKeyPair(t, keyDir)
+
+	setFlags(t, map[string]string{"key": keyPath, "cert": certPath, "recipient": certPath},
+		interopGenerateCmd.Flags().Set)
+	captureStdout(t, func() {
+		if err := runInteropGenerate(interopGenerateCmd, []string{fixtureDir}); err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+	})
+
+	raw, err := os.ReadFile(filepath.Join(fixtureDir, interopManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest interopManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 3 {
+		t.Fatalf("expected 3 fixtures in manifest, got %v", manifest.Files)
+	}
+
+	setFlags(t, map[string]string{"ca": certPath, "key": keyPath}, interopCheckCmd.Flags().Set)
+	out := captureStdout(t, func() {
+		if err := runInteropCheck(interopCheckCmd, []string{fixtureDir}); err != nil {
+			t.Errorf("check: %v", err)
+		}
+	})
+	for _, name := range []string{"unsigned.vcon.json", "signed.vcon.json", "encrypted.vcon.json"} {
+		if !strings.Contains(out, name) {
+			t.Errorf("expected %s in report, got %q", name, out)
+		}
+	}
+	if strings.Contains(out, "❌") {
+		t.Errorf("unexpected failure in report: %q", out)
+	}
+}
+
+func TestInteropCheckSkipsWithoutTrustAnchor(t *testing.T) {
+	keyDir := t.TempDir()
+	fixtureDir := t.TempDir()
+	keyPath, certPath := writeTestKeyPair(t, keyDir)
+
+	setFlags(t, map[string]string{"key": keyPath, "cert": certPath}, interopGenerateCmd.Flags().Set)
+	captureStdout(t, func() {
+		if err := runInteropGenerate(interopGenerateCmd, []string{fixtureDir}); err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+	})
+
+	res := checkInteropFixture(filepath.Join(fixtureDir, "signed.vcon.json"), nil, "")
+	if res.Status != "skip" {
+		t.Errorf("expected signed fixture to be skipped without --ca, got %s (%s)", res.Status, res.Detail)
+	}
+}
+
+func TestInteropCheckReportsFailures(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.vcon.json")
+	if err := os.WriteFile(bad, []byte(`{"uuid":"not-a-uuid","created_at":"2025-01-01T00:00:00Z","parties":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	captureStdout(t, func() {
+		if err := runInteropCheck(interopCheckCmd, []string{dir}); err == nil {
+			t.Error("expected check to fail for an invalid fixture")
+		}
+	})
+}
+
+// TestInteropExternalFixtures checks fixtures produced by another
+// implementation (e.g. the Python vcon library). Point VCON_INTEROP_DIR at a
+// directory of fixtures and VCON_INTEROP_CA / VCON_INTEROP_KEY at the matching
+// trust anchor and decryption key to run it.
+func TestInteropExternalFixtures(t *testing.T) {
+	dir := os.Getenv("VCON_INTEROP_DIR")
+	if dir == "" {
+		t.Skip("VCON_INTEROP_DIR not set - skipping cross-implementation fixtures")
+	}
+	setFlags(t, map[string]string{
+		"ca":  os.Getenv("VCON_INTEROP_CA"),
+		"key": os.Getenv("VCON_INTEROP_KEY"),
+	}, interopCheckCmd.Flags().Set)
+	out := captureStdout(t, func() {
+		if err := runInteropCheck(interopCheckCmd, []string{dir}); err != nil {
+			t.Errorf("external fixtures: %v", err)
+		}
+	})
+	t.Log(out)
+}
+
+// pythonFixtureDir holds fixtures written by the Python vcon library with
+// generate_fixtures.py, signed by testdata/keys/leaf.key and encrypted to
+// leaf.crt.
+const pythonFixtureDir = "../../testdata/interop/python"
+
+func TestInteropPythonFixtures(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join(pythonFixtureDir, interopManifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skip("no Python fixtures - run testdata/interop/python/generate_fixtures.py")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest interopManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+
+	read := func(form string) map[string]any {
+		t.Helper()
+		name := manifest.Files[form]
+		if name == "" {
+			t.Fatalf("manifest lists no %s fixture", form)
+		}
+		raw, err := os.ReadFile(filepath.Join(pythonFixtureDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj, err := unwrapEnvelope(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return obj
+	}
+
+	roots := x509.NewCertPool()
+	if !appendPEMToPool(roots, "../../testdata/keys/root.crt") {
+		t.Fatal("invalid root certificate")
+	}
+	// The fixture certificates expire; check the chains as of signing.
+	at := vcon.WithVerificationTime(manifest.GeneratedAt)
+
+	data, _ := json.Marshal(read("unsigned"))
+	unsigned, err := vcon.BuildFromJSON(string(data))
+	if err != nil {
+		t.Fatalf("unsigned: %v", err)
+	}
+	signed, err := (&vcon.SignedVCon{JSON: read("signed")}).Verify(roots, at)
+	if err != nil {
+		t.Fatalf("signed: %v", err)
+	}
+	plain, err := (&vcon.EncryptedVCon{JSON: read("encrypted")}).Decrypt(readDecryptionKey("../../testdata/keys/leaf.key"))
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	decrypted, err := (&vcon.SignedVCon{JSON: plain}).Verify(roots, at)
+	if err != nil {
+		t.Fatalf("encrypted: %v", err)
+	}
+
+	for form, v := range map[string]*vcon.VCon{"unsigned": unsigned, "signed": signed, "encrypted": decrypted} {
+		if problems := interopChecks(v); len(problems) > 0 {
+			t.Errorf("%s: %s", form, strings.Join(problems, "; "))
+		}
+		if v.UUID != manifest.UUID {
+			t.Errorf("%s: uuid %s, manifest says %s", form, v.UUID, manifest.UUID)
+		}
+		if len(v.Parties) != 2 || v.Parties[0].Name != "Alice" || v.Parties[0].Tel != "tel:+12025551234" ||
+			v.Parties[1].Name != "Bob" || v.Parties[1].Mailto != "mailto:bob@example.com" {
+			t.Errorf("%s: unexpected parties %+v", form, v.Parties)
+		}
+		if len(v.Dialog) != 1 || v.Dialog[0].Body != "Hello from py-vcon" || v.Dialog[0].MediaType != vcon.MIMETypePlainText {
+			t.Errorf("%s: unexpected dialog %+v", form, v.Dialog)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+// keyRingPassphraseEnv holds the passphrase protecting the keyring file.
+const keyRingPassphraseEnv = "VCONCTL_KEYRING_PASSPHRASE"
+
+// keyRingFileEnv overrides the default keyring location.
+const keyRingFileEnv = "VCONCTL_KEYRING"
+
+// keyRingIterations is the PBES2 (PBKDF2-HMAC-SHA512) iteration count used
+// when saving the keyring.
+var keyRingIterations = 210000
+
+var keyringCmd = &cobra.Command{
+	Use:   "keyring",
+	Short: "Manage the local encrypted keyring of signing and decryption keys",
+	Long: `Manage a local keyring of private keys (and their certificates) referenced
+by alias. sign, seal and decrypt accept --keyring-alias instead of key and
+certificate paths.
+
+The keyring is a JWE file encrypted with PBES2 under the passphrase in
+$` + keyRingPassphraseEnv + `. It lives in the user config directory unless
+--keyring-file or $` + keyRingFileEnv + ` names another file.`,
+}
+
+var keyringAddCmd = &cobra.Command{
+	Use:   "add <alias> --key key.pem [--cert cert.pem]",
+	Short: "Add a private key, and optionally its certificate, under an alias",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runKeyringAdd,
+}
+
+var keyringListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the aliases in the keyring",
+	Args:  cobra.NoArgs,
+	RunE:  runKeyringList,
+}
+
+var keyringRmCmd = &cobra.Command{
+	Use:   "rm <alias>",
+	Short: "Remove an alias from the keyring",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runKeyringRm,
+}
+
+// keyRing is the decrypted content of the keyring file.
+type keyRing struct {
+	Entries map[string]keyRingEntry `json:"entries"`
+}
+
+// keyRingEntry is one aliased key. Key and Cert are PEM encoded.
+type keyRingEntry struct {
+	Key   string    `json:"key"`
+	Cert  string    `json:"cert,omitempty"`
+	Added time.Time `json:"added"`
+}
+
+func runKeyringAdd(cmd *cobra.Command, args []string) error {
+	alias := args[0]
+	keyPath, _ := cmd.Flags().GetString("key")
+	certPath, _ := cmd.Flags().GetString("cert")
+	force, _ := cmd.Flags().GetBool("force")
+	if keyPath == "" {
+		return errors.New("--key is required")
+	}
+
+	entry := keyRingEntry{Added: time.Now().UTC()}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+	key, err := parsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return fmt.Errorf("%s: %w", keyPath, err)
+	}
+	entry.Key = string(keyPEM)
+	if certPath != "" {
+		certPEM, err := os.ReadFile(certPath)
+		if err != nil {
+			return err
+		}
+		entry.Cert = string(certPEM)
+		cert, err := entry.certificate()
+		if err != nil {
+			return fmt.Errorf("%s: %w", certPath, err)
+		}
+		if !keyMatchesCert(key, cert) {
+			return fmt.Errorf("%s does not certify the key in %s", certPath, keyPath)
+		}
+	}
+
+	ring, err := loadKeyRing()
+	if err != nil {
+		return err
+	}
+	if _, exists := ring.Entries[alias]; exists && !force {
+		return fmt.Errorf("alias %q already exists; use --force to replace it", alias)
+	}
+	ring.Entries[alias] = entry
+	if err := ring.save(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Added %q to %s\n", alias, keyRingPath())
+	return nil
+}
+
+func runKeyringList(_ *cobra.Command, _ []string) error {
+	ring, err := loadKeyRing()
+	if err != nil {
+		return err
+	}
+	if len(ring.Entries) == 0 {
+		fmt.Println("Keyring is empty")
+		return nil
+	}
+
+	aliases := make([]string, 0, len(ring.Entries))
+	for alias := range ring.Entries {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	fmt.Printf("%-20s %-44s %-30s %s\n", "ALIAS", "KID", "CERTIFICATE", "ADDED")
+	for _, alias := range aliases {
+		e := ring.Entries[alias]
+		kid := "?"
+		if key, err := e.privateKey(); err == nil {
+			kid, _ = vcon.RecipientKeyID(key.Public())
+		}
+		subject := "-"
+		if cert, err := e.certificate(); err == nil && cert != nil {
+			subject = cert.Subject.CommonName
+		}
+		fmt.Printf("%-20s %-44s %-30s %s\n", alias, kid, subject, e.Added.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func runKeyringRm(_ *cobra.Command, args []string) error {
+	alias := args[0]
+	ring, err := loadKeyRing()
+	if err != nil {
+		return err
+	}
+	if _, ok := ring.Entries[alias]; !ok {
+		return fmt.Errorf("alias %q not found", alias)
+	}
+	delete(ring.Entries, alias)
+	if err := ring.save(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Removed %q\n", alias)
+	return nil
+}
+
+// keyRingPath returns the keyring file location.
+func keyRingPath() string {
+	if keyRingFile != "" {
+		return keyRingFile
+	}
+	if p := os.Getenv(keyRingFileEnv); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "vconctl", "keyring.jwe")
+}
+
+func keyRingPassphrase() ([]byte, error) {
+	pass := os.Getenv(keyRingPassphraseEnv)
+	if pass == "" {
+		return nil, fmt.Errorf("$%s must hold the keyring passphrase", keyRingPassphraseEnv)
+	}
+	return []byte(pass), nil
+}
+
+// loadKeyRing decrypts the keyring file. A missing file is an empty keyring.
+func loadKeyRing() (*keyRing, error) {
+	ring := &keyRing{Entries: map[string]keyRingEntry{}}
+	pass, err := keyRingPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(keyRingPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return ring, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := jose.ParseEncrypted(string(data),
+		[]jose.KeyAlgorithm{jose.PBES2_HS512_A256KW},
+		[]jose.ContentEncryption{jose.A256GCM})
+	if err != nil {
+		return nil, fmt.Errorf("parse keyring: %w", err)
+	}
+	plain, err := obj.Decrypt(pass)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt keyring (wrong passphrase?): %w", err)
+	}
+	if err := json.Unmarshal(plain, ring); err != nil {
+		return nil, fmt.Errorf("decode keyring: %w", err)
+	}
+	if ring.Entries == nil {
+		ring.Entries = map[string]keyRingEntry{}
+	}
+	return ring, nil
+}
+
+// save encrypts the keyring and replaces the file atomically.
+func (r *keyRing) save() error {
+	pass, err := keyRingPassphrase()
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	enc, err := jose.NewEncrypter(jose.A256GCM,
+		jose.Recipient{Algorithm: jose.PBES2_HS512_A256KW, Key: pass, PBES2Count: keyRingIterations}, nil)
+	if err != nil {
+		return err
+	}
+	obj, err := enc.Encrypt(plain)
+	if err != nil {
+		return fmt.Errorf("encrypt keyring: %w", err)
+	}
+	compact, err := obj.CompactSerialize()
+	if err != nil {
+		return err
+	}
+
+	path := keyRingPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return vcon.WriteFileAtomic(path, []byte(compact), 0600)
+}
+
+func (e keyRingEntry) privateKey() (crypto.Signer, error) {
+	return parsePrivateKeyPEM([]byte(e.Key))
+}
+
+// certificate returns the stored certificate, or nil when there is none.
+func (e keyRingEntry) certificate() (*x509.Certificate, error) {
+	if e.Cert == "" {
+		return nil, nil
+	}
+	b, _ := pem.Decode([]byte(e.Cert))
+	if b == nil || b.Type != "CERTIFICATE" {
+		return nil, errors.New("invalid certificate PEM")
+	}
+	return x509.ParseCertificate(b.Bytes)
+}
+
+// keyRingEntryFor looks up alias in the keyring.
+func keyRingEntryFor(alias string) (keyRingEntry, error) {
+	ring, err := loadKeyRing()
+	if err != nil {
+		return keyRingEntry{}, err
+	}
+	e, ok := ring.Entries[alias]
+	if !ok {
+		return keyRingEntry{}, fmt.Errorf("alias %q not found in keyring %s", alias, keyRingPath())
+	}
+	return e, nil
+}
+
+// signingMaterial returns the signing key and certificate named by
+// --keyring-alias, or read from --key and --cert.
+func signingMaterial(cmd *cobra.Command) (crypto.Signer, *x509.Certificate, error) {
+	alias, _ := cmd.Flags().GetString("keyring-alias")
+	if alias == "" {
+		keyPath, _ := cmd.Flags().GetString("key")
+		certPath, _ := cmd.Flags().GetString("cert")
+		if keyPath == "" || certPath == "" {
+			return nil, nil, errors.New("--key and --cert (or --keyring-alias) are required")
+		}
+		return readPrivateKey(keyPath), readCertificate(certPath), nil
+	}
+
+	e, err := keyRingEntryFor(alias)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := e.privateKey()
+	if err != nil {
+		return nil, nil, fmt.Errorf("alias %q: %w", alias, err)
+	}
+	cert, err := e.certificate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("alias %q: %w", alias, err)
+	}
+	if cert == nil {
+		return nil, nil, fmt.Errorf("alias %q has no certificate; add it with --cert to sign", alias)
+	}
+	return key, cert, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/pflag"
+)
+
+// useTestKeyRing points the keyring commands at a temporary file.
+func useTestKeyRing(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keyring.jwe")
+	keyRingFile = path
+	t.Setenv(keyRingPassphraseEnv, "correct horse battery staple")
+	iterations := keyRingIterations
+	keyRingIterations = 1000
+	t.Cleanup(func() { keyRingFile, keyRingIterations = "", iterations })
+	return path
+}
+
+func TestKeyringAddListRm(t *testing.T) {
+	path := useTestKeyRing(t)
+	keyPath, certPath := writeTestKeyPair(t, t.TempDir())
+
+	setFlags(t, map[string]string{"key": keyPath, "cert": certPath}, keyringAddCmd.Flags().Set)
+	captureStdout(t, func() {
+		if err := runKeyringAdd(keyringAddCmd, []string{"prod-signer"}); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	})
+	if err := runKeyringAdd(keyringAddCmd, []string{"prod-signer"}); err == nil {
+		t.Error("expected duplicate alias to be refused without --force")
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "PRIVATE KEY") {
+		t.Error("keyring file stores the key in the clear")
+	}
+
+	out := captureStdout(t, func() {
+		if err := runKeyringList(keyringListCmd, nil); err != nil {
+			t.Fatalf("list: %v", err)
+		}
+	})
+	if !strings.Contains(out, "prod-signer") || !strings.Contains(out, "KID") {
+		t.Errorf("list output %q", out)
+	}
+
+	t.Setenv(keyRingPassphraseEnv, "wrong")
+	if err := runKeyringList(keyringListCmd, nil); err == nil {
+		t.Error("expected wrong passphrase to fail")
+	}
+	t.Setenv(keyRingPassphraseEnv, "correct horse battery staple")
+
+	captureStdout(t, func() {
+		if err := runKeyringRm(keyringRmCmd, []string{"prod-signer"}); err != nil {
+			t.Fatalf("rm: %v", err)
+		}
+	})
+	if err := runKeyringRm(keyringRmCmd, []string{"prod-signer"}); err == nil {
+		t.Error("expected removing a missing alias to fail")
+	}
+}
+
+func TestSealWithKeyringAlias(t *testing.T) {
+	useTestKeyRing(t)
+	keyPath, certPath := writeTestKeyPair(t, t.TempDir())
+	_, recipientCert := writeTestKeyPair(t, t.TempDir())
+
+	setFlags(t, map[string]string{"key": keyPath, "cert": certPath}, keyringAddCmd.Flags().Set)
+	captureStdout(t, func() {
+		if err := runKeyringAdd(keyringAddCmd, []string{"prod-signer"}); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	})
+
+	dir := t.TempDir()
+	v := vcon.New("test.example.com")
+	v.AddParty(vcon.Party{Name: "Alice"})
+	src := filepath.Join(dir, "call.json")
+	if err := os.WriteFile(src, []byte(v.ToJSON()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setFlags(t, map[string]string{"keyring-alias": "prod-signer"}, sealCmd.Flags().Set)
+	if err := sealCmd.Flags().Set("recipient", recipientCert); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sealCmd.Flags().Lookup("recipient").Value.(pflag.SliceValue).Replace(nil) })
+	captureStdout(t, func() {
+		if err := runSeal(sealCmd, []string{src}); err != nil {
+			t.Fatalf("seal with alias: %v", err)
+		}
+	})
+	if _, err := os.Stat(filepath.Join(dir, "call.encrypted.json")); err != nil {
+		t.Error(err)
+	}
+
+	sealCmd.Flags().Set("keyring-alias", "unknown")
+	if err := runSeal(sealCmd, []string{src}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected unknown alias error, got %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -10,8 +13,11 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/go-jose/go-jose/v4"
 	"github.com/spf13/cobra"
 )
 
@@ -19,25 +25,39 @@ import (
 
 var genkeyCmd = &cobra.Command{
 	Use:   "genkey",
-	Short: "Generate a test RSA key pair and self-signed certificate",
+	Short: "Generate a test RSA or ECDSA key pair and self-signed certificate",
 	Run: func(cmd *cobra.Command, args []string) {
 		keyPath, _ := cmd.Flags().GetString("key")
 		certPath, _ := cmd.Flags().GetString("cert")
+		curve, _ := cmd.Flags().GetString("curve")
 		if keyPath == "" {
 			keyPath = "test_key.pem"
 		}
 		if certPath == "" {
 			certPath = "test_cert.pem"
 		}
-		generateKeyPair(keyPath, certPath)
+		generateKeyPair(keyPath, certPath, curve)
 	},
 }
 
-func generateKeyPair(keyPath, certPath string) {
-	fmt.Printf("Generating RSA key pair and certificate…\n")
-
-	// Generate RSA private key
-	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+// generateKeyPair writes an RSA key, or an ECDSA key on curve when one is
+// named, with a self-signed certificate.
+func generateKeyPair(keyPath, certPath, curve string) {
+	keyUsage := x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
+	var priv crypto.Signer
+	var err error
+	if curve == "" {
+		fmt.Printf("Generating RSA key pair and certificate…\n")
+		priv, err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		c, ok := ecdsaCurves[strings.ToUpper(curve)]
+		if !ok {
+			die("generating private key", fmt.Errorf("unsupported curve %q (want P-256 or P-384)", curve))
+		}
+		fmt.Printf("Generating ECDSA %s key pair and certificate…\n", c.Params().Name)
+		priv, err = ecdsa.GenerateKey(c, rand.Reader)
+		keyUsage = x509.KeyUsageDigitalSignature
+	}
 	if err != nil {
 		die("generating private key", err)
 	}
@@ -63,13 +83,13 @@ func generateKeyPair(keyPath, certPath string) {
 		},
 		NotBefore:             notBefore,
 		NotAfter:              notAfter,
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:              keyUsage,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
 	}
 
 	// Create self-signed certificate
-	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, priv.Public(), priv)
 	if err != nil {
 		die("creating certificate", err)
 	}
@@ -90,13 +110,20 @@ func generateKeyPair(keyPath, certPath string) {
 		Bytes: certDER,
 	})
 
+	// Check both outputs first so a key is never written without its certificate
+	for _, p := range []string{keyPath, certPath} {
+		if err := checkOutput(p); err != nil {
+			die("writing key pair", err)
+		}
+	}
+
 	// Write private key to file
-	if err := os.WriteFile(keyPath, privKeyPEM, 0600); err != nil {
+	if err := writeOutputFile(keyPath, privKeyPEM, 0600); err != nil {
 		die("writing private key", err)
 	}
 
 	// Write certificate to file
-	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+	if err := writeOutputFile(certPath, certPEM, 0644); err != nil {
 		die("writing certificate", err)
 	}
 
@@ -123,37 +150,141 @@ func writeJSON(path string, v any) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return writeOutputFile(path, data, 0644)
 }
 
-func readPrivateKey(p string) *rsa.PrivateKey {
+// ecdsaCurves are the curves genkey --curve accepts.
+var ecdsaCurves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+}
+
+func readPrivateKey(p string) crypto.Signer {
 	raw, err := os.ReadFile(p)
 	if err != nil {
 		die("reading private key", err)
 	}
+	k, err := parsePrivateKeyPEM(raw)
+	if err != nil {
+		die("private key", err)
+	}
+	return k
+}
+
+// readDecryptionKey reads an RSA private key; vCons are encrypted with
+// RSA-OAEP only.
+func readDecryptionKey(p string) *rsa.PrivateKey {
+	k, ok := readPrivateKey(p).(*rsa.PrivateKey)
+	if !ok {
+		die("private key", fmt.Errorf("%s is not an RSA key, which decryption needs", p))
+	}
+	return k
+}
+
+// parsePrivateKeyPEM parses an RSA or ECDSA private key in PKCS#1, SEC 1 or
+// PKCS#8 form.
+func parsePrivateKeyPEM(raw []byte) (crypto.Signer, error) {
 	b, _ := pem.Decode(raw)
 	if b == nil {
-		die("decoding PEM", fmt.Errorf("no block found"))
+		return nil, fmt.Errorf("no PEM block found")
 	}
 
 	switch b.Type {
 	case "RSA PRIVATE KEY":
 		k, err := x509.ParsePKCS1PrivateKey(b.Bytes)
 		if err != nil {
-			die("PKCS1 parse", err)
+			return nil, fmt.Errorf("PKCS1 parse: %w", err)
+		}
+		return k, nil
+	case "EC PRIVATE KEY":
+		k, err := x509.ParseECPrivateKey(b.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("SEC1 parse: %w", err)
 		}
-		return k
+		return k, nil
 	case "PRIVATE KEY":
 		k, err := x509.ParsePKCS8PrivateKey(b.Bytes)
 		if err != nil {
-			die("PKCS8 parse", err)
+			return nil, fmt.Errorf("PKCS8 parse: %w", err)
+		}
+		switch k := k.(type) {
+		case *rsa.PrivateKey:
+			return k, nil
+		case *ecdsa.PrivateKey:
+			return k, nil
+		}
+		return nil, fmt.Errorf("unsupported PKCS8 key type %T", k)
+	}
+	return nil, fmt.Errorf("unsupported key type %q", b.Type)
+}
+
+// describeKey names the type and size of a key, e.g. "RSA 2048-bit" or
+// "ECDSA P-256".
+func describeKey(key crypto.Signer) string {
+	switch pub := key.Public().(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d-bit", pub.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + pub.Curve.Params().Name
+	}
+	return fmt.Sprintf("%T", key)
+}
+
+// keyMatchesCert reports whether cert certifies the public half of key.
+func keyMatchesCert(key crypto.Signer, cert *x509.Certificate) bool {
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(cert.PublicKey)
+}
+
+// readKeyRingDir loads every PEM private key (*.pem, *.key) in dir. Files
+// holding something else, such as certificates, are skipped.
+func readKeyRingDir(dir string) ([]jose.JSONWebKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []jose.JSONWebKey
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".pem" && ext != ".key") {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
 		}
-		if rsaK, ok := k.(*rsa.PrivateKey); ok {
-			return rsaK
+		k, err := parsePrivateKeyPEM(raw)
+		if err != nil {
+			continue
 		}
+		keys = append(keys, jose.JSONWebKey{Key: k, KeyID: strings.TrimSuffix(e.Name(), ext)})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no private keys found in %s", dir)
+	}
+	return keys, nil
+}
+
+// readJWKS loads the private keys of a JSON Web Key Set file.
+func readJWKS(path string) ([]jose.JSONWebKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set jose.JSONWebKeySet
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("parse JWKS %s: %w", path, err)
+	}
+	var keys []jose.JSONWebKey
+	for _, k := range set.Keys {
+		if !k.IsPublic() {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no private keys found in %s", path)
 	}
-	die("private key", fmt.Errorf("unsupported key type %q", b.Type))
-	return nil
+	return keys, nil
 }
 
 func readCertificate(p string) *x509.Certificate {
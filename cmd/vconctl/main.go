@@ -13,28 +13,45 @@ import (
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "vconctl",
-	Short: "vconctl - a tool for working with vCon files",
-	Long:  `vconctl is a command-line utility for validating, signing, encrypting, verifying, and decrypting vCon (Virtual Conversation) files.`,
+	Use:               "vconctl",
+	Short:             "vconctl - a tool for working with vCon files",
+	Long:              `vconctl is a command-line utility for validating, signing, encrypting, verifying, and decrypting vCon (Virtual Conversation) files.`,
+	PersistentPreRunE: configure,
 }
 
 var (
-	audioInput   string
+	audioInputs  []string
+	audioMixed   string
 	audioParties []string
 	audioDate    string
 	vConOut      string
+	emailGroupBy string
 
 	// Global domain flag for UUID generation
 	globalDomain string
+
+	// Global HTTP client flags, applied with vcon.SetHTTPConfig
+	httpFlags vcon.HTTPConfig
+
+	// Global size limits enforced by validate and the converters
+	sizeLimits vcon.Limits
+
+	// Keyring file used by the keyring commands and --keyring-alias
+	keyRingFile string
 )
 
 var convertCmd = &cobra.Command{
 	Use:   "convert",
-	Short: "Convert external artefacts (audio, Zoom, email) into vCon containers",
+	Short: "Convert external artefacts (audio, Zoom, email, mbox, calendar invites, JSON exports) into vCon containers",
 }
 
 func main() {
-	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	if ran, code, err := runPlugin(os.Args[1:]); ran {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(code)
+	}
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -42,35 +59,183 @@ func main() {
 }
 
 func init() {
-	rootCmd.AddCommand(validateCmd, signCmd, encryptCmd, verifyCmd, decryptCmd, genkeyCmd, convertCmd, detectCmd)
-	convertCmd.AddCommand(audioCmd, zoomCmd, emailCmd)
+	rootCmd.AddCommand(validateCmd, signCmd, encryptCmd, sealCmd, verifyCmd, decryptCmd, genkeyCmd, convertCmd, detectCmd, interopCmd, conformanceCmd, debugCmd, keyringCmd, reviewCmd, exportCmd, sliceCmd, docsCmd, pluginCmd, migrateCmd, archiveCmd, resignCmd, doctorCmd)
+	convertCmd.AddCommand(audioCmd, zoomCmd, emailCmd, mboxCmd, icsCmd, genericJSONCmd)
+	interopCmd.AddCommand(interopGenerateCmd, interopCheckCmd)
+	debugCmd.AddCommand(debugCanonicalCmd)
+	keyringCmd.AddCommand(keyringAddCmd, keyringListCmd, keyringRmCmd)
+	reviewCmd.AddCommand(reviewSetCmd)
+	docsCmd.AddCommand(docsManCmd, docsMarkdownCmd)
+	pluginCmd.AddCommand(pluginListCmd)
+	archiveCmd.AddCommand(archiveCreateCmd, archiveVerifyCmd)
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&globalDomain, "domain", "vcon.example.com", "Domain name for UUID generation")
+	rootCmd.PersistentFlags().StringVar(&httpFlags.UserAgent, "user-agent", vcon.DefaultUserAgent, "User-Agent for HTTP requests")
+	rootCmd.PersistentFlags().StringVar(&httpFlags.Proxy, "proxy", "", "HTTP(S) proxy URL (default: HTTP_PROXY/HTTPS_PROXY environment)")
+	rootCmd.PersistentFlags().StringVar(&httpFlags.CAFile, "ca-bundle", "", "PEM bundle of additional CAs trusted for HTTPS")
+	rootCmd.PersistentFlags().StringVar(&httpFlags.ClientCertFile, "client-cert", "", "PEM client certificate for mutual TLS")
+	rootCmd.PersistentFlags().StringVar(&httpFlags.ClientKeyFile, "client-key", "", "PEM client key for mutual TLS")
+	rootCmd.PersistentFlags().DurationVar(&httpFlags.Timeout, "http-timeout", 0, "Timeout for each HTTP request (0 = none)")
+	rootCmd.PersistentFlags().IntVar(&sizeLimits.MaxInlineBodyBytes, "max-inline-body", 0, "Maximum bytes of any inline body (0 = no limit)")
+	rootCmd.PersistentFlags().IntVar(&sizeLimits.MaxVConBytes, "max-vcon-size", 0, "Maximum bytes of a serialized vCon (0 = no limit)")
+	rootCmd.PersistentFlags().BoolVar(&forceOverwrite, "force", false, "Overwrite existing output files")
+	rootCmd.PersistentFlags().BoolVar(&backupOriginals, "backup", false, "Keep each replaced file as <file>.bak")
+	rootCmd.PersistentFlags().StringVar(&fileModeFlag, "file-mode", "", "Octal mode of written files (default 644; private keys keep only the owner bits)")
+	rootCmd.PersistentFlags().StringVar(&umaskFlag, "umask", "", "Octal permission bits cleared from every written file, e.g. 077")
+	rootCmd.PersistentFlags().StringVar(&chownFlag, "chown", "", "Owner of written files as user[:group], names or numeric ids")
+	rootCmd.PersistentFlags().StringVar(&keyRingFile, "keyring-file", "", "Keyring file (default: $VCONCTL_KEYRING or <config dir>/vconctl/keyring.jwe)")
 
 	// flags
-	signCmd.Flags().StringP("key", "k", "", "Path to private key file (required)")
-	signCmd.Flags().StringP("cert", "c", "", "Path to certificate file (required)")
+	validateCmd.Flags().Bool("strict-reject", false, "Reject files with non-standard properties and exit non-zero")
+	validateCmd.Flags().Bool("report-unknown", false, "Warn about each non-standard property instead of dropping it silently")
+	validateCmd.Flags().Bool("strict-uris", false, "Fail on malformed party tel and mailto URIs instead of warning")
+	validateCmd.Flags().Bool("check-durations", false, "Measure recordings with ffprobe and fail when a declared duration does not match")
+	validateCmd.Flags().Float64("duration-tolerance", 1, "Seconds a declared duration may differ from the media with --check-durations")
+	validateCmd.Flags().Bool("verify-content", false, "Fail when inline or downloaded external content does not match its content_hash")
+	validateCmd.Flags().String("schema", "", "Also check files against this JSON Schema (path or URL), e.g. an organization profile")
+
+	signCmd.Flags().StringP("key", "k", "", "Path to private key file (required unless --keyring-alias)")
+	signCmd.Flags().StringP("cert", "c", "", "Path to certificate file (required unless --keyring-alias)")
+	signCmd.Flags().String("keyring-alias", "", "Keyring alias holding the signing key and certificate")
 	signCmd.Flags().StringP("output", "o", "", "Path to output file (defaults to <file>.signed.json)")
+	signCmd.Flags().Bool("dry-run", false, "Report what would be written without writing it")
 
 	encryptCmd.Flags().StringP("cert", "c", "", "Path to recipient certificate (required)")
 	encryptCmd.Flags().StringP("output", "o", "", "Path to output file (defaults to <file>.encrypted.json)")
+	encryptCmd.Flags().Bool("dry-run", false, "Report what would be written without writing it")
+
+	sealCmd.Flags().StringP("key", "k", "", "Path to signing private key (required unless --keyring-alias)")
+	sealCmd.Flags().StringP("cert", "c", "", "Path to signing certificate (required unless --keyring-alias)")
+	sealCmd.Flags().String("keyring-alias", "", "Keyring alias holding the signing key and certificate")
+	sealCmd.Flags().StringArray("recipient", nil, "Path to recipient certificate (required, repeatable)")
+	sealCmd.Flags().StringP("output", "o", "", "Path to output file (defaults to <file>.encrypted.json)")
+	sealCmd.Flags().Bool("dry-run", false, "Report what would be written without writing it")
 
 	verifyCmd.Flags().StringP("cert", "c", "", "Path to trust anchor (leaf or CA) (required)")
+	verifyCmd.Flags().StringSlice("alg", nil, "Accepted signature algorithms, e.g. RS256,ES256 (default: all supported)")
+	verifyCmd.Flags().StringSlice("require-header", nil, "JWS header parameters every signature must carry, e.g. uuid,kid")
+	verifyCmd.Flags().Bool("skip-uuid-check", false, "Do not compare a uuid header with the vCon uuid")
 
-	decryptCmd.Flags().StringP("key", "k", "", "Path to private key file (required)")
+	decryptCmd.Flags().StringP("key", "k", "", "Path to private key file")
+	decryptCmd.Flags().String("keyring-alias", "", "Keyring alias holding the private key")
+	decryptCmd.Flags().String("keyring", "", "Directory of PEM private keys; the key is chosen by recipient kid")
+	decryptCmd.Flags().String("jwks", "", "JSON Web Key Set of private keys; the key is chosen by recipient kid")
 	decryptCmd.Flags().StringP("output", "o", "", "Path to output file (defaults to <file>.decrypted.json)")
 
 	genkeyCmd.Flags().StringP("key", "k", "", "Output private-key path (default: test_key.pem)")
 	genkeyCmd.Flags().StringP("cert", "c", "", "Output certificate path (default: test_cert.pem)")
+	genkeyCmd.Flags().String("curve", "", "Generate an ECDSA key on this curve (P-256 or P-384) instead of RSA")
+
+	convertCmd.PersistentFlags().BoolVar(&convertProvenance, "provenance", false, "Attach a build provenance record (tool version, source hashes, steps) to each vCon")
+	convertCmd.PersistentFlags().StringVar(&convertDialogData.Campaign, "campaign", "", "Campaign of every dialog (CC extension)")
+	convertCmd.PersistentFlags().StringVar(&convertDialogData.InteractionType, "interaction-type", "", "Interaction type of every dialog, e.g. inbound (CC extension)")
+	convertCmd.PersistentFlags().StringVar(&convertDialogData.InteractionID, "interaction-id", "", "Interaction ID of every dialog (CC extension)")
+	convertCmd.PersistentFlags().StringVar(&convertDialogData.Skill, "skill", "", "Routing skill of every dialog (CC extension)")
+	convertCmd.PersistentFlags().StringVar(&convertClamd, "clamd", "", "Scan attachments with the ClamAV daemon at host:port or /unix/socket")
+	convertCmd.PersistentFlags().StringVar(&convertOnThreat, "on-threat", "reject", "What to do with a malicious attachment: reject or quarantine")
+	convertCmd.PersistentFlags().StringVar(&convertQuarantineDir, "quarantine-dir", "", "Directory receiving quarantined attachments")
 
-	audioCmd.Flags().StringVar(&audioInput, "input", "", "Path or URL to recording (required)")
-	audioCmd.Flags().StringArrayVar(&audioParties, "party", nil, "Party spec 'name,tel:+1555...' or 'name,mailto:bob@a.b'")
-	audioCmd.Flags().StringVar(&audioDate, "date", "", "Recording start (RFC3339); default file mtime")
+	audioCmd.Flags().StringArrayVar(&audioInputs, "input", nil, "Path or URL to recording (required, repeatable: one per party)")
+	audioCmd.Flags().StringVar(&audioMixed, "mixed", "", "Mixed recording; per-party --input files become channel attachments")
+	audioCmd.Flags().StringArrayVar(&audioParties, "party", nil, "Party spec 'name,tel:+1555...[,role]' or 'name,mailto:bob@a.b[,role]'")
+	audioCmd.Flags().StringVar(&audioDate, "date", "", "Recording start (RFC3339); default media creation time, then file mtime")
 	audioCmd.Flags().StringVarP(&vConOut, "output", "o", "", "Output vCon (default: <rec>.json)")
 	audioCmd.MarkFlagRequired("input")
 
-	emailCmd.Flags().StringVarP(&vConOut, "output", "o", "", "Output vCon (default: <file>.json)")
+	emailCmd.Flags().StringVarP(&vConOut, "output", "o", "", "Output vCon, or directory when several are produced (default: <file>.json)")
+	emailCmd.Flags().StringVar(&emailGroupBy, "group-by", emailGroupByMessage, "One vCon per 'message' or per 'thread'")
+	mboxCmd.Flags().StringVarP(&vConOut, "output", "o", "", "Output vCon, or directory when several are produced (default: <file>-<n>.vcon.json)")
+	mboxCmd.Flags().StringVar(&emailGroupBy, "group-by", emailGroupByMessage, "One vCon per 'message' or per 'thread'")
+
+	icsCmd.Flags().StringVarP(&vConOut, "output", "o", "", "Output vCon (default: <file>.vcon.json)")
+
+	genericJSONCmd.Flags().String("mapping", "", "JSON mapping file of JMESPath expressions (required)")
+	genericJSONCmd.Flags().StringVarP(&vConOut, "output", "o", "", "Output vCon, or directory when several are produced (default: <file>.vcon.json)")
+	genericJSONCmd.MarkFlagRequired("mapping")
+
+	interopGenerateCmd.Flags().StringP("key", "k", "", "Private key used to sign the fixture")
+	interopGenerateCmd.Flags().StringP("cert", "c", "", "Certificate embedded in the signature")
+	interopGenerateCmd.Flags().String("recipient", "", "Recipient certificate used to encrypt the signed fixture")
+	interopCheckCmd.Flags().String("ca", "", "Trust anchor for signed/encrypted fixtures")
+	interopCheckCmd.Flags().StringP("key", "k", "", "Private key for encrypted fixtures")
+
+	conformanceCmd.Flags().String("format", "table", "Report format: table or json")
+
+	keyringAddCmd.Flags().StringP("key", "k", "", "Path to private key file (required)")
+	keyringAddCmd.Flags().StringP("cert", "c", "", "Path to the key's certificate (needed for signing)")
+	keyringAddCmd.Flags().Bool("force", false, "Replace an existing alias")
+
+	reviewSetCmd.Flags().Int("analysis", 0, "Index of the analysis entry")
+	reviewSetCmd.Flags().String("status", "", "Review status: auto, human-reviewed or disputed (required)")
+	reviewSetCmd.Flags().String("reviewer", "", "Who reviewed the analysis")
+	reviewSetCmd.Flags().String("note", "", "Free-text review note")
+	reviewSetCmd.Flags().Float64("confidence", 0, "Also set the confidence score (0 to 1)")
+	reviewSetCmd.Flags().StringP("output", "o", "", "Path to output file (defaults to rewriting <file>)")
+	reviewSetCmd.Flags().Bool("dry-run", false, "Report the changes without writing them")
+	reviewSetCmd.MarkFlagRequired("status")
+
+	exportCmd.Flags().String("profile", "", "Export profile: anonymized (required)")
+	exportCmd.Flags().String("pseudonym-key", "", "File holding a secret key for stable pseudonyms across vCons")
+	exportCmd.Flags().Float64("pitch-shift", 0, "Keep audio, shifted by this many semitones with ffmpeg")
+	exportCmd.Flags().StringP("output", "o", "", "Path to output file (defaults to <file>.<profile>.json)")
+	exportCmd.Flags().Bool("dry-run", false, "Report the changes without writing them")
+	exportCmd.MarkFlagRequired("profile")
+
+	sliceCmd.Flags().String("from", "", "Start of the window: RFC3339 time or offset from the first dialog, e.g. 5m (required)")
+	sliceCmd.Flags().String("to", "", "End of the window: RFC3339 time or offset from the first dialog (required)")
+	sliceCmd.Flags().Bool("trim-media", false, "Cut recordings straddling the window with ffmpeg")
+	sliceCmd.Flags().StringP("output", "o", "", "Path to output file (defaults to <file>.slice.json)")
+	sliceCmd.Flags().Bool("dry-run", false, "Report the changes without writing them")
+	sliceCmd.MarkFlagRequired("from")
+	sliceCmd.MarkFlagRequired("to")
+
+	migrateCmd.Flags().String("to", "", "Target spec version (default: "+vcon.SpecVersion+")")
+	migrateCmd.Flags().StringP("output", "o", "", "Path to output file (defaults to rewriting <file>)")
+	migrateCmd.Flags().Bool("dry-run", false, "Report the changes without writing them")
+
+	archiveCreateCmd.Flags().StringP("output", "o", "", "Path to the archive: .tar, .tar.gz, .tgz or .zip (required)")
+	archiveCreateCmd.Flags().StringP("key", "k", "", "Path to the private key signing the manifest (required unless --keyring-alias)")
+	archiveCreateCmd.Flags().StringP("cert", "c", "", "Path to the certificate for --key (required unless --keyring-alias)")
+	archiveCreateCmd.Flags().String("keyring-alias", "", "Keyring alias holding the signing key and certificate")
+	archiveCreateCmd.Flags().String("previous", "", "Earlier archive this one follows in the chain of custody")
+
+	archiveVerifyCmd.Flags().String("ca", "", "Path to trust anchor (leaf or CA) for the manifest signature (required)")
+	archiveVerifyCmd.Flags().Bool("vcons", false, "Also verify the signature on every archived vCon against --ca")
+	archiveVerifyCmd.Flags().String("previous", "", "Earlier archive the manifest must link to")
+
+	resignCmd.Flags().String("old-ca", "", "Path to the old trust anchor every vCon must verify against (required)")
+	resignCmd.Flags().StringP("key", "k", "", "Path to the new private key (required unless --keyring-alias)")
+	resignCmd.Flags().StringP("cert", "c", "", "Path to the new certificate (required unless --keyring-alias)")
+	resignCmd.Flags().String("keyring-alias", "", "Keyring alias holding the new signing key and certificate")
+	resignCmd.Flags().String("mode", resignModeReplace, "replace the old signature or append the new one")
+	resignCmd.Flags().String("at", "", "Verify old signatures as of this RFC3339 time, e.g. before the old certificate expired")
+	resignCmd.Flags().String("log", "", "Also write the per-file results to this JSON Lines file")
+	resignCmd.Flags().Bool("dry-run", false, "Report what would be re-signed without writing it")
+	resignCmd.MarkFlagRequired("old-ca")
+
+	doctorCmd.Flags().StringP("key", "k", "", "Private key to check")
+	doctorCmd.Flags().StringP("cert", "c", "", "Certificate to check, and match against --key")
+	doctorCmd.Flags().String("keyring-alias", "", "Keyring alias that must hold a usable signing key and certificate")
+	doctorCmd.Flags().StringArray("endpoint", nil, "URL that must be reachable, e.g. a collector (repeatable)")
+	doctorCmd.Flags().String("clamd", "", "ClamAV daemon to ping, as for convert --clamd")
+}
+
+// configure applies the global flags before every command.
+func configure(cmd *cobra.Command, args []string) error {
+	if err := configureHTTP(cmd, args); err != nil {
+		return err
+	}
+	return configureOutput(cmd, args)
+}
+
+// configureHTTP installs the global HTTP flags for every network call made by
+// the command.
+func configureHTTP(_ *cobra.Command, _ []string) error {
+	if err := vcon.SetHTTPConfig(httpFlags); err != nil {
+		return fmt.Errorf("http configuration: %w", err)
+	}
+	return nil
 }
 
 func die(context string, err error) {
@@ -79,9 +244,16 @@ func die(context string, err error) {
 }
 
 func parseParty(spec string) *vcon.Party {
-	parts := strings.SplitN(spec, ",", 2)
+	p, _ := parsePartyWithRole(spec)
+	return p
+}
+
+// parsePartyWithRole parses a party spec "name[,address[,role]]" and returns
+// the party together with its (possibly empty) role.
+func parsePartyWithRole(spec string) (*vcon.Party, string) {
+	parts := strings.SplitN(spec, ",", 3)
 	p := &vcon.Party{Name: parts[0]}
-	if len(parts) == 2 {
+	if len(parts) >= 2 {
 		addr := parts[1]
 		switch {
 		case strings.HasPrefix(addr, "tel:"):
@@ -94,7 +266,11 @@ func parseParty(spec string) *vcon.Party {
 			p.Did = addr
 		}
 	}
-	return p
+	var role string
+	if len(parts) == 3 {
+		role = strings.TrimSpace(parts[2])
+	}
+	return p, role
 }
 
 func getDate(flag, path string) time.Time {
@@ -109,10 +285,38 @@ func getDate(flag, path string) time.Time {
 	return time.Now()
 }
 
+// writeVconFiles writes the vCons produced from src. A single vCon is written
+// like writeVconFile; several are written as <src>-<n>.vcon.json, into the
+// out directory if one was given.
+func writeVconFiles(vcons []*vcon.VCon, out, src string) error {
+	if len(vcons) == 1 {
+		return writeVconFile(vcons[0], out, src)
+	}
+
+	dir := filepath.Dir(src)
+	if out != "" {
+		dir = out
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	base := strings.TrimSuffix(filepath.Base(src), filepath.Ext(src))
+	for i, v := range vcons {
+		if err := writeVconFile(v, filepath.Join(dir, fmt.Sprintf("%s-%d.vcon.json", base, i+1)), src); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("✅ Wrote %d vCon(s) to %s\n", len(vcons), dir)
+	return nil
+}
+
 func writeVconFile(v *vcon.VCon, out, src string) error {
+	if err := v.CheckLimits(sizeLimits); err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
 	if out == "" {
 		out = strings.TrimSuffix(src, filepath.Ext(src)) + ".vcon.json"
 	}
 	blob, _ := json.MarshalIndent(v, "", "  ")
-	return os.WriteFile(out, blob, 0644)
+	return writeOutputFile(out, blob, 0644)
 }
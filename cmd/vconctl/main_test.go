@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -163,8 +165,8 @@ func TestWriteVconFile(t *testing.T) {
 		{
 			name:        "default output path from eml",
 			out:         "",
-			src:         tmpDir + "/test.eml",
-			expectedOut: tmpDir + "/test.vcon.json",
+			src:         tmpDir + "/mail.eml",
+			expectedOut: tmpDir + "/mail.vcon.json",
 		},
 	}
 
@@ -193,6 +195,109 @@ func TestWriteVconFile(t *testing.T) {
 	}
 }
 
+func TestWriteOutputFile(t *testing.T) {
+	t.Cleanup(func() { forceOverwrite, backupOriginals = false, false })
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+
+	if err := writeOutputFile(path, []byte("first"), 0644); err != nil {
+		t.Fatalf("write new file: %v", err)
+	}
+	if err := writeOutputFile(path, []byte("second"), 0644); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected an existing output to be refused, got %v", err)
+	}
+
+	forceOverwrite, backupOriginals = true, true
+	if err := writeOutputFile(path, []byte("second"), 0644); err != nil {
+		t.Fatalf("write with --force: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "second" {
+		t.Errorf("content = %q", got)
+	}
+	if got, _ := os.ReadFile(path + ".bak"); string(got) != "first" {
+		t.Errorf("backup = %q", got)
+	}
+
+	// Only the output and its backup remain: no temporary files.
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("unexpected files in %s: %v", dir, entries)
+	}
+}
+
+func TestOutputPermissions(t *testing.T) {
+	t.Cleanup(func() {
+		fileModeFlag, umaskFlag, chownFlag = "", "", ""
+		outputPerms = outputPermissions{uid: -1, gid: -1}
+	})
+	dir := t.TempDir()
+
+	fileModeFlag, umaskFlag = "640", "007"
+	chownFlag = strconv.Itoa(os.Getuid()) + ":" + strconv.Itoa(os.Getgid())
+	if err := configureOutput(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	vconPath, keyPath := filepath.Join(dir, "call.json"), filepath.Join(dir, "key.pem")
+	if err := writeOutputFile(vconPath, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeOutputFile(keyPath, []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]os.FileMode{vconPath: 0640, keyPath: 0600} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != want {
+			t.Errorf("%s mode = %o, want %o", filepath.Base(path), fi.Mode().Perm(), want)
+		}
+	}
+
+	fileModeFlag, umaskFlag, chownFlag = "", "077", ""
+	if err := configureOutput(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if perm := outputPerms.filePerm(0644); perm != 0600 {
+		t.Errorf("umask 077: mode = %o, want 600", perm)
+	}
+
+	// A replaced file keeps its mode, less the --umask bits.
+	if err := os.Chmod(vconPath, 0664); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceFile(vconPath, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(vconPath); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Errorf("replaced with umask 077: mode = %o, want 600", fi.Mode().Perm())
+	}
+	fileModeFlag, umaskFlag = "", ""
+	if err := configureOutput(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(vconPath, 0664); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceFile(vconPath, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(vconPath); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0664 {
+		t.Errorf("replaced: mode = %o, want 664", fi.Mode().Perm())
+	}
+
+	for _, tc := range []struct{ mode, umask, owner string }{{"999", "", ""}, {"", "1777", ""}, {"", "", ":"}, {"", "", "no-such-user-vconctl"}} {
+		fileModeFlag, umaskFlag, chownFlag = tc.mode, tc.umask, tc.owner
+		if err := configureOutput(nil, nil); err == nil {
+			t.Errorf("expected %+v to be rejected", tc)
+		}
+	}
+}
+
 func TestFetchIfRemote(t *testing.T) {
 	// Create a temporary file for local test
 	tmpFile, err := os.CreateTemp("", "test_local")
@@ -246,3 +351,15 @@ func TestFetchIfRemote(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePartyWithRole(t *testing.T) {
+	p, role := parsePartyWithRole("Alice,tel:+15551234567,caller")
+	if p.Name != "Alice" || p.Tel != "tel:+15551234567" || role != "caller" {
+		t.Errorf("unexpected result: %+v role=%q", p, role)
+	}
+
+	p, role = parsePartyWithRole("Bob,mailto:bob@example.com")
+	if p.Mailto != "mailto:bob@example.com" || role != "" {
+		t.Errorf("unexpected result: %+v role=%q", p, role)
+	}
+}
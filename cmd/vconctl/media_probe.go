@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// Formats probeHeaders understands, named as ffprobe names them.
+var headerProbeFormats = []string{"wav", "mp3", "ogg"}
+
+// ffprobeAvailable reports whether ffprobe is on the PATH. Without it
+// probeMedia falls back to probeHeaders.
+func ffprobeAvailable() bool {
+	_, err := exec.LookPath("ffprobe")
+	return err == nil
+}
+
+// probeHeaders reads the duration and format of a WAV, MP3 or Ogg
+// (Vorbis or Opus) file from its headers, for systems without ffprobe. It
+// finds no creation time.
+func probeHeaders(path string) (*mediaInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, 12)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	var (
+		format   string
+		duration float64
+	)
+	switch {
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		format = "wav"
+		duration, err = wavDuration(f)
+	case bytes.HasPrefix(head, []byte("OggS")):
+		format = "ogg"
+		duration, err = oggDuration(f, fi.Size())
+	case bytes.HasPrefix(head, []byte("ID3")) || (len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0):
+		format = "mp3"
+		duration, err = mp3Duration(f, fi.Size())
+	default:
+		return nil, fmt.Errorf("ffprobe not found and %s is not a WAV, MP3 or Ogg file; install ffprobe to read other formats", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", format, err)
+	}
+	return &mediaInfo{DurationSeconds: duration, FormatName: format}, nil
+}
+
+// wavDuration walks the RIFF chunks after the WAVE header and divides the
+// size of the data chunk by the byte rate of the fmt chunk.
+func wavDuration(f *os.File) (float64, error) {
+	if _, err := f.Seek(12, io.SeekStart); err != nil {
+		return 0, err
+	}
+	var byteRate uint32
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(f, hdr[:]); err != nil {
+			return 0, errors.New("no data chunk")
+		}
+		id, size := string(hdr[:4]), binary.LittleEndian.Uint32(hdr[4:])
+		switch id {
+		case "fmt ":
+			var fmtChunk [16]byte
+			if size < 16 {
+				return 0, errors.New("short fmt chunk")
+			}
+			if _, err := io.ReadFull(f, fmtChunk[:]); err != nil {
+				return 0, err
+			}
+			byteRate = binary.LittleEndian.Uint32(fmtChunk[8:12])
+			size -= 16
+		case "data":
+			if byteRate == 0 {
+				return 0, errors.New("data chunk before a valid fmt chunk")
+			}
+			if size == 0xFFFFFFFF {
+				// Streamed WAV: the data runs to the end of the file.
+				pos, _ := f.Seek(0, io.SeekCurrent)
+				end, _ := f.Seek(0, io.SeekEnd)
+				size = uint32(end - pos)
+			}
+			return float64(size) / float64(byteRate), nil
+		}
+		if _, err := f.Seek(int64(size)+int64(size&1), io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// MPEG audio bitrates in kbps by [version 1 or 2][layer I, II or III][index].
+// MPEG 2.5 uses the version 2 table.
+var mp3Bitrates = [2][3][15]int{
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	},
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	},
+}
+
+// MPEG audio sample rates by version bits (MPEG 2.5, reserved, 2, 1).
+var mp3SampleRates = [4][3]int{
+	{11025, 12000, 8000},
+	{},
+	{22050, 24000, 16000},
+	{44100, 48000, 32000},
+}
+
+// mp3Frame is a parsed MPEG audio frame header.
+type mp3Frame struct {
+	mpeg1      bool
+	layer      int // 1, 2 or 3
+	bitrate    int // bits per second; 0 for free format
+	sampleRate int
+	mono       bool
+}
+
+func parseMP3Frame(h []byte) (mp3Frame, bool) {
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	version, layerBits := h[1]>>3&3, h[1]>>1&3
+	brIndex, srIndex := h[2]>>4, h[2]>>2&3
+	if version == 1 || layerBits == 0 || brIndex == 15 || srIndex == 3 {
+		return mp3Frame{}, false
+	}
+	fr := mp3Frame{
+		mpeg1:      version == 3,
+		layer:      4 - int(layerBits),
+		sampleRate: mp3SampleRates[version][srIndex],
+		mono:       h[3]>>6 == 3,
+	}
+	table := 1
+	if fr.mpeg1 {
+		table = 0
+	}
+	fr.bitrate = mp3Bitrates[table][fr.layer-1][brIndex] * 1000
+	return fr, true
+}
+
+// samplesPerFrame returns the number of samples each frame decodes to.
+func (fr mp3Frame) samplesPerFrame() int {
+	switch {
+	case fr.layer == 1:
+		return 384
+	case fr.layer == 3 && !fr.mpeg1:
+		return 576
+	}
+	return 1152
+}
+
+// sideInfoSize returns the bytes between a Layer III header and the Xing
+// tag of its first frame.
+func (fr mp3Frame) sideInfoSize() int {
+	switch {
+	case fr.mpeg1 && fr.mono:
+		return 17
+	case fr.mpeg1:
+		return 32
+	case fr.mono:
+		return 9
+	}
+	return 17
+}
+
+// mp3Duration skips an ID3v2 tag, finds the first frame and uses the frame
+// count of a Xing, Info or VBRI header; without one the stream is taken to
+// be constant bitrate.
+func mp3Duration(f *os.File, size int64) (float64, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 64<<10)
+	n, _ := io.ReadFull(f, buf)
+	buf = buf[:n]
+
+	// offset is the position of buf in the file.
+	var offset int64
+	start := 0
+	if len(buf) >= 10 && string(buf[:3]) == "ID3" {
+		tagSize := int(buf[6]&0x7F)<<21 | int(buf[7]&0x7F)<<14 | int(buf[8]&0x7F)<<7 | int(buf[9]&0x7F)
+		start = 10 + tagSize
+		if buf[5]&0x10 != 0 {
+			start += 10 // footer
+		}
+		if start >= len(buf) {
+			offset, start = int64(start), 0
+			n, _ := f.ReadAt(buf[:cap(buf)], offset)
+			buf = buf[:n]
+		}
+	}
+
+	var fr mp3Frame
+	found := false
+	for ; start+4 <= len(buf); start++ {
+		if fr, found = parseMP3Frame(buf[start:]); found {
+			break
+		}
+	}
+	if !found {
+		return 0, errors.New("no MPEG audio frame found")
+	}
+
+	frame := buf[start:]
+	perFrame := float64(fr.samplesPerFrame()) / float64(fr.sampleRate)
+	if fr.layer == 3 {
+		if x := 4 + fr.sideInfoSize(); len(frame) >= x+12 {
+			tag := string(frame[x : x+4])
+			if (tag == "Xing" || tag == "Info") && binary.BigEndian.Uint32(frame[x+4:])&1 != 0 {
+				return float64(binary.BigEndian.Uint32(frame[x+8:])) * perFrame, nil
+			}
+		}
+	}
+	if len(frame) >= 36+18 && string(frame[36:40]) == "VBRI" {
+		return float64(binary.BigEndian.Uint32(frame[50:54])) * perFrame, nil
+	}
+
+	if fr.bitrate == 0 {
+		return 0, errors.New("free format stream without a frame count")
+	}
+	audio := size - offset - int64(start)
+	var tail [128]byte
+	if _, err := f.ReadAt(tail[:], size-128); err == nil && string(tail[:3]) == "TAG" {
+		audio -= 128 // ID3v1
+	}
+	return float64(audio) * 8 / float64(fr.bitrate), nil
+}
+
+// oggDuration reads the sample rate from the identification header of the
+// first logical stream and divides the last granule position of that stream
+// by it.
+func oggDuration(f *os.File, size int64) (float64, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	var hdr [27]byte
+	if _, err := io.ReadFull(f, hdr[:]); err != nil {
+		return 0, err
+	}
+	serial := binary.LittleEndian.Uint32(hdr[14:18])
+	segments := make([]byte, hdr[26])
+	if _, err := io.ReadFull(f, segments); err != nil {
+		return 0, err
+	}
+	packet := make([]byte, 19)
+	n, _ := io.ReadFull(f, packet)
+	packet = packet[:n]
+
+	var rate, preSkip float64
+	switch {
+	case len(packet) >= 16 && string(packet[:7]) == "\x01vorbis":
+		rate = float64(binary.LittleEndian.Uint32(packet[12:16]))
+	case len(packet) >= 12 && string(packet[:8]) == "OpusHead":
+		// Opus granule positions always count 48 kHz samples.
+		rate = 48000
+		preSkip = float64(binary.LittleEndian.Uint16(packet[10:12]))
+	default:
+		return 0, errors.New("only Vorbis and Opus streams are supported")
+	}
+	if rate == 0 {
+		return 0, errors.New("invalid sample rate")
+	}
+
+	tailSize := min(size, 64<<10)
+	tail := make([]byte, tailSize)
+	if _, err := f.ReadAt(tail, size-tailSize); err != nil && err != io.EOF {
+		return 0, err
+	}
+	for i := bytes.LastIndex(tail, []byte("OggS")); i >= 0; i = bytes.LastIndex(tail[:i], []byte("OggS")) {
+		if i+27 > len(tail) || binary.LittleEndian.Uint32(tail[i+14:]) != serial {
+			continue
+		}
+		granule := int64(binary.LittleEndian.Uint64(tail[i+6:]))
+		if granule < 0 {
+			continue
+		}
+		return max(float64(granule)-preSkip, 0) / rate, nil
+	}
+	return 0, errors.New("no final page with a granule position")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wavFile returns a PCM WAV header followed by dataBytes of silence.
+func wavFile(sampleRate, channels, bits, dataBytes int) []byte {
+	var b bytes.Buffer
+	le := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+	b.WriteString("RIFF")
+	le(uint32(36 + 8 + 4 + dataBytes))
+	b.WriteString("WAVE")
+	// An unrelated chunk before fmt, with odd size and padding.
+	b.WriteString("LIST")
+	le(uint32(3))
+	b.Write([]byte{1, 2, 3, 0})
+	b.WriteString("fmt ")
+	le(uint32(16))
+	le(uint16(1))
+	le(uint16(channels))
+	le(uint32(sampleRate))
+	le(uint32(sampleRate * channels * bits / 8))
+	le(uint16(channels * bits / 8))
+	le(uint16(bits))
+	b.WriteString("data")
+	le(uint32(dataBytes))
+	b.Write(make([]byte, dataBytes))
+	return b.Bytes()
+}
+
+// oggPage returns an Ogg page holding a single packet. The CRC is not set.
+func oggPage(granule int64, serial uint32, packet []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("OggS")
+	b.Write([]byte{0, 0})
+	binary.Write(&b, binary.LittleEndian, granule)
+	binary.Write(&b, binary.LittleEndian, serial)
+	b.Write(make([]byte, 8)) // sequence number and CRC
+	b.WriteByte(1)
+	b.WriteByte(byte(len(packet)))
+	b.Write(packet)
+	return b.Bytes()
+}
+
+func TestProbeHeaders(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	// 128 kbps MPEG-1 Layer III at 44.1 kHz: 417 byte frames.
+	frame := append([]byte{0xFF, 0xFB, 0x90, 0x00}, make([]byte, 413)...)
+	cbr := append([]byte("ID3\x03\x00\x00\x00\x00\x00\x05hello"), bytes.Repeat(frame, 100)...)
+	xing := append([]byte{}, frame...)
+	copy(xing[4+32:], "Xing\x00\x00\x00\x01\x00\x00\x01\xF4") // 500 frames
+
+	vorbis := append([]byte("\x01vorbis\x00\x00\x00\x00\x02"), make([]byte, 22)...)
+	binary.LittleEndian.PutUint32(vorbis[12:], 44100)
+	var ogg []byte
+	ogg = append(ogg, oggPage(0, 7, vorbis)...)
+	ogg = append(ogg, oggPage(44100*2, 7, []byte("audio"))...)
+	ogg = append(ogg, oggPage(44100*3, 9, []byte("other stream"))...)
+	opus := []byte("OpusHead\x01\x02\x38\x01\x80\xBB\x00\x00\x00\x00\x00")
+	var opusFile []byte
+	opusFile = append(opusFile, oggPage(0, 1, opus)...)
+	opusFile = append(opusFile, oggPage(48000*4+312, 1, []byte("audio"))...)
+
+	tests := []struct {
+		name, file string
+		data       []byte
+		format     string
+		duration   float64
+	}{
+		{"wav", "call.wav", wavFile(8000, 1, 16, 8000*2*3), "wav", 3},
+		{"cbr mp3", "call.mp3", cbr, "mp3", 100 * 417 * 8 / 128000.0},
+		{"xing mp3", "vbr.mp3", append(xing, bytes.Repeat(frame, 5)...), "mp3", 500 * 1152 / 44100.0},
+		{"vorbis", "call.ogg", ogg, "ogg", 2},
+		{"opus", "call.opus", opusFile, "ogg", 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			info, err := probeHeaders(write(tc.file, tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.FormatName != tc.format || math.Abs(info.DurationSeconds-tc.duration) > 0.001 {
+				t.Errorf("got %s %.4fs, want %s %.4fs", info.FormatName, info.DurationSeconds, tc.format, tc.duration)
+			}
+		})
+	}
+
+	if _, err := probeHeaders(write("clip.mp4", []byte("\x00\x00\x00\x18ftypmp42"))); err == nil || !strings.Contains(err.Error(), "install ffprobe") {
+		t.Errorf("expected an unsupported format error, got %v", err)
+	}
+
+	// probeMedia falls back to the headers when ffprobe is not on the PATH.
+	t.Setenv("PATH", t.TempDir())
+	info, err := probeMedia(write("fallback.wav", wavFile(16000, 2, 16, 16000*4)))
+	if err != nil || info.DurationSeconds != 1 {
+		t.Errorf("probeMedia without ffprobe = %+v, %v", info, err)
+	}
+	out := captureStdout(t, func() {
+		if err := runDoctor(doctorCmd, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "ffprobe: not found") || !strings.Contains(out, "wav, mp3, ogg") {
+		t.Errorf("unexpected doctor output %q", out)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// recordingStart picks the start time of a recording: an explicit --date
+// wins, then the creation time found in the media metadata, then the BWF
+// origination date of WAV files, and finally the file modification time.
+func recordingStart(flag, path string, info *mediaInfo) time.Time {
+	if flag != "" {
+		if t, err := time.Parse(time.RFC3339, flag); err == nil {
+			return t
+		}
+	}
+	if info != nil && !info.CreationTime.IsZero() {
+		return info.CreationTime
+	}
+	if t, ok := wavOriginationTime(path); ok {
+		return t
+	}
+	return getDate("", path)
+}
+
+// mediaTimeLayouts are the creation_time formats written by common muxers.
+var mediaTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000000Z",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// parseMediaTime parses a creation_time tag. Values without a zone are UTC,
+// which is what ffmpeg writes.
+func parseMediaTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range mediaTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			// Some recorders write the epoch when the clock was not set.
+			if t.Unix() <= 0 {
+				return time.Time{}, false
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// wavOriginationTime reads the origination date and time from the bext chunk
+// of a Broadcast Wave file, or from the BEXT section of its iXML chunk. BWF
+// timestamps carry no zone and are interpreted as local time.
+func wavOriginationTime(path string) (time.Time, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+
+	var header [12]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return time.Time{}, false
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return time.Time{}, false
+	}
+
+	var ixml []byte
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(f, chunk[:]); err != nil {
+			break
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		switch id {
+		case "bext", "iXML":
+			body := make([]byte, size)
+			if _, err := io.ReadFull(f, body); err != nil {
+				return time.Time{}, false
+			}
+			if id == "bext" {
+				if t, ok := parseBextOrigination(body); ok {
+					return t, true
+				}
+			} else {
+				ixml = body
+			}
+			if size%2 == 1 {
+				if _, err := f.Seek(1, io.SeekCurrent); err != nil {
+					return time.Time{}, false
+				}
+			}
+		default:
+			// Chunks are word aligned.
+			if _, err := f.Seek(size+size%2, io.SeekCurrent); err != nil {
+				return time.Time{}, false
+			}
+		}
+	}
+	if ixml != nil {
+		return parseIXMLOrigination(ixml)
+	}
+	return time.Time{}, false
+}
+
+// parseBextOrigination extracts OriginationDate (offset 320, "yyyy-mm-dd")
+// and OriginationTime (offset 330, "hh:mm:ss") from a bext chunk body.
+func parseBextOrigination(body []byte) (time.Time, bool) {
+	if len(body) < 338 {
+		return time.Time{}, false
+	}
+	return parseBWFDateTime(string(body[320:330]), string(body[330:338]))
+}
+
+func parseIXMLOrigination(body []byte) (time.Time, bool) {
+	var doc struct {
+		Bext struct {
+			Date string `xml:"BWF_ORIGINATION_DATE"`
+			Time string `xml:"BWF_ORIGINATION_TIME"`
+		} `xml:"BEXT"`
+	}
+	if err := xml.Unmarshal(bytes.TrimRight(body, "\x00"), &doc); err != nil {
+		return time.Time{}, false
+	}
+	return parseBWFDateTime(doc.Bext.Date, doc.Bext.Time)
+}
+
+// parseBWFDateTime accepts any of the separators allowed by EBU Tech 3285
+// ('-', '_', ':', ' ', '.') and treats a missing time as midnight.
+func parseBWFDateTime(date, clock string) (time.Time, bool) {
+	normalise := func(s, sep string) string {
+		s = strings.TrimRight(strings.TrimSpace(s), "\x00")
+		return strings.NewReplacer("-", sep, "_", sep, ":", sep, " ", sep, ".", sep).Replace(s)
+	}
+	date, clock = normalise(date, "-"), normalise(clock, ":")
+	if clock == "" {
+		clock = "00:00:00"
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", date+" "+clock, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeWAV writes a minimal RIFF/WAVE file containing the given chunks.
+func writeWAV(t *testing.T, chunks map[string][]byte, order ...string) string {
+	t.Helper()
+	var body []byte
+	body = append(body, "WAVE"...)
+	for _, id := range order {
+		data := chunks[id]
+		var hdr [8]byte
+		copy(hdr[:4], id)
+		binary.LittleEndian.PutUint32(hdr[4:], uint32(len(data)))
+		body = append(body, hdr[:]...)
+		body = append(body, data...)
+		if len(data)%2 == 1 {
+			body = append(body, 0)
+		}
+	}
+	var riff [8]byte
+	copy(riff[:4], "RIFF")
+	binary.LittleEndian.PutUint32(riff[4:], uint32(len(body)))
+	path := filepath.Join(t.TempDir(), "rec.wav")
+	if err := os.WriteFile(path, append(riff[:], body...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func bextChunk(date, clock string) []byte {
+	b := make([]byte, 602)
+	copy(b[320:330], date)
+	copy(b[330:338], clock)
+	return b
+}
+
+func TestWAVOriginationTimeFromBext(t *testing.T) {
+	path := writeWAV(t, map[string][]byte{
+		"fmt ": make([]byte, 16),
+		"bext": bextChunk("2024:05:06", "07-08-09"),
+		"data": {1, 2, 3},
+	}, "fmt ", "data", "bext")
+
+	got, ok := wavOriginationTime(path)
+	if !ok {
+		t.Fatal("expected origination time from bext chunk")
+	}
+	want := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+	if !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWAVOriginationTimeFromIXML(t *testing.T) {
+	ixml := []byte(`<?xml version="1.0"?><BWFXML><BEXT>` +
+		`<BWF_ORIGINATION_DATE>2023-11-12</BWF_ORIGINATION_DATE>` +
+		`<BWF_ORIGINATION_TIME>13:14:15</BWF_ORIGINATION_TIME>` +
+		`</BEXT></BWFXML>`)
+	path := writeWAV(t, map[string][]byte{"fmt ": make([]byte, 16), "iXML": ixml}, "fmt ", "iXML")
+
+	got, ok := wavOriginationTime(path)
+	if !ok {
+		t.Fatal("expected origination time from iXML chunk")
+	}
+	if want := time.Date(2023, 11, 12, 13, 14, 15, 0, time.Local); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWAVOriginationTimeMissing(t *testing.T) {
+	path := writeWAV(t, map[string][]byte{"fmt ": make([]byte, 16), "data": {0}}, "fmt ", "data")
+	if _, ok := wavOriginationTime(path); ok {
+		t.Error("expected no origination time for plain WAV")
+	}
+	if _, ok := wavOriginationTime("../../testdata/sample_vcons/1745501752.21.wav"); ok {
+		t.Error("expected no origination time for sample WAV")
+	}
+}
+
+func TestParseMediaTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"2024-02-03T04:05:06.000000Z", time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC), true},
+		{"2024-02-03T04:05:06+02:00", time.Date(2024, 2, 3, 2, 5, 6, 0, time.UTC), true},
+		{"2024-02-03 04:05:06", time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC), true},
+		{"1970-01-01T00:00:00.000000Z", time.Time{}, false},
+		{"", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseMediaTime(tt.in)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseMediaTime(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRecordingStartPrecedence(t *testing.T) {
+	path := writeWAV(t, map[string][]byte{"bext": bextChunk("2022-01-01", "10:00:00")}, "bext")
+	tagged := &mediaInfo{CreationTime: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+	if got := recordingStart("2020-01-01T00:00:00Z", path, tagged); !got.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("--date should win, got %v", got)
+	}
+	if got := recordingStart("", path, tagged); !got.Equal(tagged.CreationTime) {
+		t.Errorf("creation_time tag should win over bext, got %v", got)
+	}
+	if got := recordingStart("", path, &mediaInfo{}); !got.Equal(time.Date(2022, 1, 1, 10, 0, 0, 0, time.Local)) {
+		t.Errorf("bext should be used without a tag, got %v", got)
+	}
+
+	plain := writeWAV(t, map[string][]byte{"data": {0}}, "data")
+	mtime := time.Date(2019, 3, 3, 3, 3, 3, 0, time.UTC)
+	if err := os.Chtimes(plain, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if got := recordingStart("", plain, &mediaInfo{}); !got.Equal(mtime) {
+		t.Errorf("mtime should be the last resort, got %v", got)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+// Command: migrate
+var migrateCmd = &cobra.Command{
+	Use:   "migrate <file> [file ...]",
+	Short: "Upgrade vCons written for an older spec version",
+	Long: `Upgrade vCon documents written for an older spec version to the version
+this tool targets (or --to), renaming and dropping properties as the spec
+changed. Supported versions: ` + strings.Join(vcon.SupportedVersions(), ", ") + `.
+
+Each file is rewritten in place unless --output is given, which requires a
+single file. Files already at the target version are left untouched.
+--dry-run lists the changes and validates the result without writing it.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMigrate,
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	target, _ := cmd.Flags().GetString("to")
+	out, _ := cmd.Flags().GetString("output")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if out != "" && len(args) > 1 {
+		return errors.New("--output needs a single input file")
+	}
+	if target == "" {
+		target = vcon.SpecVersion
+	}
+
+	for _, path := range args {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		migrated, err := vcon.Migrate(data, target)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if bytes.Equal(migrated, data) {
+			fmt.Printf("✅ %s is already at %s\n", path, target)
+			continue
+		}
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, migrated, "", "  "); err != nil {
+			return err
+		}
+		dst, write := path, replaceFile
+		if out != "" {
+			dst, write = out, writeOutputFile
+		}
+		if dryRun {
+			if err := previewMigration(dst, data, migrated, pretty.Bytes(), out == ""); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if err := write(dst, pretty.Bytes(), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Migrated %s to %s\n", path, target)
+	}
+	return nil
+}
+
+// previewMigration reports, for --dry-run, the changes a migration makes and
+// whether the migrated document loads as a valid vCon.
+func previewMigration(dst string, data, migrated, pretty []byte, inPlace bool) error {
+	if err := reportWrite(dst, pretty, inPlace); err != nil {
+		return err
+	}
+	d, err := vcon.DiffJSON(data, migrated)
+	if err != nil {
+		return err
+	}
+	reportChanges(d)
+	v, err := vcon.BuildFromJSON(string(migrated), vcon.PropertyHandlingDefault)
+	if err != nil {
+		fmt.Println("   ❌ result is invalid:")
+		fmt.Printf("      %v\n", err)
+		return nil
+	}
+	reportValidation(v)
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+// Global output flags. Every file vconctl writes is written atomically, so
+// an interrupted command never leaves a truncated vCon behind.
+var (
+	// forceOverwrite lets commands replace existing output files.
+	forceOverwrite bool
+
+	// backupOriginals keeps each replaced file as <file>.bak.
+	backupOriginals bool
+
+	// --file-mode, --umask and --chown, applied to outputPerms by
+	// configureOutput.
+	fileModeFlag string
+	umaskFlag    string
+	chownFlag    string
+
+	outputPerms = outputPermissions{uid: -1, gid: -1}
+)
+
+// outputPermissions are the permissions and owner given to written files.
+type outputPermissions struct {
+	mode     os.FileMode // replaces the command's default mode when non-zero
+	umask    os.FileMode // cleared from every mode, on top of the process umask
+	uid, gid int         // -1 leaves the owner or group unchanged
+}
+
+// configureOutput parses the global file permission flags.
+func configureOutput(_ *cobra.Command, _ []string) error {
+	perms := outputPermissions{uid: -1, gid: -1}
+	var err error
+	if fileModeFlag != "" {
+		if perms.mode, err = parseFileMode(fileModeFlag); err != nil {
+			return fmt.Errorf("--file-mode: %w", err)
+		}
+	}
+	if umaskFlag != "" {
+		if perms.umask, err = parseFileMode(umaskFlag); err != nil {
+			return fmt.Errorf("--umask: %w", err)
+		}
+	}
+	if chownFlag != "" {
+		if perms.uid, perms.gid, err = parseOwner(chownFlag); err != nil {
+			return fmt.Errorf("--chown: %w", err)
+		}
+	}
+	outputPerms = perms
+	return nil
+}
+
+// parseFileMode parses octal permission bits such as 640 or 0600.
+func parseFileMode(s string) (os.FileMode, error) {
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || n > 0777 {
+		return 0, fmt.Errorf("%q is not an octal permission mode", s)
+	}
+	return os.FileMode(n), nil
+}
+
+// parseOwner parses "user[:group]", each a name or a numeric id.
+func parseOwner(s string) (uid, gid int, err error) {
+	name, group, hasGroup := strings.Cut(s, ":")
+	uid, gid = -1, -1
+	if name != "" {
+		if uid, err = strconv.Atoi(name); err != nil {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return 0, 0, err
+			}
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return 0, 0, fmt.Errorf("user %s: non-numeric uid %q", name, u.Uid)
+			}
+		}
+	}
+	if hasGroup && group != "" {
+		if gid, err = strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, err
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return 0, 0, fmt.Errorf("group %s: non-numeric gid %q", group, g.Gid)
+			}
+		}
+	}
+	if uid == -1 && gid == -1 {
+		return 0, 0, fmt.Errorf("%q names no user or group", s)
+	}
+	return uid, gid, nil
+}
+
+// filePerm returns the mode to write a file with, given the command's
+// default. Private files such as keys (no group or other bits) keep only
+// the owner bits of --file-mode.
+func (p outputPermissions) filePerm(perm os.FileMode) os.FileMode {
+	if p.mode != 0 {
+		if perm&0077 == 0 {
+			perm = p.mode & 0700
+		} else {
+			perm = p.mode
+		}
+	}
+	return perm &^ p.umask
+}
+
+// replacedPerm returns the mode of a replaced file that had mode old, given
+// the command's default: --file-mode applies as to a new file and --umask
+// clears bits from the old mode.
+func (p outputPermissions) replacedPerm(old, perm os.FileMode) os.FileMode {
+	if p.mode != 0 {
+		return p.filePerm(perm)
+	}
+	return old &^ p.umask
+}
+
+// chown gives path the owner and group of --chown, if any.
+func (p outputPermissions) chown(path string) error {
+	if p.uid == -1 && p.gid == -1 {
+		return nil
+	}
+	if err := os.Chown(path, p.uid, p.gid); err != nil {
+		return fmt.Errorf("chown %s: %w", path, err)
+	}
+	return nil
+}
+
+// checkOutput fails when path exists and --force was not given.
+func checkOutput(path string) error {
+	if forceOverwrite {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// writeOutputFile writes a new output file. An existing file is only
+// replaced with --force.
+func writeOutputFile(path string, data []byte, perm os.FileMode) error {
+	if err := checkOutput(path); err != nil {
+		return err
+	}
+	return replaceFile(path, data, perm)
+}
+
+// replaceFile writes path atomically, first copying the file it replaces to
+// <path>.bak with --backup. Commands that update a file in place use it
+// directly. A replaced file keeps its mode unless --file-mode or --umask
+// says otherwise.
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	if backupOriginals {
+		if err := backupFile(path); err != nil {
+			return err
+		}
+	}
+	old, statErr := os.Stat(path)
+	if err := vcon.WriteFileAtomic(path, data, outputPerms.filePerm(perm)); err != nil {
+		return err
+	}
+	if statErr == nil {
+		if mode := outputPerms.replacedPerm(old.Mode().Perm(), perm); mode != old.Mode().Perm() {
+			if err := os.Chmod(path, mode); err != nil {
+				return fmt.Errorf("chmod %s: %w", path, err)
+			}
+		}
+	}
+	return outputPerms.chown(path)
+}
+
+func backupFile(path string) error {
+	old, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := vcon.WriteFileAtomic(path+".bak", old, fi.Mode().Perm()); err != nil {
+		return fmt.Errorf("backup of %s: %w", path, err)
+	}
+	return outputPerms.chown(path + ".bak")
+}
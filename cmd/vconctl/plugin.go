@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// pluginPrefix is the executable name prefix of external subcommands:
+// "vconctl foo" runs vconctl-foo from the PATH when foo is not built in.
+const pluginPrefix = "vconctl-"
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Work with external vconctl-<name> subcommands",
+	Long: `Any executable named vconctl-<name> on the PATH can be run as "vconctl <name>",
+so converters and processors can ship as separate binaries. Built-in commands
+take precedence.
+
+Global flags given before <name> are passed to the plugin as environment
+variables: --domain as VCONCTL_DOMAIN, --http-timeout as VCONCTL_HTTP_TIMEOUT
+and so on. All other arguments are passed on unchanged.`,
+}
+
+// Command: plugin list
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the plugins found on the PATH",
+	Args:  cobra.NoArgs,
+	RunE:  runPluginList,
+}
+
+func runPluginList(_ *cobra.Command, _ []string) error {
+	plugins := findPlugins(filepath.SplitList(os.Getenv("PATH")))
+	if len(plugins) == 0 {
+		fmt.Println("No vconctl plugins found on the PATH")
+		return nil
+	}
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		paths := plugins[name]
+		fmt.Printf("%-16s %s\n", name, paths[0])
+		if isBuiltinCommand(name) {
+			fmt.Printf("  ⚠️  ignored: %q is a built-in command\n", name)
+		}
+		for _, p := range paths[1:] {
+			fmt.Printf("  ⚠️  shadows %s\n", p)
+		}
+	}
+	return nil
+}
+
+// findPlugins maps each plugin name to its executables in PATH order; the
+// first one is the one that runs.
+func findPlugins(dirs []string) map[string][]string {
+	plugins := map[string][]string{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), pluginPrefix)
+			if !ok || e.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			path := filepath.Join(dir, e.Name())
+			if name == "" || !isExecutable(path) {
+				continue
+			}
+			plugins[name] = append(plugins[name], path)
+		}
+	}
+	return plugins
+}
+
+func isExecutable(path string) bool {
+	_, err := exec.LookPath(path)
+	return err == nil
+}
+
+// isBuiltinCommand reports whether name is a subcommand of vconctl itself,
+// including the help and completion commands cobra adds on Execute.
+func isBuiltinCommand(name string) bool {
+	if name == "help" || name == "completion" || strings.HasPrefix(name, "__") {
+		return true
+	}
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginInvocation returns the plugin executable named by args and the
+// arguments to pass it, or an empty path when args name a built-in command
+// or no plugin. Global flags before the plugin name are parsed into their
+// variables.
+func pluginInvocation(args []string) (path string, pluginArgs []string) {
+	fs := pflag.NewFlagSet("vconctl", pflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.SetInterspersed(false)
+	fs.AddFlagSet(rootCmd.PersistentFlags())
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		return "", nil
+	}
+	name := fs.Arg(0)
+	if isBuiltinCommand(name) {
+		return "", nil
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return "", nil
+	}
+	return path, fs.Args()[1:]
+}
+
+// pluginEnv returns the environment of a plugin: vconctl's own plus one
+// VCONCTL_<FLAG> variable per global flag.
+func pluginEnv() []string {
+	env := os.Environ()
+	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		key := "VCONCTL_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		env = append(env, key+"="+f.Value.String())
+	})
+	return env
+}
+
+// runPlugin runs the plugin named by args, if any, and reports whether it
+// did. The plugin's exit code is returned so vconctl can exit with it.
+func runPlugin(args []string) (ran bool, code int, err error) {
+	path, pluginArgs := pluginInvocation(args)
+	if path == "" {
+		return false, 0, nil
+	}
+	plugin := exec.Command(path, pluginArgs...)
+	plugin.Stdin, plugin.Stdout, plugin.Stderr = os.Stdin, os.Stdout, os.Stderr
+	plugin.Env = pluginEnv()
+	if err := plugin.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return true, exitErr.ExitCode(), nil
+		}
+		return true, 1, fmt.Errorf("run plugin %s: %w", path, err)
+	}
+	return true, 0, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writePlugin creates an executable shell script named vconctl-<name> in dir
+// that prints its arguments and the domain it was given.
+func writePlugin(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, pluginPrefix+name)
+	script := "#!/bin/sh\necho \"args=$* domain=$VCONCTL_DOMAIN\"\nexit 3\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPluginInvocation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins")
+	}
+	dir := t.TempDir()
+	hello := writePlugin(t, dir, "hello")
+	writePlugin(t, dir, "sign")
+	t.Setenv("PATH", dir)
+	orig := globalDomain
+	t.Cleanup(func() { globalDomain = orig })
+
+	path, args := pluginInvocation([]string{"--domain", "acme.example", "hello", "--flag", "x"})
+	if path != hello {
+		t.Fatalf("path = %q, want %q", path, hello)
+	}
+	if strings.Join(args, " ") != "--flag x" {
+		t.Errorf("args = %q", args)
+	}
+	if globalDomain != "acme.example" {
+		t.Errorf("global flag not parsed: %q", globalDomain)
+	}
+
+	for _, args := range [][]string{{"sign", "x.json"}, {"help"}, {"missing"}, {}, {"--bogus", "hello"}} {
+		if path, _ := pluginInvocation(args); path != "" {
+			t.Errorf("%q resolved to plugin %s", args, path)
+		}
+	}
+
+	var ran bool
+	var code int
+	out := captureStdout(t, func() {
+		var err error
+		ran, code, err = runPlugin([]string{"--domain", "acme.example", "hello", "a", "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !ran || code != 3 {
+		t.Errorf("ran = %v, code = %d", ran, code)
+	}
+	if !strings.Contains(out, "args=a b domain=acme.example") {
+		t.Errorf("unexpected plugin output %q", out)
+	}
+}
+
+func TestPluginList(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	writePlugin(t, first, "hello")
+	shadowed := writePlugin(t, second, "hello")
+	writePlugin(t, second, "sign")
+	if err := os.WriteFile(filepath.Join(second, pluginPrefix+"notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	out := captureStdout(t, func() {
+		if err := runPluginList(pluginListCmd, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, want := range []string{"hello", "shadows " + shadowed, `"sign" is a built-in command`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "notes.txt") {
+		t.Errorf("non-executable file listed:\n%s", out)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// convertProvenance is set by "convert --provenance".
+var convertProvenance bool
+
+// provenanceSource is an input of a conversion: the path or URL it was given
+// as and the local file it was read from.
+type provenanceSource struct {
+	uri, path string
+}
+
+// attachBuildProvenance attaches a build provenance record to each of vcons
+// when --provenance is given: vconctl and its version, the SHA-512 of every
+// source and the conversion command with the flags it was run with. vCons
+// without a dialog to attach it to are reported and left as they are.
+func attachBuildProvenance(cmd *cobra.Command, vcons []*vcon.VCon, sources ...provenanceSource) error {
+	if !convertProvenance {
+		return nil
+	}
+	var targets []*vcon.VCon
+	for _, v := range vcons {
+		if len(v.Dialog) == 0 {
+			fmt.Fprintf(os.Stderr, "⚠️  %s has no dialog, provenance not attached\n", v.UUID)
+			continue
+		}
+		targets = append(targets, v)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	p := vcon.BuildProvenance{
+		Tool:    vcon.BuildTool{Name: "vconctl", Version: toolVersion()},
+		Steps:   []vcon.BuildStep{convertStep(cmd)},
+		BuiltAt: time.Now().UTC(),
+	}
+	for _, src := range sources {
+		data, err := os.ReadFile(src.path)
+		if err != nil {
+			return fmt.Errorf("provenance: %w", err)
+		}
+		p.Sources = append(p.Sources, vcon.NewBuildSource(src.uri, data))
+	}
+	for _, v := range targets {
+		if err := v.AttachBuildProvenance(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertStep names the conversion by its command path, with the value of
+// every flag that was set except the output location.
+func convertStep(cmd *cobra.Command) vcon.BuildStep {
+	step := vcon.BuildStep{Name: cmd.CommandPath()}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if f.Name == "output" || f.Name == "provenance" {
+			return
+		}
+		if step.Parameters == nil {
+			step.Parameters = map[string]string{}
+		}
+		step.Parameters[f.Name] = f.Value.String()
+	})
+	return step
+}
+
+// toolVersion returns the module version vconctl was built from.
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+// Command: resign
+var resignCmd = &cobra.Command{
+	Use:   "resign <dir|file> [...]",
+	Short: "Re-sign signed vCons with a new key when a signing certificate rolls over",
+	Long: `Re-sign every signed vCon in the given files and directories. Each vCon
+is verified against the old trust anchor (--old-ca) first; vCons that do not
+verify are left alone and reported as failed.
+
+--mode replace (the default) swaps the old signature for one made with the
+new key. --mode append keeps the old signature and adds the new one, so the
+result verifies only for those trusting both anchors. --at verifies the old
+signatures as of an earlier time, e.g. before the old certificate expired.
+
+Files are rewritten in place (see --backup). A line is printed per file and
+--log also writes the results as JSON Lines. The command fails when any vCon
+could not be re-signed.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runResign,
+}
+
+const (
+	resignModeReplace = "replace"
+	resignModeAppend  = "append"
+)
+
+// resignResult is one line of the --log file.
+type resignResult struct {
+	File   string `json:"file"`
+	Status string `json:"status"` // resigned, skipped or failed
+	UUID   string `json:"uuid,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func runResign(cmd *cobra.Command, args []string) error {
+	caPath, _ := cmd.Flags().GetString("old-ca")
+	mode, _ := cmd.Flags().GetString("mode")
+	at, _ := cmd.Flags().GetString("at")
+	logPath, _ := cmd.Flags().GetString("log")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if caPath == "" {
+		return fmt.Errorf("--old-ca is required")
+	}
+	if mode != resignModeReplace && mode != resignModeAppend {
+		return fmt.Errorf("invalid --mode %q (want replace or append)", mode)
+	}
+	oldRoots := x509.NewCertPool()
+	if !appendPEMToPool(oldRoots, caPath) {
+		return fmt.Errorf("invalid PEM in %s", caPath)
+	}
+	var opts []vcon.VerifyOption
+	if at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return fmt.Errorf("invalid --at: %w", err)
+		}
+		opts = append(opts, vcon.WithVerificationTime(t))
+	}
+	priv, cert, err := signingMaterial(cmd)
+	if err != nil {
+		return err
+	}
+	files, err := collectJSONFiles(args)
+	if err != nil {
+		return err
+	}
+
+	var results []resignResult
+	counts := map[string]int{}
+	for _, path := range files {
+		r := resignFile(path, oldRoots, priv, cert, mode, dryRun, opts)
+		counts[r.Status]++
+		results = append(results, r)
+		switch r.Status {
+		case "resigned":
+			if dryRun {
+				fmt.Printf("✅ %s: would be re-signed by %s\n", path, cert.Subject.CommonName)
+			} else {
+				fmt.Printf("✅ %s: re-signed by %s\n", path, cert.Subject.CommonName)
+			}
+		case "skipped":
+			fmt.Printf("⏭️  %s: %s\n", path, r.Error)
+		default:
+			fmt.Printf("❌ %s: %s\n", path, r.Error)
+		}
+	}
+	fmt.Printf("Re-signed %d, skipped %d, failed %d\n", counts["resigned"], counts["skipped"], counts["failed"])
+
+	if logPath != "" {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, r := range results {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		if err := writeOutputFile(logPath, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("writing log: %w", err)
+		}
+	}
+	if n := counts["failed"]; n > 0 {
+		return fmt.Errorf("%d of %d vCons could not be re-signed", n, len(files))
+	}
+	return nil
+}
+
+// resignFile verifies the signed vCon at path against oldRoots and rewrites
+// it signed by priv.
+func resignFile(path string, oldRoots *x509.CertPool, priv crypto.Signer, cert *x509.Certificate, mode string, dryRun bool, opts []vcon.VerifyOption) resignResult {
+	r := resignResult{File: path, Status: "failed"}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if form, err := vcon.DetectForm(data); err != nil || form != vcon.VConFormSigned {
+		r.Status, r.Error = "skipped", "not a signed vCon"
+		return r
+	}
+	var jws map[string]any
+	if err := json.Unmarshal(data, &jws); err != nil {
+		r.Error = fmt.Sprintf("parsing JSON: %v", err)
+		return r
+	}
+	signed := &vcon.SignedVCon{JSON: jws}
+	v, err := signed.Verify(oldRoots, opts...)
+	if err != nil {
+		r.Error = fmt.Sprintf("verification against the old CA failed: %v", err)
+		return r
+	}
+	r.UUID = v.UUID
+
+	if mode == resignModeAppend {
+		signed, err = signed.AddSignature(priv, []*x509.Certificate{cert})
+	} else {
+		signed, err = v.Sign(priv, []*x509.Certificate{cert})
+	}
+	if err != nil {
+		r.Error = fmt.Sprintf("signing: %v", err)
+		return r
+	}
+	if !dryRun {
+		out, err := json.MarshalIndent(signed.JSON, "", "  ")
+		if err == nil {
+			err = replaceFile(path, out, 0644)
+		}
+		if err != nil {
+			r.Error = fmt.Sprintf("writing: %v", err)
+			return r
+		}
+	}
+	r.Status = "resigned"
+	return r
+}
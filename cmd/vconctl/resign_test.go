@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+func TestResign(t *testing.T) {
+	oldKey, oldCert := writeTestKeyPair(t, t.TempDir())
+	newKey, newCert := writeTestKeyPair(t, t.TempDir())
+	_, strangerCert := writeTestKeyPair(t, t.TempDir())
+	priv, cert := readPrivateKey(oldKey), readCertificate(oldCert)
+
+	dir := t.TempDir()
+	v := vcon.New("test.example.com")
+	v.Subject = "rollover"
+	signed, err := v.Sign(priv, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(signed.JSON)
+	signedPath := filepath.Join(dir, "signed.json")
+	os.WriteFile(signedPath, data, 0644)
+	os.WriteFile(filepath.Join(dir, "plain.json"), []byte(v.ToJSON()), 0644)
+
+	newCmd := func(flags map[string]string) *cobra.Command {
+		cmd := &cobra.Command{}
+		for _, name := range []string{"old-ca", "key", "cert", "keyring-alias", "at", "log"} {
+			cmd.Flags().String(name, "", "")
+		}
+		cmd.Flags().String("mode", resignModeReplace, "")
+		cmd.Flags().Bool("dry-run", false, "")
+		for k, val := range flags {
+			if err := cmd.Flags().Set(k, val); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cmd
+	}
+	roots := func(paths ...string) *x509.CertPool {
+		pool := x509.NewCertPool()
+		for _, p := range paths {
+			appendPEMToPool(pool, p)
+		}
+		return pool
+	}
+	verify := func(pool *x509.CertPool) (map[string]any, error) {
+		jws := readBareJWS(signedPath)
+		_, err := (&vcon.SignedVCon{JSON: jws}).Verify(pool)
+		return jws, err
+	}
+
+	logPath := filepath.Join(t.TempDir(), "resign.jsonl")
+	out := captureStdout(t, func() {
+		if err := runResign(newCmd(map[string]string{"old-ca": oldCert, "key": newKey, "cert": newCert, "mode": "append", "log": logPath}), []string{dir}); err != nil {
+			t.Fatalf("resign --mode append: %v", err)
+		}
+	})
+	if !strings.Contains(out, "Re-signed 1, skipped 1, failed 0") {
+		t.Errorf("unexpected output %q", out)
+	}
+	if jws, err := verify(roots(oldCert, newCert)); err != nil {
+		t.Fatalf("appended signature does not verify: %v", err)
+	} else if n := len(jws["signatures"].([]any)); n != 2 {
+		t.Errorf("got %d signatures, want 2", n)
+	}
+	logData, _ := os.ReadFile(logPath)
+	if lines := strings.Split(strings.TrimSpace(string(logData)), "\n"); len(lines) != 2 || !strings.Contains(string(logData), `"status":"resigned"`) {
+		t.Errorf("unexpected log %q", logData)
+	}
+
+	// Replacing leaves only the new signature; the file now verifies against
+	// the new anchor alone.
+	os.WriteFile(signedPath, data, 0644)
+	captureStdout(t, func() {
+		if err := runResign(newCmd(map[string]string{"old-ca": oldCert, "key": newKey, "cert": newCert}), []string{signedPath}); err != nil {
+			t.Fatalf("resign: %v", err)
+		}
+	})
+	if _, err := verify(roots(newCert)); err != nil {
+		t.Fatalf("replaced signature does not verify: %v", err)
+	}
+
+	// vCons that do not verify against the old anchor are reported and kept.
+	before, _ := os.ReadFile(signedPath)
+	captureStdout(t, func() {
+		err = runResign(newCmd(map[string]string{"old-ca": strangerCert, "key": newKey, "cert": newCert}), []string{signedPath})
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 1") {
+		t.Errorf("expected a failure, got %v", err)
+	}
+	if after, _ := os.ReadFile(signedPath); string(after) != string(before) {
+		t.Error("a vCon failing verification was rewritten")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Manage the review status of analysis entries",
+}
+
+// Command: review set
+var reviewSetCmd = &cobra.Command{
+	Use:   "set <file> --analysis <n> --status <status>",
+	Short: "Set the review status of an analysis entry",
+	Long: `Record the human review of an analysis entry in its meta.review object.
+
+The status is auto, human-reviewed or disputed. --confidence also updates the
+entry's confidence score (0 to 1). The vCon is rewritten in place unless
+--output is given; --dry-run lists the changes without writing them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReviewSet,
+}
+
+func runReviewSet(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	idx, _ := cmd.Flags().GetInt("analysis")
+	statusFlag, _ := cmd.Flags().GetString("status")
+	reviewer, _ := cmd.Flags().GetString("reviewer")
+	note, _ := cmd.Flags().GetString("note")
+	out, _ := cmd.Flags().GetString("output")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	status, err := vcon.ParseReviewStatus(statusFlag)
+	if err != nil {
+		return err
+	}
+	v, err := vcon.LoadFromFile(path, vcon.PropertyHandlingDefault)
+	if err != nil {
+		return err
+	}
+	if idx < 0 || idx >= len(v.Analysis) {
+		return fmt.Errorf("%s: no analysis at index %d (%d entries)", path, idx, len(v.Analysis))
+	}
+
+	a := &v.Analysis[idx]
+	if cmd.Flags().Changed("confidence") {
+		score, _ := cmd.Flags().GetFloat64("confidence")
+		if err := a.SetConfidence(score); err != nil {
+			return err
+		}
+	}
+	if err := a.SetReview(vcon.Review{Status: status, Reviewer: reviewer, Note: note}); err != nil {
+		return err
+	}
+
+	write := writeOutputFile
+	if out == "" || out == path {
+		out, write = path, replaceFile
+	}
+	if dryRun {
+		before, err := vcon.LoadFromFile(path, vcon.PropertyHandlingDefault)
+		if err != nil {
+			return err
+		}
+		return previewVCon(before, v, out, out == path)
+	}
+	blob, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := write(out, blob, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("✅ analysis %d of %s marked %s\n", idx, path, status)
+	return nil
+}
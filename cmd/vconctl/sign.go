@@ -1,12 +1,16 @@
 package main
 
 import (
+	"crypto"
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/go-jose/go-jose/v4"
 	"github.com/robjsliwa/go-vcon/pkg/vcon"
 	"github.com/spf13/cobra"
 )
@@ -18,19 +22,19 @@ var signCmd = &cobra.Command{
 	Short: "Sign a vCon file using a private key and certificate",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		keyPath, _ := cmd.Flags().GetString("key")
-		certPath, _ := cmd.Flags().GetString("cert")
 		outPath, _ := cmd.Flags().GetString("output")
-		if keyPath == "" || certPath == "" {
-			fmt.Println("Error: --key and --cert are required")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		priv, cert, err := signingMaterial(cmd)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
 			_ = cmd.Help()
 			os.Exit(1)
 		}
-		signFile(args[0], keyPath, certPath, outPath)
+		signFile(args[0], priv, cert, outPath, dryRun)
 	},
 }
 
-func signFile(path, keyPath, certPath, outPath string) {
+func signFile(path string, priv crypto.Signer, cert *x509.Certificate, outPath string, dryRun bool) {
 	fmt.Printf("Signing %s…\n", path)
 
 	raw, err := os.ReadFile(path)
@@ -42,9 +46,6 @@ func signFile(path, keyPath, certPath, outPath string) {
 		die("parsing JSON", err)
 	}
 
-	priv := readPrivateKey(keyPath)
-	cert := readCertificate(certPath)
-
 	signed, err := v.Sign(priv, []*x509.Certificate{cert})
 	if err != nil {
 		die("signing vCon", err)
@@ -54,6 +55,14 @@ func signFile(path, keyPath, certPath, outPath string) {
 		ext := filepath.Ext(path)
 		outPath = path[:len(path)-len(ext)] + ".signed" + ext
 	}
+	if dryRun {
+		if err := previewJSON(outPath, signed.JSON); err != nil {
+			die("writing output", err)
+		}
+		fmt.Printf("   signed by %s\n", cert.Subject.CommonName)
+		reportValidation(&v)
+		return
+	}
 	if err := writeJSON(outPath, signed.JSON); err != nil {
 		die("writing output", err)
 	}
@@ -65,7 +74,14 @@ func signFile(path, keyPath, certPath, outPath string) {
 var verifyCmd = &cobra.Command{
 	Use:   "verify [file]",
 	Short: "Verify the signature on a signed vCon",
-	Args:  cobra.ExactArgs(1),
+	Long: `Verify every signature, certificate chain and the canonical payload of a
+signed vCon.
+
+RS, PS, ES and EdDSA algorithms are accepted so vCons signed by other
+implementations verify; --alg restricts them. A "uuid" header must match the
+vCon when present; --require-header makes headers mandatory and
+--skip-uuid-check ignores a uuid header carrying another identifier.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		caPath, _ := cmd.Flags().GetString("cert")
 		if caPath == "" {
@@ -73,11 +89,41 @@ var verifyCmd = &cobra.Command{
 			_ = cmd.Help()
 			os.Exit(1)
 		}
-		verifyFile(args[0], caPath)
+		opts, err := verifyOptions(cmd)
+		if err != nil {
+			die("verify options", err)
+		}
+		verifyFile(args[0], caPath, opts...)
 	},
 }
 
-func verifyFile(path, caPath string) {
+// verifyOptions turns the verify flags into vcon.VerifyOptions.
+func verifyOptions(cmd *cobra.Command) ([]vcon.VerifyOption, error) {
+	var opts []vcon.VerifyOption
+	if algs, _ := cmd.Flags().GetStringSlice("alg"); len(algs) > 0 {
+		allowed := make([]jose.SignatureAlgorithm, len(algs))
+		for i, a := range algs {
+			alg := jose.SignatureAlgorithm(strings.ToUpper(a))
+			if alg == "EDDSA" {
+				alg = jose.EdDSA
+			}
+			if !slices.Contains(vcon.DefaultSignatureAlgorithms, alg) {
+				return nil, fmt.Errorf("unsupported signature algorithm %q", a)
+			}
+			allowed[i] = alg
+		}
+		opts = append(opts, vcon.WithSignatureAlgorithms(allowed...))
+	}
+	if headers, _ := cmd.Flags().GetStringSlice("require-header"); len(headers) > 0 {
+		opts = append(opts, vcon.WithRequiredHeaders(headers...))
+	}
+	if skip, _ := cmd.Flags().GetBool("skip-uuid-check"); skip {
+		opts = append(opts, vcon.WithoutUUIDCheck())
+	}
+	return opts, nil
+}
+
+func verifyFile(path, caPath string, opts ...vcon.VerifyOption) {
 	fmt.Printf("Verifying %s…\n", path)
 
 	jwsMap := readBareJWS(path)
@@ -88,7 +134,7 @@ func verifyFile(path, caPath string) {
 	}
 
 	signed := vcon.SignedVCon{JSON: jwsMap}
-	vc, err := signed.Verify(root)
+	vc, err := signed.Verify(root, opts...)
 	if err != nil {
 		die("signature verification failed", err)
 	}
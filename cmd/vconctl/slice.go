@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/spf13/cobra"
+	"github.com/vansante/go-ffprobe"
+)
+
+// Command: slice
+var sliceCmd = &cobra.Command{
+	Use:   "slice <file> --from <time> --to <time>",
+	Short: "Cut a time window out of a vCon",
+	Long: `Write a new vCon holding only what happened between --from and --to, to
+share the relevant excerpt of a long conversation.
+
+Times are RFC3339 timestamps, or offsets such as 90s or 12m30s from the
+start of the first dialog. Dialogs overlapping the window are kept and
+renumbered, along with their attachments and the analysis of kept dialogs;
+party_history keeps the events inside the window. The excerpt is a redacted
+vCon of type "slice" pointing at the original.
+
+Recordings that straddle the window are kept whole unless --trim-media is
+given, which cuts them with ffmpeg and stores the excerpt inline.
+
+--dry-run lists the changes and validates the result without writing it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSlice,
+}
+
+func runSlice(cmd *cobra.Command, args []string) error {
+	src := args[0]
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	trimMedia, _ := cmd.Flags().GetBool("trim-media")
+	out, _ := cmd.Flags().GetString("output")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	v, err := vcon.LoadFromFile(src, vcon.PropertyHandlingDefault)
+	if err != nil {
+		return err
+	}
+	start, err := parseSliceTime(v, from)
+	if err != nil {
+		return fmt.Errorf("--from: %w", err)
+	}
+	end, err := parseSliceTime(v, to)
+	if err != nil {
+		return fmt.Errorf("--to: %w", err)
+	}
+	var opts []vcon.SliceOption
+	if trimMedia {
+		opts = append(opts, vcon.WithMediaTrimmer(trimMediaWindow))
+	}
+	excerpt, err := v.Slice(start, end, opts...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+
+	if out == "" {
+		out = strings.TrimSuffix(src, filepath.Ext(src)) + ".slice.json"
+	}
+	if dryRun {
+		if err := excerpt.CheckLimits(sizeLimits); err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+		return previewVCon(v, excerpt, out, false)
+	}
+	if err := writeVconFile(excerpt, out, src); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Wrote %d of %d dialogs to %s\n", len(excerpt.Dialog), len(v.Dialog), out)
+	return nil
+}
+
+// parseSliceTime reads an RFC3339 timestamp, or an offset from the start of
+// the first dialog of v.
+func parseSliceTime(v *vcon.VCon, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("required")
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	offset, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC3339 time nor an offset such as 90s", s)
+	}
+	var first *time.Time
+	for _, d := range v.Dialog {
+		if d.StartTime != nil && (first == nil || d.StartTime.Before(*first)) {
+			first = d.StartTime
+		}
+	}
+	if first == nil {
+		return time.Time{}, errors.New("an offset needs a dialog with a start time")
+	}
+	return first.Add(offset), nil
+}
+
+// trimMediaWindow cuts length of media starting offset into it with ffmpeg,
+// copying the streams into the original container. It is a variable so
+// tests can run without ffmpeg installed.
+var trimMediaWindow = func(media []byte, mediaType string, offset, length time.Duration) ([]byte, string, error) {
+	dir, err := os.MkdirTemp("", "vconctl-slice-")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in")
+	if err := os.WriteFile(in, media, 0600); err != nil {
+		return nil, "", err
+	}
+	info, err := ffprobe.GetProbeData(in, 10*time.Second)
+	if err != nil {
+		return nil, "", fmt.Errorf("ffprobe: %w", err)
+	}
+	format, _, _ := strings.Cut(info.Format.FormatName, ",")
+
+	out := filepath.Join(dir, "out")
+	ffmpeg := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-ss", fmt.Sprintf("%.3f", offset.Seconds()), "-i", in, "-t", fmt.Sprintf("%.3f", length.Seconds()),
+		"-c", "copy", "-f", format, out)
+	if output, err := ffmpeg.CombinedOutput(); err != nil {
+		return nil, "", fmt.Errorf("ffmpeg: %w: %s", err, bytes.TrimSpace(output))
+	}
+	trimmed, err := os.ReadFile(out)
+	if err != nil {
+		return nil, "", err
+	}
+	return trimmed, mediaType, nil
+}
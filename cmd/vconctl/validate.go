@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/robjsliwa/go-vcon/pkg/vcon"
 	"github.com/spf13/cobra"
@@ -12,15 +15,140 @@ import (
 var validateCmd = &cobra.Command{
 	Use:   "validate [file]",
 	Short: "Validate a vCon file",
-	Args:  cobra.MinimumNArgs(1),
-	Run: func(_ *cobra.Command, args []string) {
-		for _, p := range args {
-			fmt.Printf("Validating %s…\n", p)
-			if _, err := vcon.LoadFromFile(p, vcon.PropertyHandlingStrict); err != nil {
-				fmt.Printf("❌ %v\n", err)
+	Long: `Validate one or more vCon files against the JSON Schema.
+
+Non-standard properties are ignored by default. With --report-unknown each
+one is printed as a warning with its JSON pointer. With --strict-reject every
+non-standard property is reported by its JSON pointer, the file is rejected
+and the command exits non-zero if any file fails, for use as an ingest gate.
+
+With --max-inline-body or --max-vcon-size, files over the limits are reported
+with the bodies to externalize and the command exits non-zero.
+
+Structural problems, such as dangling party or dialog indices, fail the file
+and the command exits non-zero. Warnings, such as a party tel without the
+tel: prefix, are printed but do not; --strict-uris makes malformed tel and
+mailto URIs errors.
+
+--check-durations measures the media of each recording dialog with ffprobe,
+downloading it when it is referenced by URL, and fails the file when the
+declared duration is off by more than --duration-tolerance seconds.
+
+--verify-content downloads the content every dialog, attachment and analysis
+entry references by URL and fails the file when it, or an inline body, does
+not match its content_hash, or when it cannot be fetched.
+
+--schema checks every file against another JSON Schema as well, such as an
+organization's profile of the spec. The profile can "$ref" the spec schema
+by its $id, ` + vcon.EmbeddedSchemaID + `.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runValidate,
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	strictReject, _ := cmd.Flags().GetBool("strict-reject")
+	handling := vcon.PropertyHandlingStrict
+	if reportUnknown, _ := cmd.Flags().GetBool("report-unknown"); reportUnknown {
+		handling = vcon.PropertyHandlingReport
+	}
+	if strictReject {
+		handling = vcon.PropertyHandlingReject
+	}
+	var opts []vcon.ValidateOption
+	if strictURIs, _ := cmd.Flags().GetBool("strict-uris"); strictURIs {
+		opts = append(opts, vcon.WithStrictURIs())
+	}
+	if checkDurations, _ := cmd.Flags().GetBool("check-durations"); checkDurations {
+		tolerance, _ := cmd.Flags().GetFloat64("duration-tolerance")
+		opts = append(opts, vcon.WithDurationCheck(probeDuration, tolerance))
+	}
+	if verifyContent, _ := cmd.Flags().GetBool("verify-content"); verifyContent {
+		opts = append(opts, vcon.WithContentVerification(nil))
+	}
+	if location, _ := cmd.Flags().GetString("schema"); location != "" {
+		schema, err := vcon.LoadSchema(location)
+		if err != nil {
+			return fmt.Errorf("load schema: %w", err)
+		}
+		opts = append(opts, vcon.WithSchema(schema))
+	}
+
+	failed, overLimit, invalid := 0, 0, 0
+	for _, p := range args {
+		fmt.Printf("Validating %s…\n", p)
+		v, err := vcon.LoadFromFile(p, handling)
+		if err != nil {
+			failed++
+			var unknown *vcon.UnknownPropertiesError
+			if errors.As(err, &unknown) {
+				fmt.Printf("❌ non-standard properties:\n")
+				for _, path := range unknown.Paths {
+					fmt.Printf("   %s\n", path)
+				}
 				continue
 			}
+			fmt.Printf("❌ %v\n", err)
+			continue
+		}
+		var limitErr *vcon.LimitError
+		if err := v.CheckLimits(sizeLimits); errors.As(err, &limitErr) {
+			overLimit++
+			fmt.Printf("❌ size limits exceeded:\n")
+			for _, msg := range limitErr.Violations {
+				fmt.Printf("   %s\n", msg)
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		ok, issues := v.IsValid(opts...)
+		if !ok {
+			invalid++
+			fmt.Printf("❌ invalid:\n")
+		}
+		for _, issue := range issues {
+			if issue.Severity == vcon.SeverityError {
+				fmt.Printf("   %s\n", issue.Message)
+			} else {
+				fmt.Printf("⚠️  %s\n", issue.Message)
+			}
+		}
+		if ok {
 			fmt.Printf("✅ %s is valid\n", p)
 		}
-	},
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d file(s) invalid", invalid, len(args))
+	}
+	if overLimit > 0 {
+		return fmt.Errorf("%d of %d file(s) exceed size limits", overLimit, len(args))
+	}
+
+	if strictReject && failed > 0 {
+		return fmt.Errorf("%d of %d file(s) rejected", failed, len(args))
+	}
+	return nil
+}
+
+// probeDuration measures media content with ffprobe through a temporary
+// file.
+func probeDuration(_ context.Context, content []byte, _ string) (float64, error) {
+	f, err := os.CreateTemp("", "vconctl-media-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	info, err := probeMedia(f.Name())
+	if err != nil {
+		return 0, err
+	}
+	return info.DurationSeconds, nil
 }
@@ -1,7 +1,11 @@
 package vcon
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 )
 
 // AmendOption configures the amended object.
@@ -57,3 +61,83 @@ func (v *VCon) SetAmended(uuid string, opts ...AmendOption) {
 		opt(v.Amended)
 	}
 }
+
+// Append creates a vCon holding only what appendFn adds to this one, leaving
+// the prior version immutable. appendFn receives a deep copy and may only
+// append parties, dialogs, analysis and attachments; indices it uses refer to
+// the combined view. The result references v through its amended object and
+// Materialize recombines the two.
+func (v *VCon) Append(appendFn func(*VCon) error, opts ...AmendOption) (*VCon, error) {
+	full, err := v.Amend(appendFn, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	delta := *full
+	delta.CreatedAt = time.Now().UTC()
+	if delta.Parties, err = appendedEntries("parties", v.Parties, full.Parties); err != nil {
+		return nil, err
+	}
+	if delta.Dialog, err = appendedEntries("dialog", v.Dialog, full.Dialog); err != nil {
+		return nil, err
+	}
+	if delta.Analysis, err = appendedEntries("analysis", v.Analysis, full.Analysis); err != nil {
+		return nil, err
+	}
+	if delta.Attachments, err = appendedEntries("attachments", v.Attachments, full.Attachments); err != nil {
+		return nil, err
+	}
+	return &delta, nil
+}
+
+// appendedEntries returns the entries of after beyond those of before, and
+// fails if any of the existing entries was changed or removed.
+func appendedEntries[T any](name string, before, after []T) ([]T, error) {
+	if len(after) < len(before) {
+		return nil, fmt.Errorf("append removed %s entries", name)
+	}
+	prior, err := json.Marshal(before)
+	if err != nil {
+		return nil, err
+	}
+	kept, err := json.Marshal(after[:len(before)])
+	if err != nil {
+		return nil, err
+	}
+	if len(before) > 0 && !bytes.Equal(prior, kept) {
+		return nil, fmt.Errorf("append modified existing %s entries", name)
+	}
+	return append([]T{}, after[len(before):]...), nil
+}
+
+// Materialize returns the combined view of prior and the appended vCon v
+// produced by Append: prior's entries followed by v's, under v's uuid.
+func (v *VCon) Materialize(prior *VCon) (*VCon, error) {
+	if v.Amended == nil {
+		return nil, errors.New("vCon does not reference a prior version")
+	}
+	if v.Amended.UUID != "" && v.Amended.UUID != prior.UUID {
+		return nil, fmt.Errorf("vCon amends %s, not %s", v.Amended.UUID, prior.UUID)
+	}
+
+	data, err := json.Marshal(prior)
+	if err != nil {
+		return nil, err
+	}
+	var combined VCon
+	if err := json.Unmarshal(data, &combined); err != nil {
+		return nil, err
+	}
+
+	combined.UUID = v.UUID
+	combined.CreatedAt = v.CreatedAt
+	combined.Amended = v.Amended
+	if v.Subject != "" {
+		combined.Subject = v.Subject
+	}
+	combined.Parties = append(combined.Parties, v.Parties...)
+	combined.Dialog = append(combined.Dialog, v.Dialog...)
+	combined.Analysis = append(combined.Analysis, v.Analysis...)
+	combined.Attachments = append(combined.Attachments, v.Attachments...)
+	return &combined, nil
+}
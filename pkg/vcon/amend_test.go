@@ -14,7 +14,7 @@ func TestAmend(t *testing.T) {
 	v.AddDialog(Dialog{
 		Type:      "recording",
 		StartTime: &now,
-		Parties:   []int{0},
+		Parties:   NewPartyRefs(0),
 		MediaType: "audio/wav",
 	})
 
@@ -122,3 +122,57 @@ func TestAmendPreservesOriginal(t *testing.T) {
 		t.Errorf("expected amended subject 'Modified Subject', got %s", amended.Subject)
 	}
 }
+
+func TestAppendAndMaterialize(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.AddDialog(Dialog{Type: "text", Body: "hello", Parties: NewPartyRefs(0)})
+
+	delta, err := v.Append(func(c *VCon) error {
+		c.AddParty(Party{Name: "Bob"})
+		c.AddAnalysis(Analysis{Type: "summary", Body: "greeting", Dialog: []int{0}})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if delta.Amended == nil || delta.Amended.UUID != v.UUID {
+		t.Fatalf("delta does not reference the prior vCon: %+v", delta.Amended)
+	}
+	if len(delta.Parties) != 1 || delta.Parties[0].Name != "Bob" || len(delta.Dialog) != 0 || len(delta.Analysis) != 1 {
+		t.Errorf("delta holds more than the additions: %+v", delta)
+	}
+	if len(v.Parties) != 1 {
+		t.Error("prior vCon was modified")
+	}
+
+	combined, err := delta.Materialize(v)
+	if err != nil {
+		t.Fatalf("Materialize: %v", err)
+	}
+	if combined.UUID != delta.UUID || len(combined.Parties) != 2 || len(combined.Dialog) != 1 || len(combined.Analysis) != 1 {
+		t.Errorf("unexpected combined view: %+v", combined)
+	}
+
+	if _, err := delta.Materialize(New("example.com")); err == nil {
+		t.Error("expected error materializing against the wrong vCon")
+	}
+}
+
+func TestAppendRejectsChanges(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+
+	if _, err := v.Append(func(c *VCon) error {
+		c.Parties[0].Name = "Mallory"
+		return nil
+	}); err == nil {
+		t.Error("expected error when modifying an existing party")
+	}
+	if _, err := v.Append(func(c *VCon) error {
+		c.Parties = nil
+		return nil
+	}); err == nil {
+		t.Error("expected error when removing a party")
+	}
+}
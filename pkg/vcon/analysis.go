@@ -0,0 +1,177 @@
+package vcon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
+)
+
+// AddExternalData references analysis content stored at urlStr, as
+// Dialog.AddExternalData does. Large ASR outputs are commonly kept out-of-band.
+func (a *Analysis) AddExternalData(urlStr string, filename string, mimeType string, opts ...ExternalDataOption) error {
+	return a.AddExternalDataContext(context.Background(), urlStr, filename, mimeType, opts...)
+}
+
+// AddExternalDataContext is AddExternalData with a context controlling the
+// underlying HTTP requests.
+func (a *Analysis) AddExternalDataContext(ctx context.Context, urlStr string, filename string, mimeType string, opts ...ExternalDataOption) error {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return fmt.Errorf("invalid URL format: %w", err)
+	}
+
+	cfg := &externalDataConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	content, err := resolveExternalContent(ctx, urlStr, cfg)
+	if err != nil {
+		return err
+	}
+
+	a.URL = urlStr
+	a.Body, a.Encoding = "", ""
+	if filename != "" {
+		a.Filename = filename
+	} else {
+		a.Filename = path.Base(parsedURL.Path)
+	}
+	if mimeType != "" {
+		a.MediaType = mediatype.Normalize(mimeType)
+	} else {
+		a.MediaType = contentMediaType(content.ContentType, a.Filename, content.Body)
+	}
+
+	a.ContentHash = cfg.hashFor(content)
+	a.fetched = cfg.retained(content)
+	a.fetcher = cfg.fetcher
+	return nil
+}
+
+// IsExternalData checks if the analysis content is referenced by URL
+func (a *Analysis) IsExternalData() bool {
+	return a.URL != ""
+}
+
+// IsInlineData checks if the analysis content is carried in the body
+func (a *Analysis) IsInlineData() bool {
+	return !a.IsExternalData() && a.Body != ""
+}
+
+// IsExternalDataChanged checks if external content has changed by comparing
+// hashes
+func (a *Analysis) IsExternalDataChanged() (bool, error) {
+	return a.IsExternalDataChangedContext(context.Background())
+}
+
+// IsExternalDataChangedContext is IsExternalDataChanged with a context
+// controlling the fetch.
+func (a *Analysis) IsExternalDataChangedContext(ctx context.Context) (bool, error) {
+	if !a.IsExternalData() || a.ContentHash.IsEmpty() {
+		return false, nil
+	}
+
+	content, err := pickFetcher(a.fetcher).Fetch(ctx, a.URL)
+	if err != nil {
+		return true, err
+	}
+	return !a.ContentHash.Verify(content.Body), nil
+}
+
+// Content returns the raw analysis content. Inline bodies are decoded;
+// external content is fetched on first use, verified against the content
+// hash and cached.
+func (a *Analysis) Content() ([]byte, error) {
+	return a.ContentContext(context.Background())
+}
+
+// ContentContext is Content with a context controlling the fetch.
+func (a *Analysis) ContentContext(ctx context.Context) ([]byte, error) {
+	if !a.IsExternalData() {
+		return decodeInlineBody(a.Body, a.Encoding)
+	}
+	if a.fetched != nil {
+		return a.fetched, nil
+	}
+
+	content, err := pickFetcher(a.fetcher).Fetch(ctx, a.URL)
+	if err != nil {
+		return nil, err
+	}
+	if !a.ContentHash.IsEmpty() && !a.ContentHash.Verify(content.Body) {
+		return nil, errors.New("external analysis does not match its content hash")
+	}
+	a.fetched = content.Body
+	return content.Body, nil
+}
+
+// ToInlineData converts the analysis from external to inline content. JSON
+// content is embedded with encoding "json", text with "none" and anything
+// else as base64url.
+func (a *Analysis) ToInlineData() error {
+	return a.ToInlineDataContext(context.Background())
+}
+
+// ToInlineDataContext is ToInlineData with a context controlling the fetch.
+func (a *Analysis) ToInlineDataContext(ctx context.Context) error {
+	return a.toInlineData(ctx, pickFetcher(a.fetcher))
+}
+
+func (a *Analysis) toInlineData(ctx context.Context, fetcher ContentFetcher) error {
+	if !a.IsExternalData() {
+		return errors.New("analysis is not external data")
+	}
+
+	body, err := inlineExternal(ctx, fetcher, a.URL, a.fetched, &a.MediaType, &a.Filename, &a.ContentHash)
+	if err != nil {
+		return err
+	}
+	a.fetched = nil
+
+	a.Body, a.Encoding = encodeInlineContent(a.MediaType, body)
+	a.URL = ""
+	return nil
+}
+
+// encodeInlineContent returns body as inline content of media type mt: JSON
+// with encoding "json", text with "none" and anything else as base64url.
+func encodeInlineContent(mt string, body []byte) (string, string) {
+	mt = mediatype.Base(mt)
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return string(body), "json"
+	case strings.HasPrefix(mt, "text/"):
+		return string(body), "none"
+	default:
+		return encodeBase64URL(body), "base64url"
+	}
+}
+
+// ToExternalData moves inline content out-of-band. It returns the raw bytes
+// the caller must store at urlStr, then replaces the body with the URL and
+// content hash.
+func (a *Analysis) ToExternalData(urlStr string) ([]byte, error) {
+	if a.IsExternalData() {
+		return nil, errors.New("analysis is already external data")
+	}
+	if _, err := url.Parse(urlStr); err != nil {
+		return nil, fmt.Errorf("invalid URL format: %w", err)
+	}
+
+	body, err := decodeInlineBody(a.Body, a.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	a.URL = urlStr
+	a.ContentHash = ContentHashList{ComputeSHA512(body)}
+	a.Body, a.Encoding = "", ""
+	a.fetched = nil
+	return body, nil
+}
@@ -0,0 +1,141 @@
+package vcon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const transcriptJSON = `{"segments":[{"speaker":0,"text":"Hello"}]}`
+
+func newAnalysisServer(t *testing.T, body *string, gets *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			*gets++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(*body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAnalysisExternalData(t *testing.T) {
+	body, gets := transcriptJSON, 0
+	srv := newAnalysisServer(t, &body, &gets)
+
+	a := Analysis{Type: "transcript", Vendor: "ASRCo"}
+	require.NoError(t, a.AddExternalData(srv.URL+"/t/1.json", "", ""))
+	assert.True(t, a.IsExternalData())
+	assert.False(t, a.IsInlineData())
+	assert.Equal(t, "application/json", a.MediaType)
+	assert.Equal(t, "1.json", a.Filename)
+	assert.True(t, a.ContentHash.Verify([]byte(transcriptJSON)))
+
+	// Content is fetched lazily and cached.
+	gets = 0
+	data, err := a.Content()
+	require.NoError(t, err)
+	assert.Equal(t, transcriptJSON, string(data))
+	_, err = a.Content()
+	require.NoError(t, err)
+	assert.Equal(t, 1, gets)
+
+	changed, err := a.IsExternalDataChanged()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	body = `{"segments":[]}`
+	changed, err = a.IsExternalDataChanged()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	fresh := Analysis{URL: a.URL, ContentHash: a.ContentHash}
+	_, err = fresh.Content()
+	assert.Error(t, err, "content not matching the hash must be rejected")
+}
+
+func TestAnalysisToInlineData(t *testing.T) {
+	body, gets := transcriptJSON, 0
+	srv := newAnalysisServer(t, &body, &gets)
+
+	a := Analysis{Type: "transcript", Vendor: "ASRCo"}
+	require.NoError(t, a.AddExternalData(srv.URL+"/t.json", "", "", WithRetainedBody()))
+	gets = 0
+	require.NoError(t, a.ToInlineData())
+	assert.Zero(t, gets, "retained body should be reused")
+	assert.Empty(t, a.URL)
+	assert.Equal(t, transcriptJSON, a.Body)
+	assert.Equal(t, "json", a.Encoding)
+
+	bin := Analysis{URL: srv.URL + "/t.bin", MediaType: "application/octet-stream"}
+	require.NoError(t, bin.ToInlineData())
+	assert.Equal(t, "base64url", bin.Encoding)
+	data, err := bin.Content()
+	require.NoError(t, err)
+	assert.Equal(t, transcriptJSON, string(data))
+
+	assert.Error(t, a.ToInlineData(), "already inline")
+}
+
+func TestAnalysisToExternalData(t *testing.T) {
+	a := Analysis{Type: "transcript", Body: encodeBase64URL([]byte("raw words")), Encoding: "base64url"}
+	data, err := a.ToExternalData("https://example.com/t/1.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "raw words", string(data))
+	assert.Empty(t, a.Body)
+	assert.Empty(t, a.Encoding)
+	assert.Equal(t, "https://example.com/t/1.txt", a.URL)
+	assert.True(t, a.ContentHash.Verify(data))
+
+	_, err = a.ToExternalData("https://example.com/other")
+	assert.Error(t, err)
+}
+
+func TestTypedAnalysisConstructors(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	for range 2 {
+		v.AddDialog(*NewDialog("text", time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), NewPartyRefs(0), WithBody("hello"), WithEncoding("none")))
+	}
+
+	i, err := v.AddTranscriptAnalysis([]int{0}, "TranscriptCo", "AutoTranscribe v2.0", "Alice: hello")
+	require.NoError(t, err)
+	assert.Equal(t, Analysis{Type: "transcript", Dialog: 0, MediaType: "text/plain", Vendor: "TranscriptCo",
+		Product: "AutoTranscribe v2.0", Body: "Alice: hello", Encoding: "none"}, v.Analysis[i])
+
+	segments := []map[string]any{{"speaker": 0, "text": "hello"}}
+	i, err = v.AddTranscriptAnalysis([]int{1}, "TranscriptCo", "", segments)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", v.Analysis[i].MediaType)
+	assert.Equal(t, "json", v.Analysis[i].Encoding)
+	assert.JSONEq(t, `[{"speaker":0,"text":"hello"}]`, v.Analysis[i].Body)
+
+	i, err = v.AddSummaryAnalysis([]int{0, 1}, "acme", "summarizer", "Alice said hello twice.")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, v.Analysis[i].Dialog)
+	assert.Equal(t, "summary", v.Analysis[i].Type)
+
+	i, err = v.AddSentimentAnalysis([]int{1}, "EmotionAI", "", Sentiment{Label: "positive", Score: 0.8})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"label":"positive","score":0.8}`, v.Analysis[i].Body)
+
+	loaded, err := BuildFromJSON(v.ToJSON())
+	require.NoError(t, err)
+	assert.Len(t, loaded.Analysis, 4)
+
+	_, err = v.AddSummaryAnalysis([]int{2}, "acme", "", "no such dialog")
+	assert.Error(t, err)
+	_, err = v.AddSummaryAnalysis(nil, "acme", "", "no dialog")
+	assert.Error(t, err)
+	_, err = v.AddSummaryAnalysis([]int{0}, "", "", "no vendor")
+	assert.Error(t, err)
+	_, err = v.AddSentimentAnalysis([]int{0}, "EmotionAI", "", Sentiment{Label: "ecstatic", Score: 2})
+	assert.Error(t, err)
+	assert.Len(t, v.Analysis, 4)
+}
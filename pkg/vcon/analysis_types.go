@@ -0,0 +1,82 @@
+package vcon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Analysis types written by the typed constructors.
+const (
+	AnalysisTypeTranscript = "transcript"
+	AnalysisTypeSummary    = "summary"
+	AnalysisTypeSentiment  = "sentiment"
+)
+
+// Sentiment is the body of a sentiment analysis.
+type Sentiment struct {
+	Label string  `json:"label"` // e.g. positive, neutral or negative
+	Score float64 `json:"score"` // from -1 (negative) to 1 (positive)
+}
+
+// AddTranscriptAnalysis adds a transcript of dialogs by vendor's product. A
+// string transcript is stored as text/plain; anything else, e.g. ASR
+// segments, is stored as JSON.
+func (v *VCon) AddTranscriptAnalysis(dialogs []int, vendor, product string, transcript any) (int, error) {
+	if text, ok := transcript.(string); ok {
+		return v.addTypedAnalysis(AnalysisTypeTranscript, dialogs, vendor, product, MIMETypePlainText, text, "none")
+	}
+	body, err := json.Marshal(transcript)
+	if err != nil {
+		return -1, fmt.Errorf("failed to marshal transcript: %w", err)
+	}
+	return v.addTypedAnalysis(AnalysisTypeTranscript, dialogs, vendor, product, MIMETypeJSON, string(body), "json")
+}
+
+// AddSummaryAnalysis adds a plain text summary of dialogs by vendor's
+// product.
+func (v *VCon) AddSummaryAnalysis(dialogs []int, vendor, product, summary string) (int, error) {
+	return v.addTypedAnalysis(AnalysisTypeSummary, dialogs, vendor, product, MIMETypePlainText, summary, "none")
+}
+
+// AddSentimentAnalysis adds the sentiment of dialogs, as JSON, by vendor's
+// product.
+func (v *VCon) AddSentimentAnalysis(dialogs []int, vendor, product string, sentiment Sentiment) (int, error) {
+	if sentiment.Score < -1 || sentiment.Score > 1 {
+		return -1, fmt.Errorf("sentiment score %g is outside [-1, 1]", sentiment.Score)
+	}
+	body, err := json.Marshal(sentiment)
+	if err != nil {
+		return -1, err
+	}
+	return v.addTypedAnalysis(AnalysisTypeSentiment, dialogs, vendor, product, MIMETypeJSON, string(body), "json")
+}
+
+// addTypedAnalysis adds an analysis after checking its vendor and dialog
+// references. One dialog is referenced by its index, several by a list.
+func (v *VCon) addTypedAnalysis(analysisType string, dialogs []int, vendor, product, mediaType, body, encoding string) (int, error) {
+	if vendor == "" {
+		return -1, errors.New("analysis vendor is required")
+	}
+	if len(dialogs) == 0 {
+		return -1, fmt.Errorf("%s analysis needs at least one dialog", analysisType)
+	}
+	for _, i := range dialogs {
+		if i < 0 || i >= len(v.Dialog) {
+			return -1, fmt.Errorf("dialog index %d out of range", i)
+		}
+	}
+	var dialog any = append([]int(nil), dialogs...)
+	if len(dialogs) == 1 {
+		dialog = dialogs[0]
+	}
+	return v.AddAnalysis(Analysis{
+		Type:      analysisType,
+		Dialog:    dialog,
+		MediaType: mediaType,
+		Vendor:    vendor,
+		Product:   product,
+		Body:      body,
+		Encoding:  encoding,
+	}), nil
+}
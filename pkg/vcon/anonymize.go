@@ -0,0 +1,218 @@
+package vcon
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// piiPatterns are replaced by ScrubText, in order: card and account numbers
+// before phone numbers, which would otherwise match their tails.
+var piiPatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[EMAIL]"},
+	{regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), "[NUMBER]"},
+	{regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), "[NUMBER]"},
+	{regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\b\d{3})[\s.-]?\d{3}[\s.-]?\d{4}\b`), "[PHONE]"},
+}
+
+// pseudonym names party i for PseudonymizeParties.
+func (r RedactionRules) pseudonym(i int, p Party) string {
+	if len(r.PseudonymKey) == 0 {
+		return fmt.Sprintf("Party %d", i+1)
+	}
+	identity := p.Name
+	for _, addr := range []string{p.Tel, p.Mailto, p.Sip, p.Did, p.UUID} {
+		if addr != "" {
+			identity = addr
+			break
+		}
+	}
+	mac := hmac.New(sha256.New, r.PseudonymKey)
+	mac.Write([]byte(strings.ToLower(identity)))
+	return "Party " + hex.EncodeToString(mac.Sum(nil))[:8]
+}
+
+// textScrubber replaces PII and party names in free text.
+type textScrubber struct {
+	names *strings.Replacer
+}
+
+func (r RedactionRules) newTextScrubber(v *VCon) *textScrubber {
+	var pairs []string
+	for i, p := range v.Parties {
+		if strings.TrimSpace(p.Name) == "" {
+			continue
+		}
+		replacement := "[NAME]"
+		if r.PseudonymizeParties {
+			replacement = r.pseudonym(i, p)
+		}
+		pairs = append(pairs, p.Name, replacement)
+	}
+	return &textScrubber{names: strings.NewReplacer(pairs...)}
+}
+
+func (s *textScrubber) text(in string) string {
+	out := s.names.Replace(in)
+	for _, p := range piiPatterns {
+		out = p.re.ReplaceAllString(out, p.replacement)
+	}
+	return out
+}
+
+// json scrubs every string in a JSON document, leaving keys and numbers
+// such as timestamps and offsets intact.
+func (s *textScrubber) json(data []byte) ([]byte, error) {
+	doc, err := decodeGenericJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s.value(doc))
+}
+
+func (s *textScrubber) value(v interface{}) interface{} {
+	switch x := v.(type) {
+	case string:
+		return s.text(x)
+	case map[string]interface{}:
+		for k, item := range x {
+			x[k] = s.value(item)
+		}
+	case []interface{}:
+		for i, item := range x {
+			x[i] = s.value(item)
+		}
+	}
+	return v
+}
+
+// body scrubs an inline body, keeping its encoding. ok is false when the
+// body is not text and cannot be scrubbed.
+func (s *textScrubber) body(body, encoding string) (out string, ok bool, err error) {
+	raw, err := decodeInlineBody(body, encoding)
+	if err != nil {
+		return "", false, err
+	}
+	if !utf8.Valid(raw) {
+		return "", false, nil
+	}
+	if encoding == "json" {
+		scrubbed, err := s.json(raw)
+		if err != nil {
+			return "", false, err
+		}
+		return string(scrubbed), true, nil
+	}
+	scrubbed := s.text(string(raw))
+	if encoding == "base64url" {
+		return encodeBase64URL([]byte(scrubbed)), true, nil
+	}
+	return scrubbed, true, nil
+}
+
+// scrubText applies ScrubText to v. It runs before parties are
+// pseudonymized so that their names can still be found.
+func (r RedactionRules) scrubText(v *VCon) error {
+	s := r.newTextScrubber(v)
+	v.Subject = s.text(v.Subject)
+
+	for i := range v.Dialog {
+		d := &v.Dialog[i]
+		if d.Type != "text" && !d.IsText() && !d.IsEmail() {
+			continue
+		}
+		if d.URL != "" {
+			d.URL, d.ContentHash, d.fetched = "", nil, nil
+		}
+		if d.Body == "" {
+			continue
+		}
+		body, ok, err := s.body(d.Body, d.Encoding)
+		if err != nil {
+			return fmt.Errorf("dialog %d: %w", i, err)
+		}
+		if !ok {
+			d.Body, d.Encoding = "", ""
+		} else {
+			d.Body = body
+		}
+		d.ContentHash = nil
+	}
+
+	for i := range v.Analysis {
+		a := &v.Analysis[i]
+		if a.URL != "" {
+			a.URL, a.ContentHash = "", nil
+		}
+		if a.Body == "" {
+			continue
+		}
+		body, ok, err := s.body(a.Body, a.Encoding)
+		if err != nil {
+			return fmt.Errorf("analysis %d: %w", i, err)
+		}
+		if !ok {
+			a.Body, a.Encoding = "", ""
+		} else {
+			a.Body = body
+		}
+		a.ContentHash = nil
+	}
+	return nil
+}
+
+// applyMedia applies VoiceTransform and DropMediaBodies to v.
+func (r RedactionRules) applyMedia(ctx context.Context, v *VCon) error {
+	for i := range v.Dialog {
+		d := &v.Dialog[i]
+		audio := d.IsAudio() || strings.HasPrefix(d.MediaType, "audio/")
+		video := d.IsVideo() || strings.HasPrefix(d.MediaType, "video/")
+		// A recording without a media type is treated as media too.
+		if !audio && !video && (d.Type != "recording" || d.MediaType != "") {
+			continue
+		}
+		if r.VoiceTransform != nil && audio && (d.Body != "" || d.URL != "") {
+			if err := r.transformVoice(ctx, d, pickFetcher(d.fetcher, v.contentFetcher())); err != nil {
+				return fmt.Errorf("dialog %d: %w", i, err)
+			}
+			continue
+		}
+		if r.DropMediaBodies {
+			d.Body, d.Encoding, d.URL = "", "", ""
+			d.ContentHash = nil
+			d.fetched = nil
+		}
+	}
+	return nil
+}
+
+func (r RedactionRules) transformVoice(ctx context.Context, d *Dialog, fetcher ContentFetcher) error {
+	var media []byte
+	var err error
+	if d.URL != "" {
+		media, _, err = retainedOrFetch(ctx, fetcher, d.URL, d.fetched, d.ContentHash)
+	} else {
+		media, err = decodeInlineBody(d.Body, d.Encoding)
+	}
+	if err != nil {
+		return err
+	}
+	out, mediaType, err := r.VoiceTransform(media, d.MediaType)
+	if err != nil {
+		return fmt.Errorf("voice transform: %w", err)
+	}
+	d.Body, d.Encoding, d.URL = encodeBase64URL(out), "base64url", ""
+	d.MediaType = mediaType
+	d.ContentHash = ContentHashList{ComputeSHA512(out)}
+	d.fetched = nil
+	return nil
+}
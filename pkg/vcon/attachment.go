@@ -1,8 +1,9 @@
 package vcon
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -22,7 +23,9 @@ const (
 	AttachmentTypeDocument AttachmentType = "document"
 )
 
-// Attachment is a file linked to a dialog/party.
+// Attachment is a file linked to a dialog/party. A body that is not a JSON
+// string, such as the array of the Python library's tags attachment, is kept
+// verbatim in Extra["body"].
 type Attachment struct {
 	Body        string          `json:"body,omitempty"`
 	Encoding    string          `json:"encoding,omitempty"`
@@ -34,6 +37,15 @@ type Attachment struct {
 	MediaType   string          `json:"mediatype,omitempty"`
 	Filename    string          `json:"filename,omitempty"`
 	Purpose     string          `json:"purpose,omitempty"`
+	// Type is the name purpose had before v0.0.3; the Python library still
+	// writes it for its tags attachment.
+	Type string `json:"type,omitempty"`
+
+	// Extra holds non-standard properties, such as extension parameters.
+	Extra ExtraProperties `json:"-"`
+
+	// handle identifies the attachment to its AttachmentRefs
+	handle uint64
 }
 
 // IntPtr returns a pointer to the given int value.
@@ -87,17 +99,21 @@ func NewAttachment(attachmentType string, body interface{}, encoding string) (*A
 func (a *Attachment) GetBody() (interface{}, error) {
 	switch a.Encoding {
 	case "base64url":
-		// Decode base64url if needed
-		decoded, err := base64.URLEncoding.DecodeString(a.Body)
+		// Padded or not, as dialog bodies are decoded
+		decoded, err := decodeInlineBody(a.Body, a.Encoding)
 		if err != nil {
-			return nil, fmt.Errorf("failed to decode base64url body: %w", err)
+			return nil, err
 		}
 		return string(decoded), nil
 
 	case "json":
 		// Parse JSON if needed
+		data := []byte(a.Body)
+		if raw, ok := a.Extra["body"]; ok && a.Body == "" {
+			data = raw
+		}
 		var result interface{}
-		if err := json.Unmarshal([]byte(a.Body), &result); err != nil {
+		if err := json.Unmarshal(data, &result); err != nil {
 			return nil, fmt.Errorf("failed to parse JSON body: %w", err)
 		}
 		return result, nil
@@ -106,3 +122,117 @@ func (a *Attachment) GetBody() (interface{}, error) {
 		return a.Body, nil
 	}
 }
+
+// ToInlineData converts the attachment from external to inline content,
+// encoded as Analysis.ToInlineData encodes it.
+func (a *Attachment) ToInlineData() error {
+	return a.ToInlineDataContext(context.Background())
+}
+
+// ToInlineDataContext is ToInlineData with a context controlling the fetch.
+func (a *Attachment) ToInlineDataContext(ctx context.Context) error {
+	return a.toInlineData(ctx, DefaultContentFetcher)
+}
+
+func (a *Attachment) toInlineData(ctx context.Context, fetcher ContentFetcher) error {
+	if a.URL == "" {
+		return errors.New("attachment is not external data")
+	}
+
+	body, err := inlineExternal(ctx, fetcher, a.URL, nil, &a.MediaType, &a.Filename, &a.ContentHash)
+	if err != nil {
+		return err
+	}
+
+	a.Body, a.Encoding = encodeInlineContent(a.MediaType, body)
+	a.URL = ""
+	return nil
+}
+
+// IsReference reports whether the attachment carries no content of its own
+// and refers by content_hash to another attachment, as left by
+// DedupeAttachments.
+func (a *Attachment) IsReference() bool {
+	return a.Body == "" && a.URL == "" && !a.ContentHash.IsEmpty()
+}
+
+// DedupeAttachments stores each distinct attachment body only once. Inline
+// bodies are compared by the SHA-512 of their decoded content. A duplicate
+// whose metadata matches an earlier attachment exactly is removed; one that
+// differs (typically the party it is attached for) keeps its metadata and
+// becomes a reference with only a content_hash, resolved by
+// AttachmentContent. It returns the number of bodies dropped.
+func (v *VCon) DedupeAttachments() int {
+	first := map[string]int{}
+	dropped := 0
+	kept := v.Attachments[:0]
+	for _, att := range v.Attachments {
+		if att.URL != "" || att.Body == "" {
+			kept = append(kept, att)
+			continue
+		}
+		data, err := decodeInlineBody(att.Body, att.Encoding)
+		if err != nil {
+			kept = append(kept, att)
+			continue
+		}
+		hash := ComputeSHA512(data)
+		key := hash.String()
+
+		idx, seen := first[key]
+		if !seen {
+			if att.ContentHash.IsEmpty() {
+				att.ContentHash = ContentHashList{hash}
+			}
+			first[key] = len(kept)
+			kept = append(kept, att)
+			continue
+		}
+
+		dropped++
+		if sameAttachmentMetadata(kept[idx], att) {
+			continue
+		}
+		att.Body, att.Encoding = "", ""
+		att.ContentHash = ContentHashList{hash}
+		kept = append(kept, att)
+	}
+	v.Attachments = kept
+	return dropped
+}
+
+func sameAttachmentMetadata(a, b Attachment) bool {
+	return a.PartyIdx == b.PartyIdx &&
+		((a.DialogIdx == nil && b.DialogIdx == nil) ||
+			(a.DialogIdx != nil && b.DialogIdx != nil && *a.DialogIdx == *b.DialogIdx)) &&
+		a.StartTime.Equal(b.StartTime) &&
+		a.MediaType == b.MediaType &&
+		a.Filename == b.Filename &&
+		a.Purpose == b.Purpose
+}
+
+// AttachmentContent returns the decoded content of the attachment at index
+// i. References left by DedupeAttachments are resolved to the attachment
+// holding the body with the same content hash.
+func (v *VCon) AttachmentContent(i int) ([]byte, error) {
+	if i < 0 || i >= len(v.Attachments) {
+		return nil, fmt.Errorf("attachment index %d out of range", i)
+	}
+	att := v.Attachments[i]
+	if !att.IsReference() {
+		if att.URL != "" {
+			return nil, errors.New("attachment content is external; fetch it from its URL")
+		}
+		return decodeInlineBody(att.Body, att.Encoding)
+	}
+	for _, other := range v.Attachments {
+		if other.Body == "" || other.URL != "" {
+			continue
+		}
+		data, err := decodeInlineBody(other.Body, other.Encoding)
+		if err == nil && att.ContentHash.Verify(data) {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("no attachment holds the content referenced by attachment %d", i)
+}
@@ -0,0 +1,123 @@
+package vcon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
+)
+
+// AttachmentFileOption configures NewAttachmentFromFile.
+type AttachmentFileOption func(*attachmentFileConfig)
+
+type attachmentFileConfig struct {
+	mediaType string
+	purpose   string
+	party     int
+	dialog    int
+	start     time.Time
+	store     BlobWriter
+}
+
+// WithAttachmentMediaType sets the media type instead of detecting it from
+// the file name and content.
+func WithAttachmentMediaType(mt string) AttachmentFileOption {
+	return func(c *attachmentFileConfig) {
+		c.mediaType = mediatype.Normalize(mt)
+	}
+}
+
+// WithAttachmentPurpose sets the attachment's purpose.
+func WithAttachmentPurpose(purpose string) AttachmentFileOption {
+	return func(c *attachmentFileConfig) {
+		c.purpose = purpose
+	}
+}
+
+// WithAttachmentParty sets the index of the party contributing the
+// attachment (default 0).
+func WithAttachmentParty(i int) AttachmentFileOption {
+	return func(c *attachmentFileConfig) {
+		c.party = i
+	}
+}
+
+// WithAttachmentDialog sets the index of the dialog the attachment belongs
+// to (default 0).
+func WithAttachmentDialog(i int) AttachmentFileOption {
+	return func(c *attachmentFileConfig) {
+		c.dialog = i
+	}
+}
+
+// WithAttachmentStart sets the start time instead of the file's
+// modification time.
+func WithAttachmentStart(t time.Time) AttachmentFileOption {
+	return func(c *attachmentFileConfig) {
+		c.start = t
+	}
+}
+
+// WithAttachmentStore references the file externally: its content is
+// written to store and the attachment carries the returned URL instead of an
+// inline body.
+func WithAttachmentStore(store BlobWriter) AttachmentFileOption {
+	return func(c *attachmentFileConfig) {
+		c.store = store
+	}
+}
+
+// NewAttachmentFromFile creates an Attachment holding the file at path,
+// base64url encoded. The media type is detected from the name and content,
+// the filename and content_hash are filled in and the start time is the
+// file's modification time; options override these.
+func NewAttachmentFromFile(path string, opts ...AttachmentFileOption) (*Attachment, error) {
+	return NewAttachmentFromFileContext(context.Background(), path, opts...)
+}
+
+// NewAttachmentFromFileContext is NewAttachmentFromFile with a context
+// passed on to the store given with WithAttachmentStore.
+func NewAttachmentFromFileContext(ctx context.Context, path string, opts ...AttachmentFileOption) (*Attachment, error) {
+	cfg := attachmentFileConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if cfg.start.IsZero() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.start = info.ModTime().UTC()
+	}
+	filename := filepath.Base(path)
+	if cfg.mediaType == "" {
+		cfg.mediaType = mediatype.Detect(filename, data)
+	}
+
+	att := &Attachment{
+		DialogIdx:   IntPtr(cfg.dialog),
+		PartyIdx:    cfg.party,
+		StartTime:   cfg.start,
+		MediaType:   cfg.mediaType,
+		Filename:    filename,
+		Purpose:     cfg.purpose,
+		ContentHash: ContentHashList{ComputeSHA512(data)},
+	}
+	if cfg.store != nil {
+		att.URL, err = cfg.store.WriteBlob(ctx, att.ContentHash.First(), att.MediaType, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store %s: %w", filename, err)
+		}
+		return att, nil
+	}
+	att.Body, att.Encoding = encodeBase64URL(data), "base64url"
+	return att, nil
+}
@@ -1,7 +1,11 @@
 package vcon
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -348,3 +352,113 @@ func TestAttachmentDialogRequired(t *testing.T) {
 		t.Error("expected error for attachment missing required 'dialog' field")
 	}
 }
+
+func TestDedupeAttachments(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pdf := encodeBase64URL([]byte("%PDF-1.7 agenda"))
+	v := New("example.com")
+	for party := 0; party < 3; party++ {
+		v.AddAttachment(Attachment{Body: pdf, Encoding: "base64url", DialogIdx: IntPtr(0), PartyIdx: party, StartTime: start, Filename: "agenda.pdf"})
+	}
+	// Exact duplicate of the first attachment, padded encoding.
+	v.AddAttachment(Attachment{Body: base64.URLEncoding.EncodeToString([]byte("%PDF-1.7 agenda")), Encoding: "base64url", DialogIdx: IntPtr(0), PartyIdx: 0, StartTime: start, Filename: "agenda.pdf"})
+	v.AddAttachment(Attachment{Body: "notes", Encoding: "none", DialogIdx: IntPtr(0), StartTime: start})
+
+	if dropped := v.DedupeAttachments(); dropped != 3 {
+		t.Fatalf("dropped = %d, want 3", dropped)
+	}
+	if len(v.Attachments) != 4 {
+		t.Fatalf("len(Attachments) = %d, want 4", len(v.Attachments))
+	}
+	if v.Attachments[0].Body != pdf || v.Attachments[0].ContentHash.IsEmpty() {
+		t.Errorf("first copy should keep its body and gain a hash: %+v", v.Attachments[0])
+	}
+	for i := 1; i <= 2; i++ {
+		att := v.Attachments[i]
+		if !att.IsReference() || att.PartyIdx != i {
+			t.Errorf("attachment %d should be a reference for party %d: %+v", i, i, att)
+		}
+		data, err := v.AttachmentContent(i)
+		if err != nil || string(data) != "%PDF-1.7 agenda" {
+			t.Errorf("AttachmentContent(%d) = %q, %v", i, data, err)
+		}
+	}
+	if v.Attachments[3].Body != "notes" {
+		t.Errorf("distinct attachment changed: %+v", v.Attachments[3])
+	}
+
+	data, _ := json.Marshal(v.Attachments[1])
+	if strings.Contains(string(data), `"body"`) {
+		t.Errorf("reference still serializes a body: %s", data)
+	}
+
+	v.Attachments[0].Body = encodeBase64URL([]byte("changed"))
+	if _, err := v.AttachmentContent(1); err == nil {
+		t.Error("expected error for dangling reference")
+	}
+}
+
+func TestNewAttachmentFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agenda.pdf")
+	content := []byte("%PDF-1.7 agenda")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	att, err := NewAttachmentFromFile(path, WithAttachmentPurpose("agenda"), WithAttachmentParty(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if att.MediaType != "application/pdf" || att.Filename != "agenda.pdf" || att.Purpose != "agenda" {
+		t.Errorf("unexpected metadata: %+v", att)
+	}
+	if !att.StartTime.Equal(mtime) || att.PartyIdx != 1 || att.DialogIdx == nil || *att.DialogIdx != 0 {
+		t.Errorf("unexpected start or references: %+v", att)
+	}
+	if att.Encoding != "base64url" || !att.ContentHash.Verify(content) {
+		t.Errorf("unexpected encoding or hash: %+v", att)
+	}
+	if body, err := att.GetBody(); err != nil || body != string(content) {
+		t.Errorf("body = %q, %v", body, err)
+	}
+
+	// Content whose length is not a multiple of three is written unpadded.
+	hello := filepath.Join(dir, "hello.txt")
+	if err := os.WriteFile(hello, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	short, err := NewAttachmentFromFile(hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := short.GetBody(); err != nil || body != "hello" {
+		t.Errorf("body = %q, %v", body, err)
+	}
+
+	store := DirBlobWriter{Dir: filepath.Join(dir, "blobs"), BaseURL: "https://media.example.com/vcon"}
+	start := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	ext, err := NewAttachmentFromFileContext(context.Background(), path,
+		WithAttachmentStore(store), WithAttachmentStart(start), WithAttachmentMediaType("Application/PDF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext.Body != "" || ext.Encoding != "" || !strings.HasPrefix(ext.URL, store.BaseURL+"/") {
+		t.Errorf("expected an external reference: %+v", ext)
+	}
+	if !ext.StartTime.Equal(start) || ext.MediaType != "application/pdf" {
+		t.Errorf("options not applied: %+v", ext)
+	}
+	stored, err := os.ReadFile(filepath.Join(store.Dir, ext.ContentHash.First().Hash+".pdf"))
+	if err != nil || string(stored) != string(content) {
+		t.Errorf("stored blob = %q, %v", stored, err)
+	}
+
+	if _, err := NewAttachmentFromFile(filepath.Join(dir, "missing.pdf")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
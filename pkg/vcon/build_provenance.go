@@ -0,0 +1,100 @@
+package vcon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BuildProvenancePurpose is the purpose of the attachment holding a vCon's
+// BuildProvenance.
+const BuildProvenancePurpose = "build_provenance"
+
+// BuildProvenance records how a vCon was built from external artefacts,
+// such as a recording or a mailbox, so consumers can trace it back to them:
+// the tool that built it, a hash of every source and the processing steps.
+type BuildProvenance struct {
+	Tool    BuildTool     `json:"tool"`
+	Sources []BuildSource `json:"sources,omitempty"`
+	Steps   []BuildStep   `json:"steps,omitempty"`
+	BuiltAt time.Time     `json:"built_at"`
+}
+
+// BuildTool identifies the program that built a vCon.
+type BuildTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// BuildSource is one artefact a vCon was built from.
+type BuildSource struct {
+	URI         string          `json:"uri"`
+	ContentHash ContentHashList `json:"content_hash"`
+}
+
+// BuildStep is one processing step, with the parameters it was run with.
+type BuildStep struct {
+	Name       string            `json:"name"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// NewBuildSource returns the source at uri with the SHA-512 of its data.
+func NewBuildSource(uri string, data []byte) BuildSource {
+	return BuildSource{URI: uri, ContentHash: ContentHashList{ComputeSHA512(data)}}
+}
+
+// AttachBuildProvenance stores p as a JSON attachment with purpose
+// BuildProvenancePurpose, replacing any recorded before. The attachment
+// belongs to the first party and dialog, so v needs at least one of each.
+// BuiltAt defaults to now.
+func (v *VCon) AttachBuildProvenance(p BuildProvenance) error {
+	if len(v.Parties) == 0 || len(v.Dialog) == 0 {
+		return errors.New("build provenance needs a party and a dialog to attach to")
+	}
+	if p.BuiltAt.IsZero() {
+		p.BuiltAt = time.Now().UTC()
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	att := Attachment{
+		Body:      string(body),
+		Encoding:  "json",
+		MediaType: "application/json",
+		DialogIdx: IntPtr(0),
+		PartyIdx:  0,
+		StartTime: p.BuiltAt,
+		Purpose:   BuildProvenancePurpose,
+	}
+	if i := v.buildProvenanceIndex(); i >= 0 {
+		v.Attachments[i] = att
+	} else {
+		v.AddAttachment(att)
+	}
+	return nil
+}
+
+// BuildProvenance returns the provenance recorded by AttachBuildProvenance,
+// or nil when there is none.
+func (v *VCon) BuildProvenance() (*BuildProvenance, error) {
+	i := v.buildProvenanceIndex()
+	if i < 0 {
+		return nil, nil
+	}
+	var p BuildProvenance
+	if err := json.Unmarshal([]byte(v.Attachments[i].Body), &p); err != nil {
+		return nil, fmt.Errorf("attachment %d: %w", i, err)
+	}
+	return &p, nil
+}
+
+func (v *VCon) buildProvenanceIndex() int {
+	for i, att := range v.Attachments {
+		if att.Purpose == BuildProvenancePurpose && att.Encoding == "json" {
+			return i
+		}
+	}
+	return -1
+}
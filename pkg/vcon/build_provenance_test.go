@@ -0,0 +1,53 @@
+package vcon
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildProvenance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+
+	p := BuildProvenance{
+		Tool:    BuildTool{Name: "vconctl", Version: "v1.2.3"},
+		Sources: []BuildSource{NewBuildSource("call.wav", []byte("RIFF"))},
+		Steps:   []BuildStep{{Name: "convert audio", Parameters: map[string]string{"date": "2024-01-01T10:00:00Z"}}},
+		BuiltAt: start,
+	}
+	assert.Error(t, v.AttachBuildProvenance(p), "no party or dialog to attach to")
+
+	none, err := v.BuildProvenance()
+	assert.NoError(t, err)
+	assert.Nil(t, none)
+
+	v.AddParty(Party{Name: "Alice"})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0), URL: "https://example.com/call.wav"})
+	require.NoError(t, v.AttachBuildProvenance(p))
+	require.Len(t, v.Attachments, 1)
+	assert.Equal(t, BuildProvenancePurpose, v.Attachments[0].Purpose)
+	assert.True(t, v.Attachments[0].StartTime.Equal(start))
+	ok, issues := v.IsValid()
+	assert.True(t, ok, "%v", issues)
+
+	// Attaching again replaces the record.
+	p.Tool.Version = "v1.2.4"
+	require.NoError(t, v.AttachBuildProvenance(p))
+	require.Len(t, v.Attachments, 1)
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	loaded, err := BuildFromJSON(string(data), PropertyHandlingStrict)
+	require.NoError(t, err)
+	got, err := loaded.BuildProvenance()
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "v1.2.4", got.Tool.Version)
+	assert.Equal(t, ComputeSHA512([]byte("RIFF")), got.Sources[0].ContentHash.First())
+	assert.Equal(t, p.Steps, got.Steps)
+	assert.True(t, got.BuiltAt.Equal(start))
+}
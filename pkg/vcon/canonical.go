@@ -1,7 +1,10 @@
 package vcon
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"sort"
 
 	jc "github.com/cyberphone/json-canonicalization/go/src/webpki.org/jsoncanonicalizer"
 )
@@ -14,3 +17,175 @@ func Canonicalise(v any) ([]byte, error) {
 	}
 	return jc.Transform(raw)
 }
+
+// MarshalCanonical returns the RFC 8785 canonical encoding of the vCon, the
+// same bytes Sign uses as its payload. Equal vCons always encode to equal
+// bytes, so the result can be hashed or stored by hash.
+func (v *VCon) MarshalCanonical() ([]byte, error) {
+	return Canonicalise(v)
+}
+
+// CanonicalHash returns the SHA-512 content hash of MarshalCanonical.
+func (v *VCon) CanonicalHash() (ContentHash, error) {
+	canon, err := v.MarshalCanonical()
+	if err != nil {
+		return ContentHash{}, err
+	}
+	return ComputeSHA512(canon), nil
+}
+
+// CanonicalDiff explains why a signed payload is not the canonical encoding
+// of the vCon it decodes to.
+type CanonicalDiff struct {
+	// Offset is the first byte at which the payload and its canonical
+	// re-encoding differ.
+	Offset int `json:"offset"`
+	// Payload and Canonical are excerpts of both around Offset.
+	Payload   string `json:"payload"`
+	Canonical string `json:"canonical"`
+	// Fields lists the properties whose values change when the payload is
+	// decoded and re-encoded. It is empty when only the serialization
+	// differs (key order, whitespace, number formatting or escaping).
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// FieldDiff is one property that differs, identified by its JSON pointer.
+// An empty Canonical means the property is dropped on re-encoding, an empty
+// Payload that it is added.
+type FieldDiff struct {
+	Path      string `json:"path"`
+	Payload   string `json:"payload,omitempty"`
+	Canonical string `json:"canonical,omitempty"`
+}
+
+// NonCanonicalError is returned by Verify when the signed payload is not
+// RFC 8785 canonical.
+type NonCanonicalError struct {
+	Diff *CanonicalDiff
+}
+
+func (e *NonCanonicalError) Error() string {
+	msg := "payload not RFC 8785 canonical"
+	if e.Diff == nil {
+		return msg
+	}
+	msg += fmt.Sprintf(": first difference at byte %d", e.Diff.Offset)
+	if len(e.Diff.Fields) > 0 {
+		msg += fmt.Sprintf(" (%s)", e.Diff.Fields[0].Path)
+	}
+	return msg
+}
+
+// canonicalExcerpt is the number of bytes shown on each side of a difference.
+const canonicalExcerpt = 32
+
+// DiagnoseCanonical decodes payload as a vCon, re-encodes it canonically and
+// reports where the two diverge. It returns nil when payload is canonical.
+func DiagnoseCanonical(payload []byte) (*CanonicalDiff, error) {
+	var v VCon
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, fmt.Errorf("decode vCon: %w", err)
+	}
+	canon, err := Canonicalise(&v)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalise vCon: %w", err)
+	}
+	return diffCanonical(payload, canon)
+}
+
+func diffCanonical(payload, canon []byte) (*CanonicalDiff, error) {
+	if bytes.Equal(payload, canon) {
+		return nil, nil
+	}
+
+	offset := 0
+	for offset < len(payload) && offset < len(canon) && payload[offset] == canon[offset] {
+		offset++
+	}
+	diff := &CanonicalDiff{
+		Offset:    offset,
+		Payload:   excerpt(payload, offset),
+		Canonical: excerpt(canon, offset),
+	}
+
+	got, err := decodeGenericJSON(payload)
+	if err != nil {
+		return nil, err
+	}
+	want, err := decodeGenericJSON(canon)
+	if err != nil {
+		return nil, err
+	}
+	diffValues("", got, want, &diff.Fields)
+	return diff, nil
+}
+
+func excerpt(data []byte, offset int) string {
+	start := max(offset-canonicalExcerpt, 0)
+	end := min(offset+canonicalExcerpt, len(data))
+	return string(data[start:end])
+}
+
+func decodeGenericJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// diffValues appends the JSON pointers at which got and want differ.
+func diffValues(path string, got, want interface{}, out *[]FieldDiff) {
+	switch g := got.(type) {
+	case map[string]interface{}:
+		if w, ok := want.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(g)+len(w))
+			for k := range g {
+				keys = append(keys, k)
+			}
+			for k := range w {
+				if _, dup := g[k]; !dup {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				diffValues(path+"/"+escapeJSONPointer(k), g[k], w[k], out)
+			}
+			return
+		}
+	case []interface{}:
+		if w, ok := want.([]interface{}); ok {
+			for i := 0; i < len(g) || i < len(w); i++ {
+				var gi, wi interface{}
+				if i < len(g) {
+					gi = g[i]
+				}
+				if i < len(w) {
+					wi = w[i]
+				}
+				diffValues(fmt.Sprintf("%s/%d", path, i), gi, wi, out)
+			}
+			return
+		}
+	}
+
+	gotJSON, wantJSON := compactJSON(got), compactJSON(want)
+	if gotJSON != wantJSON {
+		if path == "" {
+			path = "/"
+		}
+		*out = append(*out, FieldDiff{Path: path, Payload: gotJSON, Canonical: wantJSON})
+	}
+}
+
+// compactJSON renders a decoded value; absent values render as "".
+func compactJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
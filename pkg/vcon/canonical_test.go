@@ -272,3 +272,90 @@ func TestCanonicaliseWithStrings(t *testing.T) {
 		t.Error("expected escaped newline")
 	}
 }
+
+func TestDiagnoseCanonical(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	canon, err := Canonicalise(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := DiagnoseCanonical(canon)
+	if err != nil || diff != nil {
+		t.Fatalf("canonical payload reported as %+v, %v", diff, err)
+	}
+
+	// Same data, pretty-printed: only the serialization differs.
+	pretty, _ := json.MarshalIndent(v, "", "  ")
+	diff, err = DiagnoseCanonical(pretty)
+	if err != nil || diff == nil {
+		t.Fatalf("expected a diff, got %+v, %v", diff, err)
+	}
+	if diff.Offset != 1 || len(diff.Fields) != 0 {
+		t.Errorf("pretty-printed payload: offset %d, fields %+v", diff.Offset, diff.Fields)
+	}
+
+	// An empty property dropped and a timestamp rewritten on re-encoding.
+	var m map[string]interface{}
+	json.Unmarshal(canon, &m)
+	m["subject"] = ""
+	m["created_at"] = "2024-01-01T00:00:00.000Z"
+	payload, _ := json.Marshal(m)
+	diff, err = DiagnoseCanonical(payload)
+	if err != nil || diff == nil {
+		t.Fatalf("expected a diff, got %+v, %v", diff, err)
+	}
+	got := map[string]FieldDiff{}
+	for _, f := range diff.Fields {
+		got[f.Path] = f
+	}
+	if f, ok := got["/subject"]; !ok || f.Canonical != "" {
+		t.Errorf("expected /subject to be reported as dropped, got %+v", diff.Fields)
+	}
+	if f, ok := got["/created_at"]; !ok || f.Canonical != `"2024-01-01T00:00:00Z"` {
+		t.Errorf("expected /created_at to be reported as rewritten, got %+v", diff.Fields)
+	}
+
+	err = &NonCanonicalError{Diff: diff}
+	if !strings.Contains(err.Error(), "payload not RFC 8785 canonical: first difference at byte") {
+		t.Errorf("unexpected message %q", err)
+	}
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	v := New("example.com")
+	v.Subject = "Café <support> & billing"
+	v.AddParty(Party{Name: "Alice", Tel: "tel:+12025551234"})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Body: "ok", Encoding: "none"})
+
+	canon, err := v.MarshalCanonical()
+	if err != nil {
+		t.Fatalf("MarshalCanonical: %v", err)
+	}
+	if want, _ := Canonicalise(v); string(canon) != string(want) {
+		t.Fatalf("MarshalCanonical differs from Canonicalise:\n%s\n%s", canon, want)
+	}
+	if !strings.Contains(string(canon), `"subject":"Café <support> & billing"`) {
+		t.Errorf("expected unescaped subject in %s", canon)
+	}
+
+	// A pretty-printed copy loaded back encodes to the same bytes and hash.
+	pretty, _ := json.MarshalIndent(v, "", "  ")
+	reloaded, err := BuildFromJSON(string(pretty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := reloaded.MarshalCanonical()
+	if err != nil || string(again) != string(canon) {
+		t.Errorf("reloaded canonical form differs:\n%s\n%s", canon, again)
+	}
+
+	hash, err := v.CanonicalHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hash.Verify(canon) {
+		t.Errorf("hash %s does not match the canonical bytes", hash)
+	}
+}
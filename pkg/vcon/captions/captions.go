@@ -0,0 +1,280 @@
+// Package captions parses WebVTT and SRT caption files, such as the ones
+// Zoom and Teams export with meeting recordings, into structured vCon
+// transcripts.
+//
+// Cue timing is kept as offsets from the start of the recording, and the
+// speaker of each cue is taken from a WebVTT voice span (<v Alice>) or,
+// failing that, from a "Name: text" prefix as Zoom writes it.
+package captions
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+// Format is a caption file format.
+type Format string
+
+const (
+	FormatVTT Format = "vtt"
+	FormatSRT Format = "srt"
+)
+
+// Cue is one caption: the text shown between Start and End, measured from
+// the start of the recording.
+type Cue struct {
+	Start   time.Duration
+	End     time.Duration
+	Speaker string // "" when the cue names no speaker
+	Text    string
+}
+
+var (
+	voiceTag      = regexp.MustCompile(`<v(?:\.[^\s>]*)?\s+([^>]*)>`)
+	markupTag     = regexp.MustCompile(`<[^>]*>`)
+	speakerPrefix = regexp.MustCompile(`^([\p{L}\p{N}][\p{L}\p{N} .'’()@_-]{0,63}):\s+(.+)$`)
+)
+
+// ParseFile reads a caption file, choosing the format from its extension
+// or, failing that, from a WEBVTT header.
+func ParseFile(path string) ([]Cue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := Format(strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")))
+	if format != FormatVTT && format != FormatSRT {
+		format = FormatSRT
+		if bytes.HasPrefix(bytes.TrimPrefix(data, []byte("\ufeff")), []byte("WEBVTT")) {
+			format = FormatVTT
+		}
+	}
+	cues, err := Parse(bytes.NewReader(data), format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cues, nil
+}
+
+// Parse reads captions in the given format.
+func Parse(r io.Reader, format Format) ([]Cue, error) {
+	switch format {
+	case FormatVTT:
+		return ParseVTT(r)
+	case FormatSRT:
+		return ParseSRT(r)
+	}
+	return nil, fmt.Errorf("unsupported caption format %q", format)
+}
+
+// ParseVTT reads a WebVTT file. NOTE, STYLE and REGION blocks are skipped,
+// and markup other than the speaker of a voice span is removed.
+func ParseVTT(r io.Reader) ([]Cue, error) {
+	blocks, err := readBlocks(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 || !isVTTHeader(blocks[0].lines[0]) {
+		return nil, errors.New("missing WEBVTT header")
+	}
+	var cues []Cue
+	for _, b := range blocks[1:] {
+		switch strings.SplitN(b.lines[0], " ", 2)[0] {
+		case "NOTE", "STYLE", "REGION":
+			continue
+		}
+		cue, ok, err := parseCue(b, true)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			cues = append(cues, cue)
+		}
+	}
+	return cues, nil
+}
+
+// ParseSRT reads a SubRip file. Formatting tags such as <i> are removed.
+func ParseSRT(r io.Reader) ([]Cue, error) {
+	blocks, err := readBlocks(r)
+	if err != nil {
+		return nil, err
+	}
+	var cues []Cue
+	for _, b := range blocks {
+		cue, ok, err := parseCue(b, false)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			cues = append(cues, cue)
+		}
+	}
+	return cues, nil
+}
+
+// ToTranscript converts cues to a transcript, giving each segment the index
+// of the party whose name matches its speaker, ignoring case. Speakers that
+// match no party keep their label with no party index.
+func ToTranscript(cues []Cue, parties []vcon.Party) *vcon.Transcript {
+	t := &vcon.Transcript{Segments: make([]vcon.TranscriptSegment, 0, len(cues))}
+	for _, c := range cues {
+		s := vcon.TranscriptSegment{
+			Speaker: c.Speaker,
+			Start:   c.Start.Seconds(),
+			End:     c.End.Seconds(),
+			Text:    c.Text,
+		}
+		if c.Speaker != "" {
+			for i, p := range parties {
+				if strings.EqualFold(strings.TrimSpace(p.Name), c.Speaker) {
+					s.Party = vcon.IntPtr(i)
+					break
+				}
+			}
+		}
+		t.Segments = append(t.Segments, s)
+	}
+	return t
+}
+
+// Attach adds cues as the transcript of a dialog, matching speakers to the
+// vCon's parties as ToTranscript does. The cue timing must be relative to
+// the start of the dialog.
+func Attach(v *vcon.VCon, dialog int, vendor, product string, cues []Cue) (int, error) {
+	return v.AttachTranscript(dialog, vendor, product, ToTranscript(cues, v.Parties))
+}
+
+// block is a run of non-blank lines and the line number it starts on.
+type block struct {
+	line  int
+	lines []string
+}
+
+func readBlocks(r io.Reader) ([]block, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var (
+		blocks []block
+		cur    *block
+	)
+	for i, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			blocks = append(blocks, block{line: i + 1})
+			cur = &blocks[len(blocks)-1]
+		}
+		cur.lines = append(cur.lines, line)
+	}
+	return blocks, nil
+}
+
+func isVTTHeader(line string) bool {
+	return line == "WEBVTT" || strings.HasPrefix(line, "WEBVTT ") || strings.HasPrefix(line, "WEBVTT\t")
+}
+
+// parseCue reads a block of an optional identifier, a timing line and the
+// cue text. Blocks without a timing line are not cues.
+func parseCue(b block, vtt bool) (Cue, bool, error) {
+	timing := -1
+	for i, line := range b.lines {
+		if strings.Contains(line, "-->") {
+			timing = i
+			break
+		}
+	}
+	if timing < 0 {
+		return Cue{}, false, nil
+	}
+	lineNo := b.line + timing
+	from, to, _ := strings.Cut(b.lines[timing], "-->")
+	start, err := parseTimestamp(strings.TrimSpace(from))
+	if err != nil {
+		return Cue{}, false, fmt.Errorf("line %d: %w", lineNo, err)
+	}
+	// WebVTT cue settings follow the end time.
+	to = strings.TrimSpace(to)
+	if i := strings.IndexAny(to, " \t"); i >= 0 {
+		to = to[:i]
+	}
+	end, err := parseTimestamp(to)
+	if err != nil {
+		return Cue{}, false, fmt.Errorf("line %d: %w", lineNo, err)
+	}
+	if end < start {
+		return Cue{}, false, fmt.Errorf("line %d: cue ends before it starts", lineNo)
+	}
+
+	payload := strings.Join(b.lines[timing+1:], "\n")
+	var speaker string
+	if vtt {
+		if m := voiceTag.FindStringSubmatch(payload); m != nil {
+			speaker = strings.TrimSpace(m[1])
+		}
+	}
+	payload = markupTag.ReplaceAllString(payload, "")
+	if vtt {
+		payload = html.UnescapeString(payload)
+	}
+	lines := strings.Split(payload, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	text := strings.TrimSpace(strings.Join(lines, " "))
+	if speaker == "" {
+		if m := speakerPrefix.FindStringSubmatch(text); m != nil {
+			speaker, text = strings.TrimSpace(m[1]), m[2]
+		}
+	}
+	if text == "" {
+		return Cue{}, false, nil
+	}
+	return Cue{Start: start, End: end, Speaker: speaker, Text: text}, true, nil
+}
+
+// parseTimestamp reads [hh:]mm:ss.ttt, accepting a comma before the
+// fraction as SRT writes it.
+func parseTimestamp(s string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid timestamp %q", s)
+	clock, frac, ok := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	if !ok || len(frac) == 0 || len(frac) > 3 {
+		return 0, invalid
+	}
+	parts := strings.Split(clock, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, invalid
+	}
+	var d time.Duration
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (i > 0 && (len(p) != 2 || n > 59)) {
+			return 0, invalid
+		}
+		d = d*60 + time.Duration(n)
+	}
+	ms, err := strconv.Atoi(frac + strings.Repeat("0", 3-len(frac)))
+	if err != nil || ms < 0 {
+		return 0, invalid
+	}
+	return d*time.Second + time.Duration(ms)*time.Millisecond, nil
+}
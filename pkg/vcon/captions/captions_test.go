@@ -0,0 +1,112 @@
+package captions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+)
+
+const teamsVTT = "\ufeffWEBVTT\r\n\r\nNOTE exported by Teams\r\n\r\n" +
+	"a1b2c3/12-0\r\n00:00:01.500 --> 00:00:04.000 align:start\r\n<v Alice Smith>Hello, thanks for\r\njoining.</v>\r\n\r\n" +
+	"a1b2c3/13-0\r\n00:00:04.250 --> 00:00:06.000\r\n<v.loud Bob>Glad to be here &amp; ready.</v>\r\n"
+
+const zoomVTT = `WEBVTT
+
+1
+00:00:00.000 --> 00:00:02.100
+Bob: Can everyone hear me?
+
+2
+01:00:02.100 --> 01:00:03.000
+Yes.
+`
+
+const srt = `1
+00:00:01,000 --> 00:00:02,500
+<i>Alice Smith: Hi there</i>
+
+2
+00:00:03,000 --> 00:00:04,000
+Carol: Who is this?
+`
+
+func TestParseVTT(t *testing.T) {
+	cues, err := ParseVTT(strings.NewReader(teamsVTT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{
+		{Start: 1500 * time.Millisecond, End: 4 * time.Second, Speaker: "Alice Smith", Text: "Hello, thanks for joining."},
+		{Start: 4250 * time.Millisecond, End: 6 * time.Second, Speaker: "Bob", Text: "Glad to be here & ready."},
+	}
+	if len(cues) != len(want) {
+		t.Fatalf("got %d cues, want %d: %+v", len(cues), len(want), cues)
+	}
+	for i := range want {
+		if cues[i] != want[i] {
+			t.Errorf("cue %d = %+v, want %+v", i, cues[i], want[i])
+		}
+	}
+
+	cues, err = ParseVTT(strings.NewReader(zoomVTT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cues) != 2 || cues[0].Speaker != "Bob" || cues[0].Text != "Can everyone hear me?" {
+		t.Errorf("Zoom speaker prefix not parsed: %+v", cues)
+	}
+	if cues[1].Speaker != "" || cues[1].Start != time.Hour+2100*time.Millisecond {
+		t.Errorf("second cue = %+v", cues[1])
+	}
+
+	for name, bad := range map[string]string{
+		"no header":      "1\n00:00:01.000 --> 00:00:02.000\nHi\n",
+		"bad timestamp":  "WEBVTT\n\n00:00:1.000 --> 00:00:02.000\nHi\n",
+		"ends too early": "WEBVTT\n\n00:00:03.000 --> 00:00:02.000\nHi\n",
+	} {
+		if _, err := ParseVTT(strings.NewReader(bad)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseSRTAndAttach(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meeting.srt")
+	if err := os.WriteFile(path, []byte(srt), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cues, err := ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cues) != 2 || cues[0].Speaker != "Alice Smith" || cues[0].Text != "Hi there" || cues[0].End != 2500*time.Millisecond {
+		t.Fatalf("unexpected cues: %+v", cues)
+	}
+
+	v := vcon.New("example.com")
+	v.AddParty(vcon.Party{Name: "alice smith"})
+	v.AddParty(vcon.Party{Name: "Bob"})
+	v.AddDialog(*vcon.NewDialog("recording", time.Now(), vcon.NewPartyRefs(0, 1)))
+	i, err := Attach(v, 0, "Zoom", "", cues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := v.Analysis[i].Transcript()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := tr.Segments[0].Party; p == nil || *p != 0 {
+		t.Errorf("Alice Smith should map to party 0, got %v", p)
+	}
+	if s := tr.Segments[1]; s.Party != nil || s.Speaker != "Carol" || s.Start != 3 {
+		t.Errorf("unmatched speaker should keep only its label: %+v", s)
+	}
+
+	if _, err := Attach(v, 1, "Zoom", "", cues); err == nil {
+		t.Error("expected an error for a missing dialog")
+	}
+}
@@ -0,0 +1,85 @@
+package vcon
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of the INSTREAM chunks sent to clamd, well
+// below its default StreamMaxLength.
+const clamdChunkSize = 64 << 10
+
+// ClamdScanner is a Scanner backed by a ClamAV daemon, which it sends the
+// content to with the INSTREAM command.
+type ClamdScanner struct {
+	Network string        // "tcp" or "unix"
+	Address string        // e.g. "127.0.0.1:3310" or "/run/clamav/clamd.ctl"
+	Timeout time.Duration // per scan; 0 leaves it to the context
+}
+
+// NewClamdScanner returns a scanner for the clamd listening at addr: a
+// host:port, or the path of a Unix socket when addr starts with "/".
+func NewClamdScanner(addr string) *ClamdScanner {
+	network := "tcp"
+	if strings.HasPrefix(addr, "/") {
+		network = "unix"
+	}
+	return &ClamdScanner{Network: network, Address: addr, Timeout: time.Minute}
+}
+
+// Scan streams content to clamd and reports a *ThreatError for a match.
+func (c *ClamdScanner) Scan(ctx context.Context, name string, content []byte) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return fmt.Errorf("clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	w := bufio.NewWriter(conn)
+	w.WriteString("zINSTREAM\x00")
+	for len(content) > 0 {
+		n := min(len(content), clamdChunkSize)
+		binary.Write(w, binary.BigEndian, uint32(n))
+		w.Write(content[:n])
+		content = content[n:]
+	}
+	binary.Write(w, binary.BigEndian, uint32(0))
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil {
+		return fmt.Errorf("clamd: reading reply: %w", err)
+	}
+	return parseClamdReply(name, reply)
+}
+
+// parseClamdReply interprets "stream: OK", "stream: <signature> FOUND" and
+// "<message> ERROR" replies.
+func parseClamdReply(name, reply string) error {
+	reply = strings.TrimRight(reply, "\x00\n")
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &ThreatError{Name: name, Threat: strings.TrimSuffix(result, " FOUND")}
+	default:
+		return fmt.Errorf("clamd: %s", reply)
+	}
+}
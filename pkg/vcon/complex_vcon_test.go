@@ -44,8 +44,8 @@ func TestValidComplexVCon(t *testing.T) {
 		Type:       "recording",
 		StartTime:  &now,
 		Duration:   180.0, // 3 minutes
-		Parties:    []int{agentIdx, customerIdx},
-		Originator: customerIdx,
+		Parties:    vcon.NewPartyRefs(agentIdx, customerIdx),
+		Originator: vcon.IntPtr(customerIdx),
 		MediaType:  "audio/wav",
 		Body:       "base64urlencodedaudiocontent",
 		Encoding:   "base64url",
@@ -69,8 +69,8 @@ func TestValidComplexVCon(t *testing.T) {
 	transferDialogIdx := v.AddDialog(vcon.Dialog{
 		Type:           "transfer",
 		StartTime:      &threeMinLater,
-		Transferee:     customerIdx,
-		Transferor:     agentIdx,
+		Transferee:     vcon.IntPtr(customerIdx),
+		Transferor:     vcon.IntPtr(agentIdx),
 		TransferTarget: vcon.NewIntValue(transfereeIdx),
 		TargetDialog:   vcon.NewIntValue(initialCallIdx),
 	})
@@ -80,8 +80,8 @@ func TestValidComplexVCon(t *testing.T) {
 		Type:       "recording",
 		StartTime:  &fourMinLater,
 		Duration:   120.0, // 2 minutes
-		Parties:    []int{transfereeIdx, customerIdx},
-		Originator: transfereeIdx,
+		Parties:    vcon.NewPartyRefs(transfereeIdx, customerIdx),
+		Originator: vcon.IntPtr(transfereeIdx),
 		MediaType:  "audio/wav",
 		Body:       "base64urlencodedaudiocontent2",
 		Encoding:   "base64url",
@@ -160,8 +160,8 @@ func TestInvalidPartyReference(t *testing.T) {
 	v.AddDialog(vcon.Dialog{
 		Type:       "recording",
 		StartTime:  &now,
-		Parties:    []int{agentIdx, 5}, // 5 is an invalid index
-		Originator: agentIdx,
+		Parties:    vcon.NewPartyRefs(agentIdx, 5), // 5 is an invalid index
+		Originator: vcon.IntPtr(agentIdx),
 	})
 
 	valid, errors := v.IsValid()
@@ -170,7 +170,7 @@ func TestInvalidPartyReference(t *testing.T) {
 
 	hasReferenceError := false
 	for _, err := range errors {
-		if err == "dialog at index 0 references invalid party index: 5" {
+		if err.Message == "dialog at index 0 references invalid party index: 5" {
 			hasReferenceError = true
 			break
 		}
@@ -191,8 +191,8 @@ func TestInvalidDialogReference(t *testing.T) {
 	dialogIdx := v.AddDialog(vcon.Dialog{
 		Type:       "recording",
 		StartTime:  &now,
-		Parties:    []int{agentIdx},
-		Originator: agentIdx,
+		Parties:    vcon.NewPartyRefs(agentIdx),
+		Originator: vcon.IntPtr(agentIdx),
 	})
 
 	v.AddAnalysis(vcon.Analysis{
@@ -207,7 +207,7 @@ func TestInvalidDialogReference(t *testing.T) {
 
 	hasReferenceError := false
 	for _, err := range errors {
-		if err == "analysis at index 0 references invalid dialog index: 10" {
+		if err.Message == "analysis at index 0 references invalid dialog index: 10" {
 			hasReferenceError = true
 			break
 		}
@@ -227,8 +227,8 @@ func TestMissingRequiredFields(t *testing.T) {
 	v.AddDialog(vcon.Dialog{
 		Type:       "recording",
 		StartTime:  nil, // Missing required field
-		Parties:    []int{agentIdx},
-		Originator: agentIdx,
+		Parties:    vcon.NewPartyRefs(agentIdx),
+		Originator: vcon.IntPtr(agentIdx),
 	})
 
 	valid, errors := v.IsValid()
@@ -237,7 +237,7 @@ func TestMissingRequiredFields(t *testing.T) {
 
 	hasMissingFieldError := false
 	for _, err := range errors {
-		if err == "dialog at index 0 missing required field: start" {
+		if err.Message == "dialog at index 0 missing required field: start" {
 			hasMissingFieldError = true
 			break
 		}
@@ -276,8 +276,8 @@ func TestComplexConferenceScenario(t *testing.T) {
 		Type:       "recording", // Schema restricts to: recording, text, transfer, incomplete
 		StartTime:  &startTime,
 		Duration:   (endTime.Sub(startTime)).Seconds(),
-		Parties:    []int{moderatorIdx, participant1Idx, participant2Idx, participant3Idx},
-		Originator: moderatorIdx,
+		Parties:    vcon.NewPartyRefs(moderatorIdx, participant1Idx, participant2Idx, participant3Idx),
+		Originator: vcon.IntPtr(moderatorIdx),
 		MediaType:  "audio/wav",
 		Body:       "base64urlencodedconferencecall",
 		Encoding:   "base64url",
@@ -1,6 +1,7 @@
 package vcon
 
 import (
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
@@ -15,6 +16,13 @@ type ContentHash struct {
 	Hash      string // base64url-encoded hash value (no padding)
 }
 
+// ContentHashAlgorithms maps each recognised content_hash algorithm to the
+// length of its digest in bytes.
+var ContentHashAlgorithms = map[string]int{
+	"sha256": sha256.Size,
+	"sha512": sha512.Size,
+}
+
 // ParseContentHash parses a content hash string in the format "algorithm-hash".
 func ParseContentHash(s string) (ContentHash, error) {
 	alg, hash, found := strings.Cut(s, "-")
@@ -30,13 +38,62 @@ func ParseContentHash(s string) (ContentHash, error) {
 	return ContentHash{Algorithm: alg, Hash: hash}, nil
 }
 
+// DefaultHashAlgorithm is the content_hash algorithm used unless another is
+// chosen, e.g. with WithHashAlgorithms.
+const DefaultHashAlgorithm = "sha512"
+
+// hashFuncs computes the digest of each algorithm in ContentHashAlgorithms.
+var hashFuncs = map[string]func([]byte) []byte{
+	"sha256": func(data []byte) []byte { h := sha256.Sum256(data); return h[:] },
+	"sha512": func(data []byte) []byte { h := sha512.Sum512(data); return h[:] },
+}
+
 // ComputeSHA512 computes a SHA-512 content hash for the given data.
 func ComputeSHA512(data []byte) ContentHash {
-	h := sha512.Sum512(data)
-	return ContentHash{
-		Algorithm: "sha512",
-		Hash:      base64.RawURLEncoding.EncodeToString(h[:]),
+	ch, _ := ComputeContentHash("sha512", data)
+	return ch
+}
+
+// ComputeSHA256 computes a SHA-256 content hash for the given data.
+func ComputeSHA256(data []byte) ContentHash {
+	ch, _ := ComputeContentHash("sha256", data)
+	return ch
+}
+
+// ComputeContentHash computes the content hash of data with alg, one of
+// ContentHashAlgorithms.
+func ComputeContentHash(alg string, data []byte) (ContentHash, error) {
+	sum, ok := hashFuncs[alg]
+	if !ok {
+		return ContentHash{}, fmt.Errorf("unsupported content_hash algorithm %q", alg)
+	}
+	return ContentHash{Algorithm: alg, Hash: base64.RawURLEncoding.EncodeToString(sum(data))}, nil
+}
+
+// ComputeContentHashes computes one content hash of data per algorithm, in
+// the order given; without algorithms it uses DefaultHashAlgorithm.
+func ComputeContentHashes(data []byte, algs ...string) (ContentHashList, error) {
+	if len(algs) == 0 {
+		algs = []string{DefaultHashAlgorithm}
+	}
+	if err := checkHashAlgorithms(algs); err != nil {
+		return nil, err
+	}
+	l := make(ContentHashList, len(algs))
+	for i, alg := range algs {
+		l[i], _ = ComputeContentHash(alg, data)
 	}
+	return l, nil
+}
+
+// checkHashAlgorithms reports the first algorithm that cannot be computed.
+func checkHashAlgorithms(algs []string) error {
+	for _, alg := range algs {
+		if _, ok := hashFuncs[alg]; !ok {
+			return fmt.Errorf("unsupported content_hash algorithm %q", alg)
+		}
+	}
+	return nil
 }
 
 // String returns the "algorithm-hash" string representation.
@@ -45,16 +102,31 @@ func (ch ContentHash) String() string {
 }
 
 // Verify recomputes the hash of data and compares it with the stored hash.
-// Currently supports sha512.
+// Supports the algorithms of ContentHashAlgorithms.
 func (ch ContentHash) Verify(data []byte) bool {
-	switch ch.Algorithm {
-	case "sha512":
-		h := sha512.Sum512(data)
-		expected := base64.RawURLEncoding.EncodeToString(h[:])
-		return expected == ch.Hash
-	default:
+	sum, ok := hashFuncs[ch.Algorithm]
+	if !ok {
 		return false
 	}
+	return base64.RawURLEncoding.EncodeToString(sum(data)) == ch.Hash
+}
+
+// Check reports whether the hash uses one of ContentHashAlgorithms and is an
+// unpadded base64url digest of that algorithm's length. ParseContentHash
+// only checks the "algorithm-hash" shape.
+func (ch ContentHash) Check() error {
+	size, ok := ContentHashAlgorithms[ch.Algorithm]
+	if !ok {
+		return fmt.Errorf("unrecognised content_hash algorithm %q", ch.Algorithm)
+	}
+	digest, err := base64.RawURLEncoding.DecodeString(ch.Hash)
+	if err != nil {
+		return fmt.Errorf("content_hash %s is not unpadded base64url: %w", ch, err)
+	}
+	if len(digest) != size {
+		return fmt.Errorf("content_hash %s is %d bytes, want %d for %s", ch, len(digest), size, ch.Algorithm)
+	}
+	return nil
 }
 
 // IsZero returns true if the ContentHash is empty.
@@ -121,6 +193,53 @@ func (l ContentHashList) ContainsAlgorithm(alg string) bool {
 	return false
 }
 
+// Get returns the hash using alg, if the list has one.
+func (l ContentHashList) Get(alg string) (ContentHash, bool) {
+	for _, ch := range l {
+		if ch.Algorithm == alg {
+			return ch, true
+		}
+	}
+	return ContentHash{}, false
+}
+
+// Verify reports whether data matches every hash in the list whose
+// algorithm is supported, and at least one hash is. Hashes in algorithms
+// this package cannot compute are ignored, so a list may carry them for
+// other consumers.
+func (l ContentHashList) Verify(data []byte) bool {
+	verified := false
+	for _, ch := range l {
+		if _, ok := hashFuncs[ch.Algorithm]; !ok {
+			continue
+		}
+		if !ch.Verify(data) {
+			return false
+		}
+		verified = true
+	}
+	return verified
+}
+
+// recompute returns the hashes of data in the algorithms of the list,
+// keeping hashes in algorithms this package cannot compute. A list without
+// a supported algorithm yields a DefaultHashAlgorithm hash.
+func (l ContentHashList) recompute(data []byte) ContentHashList {
+	var result ContentHashList
+	computed := false
+	for _, ch := range l {
+		if next, err := ComputeContentHash(ch.Algorithm, data); err == nil {
+			ch, computed = next, true
+		}
+		result = append(result, ch)
+	}
+	if !computed {
+		ch, _ := ComputeContentHash(DefaultHashAlgorithm, data)
+		result = append(result, ch)
+	}
+	return result
+}
+
 // First returns the first content hash, or a zero value if empty.
 func (l ContentHashList) First() ContentHash {
 	if len(l) == 0 {
@@ -91,6 +91,69 @@ func TestContentHashVerify(t *testing.T) {
 	// Unknown algorithm returns false
 	ch2 := ContentHash{Algorithm: "unknown", Hash: "abc"}
 	assert.False(t, ch2.Verify(data))
+
+	// "test data" hashed with sha256
+	ch3 := ContentHash{Algorithm: "sha256", Hash: "kW8AJ6V1B0znKjMXd8NHjWUT94alkb2JLaGld78jNfk"}
+	assert.True(t, ch3.Verify(data))
+}
+
+func TestComputeContentHashes(t *testing.T) {
+	data := []byte("test data")
+
+	l, err := ComputeContentHashes(data)
+	require.NoError(t, err)
+	require.Len(t, l, 1)
+	assert.Equal(t, "sha512", l[0].Algorithm)
+
+	l, err = ComputeContentHashes(data, "sha256", "sha512")
+	require.NoError(t, err)
+	require.Len(t, l, 2)
+	assert.Equal(t, "sha256-kW8AJ6V1B0znKjMXd8NHjWUT94alkb2JLaGld78jNfk", l[0].String())
+	assert.Equal(t, ComputeSHA512(data), l[1])
+	for _, ch := range l {
+		assert.NoError(t, ch.Check())
+	}
+
+	_, err = ComputeContentHashes(data, "md5")
+	assert.Error(t, err)
+}
+
+func TestContentHashListVerify(t *testing.T) {
+	data := []byte("test data")
+	both, err := ComputeContentHashes(data, "sha256", "sha512")
+	require.NoError(t, err)
+	assert.True(t, both.Verify(data))
+	assert.False(t, both.Verify([]byte("wrong data")))
+
+	// Every supported hash must match, not only the first.
+	bad := ContentHashList{both[0], ComputeSHA512([]byte("wrong data"))}
+	assert.False(t, bad.Verify(data))
+
+	// Unsupported algorithms are ignored, but one hash must be checked.
+	withUnknown := ContentHashList{{Algorithm: "blake3", Hash: "abc"}, both[1]}
+	assert.True(t, withUnknown.Verify(data))
+	assert.False(t, ContentHashList{{Algorithm: "blake3", Hash: "abc"}}.Verify(data))
+	assert.False(t, ContentHashList(nil).Verify(data))
+
+	ch, ok := both.Get("sha512")
+	assert.True(t, ok)
+	assert.Equal(t, both[1], ch)
+	_, ok = both.Get("sha384")
+	assert.False(t, ok)
+}
+
+func TestContentHashCheck(t *testing.T) {
+	assert.NoError(t, ComputeSHA512([]byte("x")).Check())
+	assert.NoError(t, ContentHash{Algorithm: "sha256", Hash: "kW8AJ6V1B0znKjMXd8NHjWUT94alkb2JLaGld78jNfk"}.Check())
+
+	for name, ch := range map[string]ContentHash{
+		"unknown algorithm": {Algorithm: "md5", Hash: "kW8AJ6V1B0znKjMXd8NHjWUT94alkb2JLaGld78jNfk"},
+		"not base64url":     {Algorithm: "sha512", Hash: "abc+def/"},
+		"padded":            {Algorithm: "sha256", Hash: "kW8AJ6V1B0znKjMXd8NHjWUT94alkb2JLaGld78jNfk="},
+		"wrong length":      {Algorithm: "sha512", Hash: "kW8AJ6V1B0znKjMXd8NHjWUT94alkb2JLaGld78jNfk"},
+	} {
+		assert.Error(t, ch.Check(), name)
+	}
 }
 
 func TestContentHashIsZero(t *testing.T) {
@@ -0,0 +1,172 @@
+package vcon
+
+import "fmt"
+
+// WithContentVerification makes Validate and IsValid fetch the external
+// content of every dialog, attachment and analysis entry that has a
+// content_hash and check it against the hash, and check inline bodies as
+// VerifyContentHashes does, so one call checks both the structure and the
+// integrity of a vCon. A mismatch is a
+// content_hash_mismatch error and content that cannot be fetched a
+// content_unavailable error. A nil fetcher uses the item's or the vCon's
+// fetcher, as ToInlineData does; content retained by AddExternalData is not
+// trusted and is fetched again.
+func WithContentVerification(fetcher ContentFetcher) ValidateOption {
+	return func(c *validateConfig) {
+		c.verifyContent = true
+		c.contentFetcher = fetcher
+	}
+}
+
+// validateContentIntegrity verifies inline bodies and fetches and verifies
+// external content when WithContentVerification is set.
+func (v *VCon) validateContentIntegrity(cfg *validateConfig) []ValidationIssue {
+	if !cfg.verifyContent {
+		return nil
+	}
+	var issues []ValidationIssue
+	check := func(path, urlStr string, hashes ContentHashList, fetcher ContentFetcher) {
+		if urlStr == "" || hashes.IsEmpty() {
+			return
+		}
+		content, err := pickFetcher(cfg.contentFetcher, fetcher).Fetch(cfg.ctx, urlStr)
+		if err != nil {
+			issues = append(issues, issuef(path+"/url", IssueContentUnavailable,
+				"%s: cannot fetch %s: %v", path, urlStr, err))
+			return
+		}
+		for j, ch := range hashes {
+			if ch.Check() != nil {
+				continue // reported as invalid_content_hash
+			}
+			if !ch.Verify(content.Body) {
+				p := path + "/content_hash"
+				if len(hashes) > 1 {
+					p += fmt.Sprintf("/%d", j)
+				}
+				issues = append(issues, issuef(p, IssueContentHashMismatch,
+					"%s: content at %s does not match content_hash %s", path, urlStr, ch))
+			}
+		}
+	}
+	for i := range v.Dialog {
+		d := &v.Dialog[i]
+		check(fmt.Sprintf("/dialog/%d", i), d.URL, d.ContentHash, pickFetcher(d.fetcher, v.contentFetcher()))
+	}
+	for i := range v.Attachments {
+		a := &v.Attachments[i]
+		check(fmt.Sprintf("/attachments/%d", i), a.URL, a.ContentHash, v.contentFetcher())
+	}
+	for i := range v.Analysis {
+		a := &v.Analysis[i]
+		check(fmt.Sprintf("/analysis/%d", i), a.URL, a.ContentHash, pickFetcher(a.fetcher, v.contentFetcher()))
+	}
+	for _, r := range v.VerifyContentHashes() {
+		if r.Status == ContentHashMismatch {
+			path := fmt.Sprintf("/%s/%d", itemCollection[r.Item.Kind], r.Item.Index)
+			issues = append(issues, issuef(path+"/content_hash", IssueContentHashMismatch,
+				"%s: body does not match its content_hash", path))
+		}
+	}
+	return issues
+}
+
+// itemCollection maps an InlineItem kind to its vCon property.
+var itemCollection = map[string]string{"dialog": "dialog", "attachment": "attachments", "analysis": "analysis"}
+
+// ContentHashStatus is the outcome of checking one inline body against its
+// content_hash.
+type ContentHashStatus string
+
+const (
+	ContentHashVerified     ContentHashStatus = "verified"
+	ContentHashMismatch     ContentHashStatus = "mismatch"
+	ContentHashMissing      ContentHashStatus = "no_hash"
+	ContentHashUnverifiable ContentHashStatus = "unverifiable"
+)
+
+// ContentHashResult is the check of one inline dialog, attachment or
+// analysis body. Err says why an unverifiable body could not be checked.
+type ContentHashResult struct {
+	Item   InlineItem
+	Status ContentHashStatus
+	Err    error
+}
+
+// OK reports whether the body matched its hashes or has none.
+func (r ContentHashResult) OK() bool {
+	return r.Status == ContentHashVerified || r.Status == ContentHashMissing
+}
+
+// VerifyContentHashes decodes every inline dialog, attachment and analysis
+// body per its encoding and checks it against each of its content hashes,
+// without any network access. Attachments referring to another attachment's
+// content, as left by DedupeAttachments, are checked against the content
+// they resolve to. External content is skipped; WithContentVerification
+// fetches it. Results are in document order.
+func (v *VCon) VerifyContentHashes() []ContentHashResult {
+	var results []ContentHashResult
+	check := func(item InlineItem, hashes ContentHashList, content func() ([]byte, error)) {
+		r := ContentHashResult{Item: item}
+		if hashes.IsEmpty() {
+			r.Status = ContentHashMissing
+			results = append(results, r)
+			return
+		}
+		data, err := content()
+		if err != nil {
+			r.Status, r.Err = ContentHashUnverifiable, err
+			results = append(results, r)
+			return
+		}
+		r.Status, r.Err = verifyHashes(hashes, data)
+		results = append(results, r)
+	}
+
+	for i := range v.Dialog {
+		d := &v.Dialog[i]
+		if d.URL != "" || d.Body == "" {
+			continue
+		}
+		check(InlineItem{"dialog", i}, d.ContentHash, func() ([]byte, error) {
+			return decodeInlineBody(d.Body, d.Encoding)
+		})
+	}
+	for i := range v.Attachments {
+		a := &v.Attachments[i]
+		if a.URL != "" || (a.Body == "" && !a.IsReference()) {
+			continue
+		}
+		check(InlineItem{"attachment", i}, a.ContentHash, func() ([]byte, error) {
+			return v.AttachmentContent(i)
+		})
+	}
+	for i := range v.Analysis {
+		a := &v.Analysis[i]
+		if a.URL != "" || a.Body == "" {
+			continue
+		}
+		check(InlineItem{"analysis", i}, a.ContentHash, func() ([]byte, error) {
+			return decodeInlineBody(a.Body, a.Encoding)
+		})
+	}
+	return results
+}
+
+// verifyHashes checks data against every hash with a supported algorithm.
+func verifyHashes(hashes ContentHashList, data []byte) (ContentHashStatus, error) {
+	checked := 0
+	for _, ch := range hashes {
+		if err := ch.Check(); err != nil {
+			continue
+		}
+		if !ch.Verify(data) {
+			return ContentHashMismatch, nil
+		}
+		checked++
+	}
+	if checked == 0 {
+		return ContentHashUnverifiable, fmt.Errorf("no usable content_hash among %d", len(hashes))
+	}
+	return ContentHashVerified, nil
+}
@@ -0,0 +1,116 @@
+package vcon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithContentVerification(t *testing.T) {
+	store := map[string][]byte{
+		"https://media.example.com/call.wav":   []byte("recording"),
+		"https://media.example.com/notes.txt":  []byte("tampered notes"),
+		"https://media.example.com/asr.json":   []byte(`{"text":"hi"}`),
+		"https://media.example.com/nohash.txt": []byte("unchecked"),
+	}
+	fetcher := ContentFetcherFunc(func(_ context.Context, u string) (*ExternalContent, error) {
+		body, ok := store[u]
+		if !ok {
+			return nil, errors.New("404 Not Found")
+		}
+		return &ExternalContent{Body: body}, nil
+	})
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0), MediaType: "audio/wav",
+		URL: "https://media.example.com/call.wav", ContentHash: ContentHashList{ComputeSHA512([]byte("recording"))}})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0), MediaType: "audio/wav",
+		URL: "https://media.example.com/gone.wav", ContentHash: ContentHashList{ComputeSHA512([]byte("gone"))}})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(0), StartTime: start, MediaType: "text/plain",
+		URL: "https://media.example.com/notes.txt", ContentHash: ContentHashList{ComputeSHA512([]byte("notes"))}})
+	v.AddAnalysis(Analysis{Type: "transcript", Vendor: "acme", Dialog: 0, MediaType: "application/json",
+		URL: "https://media.example.com/asr.json", ContentHash: ContentHashList{ComputeSHA512([]byte(`{"text":"hi"}`))}})
+
+	ok, issues := v.IsValid()
+	require.True(t, ok, "%v", issues)
+
+	ok, issues = v.IsValid(WithContentVerification(fetcher))
+	assert.False(t, ok)
+	var codes, paths []string
+	for _, i := range issues {
+		if i.Code == IssueContentHashMismatch || i.Code == IssueContentUnavailable {
+			codes = append(codes, i.Code)
+			paths = append(paths, i.Path)
+		}
+	}
+	assert.Equal(t, []string{IssueContentUnavailable, IssueContentHashMismatch}, codes)
+	assert.Equal(t, []string{"/dialog/1/url", "/attachments/0/content_hash"}, paths)
+
+	v.Dialog = v.Dialog[:1]
+	store["https://media.example.com/notes.txt"] = []byte("notes")
+	assert.NoError(t, v.Validate(WithContentVerification(fetcher)))
+}
+
+func TestVerifyContentHashes(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+
+	good := Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0)}
+	require.NoError(t, good.AddInlineData([]byte("audio"), "call.wav", ""))
+	v.AddDialog(good)
+	tampered := good
+	tampered.Body = encodeBase64URL([]byte("edited"))
+	v.AddDialog(tampered)
+	v.AddDialog(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0), Body: "hi", Encoding: "none"})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0),
+		URL: "https://media.example.com/call.wav", ContentHash: ContentHashList{ComputeSHA512([]byte("x"))}})
+
+	notes := []byte("notes")
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(0), StartTime: start, Body: "notes", Encoding: "none",
+		ContentHash: ContentHashList{ComputeSHA512(notes), {Algorithm: "sha256", Hash: sha256URL(notes)}}})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(0), StartTime: start, ContentHash: ContentHashList{ComputeSHA512(notes)}})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(0), StartTime: start, ContentHash: ContentHashList{ComputeSHA512([]byte("lost"))}})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: 0, Body: "!!", Encoding: "base64url",
+		ContentHash: ContentHashList{ComputeSHA512(nil)}})
+
+	var got []string
+	for _, r := range v.VerifyContentHashes() {
+		got = append(got, r.Item.String()+" "+string(r.Status))
+		assert.Equal(t, r.Status == ContentHashUnverifiable, r.Err != nil, r.Item.String())
+	}
+	assert.Equal(t, []string{
+		"dialog 0 verified",
+		"dialog 1 mismatch",
+		"dialog 2 no_hash",
+		"attachment 0 verified",
+		"attachment 1 verified",
+		"attachment 2 unverifiable",
+		"analysis 0 unverifiable",
+	}, got)
+
+	ok, issues := v.IsValid(WithContentVerification(ContentFetcherFunc(func(context.Context, string) (*ExternalContent, error) {
+		return &ExternalContent{Body: []byte("x")}, nil
+	})))
+	assert.False(t, ok)
+	var mismatches []string
+	for _, i := range issues {
+		if i.Code == IssueContentHashMismatch {
+			mismatches = append(mismatches, i.Path)
+		}
+	}
+	assert.Equal(t, []string{"/dialog/1/content_hash"}, mismatches)
+}
+
+func sha256URL(data []byte) string {
+	h := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
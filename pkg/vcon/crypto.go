@@ -3,12 +3,15 @@ package vcon
 import (
 	"bytes"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-jose/go-jose/v4"
 )
@@ -23,24 +26,42 @@ type EncryptedVCon struct {
 	JSON map[string]any `json:"jwe"`
 }
 
-// Sign generates a General‑JSON JWS with detached payload.
+// Sign generates a General‑JSON JWS with detached payload. The algorithm
+// follows the key, as SigningAlgorithm picks it: RS256 for RSA keys and
+// ES256, ES384 or ES512 for ECDSA keys on P-256, P-384 or P-521.
 func (v *VCon) Sign(signer crypto.Signer, chain []*x509.Certificate) (*SignedVCon, error) {
 	payload, err := Canonicalise(v)
 	if err != nil {
 		return nil, err
 	}
 
+	gen, err := signPayload(payload, signer, chain, v.UUID)
+	if err != nil {
+		return nil, err
+	}
+	gen["payload"] = base64.RawURLEncoding.EncodeToString(payload)
+
+	return &SignedVCon{JSON: gen}, nil
+}
+
+// signPayload signs payload with an x5c chain and uuid header and returns
+// the JWS in JSON serialization.
+func signPayload(payload []byte, signer crypto.Signer, chain []*x509.Certificate, uuid string) (map[string]any, error) {
 	// embed x5c
 	var x5c []string
 	for _, c := range chain {
 		x5c = append(x5c, base64.StdEncoding.EncodeToString(c.Raw))
 	}
 
-	j, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: signer},
+	alg, err := SigningAlgorithm(signer)
+	if err != nil {
+		return nil, err
+	}
+	j, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: signer},
 		(&jose.SignerOptions{}).
 			WithContentType("application/vcon").
 			WithHeader("x5c", x5c).
-			WithHeader("uuid", v.UUID))
+			WithHeader("uuid", uuid))
 	if err != nil {
 		return nil, err
 	}
@@ -49,25 +70,288 @@ func (v *VCon) Sign(signer crypto.Signer, chain []*x509.Certificate) (*SignedVCo
 		return nil, err
 	}
 
-	general := obj.FullSerialize()
 	var gen map[string]any
-	if err = json.Unmarshal([]byte(general), &gen); err != nil {
+	if err = json.Unmarshal([]byte(obj.FullSerialize()), &gen); err != nil {
 		return nil, err
 	}
-	gen["payload"] = base64.RawURLEncoding.EncodeToString(payload)
+	return gen, nil
+}
 
-	return &SignedVCon{JSON: gen}, nil
+// SigningAlgorithm returns the JWS algorithm Sign uses with signer: RS256
+// for an RSA key, and ES256, ES384 or ES512 for an ECDSA key on P-256, P-384
+// or P-521 respectively.
+func SigningAlgorithm(signer crypto.Signer) (jose.SignatureAlgorithm, error) {
+	switch pub := signer.Public().(type) {
+	case *rsa.PublicKey:
+		return jose.RS256, nil
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return jose.ES256, nil
+		case elliptic.P384():
+			return jose.ES384, nil
+		case elliptic.P521():
+			return jose.ES512, nil
+		}
+		return "", fmt.Errorf("unsupported ECDSA curve %s", pub.Curve.Params().Name)
+	}
+	return "", fmt.Errorf("unsupported signing key type %T", signer.Public())
+}
+
+// AddSignature returns a copy of sv with one more signature, made by signer
+// over the same payload. The existing signatures are kept, so the result
+// verifies only against roots trusting every signer; use it to roll signing
+// certificates over without discarding the old signature.
+func (sv *SignedVCon) AddSignature(signer crypto.Signer, chain []*x509.Certificate) (*SignedVCon, error) {
+	payload, err := sv.payload()
+	if err != nil {
+		return nil, err
+	}
+	var v VCon
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, fmt.Errorf("decode vCon: %w", err)
+	}
+	added, err := signPayload(payload, signer, chain, v.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := signatureEntries(sv.JSON)
+	if len(sigs) == 0 {
+		return nil, errors.New("signed vCon has no signatures")
+	}
+	sigs = append(sigs, signatureEntries(added)...)
+	return &SignedVCon{JSON: map[string]any{"payload": sv.JSON["payload"], "signatures": sigs}}, nil
+}
+
+// payload returns the decoded JWS payload.
+func (sv *SignedVCon) payload() ([]byte, error) {
+	encoded, ok := sv.JSON["payload"].(string)
+	if !ok {
+		return nil, errors.New("signed vCon has no payload")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	return payload, nil
+}
+
+// Headers returns the JOSE header of each signature, protected and
+// unprotected parameters merged, without verifying anything. It lets tooling
+// route a signed vCon, e.g. by algorithm or kid, before choosing the roots to
+// Verify against; nothing in the headers is trustworthy until then.
+func (sv *SignedVCon) Headers() ([]jose.Header, error) {
+	raw, err := json.Marshal(sv.JSON)
+	if err != nil {
+		return nil, fmt.Errorf("marshal signed object: %w", err)
+	}
+	jws, err := jose.ParseSigned(string(raw), allSignatureAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("parse JWS: %w", err)
+	}
+	headers := make([]jose.Header, len(jws.Signatures))
+	for i, sig := range jws.Signatures {
+		headers[i] = sig.Header
+	}
+	return headers, nil
+}
+
+// SignerCertificates returns the x5c chain of each signature, leaf first,
+// without verifying the chains or the signatures, e.g. to index signed vCons
+// by the signer's subject. A signature without x5c has a nil chain.
+func (sv *SignedVCon) SignerCertificates() ([][]*x509.Certificate, error) {
+	sigs := signatureEntries(sv.JSON)
+	if len(sigs) == 0 {
+		return nil, errors.New("signed vCon has no signatures")
+	}
+	chains := make([][]*x509.Certificate, len(sigs))
+	for i, sig := range sigs {
+		x5c, err := signatureX5C(sig)
+		if err != nil {
+			return nil, fmt.Errorf("sig[%d]: %w", i, err)
+		}
+		for j, enc := range x5c {
+			der, err := base64.StdEncoding.DecodeString(enc)
+			if err != nil {
+				return nil, fmt.Errorf("sig[%d] x5c[%d]: %w", i, j, err)
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, fmt.Errorf("sig[%d] x5c[%d]: %w", i, j, err)
+			}
+			chains[i] = append(chains[i], cert)
+		}
+	}
+	return chains, nil
+}
+
+// signatureX5C returns the x5c parameter of a "signatures" entry, from the
+// protected header or else the unprotected one.
+func signatureX5C(sig any) ([]string, error) {
+	entry, _ := sig.(map[string]any)
+	var h struct {
+		X5C []string `json:"x5c"`
+	}
+	if protected, ok := entry["protected"].(string); ok {
+		data, err := base64.RawURLEncoding.DecodeString(protected)
+		if err != nil {
+			return nil, fmt.Errorf("decode protected header: %w", err)
+		}
+		if err := json.Unmarshal(data, &h); err != nil {
+			return nil, fmt.Errorf("decode protected header: %w", err)
+		}
+	}
+	if header, ok := entry["header"].(map[string]any); ok && len(h.X5C) == 0 {
+		data, _ := json.Marshal(header)
+		if err := json.Unmarshal(data, &h); err != nil {
+			return nil, fmt.Errorf("decode header: %w", err)
+		}
+	}
+	return h.X5C, nil
+}
+
+// UUID returns the uuid of the vCon in the payload without verifying it, so
+// signed vCons can be stored and looked up before they are verified.
+func (sv *SignedVCon) UUID() (string, error) {
+	payload, err := sv.payload()
+	if err != nil {
+		return "", err
+	}
+	var v struct {
+		UUID string `json:"uuid"`
+	}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return "", fmt.Errorf("decode vCon: %w", err)
+	}
+	if v.UUID == "" {
+		return "", errors.New("signed vCon payload has no uuid")
+	}
+	return v.UUID, nil
+}
+
+// PayloadUnsafe decodes the vCon in the payload without checking the
+// signatures, certificate chains or canonical form. Its content is not
+// trustworthy; use Verify for anything beyond routing and indexing.
+func (sv *SignedVCon) PayloadUnsafe() (*VCon, error) {
+	payload, err := sv.payload()
+	if err != nil {
+		return nil, err
+	}
+	var v VCon
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, fmt.Errorf("decode vCon: %w", err)
+	}
+	return &v, nil
+}
+
+// signatureEntries returns the signatures of a JWS in General or Flattened
+// JSON serialization as General JSON "signatures" entries.
+func signatureEntries(jws map[string]any) []any {
+	if sigs, ok := jws["signatures"].([]any); ok {
+		return append([]any(nil), sigs...)
+	}
+	if _, ok := jws["signature"]; !ok {
+		return nil
+	}
+	entry := map[string]any{}
+	for _, k := range []string{"protected", "header", "signature"} {
+		if val, ok := jws[k]; ok {
+			entry[k] = val
+		}
+	}
+	return []any{entry}
+}
+
+// DefaultSignatureAlgorithms are the JWS algorithms Verify accepts unless
+// WithSignatureAlgorithms narrows them. Sign uses RS256 or ES256/384/512;
+// the others allow vCons signed by other implementations to verify.
+var DefaultSignatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// allSignatureAlgorithms are the JWS algorithms Headers parses, so any signed
+// vCon can be inspected whatever its signer used.
+var allSignatureAlgorithms = append([]jose.SignatureAlgorithm{jose.HS256, jose.HS384, jose.HS512},
+	DefaultSignatureAlgorithms...)
+
+// VerifyOption configures Verify.
+type VerifyOption func(*verifyConfig)
+
+type verifyConfig struct {
+	algorithms      []jose.SignatureAlgorithm
+	requiredHeaders []string
+	skipUUIDCheck   bool
+	currentTime     time.Time
+}
+
+// WithSignatureAlgorithms restricts the accepted JWS algorithms.
+func WithSignatureAlgorithms(algs ...jose.SignatureAlgorithm) VerifyOption {
+	return func(c *verifyConfig) {
+		c.algorithms = algs
+	}
+}
+
+// WithRequiredHeaders rejects signatures whose header lacks any of the named
+// parameters, e.g. "uuid" or "kid".
+func WithRequiredHeaders(names ...string) VerifyOption {
+	return func(c *verifyConfig) {
+		c.requiredHeaders = append(c.requiredHeaders, names...)
+	}
+}
+
+// WithoutUUIDCheck skips comparing a "uuid" header with the vCon uuid, for
+// producers that put a different identifier there.
+func WithoutUUIDCheck() VerifyOption {
+	return func(c *verifyConfig) {
+		c.skipUUIDCheck = true
+	}
+}
+
+// WithVerificationTime checks certificate chains as of t instead of now, e.g.
+// to verify signatures made before a certificate expired.
+func WithVerificationTime(t time.Time) VerifyOption {
+	return func(c *verifyConfig) {
+		c.currentTime = t
+	}
+}
+
+// hasHeader reports whether the JOSE header carries the named parameter.
+func hasHeader(h jose.Header, name string) bool {
+	switch name {
+	case "alg":
+		return h.Algorithm != ""
+	case "kid":
+		return h.KeyID != ""
+	case "jwk":
+		return h.JSONWebKey != nil
+	case "x5c":
+		// Verify rejects signatures without a chain before headers are checked.
+		return true
+	}
+	_, ok := h.ExtraHeaders[jose.HeaderKey(name)]
+	return ok
 }
 
 // Verify validates all signatures, certificate chains and canonicalization.
-// On success it returns the decoded VCon.
-func (sv *SignedVCon) Verify(rootPool *x509.CertPool) (*VCon, error) {
+// On success it returns the decoded VCon. A "uuid" header, when present, must
+// match the vCon; other headers are only checked when required by
+// WithRequiredHeaders.
+func (sv *SignedVCon) Verify(rootPool *x509.CertPool, opts ...VerifyOption) (*VCon, error) {
+	cfg := verifyConfig{algorithms: DefaultSignatureAlgorithms}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	raw, err := json.Marshal(sv.JSON)
 	if err != nil {
 		return nil, fmt.Errorf("marshal signed object: %w", err)
 	}
 
-	jws, err := jose.ParseSigned(string(raw), []jose.SignatureAlgorithm{jose.RS256})
+	jws, err := jose.ParseSigned(string(raw), cfg.algorithms)
 	if err != nil {
 		return nil, fmt.Errorf("parse JWS: %w", err)
 	}
@@ -79,14 +363,23 @@ func (sv *SignedVCon) Verify(rootPool *x509.CertPool) (*VCon, error) {
 
 	for idx, sig := range jws.Signatures {
 		// 2.a validate and extract x5c chain
-		chains, err := sig.Header.Certificates(x509.VerifyOptions{Roots: rootPool})
+		chains, err := sig.Header.Certificates(x509.VerifyOptions{Roots: rootPool, CurrentTime: cfg.currentTime})
 		if err != nil {
 			return nil, fmt.Errorf("sig[%d] bad cert chain: %w", idx, err)
 		}
 		leaf := chains[0][0] // leaf cert is first in verified chain
 
-		// 2.b verify signature with leaf’s public key
-		payload, err := jws.Verify(leaf.PublicKey)
+		for _, name := range cfg.requiredHeaders {
+			if !hasHeader(sig.Header, name) {
+				return nil, fmt.Errorf("sig[%d] missing required header %q", idx, name)
+			}
+		}
+
+		// 2.b verify signature with leaf’s public key; go-jose only verifies
+		// objects with a single signature
+		single := *jws
+		single.Signatures = []jose.Signature{sig}
+		payload, err := single.Verify(leaf.PublicKey)
 		if err != nil {
 			return nil, fmt.Errorf("sig[%d] signature invalid: %w", idx, err)
 		}
@@ -101,10 +394,11 @@ func (sv *SignedVCon) Verify(rootPool *x509.CertPool) (*VCon, error) {
 
 			canon, _ := Canonicalise(&v)
 			if !bytes.Equal(canon, payload) {
-				return nil, errors.New("payload not RFC 8785 canonical")
+				diff, _ := diffCanonical(payload, canon)
+				return nil, &NonCanonicalError{Diff: diff}
 			}
 
-			if hu, ok := sig.Header.ExtraHeaders["uuid"].(string); ok && hu != v.UUID {
+			if hu, ok := sig.Header.ExtraHeaders["uuid"].(string); ok && !cfg.skipUUIDCheck && hu != v.UUID {
 				return nil, errors.New("header uuid ≠ body uuid")
 			}
 
@@ -122,6 +416,31 @@ func (sv *SignedVCon) Verify(rootPool *x509.CertPool) (*VCon, error) {
 	return vc, nil
 }
 
+// SignAndEncrypt signs the vCon and encrypts the signed form for rcpts in one
+// step. The fresh signature is verified against chain before encrypting, so
+// the result always wraps a valid signed vCon and never an unsigned payload.
+func (v *VCon) SignAndEncrypt(signer crypto.Signer, chain []*x509.Certificate, rcpts []jose.Recipient) (*EncryptedVCon, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("no certificate chain supplied")
+	}
+	if len(rcpts) == 0 {
+		return nil, errors.New("no recipients supplied")
+	}
+
+	signed, err := v.Sign(signer, chain)
+	if err != nil {
+		return nil, fmt.Errorf("sign vCon: %w", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(chain[len(chain)-1])
+	if _, err := signed.Verify(roots); err != nil {
+		return nil, fmt.Errorf("verify signed vCon: %w", err)
+	}
+
+	return signed.Encrypt(rcpts)
+}
+
 // Encrypt turns a *signed* vCon (General-JSON JWS in sv.JSON) into a
 // complete-serialization JWE.
 func (sv *SignedVCon) Encrypt(rcpts []jose.Recipient) (*EncryptedVCon, error) {
@@ -147,6 +466,14 @@ func (sv *SignedVCon) Encrypt(rcpts []jose.Recipient) (*EncryptedVCon, error) {
 		WithContentType("application/vcon").
 		WithHeader("uuid", tmp.UUID)
 
+	// Label every recipient so DecryptWithKeys can pick the matching key.
+	rcpts = append([]jose.Recipient(nil), rcpts...)
+	for i := range rcpts {
+		if rcpts[i].KeyID == "" {
+			rcpts[i].KeyID, _ = RecipientKeyID(rcpts[i].Key)
+		}
+	}
+
 	enc, err := jose.NewMultiEncrypter(jose.A256CBC_HS512, rcpts, opts)
 	if err != nil {
 		return nil, fmt.Errorf("new encrypter: %w", err)
@@ -173,6 +500,104 @@ func (sv *SignedVCon) Encrypt(rcpts []jose.Recipient) (*EncryptedVCon, error) {
 // Decrypt unwraps the JWE using the supplied **private RSA key**.
 // It returns the plaintext object as a generic map.
 func (ev *EncryptedVCon) Decrypt(priv *rsa.PrivateKey) (map[string]any, error) {
+	jweObj, err := ev.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := jweObj.Decrypt(priv)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt JWE: %w", err)
+	}
+	return decodePlaintext(plain)
+}
+
+// DecryptWithKeys unwraps the JWE with whichever of keys belongs to one of its
+// recipients, so archives encrypted for rotated keys still open. Keys whose
+// KeyID or RFC 7638 thumbprint matches a recipient "kid" are tried first; the
+// others follow for objects written without kid.
+func (ev *EncryptedVCon) DecryptWithKeys(keys []jose.JSONWebKey) (map[string]any, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no decryption keys supplied")
+	}
+	jweObj, err := ev.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	kids := ev.RecipientKeyIDs()
+	var matching, others []jose.JSONWebKey
+	for _, k := range keys {
+		if keyMatches(k, kids) {
+			matching = append(matching, k)
+		} else {
+			others = append(others, k)
+		}
+	}
+
+	for _, k := range append(matching, others...) {
+		if _, _, plain, err := jweObj.DecryptMulti(k.Key); err == nil {
+			return decodePlaintext(plain)
+		}
+	}
+	return nil, fmt.Errorf("decrypt JWE: none of the %d key(s) matches a recipient", len(keys))
+}
+
+// RecipientKeyIDs returns the "kid" of every recipient of the JWE.
+func (ev *EncryptedVCon) RecipientKeyIDs() []string {
+	var kids []string
+	addKid := func(header any) {
+		if h, ok := header.(map[string]any); ok {
+			if kid, ok := h["kid"].(string); ok && kid != "" {
+				kids = append(kids, kid)
+			}
+		}
+	}
+	// With one recipient its header is merged into the protected header.
+	if protected, ok := ev.JSON["protected"].(string); ok {
+		if data, err := base64.RawURLEncoding.DecodeString(protected); err == nil {
+			var h map[string]any
+			if json.Unmarshal(data, &h) == nil {
+				addKid(h)
+			}
+		}
+	}
+	addKid(ev.JSON["header"]) // flattened serialization, one recipient
+	if rcpts, ok := ev.JSON["recipients"].([]any); ok {
+		for _, r := range rcpts {
+			if m, ok := r.(map[string]any); ok {
+				addKid(m["header"])
+			}
+		}
+	}
+	return kids
+}
+
+// RecipientKeyID returns the key ID Encrypt assigns to a recipient public key:
+// its base64url RFC 7638 SHA-256 thumbprint.
+func RecipientKeyID(key any) (string, error) {
+	jwk := jose.JSONWebKey{Key: key}
+	if priv, ok := key.(crypto.Signer); ok {
+		jwk.Key = priv.Public()
+	}
+	thumb, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(thumb), nil
+}
+
+func keyMatches(k jose.JSONWebKey, kids []string) bool {
+	thumb, _ := RecipientKeyID(k.Key)
+	for _, kid := range kids {
+		if kid == k.KeyID || kid == thumb {
+			return true
+		}
+	}
+	return false
+}
+
+func (ev *EncryptedVCon) parse() (*jose.JSONWebEncryption, error) {
 	raw, err := json.Marshal(ev.JSON)
 	if err != nil {
 		return nil, fmt.Errorf("marshal JWE: %w", err)
@@ -186,12 +611,10 @@ func (ev *EncryptedVCon) Decrypt(priv *rsa.PrivateKey) (map[string]any, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parse JWE: %w", err)
 	}
+	return jweObj, nil
+}
 
-	plain, err := jweObj.Decrypt(priv)
-	if err != nil {
-		return nil, fmt.Errorf("decrypt JWE: %w", err)
-	}
-
+func decodePlaintext(plain []byte) (map[string]any, error) {
 	var out map[string]any
 	if err := json.Unmarshal(plain, &out); err != nil {
 		return nil, fmt.Errorf("decode plaintext: %w", err)
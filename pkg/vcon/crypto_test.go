@@ -1,10 +1,13 @@
 package vcon_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/json"
 	"math/big"
 	"testing"
@@ -91,6 +94,40 @@ func TestSignAndVerify(t *testing.T) {
 	assert.Equal(t, v.Parties[0].Name, verified.Parties[0].Name)
 }
 
+func TestAddSignature(t *testing.T) {
+	oldKey, oldCerts, err := generateTestCertificate()
+	require.NoError(t, err)
+	newKey, newCerts, err := generateTestCertificate()
+	require.NoError(t, err)
+
+	v := vcon.New("example.com")
+	v.Subject = "Rolled over"
+	signed, err := v.Sign(oldKey, oldCerts)
+	require.NoError(t, err)
+
+	both, err := signed.AddSignature(newKey, newCerts)
+	require.NoError(t, err)
+	assert.Len(t, both.JSON["signatures"], 2)
+	assert.Equal(t, signed.JSON["payload"], both.JSON["payload"])
+
+	roots := x509.NewCertPool()
+	roots.AddCert(oldCerts[0])
+	roots.AddCert(newCerts[0])
+	got, err := both.Verify(roots)
+	require.NoError(t, err)
+	assert.Equal(t, "Rolled over", got.Subject)
+
+	// Every signature must chain to a trusted root.
+	newOnly := x509.NewCertPool()
+	newOnly.AddCert(newCerts[0])
+	_, err = both.Verify(newOnly)
+	assert.ErrorContains(t, err, "sig[0]")
+
+	// Certificates are checked as of the verification time.
+	_, err = signed.Verify(roots, vcon.WithVerificationTime(time.Now().Add(48*time.Hour)))
+	assert.ErrorContains(t, err, "bad cert chain")
+}
+
 // TestEncryptAndDecrypt tests encryption and decryption of a signed vCon
 func TestEncryptAndDecrypt(t *testing.T) {
 	// Generate a test certificate
@@ -147,6 +184,76 @@ func TestEncryptAndDecrypt(t *testing.T) {
 	assert.Equal(t, v.Parties[0].Name, verifiedAfterDecrypt.Parties[0].Name)
 }
 
+// TestSignAndEncrypt tests sealing an unsigned vCon in one step
+func TestSignAndEncrypt(t *testing.T) {
+	privateKey, certs, err := generateTestCertificate()
+	require.NoError(t, err)
+
+	v := vcon.New("example.com")
+	v.Subject = "Sealed vCon"
+	v.AddParty(vcon.Party{Name: "Test Person"})
+
+	recipients := []jose.Recipient{{Algorithm: jose.RSA_OAEP, Key: &privateKey.PublicKey}}
+	encrypted, err := v.SignAndEncrypt(privateKey, certs, recipients)
+	require.NoError(t, err)
+
+	decrypted, err := encrypted.Decrypt(privateKey)
+	require.NoError(t, err)
+	rootPool := x509.NewCertPool()
+	rootPool.AddCert(certs[0])
+	verified, err := (&vcon.SignedVCon{JSON: decrypted}).Verify(rootPool)
+	require.NoError(t, err, "the encrypted object must wrap a signed vCon")
+	assert.Equal(t, v.UUID, verified.UUID)
+
+	_, err = v.SignAndEncrypt(privateKey, certs, nil)
+	assert.Error(t, err)
+	_, err = v.SignAndEncrypt(privateKey, nil, recipients)
+	assert.Error(t, err)
+
+	// A key that does not match the certificate never produces output.
+	otherKey, _, err := generateTestCertificate()
+	require.NoError(t, err)
+	_, err = v.SignAndEncrypt(otherKey, certs, recipients)
+	assert.Error(t, err)
+}
+
+// TestDecryptWithKeys tests picking the recipient key from a key ring
+func TestDecryptWithKeys(t *testing.T) {
+	oldKey, certs, err := generateTestCertificate()
+	require.NoError(t, err)
+	newKey, _, err := generateTestCertificate()
+	require.NoError(t, err)
+
+	v := vcon.New("example.com")
+	v.AddParty(vcon.Party{Name: "Archived"})
+	signed, err := v.Sign(oldKey, certs)
+	require.NoError(t, err)
+	encrypted, err := signed.Encrypt([]jose.Recipient{{Algorithm: jose.RSA_OAEP, Key: &oldKey.PublicKey}})
+	require.NoError(t, err)
+
+	oldKid, err := vcon.RecipientKeyID(&oldKey.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, []string{oldKid}, encrypted.RecipientKeyIDs())
+
+	// The archive was encrypted before the key was rotated.
+	plain, err := encrypted.DecryptWithKeys([]jose.JSONWebKey{{Key: newKey}, {Key: oldKey}})
+	require.NoError(t, err)
+	rootPool := x509.NewCertPool()
+	rootPool.AddCert(certs[0])
+	verified, err := (&vcon.SignedVCon{JSON: plain}).Verify(rootPool)
+	require.NoError(t, err)
+	assert.Equal(t, v.UUID, verified.UUID)
+
+	// A JWKS entry carrying the kid explicitly also matches.
+	_, err = encrypted.DecryptWithKeys([]jose.JSONWebKey{{Key: oldKey, KeyID: oldKid}})
+	assert.NoError(t, err)
+
+	_, err = encrypted.DecryptWithKeys([]jose.JSONWebKey{{Key: newKey}})
+	assert.ErrorContains(t, err, "matches a recipient")
+	_, err = encrypted.DecryptWithKeys(nil)
+	assert.Error(t, err)
+}
+
 // TestCompleteRoundTrip tests the complete vcon->sign->encrypt->decrypt->verify->original vcon flow
 func TestCompleteRoundTrip(t *testing.T) {
 	// Generate a test certificate
@@ -171,8 +278,8 @@ func TestCompleteRoundTrip(t *testing.T) {
 		Type:       "recording",
 		StartTime:  &now,
 		Duration:   120.5,
-		Parties:    []int{partyIdx},
-		Originator: partyIdx,
+		Parties:    vcon.NewPartyRefs(partyIdx),
+		Originator: vcon.IntPtr(partyIdx),
 		MediaType:  "audio/wav",
 		Body:       "base64urlencodedaudiodata",
 		Encoding:   "base64url",
@@ -250,7 +357,7 @@ func TestCryptoWorkflowWithFormDetection(t *testing.T) {
 	v.AddDialog(vcon.Dialog{
 		Type:      "text",
 		StartTime: &now,
-		Parties:   []int{0},
+		Parties:   vcon.NewPartyRefs(0),
 		Body:      "test message",
 		Encoding:  "none",
 	})
@@ -326,3 +433,161 @@ func TestVerifyRoundTrip(t *testing.T) {
 	assert.Equal(t, vc.UUID, got.UUID, "UUID should match")
 	assert.Equal(t, vc.Vcon, got.Vcon, "Version should match")
 }
+
+// signForeign signs v the way another implementation might: ES256, a kid and
+// the given extra headers instead of the uuid header written by Sign.
+func signForeign(t *testing.T, v *vcon.VCon, headers map[jose.HeaderKey]any) (*vcon.SignedVCon, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(7),
+		Subject:               pkix.Name{CommonName: "other-vendor.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	opts := (&jose.SignerOptions{}).
+		WithHeader("x5c", []string{base64.StdEncoding.EncodeToString(der)}).
+		WithHeader("kid", "vendor-key-1")
+	for k, val := range headers {
+		opts = opts.WithHeader(k, val)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, opts)
+	require.NoError(t, err)
+
+	payload, err := vcon.Canonicalise(v)
+	require.NoError(t, err)
+	obj, err := signer.Sign(payload)
+	require.NoError(t, err)
+	var gen map[string]any
+	require.NoError(t, json.Unmarshal([]byte(obj.FullSerialize()), &gen))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return &vcon.SignedVCon{JSON: gen}, roots
+}
+
+func TestVerifyForeignSignature(t *testing.T) {
+	v := vcon.New("example.com")
+	v.AddParty(vcon.Party{Name: "Alice"})
+
+	signed, roots := signForeign(t, v, nil)
+	got, err := signed.Verify(roots)
+	require.NoError(t, err, "ES256 signature without uuid header should verify")
+	assert.Equal(t, v.UUID, got.UUID)
+
+	_, err = signed.Verify(roots, vcon.WithRequiredHeaders("kid"))
+	assert.NoError(t, err)
+	_, err = signed.Verify(roots, vcon.WithRequiredHeaders("uuid"))
+	assert.ErrorContains(t, err, `missing required header "uuid"`)
+	_, err = signed.Verify(roots, vcon.WithSignatureAlgorithms(jose.RS256))
+	assert.Error(t, err, "ES256 must be rejected when only RS256 is allowed")
+
+	mismatched, roots := signForeign(t, v, map[jose.HeaderKey]any{"uuid": "urn:vendor:call:42"})
+	_, err = mismatched.Verify(roots)
+	assert.Error(t, err)
+	_, err = mismatched.Verify(roots, vcon.WithoutUUIDCheck())
+	assert.NoError(t, err)
+}
+
+func TestSignedVConIntrospection(t *testing.T) {
+	key, certs, err := generateTestCertificate()
+	require.NoError(t, err)
+	v := vcon.New("example.com")
+	v.Subject = "Routed before verification"
+	signed, err := v.Sign(key, certs)
+	require.NoError(t, err)
+
+	headers, err := signed.Headers()
+	require.NoError(t, err)
+	require.Len(t, headers, 1)
+	assert.Equal(t, string(jose.RS256), headers[0].Algorithm)
+	assert.Equal(t, v.UUID, headers[0].ExtraHeaders["uuid"])
+
+	chains, err := signed.SignerCertificates()
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+	require.Len(t, chains[0], 1)
+	assert.Equal(t, "test.example.com", chains[0][0].Subject.CommonName)
+
+	uuid, err := signed.UUID()
+	require.NoError(t, err)
+	assert.Equal(t, v.UUID, uuid)
+
+	got, err := signed.PayloadUnsafe()
+	require.NoError(t, err)
+	assert.Equal(t, v.Subject, got.Subject)
+
+	// Signatures from other implementations are inspected the same way.
+	foreign, _ := signForeign(t, v, nil)
+	headers, err = foreign.Headers()
+	require.NoError(t, err)
+	assert.Equal(t, string(jose.ES256), headers[0].Algorithm)
+	assert.Equal(t, "vendor-key-1", headers[0].KeyID)
+	chains, err = foreign.SignerCertificates()
+	require.NoError(t, err)
+	assert.Equal(t, "other-vendor.example.com", chains[0][0].Subject.CommonName)
+
+	_, err = (&vcon.SignedVCon{JSON: map[string]any{}}).UUID()
+	assert.Error(t, err)
+}
+
+func TestSignAndVerifyECDSA(t *testing.T) {
+	v := vcon.New("example.com")
+	v.AddParty(vcon.Party{Name: "Alice"})
+
+	for _, tc := range []struct {
+		curve elliptic.Curve
+		alg   jose.SignatureAlgorithm
+	}{
+		{elliptic.P256(), jose.ES256},
+		{elliptic.P384(), jose.ES384},
+	} {
+		t.Run(string(tc.alg), func(t *testing.T) {
+			key, err := ecdsa.GenerateKey(tc.curve, rand.Reader)
+			require.NoError(t, err)
+			template := x509.Certificate{
+				SerialNumber:          big.NewInt(1),
+				Subject:               pkix.Name{CommonName: "ec.example.com"},
+				NotBefore:             time.Now().Add(-time.Hour),
+				NotAfter:              time.Now().Add(time.Hour),
+				KeyUsage:              x509.KeyUsageDigitalSignature,
+				BasicConstraintsValid: true,
+			}
+			der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+			require.NoError(t, err)
+			cert, err := x509.ParseCertificate(der)
+			require.NoError(t, err)
+			roots := x509.NewCertPool()
+			roots.AddCert(cert)
+
+			alg, err := vcon.SigningAlgorithm(key)
+			require.NoError(t, err)
+			assert.Equal(t, tc.alg, alg)
+
+			signed, err := v.Sign(key, []*x509.Certificate{cert})
+			require.NoError(t, err)
+			headers, err := signed.Headers()
+			require.NoError(t, err)
+			assert.Equal(t, string(tc.alg), headers[0].Algorithm)
+
+			got, err := signed.Verify(roots, vcon.WithSignatureAlgorithms(tc.alg))
+			require.NoError(t, err)
+			assert.Equal(t, v.UUID, got.UUID)
+			_, err = signed.Verify(roots, vcon.WithSignatureAlgorithms(jose.RS256))
+			assert.Error(t, err, "the allowlist must reject %s", tc.alg)
+		})
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+	_, err = v.Sign(key, nil)
+	assert.ErrorContains(t, err, "unsupported ECDSA curve")
+}
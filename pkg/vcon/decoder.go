@@ -0,0 +1,267 @@
+package vcon
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DialogBodyFunc receives the inline body of dialog index while a Decoder
+// reads it, as a reader of the decoded content, so large recordings can be
+// written elsewhere instead of held in memory. d holds the rest of the
+// dialog and may be updated, e.g. with the URL the content was stored at.
+// The body is not kept in the decoded vCon.
+type DialogBodyFunc func(index int, d *Dialog, content io.Reader) error
+
+// DecoderOption configures a Decoder.
+type DecoderOption func(*Decoder)
+
+// WithDecoderPropertyHandling sets how non-standard properties are handled,
+// as the propertyHandling argument of BuildFromJSON does.
+func WithDecoderPropertyHandling(mode string) DecoderOption {
+	return func(d *Decoder) {
+		d.handling = mode
+	}
+}
+
+// WithDialogBodyFunc passes each inline dialog body to fn instead of
+// keeping it.
+func WithDialogBodyFunc(fn DialogBodyFunc) DecoderOption {
+	return func(d *Decoder) {
+		d.bodyFunc = fn
+	}
+}
+
+// WithoutDialogBodies drops inline dialog bodies, keeping the rest of each
+// dialog, for callers that only need the metadata of a large vCon.
+func WithoutDialogBodies() DecoderOption {
+	return func(d *Decoder) {
+		d.skipBodies = true
+	}
+}
+
+// Decoder reads vCons from a stream. Unlike BuildFromJSON, which holds the
+// document, a map of it and a re-encoded copy at once, it decodes dialogs one
+// at a time and never re-encodes their bodies, so peak memory stays close to
+// the size of the largest dialog when bodies are skipped or streamed.
+//
+// Documents are migrated, checked against the embedded JSON Schema and
+// their properties handled as BuildFromJSON does. Dialog bodies are handed
+// to a DialogBodyFunc as soon as their dialog is read when the vcon
+// version precedes the dialog array, as every encoder writes it, and once
+// the document is read otherwise. Errors about the document as a whole are
+// only returned once it is read.
+type Decoder struct {
+	dec        *json.Decoder
+	handling   string
+	bodyFunc   DialogBodyFunc
+	skipBodies bool
+}
+
+// NewDecoder returns a decoder reading from r.
+func NewDecoder(r io.Reader, opts ...DecoderOption) *Decoder {
+	d := &Decoder{dec: json.NewDecoder(r), handling: PropertyHandlingDefault}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// More reports whether another vCon follows in the stream, for input
+// holding several concatenated documents such as JSON lines.
+func (d *Decoder) More() bool {
+	return d.dec.More()
+}
+
+// pendingDialog is a decoded dialog waiting for its vCon's version.
+type pendingDialog struct {
+	index int
+	raw   map[string]interface{}
+}
+
+// dialogDecoder builds the dialogs of one document.
+type dialogDecoder struct {
+	*Decoder
+	version string
+	dialogs []Dialog
+	raw     []interface{} // dialogs without their bodies, for the schema
+	unknown []string
+	pending []pendingDialog
+}
+
+// Decode reads the next vCon from the stream. It returns io.EOF when the
+// stream holds no more.
+func (d *Decoder) Decode() (*VCon, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("failed to parse JSON: expected a vCon object, got %v", tok)
+	}
+
+	dd := &dialogDecoder{Decoder: d}
+	doc := map[string]interface{}{}
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		key := tok.(string)
+		if key == "dialog" {
+			if err := dd.readDialogs(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var value interface{}
+		if err := d.dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		doc[key] = value
+		if key == "vcon" {
+			dd.version, _ = value.(string)
+		}
+	}
+	if _, err := d.dec.Token(); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	for _, p := range dd.pending {
+		if err := dd.addDialog(p.index, p.raw); err != nil {
+			return nil, err
+		}
+	}
+	return dd.finish(doc)
+}
+
+// readDialogs reads the dialog array, handling each dialog as soon as the
+// version is known.
+func (dd *dialogDecoder) readDialogs() error {
+	tok, err := dd.dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("failed to parse JSON: dialog is not an array")
+	}
+	dd.dialogs, dd.raw = []Dialog{}, []interface{}{}
+	for i := 0; dd.dec.More(); i++ {
+		var raw map[string]interface{}
+		if err := dd.dec.Decode(&raw); err != nil {
+			return fmt.Errorf("failed to parse JSON: dialog %d: %w", i, err)
+		}
+		if dd.version == "" {
+			dd.pending = append(dd.pending, pendingDialog{index: i, raw: raw})
+			continue
+		}
+		if err := dd.addDialog(i, raw); err != nil {
+			return err
+		}
+	}
+	if _, err := dd.dec.Token(); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return nil
+}
+
+// addDialog migrates, checks and decodes one dialog, passing its body on.
+func (dd *dialogDecoder) addDialog(i int, raw map[string]interface{}) error {
+	if dd.version != SpecVersion && migrations[dd.version] != nil {
+		if err := migrateDoc(map[string]interface{}{"vcon": dd.version, "dialog": []interface{}{raw}}, SpecVersion); err != nil {
+			return err
+		}
+	}
+
+	body, hasBody := raw["body"].(string)
+	if hasBody {
+		raw["body"] = ""
+	}
+	dd.raw = append(dd.raw, raw)
+	if dd.handling == PropertyHandlingReject || dd.handling == PropertyHandlingReport {
+		findUnknown(raw, vconPropertySpec(DefaultRegistry).nested["dialog"], fmt.Sprintf("/dialog/%d", i), &dd.unknown)
+	}
+
+	tree := processPropertyTree(raw, vconPropertySpec(DefaultRegistry).nested["dialog"], dd.handling)
+	if err := normalizeDialogTimestamps(tree, i); err != nil {
+		return err
+	}
+	processed, err := json.Marshal(tree)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dialog %d: %w", i, err)
+	}
+	var dialog Dialog
+	if err := json.Unmarshal(processed, &dialog); err != nil {
+		return fmt.Errorf("failed to unmarshal dialog %d: %w", i, err)
+	}
+
+	switch {
+	case !hasBody:
+	case dd.bodyFunc != nil:
+		if err := dd.bodyFunc(i, &dialog, inlineBodyReader(body, dialog.Encoding)); err != nil {
+			return fmt.Errorf("dialog %d body: %w", i, err)
+		}
+		dialog.Body, dialog.Encoding = "", ""
+	case dd.skipBodies:
+		dialog.Encoding = ""
+	default:
+		dialog.Body = body
+	}
+	dd.dialogs = append(dd.dialogs, dialog)
+	return nil
+}
+
+// finish checks the whole document and decodes everything but the dialogs.
+func (dd *dialogDecoder) finish(doc map[string]interface{}) (*VCon, error) {
+	if dd.version != "" && dd.version != SpecVersion && migrations[dd.version] != nil {
+		if err := migrateDoc(doc, SpecVersion); err != nil {
+			return nil, err
+		}
+	}
+	if dd.raw != nil {
+		doc["dialog"] = dd.raw
+	}
+	if err := validateAgainstSchema(doc); err != nil {
+		return nil, err
+	}
+	delete(doc, "dialog")
+
+	var dropped []string
+	if dd.handling == PropertyHandlingReject || dd.handling == PropertyHandlingReport {
+		dropped = append(FindUnknownProperties(doc, DefaultRegistry), dd.unknown...)
+		sort.Strings(dropped)
+		if dd.handling == PropertyHandlingReject && len(dropped) > 0 {
+			return nil, &UnknownPropertiesError{Paths: dropped}
+		}
+	}
+
+	tree := ProcessVConProperties(doc, dd.handling)
+	if err := normalizeTimestamps(tree); err != nil {
+		return nil, err
+	}
+	processed, err := json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal processed map: %w", err)
+	}
+	var v VCon
+	if err := json.Unmarshal(processed, &v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal to VCon: %w", err)
+	}
+	v.Dialog = dd.dialogs
+	v.propertyHandling = dd.handling
+	v.registry = DefaultRegistry
+	v.droppedProperties = dropped
+	return &v, nil
+}
+
+// inlineBodyReader streams the content of an inline body, decoding
+// base64url with or without padding.
+func inlineBodyReader(body, encoding string) io.Reader {
+	if encoding != "base64url" {
+		return strings.NewReader(body)
+	}
+	return base64.NewDecoder(base64.RawURLEncoding, strings.NewReader(strings.TrimRight(body, "=")))
+}
@@ -0,0 +1,140 @@
+package vcon
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoderMatchesBuildFromJSON(t *testing.T) {
+	for _, name := range []string{"comprehensive-vcon.json", "simple-vcon.json"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile("../../testdata/sample_vcons/" + name)
+			require.NoError(t, err)
+			for _, mode := range []string{PropertyHandlingDefault, PropertyHandlingStrict, PropertyHandlingMeta} {
+				want, err := BuildFromJSON(string(data), mode)
+				require.NoError(t, err)
+				got, err := NewDecoder(strings.NewReader(string(data)), WithDecoderPropertyHandling(mode)).Decode()
+				require.NoError(t, err)
+				assert.JSONEq(t, want.ToJSON(), got.ToJSON(), mode)
+			}
+		})
+	}
+}
+
+func TestDecoderDialogBodyFunc(t *testing.T) {
+	doc := `{"vcon": "0.4.0", "uuid": "0190b6c4-0000-8000-8000-000000000000", "created_at": "2024-01-01T00:00:00Z",
+		"parties": [{"name": "Alice"}],
+		"dialog": [
+			{"type": "recording", "start": "2024-01-01T00:00:00Z", "parties": [0], "mediatype": "audio/wav",
+			 "encoding": "base64url", "body": "UklGRiQAAABXQVZF"},
+			{"type": "text", "start": "2024-01-01T00:00:01Z", "parties": [0], "mediatype": "text/plain",
+			 "encoding": "none", "body": "hello"}
+		]}`
+
+	var got []string
+	v, err := NewDecoder(strings.NewReader(doc), WithDialogBodyFunc(func(i int, d *Dialog, content io.Reader) error {
+		data, err := io.ReadAll(content)
+		got = append(got, d.Type+":"+string(data))
+		d.URL = "https://media.example.com/" + d.Type
+		return err
+	})).Decode()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"recording:RIFF$\x00\x00\x00WAVE", "text:hello"}, got)
+	for _, d := range v.Dialog {
+		assert.Empty(t, d.Body)
+		assert.Empty(t, d.Encoding)
+		assert.Equal(t, "https://media.example.com/"+d.Type, d.URL)
+	}
+	assert.NoError(t, v.Validate())
+
+	_, err = NewDecoder(strings.NewReader(doc), WithDialogBodyFunc(func(int, *Dialog, io.Reader) error {
+		return errors.New("disk full")
+	})).Decode()
+	assert.ErrorContains(t, err, "dialog 0 body: disk full")
+
+	v, err = NewDecoder(strings.NewReader(doc), WithoutDialogBodies()).Decode()
+	require.NoError(t, err)
+	assert.Empty(t, v.Dialog[0].Body)
+	assert.Equal(t, "audio/wav", v.Dialog[0].MediaType)
+}
+
+func TestDecoderLegacyVersionAfterDialogs(t *testing.T) {
+	// The version comes last, so the dialogs wait for it to be migrated.
+	doc := `{"uuid": "0190b6c4-0000-8000-8000-000000000000", "created_at": "2024-01-01T00:00:00Z",
+		"parties": [{"name": "Alice", "x_crm": "42"}],
+		"dialog": [{"type": "text", "start": "2024-01-01T00:00:00Z", "parties": [0], "mimetype": "text/plain",
+			"encoding": "base64", "body": "aGk", "x_queue": "sales"}],
+		"vcon": "0.0.2"}`
+
+	var body string
+	v, err := NewDecoder(strings.NewReader(doc), WithDecoderPropertyHandling(PropertyHandlingReport),
+		WithDialogBodyFunc(func(_ int, d *Dialog, content io.Reader) error {
+			data, err := io.ReadAll(content)
+			body = string(data)
+			return err
+		})).Decode()
+	require.NoError(t, err)
+	assert.Equal(t, SpecVersion, v.Vcon)
+	assert.Equal(t, "text/plain", v.Dialog[0].MediaType)
+	assert.Equal(t, "hi", body)
+	assert.Equal(t, []string{"/dialog/0/x_queue", "/parties/0/x_crm"}, v.DroppedProperties())
+
+	_, err = NewDecoder(strings.NewReader(doc), WithDecoderPropertyHandling(PropertyHandlingReject)).Decode()
+	var unknown *UnknownPropertiesError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, []string{"/dialog/0/x_queue", "/parties/0/x_crm"}, unknown.Paths)
+}
+
+func TestDecoderStream(t *testing.T) {
+	a, b := New("example.com"), New("example.com")
+	a.Subject, b.Subject = "first", "second"
+	dec := NewDecoder(strings.NewReader(a.ToJSON() + "\n" + b.ToJSON() + "\n"))
+
+	var subjects []string
+	for dec.More() {
+		v, err := dec.Decode()
+		require.NoError(t, err)
+		subjects = append(subjects, v.Subject)
+	}
+	assert.Equal(t, []string{"first", "second"}, subjects)
+	_, err := dec.Decode()
+	assert.ErrorIs(t, err, io.EOF)
+
+	_, err = NewDecoder(strings.NewReader(`{"uuid": "x", "created_at": "2024-01-01T00:00:00Z", "parties": []`)).Decode()
+	assert.Error(t, err)
+	_, err = NewDecoder(strings.NewReader(`{"uuid": "x", "created_at": "2024-01-01T00:00:00Z", "parties": [], "dialog": [{"type": "bogus", "start": "2024-01-01T00:00:00Z"}]}`)).Decode()
+	assert.ErrorContains(t, err, "schema validation failed")
+}
+
+func TestTimestampsNormalized(t *testing.T) {
+	// RFC 3339 and the schema allow a lower-case "t" and "z"; time.Time does not.
+	doc := `{"vcon": "0.4.0", "uuid": "0190b6c4-0000-8000-8000-000000000000",
+		"created_at": "2024-01-01t10:00:00z", "updated_at": "2024-01-01t10:05:00.250z",
+		"parties": [{"name": "Alice"}],
+		"dialog": [{"type": "text", "start": "2024-01-01t10:00:00+01:00", "parties": [0], "mediatype": "text/plain",
+			"encoding": "none", "body": "hi",
+			"party_history": [{"party": 0, "event": "join", "time": "2024-01-01t09:00:00z"}]}],
+		"attachments": [{"start": "2024-01-01t10:01:00.5-05:00", "party": 0, "dialog": 0, "encoding": "none", "body": "x"}]}`
+
+	want := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	built, err := BuildFromJSON(doc)
+	require.NoError(t, err)
+	decoded, err := NewDecoder(strings.NewReader(doc)).Decode()
+	require.NoError(t, err)
+	for _, v := range []*VCon{built, decoded} {
+		assert.True(t, v.CreatedAt.Equal(want.Add(time.Hour)))
+		require.NotNil(t, v.UpdatedAt)
+		assert.True(t, v.UpdatedAt.Equal(want.Add(65*time.Minute+250*time.Millisecond)))
+		assert.True(t, v.Dialog[0].StartTime.Equal(want))
+		assert.True(t, v.Dialog[0].PartyHistory[0].Time.Equal(want))
+		assert.True(t, v.Attachments[0].StartTime.Equal(want.Add(6*time.Hour+60500*time.Millisecond)))
+	}
+}
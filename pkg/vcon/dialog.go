@@ -1,15 +1,18 @@
 package vcon
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 	"path"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
 )
 
 // MIME types constants
@@ -28,6 +31,13 @@ const (
 	MIMETypeVideoOgg  = "video/ogg"
 	MIMETypeMultipart = "multipart/mixed"
 	MIMETypeRFC822    = "message/rfc822"
+	MIMETypeAudioMP4  = "audio/mp4"
+	MIMETypeAudioFlac = "audio/flac"
+	MIMETypeVideoMPG4 = "video/mp4"
+	MIMETypeVideoWebm = "video/webm"
+	MIMETypeTextVTT   = "text/vtt"
+	MIMETypeJSON      = "application/json"
+	MIMETypePDF       = "application/pdf"
 )
 
 // Valid encoding types (v0.4.0: "base64" removed, only "base64url", "json", "none")
@@ -49,6 +59,13 @@ var SupportedMIMETypes = []string{
 	MIMETypeVideoOgg,
 	MIMETypeMultipart,
 	MIMETypeRFC822,
+	MIMETypeAudioMP4,
+	MIMETypeAudioFlac,
+	MIMETypeVideoMPG4,
+	MIMETypeVideoWebm,
+	MIMETypeTextVTT,
+	MIMETypeJSON,
+	MIMETypePDF,
 }
 
 // Audio MIME types
@@ -62,12 +79,16 @@ var AudioMIMETypes = []string{
 	MIMETypeAudioWebm,
 	MIMETypeAudioM4a,
 	MIMETypeAudioAAC,
+	MIMETypeAudioMP4,
+	MIMETypeAudioFlac,
 }
 
 // Video MIME types
 var VideoMIMETypes = []string{
 	MIMETypeVideoMP4,
 	MIMETypeVideoOgg,
+	MIMETypeVideoMPG4,
+	MIMETypeVideoWebm,
 }
 
 // Dialog is an interaction (call leg, chat, etc.)
@@ -75,8 +96,8 @@ type Dialog struct {
 	Type         string          `json:"type"`  // recording, text, transfer, incomplete
 	StartTime    *time.Time      `json:"start"` // Required
 	Duration     float64         `json:"duration,omitempty"`
-	Parties      interface{}     `json:"parties,omitempty"` // int or []int
-	Originator   int             `json:"originator,omitempty"`
+	Parties      *PartyRefs      `json:"parties,omitempty"`
+	Originator   *int            `json:"originator,omitempty"`
 	MediaType    string          `json:"mediatype,omitempty"` // MIME type
 	Filename     string          `json:"filename,omitempty"`
 	Body         string          `json:"body,omitempty"`
@@ -88,8 +109,8 @@ type Dialog struct {
 	SessionID    interface{}     `json:"session_id,omitempty"` // SessionId or []SessionId
 
 	// Dialog Transfer fields (int or []int per v0.4.0)
-	Transferee     int         `json:"transferee,omitempty"`
-	Transferor     int         `json:"transferor,omitempty"`
+	Transferee     *int        `json:"transferee,omitempty"`
+	Transferor     *int        `json:"transferor,omitempty"`
 	TransferTarget *IntOrSlice `json:"transfer_target,omitempty"`
 	Original       *IntOrSlice `json:"original,omitempty"`
 	Consultation   *IntOrSlice `json:"consultation,omitempty"`
@@ -98,13 +119,23 @@ type Dialog struct {
 	// Additional fields
 	Application string `json:"application,omitempty"`
 	MessageID   string `json:"message_id,omitempty"`
+
+	// Extra holds non-standard properties, such as extension parameters.
+	Extra ExtraProperties `json:"-"`
+
+	// fetched holds content retained by AddExternalData(WithRetainedBody())
+	fetched []byte
+	// fetcher is the ContentFetcher given to AddExternalData, if any
+	fetcher ContentFetcher
+	// handle identifies the dialog to its DialogRefs
+	handle uint64
 }
 
 // DialogOption is a function that configures a Dialog
 type DialogOption func(*Dialog)
 
 // NewDialog creates a new Dialog with the required fields
-func NewDialog(dialogType string, start time.Time, parties interface{}, opts ...DialogOption) *Dialog {
+func NewDialog(dialogType string, start time.Time, parties *PartyRefs, opts ...DialogOption) *Dialog {
 	dialog := &Dialog{
 		Type:      dialogType,
 		StartTime: &start,
@@ -150,8 +181,45 @@ func WithURL(url string) DialogOption {
 // WithOriginator sets the originator party index for a Dialog
 func WithOriginator(originator int) DialogOption {
 	return func(d *Dialog) {
-		d.Originator = originator
+		d.Originator = IntPtr(originator)
+	}
+}
+
+// OriginatorRoles lists the party roles that mark the originating party of a
+// dialog, in order of preference. It is used by DeriveOriginator.
+var OriginatorRoles = []string{"originator", "caller", "sender", "from"}
+
+// DeriveOriginator sets Originator to the first dialog party whose role (as
+// given by roles, keyed by party index) matches one of OriginatorRoles.
+// It returns false and leaves the dialog unchanged if no party matches.
+func (d *Dialog) DeriveOriginator(roles map[int]string) bool {
+	parties := d.partyIndices()
+	for _, want := range OriginatorRoles {
+		for _, idx := range parties {
+			if strings.EqualFold(roles[idx], want) {
+				d.Originator = IntPtr(idx)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OriginatorIndex returns the originating party: Originator when set, and
+// otherwise the first party of the dialog. ok is false when neither is known.
+func (d *Dialog) OriginatorIndex() (idx int, ok bool) {
+	if d.Originator != nil {
+		return *d.Originator, true
 	}
+	if parties := d.partyIndices(); len(parties) > 0 {
+		return parties[0], true
+	}
+	return 0, false
+}
+
+// partyIndices returns the party indices referenced by the dialog.
+func (d *Dialog) partyIndices() []int {
+	return d.Parties.Indices()
 }
 
 func (d *Dialog) addContentHashToMap(result map[string]interface{}) {
@@ -189,11 +257,11 @@ func (d *Dialog) addPartyHistoryToMap(result map[string]interface{}) {
 }
 
 func (d *Dialog) addTransferFieldsToMap(result map[string]interface{}) {
-	if d.Transferee != 0 {
-		result["transferee"] = d.Transferee
+	if d.Transferee != nil {
+		result["transferee"] = *d.Transferee
 	}
-	if d.Transferor != 0 {
-		result["transferor"] = d.Transferor
+	if d.Transferor != nil {
+		result["transferor"] = *d.Transferor
 	}
 	transferFields := []struct {
 		key string
@@ -211,7 +279,8 @@ func (d *Dialog) addTransferFieldsToMap(result map[string]interface{}) {
 	}
 }
 
-// ToMap converts the Dialog to a map, excluding empty fields
+// ToMap converts the Dialog to a map, excluding empty fields. Extra
+// properties are included.
 func (d *Dialog) ToMap() map[string]interface{} {
 	result := make(map[string]interface{})
 
@@ -225,11 +294,11 @@ func (d *Dialog) ToMap() map[string]interface{} {
 	}
 	result["start"] = d.StartTime.Format(time.RFC3339)
 
-	if d.Parties != nil {
+	if !d.Parties.IsZero() {
 		result["parties"] = d.Parties
 	}
-	if d.Originator != 0 {
-		result["originator"] = d.Originator
+	if d.Originator != nil {
+		result["originator"] = *d.Originator
 	}
 	if d.MediaType != "" {
 		result["mediatype"] = d.MediaType
@@ -255,6 +324,7 @@ func (d *Dialog) ToMap() map[string]interface{} {
 	if d.Duration > 0 {
 		result["duration"] = d.Duration
 	}
+	d.Extra.addTo(result)
 
 	return result
 }
@@ -264,73 +334,171 @@ func (d *Dialog) ToDict() map[string]interface{} {
 	return d.ToMap()
 }
 
+// ExternalDataOption configures how AddExternalData retrieves external content.
+type ExternalDataOption func(*externalDataConfig)
+
+type externalDataConfig struct {
+	retainBody  bool
+	contentHash ContentHashList
+	fetcher     ContentFetcher
+	hashAlgs    []string
+}
+
+// WithRetainedBody keeps the fetched content in memory so a later call to
+// ToInlineData can use it instead of downloading the URL a second time.
+func WithRetainedBody() ExternalDataOption {
+	return func(c *externalDataConfig) {
+		c.retainBody = true
+	}
+}
+
+// WithKnownContentHash supplies the content hash up front. The URL is then
+// only probed with a HEAD request for its metadata instead of being
+// downloaded to compute the hash.
+func WithKnownContentHash(hash ContentHashList) ExternalDataOption {
+	return func(c *externalDataConfig) {
+		c.contentHash = hash
+	}
+}
+
+// WithHashAlgorithms sets the algorithms of the content hashes computed for
+// the fetched content, one hash per algorithm, instead of SHA-512 alone.
+// It has no effect with WithKnownContentHash.
+func WithHashAlgorithms(algs ...string) ExternalDataOption {
+	return func(c *externalDataConfig) {
+		c.hashAlgs = algs
+	}
+}
+
+// WithContentFetcher retrieves the content with f instead of the vCon's or
+// the default fetcher. The dialog or analysis keeps f for later fetches,
+// such as ToInlineData, for the lifetime of the value.
+func WithContentFetcher(f ContentFetcher) ExternalDataOption {
+	return func(c *externalDataConfig) {
+		c.fetcher = f
+	}
+}
+
 // AddExternalData adds external data to the dialog
-func (d *Dialog) AddExternalData(urlStr string, filename string, mimeType string) error {
-	// Validate the URL
-	_, err := url.Parse(urlStr)
+func (d *Dialog) AddExternalData(urlStr string, filename string, mimeType string, opts ...ExternalDataOption) error {
+	return d.AddExternalDataContext(context.Background(), urlStr, filename, mimeType, opts...)
+}
+
+// AddExternalDataContext adds external data to the dialog. The context
+// controls cancellation and timeouts of the underlying HTTP requests.
+func (d *Dialog) AddExternalDataContext(ctx context.Context, urlStr string, filename string, mimeType string, opts ...ExternalDataOption) error {
+	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
 		return fmt.Errorf("invalid URL format: %w", err)
 	}
 
-	// Make HTTP request to fetch content
-	resp, err := http.Get(urlStr)
-	if err != nil {
-		return fmt.Errorf("failed to fetch external data: %w", err)
+	cfg := &externalDataConfig{}
+	for _, opt := range opts {
+		opt(cfg)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to fetch external data: HTTP status %d", resp.StatusCode)
+	content, err := resolveExternalContent(ctx, urlStr, cfg)
+	if err != nil {
+		return err
 	}
 
 	// Set the URL
 	d.URL = urlStr
 
-	// Set the content type/MIME type
-	if mimeType != "" {
-		d.MediaType = mimeType
-	} else {
-		d.MediaType = resp.Header.Get("Content-Type")
-	}
-
 	// Set the filename if provided, otherwise extract from URL
 	if filename != "" {
 		d.Filename = filename
 	} else {
-		parsedURL, _ := url.Parse(urlStr)
 		d.Filename = path.Base(parsedURL.Path)
 	}
 
-	// Read the body to calculate hash
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+	// Set the content type/MIME type
+	if mimeType != "" {
+		d.MediaType = mediatype.Normalize(mimeType)
+	} else {
+		d.MediaType = contentMediaType(content.ContentType, d.Filename, content.Body)
 	}
 
-	// Calculate SHA-512 hash
-	d.ContentHash = ContentHashList{ComputeSHA512(body)}
+	d.ContentHash = cfg.hashFor(content)
+	d.fetched = cfg.retained(content)
+	d.fetcher = cfg.fetcher
 
 	return nil
 }
 
-// AddInlineData adds inline data to the dialog
-func (d *Dialog) AddInlineData(body string, filename string, mimeType string) error {
-	// Validate the encoding
-	if d.Encoding != "" && !isValidEncoding(d.Encoding) {
-		return fmt.Errorf("invalid encoding: %s", d.Encoding)
+// InlineDataOption configures AddInlineData.
+type InlineDataOption func(*inlineDataConfig)
+
+type inlineDataConfig struct {
+	preEncoded bool
+	hashAlgs   []string
+}
+
+// WithPreEncoded takes the content given to AddInlineData as already encoded
+// with the dialog's encoding, e.g. a base64url string from another system.
+// It is stored as is and decoded only to check it and compute the hash.
+func WithPreEncoded() InlineDataOption {
+	return func(c *inlineDataConfig) {
+		c.preEncoded = true
 	}
+}
 
-	d.Body = body
-	d.MediaType = mimeType
-	d.Filename = filename
+// WithInlineHashAlgorithms sets the algorithms of the content hashes
+// AddInlineData computes, one hash per algorithm, instead of SHA-512 alone.
+func WithInlineHashAlgorithms(algs ...string) InlineDataOption {
+	return func(c *inlineDataConfig) {
+		c.hashAlgs = algs
+	}
+}
+
+// AddInlineData sets content as the dialog's body, encoded with the dialog's
+// encoding: base64url unless Encoding is already "json" or "none", which
+// need valid JSON and UTF-8 text respectively. The content hash is the
+// SHA-512 of the decoded content, as verification computes it, or one hash
+// per algorithm given with WithInlineHashAlgorithms. The dialog
+// is left unchanged on error.
+func (d *Dialog) AddInlineData(content []byte, filename string, mimeType string, opts ...InlineDataOption) error {
+	cfg := &inlineDataConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	encoding := d.Encoding
+	if encoding == "" {
+		encoding = "base64url"
+	}
+	if !isValidEncoding(encoding) {
+		return fmt.Errorf("invalid encoding: %s", encoding)
+	}
 
-	// Set default encoding if not specified
-	if d.Encoding == "" {
-		d.Encoding = "base64url"
+	raw, body := content, string(content)
+	if cfg.preEncoded {
+		var err error
+		if raw, err = decodeInlineBody(body, encoding); err != nil {
+			return err
+		}
+	} else if encoding == "base64url" {
+		body = encodeBase64URL(content)
+	}
+	switch {
+	case encoding == "json" && !json.Valid(raw):
+		return errors.New("content is not valid JSON for encoding json")
+	case encoding == "none" && !utf8.Valid(raw):
+		return errors.New("content is not UTF-8 text for encoding none")
+	}
+	hashes, err := ComputeContentHashes(raw, cfg.hashAlgs...)
+	if err != nil {
+		return err
 	}
 
-	// Calculate SHA-512 hash
-	d.ContentHash = ContentHashList{ComputeSHA512([]byte(body))}
+	d.Body = body
+	d.Encoding = encoding
+	d.MediaType = mediatype.Normalize(mimeType)
+	if d.MediaType == "" {
+		d.MediaType = mediatype.FromExtension(filename)
+	}
+	d.Filename = filename
+	d.ContentHash = hashes
 
 	return nil
 }
@@ -357,13 +525,13 @@ func (d *Dialog) IsInlineData() bool {
 
 // IsText checks if the dialog is a text dialog
 func (d *Dialog) IsText() bool {
-	return d.MediaType == MIMETypePlainText
+	return mediatype.Equal(d.MediaType, MIMETypePlainText)
 }
 
 // IsAudio checks if the dialog is an audio dialog
 func (d *Dialog) IsAudio() bool {
 	for _, audioType := range AudioMIMETypes {
-		if d.MediaType == audioType {
+		if mediatype.Equal(d.MediaType, audioType) {
 			return true
 		}
 	}
@@ -373,7 +541,7 @@ func (d *Dialog) IsAudio() bool {
 // IsVideo checks if the dialog is a video dialog
 func (d *Dialog) IsVideo() bool {
 	for _, videoType := range VideoMIMETypes {
-		if d.MediaType == videoType {
+		if mediatype.Equal(d.MediaType, videoType) {
 			return true
 		}
 	}
@@ -382,76 +550,57 @@ func (d *Dialog) IsVideo() bool {
 
 // IsEmail checks if the dialog is an email dialog
 func (d *Dialog) IsEmail() bool {
-	return d.MediaType == MIMETypeRFC822
+	return mediatype.Equal(d.MediaType, MIMETypeRFC822)
 }
 
 // IsExternalDataChanged checks if external data has changed by comparing hashes
 func (d *Dialog) IsExternalDataChanged() (bool, error) {
+	return d.IsExternalDataChangedContext(context.Background())
+}
+
+// IsExternalDataChangedContext is IsExternalDataChanged with a context
+// controlling the fetch.
+func (d *Dialog) IsExternalDataChangedContext(ctx context.Context) (bool, error) {
 	if !d.IsExternalData() || d.ContentHash.IsEmpty() {
 		return false, nil
 	}
 
 	// Fetch the content again to compare hash
-	resp, err := http.Get(d.URL)
-	if err != nil {
-		return true, fmt.Errorf("failed to fetch external data: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return true, fmt.Errorf("failed to fetch external data: HTTP status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
+	content, err := pickFetcher(d.fetcher).Fetch(ctx, d.URL)
 	if err != nil {
-		return true, fmt.Errorf("failed to read response body: %w", err)
+		return true, err
 	}
 
 	// Verify using the first hash
-	return !d.ContentHash.First().Verify(body), nil
+	return !d.ContentHash.Verify(content.Body), nil
 }
 
 // ToInlineData converts the dialog from external data to inline data
 func (d *Dialog) ToInlineData() error {
-	if !d.IsExternalData() {
-		return errors.New("dialog is not external data")
-	}
+	return d.ToInlineDataContext(context.Background())
+}
 
-	// Fetch the content
-	resp, err := http.Get(d.URL)
-	if err != nil {
-		return fmt.Errorf("failed to fetch external data: %w", err)
-	}
-	defer resp.Body.Close()
+// ToInlineDataContext is ToInlineData with a context controlling the fetch.
+func (d *Dialog) ToInlineDataContext(ctx context.Context) error {
+	return d.toInlineData(ctx, pickFetcher(d.fetcher))
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to fetch external data: HTTP status %d", resp.StatusCode)
+func (d *Dialog) toInlineData(ctx context.Context, fetcher ContentFetcher) error {
+	if !d.IsExternalData() {
+		return errors.New("dialog is not external data")
 	}
 
-	// Read the body
-	body, err := io.ReadAll(resp.Body)
+	// Reuse content retained by AddExternalData when it still matches
+	body, err := inlineExternal(ctx, fetcher, d.URL, d.fetched, &d.MediaType, &d.Filename, &d.ContentHash)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return err
 	}
+	d.fetched = nil
 
 	// Set the body as base64url encoded content
 	d.Body = encodeBase64URL(body)
 	d.Encoding = "base64url"
 
-	// Set media type if not already set
-	if d.MediaType == "" {
-		d.MediaType = resp.Header.Get("Content-Type")
-	}
-
-	// Set the filename if not already set
-	if d.Filename == "" {
-		parsedURL, _ := url.Parse(d.URL)
-		d.Filename = path.Base(parsedURL.Path)
-	}
-
-	// Calculate SHA-512 hash
-	d.ContentHash = ContentHashList{ComputeSHA512(body)}
-
 	// Remove the URL since this is now inline data
 	d.URL = ""
 
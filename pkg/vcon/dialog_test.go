@@ -1,8 +1,14 @@
 package vcon
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -111,7 +117,7 @@ func TestDialogWithBody(t *testing.T) {
 		StartTime: &startTime,
 		Body:      "This is a test message",
 		MediaType: MIMETypePlainText,
-		Parties:   []int{0, 1},
+		Parties:   NewPartyRefs(0, 1),
 	}
 
 	jsonData, err := json.Marshal(dialog)
@@ -178,8 +184,8 @@ func TestDialogTransfer(t *testing.T) {
 	dialog := Dialog{
 		Type:           "transfer",
 		StartTime:      &startTime,
-		Transferee:     0,
-		Transferor:     1,
+		Transferee:     IntPtr(0),
+		Transferor:     IntPtr(1),
 		TransferTarget: NewIntValue(2),
 		Original:       NewIntValue(3),
 		Consultation:   NewIntValue(4),
@@ -196,12 +202,12 @@ func TestDialogTransfer(t *testing.T) {
 		t.Fatalf("failed to unmarshal transfer dialog: %v", err)
 	}
 
-	if unmarshaled.Transferee != dialog.Transferee {
-		t.Errorf("expected transferee %d, got %d", dialog.Transferee, unmarshaled.Transferee)
+	if unmarshaled.Transferee == nil || *unmarshaled.Transferee != *dialog.Transferee {
+		t.Errorf("expected transferee %d, got %v", *dialog.Transferee, unmarshaled.Transferee)
 	}
 
-	if unmarshaled.Transferor != dialog.Transferor {
-		t.Errorf("expected transferor %d, got %d", dialog.Transferor, unmarshaled.Transferor)
+	if unmarshaled.Transferor == nil || *unmarshaled.Transferor != *dialog.Transferor {
+		t.Errorf("expected transferor %d, got %v", *dialog.Transferor, unmarshaled.Transferor)
 	}
 
 	tt, ok := unmarshaled.TransferTarget.AsInt()
@@ -261,7 +267,7 @@ func TestDialogWithPartyHistory(t *testing.T) {
 		Type:         "recording", // Schema restricts to: recording, text, transfer, incomplete
 		StartTime:    &startTime,
 		Duration:     65.0,
-		Parties:      []int{0, 1},
+		Parties:      NewPartyRefs(0, 1),
 		PartyHistory: history,
 	}
 
@@ -332,21 +338,18 @@ func TestDialogWithEncoding(t *testing.T) {
 	}
 }
 
-func TestDialogPartiesInterface(t *testing.T) {
+func TestDialogPartiesRoundTrip(t *testing.T) {
 	startTime := time.Date(2023, 1, 15, 10, 30, 0, 0, time.UTC)
 
 	tests := []struct {
 		name    string
-		parties interface{}
+		parties *PartyRefs
+		json    string
+		indices []int
 	}{
-		{
-			name:    "single party as int",
-			parties: 0,
-		},
-		{
-			name:    "multiple parties as slice",
-			parties: []int{0, 1, 2},
-		},
+		{"single party as int", NewPartyRef(0), `0`, []int{0}},
+		{"multiple parties as slice", NewPartyRefs(0, 1, 2), `[0,1,2]`, []int{0, 1, 2}},
+		{"nested channel list", mustPartyRefs(t, []any{0, []int{1, 2}}), `[0,[1,2]]`, []int{0, 1, 2}},
 	}
 
 	for _, tt := range tests {
@@ -361,18 +364,36 @@ func TestDialogPartiesInterface(t *testing.T) {
 			if err != nil {
 				t.Fatalf("failed to marshal dialog: %v", err)
 			}
+			if !strings.Contains(string(jsonData), `"parties":`+tt.json) {
+				t.Errorf("parties not serialized as %s: %s", tt.json, jsonData)
+			}
 
 			var unmarshaled Dialog
 			if err := json.Unmarshal(jsonData, &unmarshaled); err != nil {
 				t.Fatalf("failed to unmarshal dialog: %v", err)
 			}
-
-			// The unmarshaled parties will be a float64 or []interface{} due to JSON unmarshaling
-			if unmarshaled.Parties == nil {
-				t.Error("expected parties to be preserved")
+			if !reflect.DeepEqual(unmarshaled.Parties, tt.parties) {
+				t.Errorf("parties changed in round trip: %#v, want %#v", unmarshaled.Parties, tt.parties)
+			}
+			if got := unmarshaled.Parties.Indices(); !reflect.DeepEqual(got, tt.indices) {
+				t.Errorf("Indices() = %v, want %v", got, tt.indices)
 			}
 		})
 	}
+
+	var d Dialog
+	if err := json.Unmarshal([]byte(`{"type":"text","parties":[0,"x"]}`), &d); err == nil {
+		t.Error("expected error for a non-numeric party index")
+	}
+}
+
+func mustPartyRefs(t *testing.T, v any) *PartyRefs {
+	t.Helper()
+	p, err := PartyRefsOf(v)
+	if err != nil {
+		t.Fatalf("PartyRefsOf(%v): %v", v, err)
+	}
+	return p
 }
 
 func TestDialogOmitEmpty(t *testing.T) {
@@ -431,6 +452,13 @@ func TestSupportedMIMETypes(t *testing.T) {
 		MIMETypeVideoOgg,
 		MIMETypeMultipart,
 		MIMETypeRFC822,
+		MIMETypeAudioMP4,
+		MIMETypeAudioFlac,
+		MIMETypeVideoMPG4,
+		MIMETypeVideoWebm,
+		MIMETypeTextVTT,
+		MIMETypeJSON,
+		MIMETypePDF,
 	}
 
 	if len(SupportedMIMETypes) != len(expectedTypes) {
@@ -471,3 +499,309 @@ func TestValidEncodings(t *testing.T) {
 		}
 	}
 }
+
+// newCountingServer serves body and counts requests per HTTP method.
+func newCountingServer(t *testing.T, body string) (*httptest.Server, map[string]int) {
+	t.Helper()
+	counts := map[string]int{}
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		counts[r.Method]++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if r.Method == http.MethodGet {
+			w.Write([]byte(body))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, counts
+}
+
+func TestAddExternalDataRetainedBodyAvoidsSecondFetch(t *testing.T) {
+	srv, counts := newCountingServer(t, "recording-bytes")
+
+	d := &Dialog{Type: "recording"}
+	if err := d.AddExternalData(srv.URL+"/call.wav", "", "", WithRetainedBody()); err != nil {
+		t.Fatalf("AddExternalData: %v", err)
+	}
+	if d.Filename != "call.wav" || d.MediaType != "audio/wav" {
+		t.Errorf("unexpected metadata: filename=%q mediatype=%q", d.Filename, d.MediaType)
+	}
+	if !d.ContentHash.Verify([]byte("recording-bytes")) {
+		t.Error("content hash does not match served body")
+	}
+
+	if err := d.ToInlineData(); err != nil {
+		t.Fatalf("ToInlineData: %v", err)
+	}
+	if counts[http.MethodGet] != 1 {
+		t.Errorf("expected a single GET, got %d", counts[http.MethodGet])
+	}
+	if d.Body != encodeBase64URL([]byte("recording-bytes")) || d.URL != "" {
+		t.Errorf("dialog was not converted to inline data: %+v", d)
+	}
+}
+
+func TestAddExternalDataHashAlgorithms(t *testing.T) {
+	srv, counts := newCountingServer(t, "recording-bytes")
+
+	d := &Dialog{Type: "recording"}
+	if err := d.AddExternalData(srv.URL+"/call.wav", "", "", WithHashAlgorithms("sha256")); err != nil {
+		t.Fatalf("AddExternalData: %v", err)
+	}
+	if len(d.ContentHash) != 1 || d.ContentHash[0] != ComputeSHA256([]byte("recording-bytes")) {
+		t.Errorf("expected a sha256 hash, got %v", d.ContentHash)
+	}
+
+	before := counts[http.MethodGet]
+	if err := d.AddExternalData(srv.URL+"/call.wav", "", "", WithHashAlgorithms("md5")); err == nil {
+		t.Error("expected an error for an unsupported algorithm")
+	}
+	if counts[http.MethodGet] != before {
+		t.Error("an unsupported algorithm should fail before fetching")
+	}
+}
+
+func TestAddExternalDataKnownHashUsesHead(t *testing.T) {
+	srv, counts := newCountingServer(t, "recording-bytes")
+	hash := ContentHashList{ComputeSHA512([]byte("recording-bytes"))}
+
+	d := &Dialog{Type: "recording"}
+	if err := d.AddExternalData(srv.URL+"/call.wav", "call.wav", "", WithKnownContentHash(hash)); err != nil {
+		t.Fatalf("AddExternalData: %v", err)
+	}
+	if counts[http.MethodHead] != 1 || counts[http.MethodGet] != 0 {
+		t.Errorf("expected only a HEAD request, got %v", counts)
+	}
+	if d.MediaType != "audio/wav" {
+		t.Errorf("expected media type from HEAD, got %q", d.MediaType)
+	}
+	if d.ContentHash.First() != hash.First() {
+		t.Error("expected supplied content hash to be kept")
+	}
+}
+
+func TestAddExternalDataKnownHashHeadNotSupported(t *testing.T) {
+	for _, status := range []int{http.StatusMethodNotAllowed, http.StatusNotImplemented} {
+		gets := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(status)
+				return
+			}
+			gets++
+			w.Write([]byte("recording-bytes"))
+		}))
+		hash := ContentHashList{ComputeSHA512([]byte("recording-bytes"))}
+
+		d := &Dialog{Type: "recording"}
+		if err := d.AddExternalData(srv.URL+"/call.wav", "", "", WithKnownContentHash(hash)); err != nil {
+			t.Errorf("HEAD answered %d: AddExternalData: %v", status, err)
+		}
+		if gets != 1 {
+			t.Errorf("HEAD answered %d: expected a GET instead, got %d", status, gets)
+		}
+		srv.Close()
+	}
+}
+
+func TestToInlineDataKeepsHashAlgorithms(t *testing.T) {
+	srv, _ := newCountingServer(t, "recording-bytes")
+	body := []byte("recording-bytes")
+	stale := ContentHashList{
+		{Algorithm: "sha256", Hash: "stale"},
+		{Algorithm: "blake3", Hash: "other-consumer"},
+	}
+
+	d := &Dialog{Type: "recording", URL: srv.URL + "/call.wav", ContentHash: stale}
+	if err := d.ToInlineData(); err != nil {
+		t.Fatalf("ToInlineData: %v", err)
+	}
+	want := ContentHashList{ComputeSHA256(body), stale[1]}
+	if len(d.ContentHash) != 2 || d.ContentHash[0] != want[0] || d.ContentHash[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, d.ContentHash)
+	}
+
+	a := &Attachment{URL: srv.URL + "/call.wav", ContentHash: ContentHashList{{Algorithm: "sha256", Hash: "stale"}}}
+	if err := a.ToInlineData(); err != nil {
+		t.Fatalf("Attachment.ToInlineData: %v", err)
+	}
+	if len(a.ContentHash) != 1 || a.ContentHash[0] != ComputeSHA256(body) {
+		t.Errorf("expected the attachment hash in sha256, got %v", a.ContentHash)
+	}
+
+	an := &Analysis{Type: "summary", URL: srv.URL + "/summary.txt"}
+	if err := an.ToInlineData(); err != nil {
+		t.Fatalf("Analysis.ToInlineData: %v", err)
+	}
+	if len(an.ContentHash) != 1 || an.ContentHash[0] != ComputeSHA512(body) {
+		t.Errorf("expected a sha512 hash without earlier hashes, got %v", an.ContentHash)
+	}
+}
+
+func TestToInlineDataInvalidURL(t *testing.T) {
+	const bad = "http://[::1"
+	if err := (&Dialog{Type: "recording", URL: bad}).ToInlineData(); err == nil || !strings.Contains(err.Error(), "invalid URL") {
+		t.Errorf("dialog: expected an invalid URL error, got %v", err)
+	}
+	if err := (&Analysis{Type: "summary", URL: bad}).ToInlineData(); err == nil || !strings.Contains(err.Error(), "invalid URL") {
+		t.Errorf("analysis: expected an invalid URL error, got %v", err)
+	}
+	if err := (&Attachment{URL: bad}).ToInlineData(); err == nil || !strings.Contains(err.Error(), "invalid URL") {
+		t.Errorf("attachment: expected an invalid URL error, got %v", err)
+	}
+}
+
+func TestAddExternalDataDetectsMediaType(t *testing.T) {
+	wav := "RIFF\x24\x00\x00\x00WAVEfmt "
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte(wav))
+	}))
+	t.Cleanup(srv.Close)
+
+	d := &Dialog{Type: "recording"}
+	if err := d.AddExternalData(srv.URL+"/recording", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if d.MediaType != MIMETypeAudioWav2 || !d.IsAudio() {
+		t.Errorf("expected media type sniffed from the content, got %q", d.MediaType)
+	}
+
+	if err := d.AddExternalData(srv.URL+"/recording", "", MIMETypeAudioWav); err != nil {
+		t.Fatal(err)
+	}
+	if d.MediaType != MIMETypeAudioWav2 {
+		t.Errorf("expected %s to be normalized, got %q", MIMETypeAudioWav, d.MediaType)
+	}
+
+	inline := &Dialog{Type: "recording"}
+	if err := inline.AddInlineData([]byte(wav), "call.m4a", ""); err != nil {
+		t.Fatal(err)
+	}
+	if inline.MediaType != MIMETypeAudioMP4 {
+		t.Errorf("expected media type from the file name, got %q", inline.MediaType)
+	}
+}
+
+func TestAddInlineDataEncodes(t *testing.T) {
+	content := []byte("RIFF\x00\x01binary")
+	d := &Dialog{Type: "recording"}
+	if err := d.AddInlineData(content, "call.wav", ""); err != nil {
+		t.Fatal(err)
+	}
+	if d.Encoding != "base64url" || d.Body != encodeBase64URL(content) {
+		t.Errorf("expected base64url body, got %q encoded %q", d.Body, d.Encoding)
+	}
+	decoded, err := decodeInlineBody(d.Body, d.Encoding)
+	if err != nil || !d.ContentHash.Verify(decoded) {
+		t.Errorf("content hash must match the decoded body: %v", err)
+	}
+
+	pre := &Dialog{Type: "recording"}
+	if err := pre.AddInlineData([]byte(d.Body), "call.wav", "", WithPreEncoded()); err != nil {
+		t.Fatal(err)
+	}
+	if pre.Body != d.Body || pre.ContentHash.First() != d.ContentHash.First() {
+		t.Errorf("pre-encoded content must be stored and hashed like encoded content")
+	}
+	if err := pre.AddInlineData([]byte("not base64!"), "call.wav", "", WithPreEncoded()); err == nil {
+		t.Error("expected an error for invalid base64url")
+	}
+
+	text := &Dialog{Type: "text", Encoding: "none"}
+	if err := text.AddInlineData([]byte("hello"), "", MIMETypePlainText); err != nil {
+		t.Fatal(err)
+	}
+	if text.Body != "hello" || !text.ContentHash.Verify([]byte("hello")) {
+		t.Errorf("unexpected text body %q", text.Body)
+	}
+	if err := text.AddInlineData([]byte{0xff, 0xfe}, "", ""); err == nil {
+		t.Error("expected an error for non-UTF-8 text")
+	}
+	if text.Body != "hello" {
+		t.Error("dialog should not be modified on failure")
+	}
+
+	js := &Dialog{Type: "text", Encoding: "json"}
+	if err := js.AddInlineData([]byte("{not json"), "", MIMETypeJSON); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+	bad := &Dialog{Type: "text", Encoding: "base64"}
+	if err := bad.AddInlineData(content, "", ""); err == nil {
+		t.Error("expected an error for an unknown encoding")
+	}
+
+	multi := &Dialog{Type: "recording"}
+	if err := multi.AddInlineData(content, "", "", WithInlineHashAlgorithms("sha256", "sha512")); err != nil {
+		t.Fatal(err)
+	}
+	if len(multi.ContentHash) != 2 || multi.ContentHash[0].Algorithm != "sha256" || !multi.ContentHash.Verify(content) {
+		t.Errorf("expected sha256 and sha512 hashes, got %v", multi.ContentHash)
+	}
+	if err := multi.AddInlineData(content, "", "", WithInlineHashAlgorithms("md5")); err == nil {
+		t.Error("expected an error for an unsupported algorithm")
+	}
+}
+
+func TestAddExternalDataContextCancelled(t *testing.T) {
+	srv, _ := newCountingServer(t, "recording-bytes")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	d := &Dialog{Type: "recording"}
+	if err := d.AddExternalDataContext(ctx, srv.URL+"/call.wav", "", ""); err == nil {
+		t.Error("expected error for cancelled context")
+	}
+	if d.URL != "" {
+		t.Error("dialog should not be modified on failure")
+	}
+}
+
+func TestIsExternalDataChanged(t *testing.T) {
+	srv, _ := newCountingServer(t, "recording-bytes")
+
+	d := &Dialog{Type: "recording"}
+	if err := d.AddExternalData(srv.URL+"/call.wav", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := d.IsExternalDataChanged()
+	if err != nil || changed {
+		t.Errorf("expected unchanged content, got changed=%v err=%v", changed, err)
+	}
+
+	d.ContentHash = ContentHashList{ComputeSHA512([]byte("something else"))}
+	changed, err = d.IsExternalDataChanged()
+	if err != nil || !changed {
+		t.Errorf("expected changed content, got changed=%v err=%v", changed, err)
+	}
+}
+
+func TestDialogDeriveOriginator(t *testing.T) {
+	tests := []struct {
+		name    string
+		parties interface{}
+		roles   map[int]string
+		want    int
+		found   bool
+	}{
+		{"caller second", []int{0, 1}, map[int]string{0: "agent", 1: "caller"}, 1, true},
+		{"preferred role wins", []int{0, 1, 2}, map[int]string{1: "sender", 2: "originator"}, 2, true},
+		{"case insensitive", []int{3, 4}, map[int]string{4: "Caller"}, 4, true},
+		{"decoded JSON parties", []interface{}{float64(0), float64(1)}, map[int]string{1: "from"}, 1, true},
+		{"role outside dialog", []int{0, 1}, map[int]string{2: "caller"}, 0, false},
+		{"no roles", []int{0, 1}, nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Dialog{Type: "recording", Parties: mustPartyRefs(t, tt.parties)}
+			found := d.DeriveOriginator(tt.roles)
+			if found != tt.found || (d.Originator != nil) != tt.found || (found && *d.Originator != tt.want) {
+				t.Errorf("got originator=%v found=%v, want %d/%v", d.Originator, found, tt.want, tt.found)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package vcon
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeKind classifies a Change.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is one difference between two vCons. Path is a JSON pointer into
+// the vCon; Old and New are the compact JSON values, empty when absent.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	Old  string
+	New  string
+}
+
+// String renders the change as "+ path: value", "- path: value" or
+// "~ path: old -> new".
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Path, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, c.Old, c.New)
+	}
+}
+
+// VConDiff lists the changes from one vCon to another in path order.
+type VConDiff struct {
+	Changes []Change
+}
+
+// appendSections are the arrays an appended vCon may only extend.
+var appendSections = []string{"/parties", "/dialog", "/analysis", "/attachments"}
+
+// Diff reports the structural differences from a to b. Whole entries added
+// to or removed from an array are reported at the entry's path; changed
+// entries are reported field by field.
+func Diff(a, b *VCon) (*VConDiff, error) {
+	old, err := genericVCon(a)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := genericVCon(b)
+	if err != nil {
+		return nil, err
+	}
+	d := &VConDiff{}
+	diffTree("", old, cur, &d.Changes)
+	return d, nil
+}
+
+// DiffJSON reports the structural differences between two vCon documents
+// like Diff, without decoding them into VCons, so documents written for
+// different spec versions can be compared.
+func DiffJSON(a, b []byte) (*VConDiff, error) {
+	old, err := decodeGenericJSON(a)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := decodeGenericJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	d := &VConDiff{}
+	diffTree("", old, cur, &d.Changes)
+	return d, nil
+}
+
+func genericVCon(v *VCon) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal VCon: %w", err)
+	}
+	return decodeGenericJSON(data)
+}
+
+// IsEmpty reports whether the two vCons are identical.
+func (d *VConDiff) IsEmpty() bool {
+	return len(d.Changes) == 0
+}
+
+// Section returns the changes under a top-level property, e.g. "dialog".
+func (d *VConDiff) Section(name string) []Change {
+	prefix := "/" + escapeJSONPointer(name)
+	var out []Change
+	for _, c := range d.Changes {
+		if c.Path == prefix || strings.HasPrefix(c.Path, prefix+"/") {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// OnlyAppends reports whether parties, dialog, analysis and attachments were
+// only extended with new entries, as Append requires. Other properties,
+// such as uuid and amended, are ignored.
+func (d *VConDiff) OnlyAppends() bool {
+	for _, section := range appendSections {
+		for _, c := range d.Section(section[1:]) {
+			if c.Kind != ChangeAdded {
+				return false
+			}
+			// A new field inside an existing entry is a modification.
+			if c.Path != section && strings.Contains(c.Path[len(section)+1:], "/") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// diffTree appends the changes from old to cur under path.
+func diffTree(path string, old, cur interface{}, out *[]Change) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		if c, ok := cur.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(o)+len(c))
+			for k := range o {
+				keys = append(keys, k)
+			}
+			for k := range c {
+				if _, dup := o[k]; !dup {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				p := path + "/" + escapeJSONPointer(k)
+				ov, inOld := o[k]
+				cv, inCur := c[k]
+				switch {
+				case !inOld:
+					*out = append(*out, Change{Kind: ChangeAdded, Path: p, New: compactJSON(cv)})
+				case !inCur:
+					*out = append(*out, Change{Kind: ChangeRemoved, Path: p, Old: compactJSON(ov)})
+				default:
+					diffTree(p, ov, cv, out)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if c, ok := cur.([]interface{}); ok {
+			for i := 0; i < len(o) || i < len(c); i++ {
+				p := fmt.Sprintf("%s/%d", path, i)
+				switch {
+				case i >= len(o):
+					*out = append(*out, Change{Kind: ChangeAdded, Path: p, New: compactJSON(c[i])})
+				case i >= len(c):
+					*out = append(*out, Change{Kind: ChangeRemoved, Path: p, Old: compactJSON(o[i])})
+				default:
+					diffTree(p, o[i], c[i], out)
+				}
+			}
+			return
+		}
+	}
+
+	if o, c := compactJSON(old), compactJSON(cur); o != c {
+		if path == "" {
+			path = "/"
+		}
+		*out = append(*out, Change{Kind: ChangeChanged, Path: path, Old: o, New: c})
+	}
+}
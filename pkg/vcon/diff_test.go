@@ -0,0 +1,78 @@
+package vcon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	a := New("example.com")
+	a.Subject = "Call"
+	a.AddParty(Party{Name: "Alice", Tel: "tel:+15551230001"})
+	a.AddParty(Party{Name: "Bob"})
+
+	b, err := a.Amend(func(c *VCon) error {
+		c.Parties[0].Name = "Alice Smith"
+		c.Parties[1].Mailto = "mailto:bob@example.com"
+		c.Subject = ""
+		c.AddAnalysis(Analysis{Type: "summary", Vendor: "acme"})
+		return nil
+	})
+	require.NoError(t, err)
+
+	d, err := Diff(a, b)
+	require.NoError(t, err)
+	assert.False(t, d.IsEmpty())
+
+	changes := map[string]Change{}
+	for _, c := range d.Changes {
+		changes[c.Path] = c
+	}
+	assert.Equal(t, Change{Kind: ChangeChanged, Path: "/parties/0/name", Old: `"Alice"`, New: `"Alice Smith"`}, changes["/parties/0/name"])
+	assert.Equal(t, ChangeAdded, changes["/parties/1/mailto"].Kind)
+	assert.Equal(t, ChangeRemoved, changes["/subject"].Kind)
+	assert.Equal(t, ChangeAdded, changes["/analysis"].Kind)
+	assert.Equal(t, ChangeChanged, changes["/uuid"].Kind)
+	assert.Len(t, d.Section("parties"), 2)
+	assert.False(t, d.OnlyAppends())
+
+	same, err := Diff(a, a)
+	require.NoError(t, err)
+	assert.True(t, same.IsEmpty())
+}
+
+func TestDiffOnlyAppends(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+
+	delta, err := v.Append(func(c *VCon) error {
+		c.AddParty(Party{Name: "Bob"})
+		c.AddAnalysis(Analysis{Type: "summary", Vendor: "acme"})
+		return nil
+	})
+	require.NoError(t, err)
+	combined, err := delta.Materialize(v)
+	require.NoError(t, err)
+
+	d, err := Diff(v, combined)
+	require.NoError(t, err)
+	assert.True(t, d.OnlyAppends())
+	assert.Equal(t, []Change{{Kind: ChangeAdded, Path: "/parties/1", New: `{"name":"Bob"}`}}, d.Section("parties"))
+}
+
+func TestDiffJSON(t *testing.T) {
+	legacy := []byte(`{"vcon":"0.0.2","dialog":[{"type":"text","mimetype":"text/plain"}]}`)
+	migrated, err := Migrate(legacy, "")
+	require.NoError(t, err)
+
+	d, err := DiffJSON(legacy, migrated)
+	require.NoError(t, err)
+	assert.Contains(t, d.Changes, Change{Kind: ChangeChanged, Path: "/vcon", Old: `"0.0.2"`, New: `"` + SpecVersion + `"`})
+	assert.Contains(t, d.Changes, Change{Kind: ChangeRemoved, Path: "/dialog/0/mimetype", Old: `"text/plain"`})
+	assert.Contains(t, d.Changes, Change{Kind: ChangeAdded, Path: "/dialog/0/mediatype", New: `"text/plain"`})
+
+	_, err = DiffJSON(legacy, []byte("{"))
+	assert.Error(t, err)
+}
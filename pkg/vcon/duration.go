@@ -0,0 +1,63 @@
+package vcon
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// DurationProber returns the playing time in seconds of media content, for
+// instance by running ffprobe on it.
+type DurationProber func(ctx context.Context, content []byte, mediaType string) (float64, error)
+
+// WithDurationCheck makes Validate and IsValid measure the media of every
+// recording dialog that declares a duration with probe and report an error
+// when the two differ by more than tolerance seconds. Media held at a URL
+// is downloaded, unless AddExternalData retained it. Media that cannot be
+// read or probed is reported as a warning.
+func WithDurationCheck(probe DurationProber, tolerance float64) ValidateOption {
+	return func(c *validateConfig) {
+		c.probeDuration = probe
+		c.durationTolerance = tolerance
+	}
+}
+
+// validateDurations compares the declared duration of each recording dialog
+// with the one measured by cfg.probeDuration.
+func (v *VCon) validateDurations(cfg *validateConfig) []ValidationIssue {
+	if cfg.probeDuration == nil {
+		return nil
+	}
+	var issues []ValidationIssue
+	for i, d := range v.Dialog {
+		if d.Duration <= 0 || (d.URL == "" && d.Body == "") || (d.Type != "recording" && !d.IsAudio() && !d.IsVideo()) {
+			continue
+		}
+		path := fmt.Sprintf("/dialog/%d", i)
+		actual, err := measureDialog(cfg.ctx, pickFetcher(d.fetcher, v.contentFetcher()), &d, cfg.probeDuration)
+		if err != nil {
+			issues = append(issues, warnf(path, IssueMediaUnreadable, "%s: cannot measure the media: %v", path, err))
+			continue
+		}
+		if math.Abs(actual-d.Duration) > cfg.durationTolerance {
+			issues = append(issues, issuef(path+"/duration", IssueDurationMismatch,
+				"%s: duration %.2fs does not match the media (%.2fs)", path, d.Duration, actual))
+		}
+	}
+	return issues
+}
+
+// measureDialog probes the inline or external media of d.
+func measureDialog(ctx context.Context, fetcher ContentFetcher, d *Dialog, probe DurationProber) (float64, error) {
+	var content []byte
+	var err error
+	if d.URL != "" {
+		content, _, err = retainedOrFetch(ctx, fetcher, d.URL, d.fetched, d.ContentHash)
+	} else {
+		content, err = decodeInlineBody(d.Body, d.Encoding)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return probe(ctx, content, d.MediaType)
+}
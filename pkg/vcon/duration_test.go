@@ -0,0 +1,64 @@
+package vcon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDurations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("remote-30"))
+	}))
+	t.Cleanup(srv.Close)
+
+	// The fake prober reads the duration from the content.
+	durations := map[string]float64{"inline-60": 60, "remote-30": 30}
+	probe := func(_ context.Context, content []byte, _ string) (float64, error) {
+		if d, ok := durations[string(content)]; ok {
+			return d, nil
+		}
+		return 0, errors.New("unrecognized media")
+	}
+
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	now := time.Now().UTC()
+	recording := func(duration float64, body, url string) Dialog {
+		d := Dialog{Type: "recording", StartTime: &now, Parties: NewPartyRefs(0), Duration: duration, MediaType: MIMETypeAudioWav2}
+		if url != "" {
+			d.URL = url
+		} else {
+			d.Body, d.Encoding = encodeBase64URL([]byte(body)), "base64url"
+		}
+		return d
+	}
+	v.AddDialog(recording(60.4, "inline-60", ""))
+	v.AddDialog(recording(45, "", srv.URL+"/call.wav"))
+	v.AddDialog(recording(10, "garbage", ""))
+	v.AddDialog(recording(0, "inline-60", "")) // no declared duration
+	v.AddDialog(Dialog{Type: "text", StartTime: &now, Parties: NewPartyRefs(0), Duration: 5, Body: "hi", Encoding: "none", MediaType: MIMETypePlainText})
+
+	require.NoError(t, v.Validate(), "the check is opt-in")
+
+	ok, issues := v.IsValid(WithDurationCheck(probe, 1))
+	assert.False(t, ok)
+	codes := map[string]string{}
+	for _, issue := range issues {
+		codes[issue.Path] = issue.Code
+	}
+	assert.Equal(t, map[string]string{
+		"/dialog/1/duration": IssueDurationMismatch,
+		"/dialog/2":          IssueMediaUnreadable,
+	}, codes)
+
+	v.Dialog[1].Duration = 30.2
+	ok, issues = v.IsValid(WithDurationCheck(probe, 0.5))
+	assert.True(t, ok, "%v", issues)
+}
@@ -121,6 +121,35 @@ func SetDialogData(m map[string]any, data DialogData) {
 	}
 }
 
+// DialogFields reads the CC extension fields of a dialog from its Extra.
+func DialogFields(d *vcon.Dialog) (DialogData, error) {
+	var data DialogData
+	for key, dst := range map[string]*string{
+		"campaign":         &data.Campaign,
+		"interaction_type": &data.InteractionType,
+		"interaction_id":   &data.InteractionID,
+		"skill":            &data.Skill,
+	} {
+		if _, err := d.Extra.Get(key, dst); err != nil {
+			return DialogData{}, err
+		}
+	}
+	return data, nil
+}
+
+// SetDialogFields writes the non-empty CC extension fields of data into the
+// dialog's Extra, where they are encoded as dialog parameters.
+func SetDialogFields(d *vcon.Dialog, data DialogData) error {
+	m := map[string]any{}
+	SetDialogData(m, data)
+	for key, value := range m {
+		if err := d.Extra.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // MarshalPartyData serializes PartyData to JSON.
 func MarshalPartyData(data PartyData) ([]byte, error) {
 	return json.Marshal(data)
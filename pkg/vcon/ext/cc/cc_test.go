@@ -2,6 +2,7 @@ package cc
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/robjsliwa/go-vcon/pkg/vcon"
@@ -430,3 +431,73 @@ func TestCCExtensionVConRoundTrip(t *testing.T) {
 		t.Log("note: CC 'role' field survived Go struct round-trip (unexpected but not an error)")
 	}
 }
+
+// TestCCFieldsKeptInEveryPropertyMode checks that the registered CC
+// parameters count as standard when BuildFromJSON and the Decoder process
+// properties, so no mode drops or reports them.
+func TestCCFieldsKeptInEveryPropertyMode(t *testing.T) {
+	ccJSON := `{
+		"vcon": "0.4.0",
+		"uuid": "019471e8-2a00-8a96-be3e-580a44cc285f",
+		"created_at": "2024-01-15T10:00:00Z",
+		"parties": [{"name": "Alice", "role": "agent"}],
+		"dialog": [{"type": "text", "start": "2024-01-15T10:00:00Z", "parties": [0],
+			"body": "hello", "encoding": "none", "campaign": "summer"}]
+	}`
+
+	check := func(t *testing.T, v *vcon.VCon) {
+		t.Helper()
+		var role, campaign string
+		if _, err := v.Parties[0].Extra.Get("role", &role); err != nil || role != "agent" {
+			t.Errorf("expected role=agent, got %q (%v)", role, err)
+		}
+		if _, err := v.Dialog[0].Extra.Get("campaign", &campaign); err != nil || campaign != "summer" {
+			t.Errorf("expected campaign=summer, got %q (%v)", campaign, err)
+		}
+		if dropped := v.DroppedProperties(); len(dropped) > 0 {
+			t.Errorf("expected no dropped properties, got %v", dropped)
+		}
+	}
+
+	for _, mode := range []string{vcon.PropertyHandlingStrict, vcon.PropertyHandlingReject, vcon.PropertyHandlingReport} {
+		t.Run(mode, func(t *testing.T) {
+			v, err := vcon.BuildFromJSON(ccJSON, mode)
+			if err != nil {
+				t.Fatalf("BuildFromJSON: %v", err)
+			}
+			check(t, v)
+
+			v, err = vcon.NewDecoder(strings.NewReader(ccJSON), vcon.WithDecoderPropertyHandling(mode)).Decode()
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			check(t, v)
+		})
+	}
+}
+
+func TestDialogFields(t *testing.T) {
+	d := vcon.Dialog{Type: "recording"}
+	if err := SetDialogFields(&d, DialogData{Campaign: "renewals", Skill: "billing"}); err != nil {
+		t.Fatalf("SetDialogFields: %v", err)
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var loaded vcon.Dialog
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	got, err := DialogFields(&loaded)
+	if err != nil {
+		t.Fatalf("DialogFields: %v", err)
+	}
+	if got != (DialogData{Campaign: "renewals", Skill: "billing"}) {
+		t.Errorf("unexpected CC fields after round trip: %+v", got)
+	}
+	if _, ok := loaded.Extra["interaction_id"]; ok {
+		t.Error("empty fields should not be written")
+	}
+}
@@ -35,6 +35,15 @@ type Extension interface {
 	VConParams() []string
 }
 
+// PartyEventExtension is implemented by extensions that define
+// party_history events beyond PartyEventTypes.
+type PartyEventExtension interface {
+	Extension
+
+	// PartyEvents returns the party_history event names the extension adds.
+	PartyEvents() []string
+}
+
 // ExtensionRegistry manages registered extensions.
 // Thread-safe for concurrent use.
 type ExtensionRegistry struct {
@@ -145,6 +154,25 @@ func (r *ExtensionRegistry) AllowedVConParams() map[string]struct{} {
 	return result
 }
 
+// AllowedPartyEvents returns the core party_history events merged with those
+// added by registered extensions implementing PartyEventExtension.
+func (r *ExtensionRegistry) AllowedPartyEvents() map[string]struct{} {
+	result := make(map[string]struct{})
+	for _, e := range PartyEventTypes {
+		result[string(e)] = struct{}{}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, ext := range r.extensions {
+		if pe, ok := ext.(PartyEventExtension); ok {
+			for _, e := range pe.PartyEvents() {
+				result[e] = struct{}{}
+			}
+		}
+	}
+	return result
+}
+
 // ValidateCritical checks that all names in the critical[] array
 // are registered in this registry. Returns error listing unsupported ones.
 func (r *ExtensionRegistry) ValidateCritical(critical []string) error {
@@ -23,6 +23,14 @@ func (e testExtension) AnalysisParams() []string   { return nil }
 func (e testExtension) AttachmentParams() []string { return nil }
 func (e testExtension) VConParams() []string       { return nil }
 
+// testEventExtension is a mock extension adding party_history events.
+type testEventExtension struct {
+	testExtension
+	events []string
+}
+
+func (e testEventExtension) PartyEvents() []string { return e.events }
+
 func TestExtensionRegistryRegisterAndGet(t *testing.T) {
 	r := NewExtensionRegistry()
 
@@ -49,6 +57,18 @@ func TestExtensionRegistryHas(t *testing.T) {
 	assert.True(t, r.Has("TEST"))
 }
 
+func TestExtensionRegistryAllowedPartyEvents(t *testing.T) {
+	r := NewExtensionRegistry()
+	r.Register(testExtension{name: "PLAIN"})
+	r.Register(testEventExtension{testExtension: testExtension{name: "COACH"}, events: []string{"whisper", "barge"}})
+
+	events := r.AllowedPartyEvents()
+	for _, e := range []string{"join", "drop", "hold", "unhold", "mute", "unmute", "keydown", "keyup", "whisper", "barge"} {
+		assert.Contains(t, events, e)
+	}
+	assert.Len(t, events, 10)
+}
+
 func TestExtensionRegistryValidateCritical(t *testing.T) {
 	r := NewExtensionRegistry()
 	r.Register(testExtension{name: "A"})
@@ -0,0 +1,114 @@
+package vcon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
+)
+
+// BlobWriter stores content moved out of a vCon by ExternalizeBodies and
+// returns the URL it can be fetched from. hash is the SHA-512 of content,
+// which makes a natural content-addressed key.
+type BlobWriter interface {
+	WriteBlob(ctx context.Context, hash ContentHash, mediaType string, content []byte) (string, error)
+}
+
+// BlobWriterFunc adapts a function to the BlobWriter interface.
+type BlobWriterFunc func(ctx context.Context, hash ContentHash, mediaType string, content []byte) (string, error)
+
+// WriteBlob calls f.
+func (f BlobWriterFunc) WriteBlob(ctx context.Context, hash ContentHash, mediaType string, content []byte) (string, error) {
+	return f(ctx, hash, mediaType, content)
+}
+
+// DirBlobWriter is a BlobWriter keeping content in a local directory,
+// typically served over HTTPS at BaseURL. Files are named after the hash of
+// their content, so identical bodies are stored once.
+type DirBlobWriter struct {
+	Dir     string
+	BaseURL string // e.g. "https://media.example.com/vcon"
+}
+
+// WriteBlob writes content to Dir and returns its URL under BaseURL.
+func (w DirBlobWriter) WriteBlob(_ context.Context, hash ContentHash, mediaType string, content []byte) (string, error) {
+	name := hash.Hash + mediatype.Extension(mediaType)
+	if err := os.MkdirAll(w.Dir, 0755); err != nil {
+		return "", err
+	}
+	if err := WriteFileAtomic(filepath.Join(w.Dir, name), content, 0644); err != nil {
+		return "", err
+	}
+	return url.JoinPath(strings.TrimSuffix(w.BaseURL, "/"), name)
+}
+
+// ExternalizeBodies moves inline dialog and attachment bodies larger than
+// threshold bytes, once decoded, to store, replacing each with the URL it
+// returns and a content_hash. It is the inverse of Dialog.ToInlineData and
+// keeps signed vCons small. Attachments left by DedupeAttachments that refer
+// to a moved body get its URL too. It returns the number of bodies moved.
+func (v *VCon) ExternalizeBodies(store BlobWriter, threshold int) (int, error) {
+	return v.ExternalizeBodiesContext(context.Background(), store, threshold)
+}
+
+// ExternalizeBodiesContext is ExternalizeBodies with a context passed on to
+// store.
+func (v *VCon) ExternalizeBodiesContext(ctx context.Context, store BlobWriter, threshold int) (int, error) {
+	moved := 0
+	for i := range v.Dialog {
+		d := &v.Dialog[i]
+		content, err := externalizeBody(ctx, store, threshold, &d.Body, &d.Encoding, &d.URL, &d.ContentHash, d.MediaType)
+		if err != nil {
+			return moved, fmt.Errorf("dialog %d: %w", i, err)
+		}
+		if content != nil {
+			moved++
+		}
+	}
+	for i := range v.Attachments {
+		a := &v.Attachments[i]
+		content, err := externalizeBody(ctx, store, threshold, &a.Body, &a.Encoding, &a.URL, &a.ContentHash, a.MediaType)
+		if err != nil {
+			return moved, fmt.Errorf("attachment %d: %w", i, err)
+		}
+		if content == nil {
+			continue
+		}
+		moved++
+		for j := range v.Attachments {
+			if r := &v.Attachments[j]; r.IsReference() && r.ContentHash.Verify(content) {
+				r.URL = a.URL
+			}
+		}
+	}
+	return moved, nil
+}
+
+// externalizeBody moves one inline body to store when it is larger than
+// threshold and returns its content, or nil when it stays inline.
+func externalizeBody(ctx context.Context, store BlobWriter, threshold int, body, encoding, urlStr *string, hash *ContentHashList, mediaType string) ([]byte, error) {
+	if *body == "" || *urlStr != "" {
+		return nil, nil
+	}
+	content, err := decodeInlineBody(*body, *encoding)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) <= threshold {
+		return nil, nil
+	}
+	sum := ComputeSHA512(content)
+	location, err := store.WriteBlob(ctx, sum, mediaType, content)
+	if err != nil {
+		return nil, err
+	}
+	if hash.IsEmpty() || !hash.Verify(content) {
+		*hash = ContentHashList{sum}
+	}
+	*body, *encoding, *urlStr = "", "", location
+	return content, nil
+}
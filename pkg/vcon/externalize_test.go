@@ -0,0 +1,73 @@
+package vcon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalizeBodies(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	recording := []byte("RIFF....WAVE" + strings.Repeat("x", 100))
+	v := New("example.com")
+	v.AddParty(Party{})
+	v.AddParty(Party{})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0, 1), MediaType: "audio/wav",
+		Body: encodeBase64URL(recording), Encoding: "base64url"})
+	v.AddDialog(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0, 1), MediaType: "text/plain",
+		Body: "short", Encoding: "none"})
+	slides := strings.Repeat("slide ", 20)
+	for p := range 2 {
+		v.AddAttachment(Attachment{DialogIdx: IntPtr(0), PartyIdx: p, StartTime: start, MediaType: "text/plain",
+			Body: slides, Encoding: "none"})
+	}
+	require.Equal(t, 1, v.DedupeAttachments())
+
+	dir := t.TempDir()
+	store := DirBlobWriter{Dir: dir, BaseURL: "https://media.example.com/vcon/"}
+	moved, err := v.ExternalizeBodies(store, 64)
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+	require.NoError(t, v.Validate())
+
+	d := v.Dialog[0]
+	hash := ComputeSHA512(recording)
+	assert.Empty(t, d.Body)
+	assert.Empty(t, d.Encoding)
+	assert.Equal(t, "https://media.example.com/vcon/"+hash.Hash+".wav", d.URL)
+	assert.Equal(t, ContentHashList{hash}, d.ContentHash)
+	stored, err := os.ReadFile(filepath.Join(dir, hash.Hash+".wav"))
+	require.NoError(t, err)
+	assert.Equal(t, recording, stored)
+
+	assert.Equal(t, "short", v.Dialog[1].Body, "bodies under the threshold stay inline")
+
+	// The reference left by DedupeAttachments follows the moved body.
+	require.Len(t, v.Attachments, 2)
+	assert.NotEmpty(t, v.Attachments[0].URL)
+	assert.Equal(t, v.Attachments[0].URL, v.Attachments[1].URL)
+
+	moved, err = v.ExternalizeBodies(store, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved, "only the remaining inline body moves")
+}
+
+func TestExternalizeBodiesError(t *testing.T) {
+	start := time.Now().UTC()
+	v := New("example.com")
+	v.AddDialog(Dialog{Type: "text", StartTime: &start, Body: "hello", Encoding: "none"})
+
+	failing := BlobWriterFunc(func(context.Context, ContentHash, string, []byte) (string, error) {
+		return "", errors.New("bucket unavailable")
+	})
+	_, err := v.ExternalizeBodies(failing, 0)
+	assert.ErrorContains(t, err, "dialog 0: bucket unavailable")
+	assert.Equal(t, "hello", v.Dialog[0].Body)
+}
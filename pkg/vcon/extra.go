@@ -0,0 +1,222 @@
+package vcon
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ExtraProperties holds the non-standard properties of a vCon object, such
+// as extension parameters, verbatim. They are kept when the object is
+// decoded and written back when it is encoded, so a vCon survives a load and
+// save without losing them. Which properties reach the struct at all is
+// decided by the property handling mode of BuildFromJSON.
+type ExtraProperties map[string]json.RawMessage
+
+// Get decodes the property key into dst and reports whether it was present.
+func (e ExtraProperties) Get(key string, dst interface{}) (bool, error) {
+	raw, ok := e[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return true, fmt.Errorf("extra property %q: %w", key, err)
+	}
+	return true, nil
+}
+
+// Set encodes value as the property key.
+func (e *ExtraProperties) Set(key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("extra property %q: %w", key, err)
+	}
+	if *e == nil {
+		*e = ExtraProperties{}
+	}
+	(*e)[key] = raw
+	return nil
+}
+
+// Delete removes the property key.
+func (e ExtraProperties) Delete(key string) {
+	delete(e, key)
+}
+
+// Keys returns the property names in sorted order.
+func (e ExtraProperties) Keys() []string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// addTo copies the properties into a ToMap result, leaving standard keys
+// already present untouched.
+func (e ExtraProperties) addTo(result map[string]interface{}) {
+	for k, raw := range e {
+		if _, ok := result[k]; ok {
+			continue
+		}
+		var v interface{}
+		if json.Unmarshal(raw, &v) == nil {
+			result[k] = v
+		}
+	}
+}
+
+// marshalWithExtra encodes fields, a struct type without JSON methods, and
+// adds the extra properties that do not clash with a standard one.
+func marshalWithExtra(fields interface{}, extra ExtraProperties) ([]byte, error) {
+	data, err := json.Marshal(fields)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		if _, ok := m[k]; !ok {
+			m[k] = v
+		}
+	}
+	return json.Marshal(m)
+}
+
+// unmarshalWithExtra decodes data into fields, a pointer to a struct type
+// without JSON methods, and returns the properties not named in known.
+func unmarshalWithExtra(data []byte, fields interface{}, known map[string]struct{}) (ExtraProperties, error) {
+	if err := json.Unmarshal(data, fields); err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for k := range known {
+		delete(m, k)
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+type (
+	vconFields       VCon
+	partyFields      Party
+	dialogFields     Dialog
+	attachmentFields Attachment
+	analysisFields   Analysis
+)
+
+// MarshalJSON writes the standard fields and the extra properties.
+func (v VCon) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(vconFields(v), v.Extra)
+}
+
+// UnmarshalJSON reads the standard fields and keeps the others in Extra.
+func (v *VCon) UnmarshalJSON(data []byte) error {
+	fields := vconFields(*v)
+	extra, err := unmarshalWithExtra(data, &fields, AllowedVConProperties)
+	if err != nil {
+		return err
+	}
+	fields.Extra = extra
+	*v = VCon(fields)
+	return nil
+}
+
+// MarshalJSON writes the standard fields and the extra properties.
+func (p Party) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(partyFields(p), p.Extra)
+}
+
+// UnmarshalJSON reads the standard fields and keeps the others in Extra.
+func (p *Party) UnmarshalJSON(data []byte) error {
+	fields := partyFields(*p)
+	extra, err := unmarshalWithExtra(data, &fields, AllowedPartyProperties)
+	if err != nil {
+		return err
+	}
+	fields.Extra = extra
+	*p = Party(fields)
+	return nil
+}
+
+// MarshalJSON writes the standard fields and the extra properties.
+func (d Dialog) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(dialogFields(d), d.Extra)
+}
+
+// UnmarshalJSON reads the standard fields and keeps the others in Extra.
+func (d *Dialog) UnmarshalJSON(data []byte) error {
+	fields := dialogFields(*d)
+	extra, err := unmarshalWithExtra(data, &fields, AllowedDialogProperties)
+	if err != nil {
+		return err
+	}
+	fields.Extra = extra
+	*d = Dialog(fields)
+	return nil
+}
+
+// MarshalJSON writes the standard fields and the extra properties.
+func (a Attachment) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(attachmentFields(a), a.Extra)
+}
+
+// UnmarshalJSON reads the standard fields and keeps the others in Extra, as
+// well as a body that is not a string.
+func (a *Attachment) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	body, ok := m["body"]
+	if ok && (len(body) == 0 || body[0] == '"' || string(body) == "null") {
+		ok = false
+	}
+	if ok {
+		delete(m, "body")
+		data, _ = json.Marshal(m)
+	}
+
+	fields := attachmentFields(*a)
+	extra, err := unmarshalWithExtra(data, &fields, AllowedAttachmentProperties)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if extra == nil {
+			extra = ExtraProperties{}
+		}
+		extra["body"] = body
+	}
+	fields.Extra = extra
+	*a = Attachment(fields)
+	return nil
+}
+
+// MarshalJSON writes the standard fields and the extra properties.
+func (a Analysis) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(analysisFields(a), a.Extra)
+}
+
+// UnmarshalJSON reads the standard fields and keeps the others in Extra. A
+// dialog reference is stored as an int or []int, as Merge and RemoveDialog
+// write it.
+func (a *Analysis) UnmarshalJSON(data []byte) error {
+	fields := analysisFields(*a)
+	extra, err := unmarshalWithExtra(data, &fields, AllowedAnalysisProperties)
+	if err != nil {
+		return err
+	}
+	fields.Dialog = remapIndices(fields.Dialog, identityIndex)
+	fields.Extra = extra
+	*a = Analysis(fields)
+	return nil
+}
@@ -0,0 +1,85 @@
+package vcon
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtraPropertiesRoundTrip(t *testing.T) {
+	doc := `{
+		"vcon": "0.4.0",
+		"uuid": "018f0000-0000-8000-8000-000000000000",
+		"created_at": "2024-01-01T00:00:00Z",
+		"x_tenant": "blue",
+		"parties": [{"name": "Alice", "role": "agent", "x_crm": {"id": 42}}],
+		"dialog": [{"type": "text", "start": "2024-01-01T00:00:00Z", "parties": [0], "body": "hi", "encoding": "none", "campaign": "spring"}],
+		"attachments": [{"dialog": 0, "party": 0, "start": "2024-01-01T00:00:00Z", "body": "x", "encoding": "none", "x_scan": true}],
+		"analysis": [{"type": "summary", "vendor": "acme", "body": "ok", "encoding": "none", "x_model": "m1"}],
+		"group": [{"uuid": "018f0000-0000-8000-8000-000000000001", "x_role": "parent"}]
+	}`
+
+	v, err := BuildFromJSON(doc)
+	require.NoError(t, err)
+
+	var role string
+	ok, err := v.Parties[0].Extra.Get("role", &role)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "agent", role)
+	assert.Equal(t, []string{"campaign"}, v.Dialog[0].Extra.Keys())
+
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	want, err := Canonicalise(json.RawMessage(doc))
+	require.NoError(t, err)
+	got, err := Canonicalise(json.RawMessage(out))
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+
+	// Strict mode drops them before they reach the structs.
+	strict, err := BuildFromJSON(doc, PropertyHandlingStrict)
+	require.NoError(t, err)
+	assert.Empty(t, strict.Extra)
+	assert.Empty(t, strict.Parties[0].Extra)
+	assert.NotContains(t, strict.ToJSON(), "x_tenant")
+
+	// Meta mode keeps them under meta.
+	meta, err := BuildFromJSON(doc, PropertyHandlingMeta)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"meta"}, meta.Parties[0].Extra.Keys())
+	assert.Contains(t, meta.ToJSON(), `"meta":{"role":"agent","x_crm":{"id":42}}`)
+}
+
+func TestExtraPropertiesSet(t *testing.T) {
+	p := Party{Name: "Alice"}
+	require.NoError(t, p.Extra.Set("role", "agent"))
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Alice","role":"agent"}`, string(data))
+
+	// Standard fields win over an extra property of the same name.
+	require.NoError(t, p.Extra.Set("name", "Mallory"))
+	data, err = json.Marshal(p)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Alice","role":"agent"}`, string(data))
+
+	p.Extra.Delete("role")
+	assert.Equal(t, []string{"name"}, p.Extra.Keys())
+}
+
+func TestPartyMapExtraProperties(t *testing.T) {
+	p := Party{Name: "Alice"}
+	m := p.ToMap()
+	m["contact_list"] = "VIP"
+	p.SetFromMap(m)
+
+	var list string
+	ok, err := p.Extra.Get("contact_list", &list)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "VIP", list)
+	assert.Equal(t, "VIP", p.ToMap()["contact_list"])
+}
@@ -0,0 +1,318 @@
+package vcon
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
+)
+
+// ExternalContent is content retrieved by a ContentFetcher. ContentType and
+// ContentLength may be unknown, i.e. empty and -1.
+type ExternalContent struct {
+	Body          []byte
+	ContentType   string
+	ContentLength int64
+}
+
+// ContentFetcher retrieves the content a vCon references by URL: external
+// dialog, analysis and attachment bodies and group members. Implement it to
+// reach private stores, e.g. S3 through its SDK; HTTPFetcher covers HTTPS
+// endpoints needing extra headers, bearer tokens or retries.
+//
+// Set one for a whole vCon with VCon.SetContentFetcher or for a single
+// dialog or analysis with the WithContentFetcher option of AddExternalData.
+// DefaultContentFetcher is used otherwise.
+type ContentFetcher interface {
+	Fetch(ctx context.Context, urlStr string) (*ExternalContent, error)
+}
+
+// ContentProber is implemented by fetchers able to retrieve the metadata of
+// content without its body. AddExternalData probes when the content hash is
+// known up front; fetchers without it are asked for the whole content.
+type ContentProber interface {
+	Probe(ctx context.Context, urlStr string) (*ExternalContent, error)
+}
+
+// ContentFetcherFunc adapts a function to the ContentFetcher interface.
+type ContentFetcherFunc func(ctx context.Context, urlStr string) (*ExternalContent, error)
+
+// Fetch calls f.
+func (f ContentFetcherFunc) Fetch(ctx context.Context, urlStr string) (*ExternalContent, error) {
+	return f(ctx, urlStr)
+}
+
+// DefaultContentFetcher fetches external content when neither the vCon nor
+// the item carries a fetcher. It downloads over HTTP with the client
+// installed by SetHTTPConfig.
+var DefaultContentFetcher ContentFetcher = &HTTPFetcher{}
+
+// RetryPolicy controls how HTTPFetcher retries failed requests, with the
+// rules of WithRetries: network errors, 429 and 5xx responses are retried up
+// to Retries more times, other failures are not. The delay starts at Backoff
+// (DefaultRetryBackoff when zero), doubles after every attempt up to
+// DefaultMaxRetryBackoff, and honors Retry-After.
+type RetryPolicy struct {
+	Retries int
+	Backoff time.Duration
+}
+
+// HTTPFetcher is a ContentFetcher for HTTP(S) endpoints.
+type HTTPFetcher struct {
+	// Client sends the requests; nil uses the client installed by
+	// SetHTTPConfig.
+	Client *http.Client
+	// Header holds headers added to every request.
+	Header http.Header
+	// Authorize, when set, is called on every request before it is sent,
+	// e.g. BearerToken to add an Authorization header.
+	Authorize func(req *http.Request) error
+	// Retry is the retry policy; the zero value sends each request once.
+	Retry RetryPolicy
+}
+
+// BearerToken returns an HTTPFetcher.Authorize function sending token as a
+// bearer token.
+func BearerToken(token string) func(*http.Request) error {
+	return func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// Fetch retrieves the content at urlStr with a GET request.
+func (f *HTTPFetcher) Fetch(ctx context.Context, urlStr string) (*ExternalContent, error) {
+	resp, err := f.do(ctx, http.MethodGet, urlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return &ExternalContent{
+		Body:          body,
+		ContentType:   resp.Header.Get("Content-Type"),
+		ContentLength: int64(len(body)),
+	}, nil
+}
+
+// Probe retrieves the metadata of the content at urlStr with a HEAD
+// request, without downloading the body.
+func (f *HTTPFetcher) Probe(ctx context.Context, urlStr string) (*ExternalContent, error) {
+	resp, err := f.do(ctx, http.MethodHead, urlStr)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return &ExternalContent{
+		ContentType:   resp.Header.Get("Content-Type"),
+		ContentLength: resp.ContentLength,
+	}, nil
+}
+
+// do sends a request, retrying as the policy allows. A non-200 response
+// fails with a *StatusError.
+func (f *HTTPFetcher) do(ctx context.Context, method, urlStr string) (*http.Response, error) {
+	backoff := f.Retry.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	var resp *http.Response
+	err := retry(ctx, f.Retry.Retries, backoff, DefaultMaxRetryBackoff, func() (time.Duration, error) {
+		var after time.Duration
+		var err error
+		resp, after, err = f.send(ctx, method, urlStr)
+		return after, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (f *HTTPFetcher) send(ctx context.Context, method, urlStr string) (*http.Response, time.Duration, error) {
+	req, err := NewHTTPRequest(ctx, method, urlStr, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid URL format: %w", err)
+	}
+	for k, vs := range f.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if f.Authorize != nil {
+		if err := f.Authorize(req); err != nil {
+			return nil, 0, fmt.Errorf("failed to authorize request: %w", err)
+		}
+	}
+	var resp *http.Response
+	if f.Client != nil {
+		resp, err = f.Client.Do(req)
+	} else {
+		resp, err = DoHTTP(req)
+	}
+	if err != nil {
+		return nil, 0, &retryableError{fmt.Errorf("failed to fetch external data: %w", err)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err := &StatusError{StatusCode: resp.StatusCode}
+		if resp.StatusCode == http.StatusTooManyRequests || err.ServerError() {
+			return nil, retryAfter(resp), &retryableError{fmt.Errorf("failed to fetch external data: %w", err)}
+		}
+		return nil, 0, fmt.Errorf("failed to fetch external data: %w", err)
+	}
+	return resp, 0, nil
+}
+
+// SetContentFetcher sets the fetcher used for the external content of the
+// vCon's dialogs, analysis and attachments, unless an item carries its own
+// from AddExternalData. A nil f restores DefaultContentFetcher. The fetcher
+// is not serialized and is kept by Redact and the operations built on it.
+func (v *VCon) SetContentFetcher(f ContentFetcher) {
+	v.fetcher = f
+}
+
+// contentFetcher returns the fetcher set with SetContentFetcher, or one using
+// the client set with SetHTTPClient, or nil.
+func (v *VCon) contentFetcher() ContentFetcher {
+	if v.fetcher == nil && v.httpClient != nil {
+		return &HTTPFetcher{Client: v.httpClient}
+	}
+	return v.fetcher
+}
+
+// pickFetcher returns the first non-nil fetcher, or DefaultContentFetcher.
+func pickFetcher(fetchers ...ContentFetcher) ContentFetcher {
+	for _, f := range fetchers {
+		if f != nil {
+			return f
+		}
+	}
+	return DefaultContentFetcher
+}
+
+// resolveExternalContent retrieves the content referenced by urlStr for
+// AddExternalData. When the hash is known the URL is only probed, falling
+// back to a verified download when the fetcher cannot probe or the server
+// does not support HEAD (405 or 501).
+func resolveExternalContent(ctx context.Context, urlStr string, cfg *externalDataConfig) (*ExternalContent, error) {
+	if err := checkHashAlgorithms(cfg.hashAlgs); err != nil {
+		return nil, err
+	}
+	fetcher := pickFetcher(cfg.fetcher)
+	if cfg.contentHash.IsEmpty() {
+		return fetcher.Fetch(ctx, urlStr)
+	}
+	prober, ok := fetcher.(ContentProber)
+	var content *ExternalContent
+	var err error
+	if ok {
+		content, err = prober.Probe(ctx, urlStr)
+	}
+	var statusErr *StatusError
+	if !ok || (errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusMethodNotAllowed || statusErr.StatusCode == http.StatusNotImplemented)) {
+		// Server does not support HEAD; fall back to downloading.
+		content, err = fetcher.Fetch(ctx, urlStr)
+		if err == nil && !cfg.contentHash.Verify(content.Body) {
+			return nil, errors.New("external data does not match the supplied content hash")
+		}
+	}
+	return content, err
+}
+
+// contentMediaType returns the media type of fetched content: the server's
+// Content-Type unless it is missing or generic, otherwise the type detected
+// from the bytes and the name.
+func contentMediaType(contentType, name string, body []byte) string {
+	if contentType != "" && !mediatype.Equal(contentType, mediatype.OctetStream) {
+		return mediatype.Normalize(contentType)
+	}
+	if t := mediatype.Detect(name, body); t != mediatype.OctetStream {
+		return t
+	}
+	return contentType
+}
+
+// hashFor returns the supplied content hash, or the hashes of the fetched
+// content in the algorithms of WithHashAlgorithms when none was supplied.
+// The algorithms were checked by resolveExternalContent.
+func (c *externalDataConfig) hashFor(content *ExternalContent) ContentHashList {
+	if !c.contentHash.IsEmpty() {
+		return c.contentHash
+	}
+	hashes, _ := ComputeContentHashes(content.Body, c.hashAlgs...)
+	return hashes
+}
+
+// retained returns the body to keep in memory, if WithRetainedBody was given.
+func (c *externalDataConfig) retained(content *ExternalContent) []byte {
+	if c.retainBody && content.Body != nil {
+		return content.Body
+	}
+	return nil
+}
+
+// retainedOrFetch returns the retained body when it still matches hash, and
+// downloads urlStr with fetcher otherwise. The content type is only known
+// after a fetch.
+func retainedOrFetch(ctx context.Context, fetcher ContentFetcher, urlStr string, retained []byte, hash ContentHashList) ([]byte, string, error) {
+	if retained != nil && (hash.IsEmpty() || hash.Verify(retained)) {
+		return retained, "", nil
+	}
+	content, err := fetcher.Fetch(ctx, urlStr)
+	if err != nil {
+		return nil, "", err
+	}
+	return content.Body, content.ContentType, nil
+}
+
+// inlineExternal retrieves the content at urlStr for ToInlineData, reusing
+// retained when it still matches hash, and updates what inlining changes:
+// the media type and filename when empty, and the content hash, recomputed
+// in the algorithms it already lists.
+func inlineExternal(ctx context.Context, fetcher ContentFetcher, urlStr string, retained []byte, mediaType, filename *string, hash *ContentHashList) ([]byte, error) {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL format: %w", err)
+	}
+	body, contentType, err := retainedOrFetch(ctx, fetcher, urlStr, retained, *hash)
+	if err != nil {
+		return nil, err
+	}
+	if *mediaType == "" {
+		*mediaType = contentMediaType(contentType, urlStr, body)
+	}
+	if *filename == "" {
+		if name := path.Base(parsedURL.Path); name != "." && name != "/" {
+			*filename = name
+		}
+	}
+	*hash = hash.recompute(body)
+	return body, nil
+}
+
+// decodeInlineBody returns the raw bytes of an inline body. base64url bodies
+// are accepted with or without padding.
+func decodeInlineBody(body, encoding string) ([]byte, error) {
+	if encoding != "base64url" {
+		return []byte(body), nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(body, "="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64url body: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,126 @@
+package vcon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPFetcherAuthAndRetry(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" || r.Header.Get("X-Tenant") != "acme" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if attempts++; attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write([]byte("recording"))
+	}))
+	defer srv.Close()
+
+	f := &HTTPFetcher{
+		Header:    http.Header{"X-Tenant": {"acme"}},
+		Authorize: BearerToken("s3cret"),
+		Retry:     RetryPolicy{Retries: 2, Backoff: time.Millisecond},
+	}
+	content, err := f.Fetch(context.Background(), srv.URL+"/call.wav")
+	require.NoError(t, err)
+	assert.Equal(t, "recording", string(content.Body))
+	assert.Equal(t, "audio/wav", content.ContentType)
+	assert.Equal(t, 3, attempts)
+
+	_, err = (&HTTPFetcher{Retry: RetryPolicy{Retries: 2}}).Fetch(context.Background(), srv.URL+"/call.wav")
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode, "client errors are not retried")
+	assert.Equal(t, 3, attempts)
+
+	authorizations := 0
+	failing := &HTTPFetcher{
+		Authorize: func(*http.Request) error { authorizations++; return errors.New("no token") },
+		Retry:     RetryPolicy{Retries: 2, Backoff: time.Millisecond},
+	}
+	_, err = failing.Fetch(context.Background(), srv.URL+"/call.wav")
+	assert.ErrorContains(t, err, "failed to authorize request")
+	assert.Equal(t, 1, authorizations, "authorization errors are not retried")
+	_, err = failing.Fetch(context.Background(), "://bad")
+	assert.ErrorContains(t, err, "invalid URL format")
+}
+
+func TestHTTPFetcherRetryAfter(t *testing.T) {
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if times = append(times, time.Now()); len(times) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	f := &HTTPFetcher{Retry: RetryPolicy{Retries: 1, Backoff: time.Millisecond}}
+	content, err := f.Fetch(context.Background(), srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(content.Body))
+	require.Len(t, times, 2)
+	assert.GreaterOrEqual(t, times[1].Sub(times[0]), time.Second, "Retry-After replaces the backoff")
+}
+
+func TestContentFetcherForPrivateStore(t *testing.T) {
+	objects := map[string]string{
+		"s3://calls/a.wav":      "audio-a",
+		"s3://calls/notes.txt":  "notes",
+		"s3://calls/summary.md": "summary",
+	}
+	var fetched []string
+	store := ContentFetcherFunc(func(_ context.Context, urlStr string) (*ExternalContent, error) {
+		body, ok := objects[urlStr]
+		if !ok {
+			return nil, fmt.Errorf("no such object %s", urlStr)
+		}
+		fetched = append(fetched, urlStr)
+		return &ExternalContent{Body: []byte(body), ContentLength: int64(len(body))}, nil
+	})
+
+	d := Dialog{Type: "recording"}
+	hash := ContentHashList{ComputeSHA512([]byte("audio-a"))}
+	require.NoError(t, d.AddExternalData("s3://calls/a.wav", "", "audio/wav", WithContentFetcher(store), WithKnownContentHash(hash)))
+	assert.Equal(t, hash, d.ContentHash, "a fetcher that cannot probe is asked for the content")
+	require.NoError(t, d.ToInlineData(), "the dialog keeps its fetcher")
+	assert.Equal(t, encodeBase64URL([]byte("audio-a")), d.Body)
+
+	err := (&Dialog{}).AddExternalData("s3://calls/a.wav", "", "", WithContentFetcher(store),
+		WithKnownContentHash(ContentHashList{ComputeSHA512([]byte("other"))}))
+	assert.ErrorContains(t, err, "does not match the supplied content hash")
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	v.AddParty(Party{})
+	v.AddDialog(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0), Body: "hi", Encoding: "none"})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(0), StartTime: start, URL: "s3://calls/notes.txt", MediaType: "text/plain"})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: 0, URL: "s3://calls/summary.md", MediaType: "text/markdown"})
+	v.SetContentFetcher(store)
+	require.NoError(t, v.InlineAllExternal(context.Background()))
+	assert.Equal(t, "notes", v.Attachments[0].Body)
+	assert.Equal(t, "summary", v.Analysis[0].Body)
+	assert.Len(t, fetched, 5)
+
+	v.SetContentFetcher(nil)
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(0), StartTime: start, URL: "s3://calls/notes.txt"})
+	err = v.InlineAllExternal(context.Background())
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "attachment 1"), err.Error())
+}
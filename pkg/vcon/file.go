@@ -0,0 +1,102 @@
+package vcon
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// WriteFileAtomic writes data to path through a temporary file in the same
+// directory that is synced and then renamed over path, and syncs the
+// directory so the rename survives a crash. Readers see either the old or
+// the new content, and an interrupted write leaves the original file intact.
+// Like os.WriteFile, it keeps the mode of an existing file and only creates
+// a new one with perm, subject to the process umask. A symbolic link is
+// kept: the file it points to is replaced.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	path, err := resolveLink(path)
+	if err != nil {
+		return err
+	}
+	existing := false
+	if fi, err := os.Stat(path); err == nil {
+		perm, existing = fi.Mode().Perm(), true
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	tmp, err := createTemp(path, perm)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	// The umask applied when creating the file must not narrow an existing mode.
+	if existing {
+		if err := tmp.Chmod(perm); err != nil {
+			tmp.Close()
+			return fmt.Errorf("chmod %s: %w", path, err)
+		}
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// resolveLink returns the file path refers to once symbolic links are
+// followed, or path itself when it does not exist yet.
+func resolveLink(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return path, nil
+	}
+	return resolved, err
+}
+
+// syncDir flushes the directory entry of a renamed file to disk. Windows
+// cannot sync directories, and some file systems reject it with EINVAL.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return fmt.Errorf("sync %s: %w", dir, err)
+	}
+	return nil
+}
+
+// createTemp creates a new file next to path with the given permissions.
+// os.CreateTemp always uses 0600, which would override perm.
+func createTemp(path string, perm os.FileMode) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	for range 100 {
+		f, err := os.OpenFile(prefix+strconv.FormatUint(rand.Uint64(), 36), os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+	return nil, fmt.Errorf("create temporary file for %s: too many collisions", path)
+}
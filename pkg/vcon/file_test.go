@@ -0,0 +1,69 @@
+package vcon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "call.json")
+
+	require.NoError(t, WriteFileAtomic(path, []byte("old"), 0600))
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), "a new file gets perm")
+
+	// An existing file keeps its mode, as with os.WriteFile, including the
+	// bits a typical umask would clear from a new one.
+	require.NoError(t, os.Chmod(path, 0664))
+	require.NoError(t, WriteFileAtomic(path, []byte("new"), 0644))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	fi, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0664), fi.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file left behind")
+
+	assert.Error(t, WriteFileAtomic(filepath.Join(dir, "missing", "call.json"), []byte("x"), 0644))
+}
+
+func TestWriteFileAtomicKeepsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "archive", "call.json")
+	require.NoError(t, os.Mkdir(filepath.Dir(target), 0755))
+	require.NoError(t, os.WriteFile(target, []byte("old"), 0600))
+	link := filepath.Join(dir, "latest.json")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	require.NoError(t, WriteFileAtomic(link, []byte("new"), 0644))
+	fi, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, fi.Mode().Type(), "the link is kept")
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+}
+
+func TestSaveToFileKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "call.json")
+	v := New("example.com")
+	require.NoError(t, v.SaveToFile(path))
+	require.NoError(t, os.Chmod(path, 0600))
+
+	v.Subject = "updated"
+	require.NoError(t, v.SaveToFile(path))
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), "personal data must not become world readable")
+}
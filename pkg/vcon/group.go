@@ -0,0 +1,147 @@
+package vcon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// GroupRef is an entry of the group array: a reference to a member vCon by
+// uuid, inline as body and encoding, or by url and content_hash.
+type GroupRef struct {
+	UUID        string          `json:"uuid,omitempty"`
+	Body        string          `json:"body,omitempty"`
+	Encoding    string          `json:"encoding,omitempty"`
+	URL         string          `json:"url,omitempty"`
+	ContentHash ContentHashList `json:"content_hash,omitempty"`
+
+	// Extra holds non-standard properties, which are kept verbatim.
+	Extra ExtraProperties `json:"-"`
+}
+
+// groupRefFields aliases GroupRef without its JSON methods.
+type groupRefFields GroupRef
+
+// MarshalJSON writes the standard fields followed by any non-standard ones.
+func (g GroupRef) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(groupRefFields(g), g.Extra)
+}
+
+// UnmarshalJSON reads the standard fields and keeps the others.
+func (g *GroupRef) UnmarshalJSON(data []byte) error {
+	var fields groupRefFields
+	extra, err := unmarshalWithExtra(data, &fields, AllowedGroupProperties)
+	if err != nil {
+		return err
+	}
+	fields.Extra = extra
+	*g = GroupRef(fields)
+	return nil
+}
+
+// InlineGroupRef returns a reference embedding member as a JSON body.
+func InlineGroupRef(member *VCon) (GroupRef, error) {
+	data, err := json.Marshal(member)
+	if err != nil {
+		return GroupRef{}, fmt.Errorf("failed to marshal group member: %w", err)
+	}
+	return GroupRef{Body: string(data), Encoding: "json"}, nil
+}
+
+// AddToGroup appends ref to the group and returns its index.
+func (v *VCon) AddToGroup(ref GroupRef) int {
+	v.Group = append(v.Group, ref)
+	return len(v.Group) - 1
+}
+
+// GroupLookup loads a member vCon known only by its uuid, e.g. from a store.
+type GroupLookup func(ctx context.Context, uuid string) (*VCon, error)
+
+// ErrGroupMemberNotFound is returned by Resolve for a uuid reference when no
+// lookup is given.
+var ErrGroupMemberNotFound = errors.New("group member not found")
+
+// ResolveOption configures Resolve and ResolveGroup.
+type ResolveOption func(*resolveConfig)
+
+type resolveConfig struct {
+	fetcher ContentFetcher
+}
+
+// WithGroupFetcher fetches members referenced by URL with f instead of the
+// vCon's or the default fetcher, e.g. one with an allowlist or its own
+// HTTP client.
+func WithGroupFetcher(f ContentFetcher) ResolveOption {
+	return func(c *resolveConfig) {
+		c.fetcher = f
+	}
+}
+
+// Resolve loads the referenced member vCon. Inline bodies are parsed, URLs
+// are fetched and checked against content_hash, and uuid references are
+// passed to lookup, which may be nil when the group has none. URLs are
+// fetched with the WithGroupFetcher fetcher, or DefaultContentFetcher.
+func (g GroupRef) Resolve(ctx context.Context, lookup GroupLookup, opts ...ResolveOption) (*VCon, error) {
+	cfg := resolveConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var data []byte
+	switch {
+	case g.Body != "":
+		body, err := decodeInlineBody(g.Body, g.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		data = body
+	case g.URL != "":
+		content, err := pickFetcher(cfg.fetcher).Fetch(ctx, g.URL)
+		if err != nil {
+			return nil, err
+		}
+		if !g.ContentHash.IsEmpty() && !g.ContentHash.Verify(content.Body) {
+			return nil, fmt.Errorf("group member %s does not match its content hash", g.URL)
+		}
+		data = content.Body
+	case g.UUID != "":
+		if lookup == nil {
+			return nil, fmt.Errorf("%w: %s", ErrGroupMemberNotFound, g.UUID)
+		}
+		return lookup(ctx, g.UUID)
+	default:
+		return nil, errors.New("group entry has no uuid, body or url")
+	}
+
+	form, err := DetectForm(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse group member: %w", err)
+	}
+	if form != VConFormUnsigned {
+		return nil, fmt.Errorf("group member is %s; verify or decrypt it first", form)
+	}
+	return BuildFromJSON(string(data))
+}
+
+// ResolveGroup loads every member of the group in order. Members referenced
+// by URL are fetched as WithContentVerification fetches content: with the
+// WithGroupFetcher fetcher, else the vCon's (see SetContentFetcher and
+// SetHTTPClient), else DefaultContentFetcher.
+func (v *VCon) ResolveGroup(ctx context.Context, lookup GroupLookup, opts ...ResolveOption) ([]*VCon, error) {
+	cfg := resolveConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	fetcher := WithGroupFetcher(pickFetcher(cfg.fetcher, v.contentFetcher()))
+
+	members := make([]*VCon, 0, len(v.Group))
+	for i, ref := range v.Group {
+		m, err := ref.Resolve(ctx, lookup, fetcher)
+		if err != nil {
+			return nil, fmt.Errorf("group[%d]: %w", i, err)
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
@@ -0,0 +1,93 @@
+package vcon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupResolve(t *testing.T) {
+	inlineMember := New("example.com")
+	inlineMember.AddParty(Party{Name: "Alice"})
+	storedMember := New("example.com")
+	storedMember.AddParty(Party{Name: "Bob"})
+
+	remote := minimalVConJSON()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(remote))
+	}))
+	defer srv.Close()
+
+	container := New("example.com")
+	inline, err := InlineGroupRef(inlineMember)
+	require.NoError(t, err)
+	assert.Equal(t, 0, container.AddToGroup(inline))
+	container.AddToGroup(GroupRef{URL: srv.URL, ContentHash: ContentHashList{ComputeSHA512([]byte(remote))}})
+	container.AddToGroup(GroupRef{UUID: storedMember.UUID})
+	require.NoError(t, container.Validate())
+
+	lookup := func(_ context.Context, uuid string) (*VCon, error) {
+		if uuid == storedMember.UUID {
+			return storedMember, nil
+		}
+		return nil, ErrGroupMemberNotFound
+	}
+	members, err := container.ResolveGroup(context.Background(), lookup)
+	require.NoError(t, err)
+	require.Len(t, members, 3)
+	assert.Equal(t, inlineMember.UUID, members[0].UUID)
+	assert.Equal(t, "018f0000-0000-8000-8000-000000000000", members[1].UUID)
+	assert.Same(t, storedMember, members[2])
+
+	_, err = container.ResolveGroup(context.Background(), nil)
+	assert.True(t, errors.Is(err, ErrGroupMemberNotFound))
+
+	bad := GroupRef{URL: srv.URL, ContentHash: ContentHashList{ComputeSHA512([]byte("other"))}}
+	_, err = bad.Resolve(context.Background(), nil)
+	assert.ErrorContains(t, err, "content hash")
+}
+
+func TestGroupResolveFetcher(t *testing.T) {
+	remote := minimalVConJSON()
+	var fetched []string
+	fetcher := ContentFetcherFunc(func(_ context.Context, urlStr string) (*ExternalContent, error) {
+		fetched = append(fetched, urlStr)
+		return &ExternalContent{Body: []byte(remote)}, nil
+	})
+	ref := GroupRef{URL: "https://vcons.example.com/leg2.json", ContentHash: ContentHashList{ComputeSHA512([]byte(remote))}}
+
+	m, err := ref.Resolve(context.Background(), nil, WithGroupFetcher(fetcher))
+	require.NoError(t, err)
+	assert.Equal(t, "018f0000-0000-8000-8000-000000000000", m.UUID)
+
+	// ResolveGroup uses the vCon's fetcher unless one is passed.
+	container := New("example.com")
+	container.AddToGroup(ref)
+	container.SetContentFetcher(fetcher)
+	_, err = container.ResolveGroup(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Len(t, fetched, 2)
+
+	denied := ContentFetcherFunc(func(context.Context, string) (*ExternalContent, error) {
+		return nil, errors.New("host not allowed")
+	})
+	_, err = container.ResolveGroup(context.Background(), nil, WithGroupFetcher(denied))
+	assert.ErrorContains(t, err, "host not allowed")
+	assert.Len(t, fetched, 2)
+}
+
+func TestGroupRefKeepsUnknownProperties(t *testing.T) {
+	var ref GroupRef
+	require.NoError(t, json.Unmarshal([]byte(`{"uuid":"u1","x_order":2}`), &ref))
+	assert.Equal(t, "u1", ref.UUID)
+
+	out, err := json.Marshal(ref)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"uuid":"u1","x_order":2}`, string(out))
+}
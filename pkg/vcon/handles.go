@@ -0,0 +1,186 @@
+package vcon
+
+import (
+	"iter"
+	"sync/atomic"
+)
+
+// lastHandle numbers the dialogs and attachments a ref has been taken for.
+var lastHandle atomic.Uint64
+
+// DialogRef is a handle on a dialog that stays valid while the dialog is
+// moved or other dialogs are removed, unlike its index. The zero DialogRef
+// refers to nothing.
+type DialogRef struct {
+	v      *VCon
+	handle uint64
+}
+
+// DialogRef returns a handle on the dialog at index i, or the zero
+// DialogRef when i is out of range.
+func (v *VCon) DialogRef(i int) DialogRef {
+	if i < 0 || i >= len(v.Dialog) {
+		return DialogRef{}
+	}
+	d := &v.Dialog[i]
+	if d.handle == 0 {
+		d.handle = lastHandle.Add(1)
+	}
+	return DialogRef{v: v, handle: d.handle}
+}
+
+// AddDialogRef adds d like AddDialog and returns a handle on it.
+func (v *VCon) AddDialogRef(d Dialog) DialogRef {
+	return v.DialogRef(v.AddDialog(d))
+}
+
+// FindDialogRef returns a handle on the first dialog with a matching
+// property value, as FindDialogByProperty finds it.
+func (v *VCon) FindDialogRef(by string, val interface{}) (DialogRef, bool) {
+	for i, dialog := range v.Dialog {
+		if dialogVal, ok := structToMap(dialog)[by]; ok && dialogVal == val {
+			return v.DialogRef(i), true
+		}
+	}
+	return DialogRef{}, false
+}
+
+// DialogRefs iterates over handles on the dialogs present when it starts,
+// so the loop body may remove or reorder dialogs. Removed dialogs not yet
+// reached are skipped.
+func (v *VCon) DialogRefs() iter.Seq[DialogRef] {
+	return func(yield func(DialogRef) bool) {
+		refs := make([]DialogRef, len(v.Dialog))
+		for i := range refs {
+			refs[i] = v.DialogRef(i)
+		}
+		for _, r := range refs {
+			if r.Valid() && !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// Index returns the dialog's current index, or -1 once it has been removed.
+func (r DialogRef) Index() int {
+	if r.v == nil {
+		return -1
+	}
+	for i := range r.v.Dialog {
+		if r.v.Dialog[i].handle == r.handle {
+			return i
+		}
+	}
+	return -1
+}
+
+// Valid reports whether the dialog is still part of its vCon.
+func (r DialogRef) Valid() bool {
+	return r.Index() >= 0
+}
+
+// Dialog returns the dialog for reading or changing in place, or nil once it
+// has been removed. The pointer is only good until the next change to the
+// vCon's dialogs; keep the ref instead.
+func (r DialogRef) Dialog() *Dialog {
+	i := r.Index()
+	if i < 0 {
+		return nil
+	}
+	return &r.v.Dialog[i]
+}
+
+// Remove removes the dialog with RemoveDialog.
+func (r DialogRef) Remove() error {
+	return r.v.RemoveDialog(r.Index())
+}
+
+// AttachmentRef is a handle on an attachment that stays valid while the
+// attachment is moved or others are removed, unlike its index. The zero
+// AttachmentRef refers to nothing.
+type AttachmentRef struct {
+	v      *VCon
+	handle uint64
+}
+
+// AttachmentRef returns a handle on the attachment at index i, or the zero
+// AttachmentRef when i is out of range.
+func (v *VCon) AttachmentRef(i int) AttachmentRef {
+	if i < 0 || i >= len(v.Attachments) {
+		return AttachmentRef{}
+	}
+	a := &v.Attachments[i]
+	if a.handle == 0 {
+		a.handle = lastHandle.Add(1)
+	}
+	return AttachmentRef{v: v, handle: a.handle}
+}
+
+// AddAttachmentRef adds att like AddAttachment and returns a handle on it.
+func (v *VCon) AddAttachmentRef(att Attachment) AttachmentRef {
+	return v.AttachmentRef(v.AddAttachment(att))
+}
+
+// FindAttachmentRef returns a handle on the first attachment of the given
+// type, as FindAttachmentByType finds it.
+func (v *VCon) FindAttachmentRef(attachmentType string) (AttachmentRef, bool) {
+	for i, att := range v.Attachments {
+		if att.Purpose == attachmentType || att.Type == attachmentType || att.Encoding == attachmentType {
+			return v.AttachmentRef(i), true
+		}
+	}
+	return AttachmentRef{}, false
+}
+
+// AttachmentRefs iterates over handles on the attachments present when it
+// starts, so the loop body may remove or reorder attachments. Removed
+// attachments not yet reached are skipped.
+func (v *VCon) AttachmentRefs() iter.Seq[AttachmentRef] {
+	return func(yield func(AttachmentRef) bool) {
+		refs := make([]AttachmentRef, len(v.Attachments))
+		for i := range refs {
+			refs[i] = v.AttachmentRef(i)
+		}
+		for _, r := range refs {
+			if r.Valid() && !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// Index returns the attachment's current index, or -1 once it has been
+// removed.
+func (r AttachmentRef) Index() int {
+	if r.v == nil {
+		return -1
+	}
+	for i := range r.v.Attachments {
+		if r.v.Attachments[i].handle == r.handle {
+			return i
+		}
+	}
+	return -1
+}
+
+// Valid reports whether the attachment is still part of its vCon.
+func (r AttachmentRef) Valid() bool {
+	return r.Index() >= 0
+}
+
+// Attachment returns the attachment for reading or changing in place, or nil
+// once it has been removed. The pointer is only good until the next change
+// to the vCon's attachments; keep the ref instead.
+func (r AttachmentRef) Attachment() *Attachment {
+	i := r.Index()
+	if i < 0 {
+		return nil
+	}
+	return &r.v.Attachments[i]
+}
+
+// Remove removes the attachment with RemoveAttachment.
+func (r AttachmentRef) Remove() error {
+	return r.v.RemoveAttachment(r.Index())
+}
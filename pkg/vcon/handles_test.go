@@ -0,0 +1,65 @@
+package vcon
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialogAndAttachmentRefs(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	var refs []DialogRef
+	for _, body := range []string{"first", "second", "third"} {
+		refs = append(refs, v.AddDialogRef(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0), Body: body, Encoding: "none"}))
+	}
+	notes := v.AddAttachmentRef(Attachment{DialogIdx: IntPtr(2), StartTime: start, Purpose: "notes", Body: "on third", Encoding: "none"})
+
+	require.NoError(t, refs[0].Remove())
+	assert.False(t, refs[0].Valid())
+	assert.Nil(t, refs[0].Dialog())
+	assert.Equal(t, 1, refs[2].Index())
+	refs[2].Dialog().Body = "third, annotated"
+	assert.Equal(t, "third, annotated", v.Dialog[1].Body)
+	assert.Equal(t, 1, *notes.Attachment().DialogIdx)
+
+	// Handles follow their items when the slice is reordered.
+	slices.Reverse(v.Dialog)
+	assert.Equal(t, 0, refs[2].Index())
+	assert.Equal(t, "second", refs[1].Dialog().Body)
+
+	// Copies added again are new items.
+	again := v.AddDialogRef(v.Dialog[0])
+	assert.Equal(t, 2, again.Index())
+	assert.Equal(t, 0, refs[2].Index())
+
+	found, ok := v.FindDialogRef("body", "second")
+	require.True(t, ok)
+	assert.Equal(t, refs[1], found)
+	_, ok = v.FindDialogRef("body", "missing")
+	assert.False(t, ok)
+
+	// Iteration tolerates removals.
+	var seen []string
+	for r := range v.DialogRefs() {
+		seen = append(seen, r.Dialog().Body)
+		if r == refs[2] {
+			require.NoError(t, again.Remove())
+		}
+	}
+	assert.Equal(t, []string{"third, annotated", "second"}, seen)
+
+	byType, ok := v.FindAttachmentRef("notes")
+	require.True(t, ok)
+	assert.Equal(t, notes, byType)
+	for r := range v.AttachmentRefs() {
+		require.NoError(t, r.Remove())
+	}
+	assert.False(t, notes.Valid())
+	assert.Error(t, notes.Remove())
+	assert.False(t, DialogRef{}.Valid())
+}
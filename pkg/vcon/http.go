@@ -0,0 +1,240 @@
+package vcon
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent is sent with every request unless HTTPConfig.UserAgent
+// overrides it.
+const DefaultUserAgent = "go-vcon/" + SpecVersion
+
+// HTTPConfig configures the HTTP client used for every network call made by
+// the library: LoadFromURL, PostToURL, external content fetches and the
+// vconctl converters. Install it with SetHTTPConfig, or install a client of
+// your own with SetHTTPClient.
+type HTTPConfig struct {
+	// UserAgent replaces DefaultUserAgent.
+	UserAgent string
+	// Host overrides the Host header, e.g. when addressing a gateway by IP.
+	Host string
+	// Header holds extra headers added to every request.
+	Header http.Header
+
+	// Proxy is the proxy URL. When empty the HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY environment variables are honored.
+	Proxy string
+
+	// CAFile is a PEM bundle of additional trusted CAs. It is added to
+	// RootCAs, or to the system pool when RootCAs is nil.
+	CAFile  string
+	RootCAs *x509.CertPool
+
+	// ClientCertFile and ClientKeyFile are a PEM certificate and key used for
+	// TLS client authentication. ClientCertificates may be used instead.
+	ClientCertFile     string
+	ClientKeyFile      string
+	ClientCertificates []tls.Certificate
+
+	// InsecureSkipVerify disables server certificate verification. Only use
+	// it for testing.
+	InsecureSkipVerify bool
+
+	// Timeout bounds each request; zero means no timeout.
+	Timeout time.Duration
+
+	// Transport replaces the clone of http.DefaultTransport the client is
+	// built on, e.g. to add instrumentation. An *http.Transport is cloned
+	// and the proxy and TLS settings above applied to it; any other
+	// RoundTripper is used as is and cannot be combined with them.
+	Transport http.RoundTripper
+}
+
+var (
+	httpMu     sync.RWMutex
+	httpConfig HTTPConfig
+	httpClient = http.DefaultClient
+)
+
+// SetHTTPConfig builds a client from cfg and installs it for all subsequent
+// requests made by the package, replacing any set with SetHTTPClient.
+func SetHTTPConfig(cfg HTTPConfig) error {
+	client, err := cfg.NewClient()
+	if err != nil {
+		return err
+	}
+	httpMu.Lock()
+	defer httpMu.Unlock()
+	httpConfig, httpClient = cfg, client
+	return nil
+}
+
+// SetHTTPClient installs client for all subsequent requests made by the
+// package, keeping the User-Agent, Host and headers of the installed
+// HTTPConfig. Its proxy, TLS and timeout settings are the client's own. A nil
+// client restores one built from the installed HTTPConfig.
+func SetHTTPClient(client *http.Client) error {
+	httpMu.Lock()
+	defer httpMu.Unlock()
+	if client == nil {
+		c, err := httpConfig.NewClient()
+		if err != nil {
+			return err
+		}
+		client = c
+	}
+	httpClient = client
+	return nil
+}
+
+// HTTPClient returns the client installed by SetHTTPConfig or SetHTTPClient.
+func HTTPClient() *http.Client {
+	httpMu.RLock()
+	defer httpMu.RUnlock()
+	return httpClient
+}
+
+// CurrentHTTPConfig returns the configuration installed by SetHTTPConfig.
+func CurrentHTTPConfig() HTTPConfig {
+	httpMu.RLock()
+	defer httpMu.RUnlock()
+	return httpConfig
+}
+
+// NewClient builds an *http.Client honoring the proxy, TLS and timeout
+// settings of the configuration.
+func (c HTTPConfig) NewClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport)
+	if c.Transport != nil {
+		t, ok := c.Transport.(*http.Transport)
+		if !ok {
+			if c.Proxy != "" || c.CAFile != "" || c.RootCAs != nil || c.ClientCertFile != "" || c.ClientKeyFile != "" ||
+				len(c.ClientCertificates) > 0 || c.InsecureSkipVerify {
+				return nil, errors.New("proxy and TLS settings need an *http.Transport")
+			}
+			return &http.Client{Transport: c.Transport, Timeout: c.Timeout}, nil
+		}
+		transport = t
+	}
+	transport = transport.Clone()
+
+	if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport, Timeout: c.Timeout}, nil
+}
+
+func (c HTTPConfig) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		RootCAs:            c.RootCAs,
+		Certificates:       append([]tls.Certificate(nil), c.ClientCertificates...),
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if cfg.RootCAs == nil {
+			if cfg.RootCAs, err = x509.SystemCertPool(); err != nil {
+				cfg.RootCAs = x509.NewCertPool()
+			}
+		} else {
+			cfg.RootCAs = cfg.RootCAs.Clone()
+		}
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CAFile)
+		}
+	}
+
+	if c.ClientCertFile != "" || c.ClientKeyFile != "" {
+		if c.ClientCertFile == "" || c.ClientKeyFile == "" {
+			return nil, errors.New("client certificate and key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	return cfg, nil
+}
+
+// NewHTTPRequest creates a request carrying the configured User-Agent, Host
+// and extra headers.
+func NewHTTPRequest(ctx context.Context, method, urlStr string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, body)
+	if err != nil {
+		return nil, err
+	}
+	cfg := CurrentHTTPConfig()
+	for k, vs := range cfg.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	ua := cfg.UserAgent
+	if ua == "" {
+		ua = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", ua)
+	if cfg.Host != "" {
+		req.Host = cfg.Host
+	}
+	return req, nil
+}
+
+// DoHTTP sends req with the client installed by SetHTTPConfig or
+// SetHTTPClient.
+func DoHTTP(req *http.Request) (*http.Response, error) {
+	return HTTPClient().Do(req)
+}
+
+// SetHTTPClient sets the client used by PostToURL and, unless a
+// ContentFetcher is set, for the external content of the vCon's dialogs,
+// analysis and attachments. A nil client restores the package client. Like
+// the fetcher it is not serialized and is kept by Redact.
+func (v *VCon) SetHTTPClient(client *http.Client) {
+	v.httpClient = client
+}
+
+// doHTTP sends req with the vCon's client, or the package client.
+func (v *VCon) doHTTP(req *http.Request) (*http.Response, error) {
+	if v.httpClient != nil {
+		return v.httpClient.Do(req)
+	}
+	return DoHTTP(req)
+}
+
+// PostToURL sends the vCon as JSON to urlStr with a POST request. Any 2xx
+// response is treated as success; others fail with a *StatusError. Use
+// PostToURLWithOptions to retry transient failures.
+func (v *VCon) PostToURL(urlStr string) error {
+	return v.PostToURLContext(context.Background(), urlStr)
+}
+
+// PostToURLContext is PostToURL with a context controlling the request.
+func (v *VCon) PostToURLContext(ctx context.Context, urlStr string) error {
+	return v.PostToURLWithOptionsContext(ctx, urlStr)
+}
@@ -0,0 +1,308 @@
+package vcon
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useHTTPConfig installs cfg for the duration of the test.
+func useHTTPConfig(t *testing.T, cfg HTTPConfig) {
+	t.Helper()
+	if err := SetHTTPConfig(cfg); err != nil {
+		t.Fatalf("SetHTTPConfig: %v", err)
+	}
+	t.Cleanup(func() { SetHTTPConfig(HTTPConfig{}) })
+}
+
+func minimalVConJSON() string {
+	return `{"uuid":"018f0000-0000-8000-8000-000000000000","created_at":"2024-01-01T00:00:00Z","parties":[{"name":"Alice"}]}`
+}
+
+func TestLoadFromURLSendsConfiguredHeaders(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Clone(r.Context())
+		w.Write([]byte(minimalVConJSON()))
+	}))
+	defer srv.Close()
+
+	if _, err := LoadFromURL(srv.URL); err != nil {
+		t.Fatalf("LoadFromURL: %v", err)
+	}
+	if ua := got.Header.Get("User-Agent"); ua != DefaultUserAgent {
+		t.Errorf("default User-Agent = %q, want %q", ua, DefaultUserAgent)
+	}
+
+	useHTTPConfig(t, HTTPConfig{
+		UserAgent: "acme-ingest/1.0",
+		Host:      "vcons.internal",
+		Header:    http.Header{"X-Tenant": []string{"blue"}},
+	})
+	if _, err := LoadFromURL(srv.URL); err != nil {
+		t.Fatalf("LoadFromURL: %v", err)
+	}
+	if ua := got.Header.Get("User-Agent"); ua != "acme-ingest/1.0" {
+		t.Errorf("User-Agent = %q", ua)
+	}
+	if got.Host != "vcons.internal" {
+		t.Errorf("Host = %q", got.Host)
+	}
+	if got.Header.Get("X-Tenant") != "blue" {
+		t.Errorf("missing extra header, got %v", got.Header)
+	}
+}
+
+func TestPostToURL(t *testing.T) {
+	var body []byte
+	var contentType string
+	status := http.StatusCreated
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s", r.Method)
+		}
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	if err := v.PostToURL(srv.URL); err != nil {
+		t.Fatalf("PostToURL: %v", err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	var posted VCon
+	if err := json.Unmarshal(body, &posted); err != nil || posted.UUID != v.UUID {
+		t.Errorf("server received %s (%v)", body, err)
+	}
+
+	status = http.StatusInternalServerError
+	if err := v.PostToURL(srv.URL); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected status error, got %v", err)
+	}
+}
+
+func TestHTTPConfigCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(minimalVConJSON()))
+	}))
+	defer srv.Close()
+
+	if _, err := LoadFromURL(srv.URL); err == nil {
+		t.Fatal("expected untrusted test server certificate to be rejected")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, pemData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	useHTTPConfig(t, HTTPConfig{CAFile: caFile})
+	if _, err := LoadFromURL(srv.URL); err != nil {
+		t.Errorf("LoadFromURL with CA bundle: %v", err)
+	}
+}
+
+func TestHTTPConfigProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte(minimalVConJSON()))
+	}))
+	defer proxy.Close()
+
+	useHTTPConfig(t, HTTPConfig{Proxy: proxy.URL})
+	if _, err := LoadFromURL("http://vcons.example.invalid/v/1"); err != nil {
+		t.Fatalf("LoadFromURL via proxy: %v", err)
+	}
+	if proxied != "http://vcons.example.invalid/v/1" {
+		t.Errorf("proxy saw %q", proxied)
+	}
+}
+
+func TestHTTPConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  HTTPConfig
+	}{
+		{"bad proxy", HTTPConfig{Proxy: "://nope"}},
+		{"missing CA bundle", HTTPConfig{CAFile: "/nonexistent/ca.pem"}},
+		{"cert without key", HTTPConfig{ClientCertFile: "cert.pem"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetHTTPConfig(tt.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	client, err := HTTPConfig{Timeout: 3 * time.Second}.NewClient()
+	if err != nil || client.Timeout != 3*time.Second {
+		t.Errorf("NewClient timeout = %v, %v", client, err)
+	}
+}
+
+func TestNetworkCallsHonorContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	calls := map[string]func(ctx context.Context) error{
+		"LoadFromURLContext": func(ctx context.Context) error {
+			_, err := LoadFromURLContext(ctx, srv.URL)
+			return err
+		},
+		"PostToURLContext": func(ctx context.Context) error {
+			return New("example.com").PostToURLContext(ctx, srv.URL)
+		},
+		"Dialog.ToInlineDataContext": func(ctx context.Context) error {
+			return (&Dialog{URL: srv.URL + "/a.wav"}).ToInlineDataContext(ctx)
+		},
+		"Dialog.IsExternalDataChangedContext": func(ctx context.Context) error {
+			_, err := (&Dialog{URL: srv.URL + "/a.wav", ContentHash: ContentHashList{ComputeSHA512(nil)}}).IsExternalDataChangedContext(ctx)
+			return err
+		},
+		"Analysis.ToInlineDataContext": func(ctx context.Context) error {
+			return (&Analysis{URL: srv.URL + "/t.json"}).ToInlineDataContext(ctx)
+		},
+		"Attachment.ToInlineDataContext": func(ctx context.Context) error {
+			return (&Attachment{URL: srv.URL + "/n.txt"}).ToInlineDataContext(ctx)
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := call(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected the deadline to cancel the request, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("request was not cancelled promptly: %v", elapsed)
+			}
+		})
+	}
+}
+
+// countingTransport counts the requests it passes to http.DefaultTransport.
+type countingTransport struct{ n int }
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.n++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestHTTPClientOverrides(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			return
+		}
+		if r.URL.Path == "/audio" {
+			w.Header().Set("Content-Type", "audio/wav")
+			w.Write([]byte("RIFF"))
+			return
+		}
+		w.Write([]byte(minimalVConJSON()))
+	}))
+	defer srv.Close()
+
+	rt := &countingTransport{}
+	useHTTPConfig(t, HTTPConfig{Transport: rt, UserAgent: "acme-ingest/1.0"})
+	if _, err := LoadFromURL(srv.URL); err != nil || rt.n != 1 {
+		t.Fatalf("HTTPConfig.Transport not used: %d requests, %v", rt.n, err)
+	}
+	if err := SetHTTPConfig(HTTPConfig{Transport: rt, Proxy: srv.URL}); err == nil {
+		t.Error("expected proxy settings on a custom RoundTripper to be rejected")
+	}
+
+	client := &http.Client{Transport: &countingTransport{}}
+	if err := SetHTTPClient(client); err != nil {
+		t.Fatal(err)
+	}
+	if HTTPClient() != client {
+		t.Error("HTTPClient does not return the installed client")
+	}
+	if _, err := LoadFromURL(srv.URL); err != nil || client.Transport.(*countingTransport).n != 1 {
+		t.Fatalf("SetHTTPClient not used: %v", err)
+	}
+	if err := SetHTTPClient(nil); err != nil || HTTPClient() == client {
+		t.Errorf("SetHTTPClient(nil) did not restore the configured client: %v", err)
+	}
+
+	loadRT := &countingTransport{}
+	if _, err := LoadFromURLWithOptions(srv.URL, WithHTTPClient(&http.Client{Transport: loadRT})); err != nil || loadRT.n != 1 {
+		t.Errorf("WithHTTPClient not used: %d requests, %v", loadRT.n, err)
+	}
+
+	vconRT := &countingTransport{}
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.SetHTTPClient(&http.Client{Transport: vconRT})
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0), URL: srv.URL + "/audio"})
+	if err := v.InlineAllExternal(context.Background()); err != nil {
+		t.Fatalf("InlineAllExternal: %v", err)
+	}
+	if err := v.PostToURL(srv.URL); err != nil {
+		t.Fatalf("PostToURL: %v", err)
+	}
+	if vconRT.n != 2 {
+		t.Errorf("per-vCon client made %d requests, want 2", vconRT.n)
+	}
+}
+
+func TestPostToURLWithOptionsRetries(t *testing.T) {
+	var keys []string
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusAccepted}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		w.WriteHeader(statuses[min(len(keys), len(statuses))-1])
+	}))
+	defer srv.Close()
+
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	if err := v.PostToURLWithOptions(srv.URL, WithPostRetries(3, time.Millisecond)); err != nil {
+		t.Fatalf("PostToURLWithOptions: %v", err)
+	}
+	if len(keys) != 3 || keys[0] == "" || keys[1] != keys[0] || keys[2] != keys[0] {
+		t.Errorf("every attempt must carry the same idempotency key, got %q", keys)
+	}
+
+	keys, statuses = nil, []int{http.StatusBadRequest}
+	err := v.PostToURLWithOptions(srv.URL, WithPostRetries(3, time.Millisecond), WithIdempotencyKey(v.UUID))
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || !statusErr.ClientError() || statusErr.ServerError() {
+		t.Fatalf("expected a client StatusError, got %v", err)
+	}
+	if len(keys) != 1 || keys[0] != v.UUID {
+		t.Errorf("4xx must not be retried and must carry the given key, got %q", keys)
+	}
+
+	keys, statuses = nil, []int{http.StatusBadGateway}
+	err = v.PostToURLWithOptions(srv.URL, WithPostRetries(1, time.Millisecond))
+	if !errors.As(err, &statusErr) || !statusErr.ServerError() || len(keys) != 2 {
+		t.Errorf("expected a server StatusError after 2 attempts, got %v after %d", err, len(keys))
+	}
+}
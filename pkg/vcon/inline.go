@@ -0,0 +1,153 @@
+package vcon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultInlineConcurrency is the number of downloads InlineAllExternal runs
+// at once unless WithInlineConcurrency overrides it.
+const DefaultInlineConcurrency = 4
+
+// InlineItem identifies an external dialog, attachment or analysis.
+type InlineItem struct {
+	Kind  string // "dialog", "attachment" or "analysis"
+	Index int
+}
+
+func (i InlineItem) String() string {
+	return fmt.Sprintf("%s %d", i.Kind, i.Index)
+}
+
+// InlineProgressFunc is called by InlineAllExternal as each item finishes,
+// with err nil on success. done counts the items finished so far out of
+// total. Calls are serialized, so the function need not be safe for
+// concurrent use.
+type InlineProgressFunc func(item InlineItem, done, total int, err error)
+
+// InlineOption configures InlineAllExternal.
+type InlineOption func(*inlineConfig)
+
+type inlineConfig struct {
+	concurrency int
+	progress    InlineProgressFunc
+}
+
+// WithInlineConcurrency sets the maximum number of items downloaded at once.
+// Values below one are treated as one.
+func WithInlineConcurrency(n int) InlineOption {
+	return func(c *inlineConfig) {
+		c.concurrency = max(n, 1)
+	}
+}
+
+// WithInlineProgress reports each finished item to fn.
+func WithInlineProgress(fn InlineProgressFunc) InlineOption {
+	return func(c *inlineConfig) {
+		c.progress = fn
+	}
+}
+
+// InlineFailure is an item InlineAllExternal could not convert.
+type InlineFailure struct {
+	Item InlineItem
+	Err  error
+}
+
+// InlineError lists every item InlineAllExternal could not convert. Those
+// items are left external; all others were converted.
+type InlineError struct {
+	Failures []InlineFailure
+}
+
+func (e *InlineError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = fmt.Sprintf("%s: %v", f.Item, f.Err)
+	}
+	return fmt.Sprintf("failed to inline %d items: %s", len(e.Failures), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the error of each failure, so errors.Is and errors.As see
+// them, e.g. context.Canceled.
+func (e *InlineError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// InlineAllExternal converts every external dialog, attachment and analysis
+// to inline data as their ToInlineData methods do, downloading several at
+// once. A failed item does not stop the others; the failures are returned
+// together as an *InlineError, in document order. Items not yet started when
+// ctx is cancelled fail with its error.
+func (v *VCon) InlineAllExternal(ctx context.Context, opts ...InlineOption) error {
+	cfg := inlineConfig{concurrency: DefaultInlineConcurrency}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	type job struct {
+		item    InlineItem
+		fetcher ContentFetcher
+		run     func(context.Context, ContentFetcher) error
+	}
+	var jobs []job
+	for i := range v.Dialog {
+		if d := &v.Dialog[i]; d.IsExternalData() {
+			jobs = append(jobs, job{InlineItem{"dialog", i}, pickFetcher(d.fetcher, v.contentFetcher()), d.toInlineData})
+		}
+	}
+	for i := range v.Attachments {
+		if a := &v.Attachments[i]; a.URL != "" {
+			jobs = append(jobs, job{InlineItem{"attachment", i}, pickFetcher(v.contentFetcher()), a.toInlineData})
+		}
+	}
+	for i := range v.Analysis {
+		if a := &v.Analysis[i]; a.IsExternalData() {
+			jobs = append(jobs, job{InlineItem{"analysis", i}, pickFetcher(a.fetcher, v.contentFetcher()), a.toInlineData})
+		}
+	}
+
+	errs := make([]error, len(jobs))
+	var (
+		mu   sync.Mutex
+		done int
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, cfg.concurrency)
+	for n, j := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			err := ctx.Err()
+			if err == nil {
+				err = j.run(ctx, j.fetcher)
+			}
+			errs[n] = err
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if cfg.progress != nil {
+				cfg.progress(j.item, done, len(jobs), err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var failures []InlineFailure
+	for n, err := range errs {
+		if err != nil {
+			failures = append(failures, InlineFailure{jobs[n].item, err})
+		}
+	}
+	if failures != nil {
+		return &InlineError{Failures: failures}
+	}
+	return nil
+}
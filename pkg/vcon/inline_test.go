@@ -0,0 +1,94 @@
+package vcon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineAllExternal(t *testing.T) {
+	var active, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+		switch r.URL.Path {
+		case "/missing.wav":
+			http.NotFound(w, r)
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("meeting notes"))
+		case "/summary.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"summary": "ok"}`))
+		default:
+			w.Header().Set("Content-Type", "audio/wav")
+			w.Write([]byte("audio:" + r.URL.Path))
+		}
+	}))
+	defer srv.Close()
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	v.AddParty(Party{})
+	for _, name := range []string{"a", "b", "c", "d", "e", "missing"} {
+		v.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0), URL: srv.URL + "/" + name + ".wav"})
+	}
+	v.AddDialog(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0), Body: "hi", Encoding: "none"})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(0), StartTime: start, URL: srv.URL + "/notes.txt"})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: 0, URL: srv.URL + "/summary.json"})
+
+	var mu sync.Mutex
+	var reported []string
+	err := v.InlineAllExternal(context.Background(), WithInlineConcurrency(2),
+		WithInlineProgress(func(item InlineItem, done, total int, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, 8, total)
+			assert.Equal(t, len(reported)+1, done)
+			reported = append(reported, item.String())
+		}))
+
+	var inlineErr *InlineError
+	require.ErrorAs(t, err, &inlineErr)
+	require.Len(t, inlineErr.Failures, 1)
+	assert.Equal(t, InlineItem{"dialog", 5}, inlineErr.Failures[0].Item)
+	assert.ErrorContains(t, err, "dialog 5: failed to fetch external data: HTTP request failed with status code: 404")
+	assert.Len(t, reported, 8)
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+
+	for i, d := range v.Dialog[:5] {
+		assert.Empty(t, d.URL, i)
+		assert.Equal(t, "base64url", d.Encoding)
+	}
+	assert.NotEmpty(t, v.Dialog[5].URL, "failed items stay external")
+	assert.Equal(t, "meeting notes", v.Attachments[0].Body)
+	assert.Equal(t, "none", v.Attachments[0].Encoding)
+	assert.Equal(t, "notes.txt", v.Attachments[0].Filename)
+	assert.Equal(t, `{"summary": "ok"}`, v.Analysis[0].Body)
+	assert.Equal(t, "json", v.Analysis[0].Encoding)
+}
+
+func TestInlineAllExternalCancelled(t *testing.T) {
+	v := New("example.com")
+	v.AddDialog(Dialog{Type: "recording", URL: "https://media.example.com/a.wav"})
+	v.AddDialog(Dialog{Type: "recording", URL: "https://media.example.com/b.wav"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := v.InlineAllExternal(ctx)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.NotEmpty(t, v.Dialog[0].URL)
+
+	assert.NoError(t, New("example.com").InlineAllExternal(context.Background()))
+}
@@ -0,0 +1,116 @@
+package vcon
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SensitivityKey is the non-standard dialog and attachment property holding
+// its sensitivity label.
+const SensitivityKey = "sensitivity"
+
+// Sensitivity is an access control label, ordered from public to
+// restricted.
+type Sensitivity string
+
+const (
+	SensitivityPublic     Sensitivity = "public"
+	SensitivityInternal   Sensitivity = "internal"
+	SensitivityRestricted Sensitivity = "restricted"
+)
+
+// Sensitivities lists the labels from least to most sensitive.
+var Sensitivities = []Sensitivity{SensitivityPublic, SensitivityInternal, SensitivityRestricted}
+
+// DefaultSensitivity is the label FilterByLabel assumes for unlabelled
+// dialogs and attachments.
+const DefaultSensitivity = SensitivityInternal
+
+// ParseSensitivity validates s as a sensitivity label.
+func ParseSensitivity(s string) (Sensitivity, error) {
+	if slices.Contains(Sensitivities, Sensitivity(s)) {
+		return Sensitivity(s), nil
+	}
+	return "", fmt.Errorf("invalid sensitivity %q (want public, internal or restricted)", s)
+}
+
+// allows reports whether a caller cleared for max may see an item labelled
+// l. Unlabelled items are DefaultSensitivity; unknown labels are never
+// allowed.
+func (max Sensitivity) allows(l Sensitivity) bool {
+	if l == "" {
+		l = DefaultSensitivity
+	}
+	rank := slices.Index(Sensitivities, l)
+	return rank >= 0 && rank <= slices.Index(Sensitivities, max)
+}
+
+// Sensitivity returns the dialog's label, or "" when it has none.
+func (d *Dialog) Sensitivity() Sensitivity {
+	var s string
+	if ok, err := d.Extra.Get(SensitivityKey, &s); !ok || err != nil {
+		return ""
+	}
+	return Sensitivity(s)
+}
+
+// SetSensitivity labels the dialog.
+func (d *Dialog) SetSensitivity(s Sensitivity) error {
+	if _, err := ParseSensitivity(string(s)); err != nil {
+		return err
+	}
+	return d.Extra.Set(SensitivityKey, s)
+}
+
+// Sensitivity returns the attachment's label, or "" when it has none.
+func (a *Attachment) Sensitivity() Sensitivity {
+	var s string
+	if ok, err := a.Extra.Get(SensitivityKey, &s); !ok || err != nil {
+		return ""
+	}
+	return Sensitivity(s)
+}
+
+// SetSensitivity labels the attachment.
+func (a *Attachment) SetSensitivity(s Sensitivity) error {
+	if _, err := ParseSensitivity(string(s)); err != nil {
+		return err
+	}
+	return a.Extra.Set(SensitivityKey, s)
+}
+
+// FilterByLabel returns a redacted copy of the vCon for a caller cleared for
+// maxLevel. Dialogs and attachments labelled above it are removed, along
+// with the analysis and attachments of the removed dialogs; remaining
+// references are renumbered as RemoveDialog does. Unlabelled items count as
+// DefaultSensitivity and items with an unknown label are always removed.
+// The copy's redacted type is "sensitivity:<maxLevel>".
+func (v *VCon) FilterByLabel(maxLevel Sensitivity) (*VCon, error) {
+	if _, err := ParseSensitivity(string(maxLevel)); err != nil {
+		return nil, err
+	}
+	return v.Redact("sensitivity:"+string(maxLevel), func(c *VCon) error {
+		var drop []int
+		for i := range c.Dialog {
+			if !maxLevel.allows(c.Dialog[i].Sensitivity()) {
+				drop = append(drop, i)
+			}
+		}
+		// Drop attachments first, so removing dialogs moves no content from
+		// them into the attachments kept.
+		attachments := c.Attachments[:0]
+		for _, a := range c.Attachments {
+			if !maxLevel.allows(a.Sensitivity()) || (!a.isTags() && a.DialogIdx != nil && slices.Contains(drop, *a.DialogIdx)) {
+				continue
+			}
+			attachments = append(attachments, a)
+		}
+		c.Attachments = attachments
+		for _, i := range slices.Backward(drop) {
+			if err := c.RemoveDialog(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
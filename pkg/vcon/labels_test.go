@@ -0,0 +1,72 @@
+package vcon
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterByLabel(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	for _, label := range []Sensitivity{SensitivityPublic, SensitivityRestricted, "", SensitivityInternal} {
+		d := Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0), Body: "said " + string(label), Encoding: "none"}
+		if label != "" {
+			require.NoError(t, d.SetSensitivity(label))
+		}
+		v.AddDialog(d)
+	}
+	v.Dialog[0].Original = NewIntValue(1)
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: 1, Body: "restricted summary", Encoding: "none"})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: 3, Body: "internal summary", Encoding: "none"})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(1), StartTime: start, Body: "from restricted dialog", Encoding: "none"})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(3), StartTime: start, Body: "internal notes", Encoding: "none"})
+	restricted := Attachment{DialogIdx: IntPtr(0), StartTime: start, Body: "restricted notes", Encoding: "none"}
+	require.NoError(t, restricted.SetSensitivity(SensitivityRestricted))
+	v.AddAttachment(restricted)
+
+	public, err := v.FilterByLabel(SensitivityPublic)
+	require.NoError(t, err)
+	require.Len(t, public.Dialog, 1)
+	assert.Equal(t, "said public", public.Dialog[0].Body)
+	assert.Nil(t, public.Dialog[0].Original, "reference to a removed dialog must be dropped")
+	assert.Empty(t, public.Analysis)
+	assert.Empty(t, public.Attachments)
+	assert.Equal(t, "sensitivity:public", public.Redacted.Type)
+	assert.Equal(t, v.UUID, public.Redacted.UUID)
+
+	internal, err := v.FilterByLabel(SensitivityInternal)
+	require.NoError(t, err)
+	require.Len(t, internal.Dialog, 3)
+	assert.Equal(t, []string{"said public", "said ", "said internal"},
+		[]string{internal.Dialog[0].Body, internal.Dialog[1].Body, internal.Dialog[2].Body})
+	require.Len(t, internal.Analysis, 1)
+	assert.Equal(t, 2, internal.Analysis[0].Dialog, "analysis must follow its renumbered dialog")
+	require.Len(t, internal.Attachments, 1)
+	assert.Equal(t, "internal notes", internal.Attachments[0].Body)
+	assert.Equal(t, 2, *internal.Attachments[0].DialogIdx)
+
+	all, err := v.FilterByLabel(SensitivityRestricted)
+	require.NoError(t, err)
+	assert.Len(t, all.Dialog, 4)
+	assert.Len(t, all.Attachments, 3)
+	assert.Len(t, v.Dialog, 4, "the original must not change")
+
+	// Labels survive serialization, and unknown labels are never served.
+	var decoded VCon
+	require.NoError(t, json.Unmarshal([]byte(v.ToJSON()), &decoded))
+	assert.Equal(t, SensitivityRestricted, decoded.Dialog[1].Sensitivity())
+	require.NoError(t, decoded.Dialog[0].Extra.Set(SensitivityKey, "secret"))
+	filtered, err := decoded.FilterByLabel(SensitivityRestricted)
+	require.NoError(t, err)
+	assert.Len(t, filtered.Dialog, 3)
+
+	_, err = v.FilterByLabel("top-secret")
+	assert.Error(t, err)
+	var d Dialog
+	assert.Error(t, d.SetSensitivity("secret"))
+}
@@ -0,0 +1,272 @@
+package vcon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// LoadOption configures LoadFromURLWithOptions.
+type LoadOption func(*loadConfig)
+
+type loadConfig struct {
+	propertyHandling string
+	bearerToken      string
+	basicUser        string
+	basicPassword    string
+	header           http.Header
+	query            url.Values
+	signer           func(*http.Request) error
+	retries          int
+	backoff          time.Duration
+	maxBackoff       time.Duration
+	maxBytes         int64
+	client           *http.Client
+}
+
+// Defaults used by WithRetries when no backoff is given.
+const (
+	DefaultRetryBackoff    = 500 * time.Millisecond
+	DefaultMaxRetryBackoff = 30 * time.Second
+)
+
+// WithPropertyHandling sets how non-standard properties are handled, as in
+// BuildFromJSON.
+func WithPropertyHandling(mode string) LoadOption {
+	return func(c *loadConfig) {
+		c.propertyHandling = mode
+	}
+}
+
+// WithBearerToken sends "Authorization: Bearer <token>".
+func WithBearerToken(token string) LoadOption {
+	return func(c *loadConfig) {
+		c.bearerToken = token
+	}
+}
+
+// WithBasicAuth sends HTTP basic authentication credentials.
+func WithBasicAuth(user, password string) LoadOption {
+	return func(c *loadConfig) {
+		c.basicUser, c.basicPassword = user, password
+	}
+}
+
+// WithHeader adds a request header, e.g. an API key header. It may be given
+// several times.
+func WithHeader(name, value string) LoadOption {
+	return func(c *loadConfig) {
+		if c.header == nil {
+			c.header = http.Header{}
+		}
+		c.header.Add(name, value)
+	}
+}
+
+// WithQueryParams adds params to the query of the URL, e.g. an api_key.
+func WithQueryParams(params url.Values) LoadOption {
+	return func(c *loadConfig) {
+		if c.query == nil {
+			c.query = url.Values{}
+		}
+		for k, vs := range params {
+			c.query[k] = append(c.query[k], vs...)
+		}
+	}
+}
+
+// WithRequestSigner calls sign on every attempt once the request is
+// complete, so it can add a signature over the method, URL and headers,
+// e.g. a signed query string or an HMAC Authorization header.
+func WithRequestSigner(sign func(req *http.Request) error) LoadOption {
+	return func(c *loadConfig) {
+		c.signer = sign
+	}
+}
+
+// WithRetries retries network errors, 429 and 5xx responses up to n more
+// times. The delay starts at backoff (DefaultRetryBackoff when zero), doubles
+// after every attempt up to DefaultMaxRetryBackoff, and honors Retry-After.
+func WithRetries(n int, backoff time.Duration) LoadOption {
+	return func(c *loadConfig) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+// WithMaxResponseBytes rejects responses larger than n bytes.
+func WithMaxResponseBytes(n int64) LoadOption {
+	return func(c *loadConfig) {
+		c.maxBytes = n
+	}
+}
+
+// WithHTTPClient sends the request with client instead of the package client
+// installed by SetHTTPConfig or SetHTTPClient.
+func WithHTTPClient(client *http.Client) LoadOption {
+	return func(c *loadConfig) {
+		c.client = client
+	}
+}
+
+// ErrResponseTooLarge is returned when a response exceeds the limit set with
+// WithMaxResponseBytes.
+var ErrResponseTooLarge = errors.New("response exceeds size limit")
+
+// LoadFromURLWithOptions loads a VCon from a URL, with authentication,
+// headers, query parameters, request signing, retries and a response size
+// limit configured by opts.
+func LoadFromURLWithOptions(url string, opts ...LoadOption) (*VCon, error) {
+	return LoadFromURLWithOptionsContext(context.Background(), url, opts...)
+}
+
+// LoadFromURLWithOptionsContext is LoadFromURLWithOptions with a context
+// bounding the request and the waits between retries.
+func LoadFromURLWithOptionsContext(ctx context.Context, url string, opts ...LoadOption) (*VCon, error) {
+	cfg := loadConfig{
+		propertyHandling: PropertyHandlingDefault,
+		maxBackoff:       DefaultMaxRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.backoff <= 0 {
+		cfg.backoff = DefaultRetryBackoff
+	}
+
+	data, err := cfg.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return BuildFromJSON(string(data), cfg.propertyHandling)
+}
+
+// fetch performs the GET, retrying transient failures.
+func (c *loadConfig) fetch(ctx context.Context, url string) ([]byte, error) {
+	var data []byte
+	err := retry(ctx, c.retries, c.backoff, c.maxBackoff, func() (time.Duration, error) {
+		var after time.Duration
+		var err error
+		data, after, err = c.fetchOnce(ctx, url)
+		return after, err
+	})
+	return data, err
+}
+
+func (c *loadConfig) fetchOnce(ctx context.Context, url string) ([]byte, time.Duration, error) {
+	req, err := NewHTTPRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid URL format: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, vs := range c.header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if len(c.query) > 0 {
+		q := req.URL.Query()
+		for k, vs := range c.query {
+			q[k] = append(q[k], vs...)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	switch {
+	case c.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	case c.basicUser != "":
+		req.SetBasicAuth(c.basicUser, c.basicPassword)
+	}
+	if c.signer != nil {
+		if err := c.signer(req); err != nil {
+			return nil, 0, fmt.Errorf("failed to sign request: %w", err)
+		}
+	}
+
+	client := c.client
+	if client == nil {
+		client = HTTPClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, &retryableError{fmt.Errorf("failed to fetch URL: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := &StatusError{StatusCode: resp.StatusCode}
+		if resp.StatusCode == http.StatusTooManyRequests || err.ServerError() {
+			return nil, retryAfter(resp), &retryableError{err}
+		}
+		return nil, 0, err
+	}
+
+	if c.maxBytes > 0 && resp.ContentLength > c.maxBytes {
+		return nil, 0, fmt.Errorf("%w: %d > %d bytes", ErrResponseTooLarge, resp.ContentLength, c.maxBytes)
+	}
+	body := io.Reader(resp.Body)
+	if c.maxBytes > 0 {
+		body = io.LimitReader(resp.Body, c.maxBytes+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if c.maxBytes > 0 && int64(len(data)) > c.maxBytes {
+		return nil, 0, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, c.maxBytes)
+	}
+	return data, 0, nil
+}
+
+// retry calls attempt until it succeeds, fails with an error that is not
+// retryable, or has been retried retries times. The delay starts at backoff
+// and doubles up to maxBackoff; a positive duration returned by attempt, from
+// a Retry-After header, replaces it for the next wait. LoadFromURLWithOptions,
+// PostToURLWithOptions and HTTPFetcher all retry this way.
+func retry(ctx context.Context, retries int, backoff, maxBackoff time.Duration, attempt func() (time.Duration, error)) error {
+	delay := backoff
+	for n := 0; ; n++ {
+		after, err := attempt()
+		if err == nil {
+			return nil
+		}
+		if n >= retries || !isRetryable(err) {
+			return err
+		}
+
+		wait := delay
+		if after > 0 {
+			wait = after
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, maxBackoff)
+	}
+}
+
+// retryableError marks failures worth another attempt.
+type retryableError struct{ err error }
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func isRetryable(err error) bool {
+	var r *retryableError
+	return errors.As(err, &r) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(resp *http.Response) time.Duration {
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package vcon
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadFromURLWithOptionsAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, basic := r.BasicAuth()
+		switch {
+		case r.Header.Get("Authorization") == "Bearer s3cret":
+		case basic && user == "api" && pass == "pw":
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(minimalVConJSON()))
+	}))
+	defer srv.Close()
+
+	if _, err := LoadFromURLWithOptions(srv.URL); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected 401 without credentials, got %v", err)
+	}
+	if _, err := LoadFromURLWithOptions(srv.URL, WithBearerToken("s3cret")); err != nil {
+		t.Errorf("bearer token: %v", err)
+	}
+	if _, err := LoadFromURLWithOptions(srv.URL, WithBasicAuth("api", "pw")); err != nil {
+		t.Errorf("basic auth: %v", err)
+	}
+}
+
+func TestLoadFromURLWithOptionsHeadersAndSigning(t *testing.T) {
+	var signatures []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.Header.Get("X-Conserver-Api-Token") != "tok" || q.Get("tenant") != "acme" || q.Get("format") != "json" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		want := "GET " + r.URL.Path + "?format=json&tenant=acme"
+		if q.Get("sig") != want {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		signatures = append(signatures, q.Get("sig"))
+		w.Write([]byte(minimalVConJSON()))
+	}))
+	defer srv.Close()
+
+	sign := func(req *http.Request) error {
+		q := req.URL.Query()
+		q.Set("sig", req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery)
+		req.URL.RawQuery = q.Encode()
+		return nil
+	}
+	_, err := LoadFromURLWithOptions(srv.URL+"/vcon/018f0000-0000-8000-8000-000000000000?format=json",
+		WithHeader("X-Conserver-Api-Token", "tok"),
+		WithQueryParams(url.Values{"tenant": {"acme"}}),
+		WithRequestSigner(sign))
+	if err != nil {
+		t.Fatalf("signed request: %v", err)
+	}
+	if len(signatures) != 1 {
+		t.Errorf("expected one signed request, got %v", signatures)
+	}
+
+	_, err = LoadFromURLWithOptions(srv.URL, WithRequestSigner(func(*http.Request) error { return errors.New("no key") }))
+	if err == nil || !strings.Contains(err.Error(), "failed to sign request") {
+		t.Errorf("expected the signer error, got %v", err)
+	}
+}
+
+func TestLoadFromURLWithOptionsRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write([]byte(minimalVConJSON()))
+		}
+	}))
+	defer srv.Close()
+
+	if _, err := LoadFromURLWithOptions(srv.URL, WithRetries(1, time.Millisecond)); err == nil {
+		t.Error("expected failure after exhausting one retry")
+	}
+
+	calls.Store(0)
+	v, err := LoadFromURLWithOptions(srv.URL, WithRetries(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("expected success after retries: %v", err)
+	}
+	if v.UUID == "" || calls.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls.Load())
+	}
+}
+
+func TestLoadFromURLWithOptionsDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	if _, err := LoadFromURLWithOptions(srv.URL, WithRetries(3, time.Millisecond)); err == nil {
+		t.Error("expected 404 error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("4xx should not be retried, got %d attempts", calls.Load())
+	}
+}
+
+func TestLoadFromURLWithOptionsSizeLimit(t *testing.T) {
+	chunked := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if chunked {
+			w.(http.Flusher).Flush() // no Content-Length
+		}
+		w.Write([]byte(minimalVConJSON()))
+	}))
+	defer srv.Close()
+
+	limit := int64(len(minimalVConJSON()))
+	if _, err := LoadFromURLWithOptions(srv.URL, WithMaxResponseBytes(limit)); err != nil {
+		t.Errorf("response at the limit should load: %v", err)
+	}
+	for _, c := range []bool{false, true} {
+		chunked = c
+		_, err := LoadFromURLWithOptions(srv.URL, WithMaxResponseBytes(limit-1))
+		if !errors.Is(err, ErrResponseTooLarge) {
+			t.Errorf("chunked=%v: expected ErrResponseTooLarge, got %v", c, err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// Package mediatype detects, normalizes and checks the media types of
+// dialog, attachment and analysis content.
+//
+// Producers spell the same type several ways (audio/x-wav, audio/wave and
+// audio/wav), so types are normalized to one canonical form before they
+// are compared or stored.
+package mediatype
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// OctetStream is the type of content that could not be identified.
+const OctetStream = "application/octet-stream"
+
+// aliases maps non-standard spellings to their canonical media type.
+var aliases = map[string]string{
+	"audio/x-wav":        "audio/wav",
+	"audio/wave":         "audio/wav",
+	"audio/vnd.wave":     "audio/wav",
+	"audio/mp3":          "audio/mpeg",
+	"audio/x-mp3":        "audio/mpeg",
+	"audio/mpeg3":        "audio/mpeg",
+	"audio/x-mpeg":       "audio/mpeg",
+	"audio/x-m4a":        "audio/mp4",
+	"audio/m4a":          "audio/mp4",
+	"audio/x-aac":        "audio/aac",
+	"audio/x-flac":       "audio/flac",
+	"audio/x-ogg":        "audio/ogg",
+	"video/x-mp4":        "video/mp4",
+	"video/x-webm":       "video/webm",
+	"application/x-pdf":  "application/pdf",
+	"text/x-json":        "application/json",
+	"application/x-json": "application/json",
+	"text/json":          "application/json",
+}
+
+// extensions maps file extensions to media types. It takes precedence over
+// the system MIME table, which varies between machines.
+var extensions = map[string]string{
+	".txt":  "text/plain",
+	".vtt":  "text/vtt",
+	".srt":  "application/x-subrip",
+	".html": "text/html",
+	".htm":  "text/html",
+	".csv":  "text/csv",
+	".json": "application/json",
+	".pdf":  "application/pdf",
+	".eml":  "message/rfc822",
+	".wav":  "audio/wav",
+	".mp3":  "audio/mpeg",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/ogg",
+	".weba": "audio/webm",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".flac": "audio/flac",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".ogv":  "video/ogg",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+}
+
+// preferredExtensions picks the extension of types listed under several
+// in extensions.
+var preferredExtensions = map[string]string{
+	"audio/ogg":  ".ogg",
+	"video/mp4":  ".mp4",
+	"text/html":  ".html",
+	"image/jpeg": ".jpg",
+}
+
+// Normalize returns t in canonical form: lower case, aliases replaced by
+// the registered type, and parameters such as charset kept. Values that do
+// not parse are returned trimmed but otherwise unchanged.
+func Normalize(t string) string {
+	base, params, err := mime.ParseMediaType(t)
+	if err != nil {
+		return strings.TrimSpace(t)
+	}
+	if canonical, ok := aliases[base]; ok {
+		base = canonical
+	}
+	if len(params) == 0 {
+		return base
+	}
+	return mime.FormatMediaType(base, params)
+}
+
+// Base returns the normalized type of t without its parameters.
+func Base(t string) string {
+	base, _, _ := strings.Cut(Normalize(t), ";")
+	return strings.TrimSpace(base)
+}
+
+// Equal reports whether a and b name the same type, ignoring parameters.
+func Equal(a, b string) bool {
+	return Base(a) == Base(b)
+}
+
+// FromExtension returns the media type of a file name or URL path from its
+// extension, or "" when the extension is unknown.
+func FromExtension(name string) string {
+	if i := strings.IndexAny(name, "?#"); i >= 0 && strings.Contains(name, "://") {
+		name = name[:i]
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return ""
+	}
+	if t, ok := extensions[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return Normalize(t)
+	}
+	return ""
+}
+
+// Extension returns the usual file extension of media type t, with its
+// leading dot, or "" when there is none.
+func Extension(t string) string {
+	base := Base(t)
+	if ext, ok := preferredExtensions[base]; ok {
+		return ext
+	}
+	var found []string
+	for ext, typ := range extensions {
+		if typ == base {
+			found = append(found, ext)
+		}
+	}
+	if len(found) == 0 {
+		found, _ = mime.ExtensionsByType(base)
+	}
+	if len(found) == 0 {
+		return ""
+	}
+	slices.Sort(found)
+	return found[0]
+}
+
+// Sniff identifies content from its first bytes, or returns "" when it
+// cannot. Audio and video containers are recognized on top of the formats
+// known to http.DetectContentType.
+func Sniff(content []byte) string {
+	switch {
+	case len(content) == 0:
+		return ""
+	case len(content) >= 12 && bytes.HasPrefix(content, []byte("RIFF")) && string(content[8:12]) == "WAVE":
+		return "audio/wav"
+	case bytes.HasPrefix(content, []byte("ID3")),
+		len(content) >= 2 && content[0] == 0xFF && content[1]&0xE6 == 0xE2:
+		return "audio/mpeg"
+	case bytes.HasPrefix(content, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(content, []byte("OggS")):
+		return "audio/ogg"
+	case bytes.HasPrefix(content, []byte("\x1A\x45\xDF\xA3")):
+		return "video/webm"
+	case len(content) >= 12 && string(content[4:8]) == "ftyp":
+		switch string(content[8:11]) {
+		case "M4A", "M4B":
+			return "audio/mp4"
+		case "qt ":
+			return "video/quicktime"
+		}
+		return "video/mp4"
+	case len(content) >= 2 && content[0] == 0xFF && content[1]&0xF6 == 0xF0:
+		return "audio/aac"
+	}
+	if t := http.DetectContentType(content); t != OctetStream {
+		return Normalize(t)
+	}
+	return ""
+}
+
+// Detect returns the media type of content named name. Sniffed binary
+// formats win; for text, whose flavour cannot be told from the bytes, and
+// for unrecognized content the extension decides. OctetStream is returned
+// when neither identifies it.
+func Detect(name string, content []byte) string {
+	sniffed := Sniff(content)
+	byExt := FromExtension(name)
+	switch {
+	case sniffed == "":
+		if byExt != "" {
+			return byExt
+		}
+		return OctetStream
+	case strings.HasPrefix(sniffed, "text/plain") && byExt != "":
+		return byExt
+	}
+	return sniffed
+}
+
+// Allowlist is a set of accepted media types. Entries are normalized, so
+// listing audio/wav also accepts audio/x-wav, and "type/*" accepts every
+// subtype. Parameters are ignored when checking.
+type Allowlist struct {
+	types map[string]struct{}
+}
+
+// NewAllowlist returns an allowlist of the given types.
+func NewAllowlist(types ...string) *Allowlist {
+	a := &Allowlist{types: map[string]struct{}{}}
+	a.Add(types...)
+	return a
+}
+
+// Add accepts more types.
+func (a *Allowlist) Add(types ...string) {
+	for _, t := range types {
+		a.types[Base(t)] = struct{}{}
+	}
+}
+
+// Allows reports whether t is accepted.
+func (a *Allowlist) Allows(t string) bool {
+	base := Base(t)
+	if _, ok := a.types[base]; ok {
+		return true
+	}
+	major, _, _ := strings.Cut(base, "/")
+	_, ok := a.types[major+"/*"]
+	return ok
+}
+
+// Len returns the number of entries.
+func (a *Allowlist) Len() int {
+	return len(a.types)
+}
@@ -0,0 +1,103 @@
+package mediatype
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"audio/x-wav":                "audio/wav",
+		"Audio/Wave":                 "audio/wav",
+		"audio/mp3":                  "audio/mpeg",
+		"audio/x-m4a":                "audio/mp4",
+		"video/x-mp4":                "video/mp4",
+		"text/plain; charset=UTF-8":  "text/plain; charset=UTF-8",
+		"TEXT/PLAIN;charset=utf-8":   "text/plain; charset=utf-8",
+		"application/pdf":            "application/pdf",
+		" wav ":                      "wav",
+		"":                           "",
+		"application/vnd.acme+json ": "application/vnd.acme+json",
+	}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if !Equal("audio/x-wav", "audio/wav; rate=8000") {
+		t.Error("expected aliases with parameters to be equal")
+	}
+}
+
+func TestFromExtension(t *testing.T) {
+	tests := map[string]string{
+		"call.WAV":                         "audio/wav",
+		"meeting.m4a":                      "audio/mp4",
+		"transcript.vtt":                   "text/vtt",
+		"notes.pdf":                        "application/pdf",
+		"https://example.com/a.mp3?sig=x":  "audio/mpeg",
+		"README":                           "",
+		"archive.unknown-extension-really": "",
+	}
+	for in, want := range tests {
+		if got := FromExtension(in); got != want {
+			t.Errorf("FromExtension(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExtension(t *testing.T) {
+	tests := map[string]string{
+		"audio/x-wav":               ".wav",
+		"audio/ogg":                 ".ogg",
+		"video/mp4":                 ".mp4",
+		"text/plain; charset=utf-8": ".txt",
+		"application/vnd.acme+json": "",
+	}
+	for in, want := range tests {
+		if got := Extension(in); got != want {
+			t.Errorf("Extension(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"call.bin", "RIFF\x24\x00\x00\x00WAVEfmt ", "audio/wav"},
+		{"", "ID3\x03\x00", "audio/mpeg"},
+		{"", "fLaC\x00\x00", "audio/flac"},
+		{"", "OggS\x00\x02", "audio/ogg"},
+		{"", "\x00\x00\x00\x20ftypM4A \x00", "audio/mp4"},
+		{"", "\x00\x00\x00\x20ftypisom\x00", "video/mp4"},
+		{"", "%PDF-1.7\n", "application/pdf"},
+		{"transcript.vtt", "WEBVTT\n\n00:00.000 --> 00:01.000\nhi", "text/vtt"},
+		{"notes", "plain words", "text/plain; charset=utf-8"},
+		{"call.wav", "", "audio/wav"},
+		{"blob", "\x00\x01\x02\x03", OctetStream},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.name, []byte(tt.content)); got != tt.want {
+			t.Errorf("Detect(%q, %q) = %q, want %q", tt.name, tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	a := NewAllowlist("audio/wav", "text/plain")
+	for _, allowed := range []string{"audio/wav", "audio/x-wav", "AUDIO/WAVE", "text/plain; charset=utf-8"} {
+		if !a.Allows(allowed) {
+			t.Errorf("expected %q to be allowed", allowed)
+		}
+	}
+	if a.Allows("application/pdf") {
+		t.Error("expected application/pdf to be rejected")
+	}
+	a.Add("application/pdf", "image/*")
+	if !a.Allows("application/x-pdf") || !a.Allows("image/png") || a.Allows("video/mp4") {
+		t.Error("unexpected result after Add")
+	}
+	if a.Len() != 4 {
+		t.Errorf("expected 4 entries, got %d", a.Len())
+	}
+}
@@ -0,0 +1,194 @@
+package vcon
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// MergeOption configures Merge.
+type MergeOption func(*mergeConfig)
+
+type mergeConfig struct {
+	sameParty func(a, b Party) bool
+}
+
+// WithPartyMatcher sets how Merge recognises the same party in both vCons,
+// e.g. by telephone number. By default only identical parties are merged.
+func WithPartyMatcher(match func(a, b Party) bool) MergeOption {
+	return func(c *mergeConfig) {
+		c.sameParty = match
+	}
+}
+
+// Merge combines the parties, dialogs, analysis and attachments of a and b
+// into a new vCon. b's entries follow a's, with their party and dialog
+// indices remapped; parties of b matching a party of a are merged into it.
+// The result keeps a's subject (or b's when a has none), the earlier
+// created_at, the union of both extension lists and one tags attachment with
+// a's tags and those of b's that a does not set. Redacted, amended and
+// group are not carried over.
+func Merge(a, b *VCon, opts ...MergeOption) (*VCon, error) {
+	cfg := mergeConfig{
+		sameParty: func(x, y Party) bool { return reflect.DeepEqual(x, y) },
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var merged, other VCon
+	if err := deepCopyVCon(a, &merged); err != nil {
+		return nil, err
+	}
+	if err := deepCopyVCon(b, &other); err != nil {
+		return nil, err
+	}
+
+	merged.UUID = UUID8DomainName("merged." + a.UUID)
+	merged.Redacted, merged.Amended, merged.Group = nil, nil, nil
+	if merged.Subject == "" {
+		merged.Subject = other.Subject
+	}
+	if other.CreatedAt.Before(merged.CreatedAt) {
+		merged.CreatedAt = other.CreatedAt
+	}
+	merged.Extensions = unionStrings(merged.Extensions, other.Extensions)
+	merged.Critical = unionStrings(merged.Critical, other.Critical)
+
+	partyMap := make([]int, len(other.Parties))
+	for i, p := range other.Parties {
+		partyMap[i] = slices.IndexFunc(merged.Parties, func(q Party) bool { return cfg.sameParty(q, p) })
+		if partyMap[i] < 0 {
+			partyMap[i] = merged.AddParty(p)
+		}
+	}
+	dialogOffset := len(merged.Dialog)
+	dialogMap := func(i int) int { return i + dialogOffset }
+	mapParty := func(i int) int {
+		if i >= 0 && i < len(partyMap) {
+			return partyMap[i]
+		}
+		return i
+	}
+
+	for _, d := range other.Dialog {
+		d.Parties = d.Parties.Remap(mapParty)
+		d.Originator = remapIndex(d.Originator, mapParty)
+		d.Transferee = remapIndex(d.Transferee, mapParty)
+		d.Transferor = remapIndex(d.Transferor, mapParty)
+		d.TransferTarget = remapIntOrSlice(d.TransferTarget, mapParty)
+		for j := range d.PartyHistory {
+			d.PartyHistory[j].Party = mapParty(d.PartyHistory[j].Party)
+		}
+		d.Original = remapIntOrSlice(d.Original, dialogMap)
+		d.Consultation = remapIntOrSlice(d.Consultation, dialogMap)
+		d.TargetDialog = remapIntOrSlice(d.TargetDialog, dialogMap)
+		merged.AddDialog(d)
+	}
+	for _, an := range other.Analysis {
+		an.Dialog = remapIndices(an.Dialog, dialogMap)
+		merged.AddAnalysis(an)
+	}
+	for _, att := range other.Attachments {
+		if att.isTags() {
+			continue
+		}
+		att.DialogIdx = remapIndex(att.DialogIdx, dialogMap)
+		att.PartyIdx = mapParty(att.PartyIdx)
+		merged.AddAttachment(att)
+	}
+	tags := merged.tagList()
+	for _, tag := range other.tagList() {
+		name, _, _ := strings.Cut(tag, ":")
+		if !slices.ContainsFunc(tags, tagNamed(name)) {
+			tags = append(tags, tag)
+		}
+	}
+	merged.writeTags(tags)
+	return &merged, nil
+}
+
+// deepCopyVCon copies src into dst through a JSON round trip.
+func deepCopyVCon(src, dst *VCon) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// remapIndices applies f to every index in an int or []int reference, as
+// held by Analysis.Dialog, or in the float64 and []interface{} forms of a
+// decoded JSON value. Arrays of numbers come back as []int.
+func remapIndices(v interface{}, f func(int) int) interface{} {
+	switch x := v.(type) {
+	case int:
+		return f(x)
+	case float64:
+		return f(int(x))
+	case []int:
+		out := make([]int, len(x))
+		for i, n := range x {
+			out[i] = f(n)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		ints := make([]int, 0, len(x))
+		for i, item := range x {
+			out[i] = remapIndices(item, f)
+			if n, ok := out[i].(int); ok {
+				ints = append(ints, n)
+			}
+		}
+		if len(ints) == len(x) {
+			return ints
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// identityIndex is the remapIndices function that keeps every index, turning
+// a decoded reference into its int or []int form.
+func identityIndex(i int) int { return i }
+
+// analysisDialogs returns the dialog indices an Analysis.Dialog value refers
+// to, in any of the forms remapIndices accepts.
+func analysisDialogs(ref interface{}) []int {
+	var refs []int
+	remapIndices(ref, func(n int) int {
+		refs = append(refs, n)
+		return n
+	})
+	return refs
+}
+
+func remapIndex(idx *int, f func(int) int) *int {
+	if idx == nil {
+		return nil
+	}
+	return IntPtr(f(*idx))
+}
+
+func remapIntOrSlice(v *IntOrSlice, f func(int) int) *IntOrSlice {
+	if v == nil || v.IsZero() {
+		return v
+	}
+	if n, ok := v.AsInt(); ok {
+		return NewIntValue(f(n))
+	}
+	return NewIntSliceValue(remapIndices(v.AsSlice(), f).([]int))
+}
+
+func unionStrings(a, b []string) []string {
+	out := slices.Clone(a)
+	for _, s := range b {
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
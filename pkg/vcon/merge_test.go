@@ -0,0 +1,106 @@
+package vcon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	a := New("example.com")
+	a.CreatedAt = start.Add(time.Hour)
+	a.AddParty(Party{Tel: "tel:+15551230001", Name: "Alice"})
+	a.AddParty(Party{Tel: "tel:+15551230002"})
+	a.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0, 1)})
+
+	b := New("example.com")
+	b.CreatedAt = start
+	b.Subject = "Transferred call"
+	b.Extensions = []string{"CC"}
+	b.AddParty(Party{Tel: "tel:+15551230002"})
+	b.AddParty(Party{Tel: "tel:+15551230003", Name: "Carol"})
+	b.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0, 1), Originator: IntPtr(1)})
+	b.AddDialog(Dialog{Type: "transfer", StartTime: &start, Transferee: IntPtr(0), Transferor: IntPtr(1), Original: NewIntValue(0)})
+	b.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: []int{0}})
+	b.AddAttachment(Attachment{DialogIdx: IntPtr(1), PartyIdx: 1, StartTime: start})
+
+	m, err := Merge(a, b)
+	require.NoError(t, err)
+	require.NoError(t, m.Validate())
+
+	assert.NotEqual(t, a.UUID, m.UUID)
+	assert.Equal(t, "Transferred call", m.Subject)
+	assert.Equal(t, start, m.CreatedAt)
+	assert.Equal(t, []string{"CC"}, m.Extensions)
+
+	// b's first party is identical to a's second and is merged into it.
+	require.Len(t, m.Parties, 3)
+	assert.Equal(t, "Carol", m.Parties[2].Name)
+
+	require.Len(t, m.Dialog, 3)
+	assert.Equal(t, NewPartyRefs(1, 2), m.Dialog[1].Parties)
+	assert.Equal(t, IntPtr(2), m.Dialog[1].Originator)
+	assert.Equal(t, IntPtr(1), m.Dialog[2].Transferee)
+	assert.Equal(t, IntPtr(2), m.Dialog[2].Transferor)
+	original, _ := m.Dialog[2].Original.AsInt()
+	assert.Equal(t, 1, original)
+
+	assert.Equal(t, []int{1}, m.Analysis[0].Dialog)
+	assert.Equal(t, 2, *m.Attachments[0].DialogIdx)
+	assert.Equal(t, 2, m.Attachments[0].PartyIdx)
+
+	// Inputs are untouched.
+	assert.Len(t, a.Parties, 2)
+	assert.Equal(t, NewPartyRefs(0, 1), b.Dialog[0].Parties)
+}
+
+func TestMergeTransferTargetIsAParty(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// a has more dialogs than parties, so a dialog offset would point
+	// transfer_target past the end of the merged parties.
+	a := New("example.com")
+	a.AddParty(Party{Tel: "tel:+15551230001"})
+	for i := 0; i < 3; i++ {
+		a.AddDialog(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0), Body: "hi", Encoding: "none"})
+	}
+
+	b := New("example.com")
+	b.AddParty(Party{Tel: "tel:+15551230002"})
+	b.AddParty(Party{Tel: "tel:+15551230003"})
+	b.AddParty(Party{Tel: "tel:+15551230004"})
+	b.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0, 1)})
+	b.AddDialog(Dialog{Type: "transfer", StartTime: &start, Transferee: IntPtr(0), Transferor: IntPtr(1),
+		TransferTarget: NewIntValue(2), Original: NewIntValue(0)})
+
+	m, err := Merge(a, b)
+	require.NoError(t, err)
+	valid, issues := m.IsValid()
+	assert.True(t, valid, "%v", issues)
+
+	transfer := m.Dialog[4]
+	target, _ := transfer.TransferTarget.AsInt()
+	assert.Equal(t, 3, target, "transfer_target follows the party renumbering")
+	assert.Equal(t, "tel:+15551230004", m.Parties[target].Tel)
+	original, _ := transfer.Original.AsInt()
+	assert.Equal(t, 3, original, "original follows the dialog renumbering")
+}
+
+func TestMergeWithPartyMatcher(t *testing.T) {
+	a := New("example.com")
+	a.AddParty(Party{Tel: "tel:+15551230001", Name: "Alice"})
+	b := New("example.com")
+	b.AddParty(Party{Tel: "tel:+15551230001"})
+
+	m, err := Merge(a, b)
+	require.NoError(t, err)
+	assert.Len(t, m.Parties, 2)
+
+	m, err = Merge(a, b, WithPartyMatcher(func(x, y Party) bool { return x.Tel == y.Tel }))
+	require.NoError(t, err)
+	assert.Len(t, m.Parties, 1)
+}
@@ -0,0 +1,138 @@
+package vcon
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// WithMessageID sets the message_id of a text dialog, e.g. the id a chat
+// platform gave the message.
+func WithMessageID(id string) DialogOption {
+	return func(d *Dialog) {
+		d.MessageID = id
+	}
+}
+
+// WithRecipients adds the parties a message was sent to after its sender.
+func WithRecipients(parties ...int) DialogOption {
+	return func(d *Dialog) {
+		refs := d.Parties.Indices()
+		for _, p := range parties {
+			if !slices.Contains(refs, p) {
+				refs = append(refs, p)
+			}
+		}
+		d.Parties = NewPartyRefs(refs...)
+	}
+}
+
+// AddTextMessage adds one chat message as a text dialog sent by party at t,
+// so a chat becomes a sequence of dialogs rather than one concatenated body.
+// The body is stored as plain text, and the sender is the dialog's first
+// party and originator. A message_id given with WithMessageID must be unique
+// among the dialogs. It returns the index of the new dialog.
+func (v *VCon) AddTextMessage(party int, t time.Time, body string, opts ...DialogOption) (int, error) {
+	if party < 0 || party >= len(v.Parties) {
+		return -1, fmt.Errorf("party index %d out of range", party)
+	}
+	d := NewDialog("text", t, NewPartyRefs(party),
+		WithOriginator(party), WithMediaType(MIMETypePlainText), WithBody(body), WithEncoding("none"))
+	for _, opt := range opts {
+		opt(d)
+	}
+	for _, p := range d.Parties.Indices() {
+		if p < 0 || p >= len(v.Parties) {
+			return -1, fmt.Errorf("party index %d out of range", p)
+		}
+	}
+	if d.MessageID != "" && v.FindMessage(d.MessageID) >= 0 {
+		return -1, fmt.Errorf("duplicate message_id %q", d.MessageID)
+	}
+	return v.AddDialog(*d), nil
+}
+
+// FindMessage returns the index of the dialog with the given message_id, or
+// -1 when there is none.
+func (v *VCon) FindMessage(id string) int {
+	for i := range v.Dialog {
+		if v.Dialog[i].MessageID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// TextMessages returns the indices of the text dialogs in the order they
+// were sent. Messages sent at the same time, or without a start, keep their
+// order in the vCon.
+func (v *VCon) TextMessages() []int {
+	var msgs []int
+	for i := range v.Dialog {
+		if v.Dialog[i].Type == "text" {
+			msgs = append(msgs, i)
+		}
+	}
+	slices.SortStableFunc(msgs, func(a, b int) int {
+		return compareStart(&v.Dialog[a], &v.Dialog[b])
+	})
+	return msgs
+}
+
+// SortDialogs puts the dialogs in order of their start, keeping the order of
+// dialogs that start together, and renumbers every reference to them from
+// analysis, attachments and other dialogs. DialogRefs stay valid.
+func (v *VCon) SortDialogs() {
+	order := make([]int, len(v.Dialog))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return compareStart(&v.Dialog[a], &v.Dialog[b])
+	})
+	pos := make([]int, len(order))
+	for to, from := range order {
+		pos[from] = to
+	}
+	f := func(i int) int {
+		if i < 0 || i >= len(pos) {
+			return i
+		}
+		return pos[i]
+	}
+
+	sorted := make([]Dialog, len(v.Dialog))
+	for to, from := range order {
+		sorted[to] = v.Dialog[from]
+	}
+	v.Dialog = sorted
+	for i := range v.Dialog {
+		d := &v.Dialog[i]
+		d.Original = remapIntOrSlice(d.Original, f)
+		d.Consultation = remapIntOrSlice(d.Consultation, f)
+		d.TargetDialog = remapIntOrSlice(d.TargetDialog, f)
+	}
+	for i := range v.Analysis {
+		if v.Analysis[i].Dialog != nil {
+			v.Analysis[i].Dialog = remapIndices(v.Analysis[i].Dialog, f)
+		}
+	}
+	for i := range v.Attachments {
+		if !v.Attachments[i].isTags() {
+			v.Attachments[i].DialogIdx = remapIndex(v.Attachments[i].DialogIdx, f)
+		}
+	}
+}
+
+// compareStart orders dialogs by start; dialogs without one sort last.
+func compareStart(a, b *Dialog) int {
+	switch {
+	case a.StartTime == nil && b.StartTime == nil:
+		return 0
+	case a.StartTime == nil:
+		return 1
+	case b.StartTime == nil:
+		return -1
+	}
+	return a.StartTime.Compare(*b.StartTime)
+}
@@ -0,0 +1,57 @@
+package vcon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextMessages(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	alice := v.AddParty(Party{Name: "Alice"})
+	bob := v.AddParty(Party{Name: "Bob"})
+
+	i, err := v.AddTextMessage(alice, start.Add(time.Minute), "How can I help?", WithMessageID("m2"), WithRecipients(bob))
+	require.NoError(t, err)
+	first := v.DialogRef(i)
+	_, err = v.AddTextMessage(bob, start, "Hi, my order is late", WithMessageID("m1"), WithRecipients(alice))
+	require.NoError(t, err)
+	_, err = v.AddTextMessage(bob, start.Add(2*time.Minute), "Order 1234")
+	require.NoError(t, err)
+
+	d := v.Dialog[0]
+	assert.Equal(t, "text", d.Type)
+	assert.Equal(t, []int{alice, bob}, d.Parties.Indices())
+	assert.Equal(t, alice, *d.Originator)
+	assert.Equal(t, MIMETypePlainText, d.MediaType)
+	assert.Equal(t, "none", d.Encoding)
+	assert.Equal(t, 1, v.FindMessage("m1"))
+	assert.Equal(t, -1, v.FindMessage("m3"))
+	assert.Equal(t, []int{1, 0, 2}, v.TextMessages())
+
+	_, err = v.AddTextMessage(alice, start, "again", WithMessageID("m1"))
+	assert.ErrorContains(t, err, "duplicate message_id")
+	_, err = v.AddTextMessage(2, start, "who?")
+	assert.Error(t, err)
+	_, err = v.AddTextMessage(alice, start, "to nobody", WithRecipients(5))
+	assert.Error(t, err)
+	assert.Len(t, v.Dialog, 3)
+
+	_, err = v.AddSummaryAnalysis([]int{0, 2}, "acme", "", "late order")
+	require.NoError(t, err)
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(0), PartyIdx: alice, StartTime: start, Body: "notes", Encoding: "none"})
+	v.Dialog[2].Original = NewIntValue(0)
+
+	v.SortDialogs()
+	assert.Equal(t, "m1", v.Dialog[0].MessageID)
+	assert.Equal(t, "m2", v.Dialog[1].MessageID)
+	assert.Equal(t, []int{0, 1, 2}, v.TextMessages())
+	assert.Equal(t, []int{1, 2}, v.Analysis[0].Dialog)
+	assert.Equal(t, 1, *v.Attachments[0].DialogIdx)
+	n, _ := v.Dialog[2].Original.AsInt()
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, first.Index(), "refs follow the sorted dialog")
+}
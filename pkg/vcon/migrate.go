@@ -0,0 +1,262 @@
+package vcon
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration upgrades a decoded vCon document from one spec version to the
+// next, in place. Migrate sets the "vcon" property to To afterwards.
+type Migration struct {
+	From  string
+	To    string
+	Apply func(doc map[string]interface{}) error
+}
+
+// migrations holds the registered migrations keyed by From.
+var migrations = map[string]*Migration{}
+
+// RegisterMigration adds a migration step, replacing any registered for the
+// same From version. The built-in steps lead from 0.0.1 and 0.0.2 to
+// SpecVersion.
+func RegisterMigration(m Migration) {
+	migrations[m.From] = &m
+}
+
+func init() {
+	// 0.0.1 and 0.0.2 differ only in ways that do not affect the container.
+	RegisterMigration(Migration{From: "0.0.1", To: "0.0.3", Apply: migrateV002ToV003})
+	RegisterMigration(Migration{From: "0.0.2", To: "0.0.3", Apply: migrateV002ToV003})
+	RegisterMigration(Migration{From: "0.0.3", To: SpecVersion, Apply: func(m map[string]interface{}) error {
+		migrateV003ToV040(m)
+		return nil
+	}})
+}
+
+// ErrUnsupportedVersion is returned by Migrate when no chain of registered
+// migrations leads from the document's version to the target.
+var ErrUnsupportedVersion = errors.New("unsupported vCon version")
+
+// SupportedVersions returns the versions Migrate can read or produce, oldest
+// first.
+func SupportedVersions() []string {
+	seen := map[string]struct{}{SpecVersion: {}}
+	for _, m := range migrations {
+		seen[m.From] = struct{}{}
+		seen[m.To] = struct{}{}
+	}
+	versions := make([]string, 0, len(seen))
+	for v := range seen {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return compareVersions(versions[i], versions[j]) < 0 })
+	return versions
+}
+
+// Migrate upgrades a vCon document to targetVersion, or to SpecVersion when
+// targetVersion is empty, by applying the registered migrations in turn. A
+// document already at the target is returned unchanged.
+func Migrate(raw []byte, targetVersion string) ([]byte, error) {
+	if targetVersion == "" {
+		targetVersion = SpecVersion
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if ver, _ := doc["vcon"].(string); ver == targetVersion {
+		return raw, nil
+	}
+	if err := migrateDoc(doc, targetVersion); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// migrateDoc applies the migrations from the document's version up to
+// target.
+func migrateDoc(doc map[string]interface{}, target string) error {
+	ver, _ := doc["vcon"].(string)
+	if ver == "" {
+		return fmt.Errorf("%w: document has no vcon version", ErrUnsupportedVersion)
+	}
+	for steps := 0; ver != target; steps++ {
+		m := migrations[ver]
+		if m == nil || steps > len(migrations) {
+			return fmt.Errorf("%w: no migration path from %s to %s", ErrUnsupportedVersion, doc["vcon"], target)
+		}
+		if err := m.Apply(doc); err != nil {
+			return fmt.Errorf("migrate %s to %s: %w", m.From, m.To, err)
+		}
+		ver = m.To
+		doc["vcon"] = ver
+	}
+	return nil
+}
+
+// compareVersions orders dotted numeric versions such as "0.0.3" and "0.4.0".
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// renameKey moves m[from] to m[to] unless to is already set.
+func renameKey(m map[string]interface{}, from, to string) {
+	v, ok := m[from]
+	if !ok {
+		return
+	}
+	delete(m, from)
+	if _, exists := m[to]; !exists {
+		m[to] = v
+	}
+}
+
+// migrateV002ToV003 renames mimetype, used by the earliest drafts, to
+// mediatype, the analysis vendor_schema to schema and the attachment type to
+// purpose.
+func migrateV002ToV003(m map[string]interface{}) error {
+	for _, section := range []string{"dialog", "attachments", "analysis"} {
+		migrateSliceItems(m, section, func(item map[string]interface{}) {
+			renameKey(item, "mimetype", "mediatype")
+		})
+	}
+	migrateSliceItems(m, "analysis", func(am map[string]interface{}) {
+		renameKey(am, "vendor_schema", "schema")
+	})
+	migrateSliceItems(m, "attachments", func(am map[string]interface{}) {
+		renameKey(am, "type", "purpose")
+	})
+	return nil
+}
+
+// migrateSliceItems applies a migration function to each map item in a JSON array field.
+func migrateSliceItems(m map[string]interface{}, key string, fn func(map[string]interface{})) {
+	items, ok := m[key].([]interface{})
+	if !ok {
+		return
+	}
+	for _, item := range items {
+		if itemMap, ok := item.(map[string]interface{}); ok {
+			fn(itemMap)
+		}
+	}
+}
+
+// migrateEncodingAndHash converts "base64" to "base64url" and fixes content_hash format.
+func migrateEncodingAndHash(m map[string]interface{}) {
+	if enc, ok := m["encoding"].(string); ok && enc == "base64" {
+		m["encoding"] = "base64url"
+	}
+	migrateContentHash(m)
+}
+
+// migrateV003ToV040 converts a v0.0.3 raw map to v0.4.0 format in-place.
+func migrateV003ToV040(m map[string]interface{}) {
+	m["vcon"] = "0.4.0"
+
+	delete(m, "appended")
+	delete(m, "meta")
+
+	if _, ok := m["parties"]; !ok {
+		m["parties"] = []interface{}{}
+	}
+
+	migrateSliceItems(m, "dialog", func(dm map[string]interface{}) {
+		delete(dm, "alg")
+		delete(dm, "signature")
+		delete(dm, "meta")
+		migrateEncodingAndHash(dm)
+		delete(dm, "campaign")
+		delete(dm, "interaction_type")
+		delete(dm, "interaction_id")
+		delete(dm, "skill")
+		dropStrayOriginator(dm)
+	})
+
+	migrateSliceItems(m, "parties", func(pm map[string]interface{}) {
+		delete(pm, "role")
+		delete(pm, "contact_list")
+		delete(pm, "timezone")
+		delete(pm, "meta")
+	})
+
+	migrateSliceItems(m, "attachments", func(am map[string]interface{}) {
+		delete(am, "meta")
+		migrateEncodingAndHash(am)
+		// "dialog" is now required by the IETF schema
+		if _, ok := am["dialog"]; !ok {
+			am["dialog"] = float64(0)
+		}
+	})
+
+	migrateSliceItems(m, "analysis", func(am map[string]interface{}) {
+		delete(am, "meta")
+		migrateEncodingAndHash(am)
+		// "vendor" is now required by the IETF schema
+		if _, ok := am["vendor"]; !ok || am["vendor"] == "" {
+			am["vendor"] = "unknown"
+		}
+	})
+}
+
+// dropStrayOriginator removes an originator that is not one of the dialog's
+// parties. Writers of 0.0.3 often emitted originator 0 as a default, which
+// was dropped on output; it is only meaningful when it names a party.
+func dropStrayOriginator(dm map[string]interface{}) {
+	orig, ok := dm["originator"].(float64)
+	if !ok {
+		return
+	}
+	switch parties := dm["parties"].(type) {
+	case float64:
+		if parties == orig {
+			return
+		}
+	case []interface{}:
+		for _, p := range parties {
+			if n, ok := p.(float64); ok && n == orig {
+				return
+			}
+		}
+	default:
+		return
+	}
+	delete(dm, "originator")
+}
+
+// migrateContentHash converts content_hash from old "alg:hash" format to
+// "alg-hash", re-encoding hex digests of a known algorithm as base64url.
+func migrateContentHash(m map[string]interface{}) {
+	ch, ok := m["content_hash"].(string)
+	if !ok || ch == "" {
+		return
+	}
+	ch = strings.ReplaceAll(ch, ":", "-")
+	if alg, digest, found := strings.Cut(ch, "-"); found {
+		if size, ok := ContentHashAlgorithms[alg]; ok && len(digest) == 2*size {
+			if raw, err := hex.DecodeString(digest); err == nil {
+				ch = alg + "-" + base64.RawURLEncoding.EncodeToString(raw)
+			}
+		}
+	}
+	m["content_hash"] = ch
+}
@@ -0,0 +1,80 @@
+package vcon
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const v001Doc = `{
+	"vcon": "0.0.1",
+	"uuid": "018f0000-0000-8000-8000-000000000000",
+	"created_at": "2024-01-01T00:00:00Z",
+	"parties": [{"name": "Alice"}],
+	"dialog": [{"type": "recording", "start": "2024-01-01T00:00:00Z", "parties": [0],
+		"mimetype": "audio/x-wav", "body": "UklGRg==", "encoding": "base64", "alg": "SHA-512", "signature": "abc"}],
+	"attachments": [{"type": "invoice", "party": 0, "dialog": 0, "start": "2024-01-01T00:00:00Z", "body": "x", "encoding": "none", "mimetype": "text/plain"}],
+	"analysis": [{"type": "summary", "vendor": "acme", "vendor_schema": "v2", "body": "ok", "encoding": "none"}]
+}`
+
+func TestMigrate(t *testing.T) {
+	out, err := Migrate([]byte(v001Doc), "")
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(out, &doc))
+	assert.Equal(t, SpecVersion, doc["vcon"])
+	dialog := doc["dialog"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "audio/x-wav", dialog["mediatype"])
+	assert.Equal(t, "base64url", dialog["encoding"])
+	assert.NotContains(t, dialog, "mimetype")
+	assert.NotContains(t, dialog, "alg")
+	attachment := doc["attachments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "invoice", attachment["purpose"])
+	assert.Equal(t, "text/plain", attachment["mediatype"])
+	analysis := doc["analysis"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "v2", analysis["schema"])
+
+	// Intermediate targets stop along the way.
+	out, err = Migrate([]byte(v001Doc), "0.0.3")
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"vcon":"0.0.3"`)
+	assert.Contains(t, string(out), `"mediatype":"audio/x-wav"`)
+	assert.Contains(t, string(out), `"encoding":"base64"`)
+
+	// BuildFromJSON runs the whole chain.
+	v, err := BuildFromJSON(v001Doc)
+	require.NoError(t, err)
+	assert.Equal(t, "audio/x-wav", v.Dialog[0].MediaType)
+	assert.Equal(t, "invoice", v.Attachments[0].Purpose)
+}
+
+func TestMigrateErrors(t *testing.T) {
+	current := `{"vcon":"0.4.0","uuid":"018f0000-0000-8000-8000-000000000000","created_at":"2024-01-01T00:00:00Z","parties":[]}`
+	out, err := Migrate([]byte(current), SpecVersion)
+	require.NoError(t, err)
+	assert.Equal(t, current, string(out))
+
+	_, err = Migrate([]byte(`{"vcon":"0.9.0"}`), "")
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+	_, err = Migrate([]byte(`{"uuid":"x"}`), "")
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+	_, err = Migrate([]byte(current), "0.0.3")
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion), "downgrades are not supported")
+}
+
+func TestRegisterMigration(t *testing.T) {
+	t.Cleanup(func() { delete(migrations, "0.0.0") })
+	RegisterMigration(Migration{From: "0.0.0", To: "0.0.2", Apply: func(m map[string]interface{}) error {
+		renameKey(m, "participants", "parties")
+		return nil
+	}})
+	assert.Equal(t, []string{"0.0.0", "0.0.1", "0.0.2", "0.0.3", SpecVersion}, SupportedVersions())
+
+	out, err := Migrate([]byte(`{"vcon":"0.0.0","uuid":"x","participants":[]}`), "")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"vcon":"0.4.0","uuid":"x","parties":[]}`, string(out))
+}
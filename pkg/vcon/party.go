@@ -27,6 +27,19 @@ const (
 	PartyEventKeyup PartyEventType = "keyup"
 )
 
+// PartyEventTypes lists the party_history events defined by the spec.
+// Extensions add more through PartyEventExtension.
+var PartyEventTypes = []PartyEventType{
+	PartyEventJoin,
+	PartyEventDrop,
+	PartyEventHold,
+	PartyEventUnhold,
+	PartyEventMute,
+	PartyEventUnmute,
+	PartyEventKeydown,
+	PartyEventKeyup,
+}
+
 // Party represents a participant in a vCon.
 type Party struct {
 	// Telephone number of the party (tel URL)
@@ -49,6 +62,9 @@ type Party struct {
 	Sip string `json:"sip,omitempty"`
 	// Decentralized Identifier of the party
 	Did string `json:"did,omitempty"`
+
+	// Extra holds non-standard properties, such as extension parameters.
+	Extra ExtraProperties `json:"-"`
 }
 
 // PartyOption is a function that configures a Party
@@ -108,7 +124,8 @@ func WithDid(did string) PartyOption {
 	}
 }
 
-// ToMap converts the Party to a map, excluding empty fields
+// ToMap converts the Party to a map, excluding empty fields. Extra
+// properties are included.
 func (p *Party) ToMap() map[string]interface{} {
 	result := make(map[string]interface{})
 
@@ -142,6 +159,7 @@ func (p *Party) ToMap() map[string]interface{} {
 	if p.Did != "" {
 		result["did"] = p.Did
 	}
+	p.Extra.addTo(result)
 
 	return result
 }
@@ -180,6 +198,13 @@ func (p *Party) SetFromMap(data map[string]interface{}) {
 		}
 		p.CivicAddress.SetFromMap(civicAddressMap)
 	}
+
+	// Anything else, such as extension parameters, is kept in Extra.
+	for k, v := range data {
+		if _, ok := AllowedPartyProperties[k]; !ok {
+			p.Extra.Set(k, v)
+		}
+	}
 }
 
 // ToDict converts the Party to a map
@@ -194,7 +219,7 @@ func (p *Party) ToDict() map[string]interface{} {
 type PartyHistory struct {
 	// Index of the party
 	Party int `json:"party"`
-	// Event type (e.g. "join", "leave", "hold")
+	// Event type, one of PartyEventTypes or an event added by an extension
 	Event string `json:"event"`
 	// Time of the event
 	Time time.Time `json:"time"`
@@ -0,0 +1,122 @@
+package vcon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyHeader carries the key PostToURLWithOptions sends with every
+// attempt, so a collector can discard the duplicates a retry may create.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// StatusError is returned when a server answers with an unexpected HTTP
+// status. Use errors.As to tell rejected requests (4xx), which will fail
+// again, from server failures (5xx) worth retrying later.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP request failed with status code: %d", e.StatusCode)
+}
+
+// ClientError reports a 4xx status.
+func (e *StatusError) ClientError() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// ServerError reports a 5xx status.
+func (e *StatusError) ServerError() bool {
+	return e.StatusCode >= 500
+}
+
+// PostOption configures PostToURLWithOptions.
+type PostOption func(*postConfig)
+
+type postConfig struct {
+	retries        int
+	backoff        time.Duration
+	maxBackoff     time.Duration
+	idempotencyKey string
+}
+
+// WithPostRetries retries network errors, 429 and 5xx responses up to n more
+// times, as WithRetries does for LoadFromURLWithOptions.
+func WithPostRetries(n int, backoff time.Duration) PostOption {
+	return func(c *postConfig) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+// WithIdempotencyKey sends key as the Idempotency-Key header instead of a
+// random one, e.g. to keep it across separate calls for the same vCon.
+func WithIdempotencyKey(key string) PostOption {
+	return func(c *postConfig) {
+		c.idempotencyKey = key
+	}
+}
+
+// PostToURLWithOptions sends the vCon as JSON to urlStr with a POST request,
+// retrying transient failures as configured by opts. Every attempt carries
+// the same Idempotency-Key header. A non-2xx response fails with a
+// *StatusError.
+func (v *VCon) PostToURLWithOptions(urlStr string, opts ...PostOption) error {
+	return v.PostToURLWithOptionsContext(context.Background(), urlStr, opts...)
+}
+
+// PostToURLWithOptionsContext is PostToURLWithOptions with a context
+// bounding the requests and the waits between retries.
+func (v *VCon) PostToURLWithOptionsContext(ctx context.Context, urlStr string, opts ...PostOption) error {
+	cfg := postConfig{maxBackoff: DefaultMaxRetryBackoff}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.backoff <= 0 {
+		cfg.backoff = DefaultRetryBackoff
+	}
+	if cfg.idempotencyKey == "" {
+		cfg.idempotencyKey = uuid.NewString()
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal VCon: %w", err)
+	}
+
+	return retry(ctx, cfg.retries, cfg.backoff, cfg.maxBackoff, func() (time.Duration, error) {
+		return v.postOnce(ctx, urlStr, data, cfg.idempotencyKey)
+	})
+}
+
+func (v *VCon) postOnce(ctx context.Context, urlStr string, data []byte, key string) (time.Duration, error) {
+	req, err := NewHTTPRequest(ctx, http.MethodPost, urlStr, bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("invalid URL format: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, key)
+
+	resp, err := v.doHTTP(req)
+	if err != nil {
+		return 0, &retryableError{fmt.Errorf("failed to post vCon: %w", err)}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := &StatusError{StatusCode: resp.StatusCode}
+		if resp.StatusCode == http.StatusTooManyRequests || err.ServerError() {
+			return retryAfter(resp), &retryableError{err}
+		}
+		return 0, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,166 @@
+package vcon
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UnknownPropertiesError is returned by BuildFromJSON in
+// PropertyHandlingReject mode. Paths are JSON pointers (RFC 6901) to each
+// non-standard property, e.g. "/parties/0/x_crm_id".
+type UnknownPropertiesError struct {
+	Paths []string
+}
+
+func (e *UnknownPropertiesError) Error() string {
+	return fmt.Sprintf("non-standard properties not allowed: %s", strings.Join(e.Paths, ", "))
+}
+
+// Allowed properties of the objects nested inside the top-level arrays.
+var (
+	AllowedPartyHistoryProperties = map[string]struct{}{
+		"party": {}, "time": {}, "event": {}, "button": {},
+	}
+
+	AllowedCivicAddressProperties = map[string]struct{}{
+		"country": {}, "a1": {}, "a2": {}, "a3": {}, "a4": {}, "a5": {}, "a6": {},
+		"prd": {}, "pod": {}, "sts": {}, "hno": {}, "hns": {}, "lmk": {},
+		"loc": {}, "flr": {}, "nam": {}, "pc": {},
+	}
+
+	AllowedSessionIDProperties = map[string]struct{}{
+		"local": {}, "remote": {},
+	}
+
+	AllowedGroupProperties = map[string]struct{}{
+		"uuid": {}, "body": {}, "encoding": {}, "url": {}, "content_hash": {},
+	}
+
+	AllowedRedactedProperties = map[string]struct{}{
+		"uuid": {}, "type": {}, "url": {}, "content_hash": {},
+	}
+
+	AllowedAmendedProperties = map[string]struct{}{
+		"uuid": {}, "url": {}, "content_hash": {},
+	}
+)
+
+// propertySpec describes the allowed properties of one object type and the
+// object types nested under its keys. A nested key may hold a single object
+// or an array of objects.
+type propertySpec struct {
+	allowed map[string]struct{}
+	nested  map[string]*propertySpec
+}
+
+// vconPropertySpec builds the spec tree for a whole vCon. With a registry the
+// extension parameters it declares are allowed as well; without one only the
+// core properties are.
+func vconPropertySpec(registry *ExtensionRegistry) *propertySpec {
+	vconProps, partyProps := AllowedVConProperties, AllowedPartyProperties
+	dialogProps, attachmentProps, analysisProps := AllowedDialogProperties, AllowedAttachmentProperties, AllowedAnalysisProperties
+	if registry != nil {
+		vconProps, partyProps = registry.AllowedVConParams(), registry.AllowedPartyParams()
+		dialogProps = registry.AllowedDialogParams()
+		attachmentProps = registry.AllowedAttachmentParams()
+		analysisProps = registry.AllowedAnalysisParams()
+	}
+
+	leaf := func(allowed map[string]struct{}) *propertySpec {
+		return &propertySpec{allowed: allowed}
+	}
+	return &propertySpec{
+		allowed: vconProps,
+		nested: map[string]*propertySpec{
+			"parties": {
+				allowed: partyProps,
+				nested:  map[string]*propertySpec{"civicaddress": leaf(AllowedCivicAddressProperties)},
+			},
+			"dialog": {
+				allowed: dialogProps,
+				nested: map[string]*propertySpec{
+					"party_history": leaf(AllowedPartyHistoryProperties),
+					"session_id":    leaf(AllowedSessionIDProperties),
+				},
+			},
+			"attachments": leaf(attachmentProps),
+			"analysis":    leaf(analysisProps),
+			"group":       leaf(AllowedGroupProperties),
+			"redacted":    leaf(AllowedRedactedProperties),
+			"amended":     leaf(AllowedAmendedProperties),
+		},
+	}
+}
+
+// ProcessVConProperties applies ProcessProperties to a decoded vCon and to
+// every object nested in it (parties and their civic addresses, dialogs and
+// their party_history and session_id, attachments, analysis, group,
+// redacted and amended), so non-standard fields are handled the same way at
+// every depth. The parameters of the extensions registered in
+// DefaultRegistry count as standard, as they do for FindUnknownProperties.
+func ProcessVConProperties(raw map[string]interface{}, mode string) map[string]interface{} {
+	return processPropertyTree(raw, vconPropertySpec(DefaultRegistry), mode)
+}
+
+func processPropertyTree(obj map[string]interface{}, spec *propertySpec, mode string) map[string]interface{} {
+	result := ProcessProperties(obj, spec.allowed, mode)
+	for key, child := range spec.nested {
+		switch v := result[key].(type) {
+		case map[string]interface{}:
+			result[key] = processPropertyTree(v, child, mode)
+		case []interface{}:
+			items := make([]interface{}, len(v))
+			for i, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					items[i] = processPropertyTree(m, child, mode)
+				} else {
+					items[i] = item
+				}
+			}
+			result[key] = items
+		}
+	}
+	return result
+}
+
+// FindUnknownProperties returns the JSON pointers of all properties in a
+// decoded vCon, at any depth, that are neither defined by the spec nor added
+// by an extension registered in registry (DefaultRegistry when nil). The
+// paths are sorted.
+func FindUnknownProperties(raw map[string]interface{}, registry *ExtensionRegistry) []string {
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	var paths []string
+	findUnknown(raw, vconPropertySpec(registry), "", &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+func findUnknown(obj map[string]interface{}, spec *propertySpec, prefix string, paths *[]string) {
+	for k := range obj {
+		if _, ok := spec.allowed[k]; !ok {
+			*paths = append(*paths, prefix+"/"+escapeJSONPointer(k))
+		}
+	}
+	for key, child := range spec.nested {
+		path := prefix + "/" + key
+		switch v := obj[key].(type) {
+		case map[string]interface{}:
+			findUnknown(v, child, path, paths)
+		case []interface{}:
+			for i, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					findUnknown(m, child, path+"/"+strconv.Itoa(i), paths)
+				}
+			}
+		}
+	}
+}
+
+// escapeJSONPointer escapes a reference token per RFC 6901.
+func escapeJSONPointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
@@ -0,0 +1,158 @@
+package vcon
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const vconWithUnknowns = `{
+	"uuid": "018f0000-0000-8000-8000-000000000000",
+	"created_at": "2024-01-01T00:00:00Z",
+	"x_tenant": "blue",
+	"parties": [{"name": "Alice", "x_crm_id": "42"}, {"name": "Bob"}],
+	"dialog": [{"type": "text", "start": "2024-01-01T00:00:00Z", "parties": [0, 1], "body": "hi", "encoding": "none", "a/b": 1}]
+}`
+
+func TestBuildFromJSONRejectMode(t *testing.T) {
+	_, err := BuildFromJSON(vconWithUnknowns, PropertyHandlingReject)
+	require.Error(t, err)
+
+	var unknown *UnknownPropertiesError
+	require.True(t, errors.As(err, &unknown))
+	assert.Equal(t, []string{"/dialog/0/a~1b", "/parties/0/x_crm_id", "/x_tenant"}, unknown.Paths)
+	assert.Contains(t, err.Error(), "/parties/0/x_crm_id")
+
+	// The other modes still accept the document.
+	for _, mode := range []string{PropertyHandlingDefault, PropertyHandlingStrict, PropertyHandlingMeta} {
+		_, err := BuildFromJSON(vconWithUnknowns, mode)
+		assert.NoError(t, err, mode)
+	}
+}
+
+func TestBuildFromJSONReportMode(t *testing.T) {
+	v, err := BuildFromJSON(vconWithUnknowns, PropertyHandlingReport)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dialog/0/a~1b", "/parties/0/x_crm_id", "/x_tenant"}, v.DroppedProperties())
+	assert.Empty(t, v.Extra.Keys())
+	assert.Empty(t, v.Parties[0].Extra.Keys())
+
+	ok, issues := v.IsValid()
+	assert.True(t, ok)
+	var reported []string
+	for _, issue := range issues {
+		if issue.Code == IssueUnknownProperty {
+			assert.Equal(t, SeverityWarning, issue.Severity)
+			reported = append(reported, issue.Path)
+		}
+	}
+	assert.Equal(t, v.DroppedProperties(), reported)
+
+	v, err = BuildFromJSON(vconWithUnknowns, PropertyHandlingStrict)
+	require.NoError(t, err)
+	assert.Empty(t, v.DroppedProperties())
+}
+
+func TestBuildFromJSONRejectModeAcceptsStandardDocument(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	_, err = BuildFromJSON(string(data), PropertyHandlingReject)
+	assert.NoError(t, err)
+}
+
+func TestFindUnknownPropertiesHonorsRegistry(t *testing.T) {
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(vconWithUnknowns), &raw))
+
+	r := NewExtensionRegistry()
+	r.Register(testExtension{name: "CRM", compatible: true, partyParams: []string{"x_crm_id"}})
+
+	assert.Equal(t, []string{"/dialog/0/a~1b", "/x_tenant"}, FindUnknownProperties(raw, r))
+}
+
+const vconWithNestedUnknowns = `{
+	"uuid": "018f0000-0000-8000-8000-000000000000",
+	"created_at": "2024-01-01T00:00:00Z",
+	"parties": [{"name": "Alice", "civicaddress": {"country": "US", "x_geo": {"lat": 1}}}],
+	"dialog": [{
+		"type": "recording", "start": "2024-01-01T00:00:00Z", "parties": [0],
+		"party_history": [{"party": 0, "time": "2024-01-01T00:00:00Z", "event": "join", "x_reason": "dial-in"}],
+		"session_id": {"local": "a", "remote": "b", "x_trunk": "t1"}
+	}],
+	"group": [{"uuid": "018f0000-0000-8000-8000-000000000001", "x_order": 2}]
+}`
+
+func decodeRaw(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &raw))
+	return raw
+}
+
+func TestProcessVConPropertiesNested(t *testing.T) {
+	nestedUnknowns := []string{
+		"/dialog/0/party_history/0/x_reason",
+		"/dialog/0/session_id/x_trunk",
+		"/group/0/x_order",
+		"/parties/0/civicaddress/x_geo",
+	}
+	assert.Equal(t, nestedUnknowns, FindUnknownProperties(decodeRaw(t, vconWithNestedUnknowns), nil))
+
+	t.Run("default keeps everything", func(t *testing.T) {
+		raw := decodeRaw(t, vconWithNestedUnknowns)
+		assert.Equal(t, decodeRaw(t, vconWithNestedUnknowns), ProcessVConProperties(raw, PropertyHandlingDefault))
+	})
+
+	t.Run("strict removes nested unknowns", func(t *testing.T) {
+		processed := ProcessVConProperties(decodeRaw(t, vconWithNestedUnknowns), PropertyHandlingStrict)
+		assert.Empty(t, FindUnknownProperties(processed, nil))
+		party := processed["parties"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"country": "US"}, party["civicaddress"])
+	})
+
+	t.Run("meta moves nested unknowns into the nested object", func(t *testing.T) {
+		processed := ProcessVConProperties(decodeRaw(t, vconWithNestedUnknowns), PropertyHandlingMeta)
+		dialog := processed["dialog"].([]interface{})[0].(map[string]interface{})
+		session := dialog["session_id"].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"x_trunk": "t1"}, session["meta"])
+		history := dialog["party_history"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"x_reason": "dial-in"}, history["meta"])
+	})
+
+	t.Run("reject reports nested paths", func(t *testing.T) {
+		_, err := BuildFromJSON(vconWithNestedUnknowns, PropertyHandlingReject)
+		var unknown *UnknownPropertiesError
+		require.True(t, errors.As(err, &unknown))
+		assert.Equal(t, nestedUnknowns, unknown.Paths)
+	})
+}
+
+func TestBuildFromJSONNestedRoundTrip(t *testing.T) {
+	// group items are kept verbatim, so they show the effect of each mode
+	// after a full BuildFromJSON / Marshal round trip.
+	groupItem := func(mode string) map[string]interface{} {
+		v, err := BuildFromJSON(vconWithNestedUnknowns, mode)
+		require.NoError(t, err)
+		out, err := json.Marshal(v)
+		require.NoError(t, err)
+		return decodeRaw(t, string(out))["group"].([]interface{})[0].(map[string]interface{})
+	}
+
+	assert.Equal(t, float64(2), groupItem(PropertyHandlingDefault)["x_order"])
+	assert.NotContains(t, groupItem(PropertyHandlingStrict), "x_order")
+	assert.Equal(t, map[string]interface{}{"x_order": float64(2)}, groupItem(PropertyHandlingMeta)["meta"])
+
+	// Key order in the input does not matter.
+	reordered := `{"group":[{"x_order":2,"uuid":"018f0000-0000-8000-8000-000000000001"}],
+		"parties":[{"civicaddress":{"x_geo":{"lat":1},"country":"US"},"name":"Alice"}],
+		"created_at":"2024-01-01T00:00:00Z","uuid":"018f0000-0000-8000-8000-000000000000"}`
+	assert.Equal(t,
+		ProcessVConProperties(decodeRaw(t, reordered), PropertyHandlingStrict)["group"],
+		ProcessVConProperties(decodeRaw(t, vconWithNestedUnknowns), PropertyHandlingStrict)["group"])
+}
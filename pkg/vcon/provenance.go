@@ -0,0 +1,147 @@
+package vcon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ProvenanceMetaKey is the key under Analysis.Meta holding the Provenance.
+const ProvenanceMetaKey = "provenance"
+
+// Provenance records how a machine-generated analysis was produced, so the
+// artefact can be audited: which model ran, with which prompt or
+// configuration, when, and over exactly which dialog content.
+type Provenance struct {
+	Model        string            `json:"model,omitempty"`
+	ModelVersion string            `json:"model_version,omitempty"`
+	ModelCard    string            `json:"model_card,omitempty"` // URL
+	ConfigHash   ContentHashList   `json:"config_hash,omitempty"`
+	ProcessedAt  time.Time         `json:"processed_at"`
+	Inputs       []ProvenanceInput `json:"inputs,omitempty"`
+}
+
+// ProvenanceInput identifies one dialog an analysis was computed from.
+type ProvenanceInput struct {
+	Dialog      int             `json:"dialog"`
+	ContentHash ContentHashList `json:"content_hash"`
+}
+
+// ProvenanceOption configures NewProvenance.
+type ProvenanceOption func(*Provenance)
+
+// WithModel records the model name and version.
+func WithModel(name, version string) ProvenanceOption {
+	return func(p *Provenance) {
+		p.Model, p.ModelVersion = name, version
+	}
+}
+
+// WithModelCard records the URL of the model card.
+func WithModelCard(url string) ProvenanceOption {
+	return func(p *Provenance) {
+		p.ModelCard = url
+	}
+}
+
+// WithPromptConfig records the SHA-512 of the prompt or configuration used.
+func WithPromptConfig(config []byte) ProvenanceOption {
+	return func(p *Provenance) {
+		p.ConfigHash = ContentHashList{ComputeSHA512(config)}
+	}
+}
+
+// WithProcessedAt overrides the processing time, which defaults to now.
+func WithProcessedAt(t time.Time) ProvenanceOption {
+	return func(p *Provenance) {
+		p.ProcessedAt = t
+	}
+}
+
+// NewProvenance builds the provenance of an analysis over the given dialogs
+// of v, hashing each dialog's content as it is now.
+func (v *VCon) NewProvenance(dialogs []int, opts ...ProvenanceOption) (Provenance, error) {
+	p := Provenance{ProcessedAt: time.Now().UTC()}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	for _, i := range dialogs {
+		if i < 0 || i >= len(v.Dialog) {
+			return Provenance{}, fmt.Errorf("invalid dialog index: %d", i)
+		}
+		hash, err := v.Dialog[i].provenanceHash()
+		if err != nil {
+			return Provenance{}, fmt.Errorf("dialog %d: %w", i, err)
+		}
+		p.Inputs = append(p.Inputs, ProvenanceInput{Dialog: i, ContentHash: hash})
+	}
+	return p, nil
+}
+
+// provenanceHash identifies the dialog's content: its content_hash when
+// present, the hash of its inline body, or else the hash of the canonical
+// dialog object (for dialogs without content, such as transfers).
+func (d *Dialog) provenanceHash() (ContentHashList, error) {
+	if !d.ContentHash.IsEmpty() {
+		return d.ContentHash, nil
+	}
+	if d.Body != "" {
+		body, err := decodeInlineBody(d.Body, d.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		return ContentHashList{ComputeSHA512(body)}, nil
+	}
+	canon, err := Canonicalise(d)
+	if err != nil {
+		return nil, err
+	}
+	return ContentHashList{ComputeSHA512(canon)}, nil
+}
+
+// SetProvenance stores p under Meta["provenance"].
+func (a *Analysis) SetProvenance(p Provenance) {
+	a.setMeta(ProvenanceMetaKey, p)
+}
+
+// Provenance returns the provenance recorded by SetProvenance, whether set
+// in memory or decoded from JSON, or nil when there is none.
+func (a *Analysis) Provenance() (*Provenance, error) {
+	var p Provenance
+	if ok, err := a.metaValue(ProvenanceMetaKey, &p); !ok || err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ErrProvenanceMismatch is returned by VerifyProvenance when a dialog no
+// longer matches the content an analysis was computed from.
+var ErrProvenanceMismatch = errors.New("analysis inputs changed since processing")
+
+// VerifyProvenance checks that every dialog the analysis at index i was
+// computed from still has the recorded content.
+func (v *VCon) VerifyProvenance(i int) error {
+	if i < 0 || i >= len(v.Analysis) {
+		return fmt.Errorf("invalid analysis index: %d", i)
+	}
+	p, err := v.Analysis[i].Provenance()
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return fmt.Errorf("analysis %d has no provenance", i)
+	}
+	for _, in := range p.Inputs {
+		if in.Dialog < 0 || in.Dialog >= len(v.Dialog) {
+			return fmt.Errorf("%w: dialog %d no longer exists", ErrProvenanceMismatch, in.Dialog)
+		}
+		hash, err := v.Dialog[in.Dialog].provenanceHash()
+		if err != nil {
+			return err
+		}
+		if hash.First() != in.ContentHash.First() {
+			return fmt.Errorf("%w: dialog %d", ErrProvenanceMismatch, in.Dialog)
+		}
+	}
+	return nil
+}
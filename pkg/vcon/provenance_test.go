@@ -0,0 +1,54 @@
+package vcon
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalysisProvenance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.AddDialog(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0), Body: "hello", Encoding: "none"})
+
+	p, err := v.NewProvenance([]int{0},
+		WithModel("whisper", "large-v3"),
+		WithPromptConfig([]byte("summarize briefly")),
+		WithProcessedAt(start))
+	require.NoError(t, err)
+	require.Len(t, p.Inputs, 1)
+	assert.Equal(t, ComputeSHA512([]byte("hello")), p.Inputs[0].ContentHash.First())
+	assert.Equal(t, ComputeSHA512([]byte("summarize briefly")), p.ConfigHash.First())
+
+	a := Analysis{Type: "summary", Vendor: "acme", Dialog: []int{0}, Body: "greeting"}
+	a.SetProvenance(p)
+	v.AddAnalysis(a)
+	require.NoError(t, v.VerifyProvenance(0))
+
+	// The provenance survives a JSON round trip, also in strict mode.
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	loaded, err := BuildFromJSON(string(data), PropertyHandlingStrict)
+	require.NoError(t, err)
+	got, err := loaded.Analysis[0].Provenance()
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "large-v3", got.ModelVersion)
+	assert.True(t, got.ProcessedAt.Equal(start))
+	require.NoError(t, loaded.VerifyProvenance(0))
+
+	loaded.Dialog[0].Body = "goodbye"
+	assert.True(t, errors.Is(loaded.VerifyProvenance(0), ErrProvenanceMismatch))
+
+	none, err := (&Analysis{}).Provenance()
+	assert.NoError(t, err)
+	assert.Nil(t, none)
+
+	_, err = v.NewProvenance([]int{3})
+	assert.Error(t, err)
+}
@@ -1,7 +1,10 @@
 package vcon
 
 import (
+	"context"
 	"encoding/json"
+	"strings"
+	"unicode/utf8"
 )
 
 // RedactOption configures the redacted object.
@@ -28,6 +31,7 @@ func (v *VCon) Redact(redactionType string, redactFn func(*VCon) error, opts ...
 	if err := json.Unmarshal(data, &copy); err != nil {
 		return nil, err
 	}
+	copy.fetcher, copy.httpClient = v.fetcher, v.httpClient
 
 	// Apply the redaction function
 	if err := redactFn(&copy); err != nil {
@@ -60,3 +64,134 @@ func (v *VCon) SetRedacted(uuid, redactionType string, opts ...RedactOption) {
 		opt(v.Redacted)
 	}
 }
+
+// RedactionRules is a declarative redaction function for Redact. Parties and
+// dialogs stay in place, so indices in the redacted vCon still refer to the
+// same entries as in the original.
+type RedactionRules struct {
+	// DropDialogBodies removes the inline body, URL and content hash of
+	// every dialog.
+	DropDialogBodies bool
+	// MaskPartyAddresses masks the tel, mailto and sip addresses of every
+	// party, keeping only enough to tell them apart.
+	MaskPartyAddresses bool
+	// RemoveAttachments removes all attachments.
+	RemoveAttachments bool
+
+	// PseudonymizeParties replaces every party with one carrying only a
+	// pseudonym as its name. See PseudonymKey.
+	PseudonymizeParties bool
+	// PseudonymKey, when set, derives each pseudonym from a keyed hash of the
+	// party's address, so the same person gets the same pseudonym in every
+	// vCon exported with the key. Otherwise parties are named "Party 1",
+	// "Party 2" and so on.
+	PseudonymKey []byte
+	// ScrubText replaces email addresses, phone and card numbers and party
+	// names in the subject, in text dialogs and in analysis bodies. Content
+	// held at a URL and binary analysis bodies cannot be scrubbed and are
+	// removed.
+	ScrubText bool
+	// VoiceTransform, when set, rewrites the content of every audio dialog,
+	// e.g. to shift its pitch. The result is stored inline with the media
+	// type it returns.
+	VoiceTransform func(media []byte, mediaType string) ([]byte, string, error)
+	// DropMediaBodies removes the content of audio and video dialogs that
+	// VoiceTransform did not rewrite.
+	DropMediaBodies bool
+}
+
+// Apply redacts v in place according to the rules. It has the signature of
+// the redactFn passed to Redact.
+func (r RedactionRules) Apply(v *VCon) error {
+	return r.ApplyContext(context.Background(), v)
+}
+
+// ApplyContext is Apply with a context controlling the fetches of external
+// media rewritten by VoiceTransform. Pass it to Redact in a closure.
+func (r RedactionRules) ApplyContext(ctx context.Context, v *VCon) error {
+	if r.ScrubText {
+		if err := r.scrubText(v); err != nil {
+			return err
+		}
+	}
+	if err := r.applyMedia(ctx, v); err != nil {
+		return err
+	}
+	if r.DropDialogBodies {
+		for i := range v.Dialog {
+			d := &v.Dialog[i]
+			d.Body, d.Encoding, d.URL = "", "", ""
+			d.ContentHash = nil
+			d.fetched = nil
+		}
+	}
+	if r.PseudonymizeParties {
+		for i := range v.Parties {
+			v.Parties[i] = Party{Name: r.pseudonym(i, v.Parties[i])}
+		}
+	}
+	if r.MaskPartyAddresses {
+		for i := range v.Parties {
+			p := &v.Parties[i]
+			p.Tel = maskTel(p.Tel)
+			p.Mailto = maskUserAddress(p.Mailto)
+			p.Sip = maskUserAddress(p.Sip)
+		}
+	}
+	if r.RemoveAttachments {
+		v.Attachments = nil
+	}
+	return nil
+}
+
+// RedactWithRules creates a redacted copy of this VCon by applying rules.
+func (v *VCon) RedactWithRules(redactionType string, rules RedactionRules, opts ...RedactOption) (*VCon, error) {
+	return v.Redact(redactionType, rules.Apply, opts...)
+}
+
+// maskTel keeps the scheme, a leading "+" and the last four digits of a tel
+// URL: "tel:+12025551234" becomes "tel:+*******1234".
+func maskTel(tel string) string {
+	if tel == "" {
+		return ""
+	}
+	// Parameters such as ext= may identify the party too, so they are dropped.
+	number, _, _ := strings.Cut(strings.TrimPrefix(tel, "tel:"), ";")
+	masked := []byte(number)
+	digits := 0
+	for i := len(masked) - 1; i >= 0; i-- {
+		if masked[i] < '0' || masked[i] > '9' {
+			continue
+		}
+		digits++
+		if digits > 4 {
+			masked[i] = '*'
+		}
+	}
+	return "tel:" + string(masked)
+}
+
+// maskUserAddress keeps the scheme, the first character of the user part and
+// the host of a mailto or sip URI: "mailto:alice@example.com" becomes
+// "mailto:a***@example.com".
+func maskUserAddress(uri string) string {
+	if uri == "" {
+		return ""
+	}
+	scheme, rest, ok := strings.Cut(uri, ":")
+	if !ok {
+		scheme, rest = "", uri
+	} else {
+		scheme += ":"
+	}
+	user, host, ok := strings.Cut(rest, "@")
+	if !ok {
+		return scheme + "***"
+	}
+	first := ""
+	if user != "" {
+		_, size := utf8.DecodeRuneInString(user)
+		first = user[:size]
+	}
+	return scheme + first + "***@" + host
+}
@@ -1,6 +1,7 @@
 package vcon
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -15,7 +16,7 @@ func TestRedact(t *testing.T) {
 	v.AddDialog(Dialog{
 		Type:      "recording",
 		StartTime: &now,
-		Parties:   []int{0, 1},
+		Parties:   NewPartyRefs(0, 1),
 		Body:      "sensitive-audio-data",
 		Encoding:  "base64url",
 		MediaType: "audio/wav",
@@ -100,3 +101,119 @@ func TestSetRedacted(t *testing.T) {
 		t.Errorf("expected type audio, got %s", v.Redacted.Type)
 	}
 }
+
+func TestRedactWithRules(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice", Tel: "tel:+12025551234;ext=55"})
+	v.AddParty(Party{Name: "Bob", Mailto: "mailto:bob@example.com", Sip: "sip:bob@pbx.example.com"})
+	now := time.Now().UTC()
+	v.AddDialog(Dialog{Type: "recording", StartTime: &now, Parties: NewPartyRefs(0, 1), URL: "https://example.com/a.wav",
+		ContentHash: ContentHashList{ComputeSHA512([]byte("audio"))}})
+	v.AddDialog(Dialog{Type: "text", StartTime: &now, Parties: NewPartyRefs(0, 1), Body: "my card is 4111...", Encoding: "none"})
+	v.AddAttachment(Attachment{Body: "id scan", Encoding: "none", DialogIdx: IntPtr(0), StartTime: now})
+
+	rules := RedactionRules{DropDialogBodies: true, MaskPartyAddresses: true, RemoveAttachments: true}
+	redacted, err := v.RedactWithRules("pii", rules)
+	if err != nil {
+		t.Fatalf("redact error: %v", err)
+	}
+
+	if redacted.Redacted == nil || redacted.Redacted.UUID != v.UUID || redacted.Redacted.Type != "pii" {
+		t.Errorf("unexpected redacted object %+v", redacted.Redacted)
+	}
+	if len(redacted.Dialog) != 2 || redacted.Dialog[1].Type != "text" {
+		t.Fatalf("dialog indices not preserved: %+v", redacted.Dialog)
+	}
+	for i, d := range redacted.Dialog {
+		if d.Body != "" || d.URL != "" || !d.ContentHash.IsEmpty() {
+			t.Errorf("dialog %d still carries content: %+v", i, d)
+		}
+	}
+	if got := redacted.Parties[0].Tel; got != "tel:+*******1234" {
+		t.Errorf("masked tel = %q", got)
+	}
+	if got := redacted.Parties[1].Mailto; got != "mailto:b***@example.com" {
+		t.Errorf("masked mailto = %q", got)
+	}
+	if got := redacted.Parties[1].Sip; got != "sip:b***@pbx.example.com" {
+		t.Errorf("masked sip = %q", got)
+	}
+	if redacted.Parties[1].Name != "Bob" {
+		t.Error("names are not part of the address rule")
+	}
+	if len(redacted.Attachments) != 0 {
+		t.Errorf("attachments not removed: %+v", redacted.Attachments)
+	}
+
+	// The original half of the pair is untouched.
+	if v.Parties[0].Tel != "tel:+12025551234;ext=55" || v.Dialog[1].Body == "" || len(v.Attachments) != 1 {
+		t.Error("original vCon was modified")
+	}
+}
+
+func TestRedactWithRulesAnonymized(t *testing.T) {
+	v := New("example.com")
+	v.Subject = "Refund for Alice Smith"
+	v.AddParty(Party{Name: "Alice Smith", Tel: "tel:+12025551234"})
+	v.AddParty(Party{Name: "Bob", Mailto: "mailto:bob@example.com"})
+	now := time.Now().UTC()
+	v.AddDialog(Dialog{Type: "text", StartTime: &now, Parties: NewPartyRefs(0, 1), MediaType: MIMETypePlainText, Encoding: "none",
+		Body: "Hi, I'm Alice Smith, call me on (202) 555-1234 or mail alice@example.org; card 4111 1111 1111 1111"})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &now, Parties: NewPartyRefs(0, 1), MediaType: "audio/wav", Body: "UklGRg", Encoding: "base64url"})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &now, Parties: NewPartyRefs(0, 1), MediaType: "video/mp4", URL: "https://example.com/call.mp4"})
+	v.AddAnalysis(Analysis{Type: "transcript", Vendor: "acme", Encoding: "json",
+		Body: `{"start":"2025-01-01T10:00:00Z","text":"Bob here, reach me at bob@example.com"}`})
+
+	var transformed string
+	rules := RedactionRules{
+		PseudonymizeParties: true,
+		ScrubText:           true,
+		DropMediaBodies:     true,
+		RemoveAttachments:   true,
+		VoiceTransform: func(media []byte, mediaType string) ([]byte, string, error) {
+			transformed = mediaType
+			return []byte("shifted"), "audio/wav", nil
+		},
+	}
+	redacted, err := v.RedactWithRules("anonymized", rules)
+	if err != nil {
+		t.Fatalf("redact error: %v", err)
+	}
+
+	if !reflect.DeepEqual(redacted.Parties, []Party{{Name: "Party 1"}, {Name: "Party 2"}}) {
+		t.Errorf("parties not pseudonymized: %+v", redacted.Parties)
+	}
+	if redacted.Subject != "Refund for Party 1" {
+		t.Errorf("subject = %q", redacted.Subject)
+	}
+	want := "Hi, I'm Party 1, call me on [PHONE] or mail [EMAIL]; card [NUMBER]"
+	if got := redacted.Dialog[0].Body; got != want {
+		t.Errorf("text body = %q, want %q", got, want)
+	}
+	if transformed != "audio/wav" || redacted.Dialog[1].Body != encodeBase64URL([]byte("shifted")) {
+		t.Errorf("audio not transformed: %+v", redacted.Dialog[1])
+	}
+	if d := redacted.Dialog[2]; d.URL != "" || d.Body != "" {
+		t.Errorf("video not dropped: %+v", d)
+	}
+	want = `{"start":"2025-01-01T10:00:00Z","text":"Party 2 here, reach me at [EMAIL]"}`
+	if got := redacted.Analysis[0].Body; got != want {
+		t.Errorf("analysis body = %s, want %s", got, want)
+	}
+}
+
+func TestPseudonymKey(t *testing.T) {
+	rules := RedactionRules{PseudonymizeParties: true, PseudonymKey: []byte("secret")}
+	alice := Party{Name: "Alice", Tel: "tel:+12025551234"}
+	a, b := rules.pseudonym(0, alice), rules.pseudonym(3, Party{Tel: "tel:+12025551234"})
+	if a != b {
+		t.Errorf("same address gave %q and %q", a, b)
+	}
+	if c := rules.pseudonym(0, Party{Tel: "tel:+12025559999"}); c == a {
+		t.Error("different addresses share a pseudonym")
+	}
+	other := RedactionRules{PseudonymKey: []byte("other")}
+	if other.pseudonym(0, alice) == a {
+		t.Error("pseudonym does not depend on the key")
+	}
+}
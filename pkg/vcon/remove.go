@@ -0,0 +1,225 @@
+package vcon
+
+import "fmt"
+
+// RemoveParty removes the party at index i and renumbers every reference to
+// the parties after it. References to the removed party are dropped: it
+// leaves dialog party lists and transfer targets, originator, transferee and
+// transferor are cleared, and its party_history events and the attachments
+// it contributed, other than the tags, are removed.
+func (v *VCon) RemoveParty(i int) error {
+	if i < 0 || i >= len(v.Parties) {
+		return fmt.Errorf("party index %d out of range", i)
+	}
+	f := removedIndex(i)
+	v.Parties = append(v.Parties[:i], v.Parties[i+1:]...)
+
+	for j := range v.Dialog {
+		d := &v.Dialog[j]
+		d.Parties = dropPartyRefs(d.Parties, f)
+		d.Originator = dropIndex(d.Originator, f)
+		d.Transferee = dropIndex(d.Transferee, f)
+		d.Transferor = dropIndex(d.Transferor, f)
+		d.TransferTarget = dropRefs(d.TransferTarget, f)
+		var history []PartyHistory
+		for _, h := range d.PartyHistory {
+			if h.Party = f(h.Party); h.Party >= 0 {
+				history = append(history, h)
+			}
+		}
+		d.PartyHistory = history
+	}
+	v.removeAttachments(func(a *Attachment) bool {
+		if a.isTags() {
+			return false
+		}
+		a.PartyIdx = f(a.PartyIdx)
+		return a.PartyIdx < 0
+	})
+	return nil
+}
+
+// RemoveDialog removes the dialog at index i and renumbers every reference
+// to the dialogs after it. References to the removed dialog are dropped from
+// analysis and from the transfer fields of other dialogs; analysis left
+// without a dialog and the attachments of the removed dialog, other than the
+// tags, are removed.
+func (v *VCon) RemoveDialog(i int) error {
+	if i < 0 || i >= len(v.Dialog) {
+		return fmt.Errorf("dialog index %d out of range", i)
+	}
+	f := removedIndex(i)
+	v.Dialog = append(v.Dialog[:i], v.Dialog[i+1:]...)
+
+	for j := range v.Dialog {
+		d := &v.Dialog[j]
+		d.Original = dropRefs(d.Original, f)
+		d.Consultation = dropRefs(d.Consultation, f)
+		d.TargetDialog = dropRefs(d.TargetDialog, f)
+	}
+	var analysis []Analysis
+	for _, a := range v.Analysis {
+		if a.Dialog == nil {
+			analysis = append(analysis, a)
+			continue
+		}
+		switch x := remapIndices(a.Dialog, f).(type) {
+		case int:
+			if x < 0 {
+				continue
+			}
+			a.Dialog = x
+		case []int:
+			var kept []int
+			for _, n := range x {
+				if n >= 0 {
+					kept = append(kept, n)
+				}
+			}
+			if len(kept) == 0 {
+				continue
+			}
+			a.Dialog = kept
+		}
+		analysis = append(analysis, a)
+	}
+	v.Analysis = analysis
+	v.removeAttachments(func(a *Attachment) bool {
+		if a.isTags() {
+			return false
+		}
+		a.DialogIdx = remapIndex(a.DialogIdx, f)
+		return a.DialogIdx != nil && *a.DialogIdx < 0
+	})
+	return nil
+}
+
+// RemoveAttachment removes the attachment at index i. When other
+// attachments refer to its content by hash, as left by DedupeAttachments,
+// the first of them receives the body.
+func (v *VCon) RemoveAttachment(i int) error {
+	if i < 0 || i >= len(v.Attachments) {
+		return fmt.Errorf("attachment index %d out of range", i)
+	}
+	j := 0
+	v.removeAttachments(func(*Attachment) bool {
+		j++
+		return j-1 == i
+	})
+	return nil
+}
+
+// removeAttachments removes the attachments for which drop, which may also
+// update them, returns true. Content referenced by a remaining attachment
+// moves to the first such reference.
+func (v *VCon) removeAttachments(drop func(*Attachment) bool) {
+	var kept, removed []Attachment
+	for _, a := range v.Attachments {
+		if drop(&a) {
+			removed = append(removed, a)
+		} else {
+			kept = append(kept, a)
+		}
+	}
+	for _, r := range removed {
+		if r.Body == "" || r.URL != "" {
+			continue
+		}
+		data, err := decodeInlineBody(r.Body, r.Encoding)
+		if err != nil {
+			continue
+		}
+		for k := range kept {
+			if kept[k].IsReference() && kept[k].ContentHash.Verify(data) {
+				kept[k].Body, kept[k].Encoding = r.Body, r.Encoding
+				break
+			}
+		}
+	}
+	v.Attachments = kept
+}
+
+// removedIndex maps indices to their position once entry i is removed, and
+// i itself to -1.
+func removedIndex(i int) func(int) int {
+	return func(n int) int {
+		switch {
+		case n == i:
+			return -1
+		case n > i:
+			return n - 1
+		}
+		return n
+	}
+}
+
+// dropIndex renumbers idx with f, clearing it when f maps it to -1.
+func dropIndex(idx *int, f func(int) int) *int {
+	if idx = remapIndex(idx, f); idx != nil && *idx < 0 {
+		return nil
+	}
+	return idx
+}
+
+// dropRefs renumbers references with f, dropping the ones it maps to -1.
+func dropRefs(ref *IntOrSlice, f func(int) int) *IntOrSlice {
+	if ref == nil || ref.IsZero() {
+		return ref
+	}
+	var kept []int
+	for _, i := range ref.AsSlice() {
+		if n := f(i); n >= 0 {
+			kept = append(kept, n)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	if _, single := ref.AsInt(); single {
+		return NewIntValue(kept[0])
+	}
+	return NewIntSliceValue(kept)
+}
+
+// dropPartyRefs renumbers party references with f, dropping the ones it
+// maps to -1 along with lists they leave empty.
+func dropPartyRefs(p *PartyRefs, f func(int) int) *PartyRefs {
+	if p.IsZero() {
+		return p
+	}
+	keep := func(in []int) []int {
+		out := []int{}
+		for _, n := range in {
+			if n = f(n); n >= 0 {
+				out = append(out, n)
+			}
+		}
+		return out
+	}
+	switch x := p.value.(type) {
+	case int:
+		if n := f(x); n >= 0 {
+			return NewPartyRef(n)
+		}
+		return nil
+	case []int:
+		return &PartyRefs{value: keep(x)}
+	case []any:
+		out := []any{}
+		for _, item := range x {
+			switch y := item.(type) {
+			case int:
+				if n := f(y); n >= 0 {
+					out = append(out, n)
+				}
+			case []int:
+				if kept := keep(y); len(kept) > 0 {
+					out = append(out, kept)
+				}
+			}
+		}
+		refs, _ := PartyRefsOf(out)
+		return refs
+	}
+	return p
+}
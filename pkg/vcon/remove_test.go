@@ -0,0 +1,108 @@
+package vcon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func removeFixture() *VCon {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	v.AddParty(Party{Tel: "tel:+15551230001", Name: "Alice"})
+	v.AddParty(Party{Tel: "tel:+15551230002", Name: "Bob"})
+	v.AddParty(Party{Tel: "tel:+15551230003", Name: "Carol"})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(0, 1), Originator: IntPtr(0),
+		PartyHistory: []PartyHistory{{Party: 0, Event: "join", Time: start}, {Party: 1, Event: "join", Time: start}}})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Parties: NewPartyRefs(1, 2), Originator: IntPtr(2)})
+	v.AddDialog(Dialog{Type: "transfer", StartTime: &start, Transferee: IntPtr(0), Transferor: IntPtr(1), TransferTarget: NewIntValue(2),
+		Original: NewIntValue(0), TargetDialog: NewIntValue(1)})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: 0})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: []int{0, 1}})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(0), PartyIdx: 0, StartTime: start, Body: "notes", Encoding: "none"})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(1), PartyIdx: 2, StartTime: start, Body: "slides", Encoding: "none"})
+	return v
+}
+
+func TestRemoveParty(t *testing.T) {
+	v := removeFixture()
+
+	require.NoError(t, v.RemoveParty(0))
+	require.NoError(t, v.Validate())
+
+	require.Len(t, v.Parties, 2)
+	assert.Equal(t, "Bob", v.Parties[0].Name)
+	assert.Equal(t, []int{0}, v.Dialog[0].Parties.Indices())
+	assert.Nil(t, v.Dialog[0].Originator)
+	require.Len(t, v.Dialog[0].PartyHistory, 1)
+	assert.Equal(t, 0, v.Dialog[0].PartyHistory[0].Party)
+	assert.Equal(t, []int{0, 1}, v.Dialog[1].Parties.Indices())
+	assert.Equal(t, IntPtr(1), v.Dialog[1].Originator)
+	assert.Nil(t, v.Dialog[2].Transferee)
+	assert.Equal(t, IntPtr(0), v.Dialog[2].Transferor)
+	assert.Equal(t, NewIntValue(1), v.Dialog[2].TransferTarget)
+
+	require.Len(t, v.Attachments, 1, "Alice's attachment goes with her")
+	assert.Equal(t, 1, v.Attachments[0].PartyIdx)
+
+	assert.Error(t, v.RemoveParty(2))
+}
+
+func TestRemovePartyNestedRefs(t *testing.T) {
+	v := New("example.com")
+	for range 3 {
+		v.AddParty(Party{})
+	}
+	refs, err := PartyRefsOf([]any{0, []int{1, 2}, []int{1}})
+	require.NoError(t, err)
+	v.AddDialog(Dialog{Type: "text", Parties: refs})
+
+	require.NoError(t, v.RemoveParty(1))
+	data, err := v.Dialog[0].Parties.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[0, [1]]`, string(data))
+}
+
+func TestRemoveDialog(t *testing.T) {
+	v := removeFixture()
+
+	require.NoError(t, v.RemoveDialog(0))
+	require.NoError(t, v.Validate())
+
+	require.Len(t, v.Dialog, 2)
+	assert.Nil(t, v.Dialog[1].Original)
+	assert.Equal(t, NewIntValue(0), v.Dialog[1].TargetDialog)
+
+	require.Len(t, v.Analysis, 1, "the analysis of the removed dialog alone goes with it")
+	assert.Equal(t, []int{0}, v.Analysis[0].Dialog)
+
+	require.Len(t, v.Attachments, 1)
+	assert.Equal(t, "slides", v.Attachments[0].Body)
+	assert.Equal(t, 0, *v.Attachments[0].DialogIdx)
+
+	assert.Error(t, v.RemoveDialog(-1))
+}
+
+func TestRemoveAttachmentKeepsReferencedContent(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	v.AddParty(Party{})
+	v.AddParty(Party{})
+	v.AddDialog(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0, 1)})
+	for p := range 2 {
+		v.AddAttachment(Attachment{DialogIdx: IntPtr(0), PartyIdx: p, StartTime: start, Body: "c2hhcmVk", Encoding: "base64url"})
+	}
+	require.Equal(t, 1, v.DedupeAttachments())
+	require.True(t, v.Attachments[1].IsReference())
+
+	require.NoError(t, v.RemoveAttachment(0))
+	require.Len(t, v.Attachments, 1)
+	content, err := v.AttachmentContent(0)
+	require.NoError(t, err)
+	assert.Equal(t, "shared", string(content))
+	assert.Equal(t, 1, v.Attachments[0].PartyIdx)
+
+	assert.Error(t, v.RemoveAttachment(1))
+}
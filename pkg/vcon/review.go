@@ -0,0 +1,114 @@
+package vcon
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Keys under Analysis.Meta used by the confidence and review helpers.
+const (
+	ConfidenceMetaKey = "confidence"
+	ReviewMetaKey     = "review"
+)
+
+// ReviewStatus is the human-in-the-loop status of an analysis.
+type ReviewStatus string
+
+const (
+	// ReviewAuto marks machine output nobody has checked yet.
+	ReviewAuto ReviewStatus = "auto"
+	// ReviewHumanReviewed marks output confirmed by a reviewer.
+	ReviewHumanReviewed ReviewStatus = "human-reviewed"
+	// ReviewDisputed marks output a reviewer disagrees with.
+	ReviewDisputed ReviewStatus = "disputed"
+)
+
+// ReviewStatuses lists the valid review statuses.
+var ReviewStatuses = []ReviewStatus{ReviewAuto, ReviewHumanReviewed, ReviewDisputed}
+
+// ParseReviewStatus validates s as a review status.
+func ParseReviewStatus(s string) (ReviewStatus, error) {
+	for _, st := range ReviewStatuses {
+		if string(st) == s {
+			return st, nil
+		}
+	}
+	return "", fmt.Errorf("invalid review status %q (want auto, human-reviewed or disputed)", s)
+}
+
+// Review records the review status of an analysis and who set it.
+type Review struct {
+	Status     ReviewStatus `json:"status"`
+	Reviewer   string       `json:"reviewer,omitempty"`
+	ReviewedAt time.Time    `json:"reviewed_at,omitzero"`
+	Note       string       `json:"note,omitempty"`
+}
+
+// SetConfidence stores a confidence score between 0 and 1.
+func (a *Analysis) SetConfidence(score float64) error {
+	if score < 0 || score > 1 {
+		return fmt.Errorf("confidence %v out of range [0, 1]", score)
+	}
+	a.setMeta(ConfidenceMetaKey, score)
+	return nil
+}
+
+// Confidence returns the confidence score; ok is false when none is set.
+func (a *Analysis) Confidence() (score float64, ok bool) {
+	score, ok = a.Meta[ConfidenceMetaKey].(float64)
+	return score, ok
+}
+
+// SetReview stores the review status, stamping ReviewedAt when it is zero.
+func (a *Analysis) SetReview(r Review) error {
+	if _, err := ParseReviewStatus(string(r.Status)); err != nil {
+		return err
+	}
+	if r.ReviewedAt.IsZero() {
+		r.ReviewedAt = time.Now().UTC()
+	}
+	a.setMeta(ReviewMetaKey, r)
+	return nil
+}
+
+// Review returns the recorded review, or nil when the analysis has none.
+func (a *Analysis) Review() (*Review, error) {
+	var r Review
+	if ok, err := a.metaValue(ReviewMetaKey, &r); !ok || err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ReviewStatus returns the review status, ReviewAuto when none is recorded.
+func (a *Analysis) ReviewStatus() ReviewStatus {
+	if r, err := a.Review(); err == nil && r != nil {
+		return r.Status
+	}
+	return ReviewAuto
+}
+
+func (a *Analysis) setMeta(key string, value interface{}) {
+	if a.Meta == nil {
+		a.Meta = map[string]interface{}{}
+	}
+	a.Meta[key] = value
+}
+
+// metaValue decodes Meta[key] into dst, which works both for values set in
+// memory and for the generic maps produced by decoding JSON.
+func (a *Analysis) metaValue(key string, dst interface{}) (bool, error) {
+	raw, ok := a.Meta[key]
+	if !ok {
+		return false, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return false, fmt.Errorf("invalid analysis %s: %w", key, err)
+	}
+	return true, nil
+}
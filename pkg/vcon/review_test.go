@@ -0,0 +1,38 @@
+package vcon
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalysisReview(t *testing.T) {
+	a := Analysis{Type: "sentiment", Vendor: "acme"}
+	assert.Equal(t, ReviewAuto, a.ReviewStatus())
+	_, ok := a.Confidence()
+	assert.False(t, ok)
+
+	require.NoError(t, a.SetConfidence(0.82))
+	require.NoError(t, a.SetReview(Review{Status: ReviewDisputed, Reviewer: "qa@example.com", Note: "wrong speaker"}))
+	assert.Error(t, a.SetConfidence(1.5))
+	assert.Error(t, a.SetReview(Review{Status: "approved"}))
+
+	// Both survive a JSON round trip.
+	data, err := json.Marshal(a)
+	require.NoError(t, err)
+	var loaded Analysis
+	require.NoError(t, json.Unmarshal(data, &loaded))
+
+	score, ok := loaded.Confidence()
+	assert.True(t, ok)
+	assert.Equal(t, 0.82, score)
+	r, err := loaded.Review()
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, ReviewDisputed, r.Status)
+	assert.Equal(t, "qa@example.com", r.Reviewer)
+	assert.False(t, r.ReviewedAt.IsZero())
+	assert.Equal(t, ReviewDisputed, loaded.ReviewStatus())
+}
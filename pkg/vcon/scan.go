@@ -0,0 +1,60 @@
+package vcon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Scanner inspects content, such as an email attachment, before it is
+// embedded in a vCon. Scan returns a *ThreatError when the content is
+// malicious and any other error when it could not be scanned.
+// ClamdScanner is the ClamAV implementation.
+type Scanner interface {
+	Scan(ctx context.Context, name string, content []byte) error
+}
+
+// ThreatError reports content a Scanner found to be malicious.
+type ThreatError struct {
+	Name   string // file name or other label of the content
+	Threat string // signature name reported by the scanner
+}
+
+func (e *ThreatError) Error() string {
+	return fmt.Sprintf("%s: %s found", e.Name, e.Threat)
+}
+
+// AttachmentThreat is an attachment flagged by ScanAttachments.
+type AttachmentThreat struct {
+	Index  int
+	Threat string
+}
+
+// ScanAttachments passes the inline content of every attachment to s and
+// returns the ones found to be malicious. References left by
+// DedupeAttachments and attachments held at a URL are not scanned, as
+// their content is not embedded. The error is only set when scanning
+// itself fails.
+func (v *VCon) ScanAttachments(ctx context.Context, s Scanner) ([]AttachmentThreat, error) {
+	var threats []AttachmentThreat
+	for i, att := range v.Attachments {
+		if att.URL != "" || att.IsReference() {
+			continue
+		}
+		content, err := decodeInlineBody(att.Body, att.Encoding)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %d: %w", i, err)
+		}
+		name := att.Filename
+		if name == "" {
+			name = fmt.Sprintf("attachment %d", i)
+		}
+		var threat *ThreatError
+		if err := s.Scan(ctx, name, content); errors.As(err, &threat) {
+			threats = append(threats, AttachmentThreat{Index: i, Threat: threat.Threat})
+		} else if err != nil {
+			return nil, fmt.Errorf("scan attachment %d: %w", i, err)
+		}
+	}
+	return threats, nil
+}
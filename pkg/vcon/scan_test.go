@@ -0,0 +1,104 @@
+package vcon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClamd answers INSTREAM requests like clamd, flagging content that
+// contains "EICAR".
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var content []byte
+				for {
+					var n uint32
+					if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+						return
+					}
+					if n == 0 {
+						break
+					}
+					chunk := make([]byte, n)
+					if _, err := io.ReadFull(r, chunk); err != nil {
+						return
+					}
+					content = append(content, chunk...)
+				}
+				if bytes.Contains(content, []byte("EICAR")) {
+					conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+				} else {
+					conn.Write([]byte("stream: OK\x00"))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestClamdScanner(t *testing.T) {
+	s := NewClamdScanner(fakeClamd(t))
+	assert.Equal(t, "tcp", s.Network)
+	ctx := context.Background()
+
+	assert.NoError(t, s.Scan(ctx, "notes.txt", []byte("hello")))
+
+	// Content larger than one chunk is streamed in several.
+	big := append(bytes.Repeat([]byte("x"), clamdChunkSize+10), "EICAR"...)
+	err := s.Scan(ctx, "invoice.pdf", big)
+	var threat *ThreatError
+	require.True(t, errors.As(err, &threat), "got %v", err)
+	assert.Equal(t, &ThreatError{Name: "invoice.pdf", Threat: "Eicar-Test-Signature"}, threat)
+	assert.Equal(t, "invoice.pdf: Eicar-Test-Signature found", err.Error())
+
+	assert.Equal(t, "unix", NewClamdScanner("/run/clamav/clamd.ctl").Network)
+	down := &ClamdScanner{Network: "tcp", Address: "127.0.0.1:1", Timeout: time.Second}
+	err = down.Scan(ctx, "a", nil)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &threat))
+
+	assert.EqualError(t, parseClamdReply("a", "INSTREAM size limit exceeded. ERROR\x00"), "clamd: INSTREAM size limit exceeded. ERROR")
+}
+
+func TestScanAttachments(t *testing.T) {
+	now := time.Now().UTC()
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.AddDialog(Dialog{Type: "text", StartTime: &now, Parties: NewPartyRefs(0), Body: "hi", Encoding: "none"})
+	v.AddAttachment(Attachment{Filename: "clean.txt", Body: encodeBase64URL([]byte("fine")), Encoding: "base64url", DialogIdx: IntPtr(0), StartTime: now})
+	v.AddAttachment(Attachment{Filename: "bad.exe", Body: encodeBase64URL([]byte("EICAR")), Encoding: "base64url", DialogIdx: IntPtr(0), StartTime: now})
+	v.AddAttachment(Attachment{URL: "https://example.com/EICAR", DialogIdx: IntPtr(0), StartTime: now})
+	v.AddAttachment(Attachment{Body: "EICAR too", Encoding: "none", DialogIdx: IntPtr(0), StartTime: now})
+
+	threats, err := v.ScanAttachments(context.Background(), NewClamdScanner(fakeClamd(t)))
+	require.NoError(t, err)
+	assert.Equal(t, []AttachmentThreat{{Index: 1, Threat: "Eicar-Test-Signature"}, {Index: 3, Threat: "Eicar-Test-Signature"}}, threats)
+
+	_, err = v.ScanAttachments(context.Background(), &ClamdScanner{Network: "tcp", Address: "127.0.0.1:1"})
+	assert.Error(t, err)
+}
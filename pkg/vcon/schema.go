@@ -0,0 +1,164 @@
+package vcon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// EmbeddedSchemaID is the $id of the embedded vCon schema. Profile schemas
+// passed to CompileSchema or LoadSchema can "$ref" it to add constraints to
+// the spec rather than restate it.
+const EmbeddedSchemaID = "https://ietf.org/vcon/schemas/unsigned-vcon.json"
+
+var (
+	embeddedSchemaOnce sync.Once
+	embeddedSchema     *jsonschema.Schema
+	embeddedSchemaErr  error
+)
+
+// EmbeddedSchema returns the compiled schema of the vCon draft this library
+// targets. BuildFromJSON checks every document against it.
+func EmbeddedSchema() (*jsonschema.Schema, error) {
+	embeddedSchemaOnce.Do(func() {
+		embeddedSchema, embeddedSchemaErr = newSchemaCompiler().Compile(EmbeddedSchemaID)
+	})
+	return embeddedSchema, embeddedSchemaErr
+}
+
+// CompileSchema compiles a JSON Schema document, such as an organization's
+// profile of the spec. References to EmbeddedSchemaID resolve to the
+// embedded schema and http(s) references are fetched with the configured
+// HTTP client.
+func CompileSchema(doc []byte) (*jsonschema.Schema, error) {
+	data, err := jsonschema.UnmarshalJSON(bytes.NewReader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	const loc = "urn:go-vcon:schema"
+	compiler := newSchemaCompiler()
+	if err := compiler.AddResource(loc, data); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(loc)
+}
+
+// LoadSchema compiles the JSON Schema at location, an http(s) URL or a file
+// path, resolving references as CompileSchema does.
+func LoadSchema(location string) (*jsonschema.Schema, error) {
+	return newSchemaCompiler().Compile(location)
+}
+
+// newSchemaCompiler returns a draft-07 compiler that knows the embedded
+// schema and loads files and http(s) URLs.
+func newSchemaCompiler() *jsonschema.Compiler {
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft7)
+	// Override the default email format validator to also accept mailto: URIs,
+	// since the vCon spec defines the mailto field as a MAILTO URL (RFC 6068).
+	compiler.RegisterFormat(&jsonschema.Format{
+		Name: "email",
+		Validate: func(v interface{}) error {
+			s, ok := v.(string)
+			if !ok {
+				return nil
+			}
+			s = strings.TrimPrefix(s, "mailto:")
+			parts := strings.SplitN(s, "@", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return fmt.Errorf("invalid email: %s", v)
+			}
+			return nil
+		},
+	})
+	compiler.UseLoader(jsonschema.SchemeURLLoader{
+		"file":  jsonschema.FileLoader{},
+		"http":  httpSchemaLoader{},
+		"https": httpSchemaLoader{},
+	})
+
+	// The embedded schema is valid JSON, so neither call can fail.
+	data, _ := jsonschema.UnmarshalJSON(bytes.NewReader(vconSchema))
+	_ = compiler.AddResource(EmbeddedSchemaID, data)
+	return compiler
+}
+
+// httpSchemaLoader fetches schemas with the HTTP client installed by
+// SetHTTPConfig.
+type httpSchemaLoader struct{}
+
+func (httpSchemaLoader) Load(u string) (any, error) {
+	content, err := (&HTTPFetcher{}).Fetch(context.Background(), u)
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(content.Body))
+}
+
+func validateAgainstSchema(rawMap map[string]interface{}) error {
+	schema, err := EmbeddedSchema()
+	if err != nil {
+		return err
+	}
+	if err := schema.Validate(rawMap); err != nil {
+		return fmt.Errorf("schema validation failed: %w", err)
+	}
+	return nil
+}
+
+// WithSchema makes Validate and IsValid also check the vCon against schema,
+// for example a profile compiled with CompileSchema or LoadSchema, and
+// report each violation as an error. EmbeddedSchema checks it against the
+// spec.
+func WithSchema(schema *jsonschema.Schema) ValidateOption {
+	return func(c *validateConfig) {
+		c.schema = schema
+	}
+}
+
+// validateSchema checks v, as it would be encoded, against schema.
+func (v *VCon) validateSchema(schema *jsonschema.Schema) []ValidationIssue {
+	if schema == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return []ValidationIssue{issuef("", IssueSchemaViolation, "cannot encode vCon: %v", err)}
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return []ValidationIssue{issuef("", IssueSchemaViolation, "cannot encode vCon: %v", err)}
+	}
+	var verr *jsonschema.ValidationError
+	if err := schema.Validate(doc); !errors.As(err, &verr) {
+		return nil
+	}
+
+	// Report the leaves only, not the allOf and $ref units wrapping them.
+	units := verr.BasicOutput().Errors
+	isParent := func(u jsonschema.OutputUnit) bool {
+		return slices.ContainsFunc(units, func(w jsonschema.OutputUnit) bool {
+			return strings.HasPrefix(w.KeywordLocation, u.KeywordLocation+"/")
+		})
+	}
+	var issues []ValidationIssue
+	for _, unit := range units {
+		if unit.Error == nil || isParent(unit) {
+			continue
+		}
+		path := unit.InstanceLocation
+		display := path
+		if display == "" {
+			display = "/"
+		}
+		issues = append(issues, issuef(path, IssueSchemaViolation, "%s: %s", display, unit.Error))
+	}
+	return issues
+}
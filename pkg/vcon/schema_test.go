@@ -0,0 +1,83 @@
+package vcon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// profileSchema extends the spec with a required subject and a tenant
+// property on every party.
+const profileSchema = `{
+	"allOf": [
+		{"$ref": "https://ietf.org/vcon/schemas/unsigned-vcon.json"},
+		{
+			"required": ["subject"],
+			"properties": {
+				"parties": {"items": {"required": ["x_tenant"]}}
+			}
+		}
+	]
+}`
+
+func schemaTestVCon() *VCon {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice", Tel: "tel:+12025551234"})
+	now := time.Now().UTC()
+	v.AddDialog(Dialog{Type: "text", StartTime: &now, Parties: NewPartyRefs(0), Body: "hi", Encoding: "none", MediaType: "text/plain"})
+	return v
+}
+
+func TestValidateWithSchema(t *testing.T) {
+	embedded, err := EmbeddedSchema()
+	require.NoError(t, err)
+	v := schemaTestVCon()
+	assert.NoError(t, v.Validate(WithSchema(embedded)))
+
+	profile, err := CompileSchema([]byte(profileSchema))
+	require.NoError(t, err)
+	ok, issues := v.IsValid(WithSchema(profile))
+	assert.False(t, ok)
+	paths := map[string]bool{}
+	for _, issue := range issues {
+		assert.Equal(t, IssueSchemaViolation, issue.Code)
+		paths[issue.Path] = true
+	}
+	assert.True(t, paths[""], "missing subject: %v", issues)
+	assert.True(t, paths["/parties/0"], "missing x_tenant: %v", issues)
+
+	v.Subject = "Billing question"
+	require.NoError(t, v.Parties[0].Extra.Set("x_tenant", "blue"))
+	assert.NoError(t, v.Validate(WithSchema(profile)))
+
+	// Without WithSchema the profile is not enforced.
+	v.Subject = ""
+	assert.NoError(t, v.Validate())
+
+	_, err = CompileSchema([]byte("{"))
+	assert.Error(t, err)
+}
+
+func TestLoadSchema(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "profile.json")
+	require.NoError(t, os.WriteFile(file, []byte(profileSchema), 0o644))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(profileSchema))
+	}))
+	t.Cleanup(srv.Close)
+
+	for _, location := range []string{file, srv.URL + "/profile.json"} {
+		schema, err := LoadSchema(location)
+		require.NoError(t, err, location)
+		assert.Error(t, schemaTestVCon().Validate(WithSchema(schema)), location)
+	}
+
+	_, err := LoadSchema(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
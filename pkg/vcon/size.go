@@ -0,0 +1,119 @@
+package vcon
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SizeReport describes the serialized size of a vCon.
+type SizeReport struct {
+	// Total is the size in bytes of the compact JSON encoding.
+	Total int `json:"total"`
+	// Sections maps each top-level property to the size of its value.
+	Sections map[string]int `json:"sections"`
+	// InlineBodies lists the inline dialog, attachment and analysis bodies,
+	// largest first.
+	InlineBodies []InlineBodySize `json:"inline_bodies,omitempty"`
+}
+
+// InlineBodySize is the size of one inline body, e.g. Path "dialog[2]".
+type InlineBodySize struct {
+	Path  string `json:"path"`
+	Bytes int    `json:"bytes"`
+}
+
+// Size reports the total serialized size of the vCon with a per-section
+// breakdown.
+func (v *VCon) Size() (SizeReport, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return SizeReport{}, fmt.Errorf("failed to marshal VCon: %w", err)
+	}
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return SizeReport{}, err
+	}
+
+	report := SizeReport{Total: len(data), Sections: make(map[string]int, len(sections))}
+	for k, raw := range sections {
+		report.Sections[k] = len(raw)
+	}
+	report.InlineBodies = v.inlineBodies()
+	return report, nil
+}
+
+func (v *VCon) inlineBodies() []InlineBodySize {
+	var bodies []InlineBodySize
+	add := func(section string, i int, url, body string) {
+		if url == "" && body != "" {
+			bodies = append(bodies, InlineBodySize{Path: fmt.Sprintf("%s[%d]", section, i), Bytes: len(body)})
+		}
+	}
+	for i, d := range v.Dialog {
+		add("dialog", i, d.URL, d.Body)
+	}
+	for i, a := range v.Attachments {
+		add("attachments", i, a.URL, a.Body)
+	}
+	for i, a := range v.Analysis {
+		add("analysis", i, a.URL, a.Body)
+	}
+	sort.SliceStable(bodies, func(i, j int) bool { return bodies[i].Bytes > bodies[j].Bytes })
+	return bodies
+}
+
+// Limits bounds the size of a vCon. Zero values mean no limit.
+type Limits struct {
+	// MaxInlineBodyBytes bounds each inline dialog, attachment or analysis
+	// body.
+	MaxInlineBodyBytes int
+	// MaxVConBytes bounds the compact JSON encoding of the whole vCon.
+	MaxVConBytes int
+}
+
+// LimitError lists every limit a vCon exceeds.
+type LimitError struct {
+	Violations []string
+}
+
+func (e *LimitError) Error() string {
+	return "vCon exceeds size limits: " + strings.Join(e.Violations, "; ")
+}
+
+// CheckLimits returns a *LimitError when the vCon exceeds l. The messages
+// name the offending bodies and suggest moving them out-of-band.
+func (v *VCon) CheckLimits(l Limits) error {
+	if l.MaxInlineBodyBytes <= 0 && l.MaxVConBytes <= 0 {
+		return nil
+	}
+	report, err := v.Size()
+	if err != nil {
+		return err
+	}
+
+	var violations []string
+	if l.MaxInlineBodyBytes > 0 {
+		for _, b := range report.InlineBodies {
+			if b.Bytes > l.MaxInlineBodyBytes {
+				violations = append(violations, fmt.Sprintf(
+					"%s inline body is %d bytes, over the %d byte limit; store it externally and reference it with url and content_hash",
+					b.Path, b.Bytes, l.MaxInlineBodyBytes))
+			}
+		}
+	}
+	if l.MaxVConBytes > 0 && report.Total > l.MaxVConBytes {
+		msg := fmt.Sprintf("vCon is %d bytes, over the %d byte limit", report.Total, l.MaxVConBytes)
+		if len(report.InlineBodies) > 0 {
+			largest := report.InlineBodies[0]
+			msg += fmt.Sprintf("; externalize inline bodies, starting with %s (%d bytes)", largest.Path, largest.Bytes)
+		}
+		violations = append(violations, msg)
+	}
+
+	if len(violations) > 0 {
+		return &LimitError{Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package vcon
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sizedVCon() *VCon {
+	now := time.Now().UTC()
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.AddDialog(Dialog{Type: "text", StartTime: &now, Parties: NewPartyRefs(0), Body: strings.Repeat("a", 200), Encoding: "none"})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &now, Parties: NewPartyRefs(0), URL: "https://example.com/r.wav"})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Body: strings.Repeat("b", 50), Encoding: "none"})
+	return v
+}
+
+func TestSize(t *testing.T) {
+	v := sizedVCon()
+	report, err := v.Size()
+	require.NoError(t, err)
+
+	data, _ := json.Marshal(v)
+	assert.Equal(t, len(data), report.Total)
+	assert.Greater(t, report.Sections["dialog"], 200)
+	assert.Contains(t, report.Sections, "parties")
+	assert.Equal(t, []InlineBodySize{{"dialog[0]", 200}, {"analysis[0]", 50}}, report.InlineBodies)
+}
+
+func TestCheckLimits(t *testing.T) {
+	v := sizedVCon()
+	assert.NoError(t, v.CheckLimits(Limits{}))
+	assert.NoError(t, v.CheckLimits(Limits{MaxInlineBodyBytes: 200, MaxVConBytes: 1 << 20}))
+
+	err := v.CheckLimits(Limits{MaxInlineBodyBytes: 100, MaxVConBytes: 100})
+	var limitErr *LimitError
+	require.True(t, errors.As(err, &limitErr), "got %v", err)
+	require.Len(t, limitErr.Violations, 2)
+	assert.Contains(t, limitErr.Violations[0], "dialog[0]")
+	assert.Contains(t, limitErr.Violations[0], "store it externally")
+	assert.Contains(t, limitErr.Violations[1], "starting with dialog[0]")
+}
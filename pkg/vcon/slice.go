@@ -0,0 +1,203 @@
+package vcon
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SliceRedactionType is the redaction type of the vCons produced by Slice.
+const SliceRedactionType = "slice"
+
+// MediaTrimmer cuts media to the part that starts offset into it and lasts
+// length, for instance with ffmpeg, and returns it with its media type.
+type MediaTrimmer func(media []byte, mediaType string, offset, length time.Duration) ([]byte, string, error)
+
+// SliceOption configures Slice.
+type SliceOption func(*sliceConfig)
+
+type sliceConfig struct {
+	trim   MediaTrimmer
+	redact []RedactOption
+}
+
+// WithMediaTrimmer trims the media of recordings that straddle the window
+// with trim, so they hold only the excerpt; their start and duration are
+// adjusted and the result is stored inline. Without it such recordings are
+// kept whole.
+func WithMediaTrimmer(trim MediaTrimmer) SliceOption {
+	return func(c *sliceConfig) {
+		c.trim = trim
+	}
+}
+
+// WithSliceRedactOptions configures the redacted object pointing at the
+// original vCon, e.g. with WithRedactedURL.
+func WithSliceRedactOptions(opts ...RedactOption) SliceOption {
+	return func(c *sliceConfig) {
+		c.redact = append(c.redact, opts...)
+	}
+}
+
+// Slice returns a copy of v holding only what happened from start up to
+// end, to share the relevant excerpt of a long conversation. It is a
+// redaction of v with type SliceRedactionType.
+//
+// Dialogs overlapping the window are kept and renumbered; their
+// party_history keeps the events inside it. Attachments follow their
+// dialog, and analysis is kept when every dialog it covers is, even though
+// it may describe the whole dialog. Parties are all kept, so party indices
+// do not change.
+func (v *VCon) Slice(start, end time.Time, opts ...SliceOption) (*VCon, error) {
+	return v.SliceContext(context.Background(), start, end, opts...)
+}
+
+// SliceContext is Slice with a context controlling the fetches of external
+// media trimmed by WithMediaTrimmer.
+func (v *VCon) SliceContext(ctx context.Context, start, end time.Time, opts ...SliceOption) (*VCon, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("slice end %s is not after its start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+	cfg := &sliceConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return v.Redact(SliceRedactionType, func(c *VCon) error {
+		return c.slice(ctx, start, end, cfg)
+	}, cfg.redact...)
+}
+
+// slice removes from v everything outside [start, end).
+func (v *VCon) slice(ctx context.Context, start, end time.Time, cfg *sliceConfig) error {
+	dialogMap := make([]int, len(v.Dialog))
+	var dialogs []Dialog
+	for i, d := range v.Dialog {
+		dialogMap[i] = -1
+		if d.StartTime == nil || !overlaps(*d.StartTime, dialogEnd(&d), start, end) {
+			continue
+		}
+		if cfg.trim != nil && d.Duration > 0 && (d.StartTime.Before(start) || dialogEnd(&d).After(end)) {
+			if err := trimDialog(ctx, &d, pickFetcher(d.fetcher, v.contentFetcher()), start, end, cfg.trim); err != nil {
+				return fmt.Errorf("trim dialog %d: %w", i, err)
+			}
+		}
+		var history []PartyHistory
+		for _, h := range d.PartyHistory {
+			if !h.Time.Before(start) && h.Time.Before(end) {
+				history = append(history, h)
+			}
+		}
+		d.PartyHistory = history
+		dialogMap[i] = len(dialogs)
+		dialogs = append(dialogs, d)
+	}
+	mapDialog := func(i int) int {
+		if i >= 0 && i < len(dialogMap) {
+			return dialogMap[i]
+		}
+		return -1
+	}
+
+	for i := range dialogs {
+		d := &dialogs[i]
+		d.Original = dropRefs(d.Original, mapDialog)
+		d.Consultation = dropRefs(d.Consultation, mapDialog)
+		d.TargetDialog = dropRefs(d.TargetDialog, mapDialog)
+	}
+	v.Dialog = dialogs
+
+	var analysis []Analysis
+	for _, a := range v.Analysis {
+		a.Dialog = remapIndices(a.Dialog, mapDialog)
+		var refs []int
+		switch x := a.Dialog.(type) {
+		case int:
+			refs = []int{x}
+		case []int:
+			refs = x
+		}
+		if len(refs) == 0 || containsInt(refs, -1) {
+			continue
+		}
+		analysis = append(analysis, a)
+	}
+	v.Analysis = analysis
+
+	var attachments []Attachment
+	for _, a := range v.Attachments {
+		if a.isTags() {
+			// the tags describe the whole vCon
+		} else if a.DialogIdx == nil {
+			if a.StartTime.Before(start) || !a.StartTime.Before(end) {
+				continue
+			}
+		} else if a.DialogIdx = remapIndex(a.DialogIdx, mapDialog); *a.DialogIdx < 0 {
+			continue
+		}
+		attachments = append(attachments, a)
+	}
+	v.Attachments = attachments
+	return nil
+}
+
+// dialogEnd returns when the dialog ends, its start when it has no duration.
+func dialogEnd(d *Dialog) time.Time {
+	return d.StartTime.Add(time.Duration(d.Duration * float64(time.Second)))
+}
+
+// overlaps reports whether [from, to] meets [start, end). An instant, such
+// as a chat message, overlaps when it falls inside the window.
+func overlaps(from, to, start, end time.Time) bool {
+	if !to.After(from) {
+		return !from.Before(start) && from.Before(end)
+	}
+	return from.Before(end) && to.After(start)
+}
+
+// trimDialog replaces the media of d with the part inside [start, end).
+func trimDialog(ctx context.Context, d *Dialog, fetcher ContentFetcher, start, end time.Time, trim MediaTrimmer) error {
+	var media []byte
+	var err error
+	switch {
+	case d.URL != "":
+		media, _, err = retainedOrFetch(ctx, fetcher, d.URL, d.fetched, d.ContentHash)
+	case d.Body != "":
+		media, err = decodeInlineBody(d.Body, d.Encoding)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	from, to := *d.StartTime, dialogEnd(d)
+	if from.Before(start) {
+		from = start
+	}
+	if to.After(end) {
+		to = end
+	}
+	trimmed, mediaType, err := trim(media, d.MediaType, from.Sub(*d.StartTime), to.Sub(from))
+	if err != nil {
+		return err
+	}
+
+	d.StartTime = &from
+	d.Duration = to.Sub(from).Seconds()
+	d.Body, d.Encoding, d.URL = encodeBase64URL(trimmed), "base64url", ""
+	if mediaType != "" {
+		d.MediaType = mediaType
+	}
+	d.ContentHash = ContentHashList{ComputeSHA512(trimmed)}
+	d.fetched = nil
+	return nil
+}
+
+func containsInt(s []int, n int) bool {
+	for _, x := range s {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package vcon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sliceFixture() (*VCon, time.Time) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	at := func(s int) *time.Time {
+		t := start.Add(time.Duration(s) * time.Second)
+		return &t
+	}
+
+	v := New("example.com")
+	v.AddParty(Party{Tel: "tel:+15551230001"})
+	v.AddParty(Party{Tel: "tel:+15551230002"})
+	v.AddDialog(Dialog{
+		Type: "recording", StartTime: at(0), Duration: 600, Parties: NewPartyRefs(0, 1),
+		MediaType: "audio/wav", Body: encodeBase64URL([]byte("0123456789")), Encoding: "base64url",
+		PartyHistory: []PartyHistory{
+			{Party: 0, Event: "join", Time: *at(0)},
+			{Party: 1, Event: "hold", Time: *at(100)},
+			{Party: 1, Event: "unhold", Time: *at(400)},
+		},
+	})
+	v.AddDialog(Dialog{Type: "text", StartTime: at(30), Parties: NewPartyRefs(0, 1), Body: "early", Encoding: "none", MediaType: "text/plain"})
+	v.AddDialog(Dialog{Type: "text", StartTime: at(200), Parties: NewPartyRefs(1, 0), Body: "inside", Encoding: "none", MediaType: "text/plain"})
+	v.AddDialog(Dialog{Type: "transfer", StartTime: at(250), Transferee: IntPtr(0), Transferor: IntPtr(1), Original: NewIntValue(0), Consultation: NewIntValue(1)})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: 0})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: []int{0, 1}})
+	v.AddAnalysis(Analysis{Type: "sentiment", Vendor: "acme", Dialog: 2})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(1), PartyIdx: 0, StartTime: *at(30)})
+	v.AddAttachment(Attachment{DialogIdx: IntPtr(2), PartyIdx: 1, StartTime: *at(200)})
+	return v, start
+}
+
+func TestSlice(t *testing.T) {
+	v, start := sliceFixture()
+
+	s, err := v.Slice(start.Add(150*time.Second), start.Add(300*time.Second))
+	require.NoError(t, err)
+	require.NoError(t, s.Validate())
+
+	assert.NotEqual(t, v.UUID, s.UUID)
+	require.NotNil(t, s.Redacted)
+	assert.Equal(t, v.UUID, s.Redacted.UUID)
+	assert.Equal(t, SliceRedactionType, s.Redacted.Type)
+	assert.Len(t, s.Parties, 2)
+
+	// The text sent before the window is dropped; the recording is kept
+	// whole without a trimmer.
+	require.Len(t, s.Dialog, 3)
+	assert.Equal(t, v.Dialog[0].Body, s.Dialog[0].Body)
+	assert.Equal(t, 600.0, s.Dialog[0].Duration)
+	assert.Empty(t, s.Dialog[0].PartyHistory)
+	assert.Equal(t, "inside", s.Dialog[1].Body)
+
+	// The transfer keeps its reference to the recording, renumbered, and
+	// loses the one to the dropped text.
+	original, _ := s.Dialog[2].Original.AsInt()
+	assert.Equal(t, 0, original)
+	assert.Nil(t, s.Dialog[2].Consultation)
+
+	require.Len(t, s.Analysis, 2)
+	assert.Equal(t, 0, s.Analysis[0].Dialog)
+	assert.Equal(t, "sentiment", s.Analysis[1].Type)
+	assert.Equal(t, 1, s.Analysis[1].Dialog)
+
+	require.Len(t, s.Attachments, 1)
+	assert.Equal(t, 1, *s.Attachments[0].DialogIdx)
+	assert.Equal(t, 1, s.Attachments[0].PartyIdx)
+
+	// The original is untouched.
+	assert.Len(t, v.Dialog, 4)
+	assert.Len(t, v.Dialog[0].PartyHistory, 3)
+}
+
+func TestSlicePartyHistory(t *testing.T) {
+	v, start := sliceFixture()
+
+	s, err := v.Slice(start.Add(50*time.Second), start.Add(450*time.Second))
+	require.NoError(t, err)
+	require.Len(t, s.Dialog[0].PartyHistory, 2)
+	assert.Equal(t, "hold", s.Dialog[0].PartyHistory[0].Event)
+	assert.Equal(t, "unhold", s.Dialog[0].PartyHistory[1].Event)
+}
+
+func TestSliceTrimsMedia(t *testing.T) {
+	v, start := sliceFixture()
+
+	var gotOffset, gotLength time.Duration
+	trim := func(media []byte, mediaType string, offset, length time.Duration) ([]byte, string, error) {
+		assert.Equal(t, "0123456789", string(media))
+		assert.Equal(t, "audio/wav", mediaType)
+		gotOffset, gotLength = offset, length
+		return []byte("345"), "", nil
+	}
+
+	s, err := v.Slice(start.Add(150*time.Second), start.Add(300*time.Second), WithMediaTrimmer(trim))
+	require.NoError(t, err)
+	require.NoError(t, s.Validate())
+	assert.Equal(t, 150*time.Second, gotOffset)
+	assert.Equal(t, 150*time.Second, gotLength)
+
+	d := s.Dialog[0]
+	assert.Equal(t, start.Add(150*time.Second), *d.StartTime)
+	assert.Equal(t, 150.0, d.Duration)
+	assert.Equal(t, "audio/wav", d.MediaType)
+	assert.Equal(t, ContentHashList{ComputeSHA512([]byte("345"))}, d.ContentHash)
+	body, err := decodeInlineBody(d.Body, d.Encoding)
+	require.NoError(t, err)
+	assert.Equal(t, "345", string(body))
+}
+
+func TestSliceErrors(t *testing.T) {
+	v, start := sliceFixture()
+
+	_, err := v.Slice(start, start)
+	assert.Error(t, err)
+
+	failing := func([]byte, string, time.Duration, time.Duration) ([]byte, string, error) {
+		return nil, "", errors.New("ffmpeg failed")
+	}
+	_, err = v.Slice(start.Add(time.Minute), start.Add(2*time.Minute), WithMediaTrimmer(failing))
+	assert.ErrorContains(t, err, "trim dialog 0: ffmpeg failed")
+}
@@ -0,0 +1,157 @@
+package vcon
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// ErrAccessDenied is returned by a SecureStore when the tenant's policy does
+// not allow the operation. PolicyProviders may return it, wrapped or not, to
+// refuse a caller.
+var ErrAccessDenied = errors.New("access denied")
+
+// StoragePolicy holds the keys a SecureStore uses for one tenant.
+type StoragePolicy struct {
+	// Signer and Chain sign every vCon before it is encrypted.
+	Signer crypto.Signer
+	Chain  []*x509.Certificate
+	// Recipients are the keys every stored vCon is encrypted for.
+	Recipients []jose.Recipient
+
+	// DecryptionKeys open stored vCons. A policy without them cannot read.
+	DecryptionKeys []jose.JSONWebKey
+	// Roots verifies the signature of a vCon read back; nil trusts the last
+	// certificate of Chain.
+	Roots *x509.CertPool
+}
+
+// PolicyProvider returns the StoragePolicy of a tenant. It is called on every
+// Save and Load with the caller's context, so it can authorize the caller and
+// return ErrAccessDenied.
+type PolicyProvider interface {
+	Policy(ctx context.Context, tenant string) (*StoragePolicy, error)
+}
+
+// PolicyProviderFunc adapts a function to a PolicyProvider.
+type PolicyProviderFunc func(ctx context.Context, tenant string) (*StoragePolicy, error)
+
+// Policy calls f.
+func (f PolicyProviderFunc) Policy(ctx context.Context, tenant string) (*StoragePolicy, error) {
+	return f(ctx, tenant)
+}
+
+// SecureStore persists vCons signed and encrypted under their tenant's
+// policy, so plaintext conversation data is never written to disk. Each vCon
+// is stored as <Dir>/<tenant>/<uuid>.json in the JWE form.
+type SecureStore struct {
+	Dir      string
+	Policies PolicyProvider
+}
+
+// Save signs and encrypts v with the tenant's policy and writes it
+// atomically, replacing any earlier version.
+func (s *SecureStore) Save(ctx context.Context, tenant string, v *VCon) error {
+	p, err := s.path(tenant, v.UUID)
+	if err != nil {
+		return err
+	}
+	policy, err := s.policy(ctx, tenant)
+	if err != nil {
+		return err
+	}
+	if policy.Signer == nil || len(policy.Chain) == 0 || len(policy.Recipients) == 0 {
+		return fmt.Errorf("tenant %q: policy needs a signer, certificate chain and recipients", tenant)
+	}
+
+	encrypted, err := v.SignAndEncrypt(policy.Signer, policy.Chain, policy.Recipients)
+	if err != nil {
+		return fmt.Errorf("failed to seal vCon: %w", err)
+	}
+	data, err := json.Marshal(encrypted.JSON)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JWE: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(p, data, 0600); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// Load reads the vCon with the given UUID, decrypts it with the tenant's
+// keys and verifies its signature. Files that are not encrypted are refused.
+func (s *SecureStore) Load(ctx context.Context, tenant, uuid string) (*VCon, error) {
+	p, err := s.path(tenant, uuid)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.policy(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	if len(policy.DecryptionKeys) == 0 {
+		return nil, fmt.Errorf("tenant %q: %w: policy has no decryption keys", tenant, ErrAccessDenied)
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if form, err := DetectForm(data); err != nil || form != VConFormEncrypted {
+		return nil, fmt.Errorf("%s: stored vCon is not encrypted", p)
+	}
+	var jwe map[string]any
+	if err := json.Unmarshal(data, &jwe); err != nil {
+		return nil, fmt.Errorf("failed to parse JWE: %w", err)
+	}
+	plain, err := (&EncryptedVCon{JSON: jwe}).DecryptWithKeys(policy.DecryptionKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := policy.Roots
+	if roots == nil {
+		if len(policy.Chain) == 0 {
+			return nil, fmt.Errorf("tenant %q: policy needs Roots or a certificate chain to verify", tenant)
+		}
+		roots = x509.NewCertPool()
+		roots.AddCert(policy.Chain[len(policy.Chain)-1])
+	}
+	return (&SignedVCon{JSON: plain}).Verify(roots)
+}
+
+func (s *SecureStore) policy(ctx context.Context, tenant string) (*StoragePolicy, error) {
+	if s.Policies == nil {
+		return nil, errors.New("secure store has no policy provider")
+	}
+	policy, err := s.Policies.Policy(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %q: %w", tenant, err)
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("tenant %q: %w: no storage policy", tenant, ErrAccessDenied)
+	}
+	return policy, nil
+}
+
+// path returns the file of a vCon, rejecting names that would leave the
+// tenant's directory.
+func (s *SecureStore) path(tenant, uuid string) (string, error) {
+	for _, name := range []string{tenant, uuid} {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return "", fmt.Errorf("invalid store name %q", name)
+		}
+	}
+	return filepath.Join(s.Dir, tenant, uuid+".json"), nil
+}
@@ -0,0 +1,81 @@
+package vcon_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/robjsliwa/go-vcon/pkg/vcon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecureStore(t *testing.T) {
+	key, certs, err := generateTestCertificate()
+	require.NoError(t, err)
+	writer := &vcon.StoragePolicy{
+		Signer:     key,
+		Chain:      certs,
+		Recipients: []jose.Recipient{{Algorithm: jose.RSA_OAEP, Key: &key.PublicKey}},
+	}
+	reader := *writer
+	reader.DecryptionKeys = []jose.JSONWebKey{{Key: key}}
+
+	type roleKey struct{}
+	store := &vcon.SecureStore{
+		Dir: t.TempDir(),
+		Policies: vcon.PolicyProviderFunc(func(ctx context.Context, tenant string) (*vcon.StoragePolicy, error) {
+			if tenant != "acme" {
+				return nil, vcon.ErrAccessDenied
+			}
+			if ctx.Value(roleKey{}) == "auditor" {
+				return &reader, nil
+			}
+			return writer, nil
+		}),
+	}
+
+	v := vcon.New("example.com")
+	v.Subject = "Confidential call"
+	v.AddParty(vcon.Party{Name: "Alice"})
+	require.NoError(t, store.Save(context.Background(), "acme", v))
+
+	p := filepath.Join(store.Dir, "acme", v.UUID+".json")
+	data, err := os.ReadFile(p)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "Confidential call", "plaintext reached disk")
+	form, err := vcon.DetectForm(data)
+	require.NoError(t, err)
+	assert.Equal(t, vcon.VConFormEncrypted, form)
+	info, err := os.Stat(p)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	auditor := context.WithValue(context.Background(), roleKey{}, "auditor")
+	got, err := store.Load(auditor, "acme", v.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, "Confidential call", got.Subject)
+
+	_, err = store.Load(context.Background(), "acme", v.UUID)
+	assert.ErrorIs(t, err, vcon.ErrAccessDenied, "a writer without decryption keys must not read")
+	assert.ErrorIs(t, store.Save(context.Background(), "globex", v), vcon.ErrAccessDenied)
+	assert.Error(t, store.Save(context.Background(), "../acme", v))
+
+	// A plaintext file planted in the store is refused.
+	require.NoError(t, v.SaveToFile(p))
+	_, err = store.Load(auditor, "acme", v.UUID)
+	assert.ErrorContains(t, err, "not encrypted")
+
+	// Incomplete policies never write.
+	incomplete := &vcon.SecureStore{Dir: t.TempDir(), Policies: vcon.PolicyProviderFunc(
+		func(context.Context, string) (*vcon.StoragePolicy, error) {
+			return &vcon.StoragePolicy{Signer: key, Chain: certs}, nil
+		})}
+	err = incomplete.Save(context.Background(), "acme", v)
+	assert.True(t, err != nil && strings.Contains(err.Error(), "recipients"), "got %v", err)
+	entries, _ := os.ReadDir(incomplete.Dir)
+	assert.Empty(t, entries)
+}
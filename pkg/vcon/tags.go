@@ -0,0 +1,147 @@
+package vcon
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AddTag sets a tag on the VCon, replacing any earlier value of the same
+// name. Tags are kept as the Python library keeps them, in an attachment of
+// type "tags" whose JSON body is an array of "name:value" strings. Tags
+// attachments with purpose "tags" and a JSON string body, and legacy comma
+// separated ones, are read and converted on the first change. The tags
+// describe the whole vCon: the party and dialog a new attachment names to
+// satisfy the schema are not checked by Validate, nor renumbered or removed
+// with the parties and dialogs.
+func (v *VCon) AddTag(tagName string, tagValue string) {
+	tag := tagName + ":" + tagValue
+	tags := v.tagList()
+	if i := slices.IndexFunc(tags, tagNamed(tagName)); i >= 0 {
+		tags[i] = tag
+	} else {
+		tags = append(tags, tag)
+	}
+	v.writeTags(tags)
+}
+
+// GetTag gets a tag value by its name
+func (v *VCon) GetTag(tagName string) string {
+	return v.ListTags()[tagName]
+}
+
+// RemoveTag removes a tag and reports whether it was set. The tags
+// attachment goes when its last tag does.
+func (v *VCon) RemoveTag(tagName string) bool {
+	tags := v.tagList()
+	kept := slices.DeleteFunc(slices.Clone(tags), tagNamed(tagName))
+	if len(kept) == len(tags) {
+		return false
+	}
+	v.writeTags(kept)
+	return true
+}
+
+// ListTags returns all tags of the VCon by name, from either the JSON array
+// attachment or the legacy comma separated one.
+func (v *VCon) ListTags() map[string]string {
+	result := make(map[string]string)
+	for _, tag := range v.tagList() {
+		if name, value, ok := strings.Cut(tag, ":"); ok {
+			if _, seen := result[name]; !seen {
+				result[name] = value
+			}
+		}
+	}
+	return result
+}
+
+// SetTags replaces all tags of the VCon with tags, written in name order.
+// An empty map removes the tags attachment.
+func (v *VCon) SetTags(tags map[string]string) {
+	list := make([]string, 0, len(tags))
+	for name, value := range tags {
+		list = append(list, name+":"+value)
+	}
+	slices.SortFunc(list, func(a, b string) int {
+		an, _, _ := strings.Cut(a, ":")
+		bn, _, _ := strings.Cut(b, ":")
+		return strings.Compare(an, bn)
+	})
+	v.writeTags(list)
+}
+
+// tagNamed matches the "name:value" strings of the named tag.
+func tagNamed(tagName string) func(string) bool {
+	return func(tag string) bool {
+		name, _, _ := strings.Cut(tag, ":")
+		return name == tagName
+	}
+}
+
+// writeTags replaces the body of the tags attachment in one step, creating
+// the attachment or, when tags is empty, removing it.
+func (v *VCon) writeTags(tags []string) {
+	i := v.tagsIndex()
+	if len(tags) == 0 {
+		if i >= 0 {
+			v.RemoveAttachment(i)
+		}
+		return
+	}
+	if i < 0 {
+		i = v.AddAttachment(Attachment{
+			DialogIdx: IntPtr(0),
+			StartTime: time.Now().UTC(),
+		})
+	}
+	att := &v.Attachments[i]
+	att.Type = string(AttachmentTypeTags)
+	att.Purpose = ""
+	att.Encoding = "json"
+	att.Body = ""
+	att.Extra.Set("body", tags)
+}
+
+// isTags reports whether a is a tags attachment, in any of the forms tagList
+// reads.
+func (a *Attachment) isTags() bool {
+	return a.Type == string(AttachmentTypeTags) || a.Purpose == string(AttachmentTypeTags) || a.Encoding == "tags"
+}
+
+// tagsIndex returns the index of the tags attachment, or -1.
+func (v *VCon) tagsIndex() int {
+	for i := range v.Attachments {
+		if v.Attachments[i].isTags() {
+			return i
+		}
+	}
+	return -1
+}
+
+// tagList returns the "name:value" strings of the tags attachment.
+func (v *VCon) tagList() []string {
+	i := v.tagsIndex()
+	if i < 0 {
+		return nil
+	}
+	att := v.Attachments[i]
+	var tags []string
+	if att.Encoding == "tags" {
+		for _, tag := range strings.Split(att.Body, ",") {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		return tags
+	}
+	body, ok := att.Extra["body"]
+	if !ok {
+		body = []byte(att.Body)
+	}
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil
+	}
+	return tags
+}
@@ -0,0 +1,89 @@
+package vcon
+
+import (
+	"fmt"
+	"time"
+)
+
+// PartyTimezoneKey is the non-standard party property holding the IANA time
+// zone the party is in, such as "Europe/Paris". The spec stores every
+// timestamp in UTC; the time zone is only used to render them locally.
+const PartyTimezoneKey = "timezone"
+
+// Timezone returns the time zone declared by the party, or "" when none is.
+func (p *Party) Timezone() string {
+	var tz string
+	if ok, err := p.Extra.Get(PartyTimezoneKey, &tz); !ok || err != nil {
+		return ""
+	}
+	return tz
+}
+
+// SetTimezone declares the party's IANA time zone.
+func (p *Party) SetTimezone(name string) error {
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+	return p.Extra.Set(PartyTimezoneKey, name)
+}
+
+// Location returns the party's time zone, UTC when it declares none.
+func (p *Party) Location() (*time.Location, error) {
+	tz := p.Timezone()
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %w", err)
+	}
+	return loc, nil
+}
+
+// partyLocation returns the time zone of the party at index i.
+func (v *VCon) partyLocation(i int) (*time.Location, error) {
+	if i < 0 || i >= len(v.Parties) {
+		return nil, fmt.Errorf("invalid party index: %d", i)
+	}
+	loc, err := v.Parties[i].Location()
+	if err != nil {
+		return nil, fmt.Errorf("party %d: %w", i, err)
+	}
+	return loc, nil
+}
+
+// LocalDialogStart returns the start of the dialog at index dialog in the
+// time zone of the party at index party, for display.
+func (v *VCon) LocalDialogStart(dialog, party int) (time.Time, error) {
+	if dialog < 0 || dialog >= len(v.Dialog) {
+		return time.Time{}, fmt.Errorf("invalid dialog index: %d", dialog)
+	}
+	start := v.Dialog[dialog].StartTime
+	if start == nil {
+		return time.Time{}, fmt.Errorf("dialog %d has no start", dialog)
+	}
+	loc, err := v.partyLocation(party)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return start.In(loc), nil
+}
+
+// LocalPartyHistory returns a copy of the party_history of the dialog at
+// index dialog with each event time in the time zone of its own party, for
+// display. The dialog itself is not changed.
+func (v *VCon) LocalPartyHistory(dialog int) ([]PartyHistory, error) {
+	if dialog < 0 || dialog >= len(v.Dialog) {
+		return nil, fmt.Errorf("invalid dialog index: %d", dialog)
+	}
+	history := make([]PartyHistory, len(v.Dialog[dialog].PartyHistory))
+	for i, h := range v.Dialog[dialog].PartyHistory {
+		loc, err := v.partyLocation(h.Party)
+		if err != nil {
+			return nil, fmt.Errorf("party_history %d: %w", i, err)
+		}
+		h.Time = h.Time.In(loc)
+		history[i] = h
+	}
+	return history, nil
+}
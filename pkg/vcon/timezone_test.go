@@ -0,0 +1,54 @@
+package vcon
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartyTimezone(t *testing.T) {
+	start := time.Date(2024, 1, 15, 15, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	alice := Party{Name: "Alice"}
+	require.NoError(t, alice.SetTimezone("America/New_York"))
+	assert.Error(t, alice.SetTimezone("Nowhere/Special"))
+	v.AddParty(alice)
+	v.AddParty(Party{Name: "Bob"})
+	v.AddDialog(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0, 1),
+		PartyHistory: []PartyHistory{
+			{Party: 0, Event: "join", Time: start},
+			{Party: 1, Event: "join", Time: start.Add(time.Minute)},
+		}})
+
+	// The time zone survives a round trip as a non-standard property.
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	loaded, err := BuildFromJSON(string(data))
+	require.NoError(t, err)
+	assert.Equal(t, "America/New_York", loaded.Parties[0].Timezone())
+	assert.Equal(t, "", loaded.Parties[1].Timezone())
+
+	local, err := loaded.LocalDialogStart(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-15T10:00:00-05:00", local.Format(time.RFC3339))
+	local, err = loaded.LocalDialogStart(0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-15T15:00:00Z", local.Format(time.RFC3339))
+	_, err = loaded.LocalDialogStart(0, 2)
+	assert.Error(t, err)
+	_, err = loaded.LocalDialogStart(1, 0)
+	assert.Error(t, err)
+
+	history, err := loaded.LocalPartyHistory(0)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-15T10:00:00-05:00", history[0].Time.Format(time.RFC3339))
+	assert.Equal(t, "2024-01-15T15:01:00Z", history[1].Time.Format(time.RFC3339))
+	assert.Equal(t, time.UTC, loaded.Dialog[0].PartyHistory[0].Time.Location(), "stored times are not changed")
+
+	ok, issues := loaded.IsValid()
+	assert.True(t, ok)
+	assert.Empty(t, issues)
+}
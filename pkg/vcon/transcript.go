@@ -0,0 +1,136 @@
+package vcon
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
+)
+
+// Transcript is the structured body of a transcript analysis: what was said,
+// by whom and when, one segment per utterance.
+type Transcript struct {
+	Language string              `json:"language,omitempty"` // BCP 47 tag, e.g. "en-US"
+	Segments []TranscriptSegment `json:"segments"`
+}
+
+// TranscriptSegment is one utterance of a transcript.
+type TranscriptSegment struct {
+	Party      *int     `json:"party,omitempty"`   // index of the speaking party, nil when unknown
+	Speaker    string   `json:"speaker,omitempty"` // speaker label from the source, e.g. a caption file
+	Start      float64  `json:"start"`             // seconds from the start of the dialog
+	End        float64  `json:"end"`               // seconds from the start of the dialog
+	Text       string   `json:"text"`
+	Confidence *float64 `json:"confidence,omitempty"` // 0 to 1
+}
+
+// Text returns the text of every segment, one per line.
+func (t *Transcript) Text() string {
+	lines := make([]string, len(t.Segments))
+	for i, s := range t.Segments {
+		lines[i] = s.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validate checks the segment offsets and confidences and, when v is given,
+// the party indices and the duration of dialog.
+func (t *Transcript) validate(v *VCon, dialog int) error {
+	for i, s := range t.Segments {
+		if s.Start < 0 || s.End < s.Start {
+			return fmt.Errorf("segment %d: invalid offsets %g to %g", i, s.Start, s.End)
+		}
+		if s.Confidence != nil && (*s.Confidence < 0 || *s.Confidence > 1) {
+			return fmt.Errorf("segment %d: confidence %g out of range [0, 1]", i, *s.Confidence)
+		}
+		if v == nil {
+			continue
+		}
+		if s.Party != nil && (*s.Party < 0 || *s.Party >= len(v.Parties)) {
+			return fmt.Errorf("segment %d: party index %d out of range", i, *s.Party)
+		}
+		if d := v.Dialog[dialog].Duration; d > 0 && s.End > d {
+			return fmt.Errorf("segment %d: ends at %gs, after the dialog's %gs", i, s.End, d)
+		}
+	}
+	return nil
+}
+
+// Transcript decodes the body of a transcript analysis. External content is
+// fetched as Content does.
+func (a *Analysis) Transcript() (*Transcript, error) {
+	if a.Type != AnalysisTypeTranscript {
+		return nil, fmt.Errorf("analysis type is %q, not %q", a.Type, AnalysisTypeTranscript)
+	}
+	body, err := a.Content()
+	if err != nil {
+		return nil, err
+	}
+	var t Transcript
+	if err := json.Unmarshal(body, &t); err != nil {
+		return nil, fmt.Errorf("transcript is not structured JSON: %w", err)
+	}
+	return &t, nil
+}
+
+// SetTranscript stores t as the analysis body and makes it a JSON
+// transcript analysis.
+func (a *Analysis) SetTranscript(t *Transcript) error {
+	if err := t.validate(nil, 0); err != nil {
+		return err
+	}
+	body, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	a.Type = AnalysisTypeTranscript
+	a.MediaType = MIMETypeJSON
+	a.Body, a.Encoding = string(body), "json"
+	a.URL, a.ContentHash = "", nil
+	a.fetched = nil
+	return nil
+}
+
+// AttachTranscript adds t as the transcript of a dialog, as
+// AddTranscriptAnalysis does, after checking its segments against the
+// dialog's parties and duration.
+func (v *VCon) AttachTranscript(dialog int, vendor, product string, t *Transcript) (int, error) {
+	if dialog < 0 || dialog >= len(v.Dialog) {
+		return -1, fmt.Errorf("dialog index %d out of range", dialog)
+	}
+	if err := t.validate(v, dialog); err != nil {
+		return -1, err
+	}
+	return v.AddTranscriptAnalysis([]int{dialog}, vendor, product, t)
+}
+
+// DialogTranscript returns the first structured transcript of a dialog, or
+// nil when it has none.
+func (v *VCon) DialogTranscript(dialog int) (*Transcript, error) {
+	for i := range v.Analysis {
+		a := &v.Analysis[i]
+		if a.Type != AnalysisTypeTranscript || !refersTo(a.Dialog, dialog) {
+			continue
+		}
+		if a.Encoding != "json" && !mediatype.Equal(a.MediaType, MIMETypeJSON) {
+			continue // a plain text transcript
+		}
+		t, err := a.Transcript()
+		if err != nil {
+			return nil, fmt.Errorf("analysis %d: %w", i, err)
+		}
+		return t, nil
+	}
+	return nil, nil
+}
+
+// refersTo reports whether an int or []int reference includes i.
+func refersTo(ref interface{}, i int) bool {
+	found := false
+	remapIndices(ref, func(n int) int {
+		found = found || n == i
+		return n
+	})
+	return found
+}
@@ -0,0 +1,67 @@
+package vcon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscript(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.AddParty(Party{Name: "Bob"})
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Duration: 30, Parties: NewPartyRefs(0, 1), URL: "https://example.com/call.wav"})
+	v.AddDialog(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0), Body: "hi", Encoding: "none"})
+
+	confidence := 0.92
+	tr := &Transcript{Language: "en-US", Segments: []TranscriptSegment{
+		{Party: IntPtr(0), Start: 0, End: 2.5, Text: "Hello, this is Alice.", Confidence: &confidence},
+		{Party: IntPtr(1), Start: 3, End: 4.2, Text: "Hi Alice."},
+		{Start: 5, End: 6, Text: "[inaudible]"},
+	}}
+
+	_, err := v.AddTranscriptAnalysis([]int{0}, "acme", "", "plain text first")
+	require.NoError(t, err)
+	i, err := v.AttachTranscript(0, "TranscriptCo", "AutoTranscribe v2.0", tr)
+	require.NoError(t, err)
+	assert.Equal(t, "transcript", v.Analysis[i].Type)
+	assert.Equal(t, "application/json", v.Analysis[i].MediaType)
+
+	loaded, err := BuildFromJSON(v.ToJSON())
+	require.NoError(t, err)
+	got, err := loaded.DialogTranscript(0)
+	require.NoError(t, err)
+	assert.Equal(t, tr, got)
+	assert.Equal(t, "Hello, this is Alice.\nHi Alice.\n[inaudible]", got.Text())
+
+	none, err := loaded.DialogTranscript(1)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+
+	_, err = loaded.Analysis[0].Transcript()
+	assert.Error(t, err, "plain text is not a structured transcript")
+
+	var a Analysis
+	require.NoError(t, a.SetTranscript(tr))
+	assert.Equal(t, "json", a.Encoding)
+	decoded, err := a.Transcript()
+	require.NoError(t, err)
+	assert.Equal(t, tr, decoded)
+
+	bad := []TranscriptSegment{
+		{Party: IntPtr(2), Start: 0, End: 1, Text: "unknown party"},
+		{Start: 2, End: 1, Text: "ends before it starts"},
+		{Start: 29, End: 31, Text: "after the dialog"},
+		{Start: 0, End: 1, Text: "overconfident", Confidence: new(float64)},
+	}
+	*bad[3].Confidence = 1.5
+	for _, s := range bad {
+		_, err := v.AttachTranscript(0, "TranscriptCo", "", &Transcript{Segments: []TranscriptSegment{s}})
+		assert.Error(t, err, s.Text)
+	}
+	_, err = v.AttachTranscript(5, "TranscriptCo", "", tr)
+	assert.Error(t, err)
+}
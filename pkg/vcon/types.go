@@ -101,3 +101,183 @@ func (v *IntOrSlice) UnmarshalJSON(data []byte) error {
 
 	return fmt.Errorf("IntOrSlice: expected int or []int, got %s", string(data))
 }
+
+// PartyRefs is the parties value of a dialog: a single party index, or a
+// list whose items are party indices or lists of indices (parties sharing
+// one channel of the recording). Decoding normalizes JSON numbers to int, so
+// the same value is seen whether the dialog was built in Go or parsed.
+type PartyRefs struct {
+	value any // int, []int or []any of int and []int
+}
+
+// NewPartyRef references a single party.
+func NewPartyRef(idx int) *PartyRefs {
+	return &PartyRefs{value: idx}
+}
+
+// NewPartyRefs references a list of parties.
+func NewPartyRefs(idx ...int) *PartyRefs {
+	return &PartyRefs{value: append([]int{}, idx...)}
+}
+
+// PartyRefsOf converts an int, []int or decoded JSON value into PartyRefs.
+func PartyRefsOf(v any) (*PartyRefs, error) {
+	if v == nil {
+		return nil, nil
+	}
+	value, err := normalizePartyRefs(v, true)
+	if err != nil {
+		return nil, err
+	}
+	return &PartyRefs{value: value}, nil
+}
+
+// normalizePartyRefs converts v to int, []int or []any, accepting nested
+// lists only at the top level.
+func normalizePartyRefs(v any, top bool) (any, error) {
+	switch x := v.(type) {
+	case int:
+		return x, nil
+	case float64:
+		if x != float64(int(x)) {
+			return nil, fmt.Errorf("PartyRefs: %v is not a party index", x)
+		}
+		return int(x), nil
+	case json.Number:
+		n, err := x.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("PartyRefs: %s is not a party index", x)
+		}
+		return int(n), nil
+	case []int:
+		return append([]int{}, x...), nil
+	case []any:
+		if !top {
+			out := make([]int, len(x))
+			for i, item := range x {
+				n, err := normalizePartyRefs(item, false)
+				idx, ok := n.(int)
+				if err != nil || !ok {
+					return nil, fmt.Errorf("PartyRefs: invalid party index %v", item)
+				}
+				out[i] = idx
+			}
+			return out, nil
+		}
+		items := make([]any, len(x))
+		flat := make([]int, 0, len(x))
+		for i, item := range x {
+			n, err := normalizePartyRefs(item, false)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = n
+			if idx, ok := n.(int); ok {
+				flat = append(flat, idx)
+			}
+		}
+		if len(flat) == len(x) {
+			return flat, nil
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("PartyRefs: expected int or list of ints, got %T", v)
+	}
+}
+
+// AsInt returns the index if a single party is referenced.
+func (p *PartyRefs) AsInt() (int, bool) {
+	if p == nil {
+		return 0, false
+	}
+	i, ok := p.value.(int)
+	return i, ok
+}
+
+// Indices returns every referenced party index, flattening nested lists.
+func (p *PartyRefs) Indices() []int {
+	if p == nil {
+		return nil
+	}
+	switch v := p.value.(type) {
+	case int:
+		return []int{v}
+	case []int:
+		return append([]int{}, v...)
+	case []any:
+		var out []int
+		for _, item := range v {
+			switch x := item.(type) {
+			case int:
+				out = append(out, x)
+			case []int:
+				out = append(out, x...)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// Remap returns a copy with every index replaced by f(index), keeping the
+// shape of the value.
+func (p *PartyRefs) Remap(f func(int) int) *PartyRefs {
+	if p == nil {
+		return nil
+	}
+	mapInts := func(in []int) []int {
+		out := make([]int, len(in))
+		for i, n := range in {
+			out[i] = f(n)
+		}
+		return out
+	}
+	switch v := p.value.(type) {
+	case int:
+		return &PartyRefs{value: f(v)}
+	case []int:
+		return &PartyRefs{value: mapInts(v)}
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			switch x := item.(type) {
+			case int:
+				out[i] = f(x)
+			case []int:
+				out[i] = mapInts(x)
+			}
+		}
+		return &PartyRefs{value: out}
+	default:
+		return &PartyRefs{}
+	}
+}
+
+// IsZero returns true if no parties are referenced.
+func (p *PartyRefs) IsZero() bool {
+	return p == nil || p.value == nil
+}
+
+// MarshalJSON serializes as an int or a (possibly nested) list of ints.
+func (p PartyRefs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.value)
+}
+
+// UnmarshalJSON accepts an int or a list of ints and lists of ints.
+func (p *PartyRefs) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		p.value = nil
+		return nil
+	}
+	value, err := normalizePartyRefs(raw, true)
+	if err != nil {
+		return err
+	}
+	p.value = value
+	return nil
+}
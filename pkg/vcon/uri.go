@@ -0,0 +1,78 @@
+package vcon
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// ValidateTelURI checks that s is a tel URI (RFC 3966): "tel:" followed by
+// a global number such as +1-202-555-1234, or a local number with a
+// phone-context parameter, and optional ";name=value" parameters.
+func ValidateTelURI(s string) error {
+	rest, ok := cutScheme(s, "tel")
+	if !ok {
+		return fmt.Errorf("%q does not start with tel:", s)
+	}
+	number, params, _ := strings.Cut(rest, ";")
+	hasContext := false
+	if params != "" {
+		for _, param := range strings.Split(params, ";") {
+			name, _, _ := strings.Cut(param, "=")
+			if name == "" || strings.Trim(name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-") != "" {
+				return fmt.Errorf("%q has a malformed parameter %q", s, param)
+			}
+			hasContext = hasContext || strings.EqualFold(name, "phone-context")
+		}
+	}
+	digits := "0123456789"
+	if global, ok := strings.CutPrefix(number, "+"); ok {
+		number = global
+	} else {
+		if !hasContext {
+			return fmt.Errorf("%q is a local number without phone-context; use the +<country code> form", s)
+		}
+		digits += "abcdefABCDEF*#"
+	}
+	if !strings.ContainsAny(number, digits) {
+		return fmt.Errorf("%q has no digits", s)
+	}
+	if strings.Trim(number, digits+"-.()") != "" {
+		return fmt.Errorf("%q has characters other than digits and -.() in the number", s)
+	}
+	return nil
+}
+
+// ValidateMailtoURI checks that s is a mailto URI (RFC 6068) naming at least
+// one address: "mailto:" followed by comma-separated, percent-encoded
+// addr-specs and optional "?header=value" fields.
+func ValidateMailtoURI(s string) error {
+	rest, ok := cutScheme(s, "mailto")
+	if !ok {
+		return fmt.Errorf("%q does not start with mailto:", s)
+	}
+	to, _, _ := strings.Cut(rest, "?")
+	if to == "" {
+		return fmt.Errorf("%q names no address", s)
+	}
+	for _, enc := range strings.Split(to, ",") {
+		addr, err := url.PathUnescape(enc)
+		if err != nil {
+			return fmt.Errorf("%q: %w", s, err)
+		}
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil || parsed.Address != addr {
+			return fmt.Errorf("%q: %q is not an email address", s, addr)
+		}
+	}
+	return nil
+}
+
+// cutScheme returns s without its scheme, compared case-insensitively.
+func cutScheme(s, scheme string) (string, bool) {
+	if len(s) <= len(scheme) || s[len(scheme)] != ':' || !strings.EqualFold(s[:len(scheme)], scheme) {
+		return "", false
+	}
+	return s[len(scheme)+1:], true
+}
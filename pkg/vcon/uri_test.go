@@ -0,0 +1,54 @@
+package vcon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTelURI(t *testing.T) {
+	for _, s := range []string{
+		"tel:+12025551234",
+		"tel:+1-202-555-1234",
+		"tel:+1(202)555.1234;ext=42",
+		"TEL:+441632960000",
+		"tel:7042;phone-context=example.com",
+		"tel:*86;phone-context=+1-202",
+	} {
+		assert.NoError(t, ValidateTelURI(s), s)
+	}
+	for _, s := range []string{
+		"+12025551234",
+		"tel:",
+		"tel:+",
+		"tel:5551234",
+		"tel:+1 202 555 1234",
+		"tel:+1202555abcd",
+		"tel:+12025551234;=x",
+	} {
+		assert.Error(t, ValidateTelURI(s), s)
+	}
+}
+
+func TestValidateMailtoURI(t *testing.T) {
+	for _, s := range []string{
+		"mailto:alice@example.com",
+		"MAILTO:alice@example.com",
+		"mailto:alice@example.com,bob@example.org",
+		"mailto:gorby%25kremvax@example.com",
+		"mailto:alice@example.com?subject=hello",
+	} {
+		assert.NoError(t, ValidateMailtoURI(s), s)
+	}
+	for _, s := range []string{
+		"alice@example.com",
+		"mailto:",
+		"mailto:?subject=hello",
+		"mailto:alice",
+		"mailto:Alice <alice@example.com>",
+		"mailto:alice@example.com,",
+		"mailto:%zz@example.com",
+	} {
+		assert.Error(t, ValidateMailtoURI(s), s)
+	}
+}
@@ -0,0 +1,572 @@
+package vcon
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robjsliwa/go-vcon/pkg/vcon/mediatype"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Severity ranks a ValidationIssue. Only errors make a vCon invalid.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Validation issue codes.
+const (
+	IssueMissingField        = "missing_field"
+	IssueMutuallyExclusive   = "mutually_exclusive"
+	IssueCriticalExtension   = "unsupported_critical_extension"
+	IssueInvalidPartyIndex   = "invalid_party_index"
+	IssueInvalidDialogIndex  = "invalid_dialog_index"
+	IssueInvalidOriginator   = "invalid_originator"
+	IssueOriginatorNotParty  = "originator_not_in_parties"
+	IssueInvalidEncoding     = "invalid_encoding"
+	IssueInvalidMediaType    = "invalid_mediatype"
+	IssueUnsupportedMedia    = "unsupported_mediatype"
+	IssueNonUTCTimestamp     = "non_utc_timestamp"
+	IssueInvalidTimezone     = "invalid_timezone"
+	IssueInvalidContentHash  = "invalid_content_hash"
+	IssueInvalidURI          = "invalid_uri"
+	IssueUnknownProperty     = "unknown_property"
+	IssueSchemaViolation     = "schema_violation"
+	IssueDurationMismatch    = "duration_mismatch"
+	IssuePartyHistory        = "inconsistent_party_history"
+	IssueMediaUnreadable     = "media_unreadable"
+	IssueInvalidPartyEvent   = "invalid_party_event"
+	IssueContentHashMismatch = "content_hash_mismatch"
+	IssueContentUnavailable  = "content_unavailable"
+)
+
+// ValidationIssue is one problem found by Validate. Path is a JSON pointer to
+// the offending value, "" for the vCon as a whole, so a UI can map the issue
+// back to a party, dialog or other entry.
+type ValidationIssue struct {
+	Path     string   `json:"path"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+	Severity Severity `json:"severity"`
+}
+
+// String returns the issue message.
+func (i ValidationIssue) String() string {
+	return i.Message
+}
+
+// ValidationError is returned by Validate and lists every issue found.
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Issues) == 1 {
+		return e.Issues[0].Message
+	}
+	return fmt.Sprintf("%s (and %d more issues)", e.Issues[0].Message, len(e.Issues)-1)
+}
+
+// ValidateOption configures Validate and IsValid.
+type ValidateOption func(*validateConfig)
+
+type validateConfig struct {
+	mediaTypes []*mediatype.Allowlist
+	strictURIs bool
+	schema     *jsonschema.Schema
+
+	probeDuration     DurationProber
+	durationTolerance float64
+
+	verifyContent  bool
+	contentFetcher ContentFetcher
+
+	// ctx bounds the media fetches and probes of WithDurationCheck and the
+	// fetches of WithContentVerification.
+	ctx context.Context
+}
+
+// WithAllowedMediaTypes rejects media types other than the given ones, e.g.
+// SupportedMIMETypes. Without it any well-formed media type is accepted.
+// Types are compared normalized, so audio/wav also accepts audio/x-wav, and
+// parameters such as charset are ignored.
+func WithAllowedMediaTypes(types ...string) ValidateOption {
+	return WithMediaTypeAllowlist(mediatype.NewAllowlist(types...))
+}
+
+// WithMediaTypeAllowlist rejects media types the allowlist does not accept.
+// Like WithAllowedMediaTypes, it can be given several times; a type
+// accepted by any of them passes.
+func WithMediaTypeAllowlist(a *mediatype.Allowlist) ValidateOption {
+	return func(c *validateConfig) {
+		c.mediaTypes = append(c.mediaTypes, a)
+	}
+}
+
+// allowsMediaType reports whether t passes the allowlists, if any.
+func (c *validateConfig) allowsMediaType(t string) bool {
+	if len(c.mediaTypes) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(c.mediaTypes, func(a *mediatype.Allowlist) bool { return a.Allows(t) })
+}
+
+// WithStrictURIs reports malformed party tel and mailto URIs as errors. By
+// default they are warnings, since many producers write bare numbers such as
+// "+12025551234".
+func WithStrictURIs() ValidateOption {
+	return func(c *validateConfig) {
+		c.strictURIs = true
+	}
+}
+
+func issuef(path, code, format string, args ...interface{}) ValidationIssue {
+	return ValidationIssue{Path: path, Code: code, Message: fmt.Sprintf(format, args...), Severity: SeverityError}
+}
+
+func warnf(path, code, format string, args ...interface{}) ValidationIssue {
+	issue := issuef(path, code, format, args...)
+	issue.Severity = SeverityWarning
+	return issue
+}
+
+func (v *VCon) validateCoreFields() []ValidationIssue {
+	var issues []ValidationIssue
+	if v.UUID == "" {
+		issues = append(issues, issuef("/uuid", IssueMissingField, "missing required field: uuid"))
+	}
+	if v.CreatedAt.IsZero() {
+		issues = append(issues, issuef("/created_at", IssueMissingField, "missing required field: created_at"))
+	}
+	return issues
+}
+
+func (v *VCon) validateMutualExclusion() []ValidationIssue {
+	count := 0
+	if v.Redacted != nil {
+		count++
+	}
+	if v.Amended != nil {
+		count++
+	}
+	if len(v.Group) > 0 {
+		count++
+	}
+	if count > 1 {
+		return []ValidationIssue{issuef("", IssueMutuallyExclusive, "redacted, amended, and group are mutually exclusive")}
+	}
+	return nil
+}
+
+func (v *VCon) validateCriticalExtensions() []ValidationIssue {
+	if len(v.Critical) == 0 {
+		return nil
+	}
+	if err := v.extensionRegistry().ValidateCritical(v.Critical); err != nil {
+		return []ValidationIssue{issuef("/critical", IssueCriticalExtension, "critical extension validation: %s", err)}
+	}
+	return nil
+}
+
+// extensionRegistry returns the registry set with WithRegistry, or
+// DefaultRegistry.
+func (v *VCon) extensionRegistry() *ExtensionRegistry {
+	if v.registry == nil {
+		return DefaultRegistry
+	}
+	return v.registry
+}
+
+// validateParties checks the tel and mailto URIs of every party.
+func (v *VCon) validateParties(cfg *validateConfig) []ValidationIssue {
+	var issues []ValidationIssue
+	report := issuef
+	if !cfg.strictURIs {
+		report = warnf
+	}
+	for i, p := range v.Parties {
+		if p.Tel != "" {
+			if err := ValidateTelURI(p.Tel); err != nil {
+				issues = append(issues, report(fmt.Sprintf("/parties/%d/tel", i), IssueInvalidURI,
+					"party at index %d tel: %s", i, err))
+			}
+		}
+		if p.Mailto != "" {
+			if err := ValidateMailtoURI(p.Mailto); err != nil {
+				issues = append(issues, report(fmt.Sprintf("/parties/%d/mailto", i), IssueInvalidURI,
+					"party at index %d mailto: %s", i, err))
+			}
+		}
+	}
+	return issues
+}
+
+func (v *VCon) validateDialogs() []ValidationIssue {
+	var issues []ValidationIssue
+	var events map[string]struct{}
+	for i, dialog := range v.Dialog {
+		for _, partyIdx := range dialog.Parties.Indices() {
+			if partyIdx < 0 || partyIdx >= len(v.Parties) {
+				issues = append(issues, issuef(fmt.Sprintf("/dialog/%d/parties", i), IssueInvalidPartyIndex,
+					"dialog at index %d references invalid party index: %d", i, partyIdx))
+			}
+		}
+		issues = append(issues, v.validateOriginator(i, &dialog)...)
+		issues = append(issues, v.validateTransfer(i, &dialog)...)
+		for j, h := range dialog.PartyHistory {
+			if h.Party < 0 || h.Party >= len(v.Parties) {
+				issues = append(issues, issuef(fmt.Sprintf("/dialog/%d/party_history/%d/party", i, j), IssueInvalidPartyIndex,
+					"dialog at index %d party_history %d references invalid party index: %d", i, j, h.Party))
+			}
+			if events == nil {
+				events = v.extensionRegistry().AllowedPartyEvents()
+			}
+			if _, ok := events[h.Event]; !ok {
+				issues = append(issues, issuef(fmt.Sprintf("/dialog/%d/party_history/%d/event", i, j), IssueInvalidPartyEvent,
+					"dialog at index %d party_history %d has unknown event: %q", i, j, h.Event))
+			}
+		}
+		if dialog.Type == "" {
+			issues = append(issues, issuef(fmt.Sprintf("/dialog/%d/type", i), IssueMissingField,
+				"dialog at index %d missing required field: type", i))
+		}
+		if dialog.StartTime == nil {
+			issues = append(issues, issuef(fmt.Sprintf("/dialog/%d/start", i), IssueMissingField,
+				"dialog at index %d missing required field: start", i))
+		}
+	}
+	return issues
+}
+
+func (v *VCon) validateOriginator(i int, dialog *Dialog) []ValidationIssue {
+	if dialog.Originator == nil {
+		return nil
+	}
+	path := fmt.Sprintf("/dialog/%d/originator", i)
+	originator := *dialog.Originator
+	if originator < 0 || originator >= len(v.Parties) {
+		return []ValidationIssue{issuef(path, IssueInvalidOriginator,
+			"dialog at index %d references invalid originator index: %d", i, originator)}
+	}
+	if parties := dialog.partyIndices(); len(parties) > 0 && !slices.Contains(parties, originator) {
+		return []ValidationIssue{issuef(path, IssueOriginatorNotParty,
+			"dialog at index %d originator %d is not one of its parties", i, originator)}
+	}
+	return nil
+}
+
+// validateTransfer checks the indices of a transfer dialog: transferee,
+// transferor and transfer_target name parties, original, consultation and
+// target_dialog name dialogs.
+func (v *VCon) validateTransfer(i int, dialog *Dialog) []ValidationIssue {
+	var issues []ValidationIssue
+	check := func(field string, ref *IntOrSlice, n int, code, kind string) {
+		if ref == nil || ref.IsZero() {
+			return
+		}
+		_, single := ref.AsInt()
+		for j, idx := range ref.AsSlice() {
+			if idx >= 0 && idx < n {
+				continue
+			}
+			path := fmt.Sprintf("/dialog/%d/%s", i, field)
+			if !single {
+				path += fmt.Sprintf("/%d", j)
+			}
+			issues = append(issues, issuef(path, code,
+				"dialog at index %d %s references invalid %s index: %d", i, field, kind, idx))
+		}
+	}
+	partyRef := func(p *int) *IntOrSlice {
+		if p == nil {
+			return nil
+		}
+		return NewIntValue(*p)
+	}
+	check("transferee", partyRef(dialog.Transferee), len(v.Parties), IssueInvalidPartyIndex, "party")
+	check("transferor", partyRef(dialog.Transferor), len(v.Parties), IssueInvalidPartyIndex, "party")
+	check("transfer_target", dialog.TransferTarget, len(v.Parties), IssueInvalidPartyIndex, "party")
+	check("original", dialog.Original, len(v.Dialog), IssueInvalidDialogIndex, "dialog")
+	check("consultation", dialog.Consultation, len(v.Dialog), IssueInvalidDialogIndex, "dialog")
+	check("target_dialog", dialog.TargetDialog, len(v.Dialog), IssueInvalidDialogIndex, "dialog")
+	return issues
+}
+
+func (v *VCon) validateAnalysis() []ValidationIssue {
+	var issues []ValidationIssue
+	for i, analysis := range v.Analysis {
+		if analysis.Vendor == "" {
+			issues = append(issues, issuef(fmt.Sprintf("/analysis/%d/vendor", i), IssueMissingField,
+				"analysis at index %d missing required field: vendor", i))
+		}
+		_, single := remapIndices(analysis.Dialog, identityIndex).(int)
+		for j, dialogIdx := range analysisDialogs(analysis.Dialog) {
+			if dialogIdx >= 0 && dialogIdx < len(v.Dialog) {
+				continue
+			}
+			path := fmt.Sprintf("/analysis/%d/dialog/%d", i, j)
+			if single {
+				path = fmt.Sprintf("/analysis/%d/dialog", i)
+			}
+			issues = append(issues, issuef(path, IssueInvalidDialogIndex,
+				"analysis at index %d references invalid dialog index: %d", i, dialogIdx))
+		}
+	}
+	return issues
+}
+
+func (v *VCon) validateAttachments() []ValidationIssue {
+	var issues []ValidationIssue
+	for i, att := range v.Attachments {
+		if att.isTags() {
+			continue // describes the whole vCon; its dialog and party are nominal
+		}
+		path := fmt.Sprintf("/attachments/%d/dialog", i)
+		if att.DialogIdx == nil {
+			issues = append(issues, issuef(path, IssueMissingField,
+				"attachment at index %d missing required field: dialog", i))
+		} else if *att.DialogIdx < 0 || *att.DialogIdx >= len(v.Dialog) {
+			issues = append(issues, issuef(path, IssueInvalidDialogIndex,
+				"attachment at index %d references invalid dialog index: %d", i, *att.DialogIdx))
+		}
+		if att.PartyIdx < 0 || att.PartyIdx >= len(v.Parties) {
+			issues = append(issues, issuef(fmt.Sprintf("/attachments/%d/party", i), IssueInvalidPartyIndex,
+				"attachment at index %d references invalid party index: %d", i, att.PartyIdx))
+		}
+	}
+	return issues
+}
+
+// validateContent checks the encoding and mediatype of every dialog,
+// attachment and analysis entry.
+func (v *VCon) validateContent(cfg *validateConfig) []ValidationIssue {
+	var issues []ValidationIssue
+	check := func(path, encoding, mediaType string, encodings []string) {
+		if encoding != "" && !slices.Contains(encodings, encoding) {
+			issues = append(issues, issuef(path+"/encoding", IssueInvalidEncoding,
+				"%s: invalid encoding %q (want one of %s)", path, encoding, strings.Join(encodings, ", ")))
+		}
+		if mediaType == "" {
+			return
+		}
+		base, _, err := mime.ParseMediaType(mediaType)
+		if err != nil || strings.Count(base, "/") != 1 || strings.HasPrefix(base, "/") || strings.HasSuffix(base, "/") {
+			issues = append(issues, issuef(path+"/mediatype", IssueInvalidMediaType,
+				"%s: malformed mediatype %q", path, mediaType))
+			return
+		}
+		if !cfg.allowsMediaType(base) {
+			issues = append(issues, issuef(path+"/mediatype", IssueUnsupportedMedia,
+				"%s: unsupported mediatype %q", path, mediaType))
+		}
+	}
+	for i, d := range v.Dialog {
+		check(fmt.Sprintf("/dialog/%d", i), d.Encoding, d.MediaType, ValidEncodings)
+	}
+	for i, a := range v.Attachments {
+		check(fmt.Sprintf("/attachments/%d", i), a.Encoding, a.MediaType, ValidAttachmentEncodings)
+	}
+	for i, a := range v.Analysis {
+		check(fmt.Sprintf("/analysis/%d", i), a.Encoding, a.MediaType, ValidEncodings)
+	}
+	return issues
+}
+
+// validateContentHashes checks every content_hash in the vCon with
+// ContentHash.Check, so a malformed hash is found here rather than when the
+// content is first verified.
+func (v *VCon) validateContentHashes() []ValidationIssue {
+	var issues []ValidationIssue
+	check := func(path string, hashes ContentHashList) {
+		for j, ch := range hashes {
+			p := path + "/content_hash"
+			if len(hashes) > 1 {
+				p += fmt.Sprintf("/%d", j)
+			}
+			if err := ch.Check(); err != nil {
+				issues = append(issues, issuef(p, IssueInvalidContentHash, "%s: %s", path, err))
+			}
+		}
+	}
+	if v.Redacted != nil {
+		check("/redacted", v.Redacted.ContentHash)
+	}
+	if v.Amended != nil {
+		check("/amended", v.Amended.ContentHash)
+	}
+	for i, g := range v.Group {
+		check(fmt.Sprintf("/group/%d", i), g.ContentHash)
+	}
+	for i, d := range v.Dialog {
+		check(fmt.Sprintf("/dialog/%d", i), d.ContentHash)
+	}
+	for i, a := range v.Attachments {
+		check(fmt.Sprintf("/attachments/%d", i), a.ContentHash)
+	}
+	for i, a := range v.Analysis {
+		check(fmt.Sprintf("/analysis/%d", i), a.ContentHash)
+	}
+	return issues
+}
+
+// validateTimes warns about timestamps stored with a UTC offset other than
+// zero and about parties declaring an unknown time zone. Timestamps are kept
+// with the offset they were read with, so these are reported rather than
+// silently normalised.
+func (v *VCon) validateTimes() []ValidationIssue {
+	var issues []ValidationIssue
+	check := func(path string, t time.Time) {
+		if _, offset := t.Zone(); offset != 0 && !t.IsZero() {
+			issues = append(issues, warnf(path, IssueNonUTCTimestamp,
+				"%s: timestamp %s is not UTC", path, t.Format(time.RFC3339)))
+		}
+	}
+	check("/created_at", v.CreatedAt)
+	if v.UpdatedAt != nil {
+		check("/updated_at", *v.UpdatedAt)
+	}
+	for i, p := range v.Parties {
+		if _, err := p.Location(); err != nil {
+			issues = append(issues, warnf(fmt.Sprintf("/parties/%d/%s", i, PartyTimezoneKey), IssueInvalidTimezone,
+				"party at index %d: %s", i, err))
+		}
+	}
+	for i, d := range v.Dialog {
+		if d.StartTime != nil {
+			check(fmt.Sprintf("/dialog/%d/start", i), *d.StartTime)
+		}
+		for j, h := range d.PartyHistory {
+			check(fmt.Sprintf("/dialog/%d/party_history/%d/time", i, j), h.Time)
+		}
+	}
+	for i, a := range v.Attachments {
+		check(fmt.Sprintf("/attachments/%d/start", i), a.StartTime)
+	}
+	return issues
+}
+
+// validatePartyHistory warns about party_history events outside the
+// dialog's start and duration, events out of chronological order, and
+// parties dropping before they joined. Parties listed by the dialog whose
+// first event is not a join are taken to be present from the start.
+func (v *VCon) validatePartyHistory() []ValidationIssue {
+	var issues []ValidationIssue
+	for i, d := range v.Dialog {
+		if len(d.PartyHistory) == 0 {
+			continue
+		}
+		var end time.Time
+		if d.StartTime != nil && d.Duration > 0 {
+			end = d.StartTime.Add(time.Duration(d.Duration * float64(time.Second)))
+		}
+
+		joined := map[int]bool{}
+		for _, p := range d.Parties.Indices() {
+			joined[p] = true
+		}
+		seen := map[int]bool{}
+		for _, h := range d.PartyHistory {
+			if !seen[h.Party] && h.Event == string(PartyEventJoin) {
+				joined[h.Party] = false
+			}
+			seen[h.Party] = true
+		}
+
+		for j, h := range d.PartyHistory {
+			path := fmt.Sprintf("/dialog/%d/party_history/%d", i, j)
+			at := h.Time.Format(time.RFC3339)
+			switch {
+			case d.StartTime != nil && h.Time.Before(*d.StartTime):
+				issues = append(issues, warnf(path+"/time", IssuePartyHistory,
+					"%s: event at %s is before the dialog starts", path, at))
+			case !end.IsZero() && h.Time.After(end):
+				issues = append(issues, warnf(path+"/time", IssuePartyHistory,
+					"%s: event at %s is after the dialog ends", path, at))
+			}
+			if j > 0 && h.Time.Before(d.PartyHistory[j-1].Time) {
+				issues = append(issues, warnf(path+"/time", IssuePartyHistory,
+					"%s: event at %s is earlier than the one before it", path, at))
+			}
+			switch h.Event {
+			case string(PartyEventJoin):
+				joined[h.Party] = true
+			case string(PartyEventDrop):
+				if !joined[h.Party] {
+					issues = append(issues, warnf(path, IssuePartyHistory,
+						"%s: party %d drops at %s without having joined", path, h.Party, at))
+				}
+				joined[h.Party] = false
+			}
+		}
+	}
+	return issues
+}
+
+func (v *VCon) validationIssues(cfg *validateConfig) []ValidationIssue {
+	var issues []ValidationIssue
+	issues = append(issues, v.validateCoreFields()...)
+	issues = append(issues, v.validateMutualExclusion()...)
+	issues = append(issues, v.validateCriticalExtensions()...)
+	issues = append(issues, v.validateParties(cfg)...)
+	issues = append(issues, v.validateDialogs()...)
+	issues = append(issues, v.validateAnalysis()...)
+	issues = append(issues, v.validateAttachments()...)
+	issues = append(issues, v.validateContent(cfg)...)
+	issues = append(issues, v.validateContentHashes()...)
+	issues = append(issues, v.validateTimes()...)
+	issues = append(issues, v.validatePartyHistory()...)
+	issues = append(issues, v.validateSchema(cfg.schema)...)
+	issues = append(issues, v.validateDurations(cfg)...)
+	issues = append(issues, v.validateContentIntegrity(cfg)...)
+	for _, path := range v.droppedProperties {
+		issues = append(issues, warnf(path, IssueUnknownProperty, "%s: non-standard property dropped", path))
+	}
+	return issues
+}
+
+// Validate validates the VCon structure. The error is a *ValidationError
+// listing every issue.
+func (v *VCon) Validate(opts ...ValidateOption) error {
+	return v.ValidateContext(context.Background(), opts...)
+}
+
+// ValidateContext is Validate with a context controlling the media fetches
+// and probes made by WithDurationCheck and the fetches made by
+// WithContentVerification.
+func (v *VCon) ValidateContext(ctx context.Context, opts ...ValidateOption) error {
+	if ok, issues := v.IsValidContext(ctx, opts...); !ok {
+		return &ValidationError{Issues: issues}
+	}
+	return nil
+}
+
+// IsValid validates the VCon and reports whether it is free of errors,
+// together with every issue found.
+func (v *VCon) IsValid(opts ...ValidateOption) (bool, []ValidationIssue) {
+	return v.IsValidContext(context.Background(), opts...)
+}
+
+// IsValidContext is IsValid with a context, as for ValidateContext.
+func (v *VCon) IsValidContext(ctx context.Context, opts ...ValidateOption) (bool, []ValidationIssue) {
+	cfg := &validateConfig{ctx: ctx}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	issues := v.validationIssues(cfg)
+	return !hasErrors(issues), issues
+}
+
+func hasErrors(issues []ValidationIssue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
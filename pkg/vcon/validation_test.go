@@ -0,0 +1,282 @@
+package vcon
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationIssues(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	now := time.Now().UTC()
+	v.AddDialog(Dialog{Type: "text", StartTime: &now, Parties: NewPartyRefs(0, 3), Originator: IntPtr(1)})
+	v.AddDialog(Dialog{StartTime: &now, Parties: NewPartyRefs(0)})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Dialog: []int{0, 7}})
+	v.AddAttachment(Attachment{Purpose: "notes"})
+
+	ok, issues := v.IsValid()
+	assert.False(t, ok)
+	assert.Equal(t, []ValidationIssue{
+		{Path: "/dialog/0/parties", Code: IssueInvalidPartyIndex, Message: "dialog at index 0 references invalid party index: 3", Severity: SeverityError},
+		{Path: "/dialog/0/originator", Code: IssueInvalidOriginator, Message: "dialog at index 0 references invalid originator index: 1", Severity: SeverityError},
+		{Path: "/dialog/1/type", Code: IssueMissingField, Message: "dialog at index 1 missing required field: type", Severity: SeverityError},
+		{Path: "/analysis/0/dialog/1", Code: IssueInvalidDialogIndex, Message: "analysis at index 0 references invalid dialog index: 7", Severity: SeverityError},
+		{Path: "/attachments/0/dialog", Code: IssueMissingField, Message: "attachment at index 0 missing required field: dialog", Severity: SeverityError},
+	}, issues)
+
+	err := v.Validate()
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, issues, verr.Issues)
+	assert.Equal(t, "dialog at index 0 references invalid party index: 3 (and 4 more issues)", err.Error())
+
+	data, err := json.Marshal(issues[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"path":"/dialog/0/parties","code":"invalid_party_index",
+		"message":"dialog at index 0 references invalid party index: 3","severity":"error"}`, string(data))
+
+	valid := New("example.com")
+	ok, issues = valid.IsValid()
+	assert.True(t, ok)
+	assert.Empty(t, issues)
+	assert.NoError(t, valid.Validate())
+}
+
+func TestValidateReferences(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.AddParty(Party{Name: "Bob"})
+	now := time.Now().UTC()
+	v.AddDialog(Dialog{Type: "text", StartTime: &now, Parties: NewPartyRefs(0, 1),
+		PartyHistory: []PartyHistory{{Party: 0, Event: "join", Time: now}, {Party: 4, Event: "join", Time: now}}})
+	v.AddDialog(Dialog{Type: "transfer", StartTime: &now,
+		Transferee: IntPtr(0), Transferor: IntPtr(2), TransferTarget: NewIntSliceValue([]int{1, 9}),
+		Original: NewIntValue(0), Consultation: NewIntValue(5), TargetDialog: NewIntValue(-1)})
+	v.AddAttachment(Attachment{Purpose: "notes", DialogIdx: IntPtr(0), PartyIdx: 3})
+
+	_, issues := v.IsValid()
+	paths := map[string]string{}
+	for _, issue := range issues {
+		paths[issue.Path] = issue.Code
+	}
+	assert.Equal(t, map[string]string{
+		"/dialog/0/party_history/1/party": IssueInvalidPartyIndex,
+		"/dialog/1/transferor":            IssueInvalidPartyIndex,
+		"/dialog/1/transfer_target/1":     IssueInvalidPartyIndex,
+		"/dialog/1/consultation":          IssueInvalidDialogIndex,
+		"/dialog/1/target_dialog":         IssueInvalidDialogIndex,
+		"/attachments/0/party":            IssueInvalidPartyIndex,
+	}, paths)
+	assert.Contains(t, issues, issuef("/dialog/1/transferor", IssueInvalidPartyIndex,
+		"dialog at index 1 transferor references invalid party index: 2"))
+}
+
+func TestValidateAnalysisDialogFromJSON(t *testing.T) {
+	for _, ref := range []string{`7`, `[0, 7]`} {
+		v, err := BuildFromJSON(`{
+			"uuid": "018e6e34-6a8b-8000-8000-000000000000",
+			"vcon": "0.4.0",
+			"created_at": "2024-01-01T00:00:00Z",
+			"parties": [{"name": "Alice"}],
+			"dialog": [{"type": "text", "start": "2024-01-01T00:00:00Z", "parties": [0], "body": "hi", "encoding": "none"}],
+			"analysis": [{"type": "summary", "vendor": "acme", "dialog": ` + ref + `, "body": "ok", "encoding": "none"}]
+		}`)
+		require.NoError(t, err)
+		_, issues := v.IsValid()
+		var codes []string
+		for _, issue := range issues {
+			if issue.Code == IssueInvalidDialogIndex {
+				codes = append(codes, issue.Path)
+			}
+		}
+		assert.Len(t, codes, 1, "analysis dialog %s", ref)
+	}
+}
+
+func TestValidateEncodingAndMediaType(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	now := time.Now().UTC()
+	v.AddDialog(Dialog{Type: "text", StartTime: &now, Parties: NewPartyRefs(0), Body: "aGk", Encoding: "base32", MediaType: "text/plain; charset=utf-8"})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &now, Parties: NewPartyRefs(0), URL: "https://example.com/a.amr", MediaType: "audio/amr"})
+	v.AddAttachment(Attachment{Purpose: "notes", DialogIdx: IntPtr(0), Body: "x", Encoding: "none", MediaType: "wav"})
+	v.AddAnalysis(Analysis{Type: "summary", Vendor: "acme", Body: "{}", Encoding: "json", MediaType: "application/vnd.acme+json"})
+
+	codes := func(issues []ValidationIssue) map[string]string {
+		m := map[string]string{}
+		for _, issue := range issues {
+			m[issue.Path] = issue.Code
+		}
+		return m
+	}
+
+	_, issues := v.IsValid()
+	assert.Equal(t, map[string]string{
+		"/dialog/0/encoding":       IssueInvalidEncoding,
+		"/attachments/0/mediatype": IssueInvalidMediaType,
+	}, codes(issues))
+
+	_, issues = v.IsValid(WithAllowedMediaTypes(SupportedMIMETypes...))
+	assert.Equal(t, map[string]string{
+		"/dialog/0/encoding":       IssueInvalidEncoding,
+		"/dialog/1/mediatype":      IssueUnsupportedMedia,
+		"/attachments/0/mediatype": IssueInvalidMediaType,
+		"/analysis/0/mediatype":    IssueUnsupportedMedia,
+	}, codes(issues))
+
+	v.Dialog[0].Encoding = "base64url"
+	v.Attachments[0].MediaType = ""
+	assert.NoError(t, v.Validate())
+	assert.Error(t, v.Validate(WithAllowedMediaTypes(SupportedMIMETypes...)))
+	assert.NoError(t, v.Validate(WithAllowedMediaTypes(SupportedMIMETypes...), WithAllowedMediaTypes("audio/amr", "application/vnd.acme+json")))
+}
+
+func TestValidateTimes(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*60*60)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, paris)
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.AddParty(Party{Name: "Bob", Extra: ExtraProperties{PartyTimezoneKey: json.RawMessage(`"Mars/Olympus_Mons"`)}})
+	v.AddDialog(Dialog{Type: "text", StartTime: &start, Parties: NewPartyRefs(0, 1),
+		PartyHistory: []PartyHistory{{Party: 0, Event: "join", Time: start.UTC()}}})
+
+	ok, issues := v.IsValid()
+	assert.True(t, ok, "time issues are warnings")
+	require.Len(t, issues, 2)
+	assert.Equal(t, "/parties/1/timezone", issues[0].Path)
+	assert.Equal(t, IssueInvalidTimezone, issues[0].Code)
+	assert.Equal(t, ValidationIssue{Path: "/dialog/0/start", Code: IssueNonUTCTimestamp,
+		Message: "/dialog/0/start: timestamp 2024-06-01T12:00:00+02:00 is not UTC", Severity: SeverityWarning}, issues[1])
+	assert.NoError(t, v.Validate())
+
+	// The offset survives a round trip rather than being normalised away.
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	loaded, err := BuildFromJSON(string(data))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06-01T12:00:00+02:00", loaded.Dialog[0].StartTime.Format(time.RFC3339))
+	_, issues = loaded.IsValid()
+	assert.Len(t, issues, 2)
+}
+
+func TestValidateContentHashes(t *testing.T) {
+	now := time.Now().UTC()
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &now, Parties: NewPartyRefs(0), URL: "https://example.com/a.wav",
+		ContentHash: ContentHashList{ComputeSHA512([]byte("a")), {Algorithm: "sha1", Hash: "abc"}}})
+	v.AddAttachment(Attachment{Purpose: "notes", DialogIdx: IntPtr(0), URL: "https://example.com/n.txt", StartTime: now,
+		ContentHash: ContentHashList{{Algorithm: "sha512", Hash: "not-a-digest"}}})
+	v.Amended = &AmendedObject{UUID: "x", ContentHash: ContentHashList{ComputeSHA512([]byte("b"))}}
+
+	ok, issues := v.IsValid()
+	assert.False(t, ok)
+	paths := map[string]string{}
+	for _, issue := range issues {
+		paths[issue.Path] = issue.Code
+	}
+	assert.Equal(t, map[string]string{
+		"/dialog/0/content_hash/1":    IssueInvalidContentHash,
+		"/attachments/0/content_hash": IssueInvalidContentHash,
+	}, paths)
+	assert.Contains(t, issues[0].Message, `unrecognised content_hash algorithm "sha1"`)
+}
+
+func TestValidatePartyURIs(t *testing.T) {
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice", Tel: "+12025551234", Mailto: "mailto:alice@example.com"})
+	v.AddParty(Party{Name: "Bob", Tel: "tel:+18005559876", Mailto: "mailto:bob"})
+
+	ok, issues := v.IsValid()
+	assert.True(t, ok, "malformed URIs are warnings by default")
+	require.Len(t, issues, 2)
+	assert.Equal(t, ValidationIssue{Path: "/parties/0/tel", Code: IssueInvalidURI,
+		Message: `party at index 0 tel: "+12025551234" does not start with tel:`, Severity: SeverityWarning}, issues[0])
+	assert.Equal(t, "/parties/1/mailto", issues[1].Path)
+
+	ok, issues = v.IsValid(WithStrictURIs())
+	assert.False(t, ok)
+	require.Len(t, issues, 2)
+	assert.Equal(t, SeverityError, issues[0].Severity)
+	assert.Error(t, v.Validate(WithStrictURIs()))
+}
+
+func TestValidatePartyHistoryEvents(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	v := New("example.com")
+	v.AddParty(Party{Name: "Alice"})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Duration: 60, Parties: NewPartyRefs(0), URL: "https://example.com/a.wav",
+		PartyHistory: []PartyHistory{
+			{Party: 0, Event: "join", Time: start},
+			{Party: 0, Event: "keydown", Button: "5", Time: start.Add(time.Second)},
+			{Party: 0, Event: "whisper", Time: start.Add(2 * time.Second)},
+			{Party: 1, Event: "leave", Time: start.Add(3 * time.Second)},
+		}})
+
+	err := v.Validate()
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	var got []string
+	for _, issue := range verr.Issues {
+		got = append(got, issue.Code+" "+issue.Path)
+	}
+	assert.Equal(t, []string{
+		"invalid_party_event /dialog/0/party_history/2/event",
+		"invalid_party_index /dialog/0/party_history/3/party",
+		"invalid_party_event /dialog/0/party_history/3/event",
+	}, got)
+	assert.Equal(t, `dialog at index 0 party_history 2 has unknown event: "whisper"`, verr.Issues[0].Message)
+
+	// An extension can define more events.
+	reg := NewExtensionRegistry()
+	reg.Register(testEventExtension{testExtension: testExtension{name: "COACH"}, events: []string{"whisper"}})
+	WithRegistry(reg)(v)
+	v.Dialog[0].PartyHistory = v.Dialog[0].PartyHistory[:3]
+	assert.NoError(t, v.Validate())
+}
+
+func TestValidatePartyHistory(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	v := New("example.com")
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		v.AddParty(Party{Name: name})
+	}
+	// Alice and Bob are on the call from the start, Carol joins later.
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Duration: 600, Parties: NewPartyRefs(0, 1, 2), URL: "https://example.com/a.wav",
+		PartyHistory: []PartyHistory{
+			{Party: 2, Event: "join", Time: at(60)},
+			{Party: 1, Event: "hold", Time: at(120)},
+			{Party: 1, Event: "drop", Time: at(300)},
+			{Party: 2, Event: "drop", Time: at(500)},
+			{Party: 2, Event: "join", Time: at(550)}, // rejoins
+			{Party: 0, Event: "drop", Time: at(600)},
+		}})
+	v.AddDialog(Dialog{Type: "recording", StartTime: &start, Duration: 60, Parties: NewPartyRefs(0, 1), URL: "https://example.com/b.wav",
+		PartyHistory: []PartyHistory{
+			{Party: 1, Event: "drop", Time: at(-5)},
+			{Party: 1, Event: "drop", Time: at(30)},
+			{Party: 2, Event: "drop", Time: at(20)},
+			{Party: 0, Event: "drop", Time: at(90)},
+		}})
+
+	ok, issues := v.IsValid()
+	assert.True(t, ok, "party history issues are warnings")
+	var messages []string
+	for _, issue := range issues {
+		assert.Equal(t, IssuePartyHistory, issue.Code)
+		messages = append(messages, issue.Message)
+	}
+	assert.Equal(t, []string{
+		"/dialog/1/party_history/0: event at 2024-06-01T11:59:55Z is before the dialog starts",
+		"/dialog/1/party_history/1: party 1 drops at 2024-06-01T12:00:30Z without having joined",
+		"/dialog/1/party_history/2: event at 2024-06-01T12:00:20Z is earlier than the one before it",
+		"/dialog/1/party_history/2: party 2 drops at 2024-06-01T12:00:20Z without having joined",
+		"/dialog/1/party_history/3: event at 2024-06-01T12:01:30Z is after the dialog ends",
+	}, messages)
+}
@@ -1,18 +1,19 @@
 package vcon
 
 import (
+	"context"
 	"crypto/sha1"
 	_ "embed"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
 //go:embed schema/vcon.json
@@ -26,6 +27,8 @@ const (
 	PropertyHandlingDefault = "default" // Keep non-standard properties
 	PropertyHandlingStrict  = "strict"  // Remove non-standard properties
 	PropertyHandlingMeta    = "meta"    // Move non-standard properties to meta
+	PropertyHandlingReject  = "reject"  // Fail on non-standard properties
+	PropertyHandlingReport  = "report"  // Remove non-standard properties, reporting each
 )
 
 // Allowed properties for validation
@@ -55,40 +58,51 @@ var (
 	AllowedAttachmentProperties = map[string]struct{}{
 		"body": {}, "encoding": {}, "url": {}, "content_hash": {}, "dialog": {},
 		"party": {}, "start": {}, "mediatype": {}, "filename": {}, "purpose": {},
+		"type": {},
 	}
 
 	AllowedAnalysisProperties = map[string]struct{}{
 		"type": {}, "dialog": {}, "mediatype": {}, "filename": {}, "vendor": {},
 		"product": {}, "schema": {}, "body": {}, "encoding": {}, "url": {},
-		"content_hash": {},
+		"content_hash": {}, "meta": {},
 	}
 )
 
-// Global for UUID8 timestamp tracking
-var lastV8Timestamp int64
+// lastV8Timestamp is the encoded timestamp of the last UUID8Time UUID,
+// guarded by uuid8Mu.
+var (
+	uuid8Mu         sync.Mutex
+	lastV8Timestamp uint64
+)
 
 // Core Types
 
 // VCon is the top-level container.
 type VCon struct {
-	Vcon        string            `json:"vcon,omitempty"`
-	UUID        string            `json:"uuid"`
-	CreatedAt   time.Time         `json:"created_at"`
-	UpdatedAt   *time.Time        `json:"updated_at,omitempty"`
-	Subject     string            `json:"subject,omitempty"`
-	Group       []json.RawMessage `json:"group,omitempty"`
-	Redacted    *RedactedObject   `json:"redacted,omitempty"`
-	Amended     *AmendedObject    `json:"amended,omitempty"`
-	Extensions  []string          `json:"extensions,omitempty"`
-	Critical    []string          `json:"critical,omitempty"`
-	Parties     []Party           `json:"parties"`
-	Dialog      []Dialog          `json:"dialog,omitempty"`
-	Analysis    []Analysis        `json:"analysis,omitempty"`
-	Attachments []Attachment      `json:"attachments,omitempty"`
+	Vcon        string          `json:"vcon,omitempty"`
+	UUID        string          `json:"uuid"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   *time.Time      `json:"updated_at,omitempty"`
+	Subject     string          `json:"subject,omitempty"`
+	Group       []GroupRef      `json:"group,omitempty"`
+	Redacted    *RedactedObject `json:"redacted,omitempty"`
+	Amended     *AmendedObject  `json:"amended,omitempty"`
+	Extensions  []string        `json:"extensions,omitempty"`
+	Critical    []string        `json:"critical,omitempty"`
+	Parties     []Party         `json:"parties"`
+	Dialog      []Dialog        `json:"dialog,omitempty"`
+	Analysis    []Analysis      `json:"analysis,omitempty"`
+	Attachments []Attachment    `json:"attachments,omitempty"`
+
+	// Extra holds non-standard properties, such as extension parameters.
+	Extra ExtraProperties `json:"-"`
 
 	// Internal fields
-	propertyHandling string             `json:"-"`
-	registry         *ExtensionRegistry `json:"-"`
+	propertyHandling  string             `json:"-"`
+	registry          *ExtensionRegistry `json:"-"`
+	droppedProperties []string
+	fetcher           ContentFetcher
+	httpClient        *http.Client
 }
 
 // Analysis holds machine-generated artefacts.
@@ -104,6 +118,19 @@ type Analysis struct {
 	Encoding    string          `json:"encoding,omitempty"`
 	URL         string          `json:"url,omitempty"`
 	ContentHash ContentHashList `json:"content_hash,omitempty"`
+
+	// Meta holds non-standard metadata, such as the Provenance set by
+	// SetProvenance, and the properties moved here by PropertyHandlingMeta.
+	Meta map[string]interface{} `json:"meta,omitempty"`
+
+	// Extra holds non-standard properties, such as extension parameters.
+	Extra ExtraProperties `json:"-"`
+
+	// fetched caches external content retrieved by Content or retained by
+	// AddExternalData(WithRetainedBody())
+	fetched []byte
+	// fetcher is the ContentFetcher given to AddExternalData, if any
+	fetcher ContentFetcher
 }
 
 // ProcessProperties handles properties based on the provided mode.
@@ -128,7 +155,7 @@ func ProcessProperties(obj map[string]interface{}, allowedProps map[string]struc
 
 	// Handle non-standard properties based on mode
 	switch mode {
-	case PropertyHandlingStrict:
+	case PropertyHandlingStrict, PropertyHandlingReject, PropertyHandlingReport:
 		// Ignore non-standard properties
 	case PropertyHandlingMeta:
 		// Move non-standard properties to meta
@@ -187,71 +214,6 @@ func New(domain string, propertyHandling ...string) *VCon {
 	return vcon
 }
 
-func validateAgainstSchema(rawMap map[string]interface{}) error {
-	compiler := jsonschema.NewCompiler()
-	compiler.DefaultDraft(jsonschema.Draft7)
-	// Override the default email format validator to also accept mailto: URIs,
-	// since the vCon spec defines the mailto field as a MAILTO URL (RFC 6068).
-	compiler.RegisterFormat(&jsonschema.Format{
-		Name: "email",
-		Validate: func(v interface{}) error {
-			s, ok := v.(string)
-			if !ok {
-				return nil
-			}
-			s = strings.TrimPrefix(s, "mailto:")
-			parts := strings.SplitN(s, "@", 2)
-			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-				return fmt.Errorf("invalid email: %s", v)
-			}
-			return nil
-		},
-	})
-
-	var schemaData interface{}
-	if err := json.Unmarshal(vconSchema, &schemaData); err != nil {
-		return err
-	}
-	if err := compiler.AddResource("vcon.schema.json", schemaData); err != nil {
-		return err
-	}
-	schema, err := compiler.Compile("vcon.schema.json")
-	if err != nil {
-		return err
-	}
-	if err := schema.Validate(rawMap); err != nil {
-		return fmt.Errorf("schema validation failed: %w", err)
-	}
-	return nil
-}
-
-func processNestedSlices(m map[string]interface{}, handling string) {
-	sliceProps := []struct {
-		key     string
-		allowed map[string]struct{}
-	}{
-		{"parties", AllowedPartyProperties},
-		{"dialog", AllowedDialogProperties},
-		{"attachments", AllowedAttachmentProperties},
-		{"analysis", AllowedAnalysisProperties},
-	}
-	for _, sp := range sliceProps {
-		items, ok := m[sp.key].([]interface{})
-		if !ok {
-			continue
-		}
-		processed := make([]interface{}, len(items))
-		for i, item := range items {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				processed[i] = ProcessProperties(itemMap, sp.allowed, handling)
-			} else {
-				processed[i] = item
-			}
-		}
-		m[sp.key] = processed
-	}
-}
-
 // BuildFromJSON creates a VCon from a JSON string
 func BuildFromJSON(jsonStr string, propertyHandling ...string) (*VCon, error) {
 	handling := PropertyHandlingDefault
@@ -264,28 +226,34 @@ func BuildFromJSON(jsonStr string, propertyHandling ...string) (*VCon, error) {
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	// Auto-detect v0.0.3 and migrate
-	if ver, ok := rawMap["vcon"].(string); ok && ver == "0.0.3" {
-		migrateV003ToV040(rawMap)
+	// Upgrade documents written for an older spec version
+	if ver, ok := rawMap["vcon"].(string); ok && ver != SpecVersion && migrations[ver] != nil {
+		if err := migrateDoc(rawMap, SpecVersion); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := validateAgainstSchema(rawMap); err != nil {
 		return nil, err
 	}
 
-	// Process top-level properties
-	processedMap := ProcessProperties(rawMap, AllowedVConProperties, handling)
-
-	// Handle created_at if it's a string
-	if createdAt, ok := processedMap["created_at"].(string); ok {
-		parsedTime, err := time.Parse(time.RFC3339, createdAt)
-		if err != nil {
-			return nil, fmt.Errorf("invalid created_at format: %w", err)
+	var dropped []string
+	switch handling {
+	case PropertyHandlingReject:
+		if paths := FindUnknownProperties(rawMap, DefaultRegistry); len(paths) > 0 {
+			return nil, &UnknownPropertiesError{Paths: paths}
 		}
-		processedMap["created_at"] = parsedTime
+	case PropertyHandlingReport:
+		dropped = FindUnknownProperties(rawMap, DefaultRegistry)
 	}
 
-	processNestedSlices(processedMap, handling)
+	// Process properties at every level
+	processedMap := ProcessVConProperties(rawMap, handling)
+
+	// Parse every timestamp
+	if err := normalizeTimestamps(processedMap); err != nil {
+		return nil, err
+	}
 
 	// Marshal back to JSON and then to VCon
 	processedJSON, err := json.Marshal(processedMap)
@@ -300,131 +268,133 @@ func BuildFromJSON(jsonStr string, propertyHandling ...string) (*VCon, error) {
 
 	vcon.propertyHandling = handling
 	vcon.registry = DefaultRegistry
+	vcon.droppedProperties = dropped
 	return &vcon, nil
 }
 
-// migrateV003ToV040 converts a v0.0.3 raw map to v0.4.0 format in-place.
-// migrateSliceItems applies a migration function to each map item in a JSON array field.
-func migrateSliceItems(m map[string]interface{}, key string, fn func(map[string]interface{})) {
-	items, ok := m[key].([]interface{})
-	if !ok {
-		return
+// normalizeTimestamps replaces the timestamp strings of a processed vCon map
+// (created_at, updated_at, the start of each dialog and attachment and the
+// time of each party_history event) with parsed times, so every form the
+// schema accepts unmarshals, and a bad one is reported by its path.
+func normalizeTimestamps(m map[string]interface{}) error {
+	if err := normalizeTimestamp(m, "created_at", "created_at"); err != nil {
+		return err
 	}
-	for _, item := range items {
-		if itemMap, ok := item.(map[string]interface{}); ok {
-			fn(itemMap)
+	if err := normalizeTimestamp(m, "updated_at", "updated_at"); err != nil {
+		return err
+	}
+	dialogs, _ := m["dialog"].([]interface{})
+	for i, d := range dialogs {
+		if dm, ok := d.(map[string]interface{}); ok {
+			if err := normalizeDialogTimestamps(dm, i); err != nil {
+				return err
+			}
 		}
 	}
-}
-
-// migrateEncodingAndHash converts "base64" to "base64url" and fixes content_hash format.
-func migrateEncodingAndHash(m map[string]interface{}) {
-	if enc, ok := m["encoding"].(string); ok && enc == "base64" {
-		m["encoding"] = "base64url"
+	attachments, _ := m["attachments"].([]interface{})
+	for i, a := range attachments {
+		if am, ok := a.(map[string]interface{}); ok {
+			if err := normalizeTimestamp(am, "start", fmt.Sprintf("attachments[%d].start", i)); err != nil {
+				return err
+			}
+		}
 	}
-	migrateContentHash(m)
+	return nil
 }
 
-func migrateV003ToV040(m map[string]interface{}) {
-	m["vcon"] = "0.4.0"
-
-	delete(m, "appended")
-	delete(m, "meta")
-
-	if _, ok := m["parties"]; !ok {
-		m["parties"] = []interface{}{}
-	}
-
-	migrateSliceItems(m, "dialog", func(dm map[string]interface{}) {
-		delete(dm, "alg")
-		delete(dm, "signature")
-		delete(dm, "meta")
-		migrateEncodingAndHash(dm)
-		delete(dm, "campaign")
-		delete(dm, "interaction_type")
-		delete(dm, "interaction_id")
-		delete(dm, "skill")
-	})
-
-	migrateSliceItems(m, "parties", func(pm map[string]interface{}) {
-		delete(pm, "role")
-		delete(pm, "contact_list")
-		delete(pm, "timezone")
-		delete(pm, "meta")
-	})
-
-	migrateSliceItems(m, "attachments", func(am map[string]interface{}) {
-		delete(am, "meta")
-		migrateEncodingAndHash(am)
-		// "dialog" is now required by the IETF schema
-		if _, ok := am["dialog"]; !ok {
-			am["dialog"] = float64(0)
-		}
-	})
-
-	migrateSliceItems(m, "analysis", func(am map[string]interface{}) {
-		delete(am, "meta")
-		migrateEncodingAndHash(am)
-		// "vendor" is now required by the IETF schema
-		if _, ok := am["vendor"]; !ok || am["vendor"] == "" {
-			am["vendor"] = "unknown"
+// normalizeDialogTimestamps normalizes the start of dialog i and the times
+// of its party_history events.
+func normalizeDialogTimestamps(d map[string]interface{}, i int) error {
+	if err := normalizeTimestamp(d, "start", fmt.Sprintf("dialog[%d].start", i)); err != nil {
+		return err
+	}
+	history, _ := d["party_history"].([]interface{})
+	for j, h := range history {
+		if hm, ok := h.(map[string]interface{}); ok {
+			if err := normalizeTimestamp(hm, "time", fmt.Sprintf("dialog[%d].party_history[%d].time", i, j)); err != nil {
+				return err
+			}
 		}
-	})
+	}
+	return nil
 }
 
-// migrateContentHash converts content_hash from old "alg:hash" format to "alg-hash".
-func migrateContentHash(m map[string]interface{}) {
-	ch, ok := m["content_hash"].(string)
-	if !ok || ch == "" {
-		return
+// normalizeTimestamp parses m[key] when it is a string; path names it in
+// errors.
+func normalizeTimestamp(m map[string]interface{}, key, path string) error {
+	s, ok := m[key].(string)
+	if !ok {
+		return nil
 	}
-	m["content_hash"] = strings.ReplaceAll(ch, ":", "-")
+	// RFC 3339 allows a lower-case "t" and "z", which time.Parse rejects.
+	t, err := time.Parse(time.RFC3339, strings.ToUpper(s))
+	if err != nil {
+		return fmt.Errorf("invalid %s format: %w", path, err)
+	}
+	m[key] = t
+	return nil
+}
+
+// DroppedProperties returns the JSON pointers of the non-standard properties
+// BuildFromJSON removed in PropertyHandlingReport mode. Validate reports
+// each of them as a warning.
+func (v *VCon) DroppedProperties() []string {
+	return v.droppedProperties
 }
 
-// UUID8DomainName generates a UUID8 using a domain name
+// UUID8DomainName generates a vCon UUIDv8 for a domain name: the time of
+// generation followed by 62 bits of the SHA-1 hash of the domain, as in the
+// vCon specification and its Python reference implementation.
 func UUID8DomainName(domain string) string {
-	// SHA1 hash the domain name
-	hasher := sha1.New()
-	hasher.Write([]byte(domain))
-	dnSHA1 := hasher.Sum(nil)
+	return UUID8Time(domainBits(domain))
+}
 
-	// Get upper 64 bits of the hash
-	hashUpper64 := dnSHA1[0:8]
-	var int64Val uint64
-	for _, b := range hashUpper64 {
-		int64Val = (int64Val << 8) | uint64(b)
-	}
+// UUID8DomainNameAt generates the vCon UUIDv8 of a domain name for the given
+// time. The same domain and time always give the same UUID, which matches the
+// one the Python reference implementation generates.
+func UUID8DomainNameAt(domain string, t time.Time) string {
+	return uuid8(uuid8Timestamp(t.UnixNano()), domainBits(domain))
+}
 
-	return UUID8Time(int64Val)
+// domainBits returns the upper 64 bits of the SHA-1 hash of domain; uuid8
+// keeps the lower 62 of them.
+func domainBits(domain string) uint64 {
+	sum := sha1.Sum([]byte(domain))
+	return binary.BigEndian.Uint64(sum[:8])
 }
 
-// UUID8Time generates a UUID8 using a timestamp and custom bits
+// UUID8Time generates a UUIDv8 from the current time and custom bits. The
+// lower 62 bits of customC62Bits fill custom_c; the upper two are replaced
+// by the variant. UUIDs generated by one process are strictly increasing,
+// even within the same 244ns timestamp step.
 func UUID8Time(customC62Bits uint64) string {
-	now := time.Now().UnixNano()
+	ts := uuid8Timestamp(time.Now().UnixNano())
 
-	// Ensure timestamp is monotonically increasing
-	if now <= lastV8Timestamp {
-		now = lastV8Timestamp + 1
+	uuid8Mu.Lock()
+	if ts <= lastV8Timestamp {
+		ts = lastV8Timestamp + 1
 	}
-	lastV8Timestamp = now
+	lastV8Timestamp = ts
+	uuid8Mu.Unlock()
 
-	// Create UUID v7 format: timestamp_ms + rand
-	// Then modify version bits to make it UUID v8
-	uuidV7, err := uuid.NewV7()
-	if err != nil {
-		// Fallback to V4 if V7 fails
-		uuidV7 = uuid.New()
-	}
-	uuidBytes := uuidV7[:]
-
-	// Set the version to 8
-	uuidBytes[6] = (uuidBytes[6] & 0x0F) | 0x80
+	return uuid8(ts, customC62Bits)
+}
 
-	// Create UUID from the bytes
-	uuidObj, _ := uuid.FromBytes(uuidBytes)
-	uuidStr := uuidObj.String()
+// uuid8Timestamp encodes a Unix time in nanoseconds as the 60 timestamp bits
+// of a vCon UUIDv8: 48 bits of milliseconds (unix_ts_ms) followed by the
+// upper 12 bits of the sub-millisecond fraction in 1/2^20 steps.
+func uuid8Timestamp(nanos int64) uint64 {
+	ms, ns := uint64(nanos/1e6), uint64(nanos%1e6)
+	subsec := ns << 20 / 1e6
+	return (ms&0xFFFFFFFFFFFF)<<12 | subsec>>8
+}
 
-	return uuidStr
+// uuid8 lays out a UUIDv8 from its 60 timestamp bits and custom bits.
+func uuid8(ts uint64, custom uint64) string {
+	var u uuid.UUID
+	binary.BigEndian.PutUint64(u[0:8], ts>>12<<16|0x8000|ts&0xFFF)
+	binary.BigEndian.PutUint64(u[8:16], custom&^(3<<62)|1<<63)
+	return u.String()
 }
 
 // ToJSON serializes the VCon to a JSON string
@@ -448,6 +418,7 @@ func (v *VCon) AddParty(p Party) int {
 }
 
 func (v *VCon) AddDialog(d Dialog) int {
+	d.handle = 0 // a copy of a dialog is a new dialog
 	v.Dialog = append(v.Dialog, d)
 	return len(v.Dialog) - 1
 }
@@ -458,6 +429,7 @@ func (v *VCon) AddAnalysis(a Analysis) int {
 }
 
 func (v *VCon) AddAttachment(att Attachment) int {
+	att.handle = 0 // a copy of an attachment is a new attachment
 	v.Attachments = append(v.Attachments, att)
 	return len(v.Attachments) - 1
 }
@@ -484,10 +456,11 @@ func (v *VCon) FindDialogByProperty(by string, val interface{}) *Dialog {
 	return nil
 }
 
-// FindAttachmentByType finds an attachment by its type
+// FindAttachmentByType finds an attachment by its purpose. Attachments
+// written before purpose existed are matched by their type or encoding.
 func (v *VCon) FindAttachmentByType(attachmentType string) map[string]interface{} {
 	for _, att := range v.Attachments {
-		if att.Encoding == attachmentType {
+		if att.Purpose == attachmentType || att.Type == attachmentType || att.Encoding == attachmentType {
 			return structToMap(att)
 		}
 	}
@@ -504,56 +477,6 @@ func (v *VCon) FindAnalysisByType(analysisType string) map[string]interface{} {
 	return nil
 }
 
-// AddTag adds a tag to the VCon
-func (v *VCon) AddTag(tagName string, tagValue string) {
-	tagsAttachment := v.FindAttachmentByType("tags")
-	if tagsAttachment == nil {
-		// Create new tags attachment
-		v.AddAttachment(Attachment{
-			Encoding: "tags",
-			Body:     fmt.Sprintf("%s:%s", tagName, tagValue),
-		})
-		return
-	}
-
-	// Add tag to existing tags
-	currentTags, ok := tagsAttachment["body"].(string)
-	if !ok {
-		tagsAttachment["body"] = fmt.Sprintf("%s:%s", tagName, tagValue)
-	} else {
-		tagsAttachment["body"] = fmt.Sprintf("%s,%s:%s", currentTags, tagName, tagValue)
-	}
-}
-
-// GetTag gets a tag value by its name
-func (v *VCon) GetTag(tagName string) string {
-	tagsAttachment := v.FindAttachmentByType("tags")
-	if tagsAttachment == nil {
-		return ""
-	}
-
-	tags, ok := tagsAttachment["body"].(string)
-	if !ok {
-		return ""
-	}
-
-	// Parse tags
-	tagPairs := parseTags(tags)
-	return tagPairs[tagName]
-}
-
-// Helper to parse tags
-func parseTags(tagString string) map[string]string {
-	result := make(map[string]string)
-	for _, tag := range strings.Split(tagString, ",") {
-		parts := strings.SplitN(tag, ":", 2)
-		if len(parts) == 2 {
-			result[parts[0]] = parts[1]
-		}
-	}
-	return result
-}
-
 // Helper to convert struct to map
 func structToMap(obj interface{}) map[string]interface{} {
 	data, _ := json.Marshal(obj)
@@ -562,14 +485,15 @@ func structToMap(obj interface{}) map[string]interface{} {
 	return result
 }
 
-// SaveToFile saves the VCon to a file
+// SaveToFile saves the VCon to a file. The file is replaced atomically, so
+// an interrupted save never leaves a truncated vCon behind.
 func (v *VCon) SaveToFile(filePath string) error {
 	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal VCon: %w", err)
 	}
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
+	if err := WriteFileAtomic(filePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -586,138 +510,17 @@ func LoadFromFile(filePath string, propertyHandling ...string) (*VCon, error) {
 	return BuildFromJSON(string(data), propertyHandling...)
 }
 
-// LoadFromURL loads a VCon from a URL
+// LoadFromURL loads a VCon from a URL. Use LoadFromURLWithOptions for
+// authentication, retries and size limits.
 func LoadFromURL(url string, propertyHandling ...string) (*VCon, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP request failed with status code: %d", resp.StatusCode)
-	}
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	return BuildFromJSON(string(data), propertyHandling...)
-}
-
-func (v *VCon) validateCoreFields() []string {
-	var errs []string
-	if v.UUID == "" {
-		errs = append(errs, "missing required field: uuid")
-	}
-	if v.CreatedAt.IsZero() {
-		errs = append(errs, "missing required field: created_at")
-	}
-	return errs
+	return LoadFromURLContext(context.Background(), url, propertyHandling...)
 }
 
-func (v *VCon) validateMutualExclusion() []string {
-	count := 0
-	if v.Redacted != nil {
-		count++
-	}
-	if v.Amended != nil {
-		count++
-	}
-	if len(v.Group) > 0 {
-		count++
-	}
-	if count > 1 {
-		return []string{"redacted, amended, and group are mutually exclusive"}
-	}
-	return nil
-}
-
-func (v *VCon) validateCriticalExtensions() []string {
-	if len(v.Critical) == 0 {
-		return nil
-	}
-	reg := v.registry
-	if reg == nil {
-		reg = DefaultRegistry
-	}
-	if err := reg.ValidateCritical(v.Critical); err != nil {
-		return []string{fmt.Sprintf("critical extension validation: %s", err)}
-	}
-	return nil
-}
-
-func (v *VCon) validateDialogs() []string {
-	var errs []string
-	for i, dialog := range v.Dialog {
-		if parties, ok := dialog.Parties.([]int); ok {
-			for _, partyIdx := range parties {
-				if partyIdx < 0 || partyIdx >= len(v.Parties) {
-					errs = append(errs, fmt.Sprintf("dialog at index %d references invalid party index: %d", i, partyIdx))
-				}
-			}
-		}
-		if dialog.Type == "" {
-			errs = append(errs, fmt.Sprintf("dialog at index %d missing required field: type", i))
-		}
-		if dialog.StartTime == nil {
-			errs = append(errs, fmt.Sprintf("dialog at index %d missing required field: start", i))
-		}
-	}
-	return errs
-}
-
-func (v *VCon) validateAnalysis() []string {
-	var errs []string
-	for i, analysis := range v.Analysis {
-		if analysis.Vendor == "" {
-			errs = append(errs, fmt.Sprintf("analysis at index %d missing required field: vendor", i))
-		}
-		if dialogs, ok := analysis.Dialog.([]int); ok {
-			for _, dialogIdx := range dialogs {
-				if dialogIdx < 0 || dialogIdx >= len(v.Dialog) {
-					errs = append(errs, fmt.Sprintf("analysis at index %d references invalid dialog index: %d", i, dialogIdx))
-				}
-			}
-		}
-	}
-	return errs
-}
-
-func (v *VCon) validateAttachments() []string {
-	var errs []string
-	for i, att := range v.Attachments {
-		if att.DialogIdx == nil {
-			errs = append(errs, fmt.Sprintf("attachment at index %d missing required field: dialog", i))
-		} else if *att.DialogIdx < 0 || *att.DialogIdx >= len(v.Dialog) {
-			errs = append(errs, fmt.Sprintf("attachment at index %d references invalid dialog index: %d", i, *att.DialogIdx))
-		}
-	}
-	return errs
-}
-
-func (v *VCon) allValidationErrors() []string {
-	var errs []string
-	errs = append(errs, v.validateCoreFields()...)
-	errs = append(errs, v.validateMutualExclusion()...)
-	errs = append(errs, v.validateCriticalExtensions()...)
-	errs = append(errs, v.validateDialogs()...)
-	errs = append(errs, v.validateAnalysis()...)
-	errs = append(errs, v.validateAttachments()...)
-	return errs
-}
-
-// Validate validates the VCon structure
-func (v *VCon) Validate() error {
-	if errs := v.allValidationErrors(); len(errs) > 0 {
-		return fmt.Errorf("%s", errs[0])
+// LoadFromURLContext is LoadFromURL with a context controlling the request.
+func LoadFromURLContext(ctx context.Context, url string, propertyHandling ...string) (*VCon, error) {
+	var opts []LoadOption
+	if len(propertyHandling) > 0 {
+		opts = append(opts, WithPropertyHandling(propertyHandling[0]))
 	}
-	return nil
-}
-
-// IsValid validates the VCon and returns if it's valid and any errors
-func (v *VCon) IsValid() (bool, []string) {
-	errs := v.allValidationErrors()
-	return len(errs) == 0, errs
+	return LoadFromURLWithOptionsContext(ctx, url, opts...)
 }
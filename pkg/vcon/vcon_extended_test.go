@@ -2,7 +2,11 @@ package vcon
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestProcessProperties(t *testing.T) {
@@ -231,6 +235,36 @@ func TestUUID8DomainName(t *testing.T) {
 	if len(uuid1Again) != 36 {
 		t.Errorf("expected UUID length 36 for repeated call, got %d", len(uuid1Again))
 	}
+	if uuid1Again == uuid1 || uuid1Again[19:] != uuid1[19:] {
+		t.Errorf("expected a new timestamp and the same domain bits: %s, %s", uuid1, uuid1Again)
+	}
+
+	parsed, err := uuid.Parse(uuid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Version() != 8 || parsed.Variant() != uuid.RFC4122 {
+		t.Errorf("expected an RFC 9562 version 8 UUID, got version %d variant %s", parsed.Version(), parsed.Variant())
+	}
+}
+
+func TestUUID8DomainNameAt(t *testing.T) {
+	// Generated by the vCon Python reference implementation for the same
+	// domain and time.
+	tests := []struct {
+		domain string
+		nanos  int64
+		want   string
+	}{
+		{"example.com", 1700000000123456789, "018bcfe5-687b-874f-8caa-f24ab1a0c334"},
+		{"vcon.example.com", 1718000000000999999, "019000c7-9c00-8fff-9f76-c285defa34d1"},
+	}
+	for _, tt := range tests {
+		got := UUID8DomainNameAt(tt.domain, time.Unix(0, tt.nanos))
+		if got != tt.want {
+			t.Errorf("UUID8DomainNameAt(%q, %d) = %s, want %s", tt.domain, tt.nanos, got, tt.want)
+		}
+	}
 }
 
 func TestUUID8Time(t *testing.T) {
@@ -536,6 +570,9 @@ func TestV003MigrationFullRoundTrip(t *testing.T) {
 			if ch.Hash == "" {
 				t.Errorf("analysis[1] content_hash should have hash set, got %+v", ch)
 			}
+			if err := ch.Check(); err != nil {
+				t.Errorf("analysis[1] hex content_hash should be re-encoded as base64url: %v", err)
+			}
 		}
 	}
 
@@ -639,3 +676,25 @@ func TestDialogTypeEnumSchemaValidation(t *testing.T) {
 		t.Errorf("expected dialog type 'recording', got '%s'", v.Dialog[0].Type)
 	}
 }
+
+func TestValidateOriginator(t *testing.T) {
+	now := time.Now().UTC()
+	newVCon := func(originator int, parties *PartyRefs) *VCon {
+		v := New("example.com")
+		v.AddParty(Party{Name: "Alice"})
+		v.AddParty(Party{Name: "Bob"})
+		v.AddParty(Party{Name: "Carol"})
+		v.AddDialog(Dialog{Type: "recording", StartTime: &now, Parties: parties, Originator: IntPtr(originator)})
+		return v
+	}
+
+	if err := newVCon(1, NewPartyRefs(0, 1)).Validate(); err != nil {
+		t.Errorf("originator among dialog parties should be valid: %v", err)
+	}
+	if err := newVCon(2, NewPartyRefs(0, 1)).Validate(); err == nil || !strings.Contains(err.Error(), "not one of its parties") {
+		t.Errorf("expected originator membership error, got %v", err)
+	}
+	if err := newVCon(5, NewPartyRefs(0, 1)).Validate(); err == nil || !strings.Contains(err.Error(), "invalid originator index") {
+		t.Errorf("expected invalid originator index error, got %v", err)
+	}
+}
@@ -29,12 +29,12 @@ func TestRoundTrip(t *testing.T) {
 	now := time.Now().UTC()
 	v.AddDialog(vcon.Dialog{
 		StartTime:   &now,
-		Originator:  0,
+		Originator:  vcon.IntPtr(0),
 		Type:        "text",
 		MediaType:   "audio/wav",
-		ContentHash: vcon.ContentHashList{{Algorithm: "sha512", Hash: "test-hash"}},
+		ContentHash: vcon.ContentHashList{vcon.ComputeSHA512([]byte("test"))},
 		Body:        "Hello Alice!",
-		Parties:     1,
+		Parties:     vcon.NewPartyRef(0),
 		Encoding:    "base64url",
 	})
 
@@ -79,7 +79,7 @@ func TestAddDialog(t *testing.T) {
 		StartTime:   &now,
 		Duration:    (5 * time.Second).Seconds(),
 		MediaType:   "audio/wav",
-		ContentHash: vcon.ContentHashList{{Algorithm: "sha512", Hash: "test-hash"}},
+		ContentHash: vcon.ContentHashList{vcon.ComputeSHA512([]byte("test"))},
 	})
 
 	assert.Equal(t, 0, idx)
@@ -103,3 +103,118 @@ func TestAddAnalysis(t *testing.T) {
 	assert.Equal(t, "test-vendor", v.Analysis[0].Vendor)
 	assert.Equal(t, "test-product", v.Analysis[0].Product)
 }
+
+func TestTags(t *testing.T) {
+	v := vcon.New("example.com")
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	v.AddParty(vcon.Party{Name: "Alice"})
+	v.AddDialog(vcon.Dialog{Type: "text", StartTime: &start, Parties: vcon.NewPartyRefs(0), Body: "hi", Encoding: "none"})
+
+	v.AddTag("customer", "acme")
+	v.AddTag("priority", "high")
+	v.AddTag("priority", "low")
+
+	require.Len(t, v.Attachments, 1)
+	att := v.Attachments[0]
+	assert.Equal(t, "tags", att.Type)
+	assert.Equal(t, "json", att.Encoding)
+	assert.JSONEq(t, `["customer:acme","priority:low"]`, string(att.Extra["body"]))
+	assert.Equal(t, "acme", v.GetTag("customer"))
+	assert.Equal(t, "low", v.GetTag("priority"))
+	assert.Equal(t, "", v.GetTag("missing"))
+
+	// The body is written as a JSON array, as the Python library writes it,
+	// and passes schema validation.
+	data, err := json.Marshal(att)
+	require.NoError(t, err)
+	var encoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &encoded))
+	assert.Equal(t, "tags", encoded["type"])
+	assert.Equal(t, []any{"customer:acme", "priority:low"}, encoded["body"])
+	loaded, err := vcon.BuildFromJSON(v.ToJSON())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"customer": "acme", "priority": "low"}, loaded.ListTags())
+
+	// Tags do not need a party or dialog to refer to.
+	empty := vcon.New("example.com")
+	empty.AddTag("customer", "acme")
+	require.NoError(t, empty.Validate())
+
+	// Legacy comma separated tags are read and converted on write.
+	legacy := vcon.New("example.com")
+	legacy.AddAttachment(vcon.Attachment{Encoding: "tags", Body: "customer:acme,region:eu:west"})
+	assert.Equal(t, "eu:west", legacy.GetTag("region"))
+	legacy.AddTag("priority", "high")
+	require.Len(t, legacy.Attachments, 1)
+	assert.Equal(t, "json", legacy.Attachments[0].Encoding)
+	assert.JSONEq(t, `["customer:acme","region:eu:west","priority:high"]`, string(legacy.Attachments[0].Extra["body"]))
+
+	assert.True(t, legacy.RemoveTag("region"))
+	assert.False(t, legacy.RemoveTag("region"))
+	assert.JSONEq(t, `["customer:acme","priority:high"]`, string(legacy.Attachments[0].Extra["body"]))
+
+	v.SetTags(map[string]string{"team": "support", "channel": "voice"})
+	require.Len(t, v.Attachments, 1)
+	assert.JSONEq(t, `["channel:voice","team:support"]`, string(v.Attachments[0].Extra["body"]))
+	assert.Equal(t, map[string]string{"channel": "voice", "team": "support"}, v.ListTags())
+
+	v.SetTags(nil)
+	assert.Empty(t, v.Attachments, "an empty tag set removes the attachment")
+	assert.Empty(t, v.ListTags())
+}
+
+func TestTagsSurviveRemovalAndMerge(t *testing.T) {
+	tagged := func(tags map[string]string) *vcon.VCon {
+		v := vcon.New("example.com")
+		start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+		v.AddParty(vcon.Party{Name: "Alice"})
+		v.AddParty(vcon.Party{Name: "Bob"})
+		v.AddDialog(vcon.Dialog{Type: "text", StartTime: &start, Parties: vcon.NewPartyRefs(0, 1), Body: "hi", Encoding: "none"})
+		v.AddDialog(vcon.Dialog{Type: "text", StartTime: &start, Parties: vcon.NewPartyRefs(1), Body: "bye", Encoding: "none"})
+		v.SetTags(tags)
+		return v
+	}
+
+	v := tagged(map[string]string{"customer": "acme"})
+	require.NoError(t, v.RemoveParty(0))
+	assert.Equal(t, map[string]string{"customer": "acme"}, v.ListTags())
+	require.NoError(t, v.RemoveDialog(0))
+	assert.Equal(t, map[string]string{"customer": "acme"}, v.ListTags())
+	require.NoError(t, v.Validate())
+
+	a := tagged(map[string]string{"customer": "acme", "priority": "high"})
+	b := tagged(map[string]string{"priority": "low", "region": "eu"})
+	m, err := vcon.Merge(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"customer": "acme", "priority": "high", "region": "eu"}, m.ListTags())
+	n := 0
+	for _, att := range m.Attachments {
+		if att.Type == "tags" {
+			n++
+		}
+	}
+	assert.Equal(t, 1, n, "merge keeps a single tags attachment")
+}
+
+func TestTagsFromPythonLibrary(t *testing.T) {
+	v, err := vcon.BuildFromJSON(`{
+		"uuid": "018e6e34-6a8b-8000-8000-000000000000",
+		"vcon": "0.4.0",
+		"created_at": "2024-01-01T00:00:00Z",
+		"parties": [],
+		"attachments": [{"type": "tags", "encoding": "json", "body": ["customer:acme", "priority:high"],
+			"party": 0, "dialog": 0, "start": "2024-01-01T00:00:00Z"}]
+	}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"customer": "acme", "priority": "high"}, v.ListTags())
+
+	body, err := v.Attachments[0].GetBody()
+	require.NoError(t, err)
+	assert.Equal(t, []any{"customer:acme", "priority:high"}, body)
+
+	// The array body is written back unchanged.
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal([]byte(v.ToJSON()), &doc))
+	att := doc["attachments"].([]any)[0].(map[string]any)
+	assert.Equal(t, []any{"customer:acme", "priority:high"}, att["body"])
+}
@@ -0,0 +1,85 @@
+#!/usr/bin/env python3
+"""Write interop fixtures with the Python vcon library (py-vcon).
+
+The fixtures mirror what `vconctl interop generate` writes: an unsigned, a
+signed and an encrypted form of the same vCon plus a manifest.json. They are
+signed with testdata/keys/leaf.key (chain leaf.crt, root.crt) and encrypted to
+leaf.crt, so TestInteropPythonFixtures can verify and decrypt them with the
+keys already in the repository.
+
+Usage, from the repository root:
+
+    pip install python-vcon
+    python3 testdata/interop/python/generate_fixtures.py
+
+and commit the files it writes next to this script.
+"""
+
+import datetime
+import json
+import os
+
+import vcon
+
+HERE = os.path.dirname(os.path.abspath(__file__))
+KEYS = os.path.join(HERE, "..", "..", "keys")
+DOMAIN = "example.com"
+
+
+def fixture():
+    """Build the same content as interopFixture in cmd/vconctl/interop.go."""
+    v = vcon.Vcon()
+    v.set_uuid(DOMAIN)
+    v.set_party_parameter("name", "Alice")
+    v.set_party_parameter("tel", "tel:+12025551234", 0)
+    v.set_party_parameter("name", "Bob")
+    v.set_party_parameter("mailto", "mailto:bob@example.com", 1)
+    v.add_dialog_inline_text(
+        "Hello from py-vcon",
+        "2025-01-01T12:00:00+00:00",
+        0,
+        0,
+        "text/plain",
+    )
+    return v
+
+
+def write(name, text):
+    with open(os.path.join(HERE, name), "w", encoding="utf-8") as f:
+        f.write(text)
+        f.write("\n")
+
+
+def main():
+    leaf_key = os.path.join(KEYS, "leaf.key")
+    leaf_crt = os.path.join(KEYS, "leaf.crt")
+    root_crt = os.path.join(KEYS, "root.crt")
+
+    v = fixture()
+    unsigned = v.dumps()
+    doc = json.loads(unsigned)
+    write("unsigned.vcon.json", unsigned)
+
+    v.sign(leaf_key, [leaf_crt, root_crt])
+    write("signed.vcon.json", v.dumps())
+
+    v.encrypt(leaf_crt)
+    write("encrypted.vcon.json", v.dumps())
+
+    manifest = {
+        "implementation": "py-vcon " + getattr(vcon, "__version__", "unknown"),
+        "spec_version": doc.get("vcon", ""),
+        "domain": DOMAIN,
+        "uuid": doc["uuid"],
+        "generated_at": datetime.datetime.now(datetime.timezone.utc).isoformat(),
+        "files": {
+            "unsigned": "unsigned.vcon.json",
+            "signed": "signed.vcon.json",
+            "encrypted": "encrypted.vcon.json",
+        },
+    }
+    write("manifest.json", json.dumps(manifest, indent=2))
+
+
+if __name__ == "__main__":
+    main()